@@ -2,77 +2,490 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/savaki/cloudops-bot/pkg/agent"
 	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/charts"
 	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/contextpack"
+	"github.com/savaki/cloudops-bot/pkg/control"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
 	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/ensemble"
+	"github.com/savaki/cloudops-bot/pkg/fulloutput"
+	"github.com/savaki/cloudops-bot/pkg/iampolicy"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/killswitch"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/lock"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+	"github.com/savaki/cloudops-bot/pkg/report"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	"github.com/savaki/cloudops-bot/pkg/sealed"
 	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
-	"github.com/slack-go/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/savaki/cloudops-bot/pkg/storage/memory"
+	cwtool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatch"
+	logstool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatchlogs"
+	ec2tool "github.com/savaki/cloudops-bot/pkg/tools/ec2"
+	ecstool "github.com/savaki/cloudops-bot/pkg/tools/ecs"
+	"github.com/savaki/cloudops-bot/pkg/warmpool"
+	"github.com/savaki/cloudops-bot/pkg/watch"
+	"github.com/savaki/cloudops-bot/pkg/webhook"
 )
 
 func main() {
+	logging.Setup("agent")
 	ctx := context.Background()
 
-	// Get conversation ID from environment (passed by Step Functions)
-	conversationID := os.Getenv("CONVERSATION_ID")
-	if conversationID == "" {
-		log.Fatal("CONVERSATION_ID environment variable not set")
-	}
-
-	log.Printf("Starting agent for conversation: %s", conversationID)
-
 	// Load application configuration
 	cfg, err := appconfig.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		logging.Fatal(ctx, "failed to load config", "error", err)
 	}
 
 	// Initialize AWS SDK
 	awsCfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
-		log.Fatalf("Failed to load AWS config: %v", err)
+		logging.Fatal(ctx, "failed to load AWS config", "error", err)
 	}
 
-	// Initialize clients
+	// Initialize clients. Local development keeps conversations in memory
 	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
-	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	var convRepo storage.Store
+	if cfg.InMemory() {
+		slog.InfoContext(ctx, "in-memory storage: conversations are lost when the agent exits")
+		convRepo = memory.New()
+	} else {
+		repo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+		repo.SetHistoryTable(cfg.ConversationHistoryTable)
+		repo.SetFaultInjector(cfg.FaultInjector())
+		repo.SetShadow(cfg.ShadowMode)
+		convRepo = repo
+	}
 	slackClient := slackclient.NewClient(cfg.SlackBotToken)
-	_ = bedrock.NewClient(awsCfg) // TODO: Use in conversation handling
+	bedrockClient := bedrock.NewClient(awsCfg)
+	bedrockClient.SetModel(cfg.BedrockModelID)
+
+	// In sandbox mode the tools read the demo account instead
+	toolsCfg := awsCfg
+	if cfg.Sandbox() {
+		slog.InfoContext(ctx, "sandbox mode: tools use the sandbox role", "role_arn", cfg.SandboxRoleARN)
+		toolsCfg = sandbox.Config(awsCfg, cfg.SandboxRoleARN, cfg.SandboxExternalID)
+	}
+	bedrockClient.RegisterTool(ec2tool.New(toolsCfg))
+	bedrockClient.RegisterTool(ecstool.New(toolsCfg))
+	logsTool := logstool.New(toolsCfg)
+	bedrockClient.RegisterTool(logsTool)
+	bedrockClient.RegisterTool(cwtool.New(toolsCfg))
+	diagnoser := diagnose.New(toolsCfg)
+	bedrockClient.SetDiagnoser(diagnoser)
+
+	// Rotated bot tokens expire every 12 hours, which a long conversation can outlast
+	if cfg.TokenRotation() && !cfg.InMemory() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			logging.Fatal(ctx, "failed to get slack bot token", "error", err)
+		}
+		go rotator.Run(ctx, slackClient)
+	}
+
+	subRepo := dynamodb.NewSubscriptionRepository(ddbClient, cfg.SubscriptionsTable)
+	promptRepo := dynamodb.NewPromptRepository(ddbClient, cfg.PromptsTable)
+	usageRepo := dynamodb.NewUsageRepository(ddbClient, cfg.UsageTable)
+	permRepo := dynamodb.NewPermissionRepository(ddbClient, cfg.PermissionsTable)
+	settingsRepo := dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable)
+	auditRepo := dynamodb.NewAuditRepository(ddbClient, cfg.AuditTable)
+	auditRepo.SetToolTable(cfg.ToolAuditTable)
+	if cfg.IAMSuggestionChannel != "" && !cfg.ShadowMode && !cfg.InMemory() {
+		diagnoser.SetDenialHandler(iampolicy.NewSuggester(settingsRepo, slackClient, cfg.IAMSuggestionChannel, cfg.IAMSuggestionThreshold))
+	}
+
+	// Fault injection for resilience testing (never enabled in production)
+	if faults := cfg.FaultInjector(); faults != nil {
+		slog.WarnContext(ctx, "fault injection enabled", "latency_ms", cfg.ChaosLatencyMs, "error_rate", cfg.ChaosErrorRate)
+		subRepo.SetFaultInjector(faults)
+		promptRepo.SetFaultInjector(faults)
+		usageRepo.SetFaultInjector(faults)
+		permRepo.SetFaultInjector(faults)
+		settingsRepo.SetFaultInjector(faults)
+		auditRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+		bedrockClient.SetFaultInjector(faults)
+	}
+
+	// Conversation ID is passed by Step Functions when it launches a task.
+	// Tasks started without one join the warm pool and wait to be claimed,
+	// or locally start a conversation of their own
+	conversationID := os.Getenv("CONVERSATION_ID")
+	input, err := executionInput(conversationID)
+	if err != nil {
+		logging.Fatal(ctx, "invalid step functions input", "error", err)
+	}
+	unseal(ctx, awsCfg, input)
+	if conversationID == "" && cfg.InMemory() {
+		if conversationID, err = seedConversation(ctx, convRepo); err != nil {
+			logging.Fatal(ctx, "failed to start local conversation", "error", err)
+		}
+	}
+	if conversationID == "" {
+		poolRepo := dynamodb.NewWarmPoolRepository(ddbClient, cfg.WarmPoolTable)
+		if faults := cfg.FaultInjector(); faults != nil {
+			poolRepo.SetFaultInjector(faults)
+		}
+
+		conversationID, err = waitForConversation(ctx, poolRepo, cfg.GetWarmPoolMaxIdle())
+		if errors.Is(err, warmpool.ErrIdleTimeout) || errors.Is(err, context.Canceled) {
+			slog.InfoContext(ctx, "leaving warm pool", "reason", err)
+			return
+		}
+		if err != nil {
+			logging.Fatal(ctx, "warm pool failed", "error", err)
+		}
+	}
+
+	ctx = logging.With(ctx, logging.ConversationID, conversationID)
+	slog.InfoContext(ctx, "starting agent")
+
+	// A shadow agent runs a candidate version alongside the production
+	// agent on the same conversation. It reads everything the production
+	// agent does but only logs what it would post or write
+	if cfg.ShadowMode {
+		slog.InfoContext(ctx, "shadow mode: slack posts and conversation writes are logged, not made")
+		slackClient.SetShadow(true)
+	}
+
+	// Retries, warm pool claims, and Spot relaunches can all start a second
+	// agent for the same conversation. Only the lock holder answers; the
+	// wait covers a lease left behind by a task that died without releasing
+	runCtx := ctx
+	if cfg.LocksTable != "" && !cfg.ShadowMode && !cfg.InMemory() {
+		lockRepo := dynamodb.NewLockRepository(ddbClient, cfg.LocksTable)
+		if faults := cfg.FaultInjector(); faults != nil {
+			lockRepo.SetFaultInjector(faults)
+		}
+		locks := lock.New(lockRepo, lockOwner(ctx))
+		locks.SetIntervals(cfg.GetLockLease(), lock.DefaultRetryInterval)
+
+		lease, err := locks.Acquire(ctx, models.ConversationLockID(conversationID), 2*cfg.GetLockLease())
+		if errors.Is(err, lock.ErrHeld) {
+			slog.InfoContext(ctx, "conversation is being handled by another agent, exiting")
+			return
+		}
+		if err != nil {
+			logging.Fatal(ctx, "failed to lock conversation", "error", err)
+		}
+		defer func() {
+			if err := lease.Release(ctx); err != nil {
+				slog.WarnContext(ctx, "failed to release conversation lock", "error", err)
+			}
+		}()
+
+		var cancel context.CancelFunc
+		runCtx, cancel = lease.Context(ctx)
+		defer cancel()
+	}
+
+	// Get conversation from the context pack it was launched with, or
+	// else from DynamoDB
+	pack := launchPack(ctx, cfg, input)
+	var conversation *models.Conversation
+	if pack != nil {
+		conversation = pack.Conversation
+		slog.InfoContext(ctx, "using context pack", "created_at", pack.CreatedAt)
+	} else if conversation, err = convRepo.GetByID(ctx, conversationID); err != nil {
+		logging.Fatal(ctx, "failed to get conversation", "error", err)
+	}
+	if conversation.Ended() {
+		slog.InfoContext(ctx, "conversation already ended, exiting", "status", conversation.Status)
+		return
+	}
+
+	slog.InfoContext(ctx, "retrieved conversation", logging.ChannelID, conversation.ChannelID, logging.UserID, conversation.UserID)
+
+	// Record the task so the reaper can stop it if it stops heartbeating
+	if arn := taskArn(ctx); arn != "" && arn != conversation.TaskArn {
+		if err := convRepo.UpdateTaskArn(ctx, conversationID, arn); err != nil {
+			slog.WarnContext(ctx, "failed to record task arn", "error", err)
+		}
+		conversation.TaskArn = arn
+	}
+
+	// Run the conversation until it goes idle
+	a := agent.New(cfg, conversation, convRepo, slackClient, bedrockClient)
+	a.SetChartRenderer(charts.NewRenderer(toolsCfg))
+	if cfg.EmbeddingModelID != "" {
+		bedrockClient.SetEmbeddingModel(cfg.EmbeddingModelID)
+		a.SetEmbedder(bedrockClient)
+	}
+	if cfg.EnsembleModelID != "" {
+		secondModel := bedrock.NewClient(awsCfg)
+		secondModel.SetModel(cfg.EnsembleModelID)
+		secondModel.SetFaultInjector(cfg.FaultInjector())
+
+		e := ensemble.New(bedrockClient, secondModel)
+		e.SetCleaner(agent.CleanResponse)
+		a.SetEnsemble(e)
+	}
+	if cfg.ReportsBucket != "" && !cfg.ShadowMode {
+		a.SetReportStore(report.NewStore(awsCfg, cfg.ReportsBucket))
+	}
+	if cfg.OutputsBucket != "" && !cfg.ShadowMode {
+		a.SetOutputStore(fulloutput.NewStore(awsCfg, cfg.OutputsBucket))
+	}
+	if pack != nil {
+		a.SetContextPack(pack, cfg.GetContextPackMaxAge())
+	}
+
+	// Watchers, prompts, permissions, and the rest are kept in DynamoDB,
+	// which local development goes without
+	var notifier *watch.Notifier
+	if !cfg.InMemory() {
+		notifier = watch.NewNotifier(subRepo, slackClient)
+		a.SetNotifier(notifier)
+		if cfg.PromptsTable != "" {
+			a.SetPromptStore(promptRepo)
+		}
+		a.SetPermissions(permRepo)
+		if cfg.RBACPolicy != "" {
+			if e := rbac.Enforce(ctx, cfg.RBACPolicy, ssm.NewFromConfig(awsCfg), settingsRepo, slackClient); e != nil {
+				a.SetRBAC(e)
+			}
+		}
+		if cfg.ToolAuditTable != "" && !cfg.ShadowMode {
+			a.SetToolAudit(auditRepo)
+		}
+		a.SetKillSwitch(killswitch.New(settingsRepo, killswitch.DefaultInterval))
+		if cfg.UsageTable != "" && !cfg.ShadowMode {
+			a.SetUsageRepository(usageRepo, true)
+		}
+		// The Slack handler records new messages here, so the channel is
+		// only read when there is something to answer
+		if cfg.InboxTable != "" {
+			inboxRepo := dynamodb.NewInboxRepository(ddbClient, cfg.InboxTable)
+			inboxRepo.SetFaultInjector(cfg.FaultInjector())
+			a.SetInbox(inboxRepo)
+		}
+	}
+	var hooks *webhook.Notifier
+	if len(cfg.WebhookURLs) > 0 && !cfg.ShadowMode {
+		hooks = webhook.New(cfg.WebhookURLs, cfg.WebhookSecret)
+		a.SetWebhooks(hooks)
+	}
+	// Background jobs run in this task unless the job worker Lambda runs
+	// them. Jobs still running when the conversation ends are recorded as
+	// stopped
+	if cfg.JobsTable != "" && !cfg.ShadowMode && !cfg.InMemory() {
+		jobRepo := dynamodb.NewJobRepository(ddbClient, cfg.JobsTable)
+		jobRepo.SetFaultInjector(cfg.FaultInjector())
+		manager := jobs.NewManager(jobRepo, slackClient)
+		manager.Register(logsTool.ScanJob())
+		manager.Register(ec2tool.SweepJob(toolsCfg))
+		if cfg.JobRunner != "lambda" {
+			local := jobs.NewLocal(manager)
+			defer local.Close()
+			manager.SetDispatcher(local)
+		}
+		bedrockClient.RegisterTool(manager.StartTool())
+		bedrockClient.RegisterTool(manager.StatusTool())
+		a.SetJobs(manager)
+	}
+
+	// Operators reach the running agent through ECS Exec and cloudopsctl
+	if cfg.ControlSocket != "" {
+		server := control.NewServer(cfg.ControlSocket, a)
+		go func(ctx context.Context) {
+			if err := server.Serve(ctx); err != nil {
+				slog.WarnContext(ctx, "control api stopped", "error", err)
+			}
+		}(runCtx)
+	}
+
+	// ECS sends SIGTERM before stopping the task. Fargate Spot does so two
+	// minutes before reclaiming it, and the conversation is checkpointed so
+	// the task relaunched on demand can pick it up; any other stop ends it
+	lockedCtx := runCtx
+	runCtx, stop := signal.NotifyContext(runCtx, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Run(runCtx); err != nil {
+		if lockedCtx.Err() != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "lost the conversation lock to another agent, exiting")
+			return
+		}
+		if runCtx.Err() != nil && ctx.Err() == nil && cfg.AgentCapacity == models.CapacitySpot {
+			if err := a.Checkpoint(ctx); err != nil {
+				logging.Fatal(ctx, "failed to checkpoint interrupted conversation", "error", err)
+			}
+			slog.InfoContext(ctx, "agent interrupted; checkpointed conversation for relaunch")
+			return
+		}
+		if runCtx.Err() != nil && ctx.Err() == nil {
+			if err := a.Shutdown(ctx); err != nil {
+				logging.Fatal(ctx, "failed to end conversation on shutdown", "error", err)
+			}
+			slog.InfoContext(ctx, "agent stopped; ended conversation")
+			return
+		}
+		if updateErr := convRepo.UpdateStatus(ctx, conversationID, models.StatusFailed); updateErr != nil {
+			slog.ErrorContext(ctx, "failed to mark conversation failed", "error", updateErr)
+		}
+		notifier.StatusChanged(ctx, conversation, models.StatusFailed)
+		conversation.UpdateStatus(models.StatusFailed)
+		conversation.Error = err.Error()
+		hooks.Send(ctx, webhook.EventFailed, conversation)
+		lifecycle.Mark(ctx, slackClient, conversation, lifecycle.ForStatus(models.StatusFailed))
+		logging.Fatal(ctx, "agent failed", "error", err)
+	}
+
+	slog.InfoContext(ctx, "agent completed")
+}
+
+// waitForConversation registers this task in the warm pool and blocks until
+// a conversation is claimed for it. SIGTERM (ECS scaling in or deploying)
+// deregisters the task so no conversation is handed to it
+func waitForConversation(ctx context.Context, poolRepo *dynamodb.WarmPoolRepository, maxIdle time.Duration) (string, error) {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	defer stop()
+
+	agent := models.NewWarmAgent(taskArn(ctx))
+	slog.InfoContext(ctx, "joined warm pool", "agent_id", agent.AgentID)
+
+	return warmpool.New(poolRepo).Wait(ctx, agent, maxIdle)
+}
+
+// seedConversation starts a conversation for a local agent from
+// CHANNEL_ID, USER_ID, and INITIAL_COMMAND, as the Slack handler would
+// for a mention
+func seedConversation(ctx context.Context, convRepo storage.Store) (string, error) {
+	channelID, userID := os.Getenv("CHANNEL_ID"), os.Getenv("USER_ID")
+	if channelID == "" || userID == "" {
+		return "", errors.New("CHANNEL_ID and USER_ID are required without CONVERSATION_ID")
+	}
 
-	// Get conversation from DynamoDB
-	conversation, err := convRepo.GetByID(ctx, conversationID)
+	conv := models.NewConversation(channelID, userID, os.Getenv("INITIAL_COMMAND"))
+	if err := convRepo.Save(ctx, conv); err != nil {
+		return "", fmt.Errorf("save conversation: %w", err)
+	}
+	slog.InfoContext(ctx, "started local conversation", logging.ConversationID, conv.ConversationID, logging.ChannelID, channelID)
+	return conv.ConversationID, nil
+}
+
+// lockOwner identifies this task in conversation locks
+func lockOwner(ctx context.Context) string {
+	if arn := taskArn(ctx); arn != "" {
+		return arn
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+// executionInput validates the execution input Step Functions passes in
+// EXECUTION_INPUT, so an agent and orchestrator deployed with input
+// versions apart fail clearly. State machines from before it only set
+// CONVERSATION_ID, which is still accepted alone, returning nil
+func executionInput(conversationID string) (*models.StepFunctionInput, error) {
+	raw := os.Getenv("EXECUTION_INPUT")
+	if raw == "" {
+		return nil, nil
+	}
+	input, err := models.ParseStepFunctionInput([]byte(raw))
 	if err != nil {
-		log.Fatalf("Failed to get conversation: %v", err)
-	}
-
-	log.Printf("Retrieved conversation for channel %s, user %s", conversation.ChannelID, conversation.UserID)
-
-	// TODO: Implement conversation handling logic
-	// 1. Get message history from DynamoDB
-	// 2. Process user's initial message with Claude
-	// 3. Implement Claude tool calling for AWS operations:
-	//    - EC2: Describe instances, get console output
-	//    - RDS: Describe databases, check status
-	//    - CloudWatch: Query logs, get metrics
-	//    - Lambda: List functions, get configurations
-	//    - ECS: Describe services and tasks
-	// 4. Post Claude's response to Slack
-	// 5. Listen for follow-up messages (poll Slack API or use RTM)
-	// 6. Handle multi-turn conversation with context
-	// 7. Exit gracefully when conversation is idle (e.g., 30 minutes)
-	// 8. Update conversation status in DynamoDB before exiting
-
-	// Example placeholder response
-	message := "🤖 CloudOps assistant is ready! I can help you with AWS operations. Ask me anything about your infrastructure."
-	if _, err := slackClient.PostMessage(ctx, conversation.ChannelID, slack.MsgOptionText(message, false)); err != nil {
-		log.Printf("Failed to post message: %v", err)
-	}
-
-	// TODO: Replace this with actual conversation loop
-	fmt.Println("Agent stub executed successfully. Implement conversation handling here.")
-	log.Printf("Agent completed for conversation: %s", conversationID)
+		return nil, err
+	}
+	if input.ConversationID != conversationID {
+		return nil, fmt.Errorf("input is for conversation %s, but CONVERSATION_ID is %q", input.ConversationID, conversationID)
+	}
+	return input, nil
+}
+
+// unseal decrypts the sensitive fields of a sealed input. They are only
+// ever a head start, so an input that can't be opened goes without them
+// and the agent reads DynamoDB instead
+func unseal(ctx context.Context, awsCfg aws.Config, input *models.StepFunctionInput) {
+	if input == nil || input.Sealed == "" {
+		return
+	}
+	sealer := sealed.New(awsCfg, "")
+	open := func(s string) ([]byte, error) {
+		return sealer.Open(ctx, input.ConversationID, s)
+	}
+	if err := input.Unseal(open); err != nil {
+		slog.WarnContext(ctx, "ignoring sealed input that can't be opened", "error", err)
+		input.Sealed = ""
+	}
+}
+
+// launchPack returns the context pack the task was launched with, when
+// it is fresh enough to use instead of reading DynamoDB. A task relaunched
+// after Spot reclaimed the first gets the same input, so the pack is stale
+// by then and ignored
+func launchPack(ctx context.Context, cfg *appconfig.Config, input *models.StepFunctionInput) *contextpack.Pack {
+	if input == nil || input.ContextPack == "" || cfg.GetContextPackMaxAge() == 0 {
+		return nil
+	}
+	if input.Capacity == models.CapacitySpot && cfg.AgentCapacity != models.CapacitySpot {
+		return nil
+	}
+
+	pack, err := contextpack.Decode(input.ContextPack)
+	if err != nil {
+		slog.WarnContext(ctx, "ignoring unreadable context pack", "error", err)
+		return nil
+	}
+	if pack.Conversation.ConversationID != input.ConversationID {
+		slog.WarnContext(ctx, "ignoring context pack for another conversation", "pack_conversation_id", pack.Conversation.ConversationID)
+		return nil
+	}
+	if !pack.Fresh(time.Now(), cfg.GetContextPackMaxAge()) {
+		slog.InfoContext(ctx, "context pack is stale, reading conversation", "created_at", pack.CreatedAt)
+		return nil
+	}
+	return pack
+}
+
+// taskArn reads this task's ARN from the ECS metadata endpoint, or returns
+// "" when not running on ECS
+func taskArn(ctx context.Context) string {
+	endpoint := os.Getenv("ECS_CONTAINER_METADATA_URI_V4")
+	if endpoint == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/task", nil)
+	if err != nil {
+		return ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read task metadata", "error", err)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	var metadata struct {
+		TaskARN string `json:"TaskARN"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		slog.WarnContext(ctx, "failed to decode task metadata", "error", err)
+		return ""
+	}
+	return metadata.TaskARN
 }
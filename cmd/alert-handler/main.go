@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/savaki/cloudops-bot/pkg/alerts"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/oncall"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// Event is either a CloudWatch alarm delivered over SNS or the per-minute
+// EventBridge tick that checks for unacknowledged alerts
+type Event struct {
+	Records []events.SNSEventRecord `json:"Records"`
+}
+
+// alertHandler holds the clients used to post and escalate alerts
+type alertHandler struct {
+	cfg         *appconfig.Config
+	slackClient *slackclient.Client
+	alertRepo   *dynamodb.AlertRepository
+	routes      *alerts.Routes
+	cloudwatch  *cloudwatch.Client
+	oncall      oncall.Provider // nil when on-call lookup is disabled
+	pager       *oncall.Pager   // nil when paging is disabled
+}
+
+// Handler posts alerts to the channels their routes name and escalates
+// critical ones nobody acknowledged
+func Handler(ctx context.Context, event Event) error {
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.AlertChannel == "" {
+		return fmt.Errorf("ALERT_CHANNEL is required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	shifts := dynamodb.NewOnCallRepository(ddbClient, cfg.OnCallTable)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+	routes, err := alerts.LoadRoutes(ctx, cfg.AlertRoutes, ssm.NewFromConfig(awsCfg))
+	if err != nil {
+		return fmt.Errorf("load alert routes: %w", err)
+	}
+	h := &alertHandler{
+		cfg:         cfg,
+		slackClient: slackClient,
+		alertRepo:   dynamodb.NewAlertRepository(ddbClient, cfg.AlertsTable),
+		routes:      routes,
+		cloudwatch:  cloudwatch.NewFromConfig(awsCfg),
+		oncall:      oncall.New(cfg.OnCallProvider, cfg.OnCallAPIToken, cfg.OnCallSchedules, shifts),
+	}
+	if cfg.AlertPagerRoutingKey != "" {
+		h.pager = oncall.NewPager(cfg.AlertPagerRoutingKey)
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		h.slackClient.SetFaultInjector(faults)
+		h.alertRepo.SetFaultInjector(faults)
+		shifts.SetFaultInjector(faults)
+	}
+
+	if len(event.Records) == 0 {
+		return h.escalate(ctx, time.Now())
+	}
+
+	for _, record := range event.Records {
+		if err := h.post(ctx, record.SNS.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post announces a firing alarm in its route's channel and, for critical
+// alerts, starts tracking acknowledgments. Repeats within the digest window
+// update the first message instead
+func (h *alertHandler) post(ctx context.Context, message string) error {
+	alarm, err := alerts.ParseAlarm(message)
+	if err != nil {
+		slog.WarnContext(ctx, "ignoring SNS message", "error", err)
+		return nil
+	}
+	if !alarm.IsFiring() {
+		slog.InfoContext(ctx, "ignoring alarm transition", "alarm", alarm.AlarmName, "state", alarm.NewStateValue)
+		return nil
+	}
+
+	route := h.routes.Resolve(alarm, h.tags(ctx, alarm), alerts.Route{
+		Channel:  h.cfg.AlertChannel,
+		Team:     h.cfg.AlertTeam,
+		Severity: alerts.SeverityCritical,
+	})
+	if h.fold(ctx, alarm, route) {
+		return nil
+	}
+
+	responders := h.responders(ctx, route.Team)
+	text := alerts.Format(alarm, route, responders, h.cfg.GetAlertAckWindow())
+	ts, err := h.slackClient.PostMessage(ctx, route.Channel, slack.MsgOptionText(text, false))
+	if err != nil {
+		return fmt.Errorf("post alert: %w", err)
+	}
+	if h.cfg.AlertDigestMinutes > 0 {
+		digest := models.NewAlertDigest(route.Channel, alerts.AlarmKey(alarm), ts, alarm.AlarmName, responders, time.Now())
+		if err := h.alertRepo.SaveDigest(ctx, digest); err != nil {
+			slog.WarnContext(ctx, "failed to save alert digest", "alarm", alarm.AlarmName, "error", err)
+		}
+	}
+	if route.Severity != alerts.SeverityCritical {
+		slog.InfoContext(ctx, "posted alert", "severity", route.Severity, "alarm", alarm.AlarmName, logging.ChannelID, route.Channel, "route", route.Name)
+		return nil
+	}
+
+	alert := models.NewAlert(route.Channel, ts, alarm.AlarmName, route.Team, responders, h.cfg.GetAlertAckWindow())
+	if err := h.alertRepo.Save(ctx, alert); err != nil {
+		return fmt.Errorf("save alert: %w", err)
+	}
+
+	slog.InfoContext(ctx, "posted alert", "severity", route.Severity, "alert_id", alert.AlertID, "alarm", alarm.AlarmName, logging.ChannelID, route.Channel, "route", route.Name, "responders", responders)
+	return nil
+}
+
+// fold counts a repeat of an alarm posted within the digest window and
+// updates the alert with how often it fired, reporting whether it did.
+// An alarm whose digest can't be read is posted again rather than dropped
+func (h *alertHandler) fold(ctx context.Context, alarm *alerts.CloudWatchAlarm, route alerts.Route) bool {
+	window := h.cfg.GetAlertDigestWindow()
+	if window <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	digest, err := h.alertRepo.FoldDigest(ctx, route.Channel, alerts.AlarmKey(alarm), now, now.Add(-window))
+	if err != nil {
+		slog.WarnContext(ctx, "failed to fold alert into digest", "alarm", alarm.AlarmName, "error", err)
+		return false
+	}
+	if digest == nil {
+		return false
+	}
+
+	text := alerts.Format(alarm, route, digest.Responders, h.cfg.GetAlertAckWindow()) + "\n" + alerts.DigestLine(digest)
+	if err := h.slackClient.UpdateMessage(ctx, route.Channel, digest.MessageTS, slack.MsgOptionText(text, false)); err != nil {
+		slog.WarnContext(ctx, "failed to update alert digest", "alarm", alarm.AlarmName, "error", err)
+	}
+	slog.InfoContext(ctx, "folded alert into digest", "alarm", alarm.AlarmName, logging.ChannelID, route.Channel, "occurrences", digest.Occurrences)
+	return true
+}
+
+// tags returns the alarm's tags when a route matches on them. An alarm whose
+// tags can't be read is routed by name alone
+func (h *alertHandler) tags(ctx context.Context, alarm *alerts.CloudWatchAlarm) map[string]string {
+	if !h.routes.UsesTags() || alarm.AlarmArn == "" {
+		return nil
+	}
+
+	out, err := h.cloudwatch.ListTagsForResource(ctx, &cloudwatch.ListTagsForResourceInput{
+		ResourceARN: aws.String(alarm.AlarmArn),
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read alarm tags", "alarm", alarm.AlarmName, "error", err)
+		return nil
+	}
+
+	tags := make(map[string]string, len(out.Tags))
+	for _, tag := range out.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+// responders returns the Slack user IDs of the team's on-call
+func (h *alertHandler) responders(ctx context.Context, team string) []string {
+	if h.oncall == nil || team == "" {
+		return nil
+	}
+
+	found, err := h.oncall.OnCall(ctx, team, time.Now())
+	if err != nil {
+		if !errors.Is(err, oncall.ErrUnknownTeam) {
+			slog.WarnContext(ctx, "failed to look up on-call", "team", team, "error", err)
+		}
+		return nil
+	}
+
+	var userIDs []string
+	for _, r := range oncall.Resolve(ctx, found, h.slackClient.UserIDByEmail) {
+		if r.SlackUserID != "" {
+			userIDs = append(userIDs, r.SlackUserID)
+		}
+	}
+	return userIDs
+}
+
+// escalate DMs responders and pages for alerts past their ack deadline
+func (h *alertHandler) escalate(ctx context.Context, now time.Time) error {
+	open, err := h.alertRepo.ListOpen(ctx)
+	if err != nil {
+		return fmt.Errorf("list open alerts: %w", err)
+	}
+
+	for _, alert := range open {
+		if !alerts.NeedsEscalation(alert, now) {
+			continue
+		}
+
+		permalink, err := h.slackClient.GetPermalink(ctx, alert.ChannelID, alert.MessageTS)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to get permalink", "alert_id", alert.AlertID, "error", err)
+		}
+
+		for _, userID := range alert.Responders {
+			if _, err := h.slackClient.PostMessage(ctx, userID, slack.MsgOptionText(alerts.EscalationDM(alert, permalink), false)); err != nil {
+				slog.WarnContext(ctx, "failed to DM responder", logging.UserID, userID, "alert_id", alert.AlertID, "error", err)
+			}
+		}
+
+		action := "sent DMs to the on-call"
+		if h.pager != nil {
+			if err := h.pager.Trigger(ctx, alert.AlertID, "Unacknowledged critical alert: "+alert.Title, "cloudops-bot"); err != nil {
+				slog.WarnContext(ctx, "failed to page", "alert_id", alert.AlertID, "error", err)
+			} else {
+				action = "paged the on-call"
+			}
+		}
+		if len(alert.Responders) == 0 && h.pager == nil {
+			action = "but there is nobody to escalate to (set ALERT_TEAM or ALERT_PAGER_ROUTING_KEY)"
+		}
+
+		note := fmt.Sprintf("⏫ Not acknowledged within %d minutes, %s.", h.cfg.AlertAckMinutes, action)
+		if _, err := h.slackClient.PostMessage(ctx, alert.ChannelID, slack.MsgOptionText(note, false), slack.MsgOptionTS(alert.MessageTS)); err != nil {
+			slog.WarnContext(ctx, "failed to post escalation", "alert_id", alert.AlertID, "error", err)
+		}
+
+		alert.Status = models.AlertEscalated
+		alert.EscalatedAt = &now
+		if err := h.alertRepo.Save(ctx, alert); err != nil {
+			slog.WarnContext(ctx, "failed to save escalation", "alert_id", alert.AlertID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	logging.Setup("alert-handler")
+	lambda.Start(Handler)
+}
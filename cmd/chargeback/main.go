@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/savaki/cloudops-bot/pkg/chargeback"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// Handler posts last month's chargeback report when triggered by the
+// monthly schedule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	month := chargeback.PreviousMonth(time.Now())
+	slog.InfoContext(ctx, "generating chargeback report", "month", month)
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.ChargebackChannel == "" {
+		return fmt.Errorf("CHARGEBACK_CHANNEL is required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	usageRepo := dynamodb.NewUsageRepository(ddbClient, cfg.UsageTable)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		usageRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+	}
+
+	records, err := usageRepo.ListMonth(ctx, month)
+	if err != nil {
+		return fmt.Errorf("list usage: %w", err)
+	}
+
+	report := chargeback.Build(month, records, cfg.ChargebackRates())
+	slog.InfoContext(ctx, "built chargeback report", "month", month, "cost_centers", len(report.Lines), "total", report.Total.Cost())
+
+	if _, err := slackClient.PostMessage(ctx, cfg.ChargebackChannel, slack.MsgOptionText(report.Render(), false)); err != nil {
+		return fmt.Errorf("post chargeback: %w", err)
+	}
+	if len(report.Lines) > 0 {
+		filename := fmt.Sprintf("chargeback-%s.csv", month)
+		if err := slackClient.UploadFile(ctx, cfg.ChargebackChannel, "", filename, "Chargeback "+month, report.CSV()); err != nil {
+			return fmt.Errorf("upload chargeback csv: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	logging.Setup("chargeback")
+	lambda.Start(Handler)
+}
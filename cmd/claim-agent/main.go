@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/warmpool"
+)
+
+// Output tells the state machine whether a warm agent took the conversation
+type Output struct {
+	Claimed bool   `json:"claimed"`
+	AgentID string `json:"agentId,omitempty"`
+}
+
+// Handler hands the conversation to an idle warm-pool agent. When the pool
+// is empty the state machine falls back to launching a new task
+func Handler(ctx context.Context, input models.StepFunctionInput) (Output, error) {
+	if err := input.Validate(); err != nil {
+		return Output{}, err
+	}
+	ctx = logging.With(ctx, logging.ConversationID, input.ConversationID)
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return Output{}, fmt.Errorf("load config: %w", err)
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return Output{}, fmt.Errorf("load aws config: %w", err)
+	}
+
+	poolRepo := dynamodb.NewWarmPoolRepository(dynamodb.NewClientWithConfig(awsCfg), cfg.WarmPoolTable)
+	if faults := cfg.FaultInjector(); faults != nil {
+		poolRepo.SetFaultInjector(faults)
+	}
+
+	agent, err := warmpool.New(poolRepo).Claim(ctx, input.ConversationID)
+	if err != nil {
+		return Output{}, fmt.Errorf("claim warm agent: %w", err)
+	}
+	if agent == nil {
+		slog.InfoContext(ctx, "no warm agent available, launching a new task")
+		return Output{Claimed: false}, nil
+	}
+
+	slog.InfoContext(ctx, "conversation claimed by warm agent", "agent_id", agent.AgentID, "task_arn", agent.TaskArn)
+	return Output{Claimed: true, AgentID: agent.AgentID}, nil
+}
+
+func main() {
+	logging.Setup("claim-agent")
+	lambda.Start(Handler)
+}
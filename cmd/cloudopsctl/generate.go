@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
+)
+
+// terraformRefs are the interpolations the Terraform snippet uses for
+// anything not given on the command line
+var terraformRefs = stepfunctions.Params{
+	ClusterArn:     "${aws_ecs_cluster.cloudops.arn}",
+	TaskDefinition: "${aws_ecs_task_definition.agent.arn}",
+	ClaimFunction:  "${aws_lambda_function.claim_agent.arn}",
+	Subnets:        []string{"${aws_subnet.public_1.id}", "${aws_subnet.public_2.id}"},
+	SecurityGroup:  "${aws_security_group.ecs.id}",
+}
+
+const cloudFormationSnippet = `  ConversationStateMachine:
+    Type: AWS::StepFunctions::StateMachine
+    Properties:
+      StateMachineName: !Sub 'cloudops-conversation-${Env}'
+      RoleArn: !GetAtt StepFunctionsExecutionRole.Arn
+      DefinitionString:
+        Fn::Sub:
+          - |
+            %s
+          - ClusterArn: !GetAtt ECSCluster.Arn
+            TaskDef: !Ref AgentTaskDefinition
+            ClaimAgentFunction: !GetAtt ClaimAgentFunction.Arn
+            Subnet1: !Ref PublicSubnet1
+            Subnet2: !Ref PublicSubnet2
+            SecurityGroup: !Ref ECSSecurityGroup
+`
+
+const terraformSnippet = `resource "aws_sfn_state_machine" "conversation" {
+  name     = "cloudops-conversation-${var.env}"
+  role_arn = aws_iam_role.step_functions.arn
+
+  definition = <<-EOT
+    %s
+  EOT
+}
+`
+
+const cdkSnippet = `new sfn.CfnStateMachine(this, 'ConversationStateMachine', {
+  stateMachineName: ` + "`cloudops-conversation-${env}`" + `,
+  roleArn: stepFunctionsRole.roleArn,
+  definitionString: JSON.stringify(%s),
+  definitionSubstitutions: {
+    ClusterArn: cluster.clusterArn,
+    TaskDef: agentTaskDefinition.taskDefinitionArn,
+    ClaimAgentFunction: claimAgentFunction.functionArn,
+    Subnet1: vpc.publicSubnets[0].subnetId,
+    Subnet2: vpc.publicSubnets[1].subnetId,
+    SecurityGroup: ecsSecurityGroup.securityGroupId,
+  },
+});
+`
+
+// generateSFN writes the conversation state machine the code expects, as
+// Amazon States Language or wrapped for CloudFormation, Terraform, or CDK
+func generateSFN(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("generate-sfn", flag.ExitOnError)
+	format := fs.String("format", "asl", "asl, cloudformation, terraform, or cdk")
+	cluster := fs.String("cluster", "", "ECS cluster ARN")
+	taskDefinition := fs.String("task-definition", "", "agent task definition ARN, for conversations without a task size")
+	claimFunction := fs.String("claim-function", "", "warm pool claim Lambda ARN")
+	subnets := fs.String("subnets", "", "comma-separated subnet IDs for agent tasks")
+	securityGroup := fs.String("security-group", "", "security group ID for agent tasks")
+	timeout := fs.Duration("timeout", stepfunctions.Placeholders.TaskTimeout, "how long an agent task may run")
+	fs.Parse(args)
+
+	params := stepfunctions.Placeholders
+	if *format == "terraform" {
+		params = terraformRefs
+	}
+	params.TaskTimeout = *timeout
+	override(&params.ClusterArn, *cluster)
+	override(&params.TaskDefinition, *taskDefinition)
+	override(&params.ClaimFunction, *claimFunction)
+	override(&params.SecurityGroup, *securityGroup)
+	if *subnets != "" {
+		params.Subnets = strings.Split(*subnets, ",")
+	}
+	if params.TaskTimeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %s", params.TaskTimeout)
+	}
+
+	switch *format {
+	case "asl":
+		definition, err := marshalDefinition(params, "")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, definition)
+		return err
+	case "cloudformation":
+		return writeSnippet(w, cloudFormationSnippet, params, "            ")
+	case "terraform":
+		return writeSnippet(w, terraformSnippet, params, "    ")
+	case "cdk":
+		return writeSnippet(w, cdkSnippet, params, "  ")
+	default:
+		return fmt.Errorf("format must be asl, cloudformation, terraform, or cdk, got %q", *format)
+	}
+}
+
+func writeSnippet(w io.Writer, snippet string, params stepfunctions.Params, indent string) error {
+	definition, err := marshalDefinition(params, indent)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, snippet, definition)
+	return err
+}
+
+// marshalDefinition returns the definition as JSON, each line after the
+// first starting with indent
+func marshalDefinition(params stepfunctions.Params, indent string) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(indent, "  ")
+	if err := enc.Encode(stepfunctions.Definition(params)); err != nil {
+		return "", fmt.Errorf("marshal definition: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func override(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
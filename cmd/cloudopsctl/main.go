@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/control"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+)
+
+const usage = `Usage: cloudopsctl <command> [args]
+
+Commands:
+  state                  Print the running agent's state as JSON
+  pause                  Stop answering; messages wait until resumed
+  resume                 Answer again, including anything that waited
+  say [-user U] TEXT     Answer TEXT as if U (default: the requester) posted it
+  timeout MINUTES        End the conversation after MINUTES without a message
+  generate-sfn [flags]   Print the state machine definition the code expects;
+                         -format asl, cloudformation, terraform, or cdk
+
+Run the agent commands inside the agent's task, e.g. with
+  aws ecs execute-command --cluster C --task T --interactive --command "./cloudopsctl state"
+
+Environment: CONTROL_SOCKET (default /tmp/cloudops-agent.sock)
+`
+
+// cloudopsctl operates a running agent through its control API
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	if os.Args[1] == "generate-sfn" {
+		if err := generateSFN(os.Stdout, os.Args[2:]); err != nil {
+			logging.Fatal(ctx, "cloudopsctl failed", "error", err)
+		}
+		return
+	}
+
+	client, err := control.NewClient(getEnv("CONTROL_SOCKET", "/tmp/cloudops-agent.sock"))
+	if err != nil {
+		logging.Fatal(ctx, "failed to reach agent; is CONTROL_SOCKET set on it?", "error", err)
+	}
+
+	var state control.State
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "state":
+		state, err = client.State(ctx)
+	case "pause":
+		state, err = client.Pause(ctx, true)
+	case "resume":
+		state, err = client.Pause(ctx, false)
+	case "say":
+		fs := flag.NewFlagSet("say", flag.ExitOnError)
+		user := fs.String("user", "", "Slack user ID the message is from")
+		fs.Parse(args)
+		text := strings.Join(fs.Args(), " ")
+		if text == "" {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		state, err = client.Inject(ctx, *user, text)
+	case "timeout":
+		minutes := 0
+		if len(args) == 1 {
+			minutes, _ = strconv.Atoi(args[0])
+		}
+		if minutes <= 0 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		state, err = client.SetIdleTimeout(ctx, minutes)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logging.Fatal(ctx, "cloudopsctl failed", "error", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(state)
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/selfcost"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// Handler checks the bot's spend for the day that just closed against its
+// budgets, alerting when one is crossed
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.CostAlertChannel == "" {
+		return fmt.Errorf("COST_ALERT_CHANNEL is required")
+	}
+
+	// Cost Explorer days are UTC; check yesterday against the month it ends
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+	monthStart := time.Date(yesterday.Year(), yesterday.Month(), 1, 0, 0, 0, 0, time.UTC)
+	slog.InfoContext(ctx, "checking bot spend", "day", yesterday.Format(selfcost.DateLayout))
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	tagKey, tagValue := cfg.CostTagPair()
+	explorer := selfcost.NewExplorer(awsCfg, tagKey, tagValue, cfg.CostIncludeBedrock)
+	days, err := explorer.Days(ctx, monthStart, today)
+	if err != nil {
+		return fmt.Errorf("get spend: %w", err)
+	}
+
+	alerts := selfcost.Check(days, selfcost.Limits{Daily: cfg.CostDailyLimit, Monthly: cfg.CostMonthlyLimit})
+	if len(alerts) == 0 {
+		slog.InfoContext(ctx, "bot spend is within budget")
+		return nil
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+	if faults := cfg.FaultInjector(); faults != nil {
+		slackClient.SetFaultInjector(faults)
+	}
+
+	for _, alert := range alerts {
+		slog.WarnContext(ctx, "bot spend crossed budget", "period", alert.Period, "spend", alert.Spend, "limit", alert.Limit)
+		if _, err := slackClient.PostMessage(ctx, cfg.CostAlertChannel, slack.MsgOptionText(alert.Render(), false)); err != nil {
+			return fmt.Errorf("post %s budget alert: %w", alert.Period, err)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	logging.Setup("cost-monitor")
+	lambda.Start(Handler)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/savaki/cloudops-bot/pkg/agent"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+// localPrefix marks the run IDs of in-process agents, which are recorded
+// where an execution ARN would be
+const localPrefix = "local:"
+
+// launcher runs each conversation's agent in a goroutine in place of an
+// ECS task
+type launcher struct {
+	ctx         context.Context // the server's; agents outlive the request that started them
+	cfg         *appconfig.Config
+	toolsCfg    aws.Config
+	convRepo    storage.Store
+	slackClient *slackclient.Client
+	bedrock     *bedrock.Client
+	wg          sync.WaitGroup
+}
+
+func newLauncher(ctx context.Context, cfg *appconfig.Config, toolsCfg aws.Config, convRepo storage.Store, slackClient *slackclient.Client, bedrockClient *bedrock.Client) *launcher {
+	return &launcher{
+		ctx:         ctx,
+		cfg:         cfg,
+		toolsCfg:    toolsCfg,
+		convRepo:    convRepo,
+		slackClient: slackClient,
+		bedrock:     bedrockClient,
+	}
+}
+
+// StartConversation starts the conversation's agent. The launch's task
+// size and context pack are for ECS and ignored
+func (l *launcher) StartConversation(_ context.Context, _ string, conversation *models.Conversation, _ stepfunctions.Launch) (string, error) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.run(logging.With(l.ctx, logging.ConversationID, conversation.ConversationID), conversation.ConversationID)
+	}()
+	return localPrefix + conversation.ConversationID, nil
+}
+
+// StartShadow fails: shadow agents run a candidate task definition, which
+// only Step Functions can launch
+func (l *launcher) StartShadow(context.Context, string, *models.Conversation, stepfunctions.Launch) (string, error) {
+	return "", errors.New("shadow agents aren't run in-process")
+}
+
+// Wait blocks until every agent has stopped
+func (l *launcher) Wait() {
+	l.wg.Wait()
+}
+
+// run answers a conversation until it goes idle or the server stops
+func (l *launcher) run(ctx context.Context, conversationID string) {
+	// Read the latest copy; the starter may have moved the conversation
+	conversation, err := l.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get conversation", "error", err)
+		return
+	}
+
+	slog.InfoContext(ctx, "starting agent")
+	a := agent.New(l.cfg, conversation, l.convRepo, l.slackClient, l.bedrock)
+	a.SetChartRenderer(charts.NewRenderer(l.toolsCfg))
+
+	err = a.Run(ctx)
+	switch {
+	case err == nil:
+		slog.InfoContext(ctx, "agent completed")
+	case ctx.Err() != nil:
+		// The server is stopping; end the conversation like a stopped task
+		if err := a.Shutdown(context.WithoutCancel(ctx)); err != nil {
+			slog.ErrorContext(ctx, "failed to end conversation on shutdown", "error", err)
+		}
+	default:
+		slog.ErrorContext(ctx, "agent failed", "error", err)
+		ctx = context.WithoutCancel(ctx)
+		if err := l.convRepo.UpdateStatus(ctx, conversationID, models.StatusFailed); err != nil {
+			slog.ErrorContext(ctx, "failed to mark conversation failed", "error", err)
+		}
+		conversation.UpdateStatus(models.StatusFailed)
+		lifecycle.Mark(ctx, l.slackClient, conversation, lifecycle.ForStatus(models.StatusFailed))
+	}
+}
@@ -0,0 +1,244 @@
+// Command devserver runs the Slack handler as a plain HTTP server, so the
+// bot can be tried from a laptop behind a tunnel such as ngrok instead of
+// through API Gateway and Lambda. With -agent it also runs each
+// conversation's agent in-process rather than launching an ECS task
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/intake"
+	"github.com/savaki/cloudops-bot/pkg/interactions"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/savaki/cloudops-bot/pkg/storage/memory"
+	cwtool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatch"
+	logstool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatchlogs"
+	ec2tool "github.com/savaki/cloudops-bot/pkg/tools/ec2"
+	ecstool "github.com/savaki/cloudops-bot/pkg/tools/ecs"
+)
+
+// slashCommandReply answers slash commands, which only the deployed Slack
+// handler serves
+const slashCommandReply = "Slash commands aren't served by the dev server. Mention the bot instead, or try them against a deployed stack."
+
+// server answers Slack's events and interactions
+type server struct {
+	cfg          *appconfig.Config
+	starter      *intake.Starter
+	interactions *handler.InteractionHandler
+}
+
+func main() {
+	addr := flag.String("addr", ":3000", "address to listen on; point a tunnel such as ngrok at it")
+	inProcess := flag.Bool("agent", false, "run agents in this process instead of launching them through Step Functions")
+	flag.Parse()
+
+	logging.Setup("devserver")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		logging.Fatal(ctx, "failed to load config", "error", err)
+	}
+	if err := cfg.ValidateDevServer(*inProcess); err != nil {
+		logging.Fatal(ctx, "invalid devserver config", "error", err)
+	}
+
+	// AWS_ENDPOINT_URL points DynamoDB at DynamoDB Local
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		logging.Fatal(ctx, "failed to load AWS config", "error", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	var convRepo storage.Store
+	if cfg.InMemory() {
+		slog.InfoContext(ctx, "in-memory storage: conversations are lost when the server exits")
+		convRepo = memory.New()
+	} else {
+		repo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+		repo.SetHistoryTable(cfg.ConversationHistoryTable)
+		repo.SetFaultInjector(cfg.FaultInjector())
+		convRepo = repo
+	}
+
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() && !cfg.InMemory() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			logging.Fatal(ctx, "failed to get slack bot token", "error", err)
+		}
+		go rotator.Run(ctx, slackClient)
+	}
+	if faults := cfg.FaultInjector(); faults != nil {
+		slog.WarnContext(ctx, "fault injection enabled", "latency_ms", cfg.ChaosLatencyMs, "error_rate", cfg.ChaosErrorRate)
+		slackClient.SetFaultInjector(faults)
+	}
+
+	starter := intake.NewStarter(cfg, awsCfg, ddbClient, slackClient)
+	starter.SetStore(convRepo)
+
+	var agents *launcher
+	if *inProcess {
+		bedrockClient := bedrock.NewClient(awsCfg)
+		bedrockClient.SetModel(cfg.BedrockModelID)
+
+		// In sandbox mode the tools read the demo account instead
+		toolsCfg := awsCfg
+		if cfg.Sandbox() {
+			slog.InfoContext(ctx, "sandbox mode: tools use the sandbox role", "role_arn", cfg.SandboxRoleARN)
+			toolsCfg = sandbox.Config(awsCfg, cfg.SandboxRoleARN, cfg.SandboxExternalID)
+		}
+		bedrockClient.RegisterTool(ec2tool.New(toolsCfg))
+		bedrockClient.RegisterTool(ecstool.New(toolsCfg))
+		bedrockClient.RegisterTool(logstool.New(toolsCfg))
+		bedrockClient.RegisterTool(cwtool.New(toolsCfg))
+		bedrockClient.SetDiagnoser(diagnose.New(toolsCfg))
+
+		agents = newLauncher(ctx, cfg, toolsCfg, convRepo, slackClient, bedrockClient)
+		starter.SetLauncher(agents)
+		slog.InfoContext(ctx, "agents run in-process")
+	}
+
+	// Buttons and modals need the tables behind them; with in-memory
+	// storage only the message shortcut, which starts conversations, works
+	ih := handler.NewInteractionHandler(cfg.SigningKeys()...)
+	if cfg.InMemory() {
+		ih.OnShortcut(intake.ShortcutCallbackID, starter.Shortcut)
+	} else {
+		h := interactions.New(cfg, awsCfg, ddbClient, slackClient)
+		h.SetStarter(starter)
+		h.Register(ih)
+	}
+
+	s := &server{cfg: cfg, starter: starter, interactions: ih}
+	httpServer := &http.Server{Addr: *addr, Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.InfoContext(ctx, "serving slack requests", "addr", *addr,
+		"hint", "run `ngrok http "+strings.TrimPrefix(*addr, ":")+"` and set the app's event and interactivity request URLs to https://<tunnel>/slack/events and https://<tunnel>/slack/interactions")
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.Fatal(ctx, "server failed", "error", err)
+	}
+
+	if agents != nil {
+		agents.Wait()
+	}
+	slog.InfoContext(ctx, "devserver stopped")
+}
+
+// Handler returns the server's routes:
+//
+//	POST /slack/events        events, and interactions for apps set up before the dedicated endpoint
+//	POST /slack/interactions  button clicks, modal submissions, and shortcuts
+//	GET  /healthz             liveness, for tunnels that probe
+func (s *server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /slack/events", s.serve)
+	mux.HandleFunc("POST /slack/interactions", s.serve)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// serve adapts an HTTP request to the handler the Lambda entrypoints share
+func (s *server) serve(w http.ResponseWriter, r *http.Request) {
+	request, err := handler.ReadRequest(r)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to read request", "error", err)
+		handler.WriteResponse(w, handler.ErrorResponse(400, handler.CodeInvalidRequest, "Invalid request"))
+		return
+	}
+	handler.WriteResponse(w, s.handle(r.Context(), request))
+}
+
+// handle processes a Slack request like the Slack handler Lambda, without
+// its access policy: a tunnel hides the caller's address, and the
+// signature still has to match
+func (s *server) handle(ctx context.Context, request *handler.Request) *handler.Response {
+	if !handler.ValidateSlackRequest(
+		[]byte(request.Body),
+		request.Header("X-Slack-Request-Timestamp"),
+		request.Header("X-Slack-Signature"),
+		s.cfg.SigningKeys()...,
+	) {
+		slog.WarnContext(ctx, "invalid slack signature")
+		return handler.ErrorResponse(401, handler.CodeInvalidSignature, "Invalid signature")
+	}
+
+	// Slack retries after 3s without a response, but the original delivery is
+	// usually still running; processing the retry would duplicate its work
+	if request.Header("X-Slack-Retry-Reason") == "http_timeout" {
+		slog.InfoContext(ctx, "acknowledging slack retry after timeout without reprocessing", "retry_num", request.Header("X-Slack-Retry-Num"))
+		resp := okResponse(map[string]bool{"ok": true})
+		resp.Headers[handler.NoRetryHeader] = "1"
+		return resp
+	}
+
+	if handler.IsInteraction(request) {
+		return s.interactions.Dispatch(ctx, request.Body)
+	}
+	if strings.HasPrefix(request.Header("Content-Type"), "application/x-www-form-urlencoded") && strings.Contains(request.Body, "command=") {
+		return okResponse(map[string]string{"response_type": "ephemeral", "text": slashCommandReply})
+	}
+
+	var slackEvent models.SlackEventCallback
+	if err := json.Unmarshal([]byte(request.Body), &slackEvent); err != nil {
+		slog.ErrorContext(ctx, "failed to parse slack event", "error", err)
+		return handler.ErrorResponse(400, handler.CodeInvalidRequest, "Invalid event format")
+	}
+
+	ctx = logging.With(ctx, logging.EventID, slackEvent.EventID, logging.UserID, slackEvent.Event.User, logging.ChannelID, slackEvent.Event.Channel)
+
+	if slackEvent.Type == "url_verification" {
+		slog.InfoContext(ctx, "responding to slack URL verification challenge")
+		return okResponse(map[string]string{"challenge": slackEvent.Challenge})
+	}
+
+	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "app_mention" {
+		slog.InfoContext(ctx, "handling app mention")
+		if err := s.starter.Start(ctx, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to process mention", "error", err)
+			return handler.ErrorResponse(500, handler.CodeInternal, "Failed to process mention")
+		}
+		return okResponse(map[string]bool{"ok": true})
+	}
+
+	slog.InfoContext(ctx, "ignoring event the dev server doesn't handle", "type", slackEvent.Type, "event_type", slackEvent.Event.Type)
+	return okResponse(map[string]bool{"ok": true})
+}
+
+func okResponse(body interface{}) *handler.Response {
+	data, _ := json.Marshal(body)
+	return &handler.Response{
+		StatusCode: 200,
+		Body:       string(data),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/savaki/cloudops-bot/pkg/agent"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/eval"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/prompts"
+)
+
+// eval replays a corpus of recorded conversations against the current prompt
+// and model, grades the answers with a judge model, and writes a scorecard.
+// With -baseline it exits non-zero when scores regress, so prompt or model
+// changes can be checked before deploy
+func main() {
+	corpusPath := flag.String("corpus", "eval/corpus.jsonl", "JSON Lines file of cases to replay")
+	outPath := flag.String("out", "", "write the scorecard JSON to this file")
+	baselinePath := flag.String("baseline", "", "scorecard JSON to compare against")
+	label := flag.String("label", "", "name for this run (default: model and prompt hash)")
+	model := flag.String("model", getEnv("BEDROCK_MODEL_ID", bedrock.DefaultModelID), "Bedrock model answering the cases")
+	judgeModel := flag.String("judge-model", "", "Bedrock model grading the answers (default: -model)")
+	tolerance := flag.Float64("tolerance", 0.25, "largest average score drop that isn't a regression")
+	charts := flag.Bool("charts", true, "include chart instructions in the system prompt")
+	promptFlag := flag.String("prompt", "builtin", "system prompt: builtin, latest, or a published version number (reads PROMPTS_TABLE)")
+	flag.Parse()
+
+	if *judgeModel == "" {
+		*judgeModel = *model
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *corpusPath, *outPath, *baselinePath, *label, *model, *judgeModel, *promptFlag, *tolerance, *charts); err != nil {
+		logging.Fatal(ctx, "eval failed", "error", err)
+	}
+}
+
+func run(ctx context.Context, corpusPath, outPath, baselinePath, label, model, judgeModel, promptFlag string, tolerance float64, charts bool) error {
+	f, err := os.Open(corpusPath)
+	if err != nil {
+		return fmt.Errorf("open corpus: %w", err)
+	}
+	cases, err := eval.LoadCorpus(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("load corpus: %w", err)
+	}
+
+	var baseline *eval.Scorecard
+	if baselinePath != "" {
+		baseline = &eval.Scorecard{}
+		if err := readJSON(baselinePath, baseline); err != nil {
+			return fmt.Errorf("load baseline: %w", err)
+		}
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	answerer := bedrock.NewClient(awsCfg)
+	answerer.SetModel(model)
+	judge := bedrock.NewClient(awsCfg)
+	judge.SetModel(judgeModel)
+
+	template, err := loadPrompt(ctx, awsCfg, promptFlag)
+	if err != nil {
+		return fmt.Errorf("load prompt: %w", err)
+	}
+
+	prompt := agent.SystemPrompt(template.Template, charts)
+	if label == "" {
+		label = fmt.Sprintf("%s@%s", model, models.PromptHash(prompt))
+	}
+
+	slog.InfoContext(ctx, "replaying cases", "cases", len(cases), "model", model, "prompt", prompts.Label(template), "judge_model", judgeModel)
+	runner := eval.NewRunner(answerer, judge, prompt)
+	runner.SetCleaner(agent.CleanResponse)
+	results := runner.Run(ctx, cases)
+
+	scorecard := eval.NewScorecard(label, model, judgeModel, prompt, results, time.Now())
+	if outPath != "" {
+		if err := writeJSON(outPath, scorecard); err != nil {
+			return fmt.Errorf("write scorecard: %w", err)
+		}
+		slog.InfoContext(ctx, "scorecard written", "path", outPath)
+	}
+
+	var regressions []eval.Regression
+	if baseline != nil {
+		regressions = eval.Compare(baseline, scorecard, tolerance)
+	}
+	fmt.Print(scorecard.Markdown(baseline, regressions))
+
+	if len(regressions) > 0 {
+		return fmt.Errorf("%d regression(s) against %s", len(regressions), baseline.Label)
+	}
+	return nil
+}
+
+// loadPrompt returns the built-in prompt or a version from the prompts table
+func loadPrompt(ctx context.Context, awsCfg aws.Config, flagValue string) (*models.PromptTemplate, error) {
+	version := models.BuiltinPromptVersion
+	switch flagValue {
+	case "builtin":
+		return prompts.Builtin(), nil
+	case "latest":
+	default:
+		v, err := strconv.Atoi(flagValue)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("invalid -prompt %q", flagValue)
+		}
+		version = v
+	}
+
+	repo := dynamodb.NewPromptRepository(dynamodb.NewClientWithConfig(awsCfg), getEnv("PROMPTS_TABLE", "cloudops-prompts"))
+	return prompts.Resolve(ctx, repo, version)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/evidence"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+const usage = `Usage: export [flags]
+
+Packages audit events, conversation transcripts, and approval requests for a
+time range into a signed archive with a hash chain manifest, for compliance
+evidence requests.
+
+  export -from 2024-01-01 -to 2024-03-31 [-out FILE] [-bucket B]
+  export -tools -from 2024-01-01 -to 2024-03-31 [-user U012ABCDEF] [-out FILE]
+  export -verify FILE [-public-key KEY]
+  export -keygen
+
+Dates are inclusive days in UTC, or RFC 3339 timestamps. -tools lists the
+tool executions in the range as JSON lines, only those made for -user when
+given, instead of packaging an archive. Archives include them too when
+TOOL_AUDIT_TABLE is set.
+
+Environment: EXPORT_SIGNING_KEY (base64 Ed25519 seed from -keygen),
+EVIDENCE_BUCKET, CONVERSATIONS_TABLE, CONVERSATION_HISTORY_TABLE,
+AUDIT_TABLE, TOOL_AUDIT_TABLE, APPROVALS_TABLE, USER (recorded as the
+exporter)
+`
+
+// conversationStatuses are every status a conversation can be in
+var conversationStatuses = []string{models.StatusPending, models.StatusActive, models.StatusCompleted, models.StatusFailed, models.StatusTimeout}
+
+// transcript is a conversation record with its full message history
+type transcript struct {
+	Conversation *models.Conversation             `json:"conversation"`
+	History      []models.ConversationHistoryItem `json:"history"`
+}
+
+// export builds tamper-evident compliance archives
+func main() {
+	from := flag.String("from", "", "start of the range (YYYY-MM-DD or RFC 3339)")
+	to := flag.String("to", "", "end of the range, inclusive for dates (YYYY-MM-DD or RFC 3339)")
+	out := flag.String("out", "", "also write the archive to this file")
+	bucket := flag.String("bucket", os.Getenv("EVIDENCE_BUCKET"), "S3 bucket for the archive")
+	verify := flag.String("verify", "", "verify an archive instead of creating one")
+	publicKey := flag.String("public-key", "", "base64 public key the archive must be signed with (for -verify)")
+	keygen := flag.Bool("keygen", false, "generate a signing key")
+	tools := flag.Bool("tools", false, "list tool executions instead of creating an archive")
+	user := flag.String("user", "", "only list tool executions made for this Slack user ID (for -tools)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	var err error
+	switch {
+	case *keygen:
+		err = generateKey()
+	case *verify != "":
+		err = verifyArchive(*verify, *publicKey)
+	case *tools && *from != "" && *to != "":
+		err = listTools(context.Background(), *from, *to, *user, *out)
+	case *from != "" && *to != "":
+		err = export(context.Background(), *from, *to, *out, *bucket)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logging.Fatal(context.Background(), "export failed", "error", err)
+	}
+}
+
+func export(ctx context.Context, fromText, toText, out, bucket string) error {
+	start, err := parseTime(fromText, false)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(toText, true)
+	if err != nil {
+		return err
+	}
+	if !end.After(start) {
+		return fmt.Errorf("-to must be after -from")
+	}
+	if out == "" && bucket == "" {
+		return fmt.Errorf("set -out or -bucket (EVIDENCE_BUCKET)")
+	}
+
+	key, err := evidence.ParsePrivateKey(os.Getenv("EXPORT_SIGNING_KEY"))
+	if err != nil {
+		return fmt.Errorf("EXPORT_SIGNING_KEY: %w", err)
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	convRepo := dynamodb.NewConversationRepository(ddbClient, getEnv("CONVERSATIONS_TABLE", "cloudops-conversations"))
+	convRepo.SetHistoryTable(getEnv("CONVERSATION_HISTORY_TABLE", "cloudops-conversation-history"))
+	auditRepo := dynamodb.NewAuditRepository(ddbClient, getEnv("AUDIT_TABLE", "cloudops-audit"))
+	auditRepo.SetToolTable(os.Getenv("TOOL_AUDIT_TABLE"))
+	approvalRepo := dynamodb.NewApprovalRepository(ddbClient, getEnv("APPROVALS_TABLE", "cloudops-approvals"))
+
+	exporter := getEnv("USER", "unknown")
+	archive := evidence.New(start, end, exporter, time.Now())
+
+	events, err := auditRepo.ListBetween(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	if err := evidence.AddJSONLines(archive, "audit.jsonl", events); err != nil {
+		return err
+	}
+
+	var executions []*models.ToolExecution
+	if os.Getenv("TOOL_AUDIT_TABLE") != "" {
+		executions, err = auditRepo.ListToolExecutions(ctx, "", start, end)
+		if err != nil {
+			return err
+		}
+		sort.Slice(executions, func(i, j int) bool { return executions[i].StartedAt.Before(executions[j].StartedAt) })
+		if err := evidence.AddJSONLines(archive, "tools.jsonl", executions); err != nil {
+			return err
+		}
+	}
+
+	approvals, err := approvalRepo.ListBetween(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].CreatedAt.Before(approvals[j].CreatedAt) })
+	if err := evidence.AddJSONLines(archive, "approvals.jsonl", approvals); err != nil {
+		return err
+	}
+
+	conversations, err := conversationsBetween(ctx, convRepo, start, end)
+	if err != nil {
+		return err
+	}
+	for _, conv := range conversations {
+		history, err := convRepo.GetHistoryItems(ctx, conv.ConversationID)
+		if err != nil {
+			return fmt.Errorf("load history for %s: %w", conv.ConversationID, err)
+		}
+		data, err := json.MarshalIndent(transcript{Conversation: conv, History: history}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", conv.ConversationID, err)
+		}
+		archive.Add("conversations/"+conv.ConversationID+".json", data)
+	}
+
+	if err := archive.Sign(key); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := archive.Write(&buf); err != nil {
+		return err
+	}
+	manifest := archive.Manifest()
+	slog.InfoContext(ctx, "packaged evidence", "audit_events", len(events), "tool_executions", len(executions), "approvals", len(approvals), "transcripts", len(conversations), "root", manifest.Root)
+
+	if out != "" {
+		if err := os.WriteFile(out, buf.Bytes(), 0o444); err != nil {
+			return fmt.Errorf("write archive: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", out)
+	}
+
+	location := out
+	if bucket != "" {
+		objectKey := fmt.Sprintf("evidence/%s_%s/%s.tar.gz",
+			start.Format("20060102T150405Z"), end.Format("20060102T150405Z"), manifest.CreatedAt.Format("20060102T150405Z"))
+		_, err := s3.NewFromConfig(awsCfg).PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(bucket),
+			Key:         aws.String(objectKey),
+			Body:        bytes.NewReader(buf.Bytes()),
+			ContentType: aws.String("application/gzip"),
+			Metadata:    map[string]string{"manifest-root": manifest.Root},
+		})
+		if err != nil {
+			return fmt.Errorf("upload archive: %w", err)
+		}
+		location = fmt.Sprintf("s3://%s/%s", bucket, objectKey)
+		fmt.Printf("Uploaded %s\n", location)
+	}
+
+	event := models.NewAuditEvent(models.AuditEvidence, exporter, location)
+	event.Details["start"] = start.Format(time.RFC3339)
+	event.Details["end"] = end.Format(time.RFC3339)
+	event.Details["root"] = manifest.Root
+	event.Details["public_key"] = manifest.PublicKey
+	if err := auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to record audit event", "error", err)
+	}
+	return nil
+}
+
+// listTools writes the tool executions in a range as JSON lines, oldest
+// first, to out or stdout
+func listTools(ctx context.Context, fromText, toText, userID, out string) error {
+	start, err := parseTime(fromText, false)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(toText, true)
+	if err != nil {
+		return err
+	}
+	if !end.After(start) {
+		return fmt.Errorf("-to must be after -from")
+	}
+	table := os.Getenv("TOOL_AUDIT_TABLE")
+	if table == "" {
+		return fmt.Errorf("set TOOL_AUDIT_TABLE")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+	auditRepo := dynamodb.NewAuditRepository(dynamodb.NewClientWithConfig(awsCfg), getEnv("AUDIT_TABLE", "cloudops-audit"))
+	auditRepo.SetToolTable(table)
+
+	executions, err := auditRepo.ListToolExecutions(ctx, userID, start, end)
+	if err != nil {
+		return err
+	}
+	sort.Slice(executions, func(i, j int) bool { return executions[i].StartedAt.Before(executions[j].StartedAt) })
+
+	w := os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	for _, e := range executions {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("write tool executions: %w", err)
+		}
+	}
+	slog.InfoContext(ctx, "listed tool executions", "tool_executions", len(executions))
+	return nil
+}
+
+// conversationsBetween returns conversations created in [start, end)
+func conversationsBetween(ctx context.Context, repo *dynamodb.ConversationRepository, start, end time.Time) ([]*models.Conversation, error) {
+	var out []*models.Conversation
+	for _, status := range conversationStatuses {
+		convs, err := repo.GetByStatusSince(ctx, status, start)
+		if err != nil {
+			return nil, fmt.Errorf("list %s conversations: %w", status, err)
+		}
+		for _, c := range convs {
+			if c.CreatedAt.Before(end) {
+				out = append(out, c)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func verifyArchive(path, publicKey string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var trusted []byte
+	if publicKey != "" {
+		key, err := evidence.ParsePublicKey(publicKey)
+		if err != nil {
+			return err
+		}
+		trusted = key
+	}
+
+	m, err := evidence.Verify(f, trusted)
+	if err != nil {
+		return fmt.Errorf("%s failed verification: %w", path, err)
+	}
+
+	fmt.Printf("%s verified: %d files from %s to %s, exported by %s at %s\nroot %s\n",
+		path, len(m.Entries), m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339),
+		m.CreatedBy, m.CreatedAt.Format(time.RFC3339), m.Root)
+	if publicKey == "" {
+		fmt.Println("Signed by the embedded key; pass -public-key to confirm who signed it.")
+	}
+	return nil
+}
+
+func generateKey() error {
+	seed, public, err := evidence.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("EXPORT_SIGNING_KEY=%s\npublic key: %s\n", seed, public)
+	fmt.Fprintln(os.Stderr, "Store the signing key as a secret; share the public key with auditors.")
+	return nil
+}
+
+// parseTime reads a date or RFC 3339 timestamp. An end date covers the whole day
+func parseTime(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want YYYY-MM-DD or RFC 3339", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/handoff"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+	"github.com/slack-go/slack"
+)
+
+// lookback is how far before the shift to search for conversations that
+// were still running when it started
+const lookback = 7 * 24 * time.Hour
+
+// Handler posts a shift handoff report when triggered by the shift-change schedule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	slog.InfoContext(ctx, "generating shift handoff report")
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.HandoffChannel == "" {
+		return fmt.Errorf("HANDOFF_CHANNEL is required")
+	}
+
+	loc, err := time.LoadLocation(cfg.HandoffTimezone)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid HANDOFF_TIMEZONE, using UTC", "timezone", cfg.HandoffTimezone, "error", err)
+		loc = time.UTC
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+	}
+
+	end := time.Now()
+	window := timerange.Range{Start: end.Add(-time.Duration(cfg.HandoffShiftHours) * time.Hour), End: end}
+
+	var conversations []*models.Conversation
+	for _, status := range []string{models.StatusPending, models.StatusActive, models.StatusCompleted, models.StatusFailed, models.StatusTimeout} {
+		convs, err := convRepo.GetByStatusSince(ctx, status, window.Start.Add(-lookback))
+		if err != nil {
+			return fmt.Errorf("list %s conversations: %w", status, err)
+		}
+		conversations = append(conversations, convs...)
+	}
+
+	report := handoff.Build(conversations, window)
+	slog.InfoContext(ctx, "built handoff report", "open", len(report.Open), "resolved", len(report.Resolved), "failed", len(report.Failed))
+
+	if _, err := slackClient.PostMessage(ctx, cfg.HandoffChannel, slack.MsgOptionText(report.Render(loc), false)); err != nil {
+		return fmt.Errorf("post handoff: %w", err)
+	}
+
+	return nil
+}
+
+func main() {
+	logging.Setup("handoff")
+	lambda.Start(Handler)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	logstool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatchlogs"
+	ec2tool "github.com/savaki/cloudops-bot/pkg/tools/ec2"
+)
+
+// Handler runs background jobs as they are queued. It is triggered by the
+// jobs table's stream, filtered to new items, one job per invocation; the
+// job stops in time to record how it ended before the Lambda times out
+func Handler(ctx context.Context, event events.DynamoDBEvent) error {
+	slog.InfoContext(ctx, "received job records", "records", len(event.Records))
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if cfg.JobsTable == "" {
+		return fmt.Errorf("JOBS_TABLE is required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	jobRepo := dynamodb.NewJobRepository(ddbClient, cfg.JobsTable)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		jobRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+	}
+
+	toolsCfg := awsCfg
+	if cfg.Sandbox() {
+		toolsCfg = sandbox.Config(awsCfg, cfg.SandboxRoleARN, cfg.SandboxExternalID)
+	}
+
+	manager := jobs.NewManager(jobRepo, slackClient)
+	manager.Register(logstool.New(toolsCfg).ScanJob())
+	manager.Register(ec2tool.SweepJob(toolsCfg))
+
+	for _, record := range event.Records {
+		if record.EventName != string(events.DynamoDBOperationTypeInsert) {
+			continue
+		}
+		jobID := record.Change.Keys["job_id"].String()
+		if err := manager.Run(ctx, jobID); err != nil {
+			return fmt.Errorf("run job %s: %w", jobID, err)
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	logging.Setup("job-worker")
+	lambda.Start(Handler)
+}
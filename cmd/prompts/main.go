@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/prompts"
+)
+
+const usage = `Usage: prompts <command> [flags]
+
+Commands:
+  list                         List published system prompt versions, newest first
+  show [version]               Print a version's template (default: latest)
+  publish -file F [-note N]    Publish F as the next version
+
+Roll back by setting PROMPT_VERSION to an earlier version on the agent;
+new conversations pick it up immediately.
+
+Environment: PROMPTS_TABLE (default cloudops-prompts), AUDIT_TABLE
+(default cloudops-audit), USER (recorded as the author)
+`
+
+// prompts manages the versioned system prompt templates agents answer with
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		logging.Fatal(ctx, "failed to load AWS config", "error", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	promptRepo := dynamodb.NewPromptRepository(ddbClient, getEnv("PROMPTS_TABLE", "cloudops-prompts"))
+	auditRepo := dynamodb.NewAuditRepository(ddbClient, getEnv("AUDIT_TABLE", "cloudops-audit"))
+
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "list":
+		err = list(ctx, promptRepo)
+	case "show":
+		err = show(ctx, promptRepo, args)
+	case "publish":
+		err = publish(ctx, promptRepo, auditRepo, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logging.Fatal(ctx, "prompts failed", "error", err)
+	}
+}
+
+func list(ctx context.Context, repo *dynamodb.PromptRepository) error {
+	versions, err := repo.List(ctx, models.SystemPromptName, 50)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tHASH\tCREATED\tAUTHOR\tNOTE")
+	for _, p := range versions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", p.Version, p.Hash, p.CreatedAt.UTC().Format("2006-01-02 15:04"), p.Author, p.Note)
+	}
+	builtin := prompts.Builtin()
+	fmt.Fprintf(w, "%d\t%s\t-\t-\t%s\n", builtin.Version, builtin.Hash, builtin.Note)
+	return w.Flush()
+}
+
+func show(ctx context.Context, repo *dynamodb.PromptRepository, args []string) error {
+	version := models.BuiltinPromptVersion
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		version = v
+	}
+
+	var prompt *models.PromptTemplate
+	var err error
+	if len(args) > 0 && version == models.BuiltinPromptVersion {
+		prompt = prompts.Builtin()
+	} else {
+		prompt, err = prompts.Resolve(ctx, repo, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "# %s\n", prompts.Label(prompt))
+	fmt.Println(prompt.Template)
+	return nil
+}
+
+func publish(ctx context.Context, repo *dynamodb.PromptRepository, auditRepo *dynamodb.AuditRepository, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	file := fs.String("file", "", "file containing the prompt template")
+	note := fs.String("note", "", "what changed in this version")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("read template: %w", err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("template %s is empty", *file)
+	}
+
+	prompt := models.NewPromptTemplate(models.SystemPromptName, string(data), os.Getenv("USER"), *note)
+	if latest, err := repo.Latest(ctx, prompt.Name); err == nil && latest != nil && latest.Hash == prompt.Hash {
+		return fmt.Errorf("template is unchanged from version %d", latest.Version)
+	}
+	if err := repo.Publish(ctx, prompt); err != nil {
+		return fmt.Errorf("publish prompt: %w", err)
+	}
+
+	event := models.NewAuditEvent(models.AuditPromptPublish, prompt.Author, prompt.Name)
+	event.Details["version"] = strconv.Itoa(prompt.Version)
+	event.Details["hash"] = prompt.Hash
+	if prompt.Note != "" {
+		event.Details["note"] = prompt.Note
+	}
+	if err := auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to record audit event", "error", err)
+	}
+
+	fmt.Printf("Published %s. New conversations use it unless PROMPT_VERSION pins another version.\n", prompts.Label(prompt))
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
+	"github.com/savaki/cloudops-bot/pkg/watch"
+	"github.com/slack-go/slack"
+)
+
+const timeoutNotice = "⏱️ This session timed out because its agent stopped responding. Mention me again to start a new one."
+
+// Handler times out conversations whose agent stopped recording heartbeats
+// without ending them, say because its task crashed or ran out of memory.
+// Each is marked timed out, its execution and task are stopped, and its
+// channel is told. It runs every minute on an EventBridge schedule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	timeout := cfg.GetStaleHeartbeat()
+	if timeout <= 0 || cfg.GetHeartbeatInterval() <= 0 {
+		return nil
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	subRepo := dynamodb.NewSubscriptionRepository(ddbClient, cfg.SubscriptionsTable)
+	sfnClient := stepfunctions.NewClient(awsCfg)
+	ecsClient := ecs.NewFromConfig(awsCfg)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+	}
+	notifier := watch.NewNotifier(subRepo, slackClient)
+
+	now := time.Now()
+	reaped := 0
+	for _, status := range []string{models.StatusPending, models.StatusActive} {
+		convs, err := convRepo.GetByStatus(ctx, status)
+		if err != nil {
+			return fmt.Errorf("list %s conversations: %w", status, err)
+		}
+
+		for _, conv := range convs {
+			if !conv.Stale(now, timeout) {
+				continue
+			}
+			ctx := logging.With(ctx, logging.ConversationID, conv.ConversationID, logging.ChannelID, conv.ChannelID)
+
+			// The agent may have recovered since the conversation was listed
+			ok, err := convRepo.TimeOut(ctx, conv.ConversationID, now.Add(-timeout))
+			if err != nil {
+				slog.WarnContext(ctx, "failed to time out conversation", "error", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			reaped++
+			slog.InfoContext(ctx, "timed out conversation", "last_heartbeat", conv.LastHeartbeat, "status", conv.Status)
+
+			cause := fmt.Sprintf("no heartbeat since %s", conv.LastHeartbeat.Format(time.RFC3339))
+			if conv.ExecutionArn != "" {
+				if err := sfnClient.StopExecution(ctx, conv.ExecutionArn, cause); err != nil {
+					slog.WarnContext(ctx, "failed to stop execution", "execution_arn", conv.ExecutionArn, "error", err)
+				}
+			}
+			// Warm agents run outside any execution, so stop the task itself
+			if conv.TaskArn != "" {
+				if err := stopTask(ctx, ecsClient, conv.TaskArn, cause); err != nil {
+					slog.WarnContext(ctx, "failed to stop task", "task_arn", conv.TaskArn, "error", err)
+				}
+			}
+
+			if _, err := slackClient.PostMessage(ctx, conv.ChannelID, slack.MsgOptionText(timeoutNotice, false), slackclient.InThread(conv.ThreadTS)); err != nil {
+				slog.WarnContext(ctx, "failed to post timeout notice", "error", err)
+			}
+			notifier.StatusChanged(ctx, conv, models.StatusTimeout)
+			lifecycle.Mark(ctx, slackClient, conv, lifecycle.ForStatus(models.StatusTimeout))
+		}
+	}
+
+	if reaped > 0 {
+		slog.InfoContext(ctx, "timed out stale conversations", "conversations", reaped)
+	}
+	return nil
+}
+
+// stopTask stops an ECS task. Task ARNs name their cluster, as in
+// arn:aws:ecs:region:account:task/cluster/id
+func stopTask(ctx context.Context, client *ecs.Client, taskArn, reason string) error {
+	input := &ecs.StopTaskInput{
+		Task:   aws.String(taskArn),
+		Reason: aws.String(reason),
+	}
+	if parts := strings.Split(taskArn, "/"); len(parts) == 3 {
+		input.Cluster = aws.String(parts[1])
+	}
+	if _, err := client.StopTask(ctx, input); err != nil {
+		return fmt.Errorf("stop task: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	logging.Setup("reaper")
+	lambda.Start(Handler)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/sla"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// Handler posts escalating SLA reminders for open incidents. It runs every
+// minute on an EventBridge schedule
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return fmt.Errorf("get slack token: %w", err)
+		}
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+	}
+
+	// Incidents can outlive the bot conversation, so look at every status
+	// back to the longest resolution target (plus a day for breached ones)
+	now := time.Now()
+	since := now.Add(-cfg.SLAPolicies().Longest() - 24*time.Hour)
+
+	sent := 0
+	for _, status := range []string{models.StatusPending, models.StatusActive, models.StatusCompleted, models.StatusFailed, models.StatusTimeout} {
+		convs, err := convRepo.GetByStatusSince(ctx, status, since)
+		if err != nil {
+			return fmt.Errorf("list %s conversations: %w", status, err)
+		}
+
+		for _, conv := range convs {
+			reminders := sla.Due(conv.SLA, now)
+			if len(reminders) == 0 {
+				continue
+			}
+
+			for _, r := range reminders {
+				_, err := slackClient.PostMessage(ctx, conv.ChannelID, slack.MsgOptionText(r.Message(conv.SLA, now), false), slackclient.InThread(conv.ThreadTS))
+				if err != nil {
+					slog.WarnContext(ctx, "failed to post SLA reminder", "reminder", r.Key(), logging.ConversationID, conv.ConversationID, "error", err)
+					continue
+				}
+				sla.MarkSent(conv.SLA, r)
+				sent++
+			}
+
+			if err := convRepo.UpdateSLA(ctx, conv.ConversationID, conv.SLA); err != nil {
+				slog.WarnContext(ctx, "failed to save SLA", logging.ConversationID, conv.ConversationID, "error", err)
+			}
+		}
+	}
+
+	if sent > 0 {
+		slog.InfoContext(ctx, "posted SLA reminders", "reminders", sent)
+	}
+	return nil
+}
+
+func main() {
+	logging.Setup("sla-monitor")
+	lambda.Start(Handler)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/alerts"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// handleAlertReaction records an on-call responder's reaction on a critical
+// alert as an acknowledgment, which stops it from being escalated
+func handleAlertReaction(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	if event.Item == nil || event.Item.Type != "message" || cfg.AlertChannel == "" {
+		return nil
+	}
+	// Routed alerts can be in any channel; without routes they're all in one
+	if cfg.AlertRoutes == "" && event.Item.Channel != cfg.AlertChannel {
+		return nil
+	}
+
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	alert, err := h.alertRepo.Get(ctx, event.Item.Channel, event.Item.TS)
+	if err != nil || alert == nil {
+		return err
+	}
+
+	first := alert.AckedAt == nil
+	if !alerts.Ack(alert, event.User, time.Now()) {
+		return nil
+	}
+	if err := h.alertRepo.Save(ctx, alert); err != nil {
+		return fmt.Errorf("save alert ack: %w", err)
+	}
+
+	if first {
+		msg := fmt.Sprintf("✅ Acknowledged by <@%s> after %s", event.User, humanize.Duration(alert.AckedAt.Sub(alert.CreatedAt)))
+		if _, err := h.slackClient.PostMessage(ctx, alert.ChannelID, slack.MsgOptionText(msg, false), slack.MsgOptionTS(alert.MessageTS)); err != nil {
+			slog.WarnContext(ctx, "failed to confirm alert ack", "alert_id", alert.AlertID, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/announce"
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// canAnnounce reports whether a user may broadcast announcements
+func canAnnounce(cfg *appconfig.Config, userID string) bool {
+	return contains(cfg.AnnounceUsers, userID)
+}
+
+// announce broadcasts a notice to every announcement channel at once, or
+// reports acknowledgments for an earlier broadcast
+func (h *commandHandlers) announce(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if len(cmd.Args) == 2 && cmd.Args[0] == "status" {
+		posts, err := h.annRepo.ListPosts(ctx, cmd.Args[1])
+		if err != nil {
+			return nil, fmt.Errorf("load announcement: %w", err)
+		}
+		return commands.Ephemeral("%s", announce.Summary(cmd.Args[1], posts)), nil
+	}
+
+	if !canAnnounce(h.cfg, cmd.UserID) {
+		return commands.Ephemeral("You're not authorized to send announcements. Ask an admin to add you to `ANNOUNCE_USERS`."), nil
+	}
+	if len(h.cfg.AnnounceChannels) == 0 {
+		return commands.Ephemeral("No announcement channels are configured. Set `ANNOUNCE_CHANNELS` to enable broadcasts."), nil
+	}
+
+	kind, message, err := announce.Parse(cmd.Text)
+	if err != nil {
+		return commands.Ephemeral("Usage: `/cloudops announce [maintenance|incident] <message>`"), nil
+	}
+
+	now := time.Now()
+	id := models.NewAnnouncementID()
+	text := announce.Format(kind, message, cmd.UserID, now)
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed []string
+	)
+	for _, channelID := range h.cfg.AnnounceChannels {
+		wg.Add(1)
+		go func(channelID string) {
+			defer wg.Done()
+			if err := h.postAnnouncement(ctx, id, kind, channelID, cmd.UserID, text, now); err != nil {
+				slog.WarnContext(ctx, "failed to post announcement", "announcement_id", id, logging.ChannelID, channelID, "error", err)
+				mu.Lock()
+				failed = append(failed, channelID)
+				mu.Unlock()
+			}
+		}(channelID)
+	}
+	wg.Wait()
+
+	event := models.NewAuditEvent(models.AuditAnnounce, cmd.UserID, id)
+	event.Details["kind"] = kind
+	event.Details["message"] = message
+	event.Details["channels"] = strings.Join(h.cfg.AnnounceChannels, ",")
+	if len(failed) > 0 {
+		event.Details["failed_channels"] = strings.Join(failed, ",")
+	}
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit announcement", "announcement_id", id, "error", err)
+	}
+
+	sent := len(h.cfg.AnnounceChannels) - len(failed)
+	if len(failed) > 0 {
+		return commands.Ephemeral("📣 Sent `%s` to %d of %d channels (failed: %s). Check acknowledgments with `/cloudops announce status %s`.",
+			id, sent, len(h.cfg.AnnounceChannels), formatChannels(failed), id), nil
+	}
+	return commands.Ephemeral("📣 Sent `%s` to %d channels. Check acknowledgments with `/cloudops announce status %s`.", id, sent, id), nil
+}
+
+// postAnnouncement posts one channel's copy and records it for ack tracking
+func (h *commandHandlers) postAnnouncement(ctx context.Context, id, kind, channelID, userID, text string, now time.Time) error {
+	ts, err := h.slackClient.PostMessage(ctx, channelID, slack.MsgOptionText(text, false))
+	if err != nil {
+		return err
+	}
+
+	return h.annRepo.SavePost(ctx, &models.AnnouncementPost{
+		ChannelID:      channelID,
+		MessageTS:      ts,
+		AnnouncementID: id,
+		Kind:           kind,
+		PostedBy:       userID,
+		CreatedAt:      now,
+		TTL:            now.AddDate(0, 0, 30).Unix(),
+	})
+}
+
+// formatChannels renders channel IDs as Slack channel links
+func formatChannels(channelIDs []string) string {
+	out := make([]string, len(channelIDs))
+	for i, id := range channelIDs {
+		out[i] = "<#" + id + ">"
+	}
+	return strings.Join(out, ", ")
+}
+
+// handleAnnouncementReaction records a reaction on a broadcast as an acknowledgment
+func handleAnnouncementReaction(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	if event.Item == nil || event.Item.Type != "message" || !contains(cfg.AnnounceChannels, event.Item.Channel) {
+		return nil
+	}
+
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	err = h.annRepo.AddAck(ctx, event.Item.Channel, event.Item.TS, event.User)
+	if errors.Is(err, dynamodb.ErrNotAnnouncement) {
+		return nil
+	}
+	return err
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// breakGlass temporarily elevates the invoking user when no approver is
+// reachable. It is loud on purpose: the reason is mandatory, the ops
+// channel is told, and the elevation reverts on its own
+func (h *commandHandlers) breakGlass(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if h.cfg.BreakGlassChannel == "" {
+		return commands.Ephemeral("Break-glass isn't configured. Set `BREAK_GLASS_CHANNEL` to enable it."), nil
+	}
+
+	if len(cmd.Args) > 0 && cmd.Args[0] == "end" {
+		current, err := h.permRepo.Get(ctx, cmd.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !current.IsBreakGlass() {
+			return commands.Ephemeral("You don't have an active break-glass elevation."), nil
+		}
+		restored, err := h.endBreakGlass(ctx, current, cmd.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return commands.Ephemeral("🔒 Break-glass ended. You're %s again.", restored), nil
+	}
+
+	usage := commands.Ephemeral("Usage: `/cloudops breakglass <operator|admin> <reason>`, or `/cloudops breakglass end`")
+	if len(cmd.Args) < 2 {
+		return usage, nil
+	}
+	target := strings.ToLower(cmd.Args[0])
+	if target != models.ProfileOperator && target != models.ProfileAdmin {
+		return usage, nil
+	}
+	reason := strings.Join(cmd.Args[1:], " ")
+
+	effective, err := h.profileOf(ctx, cmd.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("check permissions: %w", err)
+	}
+	if models.ProfileAtLeast(effective, target) {
+		return commands.Ephemeral("You're already %s.", effective), nil
+	}
+	current, err := h.permRepo.Get(ctx, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	duration := time.Duration(h.cfg.BreakGlassMinutes) * time.Minute
+	elevated := models.NewBreakGlass(current, cmd.UserID, target, reason, now, duration)
+	if err := h.permRepo.Put(ctx, elevated); err != nil {
+		return nil, fmt.Errorf("elevate: %w", err)
+	}
+
+	event := models.NewAuditEvent(models.AuditBreakGlass, cmd.UserID, cmd.UserID)
+	event.Details["previous"] = effective
+	event.Details["profile"] = target
+	event.Details["reason"] = reason
+	event.Details["channel_id"] = cmd.ChannelID
+	event.Details["expires_at"] = elevated.ExpiresAt.Format(time.RFC3339)
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit break-glass", "error", err)
+	}
+
+	h.notifyBreakGlass(ctx, fmt.Sprintf("🚨 *Break-glass*: <@%s> elevated themselves from %s to *%s* in <#%s> for %s.\n>%s",
+		cmd.UserID, effective, target, cmd.ChannelID, humanize.Duration(duration), reason))
+
+	return commands.Ephemeral("🚨 You're %s until %s. This was announced in <#%s> and recorded in the audit log. End it early with `/cloudops breakglass end`.",
+		target, elevated.ExpiresAt.In(locationOrUTC(cmd.Location)).Format("15:04 MST"), h.cfg.BreakGlassChannel), nil
+}
+
+// endBreakGlass restores the profile a break-glass elevation replaced and
+// returns it. endedBy is empty when the elevation expired
+func (h *commandHandlers) endBreakGlass(ctx context.Context, elevated *models.PermissionProfile, endedBy string) (string, error) {
+	restored := models.ProfileReadOnly
+	var err error
+	if elevated.Previous != nil {
+		restored = elevated.Previous.Profile
+		err = h.permRepo.Put(ctx, elevated.Previous)
+	} else {
+		err = h.permRepo.Delete(ctx, elevated.UserID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("revert break-glass: %w", err)
+	}
+
+	actor, how := endedBy, "ended early"
+	if endedBy == "" {
+		actor, how = elevated.UserID, "expired"
+	}
+	event := models.NewAuditEvent(models.AuditBreakGlassEnd, actor, elevated.UserID)
+	event.Details["profile"] = restored
+	event.Details["ended"] = how
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit break-glass end", logging.UserID, elevated.UserID, "error", err)
+	}
+
+	h.notifyBreakGlass(ctx, fmt.Sprintf("🔒 Break-glass for <@%s> %s after %s. They're %s again.",
+		elevated.UserID, how, humanize.Duration(time.Since(elevated.GrantedAt).Truncate(time.Minute)), restored))
+	return restored, nil
+}
+
+// notifyBreakGlass posts to the ops channel, logging failures so the
+// elevation itself isn't blocked by Slack
+func (h *commandHandlers) notifyBreakGlass(ctx context.Context, text string) {
+	if h.cfg.BreakGlassChannel == "" {
+		return
+	}
+	if _, err := h.slackClient.PostMessage(ctx, h.cfg.BreakGlassChannel, slack.MsgOptionText(text, false)); err != nil {
+		slog.WarnContext(ctx, "failed to announce break-glass", "error", err)
+	}
+}
+
+// locationOrUTC returns loc, or UTC when the user's timezone is unknown
+func locationOrUTC(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
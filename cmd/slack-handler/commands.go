@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/fulloutput"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/interactions"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/oncall"
+	"github.com/savaki/cloudops-bot/pkg/postmortem"
+	"github.com/savaki/cloudops-bot/pkg/setup"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+// noConversationMessage is the reply when a command can't resolve a conversation
+const noConversationMessage = "Couldn't find a conversation here. Run this in a conversation channel or pass a conversation ID."
+
+// errNotVisible is returned for a conversation the caller may not refer to,
+// which they are told doesn't exist
+var errNotVisible = errors.New("conversation not visible to caller")
+
+// commandHandlers holds the clients used by slash command handlers
+type commandHandlers struct {
+	cfg          *appconfig.Config
+	slackClient  *slackclient.Client
+	convRepo     storage.Store
+	tagRepo      *dynamodb.TagRepository
+	subRepo      *dynamodb.SubscriptionRepository
+	slaRepo      *dynamodb.SLARepository
+	auditRepo    *dynamodb.AuditRepository
+	annRepo      *dynamodb.AnnouncementRepository
+	alertRepo    *dynamodb.AlertRepository
+	runbookRepo  *dynamodb.RunbookRepository
+	permRepo     *dynamodb.PermissionRepository
+	approvalRepo *dynamodb.ApprovalRepository
+	settingsRepo *dynamodb.SettingsRepository
+	bedrock      *bedrock.Client
+	oncall       oncall.Provider   // nil when on-call lookup is disabled
+	outputs      *fulloutput.Store // nil unless OUTPUTS_BUCKET is set
+	jobs         *jobs.Manager     // nil unless JOBS_TABLE is set
+	interactions *interactions.Handlers
+	setupWizard  *setup.Wizard
+	deferrer     *commands.Deferrer // nil outside Lambda, where slow commands finish inline
+}
+
+// isSlashCommand reports whether the request is a form-encoded slash command
+func isSlashCommand(request *handler.Request) bool {
+	contentType := request.Header("Content-Type")
+	return strings.HasPrefix(contentType, "application/x-www-form-urlencoded") &&
+		strings.Contains(request.Body, "command=")
+}
+
+// handleSlashCommand parses and dispatches a /cloudops slash command
+func handleSlashCommand(ctx context.Context, cfg *appconfig.Config, body string) *handler.Response {
+	cmd, err := commands.ParseSlashCommand([]byte(body))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to parse slash command", "error", err)
+		return badRequest("Invalid command")
+	}
+	ctx = logging.With(ctx, logging.UserID, cmd.UserID, logging.ChannelID, cmd.ChannelID)
+	slog.InfoContext(ctx, "handling slash command", "command", cmd.Name)
+
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		return internalError(ctx, "Failed to initialize commands", err)
+	}
+	return okResponse(h.run(ctx, cmd))
+}
+
+// finishCommand runs a slash command deferred past Slack's deadline by a
+// second invocation, and posts its response to the command's response URL.
+// It doesn't return an error, so Lambda doesn't retry and repeat its work
+func finishCommand(ctx context.Context, cmd *commands.Command) {
+	ctx = logging.With(ctx, logging.UserID, cmd.UserID, logging.ChannelID, cmd.ChannelID)
+	slog.InfoContext(ctx, "finishing deferred slash command", "command", cmd.Name)
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load config", "error", err)
+		return
+	}
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to initialize commands", "error", err)
+		return
+	}
+	if err := commands.Reply(ctx, cmd, h.run(ctx, cmd)); err != nil {
+		slog.ErrorContext(ctx, "failed to reply to deferred slash command", "command", cmd.Name, "error", err)
+	}
+}
+
+// run dispatches a command, turning a failure into a reply
+func (h *commandHandlers) run(ctx context.Context, cmd *commands.Command) *commands.Response {
+	cmd.Location = h.slackClient.GetUserLocation(ctx, cmd.UserID)
+
+	resp, err := h.router().Handle(ctx, cmd)
+	if err != nil {
+		slog.ErrorContext(ctx, "slash command failed", "command", cmd.Name, "error", err)
+		return commands.Ephemeral("❌ `%s` failed: %v", cmd.Name, err)
+	}
+	return resp
+}
+
+// later acknowledges a slow command with ack and finishes it in a second
+// invocation, which replies through the command's response URL. It returns
+// nil when cmd is that second invocation, or when it can't be deferred and
+// has to finish in this one
+func (h *commandHandlers) later(ctx context.Context, cmd *commands.Command, ack string) *commands.Response {
+	if cmd.Deferred || h.deferrer == nil || cmd.ResponseURL == "" {
+		return nil
+	}
+	if err := h.deferrer.Defer(ctx, cmd); err != nil {
+		slog.WarnContext(ctx, "failed to defer command, finishing it now", "command", cmd.Name, "error", err)
+		return nil
+	}
+	return commands.Ephemeral("%s", ack)
+}
+
+// newCommandHandlers creates the clients used by slash commands
+func newCommandHandlers(ctx context.Context, cfg *appconfig.Config) (*commandHandlers, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	slackClient, err := newSlackClient(ctx, cfg, ddbClient)
+	if err != nil {
+		return nil, err
+	}
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	convRepo.SetHistoryTable(cfg.ConversationHistoryTable)
+	h := &commandHandlers{
+		cfg:          cfg,
+		slackClient:  slackClient,
+		convRepo:     convRepo,
+		tagRepo:      dynamodb.NewTagRepository(ddbClient, cfg.TagsTable),
+		subRepo:      dynamodb.NewSubscriptionRepository(ddbClient, cfg.SubscriptionsTable),
+		slaRepo:      dynamodb.NewSLARepository(ddbClient, cfg.SLATable),
+		auditRepo:    dynamodb.NewAuditRepository(ddbClient, cfg.AuditTable),
+		annRepo:      dynamodb.NewAnnouncementRepository(ddbClient, cfg.AnnouncementsTable),
+		alertRepo:    dynamodb.NewAlertRepository(ddbClient, cfg.AlertsTable),
+		runbookRepo:  dynamodb.NewRunbookRepository(ddbClient, cfg.RunbooksTable),
+		permRepo:     dynamodb.NewPermissionRepository(ddbClient, cfg.PermissionsTable),
+		approvalRepo: dynamodb.NewApprovalRepository(ddbClient, cfg.ApprovalsTable),
+		settingsRepo: dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable),
+		bedrock:      bedrock.NewClient(awsCfg),
+		oncall:       newOnCallProvider(cfg, ddbClient),
+	}
+	h.auditRepo.SetToolTable(cfg.ToolAuditTable)
+	h.bedrock.SetModel(cfg.BedrockModelID)
+	h.interactions = interactions.New(cfg, awsCfg, ddbClient, slackClient)
+	h.setupWizard = setup.NewWizard(setup.Checks(cfg, setup.NewClients(awsCfg, h.bedrock, slackClient)), h.settingsRepo, slackClient)
+	if cfg.OutputsBucket != "" {
+		h.outputs = fulloutput.NewStore(awsCfg, cfg.OutputsBucket)
+	}
+	if lambdacontext.FunctionName != "" {
+		h.deferrer = commands.NewDeferrer(awsCfg, lambdacontext.FunctionName)
+	}
+	if cfg.JobsTable != "" {
+		jobRepo := dynamodb.NewJobRepository(ddbClient, cfg.JobsTable)
+		jobRepo.SetFaultInjector(cfg.FaultInjector())
+		h.jobs = jobs.NewManager(jobRepo, slackClient)
+	}
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		h.tagRepo.SetFaultInjector(faults)
+		h.subRepo.SetFaultInjector(faults)
+		h.slaRepo.SetFaultInjector(faults)
+		h.auditRepo.SetFaultInjector(faults)
+		h.annRepo.SetFaultInjector(faults)
+		h.alertRepo.SetFaultInjector(faults)
+		h.runbookRepo.SetFaultInjector(faults)
+		h.permRepo.SetFaultInjector(faults)
+		h.approvalRepo.SetFaultInjector(faults)
+		h.settingsRepo.SetFaultInjector(faults)
+		h.slackClient.SetFaultInjector(faults)
+		h.bedrock.SetFaultInjector(faults)
+	}
+
+	return h, nil
+}
+
+// router registers all /cloudops subcommands
+func (h *commandHandlers) router() *commands.Router {
+	router := commands.NewRouter()
+	router.Register("postmortem", "`[conversation-id] [--tickets]` draft a postmortem for this channel's conversation", h.postmortem)
+	router.Register("tag", "`#tag [#tag...]` tag this channel's conversation, or list its tags", h.tag)
+	router.Register("untag", "`#tag [#tag...]` remove tags from this channel's conversation", h.untag)
+	router.Register("tagged", "`#tag` list recent conversations with a tag", h.tagged)
+	router.Register("watch", "`[conversation-id] [keyword...]` get DMs on status changes or when keywords come up", h.watch)
+	router.Register("unwatch", "`[conversation-id]` stop watching a conversation", h.unwatch)
+	router.Register("ack", "`[conversation-id]` acknowledge this channel's incident", h.ack)
+	router.Register("resolve", "`[conversation-id]` mark this channel's incident resolved and stop its SLA timers", h.resolve)
+	router.Register("runbook", "`[conversation-id]` save this channel's resolution as a runbook draft, or `drafts`, `publish <id>`, `discard <id>`", h.runbook)
+	router.Register("tutorial", "`[conversation-id]` write an anonymized step-by-step tutorial of this channel's resolved investigation for the wiki", h.tutorial)
+	router.Register("announce", "`[maintenance|incident] <message>` broadcast a notice to the announcement channels, or `status <id>` to see acknowledgments", h.announce)
+	router.Register("grant", "`@user <read-only|operator|admin> [reason]` (admins) give a user a permission profile", h.grant)
+	router.Register("revoke", "`@user [reason]` (admins) return a user to read-only", h.revoke)
+	router.Register("roles", "`[@user]` list who has elevated permissions", h.roles)
+	router.Register("breakglass", "`<operator|admin> <reason>` temporarily elevate yourself in an emergency, or `end` to drop it", h.breakGlass)
+	router.Register("transfer", "`<#channel|new> [conversation-id]` move this channel's conversation to another channel or a new incident channel", h.transfer)
+	router.Register("escalate", "`[conversation-id] [reason]` hand this channel's conversation to the experts group; the bot then only answers when mentioned", h.escalate)
+	router.Register("debug", "`[on|off] [conversation-id]` show the tool calls and tokens behind each answer in this channel's conversation", h.debug)
+	router.Register("admin", "`<disable <reason>|enable|status>` (admins) stop the whole bot during an incident, or turn it back on", h.admin)
+	router.Register("oncall", "`<team>` show who's on call for a team", h.oncallCommand)
+	router.Register("jobs", "`[conversation-id]` list this channel's background jobs, or `cancel <job-id>` to stop one", h.jobsCommand)
+	router.Register("stats", "`[today|week|<time range>]` show conversations, resolution times, top tools and model spend", h.stats)
+	router.Register("setup", "(admins) check the bot's tables, credentials, model access and Slack token, and DM you what to fix", h.setup)
+	return router
+}
+
+// findConversation resolves the conversation a command refers to: an explicit
+// conv-... argument, or the most recent conversation with the channel to
+// itself. Slash commands can't be run in threads, so conversations confined
+// to one are always passed by ID. An ID only resolves for those who took
+// part in the conversation or from the channel it is held in, so it can't
+// reach into channels the caller can't see
+func (h *commandHandlers) findConversation(ctx context.Context, cmd *commands.Command) (*models.Conversation, error) {
+	for _, arg := range cmd.Args {
+		if strings.HasPrefix(arg, "conv-") {
+			conv, err := h.convRepo.GetByID(ctx, arg)
+			if err != nil {
+				return nil, err
+			}
+			if !conv.VisibleTo(cmd.UserID, cmd.ChannelID) {
+				return nil, errNotVisible
+			}
+			return conv, nil
+		}
+	}
+	return h.convRepo.GetByChannelID(ctx, cmd.ChannelID)
+}
+
+// postmortem drafts a structured postmortem and posts it as an editable snippet
+func (h *commandHandlers) postmortem(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	if resp := h.later(ctx, cmd, fmt.Sprintf("📝 Drafting a postmortem for `%s`. I'll post it here shortly.", conv.ConversationID)); resp != nil {
+		return resp, nil
+	}
+
+	history, err := h.convRepo.GetHistoryItems(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("load history: %w", err)
+	}
+	var calls []*models.ToolExecution
+	if h.cfg.ToolAuditTable != "" {
+		if calls, err = h.auditRepo.ListConversationToolExecutions(ctx, conv.ConversationID); err != nil {
+			slog.WarnContext(ctx, "failed to list tool executions for postmortem", logging.ConversationID, conv.ConversationID, "error", err)
+		}
+	}
+
+	draft, err := postmortem.NewDrafter(h.bedrock).Draft(ctx, conv, history, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("postmortem-%s.md", conv.ConversationID)
+	if err := h.slackClient.UploadFile(ctx, cmd.ChannelID, "", filename, draft.Title, []byte(draft.Markdown())); err != nil {
+		return nil, fmt.Errorf("upload postmortem: %w", err)
+	}
+
+	msg := fmt.Sprintf("📝 Postmortem draft for `%s` posted with %d action items.", conv.ConversationID, len(draft.ActionItems))
+
+	if hasFlag(cmd.Args, "--tickets") {
+		if h.cfg.TicketWebhookURL == "" {
+			return commands.Ephemeral("%s Ticket filing isn't configured (TICKET_WEBHOOK_URL).", msg), nil
+		}
+		filed, err := postmortem.NewTicketFiler(h.cfg.TicketWebhookURL).File(ctx, conv.ConversationID, draft)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to file tickets", logging.ConversationID, conv.ConversationID, "error", err)
+			return commands.Ephemeral("%s Filed %d of %d tickets before an error: %v", msg, filed, len(draft.ActionItems), err), nil
+		}
+		msg += fmt.Sprintf(" Filed %d tickets.", filed)
+	}
+
+	return commands.Ephemeral("%s", msg), nil
+}
+
+// hasFlag reports whether args contains the given flag
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
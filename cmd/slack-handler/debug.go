@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/debugmode"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// debug turns debug mode on or off for this channel's conversation. The
+// setting is saved for when the agent resumes, and announced with metadata
+// so the running agent picks it up on its next poll
+func (h *commandHandlers) debug(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if conv.Ended() {
+		return commands.Ephemeral("`%s` has already ended.", conv.ConversationID), nil
+	}
+
+	on := !conv.Debug
+	for _, arg := range cmd.Args {
+		switch strings.ToLower(arg) {
+		case "on":
+			on = true
+		case "off":
+			on = false
+		}
+	}
+	if on == conv.Debug {
+		return commands.Ephemeral("Debug mode is already %s for `%s`.", onOff(on), conv.ConversationID), nil
+	}
+
+	if err := h.convRepo.SetDebug(ctx, conv.ConversationID, on); err != nil {
+		return nil, err
+	}
+	if _, err := h.slackClient.PostMessage(ctx, conv.ChannelID,
+		slack.MsgOptionText(debugmode.Notice(cmd.UserID, on), false),
+		slack.MsgOptionMetadata(debugmode.Metadata(conv.ConversationID, on)),
+		slackclient.InThread(conv.ThreadTS),
+	); err != nil {
+		return nil, fmt.Errorf("announce debug mode: %w", err)
+	}
+	return commands.Ephemeral("Debug mode is %s for `%s`.", onOff(on), conv.ConversationID), nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
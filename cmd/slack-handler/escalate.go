@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/escalation"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/slack-go/slack"
+)
+
+// escalate hands this channel's conversation to the experts group. The
+// group is pinged with a context pack, and the metadata on that message
+// tells the running agent to step back and only answer when mentioned
+func (h *commandHandlers) escalate(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if h.cfg.ExpertsGroup == "" {
+		return commands.Ephemeral("Escalation isn't configured: set EXPERTS_GROUP to the Slack user group to ping."), nil
+	}
+
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if conv.Ended() {
+		return commands.Ephemeral("`%s` has already ended.", conv.ConversationID), nil
+	}
+	if conv.HumanAssisted {
+		return commands.Ephemeral("`%s` has already been escalated.", conv.ConversationID), nil
+	}
+
+	// Drafting the context pack takes longer than Slack waits for a reply
+	if resp := h.later(ctx, cmd, fmt.Sprintf("Escalating `%s` to the experts…", conv.ConversationID)); resp != nil {
+		return resp, nil
+	}
+
+	var reason []string
+	for _, arg := range cmd.Args {
+		if !strings.HasPrefix(arg, "conv-") {
+			reason = append(reason, arg)
+		}
+	}
+
+	if err := h.convRepo.Escalate(ctx, conv.ConversationID, cmd.UserID); err != nil {
+		if errors.Is(err, storage.ErrAlreadyEscalated) {
+			return commands.Ephemeral("`%s` has already been escalated.", conv.ConversationID), nil
+		}
+		return nil, err
+	}
+
+	pack := escalation.FromNotes(conv)
+	if history, err := h.convRepo.GetHistoryItems(ctx, conv.ConversationID); err != nil {
+		slog.WarnContext(ctx, "failed to load history for escalation", logging.ConversationID, conv.ConversationID, "error", err)
+	} else if drafted, err := escalation.NewDrafter(h.bedrock).Draft(ctx, conv, history); err != nil {
+		slog.WarnContext(ctx, "failed to draft context pack, using notes", logging.ConversationID, conv.ConversationID, "error", err)
+	} else {
+		pack = drafted
+	}
+
+	var permalink string
+	if conv.MessageTS != "" {
+		if permalink, err = h.slackClient.GetPermalink(ctx, conv.MessageChannelID(), conv.MessageTS); err != nil {
+			slog.WarnContext(ctx, "failed to link original request", logging.ConversationID, conv.ConversationID, "error", err)
+		}
+	}
+
+	if _, err := h.slackClient.PostMessage(ctx, conv.ChannelID,
+		slack.MsgOptionText(escalation.Text(conv, h.cfg.ExpertsGroup, cmd.UserID), false),
+		slack.MsgOptionBlocks(escalation.Blocks(conv, pack, h.cfg.ExpertsGroup, cmd.UserID, strings.Join(reason, " "), permalink)...),
+		slack.MsgOptionMetadata(escalation.Metadata(conv.ConversationID, cmd.UserID)),
+		slackclient.InThread(conv.ThreadTS),
+	); err != nil {
+		return nil, fmt.Errorf("post escalation: %w", err)
+	}
+	return commands.Ephemeral("Escalated `%s` to the experts.", conv.ConversationID), nil
+}
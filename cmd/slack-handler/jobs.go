@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+)
+
+// jobsCommand lists the background jobs of this channel's conversation, or
+// cancels one
+func (h *commandHandlers) jobsCommand(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if h.jobs == nil {
+		return commands.Ephemeral("Background jobs aren't enabled. Set JOBS_TABLE to turn them on."), nil
+	}
+
+	if len(cmd.Args) > 0 && strings.EqualFold(cmd.Args[0], "cancel") {
+		if len(cmd.Args) != 2 {
+			return commands.Ephemeral("Usage: `/cloudops jobs cancel <job-id>`"), nil
+		}
+		job, err := h.jobs.Cancel(ctx, cmd.Args[1], cmd.UserID)
+		switch {
+		case errors.Is(err, jobs.ErrNotFound):
+			return commands.Ephemeral("Couldn't find job `%s`.", cmd.Args[1]), nil
+		case errors.Is(err, jobs.ErrEnded):
+			return commands.Ephemeral("Job `%s` has already %s.", job.JobID, job.Status), nil
+		case err != nil:
+			return nil, err
+		}
+		return commands.Ephemeral("🛑 Cancelled job `%s`.", job.JobID), nil
+	}
+
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	list, err := h.jobs.List(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return commands.Ephemeral("No background jobs have been started in `%s`.", conv.ConversationID), nil
+	}
+
+	lines := []string{"*Background jobs in `" + conv.ConversationID + "`*"}
+	for _, job := range list {
+		lines = append(lines, "• "+jobs.Describe(job))
+	}
+	return commands.Ephemeral("%s", strings.Join(lines, "\n")), nil
+}
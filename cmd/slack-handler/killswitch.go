@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/killswitch"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// admin runs bot-wide controls. `disable` is the kill switch: no
+// conversation starts and running agents stop answering until an admin
+// runs `enable`
+func (h *commandHandlers) admin(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	usage := commands.Ephemeral("Usage: `/cloudops admin disable <reason>`, `/cloudops admin enable`, or `/cloudops admin status`")
+	if len(cmd.Args) == 0 {
+		return usage, nil
+	}
+
+	current, err := h.settingsRepo.GetKillSwitch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	disabled := current != nil && current.Disabled
+
+	action := strings.ToLower(cmd.Args[0])
+	switch action {
+	case "status":
+		if !disabled {
+			return commands.Ephemeral("▶️ The bot is enabled."), nil
+		}
+		return commands.Ephemeral("%s\nDisabled at %s.", killswitch.Message(current),
+			current.ChangedAt.In(locationOrUTC(cmd.Location)).Format("Jan 2 15:04 MST")), nil
+	case "disable", "enable":
+	default:
+		return usage, nil
+	}
+
+	if refusal, err := h.requireAdmin(ctx, cmd.UserID); refusal != nil || err != nil {
+		return refusal, err
+	}
+
+	reason := strings.Join(cmd.Args[1:], " ")
+	if action == "disable" && reason == "" {
+		return usage, nil
+	}
+	if disabled == (action == "disable") {
+		return commands.Ephemeral("The bot is already %sd.", action), nil
+	}
+
+	ks := &models.KillSwitch{
+		Disabled:  action == "disable",
+		Reason:    reason,
+		ChangedBy: cmd.UserID,
+		ChangedAt: time.Now(),
+	}
+	if err := h.settingsRepo.PutKillSwitch(ctx, ks); err != nil {
+		return nil, fmt.Errorf("%s bot: %w", action, err)
+	}
+
+	event := models.NewAuditEvent(models.AuditKillSwitch, cmd.UserID, action)
+	event.Details["channel_id"] = cmd.ChannelID
+	if reason != "" {
+		event.Details["reason"] = reason
+	}
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit kill switch", "action", action, "error", err)
+	}
+
+	if ks.Disabled {
+		return commands.InChannel("⏸️ <@%s> disabled the CloudOps bot: %s\nNo new conversations will start, and running ones pause within %s. Re-enable it with `/cloudops admin enable`.",
+			cmd.UserID, reason, killswitch.DefaultInterval), nil
+	}
+	return commands.InChannel("▶️ <@%s> re-enabled the CloudOps bot. Paused conversations pick up where they left off.", cmd.UserID), nil
+}
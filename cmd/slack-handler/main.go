@@ -4,79 +4,171 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/commands"
 	appconfig "github.com/savaki/cloudops-bot/pkg/config"
 	"github.com/savaki/cloudops-bot/pkg/dynamodb"
 	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/intake"
+	"github.com/savaki/cloudops-bot/pkg/logging"
 	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/setup"
 	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
-	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
-	"github.com/slack-go/slack"
 )
 
-// Handler is the Lambda handler for Slack events
-func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	log.Printf("Received Slack event")
+// Handler is the Lambda handler for Slack events. It accepts invocations
+// from API Gateway, a Lambda Function URL, or an ALB target group
+func Handler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	// Slow slash commands finish in an asynchronous invocation of their own
+	if cmd, ok := commands.ParseDeferred(payload); ok {
+		finishCommand(ctx, cmd)
+		return nil, nil
+	}
+
+	request, err := handler.DecodeRequest(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to decode request", "error", err)
+		return handler.EncodeResponse(handler.SourceAPIGateway, badRequest("Invalid request")), nil
+	}
+
+	return handler.EncodeResponse(request.Source, handle(ctx, request)), nil
+}
+
+// handle processes a Slack request independent of the Lambda entrypoint
+func handle(ctx context.Context, request *handler.Request) *handler.Response {
+	slog.InfoContext(ctx, "received slack event", "source", request.Source)
 
 	// Load configuration
 	cfg, err := appconfig.Load()
 	if err != nil {
-		return internalError("Failed to load config", err)
+		return configError(ctx, "Failed to load config", err)
 	}
 
 	// Validate Lambda-specific configuration
 	if err := cfg.ValidateLambda(); err != nil {
-		return internalError("Invalid Lambda config", err)
+		return configError(ctx, "Invalid Lambda config", err)
+	}
+
+	// Turn away callers outside the allowed networks or without a trusted
+	// client certificate before doing any other work
+	if err := cfg.AccessPolicy().Check(request); err != nil {
+		slog.WarnContext(ctx, "rejected request", "source", request.Source, "error", err)
+		return errorResponse(403, handler.CodeForbidden, "Forbidden")
 	}
 
 	// Validate Slack request signature
 	if !handler.ValidateSlackRequest(
 		[]byte(request.Body),
-		request.Headers["X-Slack-Request-Timestamp"],
-		request.Headers["X-Slack-Signature"],
-		cfg.SlackSigningKey,
+		request.Header("X-Slack-Request-Timestamp"),
+		request.Header("X-Slack-Signature"),
+		cfg.SigningKeys()...,
 	) {
-		log.Printf("Invalid Slack signature")
-		return badRequest("Invalid signature"), nil
+		slog.WarnContext(ctx, "invalid slack signature")
+		return errorResponse(401, handler.CodeInvalidSignature, "Invalid signature")
+	}
+
+	// Slack retries after 3s without a response, but the original delivery is
+	// usually still running; processing the retry would duplicate its work
+	if request.Header("X-Slack-Retry-Reason") == "http_timeout" {
+		slog.InfoContext(ctx, "acknowledging slack retry after timeout without reprocessing", "retry_num", request.Header("X-Slack-Retry-Num"))
+		resp := okResponse(map[string]bool{"ok": true})
+		resp.Headers[handler.NoRetryHeader] = "1"
+		return resp
+	}
+
+	// Button clicks and modal submissions arrive as a form-encoded JSON
+	// payload. Apps set up before the dedicated interactions endpoint still
+	// send them here
+	if handler.IsInteraction(request) {
+		h, err := newCommandHandlers(ctx, cfg)
+		if err != nil {
+			return internalError(ctx, "Failed to initialize interactions", err)
+		}
+		ih := handler.NewInteractionHandler(cfg.SigningKeys()...)
+		h.interactions.Register(ih)
+		return ih.Dispatch(ctx, request.Body)
+	}
+
+	// Slash commands are form-encoded rather than JSON
+	if isSlashCommand(request) {
+		return handleSlashCommand(ctx, cfg, request.Body)
 	}
 
 	// Parse Slack event
 	var slackEvent models.SlackEventCallback
 	if err := json.Unmarshal([]byte(request.Body), &slackEvent); err != nil {
-		log.Printf("Failed to parse Slack event: %v", err)
-		return badRequest("Invalid event format"), nil
+		slog.ErrorContext(ctx, "failed to parse slack event", "error", err)
+		return badRequest("Invalid event format")
 	}
 
+	ctx = logging.With(ctx, logging.EventID, slackEvent.EventID, logging.UserID, slackEvent.Event.User, logging.ChannelID, slackEvent.Event.Channel)
+
 	// Handle URL verification challenge
 	if slackEvent.Type == "url_verification" {
-		log.Printf("Responding to Slack URL verification challenge")
-		return events.APIGatewayProxyResponse{
-			StatusCode: 200,
-			Body:       fmt.Sprintf(`{"challenge":"%s"}`, slackEvent.Challenge),
-			Headers:    map[string]string{"Content-Type": "application/json"},
-		}, nil
+		slog.InfoContext(ctx, "responding to slack URL verification challenge")
+		return okResponse(map[string]string{"challenge": slackEvent.Challenge})
 	}
 
 	// Handle app mention events (spawn ECS task for conversation)
 	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "app_mention" {
 		if err := handleAppMention(ctx, cfg, slackEvent.Event); err != nil {
-			log.Printf("Failed to handle app mention: %v", err)
-			return internalError("Failed to process mention", err)
+			if setup.Misconfigured(err) {
+				promptSetup(ctx, cfg)
+			}
+			return internalError(ctx, "Failed to process mention", err)
+		}
+		return okResponse(map[string]bool{"ok": true})
+	}
+
+	// Handle reactions (tag conversations via configured emoji, acknowledge announcements and alerts)
+	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "reaction_added" {
+		if err := handleReactionAdded(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to handle reaction", "error", err)
+		}
+		if err := handleAnnouncementReaction(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to record announcement ack", "error", err)
+		}
+		if err := handleAlertReaction(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to record alert ack", "error", err)
+		}
+		return okResponse(map[string]bool{"ok": true})
+	}
+
+	// Summarize the AWS resources behind pasted console links
+	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "link_shared" {
+		if err := handleLinkShared(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to unfurl links", "error", err)
+		}
+		return okResponse(map[string]bool{"ok": true})
+	}
+
+	// Let agents know their conversation has a new message to read
+	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "message" {
+		if err := handleMessage(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to deliver message to inbox", "error", err)
+		}
+		return okResponse(map[string]bool{"ok": true})
+	}
+
+	// Walk admins through setup when they first open the bot
+	if slackEvent.Type == "event_callback" && slackEvent.Event.Type == "app_home_opened" {
+		if err := handleAppHomeOpened(ctx, cfg, slackEvent.Event); err != nil {
+			slog.ErrorContext(ctx, "failed to start setup wizard", "error", err)
 		}
-		return okResponse(map[string]bool{"ok": true}), nil
+		return okResponse(map[string]bool{"ok": true})
 	}
 
-	log.Printf("Ignoring event type: %s", slackEvent.Type)
-	return okResponse(map[string]bool{"ok": true}), nil
+	slog.InfoContext(ctx, "ignoring event", "type", slackEvent.Type, "event_type", slackEvent.Event.Type)
+	return okResponse(map[string]bool{"ok": true})
 }
 
 // handleAppMention spawns an ECS task to handle the conversation
 func handleAppMention(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
-	log.Printf("Handling app mention from user %s in channel %s", event.User, event.Channel)
+	slog.InfoContext(ctx, "handling app mention")
 
 	// Initialize AWS SDK
 	awsCfg, err := config.LoadDefaultConfig(ctx)
@@ -85,69 +177,61 @@ func handleAppMention(ctx context.Context, cfg *appconfig.Config, event models.S
 	}
 
 	// Initialize clients
-	slackClient := slackclient.NewClient(cfg.SlackBotToken)
 	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
-	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
-	sfClient := stepfunctions.NewClient(awsCfg)
-
-	// Create new conversation
-	conversation := models.NewConversation(event.Channel, event.User, event.Text)
-	log.Printf("Created conversation: %s", conversation.ConversationID)
-
-	// Save to DynamoDB
-	if err := convRepo.Save(ctx, conversation); err != nil {
-		return fmt.Errorf("save conversation: %w", err)
+	slackClient, err := newSlackClient(ctx, cfg, ddbClient)
+	if err != nil {
+		return err
 	}
-	log.Printf("Saved conversation to DynamoDB")
-
-	// Post acknowledgment message
-	msg := "🚀 Starting CloudOps assistant... I'll respond in a moment."
-	if _, err := slackClient.PostMessage(ctx, event.Channel, slack.MsgOptionText(msg, false)); err != nil {
-		log.Printf("Warning: failed to post acknowledgment: %v", err)
+	if faults := cfg.FaultInjector(); faults != nil {
+		slackClient.SetFaultInjector(faults)
 	}
 
-	// Start Step Function execution (which will spawn ECS task)
-	executionArn, err := sfClient.StartConversation(ctx, cfg.StepFunctionArn, conversation)
-	if err != nil {
-		// Try to notify user of failure
-		slackClient.PostMessage(ctx, event.Channel, slack.MsgOptionText("❌ Failed to start assistant. Please try again.", false))
-		return fmt.Errorf("start step function: %w", err)
-	}
-	log.Printf("Started Step Function execution: %s", executionArn)
+	return intake.NewStarter(cfg, awsCfg, ddbClient, slackClient).Start(ctx, event)
+}
 
-	// Update conversation with execution ARN
-	conversation.ExecutionArn = executionArn
-	conversation.UpdateStatus(models.StatusPending)
-	if err := convRepo.Save(ctx, conversation); err != nil {
-		log.Printf("Warning: failed to update conversation with execution ARN: %v", err)
+// newSlackClient returns a client for the current bot token, which is
+// read from the token table when Slack token rotation is enabled
+func newSlackClient(ctx context.Context, cfg *appconfig.Config, ddbClient *awsdynamodb.Client) (*slackclient.Client, error) {
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return nil, fmt.Errorf("get slack token: %w", err)
+		}
 	}
+	return slackClient, nil
+}
 
-	return nil
+// errorResponse returns a failure with a machine-readable error code
+func errorResponse(status int, code, message string) *handler.Response {
+	return handler.ErrorResponse(status, code, message)
 }
 
-// internalError returns a 500 error response
-func internalError(message string, err error) (events.APIGatewayProxyResponse, error) {
-	log.Printf("ERROR: %s: %v", message, err)
-	return events.APIGatewayProxyResponse{
-		StatusCode: 500,
-		Body:       fmt.Sprintf(`{"error":"%s"}`, message),
-		Headers:    map[string]string{"Content-Type": "application/json"},
-	}, nil
+// internalError returns a 503 for transient failures Slack should retry,
+// or a 500 that tells Slack not to
+func internalError(ctx context.Context, message string, err error) *handler.Response {
+	slog.ErrorContext(ctx, message, "error", err)
+	if handler.IsTransient(err) {
+		return errorResponse(503, handler.CodeTransient, message)
+	}
+	return errorResponse(500, handler.CodeInternal, message)
+}
+
+// configError returns a 500 for misconfiguration; retrying can't fix it
+func configError(ctx context.Context, message string, err error) *handler.Response {
+	slog.ErrorContext(ctx, message, "error", err)
+	return errorResponse(500, handler.CodeConfigError, message)
 }
 
 // badRequest returns a 400 error response
-func badRequest(message string) events.APIGatewayProxyResponse {
-	return events.APIGatewayProxyResponse{
-		StatusCode: 400,
-		Body:       fmt.Sprintf(`{"error":"%s"}`, message),
-		Headers:    map[string]string{"Content-Type": "application/json"},
-	}
+func badRequest(message string) *handler.Response {
+	return errorResponse(400, handler.CodeInvalidRequest, message)
 }
 
 // okResponse returns a successful response
-func okResponse(body interface{}) events.APIGatewayProxyResponse {
+func okResponse(body interface{}) *handler.Response {
 	data, _ := json.Marshal(body)
-	return events.APIGatewayProxyResponse{
+	return &handler.Response{
 		StatusCode: 200,
 		Body:       string(data),
 		Headers:    map[string]string{"Content-Type": "application/json"},
@@ -155,5 +239,6 @@ func okResponse(body interface{}) events.APIGatewayProxyResponse {
 }
 
 func main() {
+	logging.Setup("slack-handler")
 	lambda.Start(Handler)
 }
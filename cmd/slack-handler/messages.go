@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// handleMessage tells the agent of the conversation a message was posted in
+// that there is something new to read. Only messages the agent would see on
+// its next poll count; edits and deletions never reach it
+func handleMessage(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	if cfg.InboxTable == "" || event.SubType == "message_changed" || event.SubType == "message_deleted" {
+		return nil
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	convRepo.SetHistoryTable(cfg.ConversationHistoryTable)
+	convRepo.SetFaultInjector(cfg.FaultInjector())
+	conv, err := convRepo.GetByMessage(ctx, event.Channel, event.ThreadTS)
+	if err != nil || conv.Ended() || conv.ThreadTS != event.ThreadTS {
+		return nil
+	}
+
+	inbox := dynamodb.NewInboxRepository(ddbClient, cfg.InboxTable)
+	inbox.SetFaultInjector(cfg.FaultInjector())
+	return inbox.Deliver(ctx, conv.ConversationID, event.TS, time.Now())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/oncall"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// newOnCallProvider returns the configured on-call provider, or nil when
+// on-call lookup is disabled
+func newOnCallProvider(cfg *appconfig.Config, ddbClient *awsdynamodb.Client) oncall.Provider {
+	repo := dynamodb.NewOnCallRepository(ddbClient, cfg.OnCallTable)
+	if faults := cfg.FaultInjector(); faults != nil {
+		repo.SetFaultInjector(faults)
+	}
+	return oncall.New(cfg.OnCallProvider, cfg.OnCallAPIToken, cfg.OnCallSchedules, repo)
+}
+
+// currentOnCall looks up a team's responders and resolves their Slack users
+func (h *commandHandlers) currentOnCall(ctx context.Context, team string) ([]oncall.Responder, error) {
+	responders, err := h.oncall.OnCall(ctx, team, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return oncall.Resolve(ctx, responders, h.slackClient.UserIDByEmail), nil
+}
+
+// formatResponders renders responders as Slack mentions where possible
+func formatResponders(responders []oncall.Responder) string {
+	names := make([]string, len(responders))
+	for i, r := range responders {
+		if r.SlackUserID != "" {
+			names[i] = fmt.Sprintf("<@%s>", r.SlackUserID)
+		} else {
+			names[i] = r.Name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// oncallCommand answers "who's on call for <team>"
+func (h *commandHandlers) oncallCommand(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if h.oncall == nil {
+		return commands.Ephemeral("On-call lookup isn't configured (ONCALL_PROVIDER)."), nil
+	}
+	if len(cmd.Args) == 0 {
+		return commands.Ephemeral("Usage: `/cloudops oncall <team>`"), nil
+	}
+
+	team := strings.ToLower(strings.TrimPrefix(cmd.Args[0], "#"))
+	responders, err := h.currentOnCall(ctx, team)
+	if errors.Is(err, oncall.ErrUnknownTeam) {
+		return commands.Ephemeral("No on-call schedule is configured for `%s`.", team), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up on-call: %w", err)
+	}
+	if len(responders) == 0 {
+		return commands.Ephemeral("Nobody is on call for `%s` right now.", team), nil
+	}
+
+	return commands.Ephemeral("📟 On call for *%s*: %s", team, formatResponders(responders)), nil
+}
+
+// includeOnCall invites a team's on-call to the conversation channel when
+// it is tagged with that team
+func (h *commandHandlers) includeOnCall(ctx context.Context, conv *models.Conversation, team string) {
+	if h.oncall == nil {
+		return
+	}
+
+	responders, err := h.currentOnCall(ctx, team)
+	if err != nil {
+		if !errors.Is(err, oncall.ErrUnknownTeam) {
+			slog.WarnContext(ctx, "failed to look up on-call", "team", team, "error", err)
+		}
+		return
+	}
+	if len(responders) == 0 {
+		return
+	}
+
+	var userIDs []string
+	for _, r := range responders {
+		if r.SlackUserID != "" && r.SlackUserID != conv.UserID {
+			userIDs = append(userIDs, r.SlackUserID)
+		}
+	}
+	if len(userIDs) > 0 {
+		if err := h.slackClient.InviteUsersToConversation(ctx, conv.ChannelID, userIDs...); err != nil {
+			// Public channels the on-call already belongs to return errors here too
+			slog.WarnContext(ctx, "failed to invite on-call", "team", team, logging.ConversationID, conv.ConversationID, "error", err)
+		}
+	}
+
+	msg := fmt.Sprintf("📟 %s is on call for *%s*", formatResponders(responders), team)
+	if _, err := h.slackClient.PostMessage(ctx, conv.ChannelID, slack.MsgOptionText(msg, false), slackclient.InThread(conv.ThreadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post on-call", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+}
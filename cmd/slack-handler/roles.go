@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/interactions"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// profileOf returns a user's effective permission profile
+func (h *commandHandlers) profileOf(ctx context.Context, userID string) (string, error) {
+	if contains(h.cfg.AdminUsers, userID) {
+		return models.ProfileAdmin, nil
+	}
+	profile, err := h.permRepo.Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if profile.Expired(time.Now()) {
+		return h.endBreakGlass(ctx, profile, "")
+	}
+	if profile == nil {
+		return models.ProfileReadOnly, nil
+	}
+	return profile.Profile, nil
+}
+
+// requireAdmin returns a refusal when the user isn't an admin
+func (h *commandHandlers) requireAdmin(ctx context.Context, userID string) (*commands.Response, error) {
+	profile, err := h.profileOf(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("check permissions: %w", err)
+	}
+	if profile != models.ProfileAdmin {
+		return commands.Ephemeral("Only admins can manage permissions."), nil
+	}
+	return nil, nil
+}
+
+// grant assigns a permission profile to a user after confirmation
+func (h *commandHandlers) grant(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if refusal, err := h.requireAdmin(ctx, cmd.UserID); refusal != nil || err != nil {
+		return refusal, err
+	}
+
+	usage := commands.Ephemeral("Usage: `/cloudops grant @user <%s> [reason]`", strings.Join(models.Profiles, "|"))
+	if len(cmd.Args) < 2 {
+		return usage, nil
+	}
+	userID, ok := commands.ParseUser(cmd.Args[0])
+	if !ok {
+		return usage, nil
+	}
+	profile := strings.ToLower(cmd.Args[1])
+	if !models.ValidProfile(profile) {
+		return usage, nil
+	}
+	if profile == models.ProfileReadOnly {
+		return commands.Ephemeral("Everyone is %s by default. Use `/cloudops revoke <@%s>` instead.", models.ProfileReadOnly, userID), nil
+	}
+
+	return h.confirmChange(ctx, cmd, interactions.RBACChange{
+		Action:    "grant",
+		UserID:    userID,
+		Profile:   profile,
+		Reason:    strings.Join(cmd.Args[2:], " "),
+		ChannelID: cmd.ChannelID,
+	})
+}
+
+// revoke returns a user to read-only after confirmation
+func (h *commandHandlers) revoke(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if refusal, err := h.requireAdmin(ctx, cmd.UserID); refusal != nil || err != nil {
+		return refusal, err
+	}
+
+	if len(cmd.Args) < 1 {
+		return commands.Ephemeral("Usage: `/cloudops revoke @user [reason]`"), nil
+	}
+	userID, ok := commands.ParseUser(cmd.Args[0])
+	if !ok {
+		return commands.Ephemeral("Usage: `/cloudops revoke @user [reason]`"), nil
+	}
+
+	return h.confirmChange(ctx, cmd, interactions.RBACChange{
+		Action:    "revoke",
+		UserID:    userID,
+		Reason:    strings.Join(cmd.Args[1:], " "),
+		ChannelID: cmd.ChannelID,
+	})
+}
+
+// confirmChange opens a modal asking the admin to confirm a grant or revoke
+func (h *commandHandlers) confirmChange(ctx context.Context, cmd *commands.Command, change interactions.RBACChange) (*commands.Response, error) {
+	if change.UserID == cmd.UserID {
+		return commands.Ephemeral("You can't change your own permissions. Ask another admin."), nil
+	}
+	if contains(h.cfg.AdminUsers, change.UserID) {
+		return commands.Ephemeral("<@%s> is an admin through `ADMIN_USERS`, which can only be changed in the deployment.", change.UserID), nil
+	}
+
+	current, err := h.profileOf(ctx, change.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("check permissions: %w", err)
+	}
+
+	var prompt, submit string
+	switch change.Action {
+	case "grant":
+		if current == change.Profile {
+			return commands.Ephemeral("<@%s> is already %s.", change.UserID, current), nil
+		}
+		prompt = fmt.Sprintf("Grant *%s* to <@%s>? They're currently *%s*.", change.Profile, change.UserID, current)
+		submit = "Grant"
+	default:
+		if current == models.ProfileReadOnly {
+			return commands.Ephemeral("<@%s> is already %s.", change.UserID, current), nil
+		}
+		prompt = fmt.Sprintf("Revoke *%s* from <@%s>? They'll be *%s*.", current, change.UserID, models.ProfileReadOnly)
+		submit = "Revoke"
+	}
+	if change.Reason != "" {
+		prompt += "\n>" + change.Reason
+	}
+
+	metadata, err := json.Marshal(change)
+	if err != nil {
+		return nil, fmt.Errorf("marshal change: %w", err)
+	}
+
+	view := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      interactions.RBACCallbackID,
+		PrivateMetadata: string(metadata),
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Confirm permissions", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, submit, false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, prompt, false, false), nil, nil),
+		}},
+	}
+	if err := h.slackClient.OpenView(ctx, cmd.TriggerID, view); err != nil {
+		return nil, err
+	}
+
+	return commands.Ephemeral("Confirm the change in the dialog."), nil
+}
+
+// roles lists users with elevated permission profiles
+func (h *commandHandlers) roles(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if len(cmd.Args) > 0 {
+		userID, ok := commands.ParseUser(cmd.Args[0])
+		if !ok {
+			return commands.Ephemeral("Usage: `/cloudops roles [@user]`"), nil
+		}
+		profile, err := h.profileOf(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("check permissions: %w", err)
+		}
+		return commands.Ephemeral("<@%s> is *%s*.", userID, profile), nil
+	}
+
+	profiles, err := h.permRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Profile != profiles[j].Profile {
+			return profiles[i].Profile < profiles[j].Profile
+		}
+		return profiles[i].GrantedAt.Before(profiles[j].GrantedAt)
+	})
+
+	var b strings.Builder
+	b.WriteString("*Permission profiles*\n")
+	for _, id := range h.cfg.AdminUsers {
+		fmt.Fprintf(&b, "• <@%s> %s (`ADMIN_USERS`)\n", id, models.ProfileAdmin)
+	}
+	for _, p := range profiles {
+		if p.IsBreakGlass() {
+			fmt.Fprintf(&b, "• <@%s> %s by break-glass until %s: %s\n", p.UserID, p.Profile, p.ExpiresAt.UTC().Format("Jan 2 15:04 MST"), p.Reason)
+			continue
+		}
+		fmt.Fprintf(&b, "• <@%s> %s, granted by <@%s> on %s\n", p.UserID, p.Profile, p.GrantedBy, p.GrantedAt.UTC().Format("Jan 2 2006"))
+	}
+	if len(h.cfg.AdminUsers) == 0 && len(profiles) == 0 {
+		b.WriteString("No one has elevated permissions. Everyone is read-only.\n")
+	}
+	return commands.Ephemeral("%s", b.String()), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// runbook saves the channel's conversation as a runbook draft, or lists and
+// reviews drafts
+func (h *commandHandlers) runbook(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if len(cmd.Args) > 0 {
+		switch cmd.Args[0] {
+		case "drafts":
+			return h.runbookDrafts(ctx)
+		case "publish":
+			return h.reviewRunbook(ctx, cmd, models.RunbookPublished)
+		case "discard":
+			return h.reviewRunbook(ctx, cmd, models.RunbookDiscarded)
+		}
+	}
+
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	rb, created, err := h.interactions.CaptureRunbook(ctx, conv, cmd.UserID, cmd.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return commands.Ephemeral("`%s` was already saved as runbook `%s` (%s).", conv.ConversationID, rb.RunbookID, rb.Status), nil
+	}
+	return commands.Ephemeral("📘 Saved runbook draft `%s` with %d steps. Publish it with `/cloudops runbook publish %s`.",
+		rb.RunbookID, len(rb.Steps), rb.RunbookID), nil
+}
+
+// runbookDrafts lists drafts awaiting review
+func (h *commandHandlers) runbookDrafts(ctx context.Context) (*commands.Response, error) {
+	drafts, err := h.runbookRepo.ListByStatus(ctx, models.RunbookDraft, 20)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return commands.Ephemeral("No runbook drafts are waiting for review."), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Runbook drafts awaiting review* (%d)\n", len(drafts))
+	for _, rb := range drafts {
+		fmt.Fprintf(&b, "• `%s` %s, from `%s` by <@%s>\n", rb.RunbookID, rb.Title, rb.ConversationID, rb.CreatedBy)
+	}
+	return commands.Ephemeral("%s", b.String()), nil
+}
+
+// reviewRunbook publishes or discards a draft and records who did it
+func (h *commandHandlers) reviewRunbook(ctx context.Context, cmd *commands.Command, status string) (*commands.Response, error) {
+	if len(cmd.Args) < 2 {
+		return commands.Ephemeral("Usage: `/cloudops runbook %s <runbook-id>`", cmd.Args[0]), nil
+	}
+
+	rb, err := h.runbookRepo.Get(ctx, cmd.Args[1])
+	if err != nil {
+		return nil, err
+	}
+	if rb == nil {
+		return commands.Ephemeral("Couldn't find runbook `%s`.", cmd.Args[1]), nil
+	}
+	if rb.Status != models.RunbookDraft {
+		return commands.Ephemeral("`%s` was already %s by <@%s>.", rb.RunbookID, rb.Status, rb.ReviewedBy), nil
+	}
+
+	if err := h.runbookRepo.Review(ctx, rb, status, cmd.UserID); err != nil {
+		return nil, err
+	}
+
+	event := models.NewAuditEvent(models.AuditRunbookReview, cmd.UserID, rb.RunbookID)
+	event.Details["status"] = status
+	event.Details["conversation_id"] = rb.ConversationID
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit runbook review", "runbook_id", rb.RunbookID, "error", err)
+	}
+
+	if status == models.RunbookDiscarded {
+		return commands.Ephemeral("🗑️ Discarded runbook draft `%s`.", rb.RunbookID), nil
+	}
+	return commands.InChannel("📘 <@%s> published runbook `%s`: %s", cmd.UserID, rb.RunbookID, rb.Title), nil
+}
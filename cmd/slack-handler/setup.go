@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/setup"
+)
+
+// setup runs the self-check suite and DMs the admin a checklist of what to
+// fix, with a button to check again
+func (h *commandHandlers) setup(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if refusal, err := h.requireAdmin(ctx, cmd.UserID); refusal != nil || err != nil {
+		return refusal, err
+	}
+
+	results, err := h.setupWizard.Start(ctx, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if failed := len(setup.Failed(results)); failed > 0 {
+		return commands.Ephemeral("🧭 I've sent you the setup checklist in a DM: %d of %d checks need attention.", failed, len(results)), nil
+	}
+	return commands.Ephemeral("✅ All %d setup checks pass. I've sent you the details in a DM.", len(results)), nil
+}
+
+// handleAppHomeOpened starts the setup wizard the first time an admin
+// opens the bot after installing it, until every check has passed once
+func handleAppHomeOpened(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	if !contains(cfg.AdminUsers, event.User) {
+		return nil
+	}
+
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	status, err := h.settingsRepo.GetSetup(ctx)
+	switch {
+	case err != nil && setup.Misconfigured(err):
+		// Without the settings table there's nowhere to remember the prompt,
+		// and the missing table is the first thing to fix
+		_, err = h.setupWizard.Start(ctx, event.User)
+		return err
+	case err != nil:
+		return fmt.Errorf("get setup: %w", err)
+	case status.Completed():
+		return nil
+	}
+	return h.setupWizard.Prompt(ctx, []string{event.User})
+}
+
+// promptSetup DMs the setup checklist to the admins after a mention failed
+// on missing configuration
+func promptSetup(ctx context.Context, cfg *appconfig.Config) {
+	h, err := newCommandHandlers(ctx, cfg)
+	if err == nil {
+		err = h.setupWizard.Prompt(ctx, cfg.AdminUsers)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to prompt admins to finish setup", "error", err)
+	}
+}
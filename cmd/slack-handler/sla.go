@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/runbook"
+	"github.com/savaki/cloudops-bot/pkg/sla"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// startSLA starts or re-targets SLA timers when a conversation carries a
+// severity tag. Failures are logged so tagging itself still succeeds
+func (h *commandHandlers) startSLA(ctx context.Context, conv *models.Conversation) {
+	policies := h.cfg.SLAPolicies()
+	severity, ok := policies.Severity(conv.Tags)
+	if !ok || !policies.Start(conv, severity, time.Now()) {
+		return
+	}
+
+	if err := h.convRepo.UpdateSLA(ctx, conv.ConversationID, conv.SLA); err != nil {
+		slog.WarnContext(ctx, "failed to start SLA", logging.ConversationID, conv.ConversationID, "error", err)
+		return
+	}
+
+	text := fmt.Sprintf("⏱️ *%s SLA started*: acknowledge by %s and resolve by %s UTC. Use `/cloudops ack` and `/cloudops resolve`.",
+		strings.ToUpper(severity), conv.SLA.AckDue.UTC().Format("15:04"), conv.SLA.ResolveDue.UTC().Format("Jan 2 15:04"))
+	if _, err := h.slackClient.PostMessage(ctx, conv.ChannelID, slack.MsgOptionText(text, false), slackclient.InThread(conv.ThreadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to announce SLA", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+}
+
+// ack records acknowledgment of the channel's incident
+func (h *commandHandlers) ack(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if conv.SLA == nil {
+		return commands.Ephemeral("`%s` has no SLA. Tag it with a severity (e.g. `/cloudops tag sev2`) to start one.", conv.ConversationID), nil
+	}
+
+	if !sla.Ack(conv.SLA, cmd.UserID, time.Now()) {
+		return commands.Ephemeral("Already acknowledged by <@%s>.", conv.SLA.AckedBy), nil
+	}
+	if err := h.convRepo.UpdateSLA(ctx, conv.ConversationID, conv.SLA); err != nil {
+		return nil, fmt.Errorf("acknowledge incident: %w", err)
+	}
+
+	elapsed := conv.SLA.AckedAt.Sub(conv.SLA.StartedAt).Round(time.Second)
+	if conv.SLA.AckBreached {
+		return commands.InChannel("👀 <@%s> acknowledged this incident after %s (ack SLA missed).", cmd.UserID, humanize.Duration(elapsed)), nil
+	}
+	return commands.InChannel("👀 <@%s> acknowledged this incident after %s.", cmd.UserID, humanize.Duration(elapsed)), nil
+}
+
+// resolve stops the SLA timers and records the outcome
+func (h *commandHandlers) resolve(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if conv.SLA == nil {
+		return commands.Ephemeral("`%s` has no SLA to resolve.", conv.ConversationID), nil
+	}
+
+	if !sla.Resolve(conv.SLA, cmd.UserID, time.Now()) {
+		return commands.Ephemeral("`%s` is already resolved.", conv.ConversationID), nil
+	}
+	if err := h.convRepo.UpdateSLA(ctx, conv.ConversationID, conv.SLA); err != nil {
+		return nil, fmt.Errorf("resolve incident: %w", err)
+	}
+
+	outcome, _ := sla.Outcome(conv)
+	if err := h.slaRepo.SaveOutcome(ctx, outcome); err != nil {
+		slog.WarnContext(ctx, "failed to record SLA outcome", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+
+	result := "within SLA"
+	if outcome.ResolveBreached {
+		result = "resolution SLA missed"
+	}
+	resp := commands.InChannel("✅ <@%s> resolved this incident after %s (%s).",
+		cmd.UserID, humanize.Duration(time.Duration(outcome.TimeToResolveSeconds)*time.Second), result)
+	resp.Blocks = []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, resp.Text, false, false), nil, nil),
+		runbook.Offer(conv.ConversationID),
+	}
+	return resp, nil
+}
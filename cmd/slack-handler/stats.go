@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/stats"
+)
+
+// statsStatuses are the statuses whose conversations the report counts
+var statsStatuses = []string{models.StatusPending, models.StatusActive, models.StatusCompleted, models.StatusFailed, models.StatusTimeout}
+
+// stats reports the bot's usage today, over the last 7 days, or over a time
+// range such as "last 3 days"
+func (h *commandHandlers) stats(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	label, start, end, ok := stats.Period(strings.Join(cmd.Args, " "), time.Now(), cmd.Location)
+	if !ok {
+		r, rest, found := cmd.TimeRange()
+		if !found || len(rest) > 0 {
+			return commands.Ephemeral("Usage: `/cloudops stats [today|week|<time range>]`, e.g. `/cloudops stats last 3 days`"), nil
+		}
+		label, start, end = strings.Join(cmd.Args, " "), r.Start, r.End
+	}
+
+	var convs []*models.Conversation
+	for _, status := range statsStatuses {
+		found, err := h.convRepo.GetByStatusSince(ctx, status, start)
+		if err != nil {
+			return nil, fmt.Errorf("list %s conversations: %w", status, err)
+		}
+		convs = append(convs, found...)
+	}
+
+	var tools []*models.ToolExecution
+	if h.cfg.ToolAuditTable != "" {
+		var err error
+		if tools, err = h.auditRepo.ListToolExecutions(ctx, "", start, end); err != nil {
+			return nil, fmt.Errorf("list tool executions: %w", err)
+		}
+	}
+
+	report := stats.Build(label, start, end, convs, tools, h.cfg.ChargebackRates())
+	report.ToolsTracked = h.cfg.ToolAuditTable != ""
+
+	resp := commands.Ephemeral("%s", report.Summary())
+	resp.Blocks = report.Blocks(cmd.Location)
+	return resp, nil
+}
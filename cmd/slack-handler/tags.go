@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// applyTags adds tags to a conversation record, indexes them by tag, and
+// pulls in the on-call for any tag that names a team
+func (h *commandHandlers) applyTags(ctx context.Context, conv *models.Conversation, userID string, tags []string) ([]string, error) {
+	added := conv.AddTags(tags...)
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := h.convRepo.UpdateTags(ctx, conv.ConversationID, conv.Tags); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, tag := range added {
+		err := h.tagRepo.Add(ctx, &models.ConversationTag{
+			ConversationID: conv.ConversationID,
+			Tag:            tag,
+			ChannelID:      conv.ChannelID,
+			TaggedBy:       userID,
+			CreatedAt:      now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tag := range added {
+		h.includeOnCall(ctx, conv, tag)
+	}
+	h.startSLA(ctx, conv)
+
+	return added, nil
+}
+
+// formatTags renders tags as "#a #b"
+func formatTags(tags []string) string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = "#" + tag
+	}
+	return strings.Join(out, " ")
+}
+
+// tag adds tags to the channel's conversation, or lists them when none are given
+func (h *commandHandlers) tag(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	var tags []string
+	for _, arg := range cmd.Args {
+		if strings.HasPrefix(arg, "conv-") {
+			continue
+		}
+		tag, ok := models.NormalizeTag(arg)
+		if !ok {
+			return commands.Ephemeral("`%s` isn't a valid tag. Use letters, numbers, `-` and `_` (max %d characters).", arg, models.MaxTagLength), nil
+		}
+		tags = append(tags, tag)
+	}
+
+	if len(tags) == 0 {
+		if len(conv.Tags) == 0 {
+			return commands.Ephemeral("`%s` has no tags.", conv.ConversationID), nil
+		}
+		return commands.Ephemeral("`%s` is tagged %s", conv.ConversationID, formatTags(conv.Tags)), nil
+	}
+
+	added, err := h.applyTags(ctx, conv, cmd.UserID, tags)
+	if err != nil {
+		return nil, fmt.Errorf("tag conversation: %w", err)
+	}
+	if len(added) == 0 {
+		return commands.Ephemeral("Already tagged %s", formatTags(tags)), nil
+	}
+
+	return commands.InChannel("🏷️ <@%s> tagged this conversation %s", cmd.UserID, formatTags(added)), nil
+}
+
+// untag removes tags from the channel's conversation
+func (h *commandHandlers) untag(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	var removed []string
+	for _, arg := range cmd.Args {
+		tag, ok := models.NormalizeTag(arg)
+		if !ok || !conv.RemoveTag(tag) {
+			continue
+		}
+		if err := h.tagRepo.Remove(ctx, conv.ConversationID, tag); err != nil {
+			return nil, fmt.Errorf("untag conversation: %w", err)
+		}
+		removed = append(removed, tag)
+	}
+
+	if len(removed) == 0 {
+		return commands.Ephemeral("None of those tags are on `%s`.", conv.ConversationID), nil
+	}
+	if err := h.convRepo.UpdateTags(ctx, conv.ConversationID, conv.Tags); err != nil {
+		return nil, fmt.Errorf("untag conversation: %w", err)
+	}
+
+	return commands.Ephemeral("Removed %s", formatTags(removed)), nil
+}
+
+// tagged lists recent conversations with a tag
+func (h *commandHandlers) tagged(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if len(cmd.Args) == 0 {
+		return commands.Ephemeral("Usage: `/cloudops tagged #tag`"), nil
+	}
+	tag, ok := models.NormalizeTag(cmd.Args[0])
+	if !ok {
+		return commands.Ephemeral("`%s` isn't a valid tag.", cmd.Args[0]), nil
+	}
+
+	items, err := h.tagRepo.ListByTag(ctx, tag, 10)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return commands.Ephemeral("No conversations tagged #%s.", tag), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recent conversations tagged #%s:", tag)
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n• `%s` in <#%s> (%s)", item.ConversationID, item.ChannelID, item.CreatedAt.Format("Jan 2 15:04"))
+	}
+	return commands.Ephemeral("%s", b.String()), nil
+}
+
+// handleReactionAdded tags a conversation when someone reacts with an emoji
+// configured in REACTION_TAGS
+func handleReactionAdded(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	tag, ok := cfg.ReactionTags[event.Reaction]
+	if !ok || event.Item == nil {
+		return nil
+	}
+	tag, ok = models.NormalizeTag(tag)
+	if !ok {
+		slog.WarnContext(ctx, "invalid tag configured for reaction", "reaction", event.Reaction)
+		return nil
+	}
+
+	h, err := newCommandHandlers(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Reactions don't say which thread a reply is in, so only the message
+	// starting a thread finds the conversation confined to it
+	conv, err := h.convRepo.GetByMessage(ctx, event.Item.Channel, event.Item.TS)
+	if err != nil {
+		// Reactions outside conversation channels are expected
+		return nil
+	}
+
+	added, err := h.applyTags(ctx, conv, event.User, []string{tag})
+	if err != nil {
+		return fmt.Errorf("tag conversation: %w", err)
+	}
+	if len(added) > 0 {
+		msg := fmt.Sprintf("🏷️ <@%s> tagged this conversation %s", event.User, formatTags(added))
+		if _, err := h.slackClient.PostMessage(ctx, event.Item.Channel, slack.MsgOptionText(msg, false), slack.MsgOptionTS(event.Item.TS)); err != nil {
+			slog.WarnContext(ctx, "failed to confirm tag", "error", err)
+		}
+	}
+
+	return nil
+}
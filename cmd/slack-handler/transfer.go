@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/savaki/cloudops-bot/pkg/transfer"
+	"github.com/slack-go/slack"
+)
+
+// transfer moves this channel's conversation to another channel, or to a
+// new incident channel, where the agent carries on with the same context
+func (h *commandHandlers) transfer(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if conv.Ended() {
+		return commands.Ephemeral("`%s` has already ended. Mention me in the other channel to start a new conversation.", conv.ConversationID), nil
+	}
+
+	var target string
+	create := false
+	for _, arg := range cmd.Args {
+		if id, ok := commands.ParseChannel(arg); ok {
+			target = id
+		} else if strings.EqualFold(arg, "new") {
+			create = true
+		}
+	}
+
+	var visibility string
+	switch {
+	case create:
+		visibility = privacy.Private
+	case target == "":
+		return commands.Ephemeral("Usage: `/cloudops transfer <#channel|new> [conversation-id]`"), nil
+	case target == conv.ChannelID:
+		return commands.Ephemeral("`%s` is already in <#%s>.", conv.ConversationID, target), nil
+	default:
+		var resp *commands.Response
+		if visibility, resp = h.checkTransferTarget(ctx, conv, target); resp != nil {
+			return resp, nil
+		}
+	}
+	if reason := transfer.Check(conv, cmd.UserID, visibility); reason != "" {
+		return commands.Ephemeral("%s", reason), nil
+	}
+	if create {
+		if target, err = h.newIncidentChannel(ctx, conv, cmd.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	from := conv.ChannelID
+	if err := h.convRepo.Transfer(ctx, conv.ConversationID, from, target, visibility); err != nil {
+		if errors.Is(err, storage.ErrConversationMoved) {
+			return commands.Ephemeral("`%s` was just moved somewhere else.", conv.ConversationID), nil
+		}
+		return nil, err
+	}
+
+	// The notice goes first: the agent polling the old channel switches to
+	// the new one from this point, so nothing posted there afterwards is missed
+	if _, err := h.slackClient.PostMessage(ctx, from,
+		slack.MsgOptionText(transfer.Notice(target, cmd.UserID), false),
+		slack.MsgOptionMetadata(transfer.Metadata(conv.ConversationID, target, visibility)),
+		slackclient.InThread(conv.ThreadTS),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to post transfer notice", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+
+	var permalink string
+	if conv.MessageTS != "" {
+		if permalink, err = h.slackClient.GetPermalink(ctx, conv.MessageChannelID(), conv.MessageTS); err != nil {
+			slog.WarnContext(ctx, "failed to link original request", logging.ConversationID, conv.ConversationID, "error", err)
+		}
+	}
+	if _, err := h.slackClient.PostMessage(ctx, target, slack.MsgOptionText(transfer.Context(conv, from, cmd.UserID, permalink), false)); err != nil {
+		slog.WarnContext(ctx, "failed to post transfer context", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+
+	return commands.Ephemeral("Moved `%s` to <#%s>.", conv.ConversationID, target), nil
+}
+
+// checkTransferTarget returns the visibility of channelID, and a reason a
+// conversation can't move there or nil when it can
+func (h *commandHandlers) checkTransferTarget(ctx context.Context, conv *models.Conversation, channelID string) (string, *commands.Response) {
+	channel, err := h.slackClient.GetChannelInfo(ctx, channelID)
+	if err != nil || !channel.IsMember {
+		return "", commands.Ephemeral("I'm not in <#%s>. Invite me there first, then try again.", channelID)
+	}
+	visibility := privacy.ForChannel(channel)
+
+	// Slash commands find the conversation with the channel to itself, so
+	// each channel holds one at a time besides those confined to threads
+	if other, err := h.convRepo.GetByChannelID(ctx, channelID); err == nil && !other.Ended() && other.ConversationID != conv.ConversationID {
+		return visibility, commands.Ephemeral("<#%s> already has a conversation in progress (`%s`).", channelID, other.ConversationID)
+	}
+	return visibility, nil
+}
+
+// newIncidentChannel creates a private channel for a conversation and
+// invites everyone who has taken part in it
+func (h *commandHandlers) newIncidentChannel(ctx context.Context, conv *models.Conversation, userID string) (string, error) {
+	channelID, err := handler.NewChannelCreator(h.slackClient).CreateConversationChannel(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	var others []string
+	for _, p := range append([]string{conv.UserID}, conv.Participants...) {
+		if p != userID && !contains(others, p) {
+			others = append(others, p)
+		}
+	}
+	if len(others) > 0 {
+		if err := h.slackClient.InviteUsersToConversation(ctx, channelID, others...); err != nil {
+			slog.WarnContext(ctx, "failed to invite participants", logging.ChannelID, channelID, "error", err)
+		}
+	}
+	return channelID, nil
+}
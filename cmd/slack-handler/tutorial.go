@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/tutorial"
+)
+
+// tutorial writes an anonymized walkthrough of this channel's resolved
+// conversation for the team wiki and posts it as an editable snippet. The
+// tool audit log is its record of what was checked, so it needs one
+func (h *commandHandlers) tutorial(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	if h.cfg.ToolAuditTable == "" {
+		return commands.Ephemeral("Tutorials are written from the tool audit log, which isn't configured (TOOL_AUDIT_TABLE)."), nil
+	}
+
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+	if !tutorial.Resolved(conv) {
+		return commands.Ephemeral("`%s` is still open. Write its tutorial once it is resolved.", conv.ConversationID), nil
+	}
+	if resp := h.later(ctx, cmd, fmt.Sprintf("🎓 Writing a tutorial for `%s`. I'll post it here shortly.", conv.ConversationID)); resp != nil {
+		return resp, nil
+	}
+
+	history, err := h.convRepo.GetHistoryItems(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("load history: %w", err)
+	}
+	calls, err := h.auditRepo.ListConversationToolExecutions(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("list tool executions: %w", err)
+	}
+	if len(calls) == 0 {
+		return commands.Ephemeral("`%s` made no tool calls, so there are no checks to teach from.", conv.ConversationID), nil
+	}
+
+	tut, err := tutorial.NewDrafter(h.bedrock).Draft(ctx, conv, history, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("tutorial-%s.md", conv.ConversationID)
+	if err := h.slackClient.UploadFile(ctx, cmd.ChannelID, "", filename, tut.Title, []byte(tut.Markdown())); err != nil {
+		return nil, fmt.Errorf("upload tutorial: %w", err)
+	}
+	return commands.Ephemeral("🎓 Tutorial for `%s` posted with %d steps. Review it before adding it to the wiki.", conv.ConversationID, len(tut.Steps)), nil
+}
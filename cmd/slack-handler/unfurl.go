@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	"github.com/savaki/cloudops-bot/pkg/unfurl"
+)
+
+// handleLinkShared unfurls AWS console links into cards summarizing the
+// linked resources. Links in a message still being written are left alone,
+// since the card would be read before anyone asked for it
+func handleLinkShared(ctx context.Context, cfg *appconfig.Config, event models.SlackEventBody) error {
+	if event.Source == "composer" || len(event.Links) == 0 {
+		return nil
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("load aws config: %w", err)
+	}
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+
+	// Nothing reads AWS while an admin has the bot disabled
+	settingsRepo := dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable)
+	settingsRepo.SetFaultInjector(cfg.FaultInjector())
+	if ks, err := settingsRepo.GetKillSwitch(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to read kill switch", "error", err)
+	} else if ks != nil && ks.Disabled {
+		return nil
+	}
+
+	// In sandbox mode the tools read the demo account instead
+	toolsCfg := awsCfg
+	if cfg.Sandbox() {
+		toolsCfg = sandbox.Config(awsCfg, cfg.SandboxRoleARN, cfg.SandboxExternalID)
+	}
+	links := make([]string, len(event.Links))
+	for i, link := range event.Links {
+		links[i] = link.URL
+	}
+	unfurls := unfurl.New(toolsCfg).Unfurl(ctx, links)
+	if len(unfurls) == 0 {
+		return nil
+	}
+
+	slackClient, err := newSlackClient(ctx, cfg, ddbClient)
+	if err != nil {
+		return err
+	}
+	if faults := cfg.FaultInjector(); faults != nil {
+		slackClient.SetFaultInjector(faults)
+	}
+	return slackClient.Unfurl(ctx, event.Channel, event.MessageTS, unfurls)
+}
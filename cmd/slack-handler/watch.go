@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/commands"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// watch subscribes the user to DM notifications for a conversation
+func (h *commandHandlers) watch(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	var keywords []string
+	for _, arg := range cmd.Args {
+		if arg != conv.ConversationID {
+			keywords = append(keywords, arg)
+		}
+	}
+
+	if err := h.subRepo.Save(ctx, models.NewSubscription(conv.ConversationID, cmd.UserID, keywords)); err != nil {
+		return nil, fmt.Errorf("save subscription: %w", err)
+	}
+
+	msg := fmt.Sprintf("👀 Watching `%s`. I'll DM you when its status changes", conv.ConversationID)
+	if len(keywords) > 0 {
+		msg += fmt.Sprintf(" or when %s come up", strings.Join(keywords, ", "))
+	}
+	return commands.Ephemeral("%s.", msg), nil
+}
+
+// unwatch removes the user's subscription to a conversation
+func (h *commandHandlers) unwatch(ctx context.Context, cmd *commands.Command) (*commands.Response, error) {
+	conv, err := h.findConversation(ctx, cmd)
+	if err != nil {
+		return commands.Ephemeral(noConversationMessage), nil
+	}
+
+	if err := h.subRepo.Delete(ctx, conv.ConversationID, cmd.UserID); err != nil {
+		return nil, fmt.Errorf("delete subscription: %w", err)
+	}
+
+	return commands.Ephemeral("Stopped watching `%s`.", conv.ConversationID), nil
+}
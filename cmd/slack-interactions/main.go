@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/interactions"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+)
+
+// Handler is the Lambda handler for Slack's interactivity requests: button
+// clicks and modal submissions. Like the Slack handler, it accepts
+// invocations from API Gateway, a Lambda Function URL, or an ALB target group
+func Handler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	request, err := handler.DecodeRequest(payload)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to decode request", "error", err)
+		return handler.EncodeResponse(handler.SourceAPIGateway, handler.ErrorResponse(400, handler.CodeInvalidRequest, "Invalid request")), nil
+	}
+
+	return handler.EncodeResponse(request.Source, handle(ctx, request)), nil
+}
+
+// handle processes an interaction independent of the Lambda entrypoint
+func handle(ctx context.Context, request *handler.Request) *handler.Response {
+	slog.InfoContext(ctx, "received slack interaction", "source", request.Source)
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		return configError(ctx, "Failed to load config", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return configError(ctx, "Invalid config", err)
+	}
+
+	// Turn away callers outside the allowed networks or without a trusted
+	// client certificate before doing any other work
+	if err := cfg.AccessPolicy().Check(request); err != nil {
+		slog.WarnContext(ctx, "rejected request", "source", request.Source, "error", err)
+		return handler.ErrorResponse(403, handler.CodeForbidden, "Forbidden")
+	}
+
+	ih := handler.NewInteractionHandler(cfg.SigningKeys()...)
+	if !ih.Verify(request) {
+		slog.WarnContext(ctx, "invalid slack signature")
+		return handler.ErrorResponse(401, handler.CodeInvalidSignature, "Invalid signature")
+	}
+	if !handler.IsInteraction(request) {
+		return handler.ErrorResponse(400, handler.CodeInvalidRequest, "Not an interaction")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return configError(ctx, "Failed to load AWS config", err)
+	}
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	slackClient, err := newSlackClient(ctx, cfg, ddbClient)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to initialize slack client", "error", err)
+		return handler.ErrorResponse(500, handler.CodeInternal, "Failed to initialize Slack client")
+	}
+	if faults := cfg.FaultInjector(); faults != nil {
+		slackClient.SetFaultInjector(faults)
+	}
+
+	interactions.New(cfg, awsCfg, ddbClient, slackClient).Register(ih)
+	return ih.Dispatch(ctx, request.Body)
+}
+
+// newSlackClient returns a client for the current bot token, which is
+// read from the token table when Slack token rotation is enabled
+func newSlackClient(ctx context.Context, cfg *appconfig.Config, ddbClient *awsdynamodb.Client) (*slackclient.Client, error) {
+	slackClient := slackclient.NewClient(cfg.SlackBotToken)
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			return nil, fmt.Errorf("get slack token: %w", err)
+		}
+	}
+	return slackClient, nil
+}
+
+// configError returns a 500 for misconfiguration; retrying can't fix it
+func configError(ctx context.Context, message string, err error) *handler.Response {
+	slog.ErrorContext(ctx, message, "error", err)
+	return handler.ErrorResponse(500, handler.CodeConfigError, message)
+}
+
+func main() {
+	logging.Setup("slack-interactions")
+	lambda.Start(Handler)
+}
@@ -0,0 +1,980 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/savaki/cloudops-bot/pkg/agent"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/correlate"
+	"github.com/savaki/cloudops-bot/pkg/debugmode"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/ensemble"
+	"github.com/savaki/cloudops-bot/pkg/followups"
+	"github.com/savaki/cloudops-bot/pkg/fulloutput"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/iampolicy"
+	"github.com/savaki/cloudops-bot/pkg/intake"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/killswitch"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+	"github.com/savaki/cloudops-bot/pkg/report"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	cwtool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatch"
+	logstool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatchlogs"
+	ec2tool "github.com/savaki/cloudops-bot/pkg/tools/ec2"
+	ecstool "github.com/savaki/cloudops-bot/pkg/tools/ecs"
+	"github.com/savaki/cloudops-bot/pkg/unfurl"
+	"github.com/savaki/cloudops-bot/pkg/watch"
+	"github.com/savaki/cloudops-bot/pkg/webhook"
+	"github.com/savaki/cloudops-bot/pkg/workerpool"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// How often idle sessions are ended and pool load is logged
+const (
+	reapInterval  = 30 * time.Second
+	statsInterval = time.Minute
+)
+
+// session is a conversation being handled in this process
+type session struct {
+	agent        *agent.Agent
+	conversation *models.Conversation
+	startTS      string // the mention that started it; earlier messages are ignored
+	lastActivity time.Time
+
+	// Messages waiting for the sender to pause, and whether a turn is queued
+	// or running. One turn at a time per conversation keeps replies in order
+	pending *coalesce.Buffer
+	timer   *time.Timer
+	busy    bool
+}
+
+// server runs conversations in-process over Socket Mode instead of
+// launching an ECS task per conversation
+type server struct {
+	cfg         *appconfig.Config
+	awsCfg      aws.Config
+	toolsCfg    aws.Config // the demo account's in sandbox mode
+	convRepo    storage.Store
+	promptRepo  *dynamodb.PromptRepository
+	usageRepo   *dynamodb.UsageRepository
+	permRepo    *dynamodb.PermissionRepository
+	auditRepo   *dynamodb.AuditRepository // nil unless TOOL_AUDIT_TABLE is set
+	rbac        *rbac.Enforcer
+	killSwitch  *killswitch.Switch
+	ensemble    *ensemble.Ensemble // nil unless ENSEMBLE_MODEL_ID is set
+	outputs     *fulloutput.Store  // nil unless OUTPUTS_BUCKET is set
+	slackClient *slackclient.Client
+	bedrock     *bedrock.Client
+	notifier    *watch.Notifier
+	unfurler    *unfurl.Unfurler
+	webhooks    *webhook.Notifier // nil unless WEBHOOK_URLS is set
+	jobs        *jobs.Manager     // nil unless JOBS_TABLE is set
+	pool        *workerpool.Pool
+	botUserID   string
+
+	mu       sync.Mutex
+	sessions map[string]*session // by sessionKey
+}
+
+func main() {
+	logging.Setup("standalone")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := appconfig.Load()
+	if err != nil {
+		logging.Fatal(ctx, "failed to load config", "error", err)
+	}
+	if err := cfg.ValidateStandalone(); err != nil {
+		logging.Fatal(ctx, "invalid standalone config", "error", err)
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		logging.Fatal(ctx, "failed to load AWS config", "error", err)
+	}
+
+	ddbClient := dynamodb.NewClientWithConfig(awsCfg)
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	convRepo.SetHistoryTable(cfg.ConversationHistoryTable)
+	subRepo := dynamodb.NewSubscriptionRepository(ddbClient, cfg.SubscriptionsTable)
+	promptRepo := dynamodb.NewPromptRepository(ddbClient, cfg.PromptsTable)
+	usageRepo := dynamodb.NewUsageRepository(ddbClient, cfg.UsageTable)
+	permRepo := dynamodb.NewPermissionRepository(ddbClient, cfg.PermissionsTable)
+	settingsRepo := dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable)
+	auditRepo := dynamodb.NewAuditRepository(ddbClient, cfg.AuditTable)
+	auditRepo.SetToolTable(cfg.ToolAuditTable)
+	slackClient := slackclient.NewClientWithAppToken(cfg.SlackBotToken, cfg.SlackAppToken)
+	bedrockClient := bedrock.NewClient(awsCfg)
+	bedrockClient.SetModel(cfg.BedrockModelID)
+
+	// In sandbox mode the tools read the demo account instead
+	toolsCfg := awsCfg
+	if cfg.Sandbox() {
+		slog.InfoContext(ctx, "sandbox mode", "role_arn", cfg.SandboxRoleARN)
+		toolsCfg = sandbox.Config(awsCfg, cfg.SandboxRoleARN, cfg.SandboxExternalID)
+	}
+	bedrockClient.RegisterTool(ec2tool.New(toolsCfg))
+	bedrockClient.RegisterTool(ecstool.New(toolsCfg))
+	logsTool := logstool.New(toolsCfg)
+	bedrockClient.RegisterTool(logsTool)
+	bedrockClient.RegisterTool(cwtool.New(toolsCfg))
+	diagnoser := diagnose.New(toolsCfg)
+	bedrockClient.SetDiagnoser(diagnoser)
+	if cfg.IAMSuggestionChannel != "" {
+		diagnoser.SetDenialHandler(iampolicy.NewSuggester(settingsRepo, slackClient, cfg.IAMSuggestionChannel, cfg.IAMSuggestionThreshold))
+	}
+	if cfg.EmbeddingModelID != "" {
+		bedrockClient.SetEmbeddingModel(cfg.EmbeddingModelID)
+	}
+
+	// Rotated bot tokens expire every 12 hours; refresh them for as long as we run
+	if cfg.TokenRotation() {
+		rotator := slackclient.NewRotator(dynamodb.NewSlackTokenRepository(ddbClient, cfg.SlackTokensTable), cfg.SlackClientID, cfg.SlackClientSecret, cfg.SlackRefreshToken)
+		if err := rotator.Apply(ctx, slackClient); err != nil {
+			logging.Fatal(ctx, "failed to get slack bot token", "error", err)
+		}
+		go rotator.Run(ctx, slackClient)
+	}
+
+	// Fault injection for resilience testing (never enabled in production)
+	if faults := cfg.FaultInjector(); faults != nil {
+		slog.InfoContext(ctx, "fault injection enabled", "latency_ms", cfg.ChaosLatencyMs, "error_rate", cfg.ChaosErrorRate)
+		convRepo.SetFaultInjector(faults)
+		subRepo.SetFaultInjector(faults)
+		promptRepo.SetFaultInjector(faults)
+		usageRepo.SetFaultInjector(faults)
+		permRepo.SetFaultInjector(faults)
+		settingsRepo.SetFaultInjector(faults)
+		auditRepo.SetFaultInjector(faults)
+		slackClient.SetFaultInjector(faults)
+		bedrockClient.SetFaultInjector(faults)
+	}
+
+	botUserID, err := slackClient.GetBotUserID(ctx)
+	if err != nil {
+		logging.Fatal(ctx, "failed to get bot user ID", "error", err)
+	}
+
+	// Turns already running get to finish on shutdown, so the pool doesn't
+	// share the signal context
+	s := &server{
+		cfg:         cfg,
+		awsCfg:      awsCfg,
+		toolsCfg:    toolsCfg,
+		convRepo:    convRepo,
+		promptRepo:  promptRepo,
+		usageRepo:   usageRepo,
+		permRepo:    permRepo,
+		killSwitch:  killswitch.New(settingsRepo, killswitch.DefaultInterval),
+		slackClient: slackClient,
+		bedrock:     bedrockClient,
+		notifier:    watch.NewNotifier(subRepo, slackClient),
+		unfurler:    unfurl.New(toolsCfg),
+		pool:        workerpool.New(context.Background(), cfg.WorkerPoolSize, cfg.WorkerMailboxSize, cfg.WorkerQueueSize),
+		botUserID:   botUserID,
+		sessions:    map[string]*session{},
+	}
+
+	if cfg.EnsembleModelID != "" {
+		secondModel := bedrock.NewClient(awsCfg)
+		secondModel.SetModel(cfg.EnsembleModelID)
+		secondModel.SetFaultInjector(cfg.FaultInjector())
+
+		s.ensemble = ensemble.New(bedrockClient, secondModel)
+		s.ensemble.SetCleaner(agent.CleanResponse)
+	}
+	if cfg.OutputsBucket != "" {
+		s.outputs = fulloutput.NewStore(awsCfg, cfg.OutputsBucket)
+	}
+	if cfg.ToolAuditTable != "" {
+		s.auditRepo = auditRepo
+	}
+	if len(cfg.WebhookURLs) > 0 {
+		s.webhooks = webhook.New(cfg.WebhookURLs, cfg.WebhookSecret)
+	}
+	if cfg.RBACPolicy != "" {
+		s.rbac = rbac.Enforce(ctx, cfg.RBACPolicy, ssm.NewFromConfig(awsCfg), settingsRepo, slackClient)
+	}
+
+	// Background jobs run in this process unless the job worker Lambda
+	// runs them
+	var localJobs *jobs.Local
+	if cfg.JobsTable != "" {
+		jobRepo := dynamodb.NewJobRepository(ddbClient, cfg.JobsTable)
+		jobRepo.SetFaultInjector(cfg.FaultInjector())
+		s.jobs = jobs.NewManager(jobRepo, slackClient)
+		s.jobs.Register(logsTool.ScanJob())
+		s.jobs.Register(ec2tool.SweepJob(toolsCfg))
+		if cfg.JobRunner != "lambda" {
+			localJobs = jobs.NewLocal(s.jobs)
+			s.jobs.SetDispatcher(localJobs)
+		}
+		bedrockClient.RegisterTool(s.jobs.StartTool())
+		bedrockClient.RegisterTool(s.jobs.StatusTool())
+	}
+
+	client := socketmode.New(slackClient.GetRawClient())
+	go func() {
+		if err := client.RunContext(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "socket mode connection ended", "error", err)
+			stop()
+		}
+	}()
+
+	slog.InfoContext(ctx, "standalone mode started", "workers", cfg.WorkerPoolSize)
+	s.run(ctx, client)
+
+	slog.InfoContext(ctx, "shutting down, waiting for in-flight turns")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.pool.Close(shutdownCtx); err != nil {
+		slog.WarnContext(ctx, "shutdown timed out with turns still running", "error", err)
+	}
+	if localJobs != nil {
+		localJobs.Close()
+	}
+}
+
+// run dispatches Socket Mode events until ctx is cancelled. The loop itself
+// never calls AWS or Bedrock; all conversation work goes through the pool
+func (s *server) run(ctx context.Context, client *socketmode.Client) {
+	reap := time.NewTicker(reapInterval)
+	defer reap.Stop()
+	stats := time.NewTicker(statsInterval)
+	defer stats.Stop()
+
+	var lastRejected int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reap.C:
+			s.reapIdle()
+		case <-stats.C:
+			st := s.pool.Stats()
+			if st.Mailboxes > 0 || st.Rejected != lastRejected {
+				slog.InfoContext(ctx, "worker pool", "busy", st.Busy, "workers", st.Workers, "queued", st.Queued,
+					"conversations", st.Mailboxes, "rejected", st.Rejected, "max_wait_ms", st.MaxWait.Milliseconds())
+			}
+			lastRejected = st.Rejected
+		case evt := <-client.Events:
+			s.handleEvent(ctx, client, evt)
+		}
+	}
+}
+
+// handleEvent acknowledges Events API and interactive envelopes and routes
+// mentions, channel messages, shared links, and button clicks
+func (s *server) handleEvent(ctx context.Context, client *socketmode.Client, evt socketmode.Event) {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		slog.InfoContext(ctx, "connecting to slack with socket mode")
+	case socketmode.EventTypeConnected:
+		slog.InfoContext(ctx, "connected to slack")
+	case socketmode.EventTypeConnectionError:
+		slog.WarnContext(ctx, "socket mode connection error, retrying")
+	case socketmode.EventTypeEventsAPI:
+		event, ok := evt.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			return
+		}
+		if evt.Request != nil {
+			client.Ack(*evt.Request)
+		}
+
+		switch ev := event.InnerEvent.Data.(type) {
+		case *slackevents.AppMentionEvent:
+			s.handleMention(ctx, ev)
+		case *slackevents.MessageEvent:
+			s.handleMessage(ctx, ev)
+		case *slackevents.LinkSharedEvent:
+			go s.handleLinkShared(ctx, ev)
+		}
+	case socketmode.EventTypeInteractive:
+		callback, ok := evt.Data.(slack.InteractionCallback)
+		if !ok {
+			return
+		}
+		if evt.Request != nil {
+			client.Ack(*evt.Request)
+		}
+		if callback.Type == slack.InteractionTypeMessageAction && callback.CallbackID == intake.ShortcutCallbackID {
+			s.handleShortcut(ctx, &callback)
+			return
+		}
+		if callback.Type != slack.InteractionTypeBlockActions {
+			return
+		}
+
+		for _, action := range callback.ActionCallback.BlockActions {
+			switch {
+			case followups.IsAction(action.ActionID):
+				s.handleFollowUp(ctx, &callback, action.Value)
+			case privacy.IsAction(action.ActionID):
+				s.handlePrivacyChoice(ctx, &callback, action.ActionID, action.Value)
+			case action.ActionID == correlate.MergeActionID:
+				s.handleMerge(ctx, &callback, action.Value)
+			case fulloutput.IsAction(action.ActionID) && s.outputs != nil:
+				if err := s.outputs.Serve(ctx, s.slackClient, callback.Channel.ID, callback.Message.Timestamp, action.Value); err != nil {
+					slog.WarnContext(ctx, "failed to serve full output", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// sessionKey identifies the session answering messages in a channel, or in
+// a thread of it for conversations confined to one
+func sessionKey(channelID, threadTS string) string {
+	if threadTS == "" {
+		return channelID
+	}
+	return models.ThreadKey(channelID, threadTS)
+}
+
+// handleMention starts a conversation in the channel, or in the thread when
+// the mention is a reply. Mentions in a conversation already under way
+// arrive as message events too and are handled there
+func (s *server) handleMention(ctx context.Context, ev *slackevents.AppMentionEvent) {
+	ctx = logging.With(ctx, logging.UserID, ev.User, logging.ChannelID, ev.Channel)
+	s.mu.Lock()
+	_, ok := s.sessions[sessionKey(ev.Channel, ev.ThreadTimeStamp)]
+	s.mu.Unlock()
+	if ok {
+		return
+	}
+
+	// Nothing new starts while an admin has the bot disabled
+	if ks := s.killSwitch.Disabled(ctx); ks != nil {
+		threadTS := ev.ThreadTimeStamp
+		if threadTS == "" {
+			threadTS = ev.TimeStamp
+		}
+		if _, err := s.slackClient.PostMessage(ctx, ev.Channel, slack.MsgOptionText(killswitch.Message(ks), false), slackclient.InThread(threadTS)); err != nil {
+			slog.WarnContext(ctx, "failed to post kill switch notice", "error", err)
+		}
+		return
+	}
+
+	// Visibility is chosen at creation: that of the channel, unless the
+	// requester asks for more with --private or --dm
+	requested, text := privacy.Requested(ev.Text)
+	debug, text := debugmode.Requested(text)
+	visibility := privacy.Public
+	if channel, err := s.slackClient.GetChannelInfo(ctx, ev.Channel); err != nil {
+		slog.WarnContext(ctx, "failed to read channel visibility, treating it as public", "error", err)
+	} else {
+		visibility = privacy.ForChannel(channel)
+	}
+
+	// In thread mode a mention at the top of a channel starts a thread of
+	// its own rather than taking over the channel
+	threadTS := ev.ThreadTimeStamp
+	if threadTS == "" && s.cfg.ThreadPerConversation() && visibility != privacy.DM {
+		threadTS = ev.TimeStamp
+	}
+
+	conv := models.NewConversation(ev.Channel, ev.User, text)
+	ctx = logging.With(ctx, logging.ConversationID, conv.ConversationID)
+	conv.MessageTS = ev.TimeStamp
+	conv.SetThread(threadTS)
+	conv.Visibility = visibility
+	conv.Debug = debug
+	if privacy.Stricter(requested, visibility) {
+		if err := s.withdraw(ctx, conv, requested); err != nil {
+			slog.WarnContext(ctx, "failed to move conversation for privacy, keeping it in place", "error", err)
+		}
+	} else if s.cfg.ChannelPerConversation() && visibility != privacy.DM {
+		if err := s.openChannel(ctx, conv); err != nil {
+			slog.WarnContext(ctx, "failed to open conversation channel, keeping it in place", "error", err)
+		}
+	}
+
+	// Only this loop adds sessions, so the key is still free
+	key := sessionKey(conv.ChannelID, conv.ThreadTS)
+	s.mu.Lock()
+	sess := &session{
+		agent:        s.newAgent(conv),
+		conversation: conv,
+		startTS:      ev.TimeStamp,
+		lastActivity: time.Now(),
+		pending:      coalesce.NewBuffer(s.cfg.GetMessageDebounce()),
+		busy:         true,
+	}
+	s.sessions[key] = sess
+	s.mu.Unlock()
+
+	slog.InfoContext(ctx, "starting conversation")
+	lifecycle.Mark(ctx, s.slackClient, conv, lifecycle.Received)
+	started := s.submit(ctx, sess, func(ctx context.Context) {
+		if err := s.convRepo.Save(ctx, conv); err != nil {
+			slog.ErrorContext(ctx, "failed to save conversation", "error", err)
+		}
+		if err := sess.agent.Start(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to start conversation", "error", err)
+			s.post(ctx, conv, "❌ Failed to start assistant. Please try again.")
+			lifecycle.Mark(ctx, s.slackClient, conv, lifecycle.Failed)
+			conv.UpdateStatus(models.StatusFailed)
+			conv.Error = err.Error()
+			s.webhooks.Send(ctx, webhook.EventFailed, conv)
+			s.end(sess)
+		}
+		s.turnDone(ctx, sess)
+	})
+	if !started {
+		s.end(sess)
+	}
+}
+
+// withdraw moves a new conversation to the requester's DMs or a new private
+// channel when they asked for more privacy than the channel it started in
+// offers, leaving a pointer under the mention
+func (s *server) withdraw(ctx context.Context, conv *models.Conversation, level string) error {
+	var channelID string
+	var err error
+	if level == privacy.DM {
+		channelID, err = s.slackClient.OpenDM(ctx, conv.UserID)
+	} else {
+		channelID, err = handler.NewChannelCreator(s.slackClient).CreateConversationChannel(ctx, conv.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	from, threadTS := conv.ChannelID, conv.ThreadTS
+	if threadTS == "" {
+		threadTS = conv.MessageTS
+	}
+	conv.MoveTo(channelID)
+	conv.Visibility = level
+
+	pointer := fmt.Sprintf("🔒 I'll answer <@%s> in <#%s>.", conv.UserID, channelID)
+	if level == privacy.DM {
+		pointer = fmt.Sprintf("🔒 I'll answer <@%s> in a direct message.", conv.UserID)
+	}
+	if _, err := s.slackClient.PostMessage(ctx, from, slack.MsgOptionText(pointer, false), slackclient.InThread(threadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post privacy pointer", "error", err)
+	}
+	return nil
+}
+
+// openChannel moves a new conversation to a private channel of its own,
+// with the requester invited, leaving a pointer under the mention
+func (s *server) openChannel(ctx context.Context, conv *models.Conversation) error {
+	channelID, err := handler.NewChannelCreator(s.slackClient).CreateConversationChannel(ctx, conv.UserID)
+	if err != nil {
+		return err
+	}
+
+	from, threadTS := conv.ChannelID, conv.ThreadTS
+	if threadTS == "" {
+		threadTS = conv.MessageTS
+	}
+	conv.MoveTo(channelID)
+	conv.Visibility = privacy.Private
+
+	if _, err := s.slackClient.PostMessage(ctx, from, slack.MsgOptionText(intake.ChannelPointer(conv.UserID, channelID), false), slackclient.InThread(threadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post channel pointer", "error", err)
+	}
+
+	permalink, err := s.slackClient.GetPermalink(ctx, from, conv.MessageTS)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to link original request", "error", err)
+	}
+	s.post(ctx, conv, intake.ChannelKickoff(conv, permalink))
+	return nil
+}
+
+// handleMessage buffers a follow-up message for the conversation in its
+// channel or thread until the sender pauses
+func (s *server) handleMessage(ctx context.Context, ev *slackevents.MessageEvent) {
+	if ev.BotID != "" || ev.SubType != "" || ev.User == "" || ev.User == s.botUserID {
+		return
+	}
+
+	s.enqueue(ctx, sessionKey(ev.Channel, ev.ThreadTimeStamp), ev.TimeStamp, coalesce.FromSlack(ev.User, ev.Text, ev.TimeStamp))
+}
+
+// handleLinkShared unfurls AWS console links into cards summarizing the
+// linked resources. It reads AWS, so it runs outside the event loop
+func (s *server) handleLinkShared(ctx context.Context, ev *slackevents.LinkSharedEvent) {
+	// Links in a message still being written carry an ID instead of a
+	// timestamp; the card would be read before anyone asked for it
+	if _, err := strconv.ParseFloat(ev.MessageTimeStamp, 64); err != nil {
+		return
+	}
+	if s.killSwitch.Disabled(ctx) != nil {
+		return
+	}
+
+	links := make([]string, len(ev.Links))
+	for i, link := range ev.Links {
+		links[i] = link.URL
+	}
+	unfurls := s.unfurler.Unfurl(ctx, links)
+	if len(unfurls) == 0 {
+		return
+	}
+	if err := s.slackClient.Unfurl(ctx, ev.Channel, ev.MessageTimeStamp, unfurls); err != nil {
+		slog.WarnContext(ctx, "failed to unfurl links", "error", err)
+	}
+}
+
+// handleShortcut starts a conversation about the message "Ask CloudOps
+// about this" was used on, in its thread, as if the bot were mentioned
+// there with the message's text
+func (s *server) handleShortcut(ctx context.Context, callback *slack.InteractionCallback) {
+	channelID, userID := callback.Channel.ID, callback.User.ID
+	msg := callback.Message
+	threadTS := intake.ShortcutThread(msg)
+
+	s.mu.Lock()
+	_, ok := s.sessions[sessionKey(channelID, threadTS)]
+	s.mu.Unlock()
+	if ok {
+		if _, err := s.slackClient.PostMessage(ctx, channelID,
+			slack.MsgOptionPostEphemeral(userID),
+			slack.MsgOptionText("That thread already has a conversation. Mention me there to ask about it.", false),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post message", "error", err)
+		}
+		return
+	}
+
+	permalink, err := s.slackClient.GetPermalink(ctx, channelID, msg.Timestamp)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to link shared message", "error", err)
+	}
+	ts, err := s.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionText(fmt.Sprintf("🔎 <@%s> asked me to look into this.", userID), false),
+		slackclient.InThread(threadTS),
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to post in shared message's thread", "error", err)
+		return
+	}
+
+	s.handleMention(ctx, &slackevents.AppMentionEvent{
+		User:            userID,
+		Text:            intake.Seed(msg, permalink),
+		Channel:         channelID,
+		TimeStamp:       ts,
+		ThreadTimeStamp: threadTS,
+	})
+}
+
+// handleFollowUp continues the conversation with a clicked suggestion,
+// recording the click where it is held and removing the buttons
+func (s *server) handleFollowUp(ctx context.Context, callback *slack.InteractionCallback, suggestion string) {
+	channelID := callback.Channel.ID
+	threadTS := callback.Message.ThreadTimestamp
+	userID := callback.User.ID
+	key := sessionKey(channelID, threadTS)
+
+	s.mu.Lock()
+	_, ok := s.sessions[key]
+	s.mu.Unlock()
+	if !ok {
+		if _, err := s.slackClient.PostMessage(ctx, channelID,
+			slack.MsgOptionPostEphemeral(userID),
+			slack.MsgOptionText("This session has ended. Mention me again to start a new one.", false),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post message", "error", err)
+		}
+		return
+	}
+
+	ts, err := s.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionText(followups.Chosen(userID, suggestion), false),
+		slack.MsgOptionMetadata(followups.Metadata(userID, suggestion)),
+		slackclient.InThread(threadTS),
+	)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to record follow-up", "error", err)
+		return
+	}
+
+	msg := callback.Message
+	if err := s.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(followups.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove follow-up buttons", "error", err)
+	}
+
+	s.enqueue(ctx, key, ts, coalesce.FromSlack(userID, suggestion, ts))
+}
+
+// handlePrivacyChoice releases an answer held back for privacy, recording
+// the choice in the conversation and removing the buttons. Answers built on
+// restricted data carry the profile needed to release them
+func (s *server) handlePrivacyChoice(ctx context.Context, callback *slack.InteractionCallback, actionID, minProfile string) {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	msg := callback.Message
+
+	s.mu.Lock()
+	sess, ok := s.sessions[sessionKey(channelID, msg.ThreadTimestamp)]
+	s.mu.Unlock()
+
+	var reason string
+	switch {
+	case !ok:
+		reason = "This session has ended. Mention me again to start a new one."
+	case !sess.conversation.TookPart(userID):
+		reason = "Only people taking part in this conversation can release this answer."
+	case minProfile != "" && !models.ProfileAtLeast(s.profileOf(ctx, userID), minProfile):
+		reason = fmt.Sprintf("Only someone with the %s profile can release this answer.", minProfile)
+	}
+	if reason != "" {
+		if _, err := s.slackClient.PostMessage(ctx, channelID,
+			slack.MsgOptionPostEphemeral(userID),
+			slack.MsgOptionText(reason, false),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post message", "error", err)
+		}
+		return
+	}
+
+	s.post(ctx, sess.conversation, privacy.Chosen(userID, actionID))
+	if err := s.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(privacy.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove privacy buttons", "error", err)
+	}
+
+	s.submit(ctx, sess, func(ctx context.Context) {
+		if err := sess.agent.Release(ctx, userID, actionID, msg.Timestamp); err != nil {
+			slog.WarnContext(ctx, "failed to release held answer", "error", err)
+		}
+	})
+}
+
+// handleMerge merges the newer of two related conversations into the older
+// one, ending the newer one's session
+func (s *server) handleMerge(ctx context.Context, callback *slack.InteractionCallback, value string) {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	msg := callback.Message
+
+	fromID, intoID, _ := correlate.ParseMerge(value)
+	from, into := s.session(fromID), s.session(intoID)
+
+	var reason string
+	switch {
+	case from == nil || into == nil:
+		reason = "One of these conversations has already ended, so there's nothing to merge."
+	case !from.conversation.TookPart(userID) && !into.conversation.TookPart(userID):
+		reason = "Only people taking part in one of these conversations can merge them."
+	default:
+		if err := s.convRepo.MergeInto(ctx, fromID, intoID); errors.Is(err, storage.ErrNotMergeable) {
+			reason = "One of these conversations has already ended, so there's nothing to merge."
+		} else if err != nil {
+			slog.ErrorContext(ctx, "failed to merge conversation", logging.ConversationID, fromID, "error", err)
+			return
+		}
+	}
+	if reason != "" {
+		if _, err := s.slackClient.PostMessage(ctx, channelID,
+			slack.MsgOptionPostEphemeral(userID),
+			slack.MsgOptionText(reason, false),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post message", "error", err)
+		}
+		return
+	}
+
+	if err := s.mergeHistory(ctx, into.conversation, from.conversation); err != nil {
+		slog.WarnContext(ctx, "failed to merge conversation history", logging.ConversationID, fromID, "error", err)
+	}
+	s.post(ctx, from.conversation, correlate.Merged(into.conversation, userID))
+	s.post(ctx, into.conversation, correlate.Joined(from.conversation, userID))
+	if err := s.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(correlate.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove merge button", "error", err)
+	}
+
+	err := s.pool.Submit(fromID, func(ctx context.Context) {
+		if err := from.agent.Merged(ctx, intoID); err != nil {
+			slog.WarnContext(ctx, "failed to end merged conversation", logging.ConversationID, fromID, "error", err)
+		}
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to queue end of merged conversation", logging.ConversationID, fromID, "error", err)
+	}
+	s.end(from)
+
+	err = s.pool.Submit(intoID, func(ctx context.Context) {
+		into.agent.Joined(ctx, fromID)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to queue merge into conversation", logging.ConversationID, intoID, "error", err)
+	}
+}
+
+// mergeHistory interleaves the merged conversation's history into the one
+// that carries on
+func (s *server) mergeHistory(ctx context.Context, into, from *models.Conversation) error {
+	intoHistory, err := s.convRepo.GetHistoryItems(ctx, into.ConversationID)
+	if err != nil {
+		return fmt.Errorf("get history of %s: %w", into.ConversationID, err)
+	}
+	fromHistory, err := s.convRepo.GetHistoryItems(ctx, from.ConversationID)
+	if err != nil {
+		return fmt.Errorf("get history of %s: %w", from.ConversationID, err)
+	}
+	if len(fromHistory) == 0 {
+		return nil
+	}
+	return s.convRepo.ReplaceHistory(ctx, into.ConversationID, correlate.Interleave(intoHistory, fromHistory, into.ConversationID, from.ConversationID))
+}
+
+// session returns the running session of a conversation, or nil
+func (s *server) session(conversationID string) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		if sess.conversation.ConversationID == conversationID {
+			return sess
+		}
+	}
+	return nil
+}
+
+// profileOf returns a user's effective permission profile, read-only when
+// it can't be read
+func (s *server) profileOf(ctx context.Context, userID string) string {
+	for _, admin := range s.cfg.AdminUsers {
+		if admin == userID {
+			return models.ProfileAdmin
+		}
+	}
+	profile, err := s.permRepo.Get(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get permissions", logging.UserID, userID, "error", err)
+	}
+	return profile.Effective(time.Now())
+}
+
+// enqueue buffers a message for the session's conversation until the sender
+// pauses, ignoring messages from before the conversation started and
+// turning them away while the bot is disabled
+func (s *server) enqueue(ctx context.Context, key, ts string, msg coalesce.Message) {
+	ks := s.killSwitch.Disabled(ctx)
+	s.mu.Lock()
+	sess, ok := s.sessions[key]
+	if !ok || !after(ts, sess.startTS) {
+		s.mu.Unlock()
+		return
+	}
+	if ks != nil {
+		s.mu.Unlock()
+		s.post(ctx, sess.conversation, killswitch.Message(ks))
+		return
+	}
+	if s.cfg.WorkerMailboxSize > 0 && sess.pending.Len() >= s.cfg.WorkerMailboxSize {
+		s.mu.Unlock()
+		slog.WarnContext(ctx, "too many pending messages, dropping message", logging.ConversationID, sess.conversation.ConversationID)
+		s.post(ctx, sess.conversation, "⏳ I'm still working through your earlier messages. Please wait for my reply and try again.")
+		return
+	}
+
+	sess.lastActivity = time.Now()
+	sess.pending.Add(msg)
+	window := s.cfg.GetMessageDebounce()
+	if sess.timer != nil {
+		sess.timer.Reset(window)
+	} else {
+		sess.timer = time.AfterFunc(window, func() { s.flush(ctx, sess) })
+	}
+	s.mu.Unlock()
+}
+
+// flush answers the buffered messages in one turn, unless a turn is already
+// in progress; those messages go next when it finishes
+func (s *server) flush(ctx context.Context, sess *session) {
+	s.mu.Lock()
+	sess.timer = nil
+	if sess.busy || sess.pending.Len() == 0 {
+		s.mu.Unlock()
+		return
+	}
+	msgs := sess.pending.Flush()
+	sess.busy = true
+	s.mu.Unlock()
+
+	queued := s.submit(ctx, sess, func(ctx context.Context) {
+		if err := sess.agent.HandleMessages(ctx, msgs); err != nil {
+			slog.ErrorContext(ctx, "failed to handle message", logging.ConversationID, sess.conversation.ConversationID, "error", err)
+			s.post(ctx, sess.conversation, "❌ Sorry, something went wrong processing that message. Please try again.")
+		}
+		s.turnDone(ctx, sess)
+	})
+	if !queued {
+		s.mu.Lock()
+		sess.busy = false
+		s.mu.Unlock()
+	}
+}
+
+// turnDone starts the next turn if messages arrived during this one and the
+// sender has since paused
+func (s *server) turnDone(ctx context.Context, sess *session) {
+	s.mu.Lock()
+	sess.busy = false
+	next := sess.timer == nil && sess.pending.Len() > 0
+	s.mu.Unlock()
+
+	if next {
+		s.flush(ctx, sess)
+	}
+}
+
+// submit queues a turn in the conversation's mailbox, telling the user to
+// slow down when the pool pushes back
+func (s *server) submit(ctx context.Context, sess *session, task workerpool.Task) bool {
+	conv := sess.conversation
+	err := s.pool.Submit(conv.ConversationID, task)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, workerpool.ErrMailboxFull):
+		slog.WarnContext(ctx, "mailbox full, dropping message", logging.ConversationID, conv.ConversationID)
+		s.post(ctx, conv, "⏳ I'm still working through your earlier messages. Please wait for my reply and try again.")
+	case errors.Is(err, workerpool.ErrQueueFull):
+		slog.WarnContext(ctx, "worker pool queue full, dropping message", logging.ConversationID, conv.ConversationID)
+		s.post(ctx, conv, "⏳ I'm handling a lot of requests right now. Please try again in a minute.")
+	default:
+		slog.WarnContext(ctx, "failed to queue message", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+	return false
+}
+
+// reapIdle ends conversations that have been idle for the inactivity timeout
+func (s *server) reapIdle() {
+	timeout := s.cfg.GetInactivityTimeout()
+
+	s.mu.Lock()
+	var idle []*session
+	for _, sess := range s.sessions {
+		if !sess.busy && sess.pending.Len() == 0 && time.Since(sess.lastActivity) > timeout {
+			idle = append(idle, sess)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sess := range idle {
+		conv := sess.conversation
+		err := s.pool.Submit(conv.ConversationID, func(ctx context.Context) {
+			ctx = logging.With(ctx, logging.ConversationID, conv.ConversationID)
+			slog.InfoContext(ctx, "conversation idle, ending", "idle", timeout.String())
+			if err := sess.agent.Finish(ctx); err != nil {
+				slog.WarnContext(ctx, "failed to finish conversation", "error", err)
+			}
+		})
+		if err != nil {
+			// Try again on the next pass
+			slog.Warn("failed to queue end of conversation", logging.ConversationID, conv.ConversationID, "error", err)
+			continue
+		}
+		s.end(sess)
+	}
+}
+
+// end forgets a session so the next mention in its channel or thread
+// starts afresh
+func (s *server) end(sess *session) {
+	key := sessionKey(sess.conversation.ChannelID, sess.conversation.ThreadTS)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[key] == sess {
+		delete(s.sessions, key)
+	}
+}
+
+// newAgent creates an agent with the same optional features as an agent task
+func (s *server) newAgent(conv *models.Conversation) *agent.Agent {
+	a := agent.New(s.cfg, conv, s.convRepo, s.slackClient, s.bedrock)
+	a.SetBotUserID(s.botUserID)
+	a.SetChartRenderer(charts.NewRenderer(s.toolsCfg))
+	a.SetNotifier(s.notifier)
+	if s.cfg.PromptsTable != "" {
+		a.SetPromptStore(s.promptRepo)
+	}
+	if s.ensemble != nil {
+		a.SetEnsemble(s.ensemble)
+	}
+	if s.cfg.EmbeddingModelID != "" {
+		a.SetEmbedder(s.bedrock)
+	}
+	if s.cfg.ReportsBucket != "" {
+		a.SetReportStore(report.NewStore(s.awsCfg, s.cfg.ReportsBucket))
+	}
+	if s.outputs != nil {
+		a.SetOutputStore(s.outputs)
+	}
+	a.SetPermissions(s.permRepo)
+	if s.rbac != nil {
+		a.SetRBAC(s.rbac)
+	}
+	if s.auditRepo != nil {
+		a.SetToolAudit(s.auditRepo)
+	}
+	if s.webhooks != nil {
+		a.SetWebhooks(s.webhooks)
+	}
+	if s.jobs != nil {
+		a.SetJobs(s.jobs)
+	}
+	if s.cfg.UsageTable != "" {
+		// Conversations share this process, so only time spent answering is billed
+		a.SetUsageRepository(s.usageRepo, false)
+	}
+	return a
+}
+
+// post sends a plain text message to a conversation, logging failures
+func (s *server) post(ctx context.Context, conv *models.Conversation, text string) {
+	if _, err := s.slackClient.PostMessage(ctx, conv.ChannelID, slack.MsgOptionText(text, false), slackclient.InThread(conv.ThreadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post message", "error", err)
+	}
+}
+
+// after reports whether Slack timestamp a is later than b
+func after(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return fa > fb
+}
@@ -3,16 +3,20 @@ set -e
 
 # Build and push CloudOps Bot agent Docker image to ECR
 # Usage: ./build-agent.sh [environment] [region]
+#   AGENT_PLATFORMS=linux/amd64,linux/arm64: push a multi-arch image with buildx
+#   (default linux/amd64; include linux/arm64 when AgentArchitecture is ARM64)
 
 ENV=${1:-dev}
 AWS_REGION=${2:-us-east-1}
 STACK_NAME="cloudops-${ENV}"
+AGENT_PLATFORMS=${AGENT_PLATFORMS:-linux/amd64}
 
 echo "======================================================================"
 echo "Building CloudOps Bot Agent"
 echo "======================================================================"
 echo "Environment: ${ENV}"
 echo "Region: ${AWS_REGION}"
+echo "Platforms: ${AGENT_PLATFORMS}"
 echo ""
 
 # Check Docker is running
@@ -65,13 +69,36 @@ aws ecr get-login-password --region ${AWS_REGION} | \
   docker login --username AWS --password-stdin ${REPOSITORY_URI} > /dev/null 2>&1
 echo "✅ Authenticated with ECR"
 
+# Get git commit hash for tagging
+GIT_COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo "local")
+
+# A multi-arch manifest can't be loaded into the local image store, so
+# buildx pushes it straight to ECR
+if [[ "$AGENT_PLATFORMS" == *,* ]]; then
+  echo ""
+  echo "Building and pushing multi-arch image..."
+  docker buildx build \
+    --platform ${AGENT_PLATFORMS} \
+    -f deployments/Dockerfile.agent \
+    -t ${REPOSITORY_URI}:latest \
+    -t ${REPOSITORY_URI}:${GIT_COMMIT} \
+    --push .
+
+  echo ""
+  echo "======================================================================"
+  echo "✅ Agent Image Deployed Successfully"
+  echo "======================================================================"
+  echo "Repository: ${REPOSITORY_URI}"
+  echo "Tags: latest, ${GIT_COMMIT}"
+  echo "Platforms: ${AGENT_PLATFORMS}"
+  echo ""
+  exit 0
+fi
+
 # Build Docker image
 echo ""
 echo "Building Docker image..."
-docker build -f deployments/Dockerfile.agent -t cloudops-agent:latest .
-
-# Get git commit hash for tagging
-GIT_COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo "local")
+docker build --platform ${AGENT_PLATFORMS} -f deployments/Dockerfile.agent -t cloudops-agent:latest .
 
 # Show image size
 echo ""
@@ -5,6 +5,9 @@ set -e
 # Usage: ./deploy-stack.sh [environment] [--full]
 #   environment: dev, staging, prod (default: dev)
 #   --full: Deploy infrastructure + build Lambda + build Docker image
+#   SLACK_ENTRYPOINT=functionurl: serve Slack from a Lambda Function URL instead of API Gateway
+#   LAMBDA_ARCH=x86_64: build and run Lambda functions on x86_64 instead of arm64
+#   AGENT_ARCHITECTURE=ARM64: build and run the agent on Graviton instead of X86_64
 
 # Parse command line arguments
 FULL_DEPLOYMENT=false
@@ -31,6 +34,18 @@ SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" && pwd )"
 PROJECT_ROOT="$(dirname "$SCRIPT_DIR")"
 TEMPLATE_PATH="${PROJECT_ROOT}/infrastructure/cloudformation/cloudops-stack.yaml"
 
+# Go and Docker spell architectures differently from Lambda and ECS
+case "${LAMBDA_ARCH:-arm64}" in
+  arm64) LAMBDA_GOARCH=arm64 ;;
+  x86_64) LAMBDA_GOARCH=amd64 ;;
+  *) echo "❌ LAMBDA_ARCH must be arm64 or x86_64"; exit 1 ;;
+esac
+case "${AGENT_ARCHITECTURE:-X86_64}" in
+  X86_64) AGENT_PLATFORM=linux/amd64 ;;
+  ARM64) AGENT_PLATFORM=linux/arm64 ;;
+  *) echo "❌ AGENT_ARCHITECTURE must be X86_64 or ARM64"; exit 1 ;;
+esac
+
 # Error handler - show stack events on failure
 cleanup_on_error() {
   echo ""
@@ -186,6 +201,38 @@ if [ "$OPERATION" == "create-stack" ]; then
     --template-body file://${TEMPLATE_PATH} \
     --parameters \
       ParameterKey=Env,ParameterValue=${ENV} \
+      ParameterKey=SlackEntrypoint,ParameterValue=${SLACK_ENTRYPOINT:-apigateway} \
+      ParameterKey=WarmPoolSize,ParameterValue=${WARM_POOL_SIZE:-0} \
+      ParameterKey=AgentCapacity,ParameterValue=${AGENT_CAPACITY:-ondemand} \
+      ParameterKey=OnDemandChannels,ParameterValue=\"${ONDEMAND_CHANNELS:-}\" \
+      ParameterKey=TaskSizes,ParameterValue=\"${TASK_SIZES:-}\" \
+      ParameterKey=ShadowTaskDefinition,ParameterValue=${SHADOW_TASK_DEFINITION:-} \
+      ParameterKey=ShadowPercent,ParameterValue=${SHADOW_PERCENT:-100} \
+      ParameterKey=RBACPolicy,ParameterValue=${RBAC_POLICY:-} \
+      ParameterKey=WebhookURLs,ParameterValue=\"${WEBHOOK_URLS:-}\" \
+      ParameterKey=AgentArchitecture,ParameterValue=${AGENT_ARCHITECTURE:-X86_64} \
+      ParameterKey=LambdaArchitecture,ParameterValue=${LAMBDA_ARCH:-arm64} \
+      ParameterKey=PromptVersion,ParameterValue=${PROMPT_VERSION:-0} \
+      ParameterKey=JobRunner,ParameterValue=${JOB_RUNNER:-agent} \
+      ParameterKey=ConversationMode,ParameterValue=${CONVERSATION_MODE:-inplace} \
+      ParameterKey=ContextPackSeconds,ParameterValue=${CONTEXT_PACK_SECONDS:-0} \
+      ParameterKey=SealLaunchInput,ParameterValue=${SEAL_LAUNCH_INPUT:-false} \
+      ParameterKey=ExpertsGroup,ParameterValue=${EXPERTS_GROUP:-} \
+      ParameterKey=SandboxRoleARN,ParameterValue=${SANDBOX_ROLE_ARN:-} \
+      ParameterKey=SandboxExternalID,ParameterValue=${SANDBOX_EXTERNAL_ID:-} \
+      ParameterKey=EnsembleModelID,ParameterValue=${ENSEMBLE_MODEL_ID:-} \
+      ParameterKey=AdminUsers,ParameterValue=\"${ADMIN_USERS:-}\" \
+      ParameterKey=AllowedSourceCIDRs,ParameterValue=\"${ALLOWED_SOURCE_CIDRS:-}\" \
+      ParameterKey=RequireClientCert,ParameterValue=${REQUIRE_CLIENT_CERT:-false} \
+      ParameterKey=SlackClientID,ParameterValue=${SLACK_CLIENT_ID:-} \
+      ParameterKey=EmbeddingModelID,ParameterValue=${EMBEDDING_MODEL_ID:-} \
+      ParameterKey=ChargebackChannel,ParameterValue=${CHARGEBACK_CHANNEL:-} \
+      ParameterKey=ChargebackChannels,ParameterValue=\"${CHARGEBACK_CHANNELS:-}\" \
+      ParameterKey=CostAlertChannel,ParameterValue=${COST_ALERT_CHANNEL:-} \
+      ParameterKey=CostDailyLimit,ParameterValue=${COST_DAILY_LIMIT:-0} \
+      ParameterKey=CostMonthlyLimit,ParameterValue=${COST_MONTHLY_LIMIT:-0} \
+      ParameterKey=CostIncludeBedrock,ParameterValue=${COST_INCLUDE_BEDROCK:-false} \
+    --tags Key=Application,Value=${STACK_NAME} \
     --capabilities CAPABILITY_NAMED_IAM \
     --region ${AWS_REGION}
 
@@ -201,6 +248,38 @@ else
     --template-body file://${TEMPLATE_PATH} \
     --parameters \
       ParameterKey=Env,ParameterValue=${ENV} \
+      ParameterKey=SlackEntrypoint,ParameterValue=${SLACK_ENTRYPOINT:-apigateway} \
+      ParameterKey=WarmPoolSize,ParameterValue=${WARM_POOL_SIZE:-0} \
+      ParameterKey=AgentCapacity,ParameterValue=${AGENT_CAPACITY:-ondemand} \
+      ParameterKey=OnDemandChannels,ParameterValue=\"${ONDEMAND_CHANNELS:-}\" \
+      ParameterKey=TaskSizes,ParameterValue=\"${TASK_SIZES:-}\" \
+      ParameterKey=ShadowTaskDefinition,ParameterValue=${SHADOW_TASK_DEFINITION:-} \
+      ParameterKey=ShadowPercent,ParameterValue=${SHADOW_PERCENT:-100} \
+      ParameterKey=RBACPolicy,ParameterValue=${RBAC_POLICY:-} \
+      ParameterKey=WebhookURLs,ParameterValue=\"${WEBHOOK_URLS:-}\" \
+      ParameterKey=AgentArchitecture,ParameterValue=${AGENT_ARCHITECTURE:-X86_64} \
+      ParameterKey=LambdaArchitecture,ParameterValue=${LAMBDA_ARCH:-arm64} \
+      ParameterKey=PromptVersion,ParameterValue=${PROMPT_VERSION:-0} \
+      ParameterKey=JobRunner,ParameterValue=${JOB_RUNNER:-agent} \
+      ParameterKey=ConversationMode,ParameterValue=${CONVERSATION_MODE:-inplace} \
+      ParameterKey=ContextPackSeconds,ParameterValue=${CONTEXT_PACK_SECONDS:-0} \
+      ParameterKey=SealLaunchInput,ParameterValue=${SEAL_LAUNCH_INPUT:-false} \
+      ParameterKey=ExpertsGroup,ParameterValue=${EXPERTS_GROUP:-} \
+      ParameterKey=SandboxRoleARN,ParameterValue=${SANDBOX_ROLE_ARN:-} \
+      ParameterKey=SandboxExternalID,ParameterValue=${SANDBOX_EXTERNAL_ID:-} \
+      ParameterKey=EnsembleModelID,ParameterValue=${ENSEMBLE_MODEL_ID:-} \
+      ParameterKey=AdminUsers,ParameterValue=\"${ADMIN_USERS:-}\" \
+      ParameterKey=AllowedSourceCIDRs,ParameterValue=\"${ALLOWED_SOURCE_CIDRS:-}\" \
+      ParameterKey=RequireClientCert,ParameterValue=${REQUIRE_CLIENT_CERT:-false} \
+      ParameterKey=SlackClientID,ParameterValue=${SLACK_CLIENT_ID:-} \
+      ParameterKey=EmbeddingModelID,ParameterValue=${EMBEDDING_MODEL_ID:-} \
+      ParameterKey=ChargebackChannel,ParameterValue=${CHARGEBACK_CHANNEL:-} \
+      ParameterKey=ChargebackChannels,ParameterValue=\"${CHARGEBACK_CHANNELS:-}\" \
+      ParameterKey=CostAlertChannel,ParameterValue=${COST_ALERT_CHANNEL:-} \
+      ParameterKey=CostDailyLimit,ParameterValue=${COST_DAILY_LIMIT:-0} \
+      ParameterKey=CostMonthlyLimit,ParameterValue=${COST_MONTHLY_LIMIT:-0} \
+      ParameterKey=CostIncludeBedrock,ParameterValue=${COST_INCLUDE_BEDROCK:-false} \
+    --tags Key=Application,Value=${STACK_NAME} \
     --capabilities CAPABILITY_NAMED_IAM \
     --region ${AWS_REGION} 2>&1) || UPDATE_EXIT_CODE=$?
 
@@ -238,9 +317,9 @@ deploy_lambda() {
   LAMBDA_DIR="${PROJECT_ROOT}/bin"
   mkdir -p ${LAMBDA_DIR}
 
-  echo "Building Lambda handler for arm64..."
+  echo "Building Lambda handler for ${LAMBDA_GOARCH}..."
   cd ${PROJECT_ROOT}
-  GOOS=linux GOARCH=arm64 go build -o ${LAMBDA_DIR}/slack-handler ./cmd/slack-handler
+  CGO_ENABLED=0 GOOS=linux GOARCH=${LAMBDA_GOARCH} go build -o ${LAMBDA_DIR}/slack-handler ./cmd/slack-handler
 
   if [ ! -f "${LAMBDA_DIR}/slack-handler" ]; then
     echo "❌ Failed to build Lambda handler"
@@ -299,10 +378,10 @@ deploy_agent() {
     docker login --username AWS --password-stdin ${REPOSITORY_URI} > /dev/null 2>&1
 
   # Build image
-  echo "Building agent container image..."
+  echo "Building agent container image for ${AGENT_PLATFORM}..."
   cd ${PROJECT_ROOT}
   DOCKER_OUTPUT=$(mktemp)
-  if ! docker build -f deployments/Dockerfile.agent -t cloudops-agent:latest . > "$DOCKER_OUTPUT" 2>&1; then
+  if ! docker build --platform ${AGENT_PLATFORM} -f deployments/Dockerfile.agent -t cloudops-agent:latest . > "$DOCKER_OUTPUT" 2>&1; then
     echo "❌ Docker build failed:"
     cat "$DOCKER_OUTPUT"
     rm -f "$DOCKER_OUTPUT"
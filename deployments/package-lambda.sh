@@ -8,6 +8,14 @@ ENV=${1:-dev}
 HANDLER_NAME=${2:-slack-handler}
 STACK_NAME="cloudops-${ENV}"
 AWS_REGION=${AWS_REGION:-us-east-1}
+LAMBDA_ARCH=${LAMBDA_ARCH:-arm64}
+
+# Must match the stack's LambdaArchitecture parameter
+case "$LAMBDA_ARCH" in
+  arm64) LAMBDA_GOARCH=arm64 ;;
+  x86_64) LAMBDA_GOARCH=amd64 ;;
+  *) echo "❌ LAMBDA_ARCH must be arm64 or x86_64"; exit 1 ;;
+esac
 
 echo "======================================================================"
 echo "CloudOps Lambda Build & Package"
@@ -15,6 +23,7 @@ echo "======================================================================"
 echo "Environment: ${ENV}"
 echo "Handler: ${HANDLER_NAME}"
 echo "Region: ${AWS_REGION}"
+echo "Architecture: ${LAMBDA_ARCH}"
 echo ""
 
 # Check Go is installed
@@ -52,8 +61,8 @@ mkdir -p "$BUILD_DIR"
 
 # Build the Lambda handler binary
 echo ""
-echo "Building Lambda handler for ${HANDLER_NAME}..."
-GOOS=linux GOARCH=arm64 go build \
+echo "Building Lambda handler for ${HANDLER_NAME} (${LAMBDA_GOARCH})..."
+CGO_ENABLED=0 GOOS=linux GOARCH=${LAMBDA_GOARCH} go build \
   -o "$LAMBDA_BINARY" \
   "./cmd/${HANDLER_NAME}"
 
@@ -96,13 +105,14 @@ echo "✅ Package created: ${PACKAGE_SIZE}"
 
 cd - > /dev/null
 
-# Get Lambda function name from CloudFormation
+# Get Lambda function name from CloudFormation (slack-handler -> SlackHandlerFunctionName)
 echo ""
 echo "Getting Lambda function name from CloudFormation..."
+OUTPUT_KEY="$(echo "${HANDLER_NAME}" | awk -F- '{for (i = 1; i <= NF; i++) printf "%s%s", toupper(substr($i, 1, 1)), substr($i, 2)}')FunctionName"
 LAMBDA_FUNCTION=$(aws cloudformation describe-stacks \
   --stack-name ${STACK_NAME} \
   --region ${AWS_REGION} \
-  --query "Stacks[0].Outputs[?OutputKey=='SlackHandlerFunctionName'].OutputValue" \
+  --query "Stacks[0].Outputs[?OutputKey=='${OUTPUT_KEY}'].OutputValue" \
   --output text)
 
 if [ -z "$LAMBDA_FUNCTION" ] || [ "$LAMBDA_FUNCTION" == "None" ]; then
@@ -7,6 +7,12 @@ set -e
 # Options:
 #   --slack-bot-token <token>     Slack bot token (xoxb-...)
 #   --slack-signing-key <key>     Slack signing secret
+#   --slack-signing-key-secondary <key>
+#                                 Second signing secret accepted during rotation
+#   --slack-client-secret <secret>
+#                                 Slack app client secret (bot token rotation)
+#   --slack-refresh-token <token> First refresh token (bot token rotation)
+#   --webhook-secret <secret>     Signs outbound webhook deliveries (WebhookURLs)
 #   --interactive                 Prompt for missing values
 #   --update                      Update existing parameters instead of failing
 
@@ -16,6 +22,10 @@ INTERACTIVE=false
 UPDATE=false
 SLACK_BOT_TOKEN=""
 SLACK_SIGNING_KEY=""
+SLACK_SIGNING_KEY_SECONDARY=""
+SLACK_CLIENT_SECRET=""
+SLACK_REFRESH_TOKEN=""
+WEBHOOK_SECRET=""
 
 # Parse arguments
 shift || true
@@ -29,6 +39,22 @@ while [[ $# -gt 0 ]]; do
       SLACK_SIGNING_KEY="$2"
       shift 2
       ;;
+    --slack-signing-key-secondary)
+      SLACK_SIGNING_KEY_SECONDARY="$2"
+      shift 2
+      ;;
+    --slack-client-secret)
+      SLACK_CLIENT_SECRET="$2"
+      shift 2
+      ;;
+    --slack-refresh-token)
+      SLACK_REFRESH_TOKEN="$2"
+      shift 2
+      ;;
+    --webhook-secret)
+      WEBHOOK_SECRET="$2"
+      shift 2
+      ;;
     --interactive)
       INTERACTIVE=true
       shift
@@ -134,6 +160,28 @@ manage_parameter \
   "${SLACK_SIGNING_KEY}" \
   "Slack signing secret for webhook validation"
 
+# Optional: only needed while rotating the signing secret or bot token
+manage_parameter \
+  "/cloudops/${ENV}/slack-signing-key-secondary" \
+  "${SLACK_SIGNING_KEY_SECONDARY}" \
+  "Second Slack signing secret accepted during rotation"
+
+manage_parameter \
+  "/cloudops/${ENV}/slack-client-secret" \
+  "${SLACK_CLIENT_SECRET}" \
+  "Slack app client secret for bot token rotation"
+
+manage_parameter \
+  "/cloudops/${ENV}/slack-refresh-token" \
+  "${SLACK_REFRESH_TOKEN}" \
+  "First Slack refresh token for bot token rotation"
+
+# Optional: only needed with outbound webhooks
+manage_parameter \
+  "/cloudops/${ENV}/webhook-secret" \
+  "${WEBHOOK_SECRET}" \
+  "HMAC secret signing outbound webhook deliveries"
+
 echo ""
 echo "======================================================================"
 echo "Verification"
@@ -0,0 +1,1576 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/chargeback"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/contextpack"
+	"github.com/savaki/cloudops-bot/pkg/correlate"
+	"github.com/savaki/cloudops-bot/pkg/debugmode"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/ensemble"
+	"github.com/savaki/cloudops-bot/pkg/entities"
+	"github.com/savaki/cloudops-bot/pkg/escalation"
+	"github.com/savaki/cloudops-bot/pkg/followups"
+	"github.com/savaki/cloudops-bot/pkg/fulloutput"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/killswitch"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/links"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/mentions"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/mrkdwn"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/prompts"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+	"github.com/savaki/cloudops-bot/pkg/report"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	"github.com/savaki/cloudops-bot/pkg/similarity"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/sources"
+	"github.com/savaki/cloudops-bot/pkg/splitter"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/savaki/cloudops-bot/pkg/transfer"
+	"github.com/savaki/cloudops-bot/pkg/usage"
+	"github.com/savaki/cloudops-bot/pkg/watch"
+	"github.com/savaki/cloudops-bot/pkg/webhook"
+	"github.com/slack-go/slack"
+)
+
+// pollInterval is how often the agent checks the channel for new messages
+const pollInterval = 3 * time.Second
+
+// scratchpadInstructions tells the model how to maintain its working memory
+const scratchpadInstructions = `Working memory:
+You have a scratchpad that persists for the whole conversation, even when older messages are no longer visible to you. When you establish a fact worth keeping, identify a resource under investigation, form a hypothesis, or rule one out, append a block at the very end of your reply:
+
+<scratchpad>
+finding: <fact you have confirmed>
+resource: <resource ID or ARN under investigation>
+hypothesis: <possible cause still being considered>
+rejected: <hypothesis that has been ruled out, exactly as previously written>
+</scratchpad>
+
+Only include lines that changed. The block is removed before your reply is shown to the user.`
+
+// chartInstructions tells the model how to request metric graphs
+const chartInstructions = `Charts:
+When a graph of CloudWatch metrics would help answer the question, add one or more chart blocks to your reply. Each is rendered from live CloudWatch data and attached as an image:
+
+<chart>{"title":"API CPU","metrics":[{"namespace":"AWS/ECS","name":"CPUUtilization","dimensions":{"ClusterName":"prod","ServiceName":"api"},"stat":"Average"}],"hours":3,"period":300}</chart>
+
+Do not describe exact values you have not seen; the chart shows the data.`
+
+// thinkingPlaceholder is shown while waiting for Claude
+const thinkingPlaceholder = "_…thinking_"
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+// Agent runs a single conversation, feeding user messages to Claude and
+// posting the responses back to Slack
+type Agent struct {
+	cfg          *config.Config
+	conversation *models.Conversation
+	convRepo     storage.Store
+	slackClient  *slackclient.Client
+	bedrock      *bedrock.Client
+	links        *links.Builder
+	botUserID    string // the only sender whose message metadata is trusted
+	mentions     *mentions.Resolver
+	charts       *charts.Renderer
+	reports      *report.Store
+	outputs      *fulloutput.Store
+	watchers     *watch.Notifier
+	prompts      prompts.Store
+	ensemble     *ensemble.Ensemble
+	embedder     *bedrock.Client
+	prompt       *models.PromptTemplate // resolved when the conversation starts
+	permissions  *dynamodb.PermissionRepository
+	packed       *contextpack.Pack // permissions snapshot from launch, trusted until packedUntil
+	packedUntil  time.Time
+	rbac         *rbac.Enforcer
+	toolAudit    ToolAuditor
+	webhooks     *webhook.Notifier
+	killSwitch   *killswitch.Switch
+	jobs         *jobs.Manager
+	window       *bedrock.ContextManager // nil sends the whole history
+	inbox        Inbox                   // nil reads the channel on every poll
+	lastRead     time.Time               // when the channel was last read
+
+	// Chargeback metering: usage is flushed to usageRepo after each turn
+	usageRepo *dynamodb.UsageRepository
+	meter     *usage.Meter
+	dedicated bool      // the task runs only this conversation, so idle time is billed too
+	lastFlush time.Time // start of the runtime not yet flushed
+	counted   bool      // the conversation itself has been counted
+
+	// Last Slack message fully handled, where the conversation resumes if
+	// the task is interrupted
+	checkpoint string
+
+	// Answers held back for privacy, by the timestamp of the notice that
+	// asks where to send them
+	held map[string]*outgoing
+
+	// Steps each turn passes through, and the limit on how often they run
+	pipeline *Pipeline
+	limiter  *turnLimiter
+
+	// Changes made through the control API
+	op operator
+}
+
+// outgoing is a formatted answer, posted straight away or held until
+// someone confirms where it goes
+type outgoing struct {
+	text        string
+	attribution string
+	suggestions []string
+	outputs     []fulloutput.Output
+	debug       []slack.Block // tool calls and token usage, in debug mode
+	minProfile  string        // the profile needed to release it, when held
+}
+
+// New creates an agent for the given conversation
+func New(cfg *config.Config, conversation *models.Conversation, convRepo storage.Store, slackClient *slackclient.Client, bedrockClient *bedrock.Client) *Agent {
+	a := &Agent{
+		cfg:          cfg,
+		conversation: conversation,
+		convRepo:     convRepo,
+		slackClient:  slackClient,
+		bedrock:      bedrockClient,
+		links:        newLinkBuilder(cfg),
+		mentions:     mentions.NewResolver(slackClient),
+		held:         map[string]*outgoing{},
+		limiter:      &turnLimiter{limit: cfg.TurnsPerMinute},
+	}
+	if cfg.ContextTokens > 0 {
+		a.window = bedrock.NewContextManager(bedrockClient, cfg.ContextTokens)
+	}
+	a.pipeline = a.newPipeline()
+	return a
+}
+
+// SetBotUserID tells the agent its own Slack user ID, so mentions of the
+// bot are dropped from messages rather than shown to the model
+func (a *Agent) SetBotUserID(userID string) {
+	a.botUserID = userID
+	a.mentions.SetSelf(userID)
+}
+
+// SetChartRenderer enables rendering of metric charts requested by the model
+func (a *Agent) SetChartRenderer(renderer *charts.Renderer) {
+	a.charts = renderer
+}
+
+// SetReportStore enables publishing an incident report when the conversation ends
+func (a *Agent) SetReportStore(store *report.Store) {
+	a.reports = store
+}
+
+// SetOutputStore enables shortening long code blocks, with a button that
+// serves the full content from store
+func (a *Agent) SetOutputStore(store *fulloutput.Store) {
+	a.outputs = store
+}
+
+// SetNotifier enables DM notifications to users watching the conversation
+func (a *Agent) SetNotifier(notifier *watch.Notifier) {
+	a.watchers = notifier
+}
+
+// SetPromptStore enables versioned system prompts; without it the agent
+// uses the built-in prompt
+func (a *Agent) SetPromptStore(store prompts.Store) {
+	a.prompts = store
+}
+
+// SetEnsemble enables cross-checking answers with a second model in
+// conversations carrying one of the configured ensemble tags
+func (a *Agent) SetEnsemble(e *ensemble.Ensemble) {
+	a.ensemble = e
+}
+
+// SetEmbedder enables linking new conversations to similar recent
+// incidents, using the client's embedding model
+func (a *Agent) SetEmbedder(client *bedrock.Client) {
+	a.embedder = client
+}
+
+// SetPermissions enables classified tool results for users with a stored
+// permission profile; without it only configured admins see restricted data
+func (a *Agent) SetPermissions(repo *dynamodb.PermissionRepository) {
+	a.permissions = repo
+}
+
+// SetContextPack uses the permission profiles in the pack the agent was
+// launched with, instead of reading them, until the pack is maxAge old
+func (a *Agent) SetContextPack(pack *contextpack.Pack, maxAge time.Duration) {
+	a.packed = pack
+	a.packedUntil = pack.CreatedAt.Add(maxAge)
+}
+
+// SetRBAC maps senders to permission sets with a tool access policy, and
+// refuses tool calls the least privileged sender of a turn may not make
+func (a *Agent) SetRBAC(e *rbac.Enforcer) {
+	a.rbac = e
+	a.pipeline.UseTools(e.Middleware)
+}
+
+// SetWebhooks tells external systems when the conversation starts and
+// completes
+func (a *Agent) SetWebhooks(n *webhook.Notifier) {
+	a.webhooks = n
+}
+
+// SetKillSwitch pauses the agent while an admin has the bot disabled
+func (a *Agent) SetKillSwitch(s *killswitch.Switch) {
+	a.killSwitch = s
+}
+
+// SetJobs lets the model start background jobs in the conversation
+func (a *Agent) SetJobs(m *jobs.Manager) {
+	a.jobs = m
+}
+
+// SetUsageRepository enables chargeback metering of model tokens, tool calls,
+// and runtime. A dedicated agent task is billed for its whole lifetime;
+// otherwise only the time spent answering is billed
+func (a *Agent) SetUsageRepository(repo *dynamodb.UsageRepository, dedicated bool) {
+	a.usageRepo = repo
+	a.meter = usage.NewMeter()
+	a.dedicated = dedicated
+	a.lastFlush = time.Now()
+}
+
+// newLinkBuilder creates the console link builder from configuration
+func newLinkBuilder(cfg *config.Config) *links.Builder {
+	var opts []links.Option
+	if cfg.ConsoleSwitchRoleAccount != "" && cfg.ConsoleSwitchRoleName != "" {
+		opts = append(opts, links.WithSwitchRole(cfg.ConsoleSwitchRoleAccount, cfg.ConsoleSwitchRoleName, "CloudOps"))
+	}
+	if cfg.ConsoleFederationURL != "" {
+		opts = append(opts, links.WithFederation(cfg.ConsoleFederationURL))
+	}
+	return links.New(cfg.AWSRegion, opts...)
+}
+
+// Run answers the initial command, then polls the conversation channel, or
+// its thread, for follow-up messages until the conversation has been idle for the
+// configured inactivity timeout
+func (a *Agent) Run(ctx context.Context) error {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+
+	botUserID, err := a.slackClient.GetBotUserID(ctx)
+	if err != nil {
+		return fmt.Errorf("get bot user id: %w", err)
+	}
+	a.SetBotUserID(botUserID)
+
+	// Heartbeats start before the first answer, which can take minutes, so
+	// the reaper doesn't mistake a slow start for a dead agent
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go a.heartbeat(heartbeatCtx)
+
+	// Only pick up messages posted after the conversation started, or after
+	// the last one handled before an interruption
+	lastTS := fmt.Sprintf("%d.000000", conv.CreatedAt.Unix())
+	if conv.ResumeTS != "" {
+		lastTS = conv.ResumeTS
+		a.resume(ctx)
+	} else if err := a.Start(ctx); err != nil {
+		return err
+	}
+	a.checkpoint = lastTS
+
+	// Messages sent in quick succession are answered together once the
+	// sender pauses for the debounce window
+	pending := coalesce.NewBuffer(a.cfg.GetMessageDebounce())
+	lastActivity := time.Now()
+	paused := false
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	a.observe(0, lastActivity)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if timeout := a.idleTimeout(); pending.Len() == 0 && !a.operatorPaused() && time.Since(lastActivity) > timeout {
+			slog.InfoContext(ctx, "conversation idle, ending", "timeout", timeout.String())
+			return a.Finish(ctx)
+		}
+
+		messages, err := a.poll(ctx, lastTS)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to poll channel", "error", err)
+			continue
+		}
+
+		for _, msg := range messages {
+			lastTS = msg.Timestamp
+
+			// After a transfer the conversation continues in the new
+			// channel, from the time of the notice
+			if channelID, visibility, ok := transfer.FromMetadata(msg.Metadata, conv.ConversationID); ok && a.fromBot(msg) {
+				slog.InfoContext(ctx, "conversation moved", "to_channel_id", channelID)
+				conv.MoveTo(channelID)
+				if visibility != "" {
+					conv.Visibility = visibility
+				}
+				ctx = a.logContext(ctx)
+				break
+			}
+
+			// A conversation merged into a related one ends here; the
+			// notice already points the channel at the other
+			if intoID, ok := correlate.FromMetadata(msg.Metadata, conv.ConversationID); ok && a.fromBot(msg) {
+				return a.Merged(ctx, intoID)
+			}
+			if fromID, ok := correlate.IntoMetadata(msg.Metadata, conv.ConversationID); ok && a.fromBot(msg) {
+				a.Joined(ctx, fromID)
+				continue
+			}
+
+			// Debug mode is turned on and off with /cloudops debug
+			if on, ok := debugmode.FromMetadata(msg.Metadata, conv.ConversationID); ok && a.fromBot(msg) {
+				conv.Debug = on
+				continue
+			}
+
+			// Once escalated with /cloudops escalate, experts lead and the
+			// agent only answers when mentioned
+			if userID, ok := escalation.FromMetadata(msg.Metadata, conv.ConversationID); ok && a.fromBot(msg) {
+				slog.InfoContext(ctx, "conversation escalated to experts", logging.UserID, userID)
+				conv.HumanAssisted = true
+				continue
+			}
+
+			// A confirmation releases an answer held back for privacy
+			if userID, action, noticeTS, ok := privacy.FromMetadata(msg.Metadata); ok && a.fromBot(msg) {
+				lastActivity = time.Now()
+				if err := a.Release(ctx, userID, action, noticeTS); err != nil {
+					slog.WarnContext(ctx, "failed to release held answer", "error", err)
+				}
+				continue
+			}
+
+			// A clicked follow-up is posted by the bot on the user's behalf
+			if m, ok := a.followUp(msg); ok {
+				lastActivity = time.Now()
+				pending.Add(m)
+				continue
+			}
+
+			if msg.BotID != "" || msg.User == botUserID || msg.SubType != "" {
+				continue
+			}
+
+			lastActivity = time.Now()
+			if conv.HumanAssisted && !escalation.Addressed(msg.Text, botUserID) {
+				continue
+			}
+			pending.Add(coalesce.FromSlack(msg.User, msg.Text, msg.Timestamp))
+		}
+		for _, m := range a.takeInjected() {
+			slog.InfoContext(ctx, "message injected through control api", logging.UserID, m.UserID)
+			lastActivity = time.Now()
+			pending.Add(m)
+		}
+		if pending.Len() == 0 {
+			a.checkpoint = lastTS
+		}
+		a.observe(pending.Len(), lastActivity)
+
+		// An operator can pause the agent through the control API; messages
+		// wait as they do while the bot is disabled
+		if a.operatorPaused() {
+			continue
+		}
+
+		// While the bot is disabled messages wait, and are answered
+		// together once it is enabled again
+		if ks := a.killSwitch.Disabled(ctx); ks != nil {
+			if pending.Len() > 0 && !paused {
+				slog.InfoContext(ctx, "conversation paused", "changed_by", ks.ChangedBy)
+				a.post(ctx, killswitch.Message(ks))
+				paused = true
+			}
+			continue
+		}
+		if paused {
+			a.post(ctx, "▶️ I'm back. Picking up where we left off.")
+			paused = false
+		}
+
+		if pending.Ready(time.Now()) {
+			if err := a.HandleMessages(ctx, pending.Flush()); err != nil {
+				slog.ErrorContext(ctx, "failed to handle message", "error", err)
+				a.post(ctx, "❌ Sorry, something went wrong processing that message. Please try again.")
+			} else if pending.Len() == 0 {
+				a.checkpoint = lastTS
+			}
+		}
+	}
+}
+
+// heartbeat records that the agent is alive every heartbeat interval until
+// ctx is done
+func (a *Agent) heartbeat(ctx context.Context) {
+	interval := a.cfg.GetHeartbeatInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := a.convRepo.UpdateHeartbeat(ctx, a.conversation.ConversationID, now); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "failed to record heartbeat", "error", err)
+			}
+		}
+	}
+}
+
+// poll returns the messages posted to the conversation after lastTS. A
+// conversation confined to a thread only sees replies in it, and one with
+// the channel to itself never sees replies in threads, so several can share
+// a channel without talking over each other. With an inbox, the channel is
+// only read when there is something new
+func (a *Agent) poll(ctx context.Context, lastTS string) ([]slack.Message, error) {
+	now := time.Now()
+	if !a.due(ctx, lastTS, now) {
+		return nil, nil
+	}
+	a.lastRead = now
+
+	conv := a.conversation
+	if conv.Threaded() {
+		return a.slackClient.GetRepliesSince(ctx, conv.ChannelID, conv.ThreadTS, lastTS)
+	}
+	return a.slackClient.GetMessagesSince(ctx, conv.ChannelID, lastTS)
+}
+
+// Start marks the conversation active and answers the initial command
+func (a *Agent) Start(ctx context.Context) error {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+
+	if err := a.convRepo.UpdateStatus(ctx, conv.ConversationID, models.StatusActive); err != nil {
+		slog.WarnContext(ctx, "failed to mark conversation active", "error", err)
+	}
+	conv.UpdateStatus(models.StatusActive)
+	a.watchers.StatusChanged(ctx, conv, models.StatusActive)
+	a.webhooks.Send(ctx, webhook.EventStarted, conv)
+	lifecycle.Mark(ctx, a.slackClient, conv, lifecycle.ForStatus(models.StatusActive))
+	a.resolvePrompt(ctx)
+	a.assignCostCenter(ctx)
+
+	ctx = usage.WithMeter(ctx, a.meter)
+	a.linkDuplicates(ctx)
+
+	if err := a.HandleMessage(ctx, conv.UserID, conv.InitialCommand); err != nil {
+		return fmt.Errorf("handle initial command: %w", err)
+	}
+	return nil
+}
+
+// resume picks an interrupted conversation back up with the prompt it
+// started with, instead of answering the initial command again
+func (a *Agent) resume(ctx context.Context) {
+	conv := a.conversation
+	a.counted = true
+
+	if err := a.convRepo.UpdateStatus(ctx, conv.ConversationID, models.StatusActive); err != nil {
+		slog.WarnContext(ctx, "failed to mark conversation active", "error", err)
+	}
+
+	a.prompt = prompts.Builtin()
+	if conv.PromptVersion != models.BuiltinPromptVersion {
+		prompt, err := prompts.Resolve(ctx, a.prompts, conv.PromptVersion)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to resolve system prompt, using built-in", "prompt_version", conv.PromptVersion, "error", err)
+		} else {
+			a.prompt = prompt
+		}
+	}
+
+	slog.InfoContext(ctx, "resuming conversation", "interruptions", conv.Interruptions)
+	a.post(ctx, "♻️ I'm back. Picking up where we left off; anything you sent while I was away will be answered now.")
+}
+
+// Checkpoint saves where the conversation resumes when the task is about
+// to be reclaimed, and lets the channel know. ctx must outlive the one Run
+// was given, since that one is already canceled
+func (a *Agent) Checkpoint(ctx context.Context) error {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+
+	a.flushUsage(ctx)
+	if err := a.convRepo.SaveCheckpoint(ctx, conv.ConversationID, a.checkpoint); err != nil {
+		return err
+	}
+
+	a.post(ctx, "⏸️ My compute capacity is being reclaimed. I'll be back in a minute or two and will answer anything you send meanwhile.")
+	return nil
+}
+
+// Shutdown ends the conversation when the task is stopped for good, such as
+// by a deploy: it lets the channel know, records the usage not yet flushed,
+// and marks the conversation completed. ctx must outlive the one Run was
+// given, since that one is already canceled
+func (a *Agent) Shutdown(ctx context.Context) error {
+	ctx = a.logContext(ctx)
+
+	// A merge or the reaper may have ended the conversation and stopped
+	// this task, in which case the channel has already been told
+	if conv, err := a.convRepo.GetByID(ctx, a.conversation.ConversationID); err == nil && conv.Ended() {
+		slog.InfoContext(ctx, "conversation already ended, exiting", "status", conv.Status)
+		a.flushUsage(ctx)
+		return nil
+	}
+
+	a.post(ctx, "⏹️ This session is ending because I'm being shut down. Mention me again to start a new one.")
+	return a.complete(ctx)
+}
+
+// Merged ends a conversation merged into another. The merge notice already
+// points the channel at the other conversation
+func (a *Agent) Merged(ctx context.Context, intoID string) error {
+	ctx = a.logContext(ctx)
+	slog.InfoContext(ctx, "conversation merged, ending", "into_conversation_id", intoID)
+	return a.complete(ctx)
+}
+
+// Joined picks up what merging fromID into this conversation changed: its
+// history now has the other conversation's messages, so the summary of the
+// earlier ones no longer applies, and its people now take part here
+func (a *Agent) Joined(ctx context.Context, fromID string) {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+	slog.InfoContext(ctx, "conversation merged in", "from_conversation_id", fromID)
+
+	conv.Summary, conv.Summarized = "", 0
+	from, err := a.convRepo.GetByID(ctx, fromID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get merged conversation", "from_conversation_id", fromID, "error", err)
+		return
+	}
+	added := conv.AddParticipant(from.UserID)
+	for _, p := range from.Participants {
+		added = conv.AddParticipant(p) || added
+	}
+	if added {
+		if err := a.convRepo.UpdateParticipants(ctx, conv.ConversationID, conv.Participants); err != nil {
+			slog.WarnContext(ctx, "failed to save participants", "error", err)
+		}
+	}
+}
+
+// complete records the usage not yet flushed and marks the conversation
+// completed without saying goodbye or publishing a report
+func (a *Agent) complete(ctx context.Context) error {
+	conv := a.conversation
+
+	a.flushUsage(ctx)
+	conv.UpdateStatus(models.StatusCompleted)
+	a.watchers.StatusChanged(ctx, conv, models.StatusCompleted)
+	a.webhooks.Send(ctx, webhook.EventCompleted, conv)
+	lifecycle.Mark(ctx, a.slackClient, conv, lifecycle.ForStatus(models.StatusCompleted))
+	return a.convRepo.UpdateStatus(ctx, conv.ConversationID, models.StatusCompleted)
+}
+
+// Finish ends an idle conversation: it says goodbye, publishes the incident
+// report, and marks the conversation completed
+func (a *Agent) Finish(ctx context.Context) error {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+
+	ctx = usage.WithMeter(ctx, a.meter)
+	defer a.flushUsage(ctx)
+
+	a.post(ctx, "💤 Ending this session due to inactivity. Mention me again to start a new one.")
+	conv.UpdateStatus(models.StatusCompleted)
+	a.publishReport(ctx)
+	a.watchers.StatusChanged(ctx, conv, models.StatusCompleted)
+	a.webhooks.Send(ctx, webhook.EventCompleted, conv)
+	lifecycle.Mark(ctx, a.slackClient, conv, lifecycle.ForStatus(models.StatusCompleted))
+	return a.convRepo.UpdateStatus(ctx, conv.ConversationID, models.StatusCompleted)
+}
+
+// HandleMessage runs a single conversation turn: it records the user's
+// message, asks Claude for a response, and posts the response to Slack
+func (a *Agent) HandleMessage(ctx context.Context, userID, text string) error {
+	return a.HandleMessages(ctx, []coalesce.Message{{UserID: userID, Text: text}})
+}
+
+// HandleMessages answers messages sent in quick succession with a single
+// turn, so rapid corrections don't get separate, contradictory replies. The
+// turn runs through the agent's pipeline
+func (a *Agent) HandleMessages(ctx context.Context, msgs []coalesce.Message) error {
+	conv := a.conversation
+	ctx = a.logContext(ctx)
+
+	ctx = usage.WithMeter(ctx, a.meter)
+	if !a.dedicated {
+		a.lastFlush = time.Now()
+	}
+	defer a.flushUsage(ctx)
+
+	turn := &Turn{Conversation: conv}
+	for _, m := range msgs {
+		// The model sees names instead of user IDs, and learns each
+		// sender's name so it can mention them back
+		m.Text = strings.TrimSpace(a.mentions.Resolve(ctx, m.Text))
+		if m.Text == "" {
+			continue
+		}
+		a.mentions.Name(ctx, m.UserID)
+		turn.Messages = append(turn.Messages, m)
+		if conv.AddParticipant(m.UserID) {
+			turn.joined = true
+		}
+	}
+
+	// Messages from several people are attributed with mentions
+	turn.Text = a.mentions.Resolve(ctx, coalesce.Combine(turn.Messages))
+	if turn.Text == "" {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "handling messages", "messages", len(turn.Messages))
+
+	// Give immediate feedback; Bedrock can take a while. The placeholder
+	// becomes the answer, or is removed if the turn fails or a step stops it
+	turn.placeholder = a.postPlaceholder(ctx)
+	defer func() {
+		if !turn.replied {
+			a.removePlaceholder(ctx, turn.placeholder)
+		}
+	}()
+
+	started := time.Now()
+	err := a.pipeline.Handler()(ctx, turn)
+	logging.Metric(ctx, "TurnDuration", float64(time.Since(started).Milliseconds()), logging.UnitMilliseconds)
+	return err
+}
+
+// logContext tags lines logged for the conversation with its IDs
+func (a *Agent) logContext(ctx context.Context) context.Context {
+	conv := a.conversation
+	return logging.With(ctx, logging.ConversationID, conv.ConversationID, logging.ChannelID, conv.ChannelID)
+}
+
+// Pipeline returns the steps the agent's turns pass through, for adding
+// middleware to this agent only
+func (a *Agent) Pipeline() *Pipeline {
+	return a.pipeline
+}
+
+// newPipeline builds the agent's built-in steps, with the middleware
+// registered with Use and UseTools
+func (a *Agent) newPipeline() *Pipeline {
+	p := &Pipeline{}
+	p.add(StepAuth, a.authorize)
+	p.add(StepRateLimit, a.rateLimit)
+	p.add(StepContext, a.buildContext)
+	p.add(StepModel, a.callModel)
+	p.add(StepRender, a.render)
+	p.add(StepPersist, a.persist)
+	extend(p)
+	return p
+}
+
+// authorize checks tool calls and classifies their results against the
+// least privileged sender, so nobody sees restricted data or runs a tool
+// through someone else's question
+func (a *Agent) authorize(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		turn.Profile = a.profileOf(ctx, turn.Messages)
+		turn.Policy = privacy.NewPolicy(turn.Conversation.Visibility, turn.Profile)
+		return next(rbac.WithProfile(ctx, turn.Profile), turn)
+	}
+}
+
+// rateLimit holds the turn until it fits within TURNS_PER_MINUTE
+func (a *Agent) rateLimit(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		if err := a.limiter.wait(ctx, turn.Conversation.ConversationID); err != nil {
+			return err
+		}
+		return next(ctx, turn)
+	}
+}
+
+// buildContext records the user's message and loads the history the model
+// answers from
+func (a *Agent) buildContext(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		conv := turn.Conversation
+		if turn.joined {
+			if err := a.convRepo.UpdateParticipants(ctx, conv.ConversationID, conv.Participants); err != nil {
+				slog.WarnContext(ctx, "failed to save participants", "error", err)
+			}
+		}
+
+		if err := a.convRepo.SaveMessage(ctx, conv.ConversationID, models.RoleUser, turn.Text); err != nil {
+			return fmt.Errorf("save user message: %w", err)
+		}
+
+		history, err := a.convRepo.GetMessageHistory(ctx, conv.ConversationID)
+		if err != nil {
+			return fmt.Errorf("get message history: %w", err)
+		}
+		turn.History = a.fitHistory(ctx, history)
+		return next(ctx, turn)
+	}
+}
+
+// fitHistory keeps the history within the model's context budget, saving
+// the summary of messages it leaves out
+func (a *Agent) fitHistory(ctx context.Context, history []models.Message) []models.Message {
+	if a.window == nil {
+		return history
+	}
+
+	conv := a.conversation
+	w := a.window.Fit(ctx, history, conv.Summary, conv.Summarized)
+	if w.Summarized != conv.Summarized {
+		slog.InfoContext(ctx, "summarized earlier messages", "messages", w.Summarized)
+		conv.Summary, conv.Summarized = w.Summary, w.Summarized
+		if err := a.convRepo.UpdateSummary(ctx, conv.ConversationID, w.Summary, w.Summarized); err != nil {
+			slog.WarnContext(ctx, "failed to save conversation summary", "error", err)
+		}
+	}
+	return w.Messages
+}
+
+// callModel asks the model for an answer, running the tools it calls
+// through the pipeline's tool middleware
+func (a *Agent) callModel(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		answerCtx := privacy.WithPolicy(ctx, turn.Policy)
+		if a.toolAudit != nil {
+			answerCtx = bedrock.WithToolMiddleware(answerCtx, a.auditTools(turn))
+		}
+		answerCtx = bedrock.WithToolMiddleware(answerCtx, a.pipeline.tools...)
+		if a.jobs != nil {
+			requestedBy := turn.Conversation.UserID
+			if ids := senders(turn.Messages); len(ids) > 0 {
+				requestedBy = ids[len(ids)-1]
+			}
+			answerCtx = jobs.WithConversation(answerCtx, turn.Conversation, requestedBy)
+		}
+
+		// Debug mode shows the tool calls and tokens behind the answer
+		if turn.Conversation.Debug {
+			turn.trace = bedrock.NewTrace()
+			answerCtx = bedrock.WithTrace(answerCtx, turn.trace)
+		}
+
+		// Tools can ask for charts to be posted with the answer
+		if a.charts != nil {
+			turn.charts = charts.NewCollector()
+			answerCtx = charts.WithCollector(answerCtx, turn.charts)
+		}
+
+		// The answer fills in the placeholder as the model writes it
+		var s *streamer
+		if a.cfg.GetStreamInterval() > 0 && turn.placeholder != "" {
+			s = a.newStreamer(ctx, turn)
+			answerCtx = bedrock.WithStream(answerCtx, s.update)
+		}
+
+		// Tools still running past the turn's budget get what is known so
+		// far posted while the answer carries on
+		var stop func() string
+		if a.cfg.GetTurnBudget() > 0 {
+			progress := bedrock.NewProgress()
+			answerCtx = bedrock.WithProgress(answerCtx, progress)
+			stop = a.watchBudget(ctx, turn, progress, s)
+		}
+
+		response, crossCheck, err := a.answer(answerCtx, turn.History)
+		if stop != nil {
+			turn.interim = stop()
+		}
+		if err != nil {
+			return fmt.Errorf("send message to bedrock: %w", err)
+		}
+		turn.Response, turn.CrossCheck = response, crossCheck
+		return next(ctx, turn)
+	}
+}
+
+// render formats the answer for Slack and posts it, or holds it when it
+// needs confirmation first
+func (a *Agent) render(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		conv := turn.Conversation
+
+		response, update, ok := models.ParseScratchpadUpdate(turn.Response)
+		if ok {
+			turn.scratchpad = &update
+		}
+		response, widgets := charts.ParseCharts(response)
+		if turn.charts != nil {
+			widgets = append(widgets, turn.charts.Widgets()...)
+		}
+		response, suggestions := followups.Parse(response)
+		turn.Answer = response
+
+		// With a partial answer posted after the placeholder, the full
+		// answer follows it rather than filling in above it
+		if turn.interim != "" {
+			a.removePlaceholder(ctx, turn.placeholder)
+			turn.placeholder = ""
+		}
+
+		// Charts are rendered before answering so the answer can cite them
+		rendered, used := a.renderCharts(ctx, widgets)
+
+		// Long code blocks are shortened when their full content can be
+		// served on request
+		display, outputs := response, []fulloutput.Output(nil)
+		if a.outputs != nil {
+			display, outputs = fulloutput.Truncate(response, fulloutput.DefaultMaxLines, fulloutput.DefaultMaxBytes)
+		}
+
+		// The model writes GitHub-flavored markdown, which Slack doesn't
+		// render, and names people the way it saw them
+		formatted := entities.Linkify(mrkdwn.Convert(a.mentions.Mention(display)), a.cfg.AWSRegion, a.links)
+		attribution := sources.Describe(used, time.Now())
+		if turn.CrossCheck != "" {
+			attribution += "\n" + turn.CrossCheck
+		}
+		if a.cfg.Sandbox() {
+			attribution = sandbox.Banner(a.cfg.SandboxRoleARN) + "\n" + attribution
+		}
+
+		// IAM and cost details, and answers built on classified tool
+		// results, wait for someone taking part to confirm where they go
+		// before they are posted. Restricted data can only be released by
+		// an operator
+		answer := &outgoing{text: formatted, attribution: attribution, suggestions: suggestions, outputs: outputs}
+		if turn.trace != nil {
+			answer.debug = debugmode.Blocks(turn.trace)
+		}
+		categories := privacy.Sensitive(response)
+		turn.Held = privacy.NeedsConfirmation(conv.Visibility, categories)
+		if classes := turn.Policy.Confirmations(); len(classes) > 0 {
+			categories = append(categories, classes...)
+			turn.Held = true
+		}
+		var err error
+		if turn.Held {
+			minProfile := models.ProfileReadOnly
+			if turn.Policy.Restricted() {
+				minProfile = privacy.RestrictedProfile
+			}
+			err = a.hold(ctx, turn.placeholder, answer, categories, minProfile)
+		} else {
+			err = a.reply(ctx, turn.placeholder, answer)
+		}
+		if err != nil {
+			return fmt.Errorf("post response: %w", err)
+		}
+		turn.replied = true
+
+		a.uploadCharts(ctx, rendered)
+		if turn.Held {
+			a.notifyWatchers(ctx, turn.Text)
+		} else {
+			a.notifyWatchers(ctx, turn.Text, response)
+		}
+		return next(ctx, turn)
+	}
+}
+
+// persist saves the answer to the history, along with the scratchpad
+// changes and resources it brought
+func (a *Agent) persist(next Handler) Handler {
+	return func(ctx context.Context, turn *Turn) error {
+		conv := turn.Conversation
+		if turn.scratchpad != nil {
+			a.applyScratchpad(ctx, *turn.scratchpad)
+		}
+		a.recordEntities(ctx, turn.Text, turn.Answer)
+
+		if err := a.convRepo.SaveMessage(ctx, conv.ConversationID, models.RoleAssistant, turn.Answer); err != nil {
+			return fmt.Errorf("save assistant message: %w", err)
+		}
+		return next(ctx, turn)
+	}
+}
+
+// SystemPrompt combines a prompt template with the instructions for the
+// agent's response blocks, before the conversation's scratchpad is added
+func SystemPrompt(template string, withCharts bool) string {
+	prompt := template + "\n\n" + scratchpadInstructions + "\n\n" + followups.Instructions
+	if withCharts {
+		prompt += "\n\n" + chartInstructions
+	}
+	return prompt
+}
+
+// CleanResponse strips the scratchpad, chart, and follow-up blocks from a
+// model response, leaving the answer the user sees
+func CleanResponse(response string) string {
+	response, _, _ = models.ParseScratchpadUpdate(response)
+	response, _ = charts.ParseCharts(response)
+	response, _ = followups.Parse(response)
+	return response
+}
+
+// answer asks the model for a response. Critical conversations are
+// cross-checked with a second model when an ensemble is configured, and the
+// returned note says how well the models agreed
+func (a *Agent) answer(ctx context.Context, history []models.Message) (string, string, error) {
+	if a.ensemble == nil || !a.critical(ctx) {
+		response, err := a.bedrock.SendMessage(ctx, history, a.systemPrompt())
+		return response, "", err
+	}
+
+	// Both models answer, so neither answer is streamed or posted in part
+	ctx = bedrock.WithProgress(bedrock.WithStream(ctx, nil), nil)
+	result, err := a.ensemble.Answer(ctx, history, a.systemPrompt())
+	if err != nil {
+		return "", "", err
+	}
+	slog.InfoContext(ctx, "cross-checked answer", "agreement", result.Agreement)
+	return result.Answer, ensemble.Note(result.Agreement), nil
+}
+
+// critical reports whether the conversation has an ensemble tag. Tags are
+// added from slash commands outside this process, so they are re-read
+func (a *Agent) critical(ctx context.Context) bool {
+	conv := a.conversation
+	if latest, err := a.convRepo.GetByID(ctx, conv.ConversationID); err != nil {
+		slog.WarnContext(ctx, "failed to refresh conversation tags", "error", err)
+	} else {
+		conv.Tags = latest.Tags
+	}
+
+	for _, tag := range a.cfg.EnsembleTags {
+		if normalized, ok := models.NormalizeTag(tag); ok && conv.HasTag(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// systemPrompt builds the system prompt for the current turn, including the
+// conversation's scratchpad
+func (a *Agent) systemPrompt() string {
+	if a.prompt == nil {
+		a.prompt = prompts.Builtin()
+	}
+	prompt := SystemPrompt(a.prompt.Template, a.charts != nil)
+
+	if notes := a.conversation.Scratchpad.Render(); notes != "" {
+		prompt += "\n\nCurrent scratchpad:\n" + notes
+	}
+	if a.cfg.Sandbox() {
+		prompt += "\n\n" + sandbox.Instructions
+	}
+	if a.conversation.HumanAssisted {
+		prompt += "\n\n" + escalation.AssistPrompt
+	}
+
+	return prompt
+}
+
+// resolvePrompt picks the prompt version for this conversation and records
+// it, so behavior changes can be traced to the prompt that caused them. A
+// failed lookup falls back to the built-in prompt rather than failing the
+// conversation
+func (a *Agent) resolvePrompt(ctx context.Context) {
+	conv := a.conversation
+
+	prompt, err := prompts.Resolve(ctx, a.prompts, a.cfg.PromptVersion)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to resolve system prompt, using built-in", "error", err)
+		prompt = prompts.Builtin()
+	}
+	a.prompt = prompt
+	conv.PromptVersion = prompt.Version
+
+	slog.InfoContext(ctx, "using system prompt", "prompt", prompts.Label(prompt))
+	if err := a.convRepo.UpdatePromptVersion(ctx, conv.ConversationID, prompt.Version); err != nil {
+		slog.WarnContext(ctx, "failed to record prompt version", "error", err)
+	}
+}
+
+// assignCostCenter decides which team the conversation's usage is charged
+// to: the channel's mapping, else the requester's profile field
+func (a *Agent) assignCostCenter(ctx context.Context) {
+	if a.usageRepo == nil {
+		return
+	}
+	conv := a.conversation
+
+	var profileValue string
+	if _, mapped := a.cfg.ChargebackChannels[conv.ChannelID]; !mapped && a.cfg.ChargebackProfileField != "" {
+		value, err := a.slackClient.GetProfileField(ctx, conv.UserID, a.cfg.ChargebackProfileField)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to read cost center from profile", "field", a.cfg.ChargebackProfileField, logging.UserID, conv.UserID, "error", err)
+		}
+		profileValue = value
+	}
+
+	conv.CostCenter = chargeback.CostCenter(conv.ChannelID, a.cfg.ChargebackChannels, profileValue)
+	if err := a.convRepo.UpdateCostCenter(ctx, conv.ConversationID, conv.CostCenter); err != nil {
+		slog.WarnContext(ctx, "failed to record cost center", "error", err)
+	}
+}
+
+// flushUsage adds the usage metered since the last flush to the cost
+// center's monthly totals
+func (a *Agent) flushUsage(ctx context.Context) {
+	if a.usageRepo == nil {
+		return
+	}
+	conv := a.conversation
+
+	now := time.Now()
+	a.meter.AddRuntime(now.Sub(a.lastFlush))
+	a.lastFlush = now
+
+	u := a.meter.Take()
+	delta := &models.UsageRecord{
+		Month:          chargeback.Month(now),
+		CostCenter:     conv.CostCenter,
+		InputTokens:    u.InputTokens,
+		OutputTokens:   u.OutputTokens,
+		ToolCalls:      u.ToolCalls,
+		RuntimeSeconds: int64(u.Runtime.Round(time.Second) / time.Second),
+	}
+	if delta.CostCenter == "" {
+		delta.CostCenter = chargeback.Unassigned
+	}
+	if !a.counted {
+		delta.Conversations = 1
+	}
+
+	if err := a.usageRepo.Add(ctx, delta); err != nil {
+		slog.WarnContext(ctx, "failed to record usage", "error", err)
+		return
+	}
+	a.counted = true
+
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		return
+	}
+	conv.InputTokens += u.InputTokens
+	conv.OutputTokens += u.OutputTokens
+	if err := a.convRepo.AddTokens(ctx, conv.ConversationID, u.InputTokens, u.OutputTokens); err != nil {
+		slog.WarnContext(ctx, "failed to record tokens", "error", err)
+	}
+}
+
+// duplicateStatuses are the conversations a new one is compared against
+var duplicateStatuses = []string{models.StatusActive, models.StatusCompleted, models.StatusTimeout}
+
+// linkDuplicates embeds the initial report and points the channel at similar
+// recent incidents before the investigation starts. It is best-effort: any
+// failure just skips the check
+func (a *Agent) linkDuplicates(ctx context.Context) {
+	if a.embedder == nil {
+		return
+	}
+	conv := a.conversation
+
+	text := strings.TrimSpace(mentionPattern.ReplaceAllString(conv.InitialCommand, ""))
+	if text == "" {
+		return
+	}
+
+	embedding, err := a.embedder.Embed(ctx, text)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to embed initial report", "error", err)
+		return
+	}
+	conv.Embedding = embedding
+	if err := a.convRepo.UpdateEmbedding(ctx, conv.ConversationID, embedding); err != nil {
+		slog.WarnContext(ctx, "failed to save embedding", "error", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -a.cfg.DuplicateLookbackDays)
+	var recent []*models.Conversation
+	for _, status := range duplicateStatuses {
+		convs, err := a.convRepo.GetByStatusSince(ctx, status, since)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to list recent conversations", "status", status, "error", err)
+			continue
+		}
+		recent = append(recent, convs...)
+	}
+
+	matches := similarity.Nearest(embedding, conv.ConversationID, recent, a.cfg.DuplicateThreshold)
+	if len(matches) == 0 {
+		return
+	}
+
+	slog.InfoContext(ctx, "conversation is similar to an earlier one", "similar_conversation_id", matches[0].Conversation.ConversationID, "score", matches[0].Score)
+	loc := a.slackClient.GetUserLocation(ctx, conv.UserID)
+	a.post(ctx, similarity.Notice(matches, time.Now(), loc))
+}
+
+// applyScratchpad persists the scratchpad changes the model asked for
+func (a *Agent) applyScratchpad(ctx context.Context, update models.ScratchpadUpdate) {
+	conv := a.conversation
+	if conv.Scratchpad == nil {
+		conv.Scratchpad = &models.Scratchpad{}
+	}
+	conv.Scratchpad.Apply(update)
+
+	if err := a.convRepo.UpdateScratchpad(ctx, conv.ConversationID, conv.Scratchpad); err != nil {
+		slog.WarnContext(ctx, "failed to save scratchpad", "error", err)
+	}
+}
+
+// recordEntities stores AWS resources mentioned in the turn on the conversation
+func (a *Agent) recordEntities(ctx context.Context, texts ...string) {
+	conv := a.conversation
+
+	added := false
+	for _, text := range texts {
+		if conv.AddEntities(entities.Extract(text, a.cfg.AWSRegion)...) {
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	if err := a.convRepo.UpdateEntities(ctx, conv.ConversationID, conv.Entities); err != nil {
+		slog.WarnContext(ctx, "failed to save conversation entities", "error", err)
+	}
+	a.correlate(ctx)
+}
+
+// correlate links the conversation to active ones started around the same
+// time that share a resource or tag with it, telling both and offering to
+// merge them. It is best-effort: any failure just skips the link
+func (a *Agent) correlate(ctx context.Context) {
+	window := a.cfg.GetCorrelationWindow()
+	if window <= 0 {
+		return
+	}
+	conv := a.conversation
+
+	active, err := a.convRepo.GetByStatusSince(ctx, models.StatusActive, conv.CreatedAt.Add(-window))
+	if err != nil {
+		slog.WarnContext(ctx, "failed to list active conversations", "error", err)
+		return
+	}
+
+	for _, m := range correlate.Related(conv, active, window) {
+		other := m.Conversation
+		if err := a.convRepo.AddRelated(ctx, conv.ConversationID, other.ConversationID); err != nil {
+			slog.WarnContext(ctx, "failed to link related conversation", "related_conversation_id", other.ConversationID, "error", err)
+			continue
+		}
+		if err := a.convRepo.AddRelated(ctx, other.ConversationID, conv.ConversationID); err != nil {
+			slog.WarnContext(ctx, "failed to link related conversation", "related_conversation_id", other.ConversationID, "error", err)
+		}
+		conv.Related = append(conv.Related, other.ConversationID)
+		slog.InfoContext(ctx, "conversation may be the same incident as another", "related_conversation_id", other.ConversationID, "shared", m.Shared)
+
+		if _, err := a.slackClient.PostMessage(ctx, conv.ChannelID,
+			slack.MsgOptionBlocks(correlate.Blocks(conv, m)...),
+			slack.MsgOptionText(correlate.Notice(conv, m), false),
+			slackclient.InThread(conv.ThreadTS),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post related conversation", "error", err)
+		}
+		back := correlate.Match{Conversation: conv, Shared: m.Shared}
+		if _, err := a.slackClient.PostMessage(ctx, other.ChannelID,
+			slack.MsgOptionBlocks(correlate.Blocks(other, back)...),
+			slack.MsgOptionText(correlate.Notice(other, back), false),
+			slackclient.InThread(other.ThreadTS),
+		); err != nil {
+			slog.WarnContext(ctx, "failed to post in related conversation", "related_conversation_id", other.ConversationID, "error", err)
+		}
+	}
+}
+
+// notifyWatchers tells watchers when the turn mentions their keywords or resources
+func (a *Agent) notifyWatchers(ctx context.Context, texts ...string) {
+	if a.watchers == nil {
+		return
+	}
+	for _, text := range texts {
+		a.watchers.MessagePosted(ctx, a.conversation, text, entities.Extract(text, a.cfg.AWSRegion))
+	}
+}
+
+// renderedChart is a chart image waiting to be uploaded
+type renderedChart struct {
+	widget charts.Widget
+	image  []byte
+}
+
+// renderCharts renders requested metric charts, returning the images and the
+// CloudWatch data they were drawn from
+func (a *Agent) renderCharts(ctx context.Context, widgets []charts.Widget) ([]renderedChart, []sources.Source) {
+	if a.charts == nil {
+		return nil, nil
+	}
+
+	var rendered []renderedChart
+	var used []sources.Source
+	now := time.Now()
+	for _, w := range widgets {
+		image, err := a.charts.Render(ctx, w)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to render chart", "chart", w.Title, "error", err)
+			continue
+		}
+		rendered = append(rendered, renderedChart{widget: w, image: image})
+		used = append(used, a.chartSources(w, now)...)
+	}
+	return rendered, used
+}
+
+// chartSources describes the metrics behind a chart
+func (a *Agent) chartSources(w charts.Widget, now time.Time) []sources.Source {
+	hours := w.Hours
+	if hours == 0 {
+		hours = charts.DefaultHours
+	}
+	region := w.Region
+	if region == "" {
+		region = a.cfg.AWSRegion
+	}
+
+	start, end := now.Add(-time.Duration(hours)*time.Hour), now
+	if !w.Start.IsZero() {
+		start, end = w.Start, w.End
+	}
+
+	srcs := make([]sources.Source, 0, len(w.Metrics))
+	for _, m := range w.Metrics {
+		srcs = append(srcs, sources.Source{
+			Tool:    "CloudWatch metrics",
+			Target:  m.Namespace + " " + m.Name,
+			Start:   start,
+			End:     end,
+			Region:  region,
+			Account: a.cfg.ConsoleSwitchRoleAccount, // the account console links point at
+		})
+	}
+	return srcs
+}
+
+// uploadCharts attaches rendered charts to the channel
+func (a *Agent) uploadCharts(ctx context.Context, rendered []renderedChart) {
+	for i, c := range rendered {
+		filename := fmt.Sprintf("chart-%d.png", i+1)
+		if err := a.slackClient.UploadFile(ctx, a.conversation.ChannelID, a.conversation.ThreadTS, filename, c.widget.Title, c.image); err != nil {
+			slog.WarnContext(ctx, "failed to upload chart", "chart", c.widget.Title, "error", err)
+		}
+	}
+}
+
+// publishReport renders an incident report for the conversation, stores it
+// in S3, and links it in the channel
+func (a *Agent) publishReport(ctx context.Context) {
+	if a.reports == nil {
+		return
+	}
+	conv := a.conversation
+
+	history, err := a.convRepo.GetHistoryItems(ctx, conv.ConversationID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load history for report", "error", err)
+		return
+	}
+	if len(history) == 0 {
+		return
+	}
+
+	summary, err := a.summarize(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to summarize conversation for report", "error", err)
+	}
+
+	var calls []*models.ToolExecution
+	if a.toolAudit != nil {
+		if calls, err = a.toolAudit.ListConversationToolExecutions(ctx, conv.ConversationID); err != nil {
+			slog.WarnContext(ctx, "failed to list tool calls for report", "error", err)
+		}
+	}
+
+	html, err := report.Build(conv, history, calls, summary).HTML()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to render report", "error", err)
+		return
+	}
+
+	link, expires, err := a.reports.SaveHTML(ctx, conv.ConversationID, html)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to store report", "error", err)
+		return
+	}
+
+	a.post(ctx, fmt.Sprintf("📄 <%s|Incident report draft> is ready (link valid until <!date^%d^{date_short_pretty} {time}|%s>).",
+		link, expires.Unix(), expires.UTC().Format("Jan 2 15:04 UTC")))
+}
+
+// summarize asks Claude for a short summary of the conversation so far
+func (a *Agent) summarize(ctx context.Context) (string, error) {
+	history, err := a.convRepo.GetMessageHistory(ctx, a.conversation.ConversationID)
+	if err != nil {
+		return "", err
+	}
+
+	history = append(a.fitHistory(ctx, history), models.Message{
+		Role:    models.RoleUser,
+		Content: "Summarize this conversation for an incident report in 3-5 sentences: the problem, what was investigated, and the outcome. Reply with the summary only.",
+	})
+	return a.bedrock.SendMessage(ctx, history, bedrock.GetSystemPrompt())
+}
+
+// postPlaceholder posts the "thinking" message that the answer will replace,
+// returning its timestamp or "" if it couldn't be posted
+func (a *Agent) postPlaceholder(ctx context.Context) string {
+	ts, err := a.slackClient.PostMessage(ctx, a.conversation.ChannelID, slack.MsgOptionText(thinkingPlaceholder, false), slackclient.InThread(a.conversation.ThreadTS))
+	if err != nil {
+		slog.WarnContext(ctx, "failed to post thinking placeholder", "error", err)
+		return ""
+	}
+	return ts
+}
+
+// reply replaces the placeholder with the answer, a context line citing its
+// sources, and any suggested follow-ups, posting a new message when there is
+// no placeholder or it can't be updated. Answers too long for one message
+// continue in numbered follow-on messages, with the sources, follow-ups, and
+// buttons for shortened outputs on the last
+func (a *Agent) reply(ctx context.Context, placeholder string, answer *outgoing) error {
+	channelID := a.conversation.ChannelID
+	parts := splitter.Numbered(answer.text, maxMessageText)
+	if len(parts) == 0 {
+		parts = []string{answer.text}
+	}
+	outputs := answer.outputs
+
+	for i, part := range parts {
+		blocks := sectionBlocks(part)
+		last := i == len(parts)-1
+		if last {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, answer.attribution, false, false)))
+			blocks = append(blocks, answer.debug...)
+			if block := fulloutput.Block(outputs); block != nil {
+				blocks = append(blocks, block)
+			}
+			if block := followups.Block(answer.suggestions); block != nil {
+				blocks = append(blocks, block)
+			}
+		}
+		opts := []slack.MsgOption{slack.MsgOptionText(part, false), slack.MsgOptionBlocks(blocks...)}
+
+		ts, err := a.send(ctx, placeholder, i == 0, opts)
+		if err != nil {
+			return err
+		}
+		if last && len(outputs) > 0 {
+			if err := a.outputs.Save(ctx, channelID, ts, outputs); err != nil {
+				slog.WarnContext(ctx, "failed to save full outputs", "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// hold replaces the placeholder with a notice asking where a sensitive
+// answer should go, keeping the answer until someone with at least
+// minProfile confirms
+func (a *Agent) hold(ctx context.Context, placeholder string, answer *outgoing, categories []string, minProfile string) error {
+	blocks := privacy.Notice(categories, minProfile)
+	opts := []slack.MsgOption{
+		slack.MsgOptionText("🔒 This answer is waiting for confirmation before it is posted.", false),
+		slack.MsgOptionBlocks(blocks...),
+	}
+	ts, err := a.send(ctx, placeholder, true, opts)
+	if err != nil {
+		return err
+	}
+	answer.minProfile = minProfile
+	a.held[ts] = answer
+	return nil
+}
+
+// Release sends an answer held behind the notice at noticeTS: to the
+// conversation when userID confirmed posting it, or to userID alone when
+// they asked for it privately. Someone who may not release it leaves it held
+func (a *Agent) Release(ctx context.Context, userID, action, noticeTS string) error {
+	ctx = a.logContext(ctx)
+	answer, ok := a.held[noticeTS]
+	if !ok {
+		a.post(ctx, "That answer is no longer available. Please ask again.")
+		return nil
+	}
+	if !a.mayRelease(ctx, userID, answer) {
+		slog.WarnContext(ctx, "refused to release held answer", logging.UserID, userID, "min_profile", answer.minProfile)
+		return nil
+	}
+	delete(a.held, noticeTS)
+
+	if action == privacy.ActionDM {
+		return a.direct(ctx, userID, answer)
+	}
+	return a.reply(ctx, "", answer)
+}
+
+// mayRelease reports whether userID takes part in the conversation and has
+// the profile a held answer needs. The interaction handler checks the same
+// before posting a confirmation, but the agent doesn't rely on it
+func (a *Agent) mayRelease(ctx context.Context, userID string, answer *outgoing) bool {
+	if !a.conversation.TookPart(userID) {
+		return false
+	}
+	if answer.minProfile == "" || answer.minProfile == models.ProfileReadOnly {
+		return true
+	}
+	return models.ProfileAtLeast(a.profileOf(ctx, []coalesce.Message{{UserID: userID}}), answer.minProfile)
+}
+
+// direct sends an answer to a user's DMs, without the follow-up and full
+// output buttons, which only work in the conversation
+func (a *Agent) direct(ctx context.Context, userID string, answer *outgoing) error {
+	parts := splitter.Numbered(answer.text, maxMessageText)
+	if len(parts) == 0 {
+		parts = []string{answer.text}
+	}
+
+	for i, part := range parts {
+		blocks := sectionBlocks(part)
+		if i == len(parts)-1 {
+			blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, answer.attribution, false, false)))
+		}
+		if _, err := a.slackClient.PostMessage(ctx, userID, slack.MsgOptionText(part, false), slack.MsgOptionBlocks(blocks...)); err != nil {
+			return fmt.Errorf("send answer privately: %w", err)
+		}
+	}
+	return nil
+}
+
+// profileOf returns the least privileged permission profile among the
+// senders of a turn. Users whose profile can't be read count as read-only,
+// unless the tool access policy grants them more
+func (a *Agent) profileOf(ctx context.Context, msgs []coalesce.Message) string {
+	least := models.ProfileAdmin
+	for _, m := range msgs {
+		profile := models.ProfileReadOnly
+		switch {
+		case contains(a.cfg.AdminUsers, m.UserID):
+			profile = models.ProfileAdmin
+		case a.permissions != nil:
+			stored, ok := a.packedProfile(m.UserID)
+			if !ok {
+				var err error
+				if stored, err = a.permissions.Get(ctx, m.UserID); err != nil {
+					slog.WarnContext(ctx, "failed to get permissions", logging.UserID, m.UserID, "error", err)
+				}
+			}
+			profile = stored.Effective(time.Now())
+		}
+		if a.rbac != nil {
+			profile = a.rbac.Profile(ctx, m.UserID, profile)
+		}
+		if !models.ProfileAtLeast(profile, least) {
+			least = profile
+		}
+	}
+	return least
+}
+
+// packedProfile returns a user's profile from the context pack while it is
+// trusted, and whether the pack had them
+func (a *Agent) packedProfile(userID string) (*models.PermissionProfile, bool) {
+	if a.packed == nil || time.Now().After(a.packedUntil) {
+		return nil, false
+	}
+	profile, ok := a.packed.Permissions[userID]
+	return profile, ok
+}
+
+// send posts one message of a reply, replacing the placeholder with the
+// first, and returns its timestamp
+func (a *Agent) send(ctx context.Context, placeholder string, first bool, opts []slack.MsgOption) (string, error) {
+	channelID := a.conversation.ChannelID
+	if first && placeholder != "" {
+		err := a.slackClient.UpdateMessage(ctx, channelID, placeholder, opts...)
+		if err == nil {
+			return placeholder, nil
+		}
+		slog.WarnContext(ctx, "failed to replace thinking placeholder", "error", err)
+		a.removePlaceholder(ctx, placeholder)
+	}
+	return a.slackClient.PostMessage(ctx, channelID, append(opts, slackclient.InThread(a.conversation.ThreadTS))...)
+}
+
+const (
+	// maxSectionText is Slack's limit for the text of a section block
+	maxSectionText = 3000
+
+	// maxMessageText keeps each message within the 4,000 characters Slack
+	// recommends, past which it truncates notification and fallback text
+	maxMessageText = 3900
+)
+
+// sectionBlocks lays out text as section blocks
+func sectionBlocks(text string) []slack.Block {
+	var blocks []slack.Block
+	for _, chunk := range splitter.Split(text, maxSectionText) {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false), nil, nil))
+	}
+	return blocks
+}
+
+// removePlaceholder deletes a placeholder that won't be replaced
+func (a *Agent) removePlaceholder(ctx context.Context, placeholder string) {
+	if placeholder == "" {
+		return
+	}
+	if err := a.slackClient.DeleteMessage(ctx, a.conversation.ChannelID, placeholder); err != nil {
+		slog.WarnContext(ctx, "failed to remove thinking placeholder", "error", err)
+	}
+}
+
+// post sends a plain text message to the conversation channel, logging failures
+func (a *Agent) post(ctx context.Context, text string) {
+	if _, err := a.slackClient.PostMessage(ctx, a.conversation.ChannelID, slack.MsgOptionText(text, false), slackclient.InThread(a.conversation.ThreadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post message", "error", err)
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/mrkdwn"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// budgetRecheck is how often a turn past its budget checks whether the
+// model has started running tools, when it was writing as the budget ran out
+const budgetRecheck = time.Second
+
+// stillGathering ends a partial answer
+const stillGathering = "_⏳ Still gathering data; the full answer will follow when the remaining tools finish._"
+
+// watchBudget posts a partial answer once the turn has run for
+// TURN_BUDGET_SECONDS with tools still running, so slow tools don't leave
+// the user waiting in silence. The returned stop ends the watch, returning
+// the partial answer's timestamp, or "" when none was posted
+func (a *Agent) watchBudget(ctx context.Context, turn *Turn, progress *bedrock.Progress, s *streamer) (stop func() string) {
+	ctx, cancel := context.WithCancel(ctx)
+	posted := make(chan string, 1)
+	go func() {
+		timer := time.NewTimer(a.cfg.GetTurnBudget())
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				posted <- ""
+				return
+			case <-timer.C:
+			}
+			if len(progress.Running()) > 0 {
+				posted <- a.postInterim(ctx, turn, progress, s)
+				return
+			}
+			timer.Reset(budgetRecheck)
+		}
+	}()
+	return func() string {
+		cancel()
+		return <-posted
+	}
+}
+
+// postInterim posts what the tool results so far show, under the note that
+// more is coming, and returns its timestamp. The answer streaming into the
+// placeholder stops, since the full answer is posted after this one
+func (a *Agent) postInterim(ctx context.Context, turn *Turn, progress *bedrock.Progress, s *streamer) string {
+	known, err := a.bedrock.Interim(ctx, turn.History, a.systemPrompt(), progress)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to summarize partial results", "error", err)
+	}
+	format := func(text string) string {
+		return mrkdwn.Convert(a.mentions.Mention(text))
+	}
+	text := interimText(CleanResponse(known), turn.Conversation.Visibility, turn.Policy, format)
+
+	s.pause()
+	ts, err := a.slackClient.PostMessage(ctx, turn.Conversation.ChannelID, slack.MsgOptionText(text, false), slackclient.InThread(turn.Conversation.ThreadTS))
+	if err != nil {
+		slog.WarnContext(ctx, "failed to post partial answer", "error", err)
+		return ""
+	}
+	slog.InfoContext(ctx, "posted partial answer", "running", progress.Running())
+	logging.Metric(ctx, "TurnBudgetExceeded", 1, logging.UnitCount)
+	return ts
+}
+
+// interimText lays out a partial answer: what is known so far, left out
+// when it would need confirmation like the answer it precedes, and the note
+// that more is coming
+func interimText(known, visibility string, policy *privacy.Policy, format func(string) string) string {
+	known = strings.TrimSpace(known)
+	if known == "" || len(policy.Confirmations()) > 0 || privacy.NeedsConfirmation(visibility, privacy.Sensitive(known)) {
+		return stillGathering
+	}
+	return format(known) + "\n\n" + stillGathering
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+)
+
+func TestInterimText(t *testing.T) {
+	format := func(text string) string { return strings.ToUpper(text) }
+	policy := privacy.NewPolicy(privacy.Public, models.ProfileReadOnly)
+
+	tests := []struct {
+		name       string
+		known      string
+		visibility string
+		want       string
+	}{
+		{"findings", "Two tasks are failing health checks.", privacy.Public, "TWO TASKS ARE FAILING HEALTH CHECKS.\n\n" + stillGathering},
+		{"nothing known yet", "  ", privacy.Public, stillGathering},
+		{"held in public", "The role allows iam:PassRole on arn:aws:iam::123456789012:role/deploy", privacy.Public, stillGathering},
+		{"shown in private", "The role allows iam:PassRole on arn:aws:iam::123456789012:role/deploy", privacy.Private, "THE ROLE ALLOWS IAM:PASSROLE ON ARN:AWS:IAM::123456789012:ROLE/DEPLOY\n\n" + stillGathering},
+	}
+	for _, tt := range tests {
+		if got := interimText(tt.known, tt.visibility, policy, format); got != tt.want {
+			t.Errorf("%s: interimText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStreamerPause(t *testing.T) {
+	s, updater := newTestStreamer(privacy.Public)
+
+	s.update("Checking the service")
+	s.pause()
+	s.update("Checking the service and its logs")
+	if updater.updates != 1 {
+		t.Errorf("%d updates, want none after pausing", updater.updates)
+	}
+
+	var none *streamer
+	none.pause()
+}
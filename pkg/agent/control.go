@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/control"
+)
+
+// operator holds what the control API changes in a running conversation,
+// and the last snapshot of it the Run loop took. The loop applies changes
+// on its next poll
+type operator struct {
+	mu          sync.Mutex
+	paused      bool
+	idleTimeout time.Duration // 0 uses the configured inactivity timeout
+	injected    []coalesce.Message
+	snapshot    control.State
+}
+
+// State returns the conversation as of the Run loop's last poll
+func (a *Agent) State() control.State {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	state := a.op.snapshot
+	state.Paused = a.op.paused
+	state.IdleTimeout = a.idleTimeoutLocked().String()
+	return state
+}
+
+// Pause stops the agent answering until it is resumed. Messages posted
+// meanwhile wait, and the conversation isn't ended for being idle
+func (a *Agent) Pause(paused bool) {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	a.op.paused = paused
+}
+
+// Inject has the agent answer text as if userID, or the requester when
+// empty, had posted it in the conversation
+func (a *Agent) Inject(userID, text string) {
+	if userID == "" {
+		userID = a.conversation.UserID
+	}
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	a.op.injected = append(a.op.injected, coalesce.Message{UserID: userID, Text: text, At: time.Now()})
+}
+
+// SetIdleTimeout changes how long the conversation can go without a
+// message before it ends
+func (a *Agent) SetIdleTimeout(timeout time.Duration) {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	a.op.idleTimeout = timeout
+}
+
+// idleTimeout returns how long the conversation can go without a message
+func (a *Agent) idleTimeout() time.Duration {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	return a.idleTimeoutLocked()
+}
+
+func (a *Agent) idleTimeoutLocked() time.Duration {
+	if a.op.idleTimeout > 0 {
+		return a.op.idleTimeout
+	}
+	return a.cfg.GetInactivityTimeout()
+}
+
+// operatorPaused reports whether the control API paused the agent
+func (a *Agent) operatorPaused() bool {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	return a.op.paused
+}
+
+// takeInjected returns the messages injected since the last call
+func (a *Agent) takeInjected() []coalesce.Message {
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	msgs := a.op.injected
+	a.op.injected = nil
+	return msgs
+}
+
+// observe records the Run loop's state for State to report
+func (a *Agent) observe(pending int, lastActivity time.Time) {
+	conv := a.conversation
+	state := control.State{
+		ConversationID: conv.ConversationID,
+		ChannelID:      conv.ChannelID,
+		ThreadTS:       conv.ThreadTS,
+		Status:         conv.Status,
+		Pending:        pending,
+		LastActivity:   lastActivity,
+		Checkpoint:     a.checkpoint,
+		Debug:          conv.Debug,
+		HumanAssisted:  conv.HumanAssisted,
+		Participants:   append([]string(nil), conv.Participants...),
+	}
+
+	a.op.mu.Lock()
+	defer a.op.mu.Unlock()
+	a.op.snapshot = state
+}
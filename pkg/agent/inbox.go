@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// inboxFallback is how often an agent with an inbox reads the channel when
+// nothing new has been delivered, in case an event was lost
+const inboxFallback = 30 * time.Second
+
+// Inbox reports the newest message Slack's Events API delivered for a
+// conversation
+type Inbox interface {
+	Latest(ctx context.Context, conversationID string) (string, error)
+}
+
+// SetInbox has the agent read the channel only when its inbox holds a
+// message it hasn't seen, or every inboxFallback, rather than on every
+// poll. The inbox is cheaper to check than Slack, whose history APIs are
+// rate limited across the workspace
+func (a *Agent) SetInbox(inbox Inbox) {
+	a.inbox = inbox
+}
+
+// due reports whether the channel should be read for messages after lastTS
+func (a *Agent) due(ctx context.Context, lastTS string, now time.Time) bool {
+	if a.inbox == nil || now.Sub(a.lastRead) >= inboxFallback {
+		return true
+	}
+	latest, err := a.inbox.Latest(ctx, a.conversation.ConversationID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to check inbox, reading the channel", "error", err)
+		return true
+	}
+	return latest > lastTS
+}
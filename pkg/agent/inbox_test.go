@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+type fakeInbox struct {
+	latest string
+	err    error
+}
+
+func (f *fakeInbox) Latest(ctx context.Context, conversationID string) (string, error) {
+	return f.latest, f.err
+}
+
+func TestDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	conv := &models.Conversation{ConversationID: "conv-1"}
+
+	if a := (&Agent{conversation: conv, lastRead: now}); !a.due(ctx, "1700000000.000100", now) {
+		t.Error("due() without an inbox should always read the channel")
+	}
+
+	inbox := &fakeInbox{latest: "1700000000.000100"}
+	a := &Agent{conversation: conv, inbox: inbox, lastRead: now}
+	if a.due(ctx, "1700000000.000100", now) {
+		t.Error("due() should wait when the inbox has nothing newer")
+	}
+	if !a.due(ctx, "1700000000.000100", now.Add(inboxFallback)) {
+		t.Error("due() should read the channel after the fallback interval")
+	}
+
+	inbox.latest = "1700000005.000200"
+	if !a.due(ctx, "1700000000.000100", now) {
+		t.Error("due() should read the channel for a newer message")
+	}
+
+	inbox.err = errors.New("throttled")
+	if !a.due(ctx, "1700000009.000000", now) {
+		t.Error("due() should read the channel when the inbox can't be checked")
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/followups"
+	"github.com/slack-go/slack"
+)
+
+// fromBot reports whether the bot posted msg. Metadata on anyone else's
+// message is ignored, since anyone in the channel can attach any payload
+func (a *Agent) fromBot(msg slack.Message) bool {
+	return a.botUserID != "" && msg.User == a.botUserID
+}
+
+// followUp returns the clicked follow-up msg carries, as sent by the user
+// who clicked it. Only the bot posts follow-ups; one from anyone else could
+// name any user and borrow their permissions
+func (a *Agent) followUp(msg slack.Message) (coalesce.Message, bool) {
+	if !a.fromBot(msg) {
+		return coalesce.Message{}, false
+	}
+	userID, text, ok := followups.FromMetadata(msg.Metadata)
+	if !ok {
+		return coalesce.Message{}, false
+	}
+	return coalesce.FromSlack(userID, text, msg.Timestamp), true
+}
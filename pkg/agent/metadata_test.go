@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/followups"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/slack-go/slack"
+)
+
+const testBotUserID = "UBOT"
+
+// metadataMessage returns a message from sender carrying meta
+func metadataMessage(sender string, meta slack.SlackMetadata) slack.Message {
+	msg := slack.Message{}
+	msg.User = sender
+	msg.Timestamp = "1700000000.000100"
+	msg.Metadata = meta
+	return msg
+}
+
+func TestFollowUp(t *testing.T) {
+	a := &Agent{botUserID: testBotUserID}
+	meta := followups.Metadata("U1", "Show the logs")
+
+	m, ok := a.followUp(metadataMessage(testBotUserID, meta))
+	if !ok || m.UserID != "U1" || m.Text != "Show the logs" {
+		t.Errorf("followUp() = %+v, %v; want the suggestion from U1", m, ok)
+	}
+
+	// Anyone can attach the same payload naming someone else
+	if m, ok := a.followUp(metadataMessage("U2", meta)); ok {
+		t.Errorf("followUp() = %+v from a non-bot sender, want it ignored", m)
+	}
+
+	// Before the bot's user ID is known nothing is trusted
+	if _, ok := (&Agent{}).followUp(metadataMessage("", meta)); ok {
+		t.Error("followUp() trusted metadata without knowing the bot's user ID")
+	}
+}
+
+func TestMayRelease(t *testing.T) {
+	conv := models.NewConversation("C1", "U1", "why is checkout slow?")
+	conv.AddParticipant("U2")
+	a := &Agent{cfg: &config.Config{AdminUsers: []string{"U2", "U9"}}, conversation: conv}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		userID     string
+		minProfile string
+		want       bool
+	}{
+		{"requester", "U1", models.ProfileReadOnly, true},
+		{"outsider", "U3", models.ProfileReadOnly, false},
+		{"outsider admin", "U9", "", false},
+		{"restricted, read-only", "U1", privacy.RestrictedProfile, false},
+		{"restricted, admin", "U2", privacy.RestrictedProfile, true},
+	}
+	for _, tt := range tests {
+		if got := a.mayRelease(ctx, tt.userID, &outgoing{minProfile: tt.minProfile}); got != tt.want {
+			t.Errorf("%s: mayRelease() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+)
+
+// Step names a stage of the turn pipeline
+type Step string
+
+// The steps of a turn, in the order they run
+const (
+	// StepAuth decides what the senders may see
+	StepAuth Step = "auth"
+
+	// StepRateLimit holds back turns past the conversation's limit
+	StepRateLimit Step = "rate_limit"
+
+	// StepContext records the user's message and loads the history the
+	// model answers from
+	StepContext Step = "context"
+
+	// StepModel asks the model for an answer. Tool dispatch happens inside
+	// it, once for each tool call the model makes; see Pipeline.UseTools
+	StepModel Step = "model"
+
+	// StepRender formats the answer and posts it to Slack, or holds it for
+	// confirmation
+	StepRender Step = "render"
+
+	// StepPersist saves the answer and what it taught the conversation
+	StepPersist Step = "persist"
+)
+
+// Turn is one conversation turn on its way through the pipeline. Each step
+// fills in what later steps read
+type Turn struct {
+	Conversation *models.Conversation
+
+	// Messages answered in this turn, with mentions resolved, and Text,
+	// their combined content as the model sees it
+	Messages []coalesce.Message
+	Text     string
+
+	// Set by StepAuth: the least privileged sender's profile, and the
+	// policy tool results are checked against
+	Profile string
+	Policy  *privacy.Policy
+
+	// Set by StepContext: the history the model answers from, ending with
+	// Text
+	History []models.Message
+
+	// Set by StepModel: the model's response, including its scratchpad,
+	// chart and follow-up blocks, and how a second model agreed with it
+	Response   string
+	CrossCheck string
+
+	// Set by StepRender: the response without its blocks, as the
+	// conversation history keeps it, and whether it was held for
+	// confirmation instead of posted
+	Answer string
+	Held   bool
+
+	placeholder string
+	interim     string // partial answer posted when tools outlasted the turn's budget
+	joined      bool
+	replied     bool
+	trace       *bedrock.Trace
+	charts      *charts.Collector // charts requested by tools
+	scratchpad  *models.ScratchpadUpdate
+}
+
+// Handler runs the rest of the pipeline for a turn
+type Handler func(ctx context.Context, turn *Turn) error
+
+// Middleware is one step of the pipeline. It does its work and calls next
+// to continue the turn; returning without calling next stops the turn, and
+// work after next returns sees what later steps did
+type Middleware func(next Handler) Handler
+
+// Pipeline is the chain of steps a turn passes through. Middleware
+// registered at a step runs just before it, in registration order
+type Pipeline struct {
+	stages []stage
+	tools  []bedrock.ToolMiddleware
+}
+
+type stage struct {
+	step Step
+	core bool
+	mw   Middleware
+}
+
+// add appends a built-in step
+func (p *Pipeline) add(step Step, mw Middleware) {
+	p.stages = append(p.stages, stage{step: step, core: true, mw: mw})
+}
+
+// Use registers mw to run just before step, after anything already
+// registered there. It panics when the pipeline has no such step
+func (p *Pipeline) Use(step Step, mw Middleware) {
+	for i, s := range p.stages {
+		if s.core && s.step == step {
+			p.stages = slices.Insert(p.stages, i, stage{step: step, mw: mw})
+			return
+		}
+	}
+	panic(fmt.Sprintf("agent: no pipeline step %q", step))
+}
+
+// UseTools registers middleware around every tool call the model makes
+// during StepModel
+func (p *Pipeline) UseTools(mw ...bedrock.ToolMiddleware) {
+	p.tools = append(p.tools, mw...)
+}
+
+// Handler returns the function that runs a turn through the pipeline
+func (p *Pipeline) Handler() Handler {
+	h := Handler(func(ctx context.Context, turn *Turn) error { return nil })
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		h = p.stages[i].mw(h)
+	}
+	return h
+}
+
+// extension is middleware registered for every agent
+type extension struct {
+	step  Step
+	mw    Middleware
+	tools []bedrock.ToolMiddleware
+}
+
+var (
+	extensionsMu sync.Mutex
+	extensions   []extension
+)
+
+// Use registers mw to run just before step in the pipeline of every agent
+// created afterwards. Forks call it from an init function to add steps,
+// such as scanning answers for sensitive data, without changing the agent
+func Use(step Step, mw Middleware) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions = append(extensions, extension{step: step, mw: mw})
+}
+
+// UseTools registers middleware around the tool calls of every agent
+// created afterwards
+func UseTools(mw ...bedrock.ToolMiddleware) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions = append(extensions, extension{tools: mw})
+}
+
+// extend applies the registered extensions to p
+func extend(p *Pipeline) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	for _, e := range extensions {
+		if e.mw != nil {
+			p.Use(e.step, e.mw)
+		}
+		p.UseTools(e.tools...)
+	}
+}
+
+// turnLimiter holds a conversation to at most limit turns a minute
+type turnLimiter struct {
+	limit  int
+	recent []time.Time
+}
+
+// wait blocks until another turn fits within the limit
+func (l *turnLimiter) wait(ctx context.Context, conversationID string) error {
+	if l.limit <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for len(l.recent) > 0 && now.Sub(l.recent[0]) >= time.Minute {
+		l.recent = l.recent[1:]
+	}
+	if len(l.recent) >= l.limit {
+		delay := l.recent[0].Add(time.Minute).Sub(now)
+		slog.InfoContext(ctx, "conversation reached turn limit, waiting", logging.ConversationID, conversationID, "turns_per_minute", l.limit, "delay", delay.Round(time.Second).String())
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		l.recent = l.recent[1:]
+	}
+	l.recent = append(l.recent, time.Now())
+	return nil
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// record is middleware that notes it ran and continues the turn
+func record(calls *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, turn *Turn) error {
+			*calls = append(*calls, name)
+			return next(ctx, turn)
+		}
+	}
+}
+
+func TestPipelineUse(t *testing.T) {
+	var calls []string
+	p := &Pipeline{}
+	p.add(StepAuth, record(&calls, "auth"))
+	p.add(StepModel, record(&calls, "model"))
+	p.add(StepRender, record(&calls, "render"))
+
+	p.Use(StepRender, record(&calls, "dlp"))
+	p.Use(StepRender, record(&calls, "audit"))
+	p.Use(StepAuth, record(&calls, "sso"))
+
+	if err := p.Handler()(context.Background(), &Turn{}); err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	want := "[sso auth model dlp audit render]"
+	if got := fmt.Sprint(calls); got != want {
+		t.Errorf("calls = %s, want %s", got, want)
+	}
+}
+
+func TestPipelineStop(t *testing.T) {
+	var calls []string
+	p := &Pipeline{}
+	p.add(StepModel, record(&calls, "model"))
+	p.add(StepRender, record(&calls, "render"))
+
+	errBlocked := errors.New("blocked")
+	p.Use(StepRender, func(next Handler) Handler {
+		return func(ctx context.Context, turn *Turn) error {
+			if turn.Response == "secret" {
+				return errBlocked
+			}
+			return next(ctx, turn)
+		}
+	})
+
+	err := p.Handler()(context.Background(), &Turn{Response: "secret"})
+	if !errors.Is(err, errBlocked) {
+		t.Errorf("Handler() error = %v, want %v", err, errBlocked)
+	}
+	if got := fmt.Sprint(calls); got != "[model]" {
+		t.Errorf("calls = %s, want render skipped", got)
+	}
+}
+
+func TestPipelineUseUnknownStep(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Use() with an unknown step didn't panic")
+		}
+	}()
+	p := &Pipeline{}
+	p.add(StepAuth, record(new([]string), "auth"))
+	p.Use(Step("missing"), record(new([]string), "x"))
+}
+
+func TestTurnLimiter(t *testing.T) {
+	l := &turnLimiter{limit: 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 2; i++ {
+		if err := l.wait(ctx, "c1"); err != nil {
+			t.Fatalf("wait() %d error = %v", i, err)
+		}
+	}
+
+	// A third turn within the minute waits until canceled
+	cancel()
+	if err := l.wait(ctx, "c1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() over the limit = %v, want it to wait", err)
+	}
+
+	unlimited := &turnLimiter{}
+	for i := 0; i < 100; i++ {
+		if err := unlimited.wait(context.Background(), "c1"); err != nil {
+			t.Fatalf("unlimited wait() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/mrkdwn"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/slack-go/slack"
+)
+
+// streamCursor ends an answer while the model is still writing it
+const streamCursor = " ▍"
+
+// blockTags open the response blocks that are parsed out of an answer
+// rather than shown
+var blockTags = []string{"<scratchpad>", "<chart>", "<followups>"}
+
+// messageUpdater edits a posted Slack message
+type messageUpdater interface {
+	UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error
+}
+
+// streamer shows an answer in the thinking placeholder as the model writes
+// it, so long answers don't arrive all at once after a long wait. Render
+// replaces it with the finished answer. Streaming stops for good once the
+// answer looks like it will be held for confirmation, so nothing held back
+// is shown in the meantime
+type streamer struct {
+	ctx        context.Context
+	slack      messageUpdater
+	channelID  string
+	ts         string
+	visibility string
+	policy     *privacy.Policy
+	format     func(string) string
+	interval   time.Duration
+
+	last    time.Time
+	shown   string
+	stopped bool
+	paused  atomic.Bool // a partial answer was posted after the placeholder
+}
+
+// newStreamer streams the turn's answer into its placeholder
+func (a *Agent) newStreamer(ctx context.Context, turn *Turn) *streamer {
+	return &streamer{
+		ctx:        ctx,
+		slack:      a.slackClient,
+		channelID:  turn.Conversation.ChannelID,
+		ts:         turn.placeholder,
+		visibility: turn.Conversation.Visibility,
+		policy:     turn.Policy,
+		format: func(text string) string {
+			return mrkdwn.Convert(a.mentions.Mention(text))
+		},
+		interval: a.cfg.GetStreamInterval(),
+	}
+}
+
+// update shows the answer so far, at most once an interval
+func (s *streamer) update(text string) {
+	if s.stopped || s.paused.Load() {
+		return
+	}
+
+	if len(s.policy.Confirmations()) > 0 || privacy.NeedsConfirmation(s.visibility, privacy.Sensitive(text)) {
+		s.stop()
+		return
+	}
+
+	display := strings.TrimSpace(visible(text))
+	if display == "" || display == s.shown || time.Since(s.last) < s.interval {
+		return
+	}
+	s.last = time.Now()
+	s.shown = display
+
+	text = s.format(display)
+	if len(text) > maxMessageText {
+		cut := maxMessageText
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	if err := s.slack.UpdateMessage(s.ctx, s.channelID, s.ts, slack.MsgOptionText(text+streamCursor, false)); err != nil {
+		slog.WarnContext(s.ctx, "failed to stream answer", "error", err)
+		s.stopped = true
+	}
+}
+
+// pause ends streaming from another goroutine, leaving the placeholder as
+// it is. A nil streamer is already stopped
+func (s *streamer) pause() {
+	if s != nil {
+		s.paused.Store(true)
+	}
+}
+
+// stop ends streaming, putting the placeholder back if part of the answer
+// was shown
+func (s *streamer) stop() {
+	s.stopped = true
+	if s.shown == "" {
+		return
+	}
+	if err := s.slack.UpdateMessage(s.ctx, s.channelID, s.ts, slack.MsgOptionText(thinkingPlaceholder, false)); err != nil {
+		slog.WarnContext(s.ctx, "failed to restore thinking placeholder", "error", err)
+	}
+}
+
+// visible returns the part of a partial response that the user will see:
+// everything before the first response block, without a tag that may be
+// half written at the end
+func visible(text string) string {
+	for _, tag := range blockTags {
+		if i := strings.Index(text, tag); i >= 0 {
+			text = text[:i]
+		}
+	}
+	if i := strings.LastIndex(text, "<"); i >= 0 {
+		for _, tag := range blockTags {
+			if strings.HasPrefix(tag, text[i:]) {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/slack-go/slack"
+)
+
+type fakeUpdater struct {
+	updates int
+}
+
+func (f *fakeUpdater) UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error {
+	f.updates++
+	return nil
+}
+
+func newTestStreamer(visibility string) (*streamer, *fakeUpdater) {
+	updater := &fakeUpdater{}
+	return &streamer{
+		ctx:        context.Background(),
+		slack:      updater,
+		channelID:  "C123",
+		ts:         "1700000000.000100",
+		visibility: visibility,
+		policy:     privacy.NewPolicy(visibility, models.ProfileReadOnly),
+		format:     func(text string) string { return text },
+	}, updater
+}
+
+func TestStreamerUpdate(t *testing.T) {
+	s, updater := newTestStreamer(privacy.Public)
+
+	s.update("The checkout service")
+	s.update("The checkout service")
+	s.update("The checkout service is failing health checks.\n<scratchpad>")
+	if updater.updates != 2 || s.shown != "The checkout service is failing health checks." {
+		t.Errorf("%d updates showing %q, want 2 without the scratchpad", updater.updates, s.shown)
+	}
+}
+
+func TestStreamerStopsForHeldAnswers(t *testing.T) {
+	s, updater := newTestStreamer(privacy.Public)
+
+	s.update("Checking the role")
+	s.update("Checking the role policy: it allows iam:PassRole on arn:aws:iam::123456789012:role/deploy")
+	if !s.stopped {
+		t.Fatal("streaming should stop once the answer needs confirmation")
+	}
+	if updater.updates != 2 {
+		t.Errorf("%d updates, want the partial answer replaced by the placeholder", updater.updates)
+	}
+
+	s.update("More text")
+	if updater.updates != 2 {
+		t.Error("streaming shouldn't resume after it stopped")
+	}
+}
+
+func TestVisible(t *testing.T) {
+	for in, want := range map[string]string{
+		"All healthy.":                    "All healthy.",
+		"All healthy.\n<scr":              "All healthy.\n",
+		"All healthy.<chart>{\"x\":":      "All healthy.",
+		"p99 < 200ms":                     "p99 < 200ms",
+		"Done.\n<followups>\n- Show logs": "Done.\n",
+	} {
+		if got := visible(in); got != want {
+			t.Errorf("visible(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+)
+
+// maxAuditedOutput bounds the tool output kept with each execution, well
+// under DynamoDB's item size limit
+const maxAuditedOutput = 16 * 1024
+
+// ToolAuditor records tool executions for compliance reviews, and lists a
+// conversation's for its incident report
+type ToolAuditor interface {
+	RecordToolExecution(ctx context.Context, execution *models.ToolExecution) error
+	ListConversationToolExecutions(ctx context.Context, conversationID string) ([]*models.ToolExecution, error)
+}
+
+// SetToolAudit records every tool call the model makes: who it was made for,
+// its redacted input and output, how long it took, and how it ended
+func (a *Agent) SetToolAudit(auditor ToolAuditor) {
+	a.toolAudit = auditor
+}
+
+// auditTools records the tool calls of a turn. It runs outermost, so calls
+// other middleware refuses are recorded too
+func (a *Agent) auditTools(turn *Turn) bedrock.ToolMiddleware {
+	users := senders(turn.Messages)
+	userID := turn.Conversation.UserID
+	if len(users) > 0 {
+		userID = users[0]
+	}
+
+	return func(next bedrock.ToolFunc) bedrock.ToolFunc {
+		return func(ctx context.Context, call bedrock.ToolCall) (string, error) {
+			started := time.Now()
+			output, err := next(ctx, call)
+
+			execution := models.NewToolExecution(turn.Conversation.ConversationID, userID, call.Name, started)
+			if len(users) > 1 {
+				execution.Users = users
+			}
+			execution.Input = privacy.Redact(string(call.Input))
+			execution.Output = clip(privacy.Redact(output), maxAuditedOutput)
+			execution.DurationMillis = time.Since(started).Milliseconds()
+			switch {
+			case errors.Is(err, rbac.ErrDenied):
+				execution.Outcome = models.ToolRefused
+			case err != nil:
+				execution.Outcome = models.ToolFailed
+			default:
+				execution.Outcome = models.ToolSucceeded
+			}
+			if err != nil {
+				execution.Error = privacy.Redact(err.Error())
+			}
+
+			// The call already ran, so a failure to record it is only logged
+			if recordErr := a.toolAudit.RecordToolExecution(ctx, execution); recordErr != nil {
+				slog.WarnContext(ctx, "failed to record tool execution in the audit log", "tool", call.Name, "error", recordErr)
+			}
+			return output, err
+		}
+	}
+}
+
+// senders returns the distinct senders of msgs, in order
+func senders(msgs []coalesce.Message) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, m := range msgs {
+		if m.UserID != "" && !seen[m.UserID] {
+			seen[m.UserID] = true
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// clip shortens s to at most limit bytes, marking that it was cut
+func clip(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return strings.ToValidUTF8(s[:limit], "") + "\n… (truncated)"
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/coalesce"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+)
+
+type fakeAuditor struct {
+	recorded []*models.ToolExecution
+}
+
+func (f *fakeAuditor) RecordToolExecution(ctx context.Context, execution *models.ToolExecution) error {
+	f.recorded = append(f.recorded, execution)
+	return errors.New("table unavailable") // recording failures don't fail the call
+}
+
+func (f *fakeAuditor) ListConversationToolExecutions(ctx context.Context, conversationID string) ([]*models.ToolExecution, error) {
+	return f.recorded, nil
+}
+
+func TestAuditTools(t *testing.T) {
+	auditor := &fakeAuditor{}
+	a := &Agent{toolAudit: auditor}
+	turn := &Turn{
+		Conversation: &models.Conversation{ConversationID: "conv-1", UserID: "U_STARTER"},
+		Messages:     []coalesce.Message{{UserID: "U_ALICE"}, {UserID: "U_BOB"}, {UserID: "U_ALICE"}},
+	}
+
+	tools := map[string]bedrock.ToolFunc{
+		"get_secret": func(ctx context.Context, call bedrock.ToolCall) (string, error) {
+			return `{"SecretString": "password=hunter2"}`, nil
+		},
+		"describe_stack": func(ctx context.Context, call bedrock.ToolCall) (string, error) {
+			return "", errors.New("stack not found")
+		},
+		"delete_stack": func(ctx context.Context, call bedrock.ToolCall) (string, error) {
+			return "", fmt.Errorf("%w: delete_stack needs admin", rbac.ErrDenied)
+		},
+	}
+	for _, name := range []string{"get_secret", "describe_stack", "delete_stack"} {
+		run := a.auditTools(turn)(tools[name])
+		output, _ := run(context.Background(), bedrock.ToolCall{Name: name, Input: []byte(`{"name":"prod"}`)})
+		if name == "get_secret" && !strings.Contains(output, "hunter2") {
+			t.Errorf("model got %q, want the unredacted output", output)
+		}
+	}
+
+	if len(auditor.recorded) != 3 {
+		t.Fatalf("recorded %d executions, want 3", len(auditor.recorded))
+	}
+	for i, want := range []string{models.ToolSucceeded, models.ToolFailed, models.ToolRefused} {
+		e := auditor.recorded[i]
+		if e.Outcome != want {
+			t.Errorf("%s outcome = %s, want %s", e.Tool, e.Outcome, want)
+		}
+		if e.UserID != "U_ALICE" || fmt.Sprint(e.Users) != "[U_ALICE U_BOB]" || e.ConversationID != "conv-1" {
+			t.Errorf("%s recorded for %s %v in %s, want U_ALICE [U_ALICE U_BOB] in conv-1", e.Tool, e.UserID, e.Users, e.ConversationID)
+		}
+	}
+	if got := auditor.recorded[0].Output; strings.Contains(got, "hunter2") {
+		t.Errorf("recorded output %q, want the secret redacted", got)
+	}
+	if got := auditor.recorded[1].Error; got != "stack not found" {
+		t.Errorf("recorded error %q, want stack not found", got)
+	}
+}
+
+func TestClip(t *testing.T) {
+	if got := clip("short", 10); got != "short" {
+		t.Errorf("clip() = %q, want it unchanged", got)
+	}
+	if got := clip("ééééé", 5); got != "éé\n… (truncated)" {
+		t.Errorf("clip() = %q, want whole runes and a marker", got)
+	}
+}
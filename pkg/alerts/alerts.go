@@ -0,0 +1,150 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// CloudWatchAlarm is the notification CloudWatch publishes to SNS when an
+// alarm changes state
+type CloudWatchAlarm struct {
+	AlarmName        string `json:"AlarmName"`
+	AlarmArn         string `json:"AlarmArn"`
+	AlarmDescription string `json:"AlarmDescription"`
+	AccountID        string `json:"AWSAccountId"`
+	NewStateValue    string `json:"NewStateValue"`
+	NewStateReason   string `json:"NewStateReason"`
+	StateChangeTime  string `json:"StateChangeTime"`
+	Region           string `json:"Region"`
+	Trigger          struct {
+		MetricName string `json:"MetricName"`
+		Namespace  string `json:"Namespace"`
+	} `json:"Trigger"`
+}
+
+// ParseAlarm decodes a CloudWatch alarm SNS message
+func ParseAlarm(message string) (*CloudWatchAlarm, error) {
+	var alarm CloudWatchAlarm
+	if err := json.Unmarshal([]byte(message), &alarm); err != nil {
+		return nil, fmt.Errorf("unmarshal alarm: %w", err)
+	}
+	if alarm.AlarmName == "" {
+		return nil, fmt.Errorf("unmarshal alarm: missing AlarmName")
+	}
+	return &alarm, nil
+}
+
+// IsFiring reports whether the alarm transitioned into the ALARM state
+func (a *CloudWatchAlarm) IsFiring() bool {
+	return a.NewStateValue == "ALARM"
+}
+
+// Format renders an alert posted by route, mentioning the on-call
+// responders. Only critical alerts ask to be acknowledged
+func Format(alarm *CloudWatchAlarm, route Route, responders []string, ackWindow time.Duration) string {
+	var b strings.Builder
+	switch route.Severity {
+	case SeverityWarning:
+		fmt.Fprintf(&b, "🟠 *Warning: %s*\n", alarm.AlarmName)
+	case SeverityInfo:
+		fmt.Fprintf(&b, "🔵 *Info: %s*\n", alarm.AlarmName)
+	default:
+		fmt.Fprintf(&b, "🔴 *Critical alert: %s*\n", alarm.AlarmName)
+	}
+	if alarm.AlarmDescription != "" {
+		fmt.Fprintf(&b, "%s\n", alarm.AlarmDescription)
+	}
+	if alarm.Trigger.MetricName != "" {
+		fmt.Fprintf(&b, "*Metric:* %s/%s · *Region:* %s\n", alarm.Trigger.Namespace, alarm.Trigger.MetricName, alarm.Region)
+	}
+	fmt.Fprintf(&b, "> %s\n", alarm.NewStateReason)
+	if route.Playbook != "" {
+		fmt.Fprintf(&b, "*Playbook:* %s\n", route.Playbook)
+	}
+
+	if route.Severity == SeverityWarning || route.Severity == SeverityInfo {
+		if len(responders) > 0 {
+			fmt.Fprintf(&b, "\ncc %s", mentions(responders))
+		}
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	if len(responders) > 0 {
+		fmt.Fprintf(&b, "\n%s please react to acknowledge within %d minutes or this will be escalated.",
+			mentions(responders), int(ackWindow.Minutes()))
+	} else {
+		fmt.Fprintf(&b, "\nReact to acknowledge within %d minutes or this will be escalated.", int(ackWindow.Minutes()))
+	}
+	return b.String()
+}
+
+// AlarmKey identifies an alarm across its notifications, so repeats can be
+// folded into one digest
+func AlarmKey(alarm *CloudWatchAlarm) string {
+	if alarm.AlarmArn != "" {
+		return alarm.AlarmArn
+	}
+	return alarm.AccountID + "/" + alarm.Region + "/" + alarm.AlarmName
+}
+
+// DigestLine summarizes how often a digested alarm fired, appended to the
+// alert once it has fired more than once. Times show in the reader's zone
+func DigestLine(digest *models.AlertDigest) string {
+	return fmt.Sprintf("🔁 Fired %d times · first %s · last %s",
+		digest.Occurrences, slackTime(digest.FirstSeen), slackTime(digest.LastSeen))
+}
+
+func slackTime(t time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} {time}|%s>", t.Unix(), t.UTC().Format("Jan 2 15:04 UTC"))
+}
+
+// Ack records a reaction on the alert. Only on-call responders acknowledge
+// an alert; when nobody was on call, anyone can. It reports whether the
+// user's acknowledgment was recorded
+func Ack(alert *models.Alert, userID string, now time.Time) bool {
+	if len(alert.Responders) > 0 && !contains(alert.Responders, userID) {
+		return false
+	}
+	if contains(alert.AckedBy, userID) {
+		return false
+	}
+
+	alert.AckedBy = append(alert.AckedBy, userID)
+	if alert.AckedAt == nil {
+		alert.AckedAt = &now
+	}
+	if alert.Status == models.AlertOpen {
+		alert.Status = models.AlertAcked
+	}
+	return true
+}
+
+// NeedsEscalation reports whether the ack deadline passed without an ack
+func NeedsEscalation(alert *models.Alert, now time.Time) bool {
+	return alert.Status == models.AlertOpen && !now.Before(alert.AckDeadline)
+}
+
+// EscalationDM is the direct message sent to responders when nobody acknowledged
+func EscalationDM(alert *models.Alert, permalink string) string {
+	return fmt.Sprintf("🚨 Critical alert *%s* has not been acknowledged. %s", alert.Title, permalink)
+}
+
+func mentions(userIDs []string) string {
+	out := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		out[i] = "<@" + id + ">"
+	}
+	return strings.Join(out, " ")
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+const alarmMessage = `{
+	"AlarmName": "payments-api-5xx",
+	"AlarmDescription": "5xx rate above 5%",
+	"AWSAccountId": "123456789012",
+	"NewStateValue": "ALARM",
+	"NewStateReason": "Threshold Crossed: 1 datapoint [7.2] was greater than the threshold (5.0).",
+	"StateChangeTime": "2024-05-01T15:04:05.000+0000",
+	"Region": "US East (N. Virginia)",
+	"Trigger": {"MetricName": "5XXError", "Namespace": "AWS/ApiGateway"}
+}`
+
+func TestParseAlarm(t *testing.T) {
+	alarm, err := ParseAlarm(alarmMessage)
+	if err != nil {
+		t.Fatalf("ParseAlarm() error = %v", err)
+	}
+	if alarm.AlarmName != "payments-api-5xx" || !alarm.IsFiring() || alarm.Trigger.Namespace != "AWS/ApiGateway" {
+		t.Errorf("ParseAlarm() = %+v", alarm)
+	}
+
+	for _, bad := range []string{"not json", `{"NewStateValue": "ALARM"}`} {
+		if _, err := ParseAlarm(bad); err == nil {
+			t.Errorf("ParseAlarm(%q) should error", bad)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	alarm, _ := ParseAlarm(alarmMessage)
+
+	critical := Route{Severity: SeverityCritical}
+	got := Format(alarm, critical, []string{"U1", "U2"}, 5*time.Minute)
+	for _, want := range []string{"payments-api-5xx", "AWS/ApiGateway/5XXError", "<@U1> <@U2> please react", "within 5 minutes"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format() = %q, missing %q", got, want)
+		}
+	}
+
+	if got := Format(alarm, critical, nil, 5*time.Minute); !strings.Contains(got, "\nReact to acknowledge") {
+		t.Errorf("Format() without responders = %q", got)
+	}
+
+	warning := Route{Severity: SeverityWarning, Playbook: "https://wiki.example.com/payments"}
+	got = Format(alarm, warning, []string{"U1"}, 5*time.Minute)
+	if !strings.HasPrefix(got, "🟠 *Warning: payments-api-5xx*") || !strings.Contains(got, "*Playbook:* https://wiki.example.com/payments") {
+		t.Errorf("Format() for a warning = %q", got)
+	}
+	if strings.Contains(got, "acknowledge") || !strings.Contains(got, "cc <@U1>") {
+		t.Errorf("Format() should not ask to acknowledge a warning: %q", got)
+	}
+}
+
+func TestAlarmKey(t *testing.T) {
+	alarm, _ := ParseAlarm(alarmMessage)
+	if got := AlarmKey(alarm); got != "123456789012/US East (N. Virginia)/payments-api-5xx" {
+		t.Errorf("AlarmKey() without an ARN = %q", got)
+	}
+	alarm.AlarmArn = "arn:aws:cloudwatch:us-east-1:123456789012:alarm:payments-api-5xx"
+	if got := AlarmKey(alarm); got != alarm.AlarmArn {
+		t.Errorf("AlarmKey() = %q, want the ARN", got)
+	}
+}
+
+func TestDigestLine(t *testing.T) {
+	first := time.Date(2024, 5, 1, 15, 4, 0, 0, time.UTC)
+	digest := models.NewAlertDigest("C1", "key", "1714575845.000100", "payments-api-5xx", nil, first)
+	digest.Occurrences = 4
+	digest.LastSeen = first.Add(25 * time.Minute)
+
+	got := DigestLine(digest)
+	for _, want := range []string{"Fired 4 times", "<!date^1714575840^", "|May 1 15:04 UTC>", "|May 1 15:29 UTC>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DigestLine() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestAck(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	alert := &models.Alert{Status: models.AlertOpen, Responders: []string{"U1", "U2"}, CreatedAt: now}
+
+	if Ack(alert, "U9", now) {
+		t.Error("Ack() by a non-responder should be ignored")
+	}
+	if !Ack(alert, "U2", now.Add(time.Minute)) {
+		t.Fatal("Ack() by a responder should be recorded")
+	}
+	if alert.Status != models.AlertAcked || !alert.AckedAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("alert = %+v", alert)
+	}
+	if Ack(alert, "U2", now.Add(2*time.Minute)) {
+		t.Error("repeat Ack() should be ignored")
+	}
+	if !Ack(alert, "U1", now.Add(3*time.Minute)) || !alert.AckedAt.Equal(now.Add(time.Minute)) {
+		t.Error("second responder should be recorded without moving AckedAt")
+	}
+
+	// Anyone can acknowledge when nobody was on call
+	unowned := &models.Alert{Status: models.AlertOpen}
+	if !Ack(unowned, "U9", now) {
+		t.Error("Ack() without responders should accept anyone")
+	}
+}
+
+func TestNeedsEscalation(t *testing.T) {
+	deadline := time.Date(2024, 5, 1, 15, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		status string
+		now    time.Time
+		want   bool
+	}{
+		{models.AlertOpen, deadline.Add(-time.Second), false},
+		{models.AlertOpen, deadline, true},
+		{models.AlertAcked, deadline.Add(time.Hour), false},
+		{models.AlertEscalated, deadline.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		alert := &models.Alert{Status: tt.status, AckDeadline: deadline}
+		if got := NeedsEscalation(alert, tt.now); got != tt.want {
+			t.Errorf("NeedsEscalation(%s, %v) = %v, want %v", tt.status, tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Severities an alert is posted with. Only critical alerts must be
+// acknowledged, and are escalated when nobody does
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// SeverityTag is the alarm tag holding the alarm's own severity
+const SeverityTag = "severity"
+
+// ssmPrefix selects routes stored in an SSM parameter
+const ssmPrefix = "ssm:"
+
+var severities = []string{SeverityCritical, SeverityWarning, SeverityInfo}
+
+// Match selects alarms. Every condition that is set must hold
+type Match struct {
+	// Names are alarm name patterns, where * matches anything; one must match
+	Names []string `json:"names,omitempty"`
+
+	// Tags are alarm tag values, where "*" matches any value; all must match
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Severities are alarm severities, read from the severity tag; one
+	// must match
+	Severities []string `json:"severities,omitempty"`
+}
+
+// Route is where matching alarms are posted and how they are handled
+type Route struct {
+	// Name identifies the route in logs
+	Name  string `json:"name,omitempty"`
+	Match Match  `json:"match"`
+
+	// Channel is the Slack channel ID alerts are posted to
+	Channel string `json:"channel"`
+
+	// Severity overrides the alarm's own severity
+	Severity string `json:"severity,omitempty"`
+
+	// Team is the on-call team mentioned and escalated to, ALERT_TEAM's
+	// when empty
+	Team string `json:"team,omitempty"`
+
+	// Playbook links what responders should do, e.g. a runbook URL
+	Playbook string `json:"playbook,omitempty"`
+}
+
+// Routes send alarms to team channels. The first route that matches an
+// alarm wins; alarms no route matches go to ALERT_CHANNEL
+//
+//	{"routes": [
+//	  {"name": "payments", "match": {"names": ["payments-*"]}, "channel": "C0PAYMENTS", "team": "payments", "playbook": "https://wiki.example.com/payments-alarms"},
+//	  {"match": {"tags": {"team": "data"}}, "channel": "C0DATA", "severity": "warning"}
+//	]}
+type Routes struct {
+	Routes []Route `json:"routes"`
+}
+
+// ParseRoutes reads JSON routes
+func ParseRoutes(data []byte) (*Routes, error) {
+	var r Routes
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse alert routes: %w", err)
+	}
+	for i, route := range r.Routes {
+		if route.Channel == "" {
+			return nil, fmt.Errorf("alert route %d has no channel", i+1)
+		}
+		if route.Severity != "" && !validSeverity(route.Severity) {
+			return nil, fmt.Errorf("alert route %d: unknown severity %q; use one of %s", i+1, route.Severity, strings.Join(severities, ", "))
+		}
+		for _, s := range route.Match.Severities {
+			if !validSeverity(s) {
+				return nil, fmt.Errorf("alert route %d: unknown severity %q; use one of %s", i+1, s, strings.Join(severities, ", "))
+			}
+		}
+	}
+	return &r, nil
+}
+
+// ParameterAPI reads SSM parameters
+type ParameterAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// ValidRoutes checks spec: inline JSON routes, or "ssm:" followed by the
+// name of a parameter holding them
+func ValidRoutes(spec string) error {
+	if name, ok := strings.CutPrefix(spec, ssmPrefix); ok {
+		if name == "" {
+			return fmt.Errorf("no parameter name after %s", ssmPrefix)
+		}
+		return nil
+	}
+	_, err := ParseRoutes([]byte(spec))
+	return err
+}
+
+// LoadRoutes reads the routes spec holds or names. An empty spec routes
+// every alarm to the fallback
+func LoadRoutes(ctx context.Context, spec string, params ParameterAPI) (*Routes, error) {
+	name, ok := strings.CutPrefix(spec, ssmPrefix)
+	switch {
+	case strings.TrimSpace(spec) == "":
+		return &Routes{}, nil
+	case !ok:
+		return ParseRoutes([]byte(spec))
+	}
+
+	out, err := params.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get alert routes parameter %s: %w", name, err)
+	}
+	return ParseRoutes([]byte(aws.ToString(out.Parameter.Value)))
+}
+
+// UsesTags reports whether any route needs the alarm's tags
+func (r *Routes) UsesTags() bool {
+	for _, route := range r.Routes {
+		if len(route.Match.Tags) > 0 || len(route.Match.Severities) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the route for an alarm with the given tags: the first
+// matching route, or the fallback's channel when no route matches. The
+// alarm's own severity applies where the route sets none, then the
+// fallback's severity and team
+func (r *Routes) Resolve(alarm *CloudWatchAlarm, tags map[string]string, fallback Route) Route {
+	own := strings.ToLower(tags[SeverityTag])
+	if !validSeverity(own) {
+		own = ""
+	}
+
+	route := Route{Name: fallback.Name, Channel: fallback.Channel, Playbook: fallback.Playbook}
+	for _, candidate := range r.Routes {
+		if candidate.Match.matches(alarm.AlarmName, tags, own) {
+			route = candidate
+			break
+		}
+	}
+	if route.Severity == "" {
+		route.Severity = own
+	}
+	if route.Severity == "" {
+		route.Severity = fallback.Severity
+	}
+	if route.Team == "" {
+		route.Team = fallback.Team
+	}
+	return route
+}
+
+// matches reports whether an alarm meets every condition of m
+func (m Match) matches(name string, tags map[string]string, severity string) bool {
+	if len(m.Names) > 0 {
+		found := false
+		for _, pattern := range m.Names {
+			if glob(pattern, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, want := range m.Tags {
+		got, ok := tags[key]
+		if !ok || (want != "*" && got != want) {
+			return false
+		}
+	}
+	if len(m.Severities) > 0 && !contains(m.Severities, severity) {
+		return false
+	}
+	return true
+}
+
+// glob reports whether name matches pattern, where * matches any run of
+// characters, including none
+func glob(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(name)
+}
+
+func validSeverity(s string) bool {
+	return contains(severities, s)
+}
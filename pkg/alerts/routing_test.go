@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const testRoutes = `{"routes": [
+	{"name": "payments", "match": {"names": ["payments-*", "checkout-latency"]}, "channel": "C0PAY", "team": "payments", "playbook": "https://wiki.example.com/payments"},
+	{"name": "data", "match": {"tags": {"team": "data", "env": "*"}}, "channel": "C0DATA", "severity": "warning"},
+	{"name": "pages", "match": {"severities": ["critical"]}, "channel": "C0PAGE"}
+]}`
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(testRoutes))
+	if err != nil {
+		t.Fatalf("ParseRoutes() error = %v", err)
+	}
+	if len(routes.Routes) != 3 || !routes.UsesTags() {
+		t.Errorf("ParseRoutes() = %+v", routes)
+	}
+
+	for _, bad := range []string{
+		"not json",
+		`{"routes": [{"match": {"names": ["x"]}}]}`,
+		`{"routes": [{"channel": "C1", "severity": "sev1"}]}`,
+		`{"routes": [{"channel": "C1", "match": {"severities": ["high"]}}]}`,
+	} {
+		if _, err := ParseRoutes([]byte(bad)); err == nil {
+			t.Errorf("ParseRoutes(%q) should error", bad)
+		}
+	}
+
+	names, _ := ParseRoutes([]byte(`{"routes": [{"match": {"names": ["x"]}, "channel": "C1"}]}`))
+	if names.UsesTags() {
+		t.Error("UsesTags() should be false when routes only match names")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	routes, _ := ParseRoutes([]byte(testRoutes))
+	fallback := Route{Channel: "C0OPS", Team: "platform", Severity: SeverityCritical}
+
+	tests := []struct {
+		name     string
+		alarm    string
+		tags     map[string]string
+		channel  string
+		severity string
+		team     string
+	}{
+		{name: "name glob", alarm: "payments-api-5xx", channel: "C0PAY", severity: SeverityCritical, team: "payments"},
+		{name: "exact name", alarm: "checkout-latency", channel: "C0PAY", severity: SeverityCritical, team: "payments"},
+		{name: "alarm severity kept", alarm: "payments-queue-depth", tags: map[string]string{"severity": "Info"}, channel: "C0PAY", severity: SeverityInfo, team: "payments"},
+		{name: "tags", alarm: "etl-lag", tags: map[string]string{"team": "data", "env": "prod"}, channel: "C0DATA", severity: SeverityWarning, team: "platform"},
+		{name: "missing tag", alarm: "etl-lag", tags: map[string]string{"team": "data"}, channel: "C0OPS", severity: SeverityCritical, team: "platform"},
+		{name: "severity tag", alarm: "db-cpu", tags: map[string]string{"severity": "critical"}, channel: "C0PAGE", severity: SeverityCritical, team: "platform"},
+		{name: "unmatched", alarm: "db-cpu", tags: map[string]string{"severity": "warning"}, channel: "C0OPS", severity: SeverityWarning, team: "platform"},
+		{name: "glob is anchored", alarm: "old-payments-api", channel: "C0OPS", severity: SeverityCritical, team: "platform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := routes.Resolve(&CloudWatchAlarm{AlarmName: tt.alarm}, tt.tags, fallback)
+			if got.Channel != tt.channel || got.Severity != tt.severity || got.Team != tt.team {
+				t.Errorf("Resolve() = %+v, want channel %s, severity %s, team %s", got, tt.channel, tt.severity, tt.team)
+			}
+		})
+	}
+}
+
+type fakeParameters map[string]string
+
+func (f fakeParameters) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	value, ok := f[aws.ToString(params.Name)]
+	if !ok {
+		return nil, errors.New("ParameterNotFound")
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestLoadRoutes(t *testing.T) {
+	ctx := context.Background()
+	params := fakeParameters{"/cloudops/alert-routes": testRoutes}
+
+	for _, spec := range []string{testRoutes, "ssm:/cloudops/alert-routes"} {
+		routes, err := LoadRoutes(ctx, spec, params)
+		if err != nil || len(routes.Routes) != 3 {
+			t.Errorf("LoadRoutes(%.20q) = %+v, %v", spec, routes, err)
+		}
+	}
+	if routes, err := LoadRoutes(ctx, "", params); err != nil || len(routes.Routes) != 0 {
+		t.Errorf("LoadRoutes() without a spec = %+v, %v", routes, err)
+	}
+	if _, err := LoadRoutes(ctx, "ssm:/missing", params); err == nil {
+		t.Error("LoadRoutes() should fail when the parameter can't be read")
+	}
+
+	if err := ValidRoutes("ssm:"); err == nil {
+		t.Error("ValidRoutes() should require a parameter name")
+	}
+}
@@ -0,0 +1,80 @@
+package announce
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Announcement kinds
+const (
+	KindMaintenance = "maintenance"
+	KindIncident    = "incident"
+)
+
+var headers = map[string]string{
+	KindMaintenance: "🛠️ *Scheduled maintenance*",
+	KindIncident:    "🚨 *Incident notice*",
+}
+
+// Parse splits "[maintenance|incident] message" into its kind and message,
+// defaulting to maintenance
+func Parse(text string) (kind, message string, err error) {
+	text = strings.TrimSpace(text)
+	first, rest, _ := strings.Cut(text, " ")
+	if _, ok := headers[strings.ToLower(first)]; ok {
+		kind, message = strings.ToLower(first), strings.TrimSpace(rest)
+	} else {
+		kind, message = KindMaintenance, text
+	}
+
+	if message == "" {
+		return "", "", fmt.Errorf("announcement message is required")
+	}
+	return kind, message, nil
+}
+
+// Format renders an announcement for posting
+func Format(kind, message, userID string, at time.Time) string {
+	header, ok := headers[kind]
+	if !ok {
+		header = headers[KindMaintenance]
+	}
+
+	return fmt.Sprintf("%s\n\n%s\n\n_Posted by <@%s> at %s UTC · react to acknowledge_",
+		header, message, userID, at.UTC().Format("Jan 2 15:04"))
+}
+
+// Summary describes who has acknowledged an announcement in each channel
+func Summary(announcementID string, posts []*models.AnnouncementPost) string {
+	if len(posts) == 0 {
+		return fmt.Sprintf("No announcement found with ID `%s`.", announcementID)
+	}
+
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ChannelID < posts[j].ChannelID })
+
+	acked := map[string]bool{}
+	var lines []string
+	for _, post := range posts {
+		users := make([]string, len(post.AckedBy))
+		for i, u := range post.AckedBy {
+			users[i] = "<@" + u + ">"
+			acked[u] = true
+		}
+		sort.Strings(users)
+
+		line := fmt.Sprintf("• <#%s>: ", post.ChannelID)
+		if len(users) == 0 {
+			line += "_no acknowledgments yet_"
+		} else {
+			line += strings.Join(users, ", ")
+		}
+		lines = append(lines, line)
+	}
+
+	return fmt.Sprintf("*Announcement `%s`*: acknowledged by %d people across %d channels\n%s",
+		announcementID, len(acked), len(posts), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,67 @@
+package announce
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text        string
+		wantKind    string
+		wantMessage string
+		wantErr     bool
+	}{
+		{"Incident  Payments API is degraded", KindIncident, "Payments API is degraded", false},
+		{"maintenance RDS upgrade at 02:00 UTC", KindMaintenance, "RDS upgrade at 02:00 UTC", false},
+		{"RDS upgrade tonight", KindMaintenance, "RDS upgrade tonight", false},
+		{"incident", "", "", true},
+		{"  ", "", "", true},
+	}
+
+	for _, tt := range tests {
+		kind, message, err := Parse(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if kind != tt.wantKind || message != tt.wantMessage {
+			t.Errorf("Parse(%q) = %q, %q, want %q, %q", tt.text, kind, message, tt.wantKind, tt.wantMessage)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	got := Format(KindIncident, "Payments API is degraded", "U1", time.Date(2024, 5, 1, 15, 4, 0, 0, time.UTC))
+	for _, want := range []string{"Incident notice", "Payments API is degraded", "<@U1>", "May 1 15:04 UTC"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	posts := []*models.AnnouncementPost{
+		{ChannelID: "C2", AckedBy: []string{"U2", "U1"}},
+		{ChannelID: "C1", AckedBy: []string{"U1"}},
+		{ChannelID: "C3"},
+	}
+
+	got := Summary("ann-1", posts)
+	if !strings.Contains(got, "acknowledged by 2 people across 3 channels") {
+		t.Errorf("Summary() = %q", got)
+	}
+	if !strings.Contains(got, "<#C2>: <@U1>, <@U2>") || !strings.Contains(got, "<#C3>: _no acknowledgments yet_") {
+		t.Errorf("Summary() = %q", got)
+	}
+	if !strings.HasPrefix(strings.Split(got, "\n")[1], "• <#C1>") {
+		t.Errorf("Summary() should sort by channel: %q", got)
+	}
+
+	if got := Summary("ann-2", nil); !strings.Contains(got, "No announcement") {
+		t.Errorf("Summary(nil) = %q", got)
+	}
+}
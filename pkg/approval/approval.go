@@ -0,0 +1,214 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// Slack identifiers for the approve/deny buttons
+const (
+	BlockID       = "approval"
+	ApproveAction = "approval_approve"
+	DenyAction    = "approval_deny"
+)
+
+// DefaultClass is the policy key applied to action classes without their own
+const DefaultClass = "*"
+
+// Reasons a vote is refused
+var (
+	ErrNotPending    = errors.New("this request has already been decided")
+	ErrExpired       = errors.New("this request has expired")
+	ErrSelfApproval  = errors.New("you can't approve your own request")
+	ErrNotInGroup    = errors.New("you don't have the permission profile this request needs")
+	ErrAlreadyVoted  = errors.New("you've already voted on this request")
+	errInvalidPolicy = errors.New("want class=profile/quorum/expiry[/self]")
+)
+
+// Policy is who must sign off on an action class, and how many of them
+type Policy struct {
+	Group     string        // minimum permission profile of approvers
+	Quorum    int           // approvals needed
+	Expiry    time.Duration // how long a request stays open
+	AllowSelf bool          // whether the requester's own approval counts
+}
+
+// Policies maps action classes to their approval policies
+type Policies map[string]Policy
+
+// DefaultPolicies returns the built-in policy: one operator, within an hour,
+// and never the requester
+func DefaultPolicies() Policies {
+	return Policies{
+		DefaultClass: {Group: models.ProfileOperator, Quorum: 1, Expiry: time.Hour},
+	}
+}
+
+// ParsePolicies parses "prod-terminate=admin/2/30m,scale=operator/1/15m/self"
+// over the defaults
+func ParsePolicies(s string) (Policies, error) {
+	policies := DefaultPolicies()
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		class, spec, ok := strings.Cut(entry, "=")
+		parts := strings.Split(spec, "/")
+		if !ok || len(parts) < 3 || len(parts) > 4 {
+			return nil, fmt.Errorf("invalid approval policy %q: %w", entry, errInvalidPolicy)
+		}
+
+		group := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !models.ValidProfile(group) || group == models.ProfileReadOnly {
+			return nil, fmt.Errorf("invalid approver profile in %q: want operator or admin", entry)
+		}
+		quorum, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || quorum < 1 {
+			return nil, fmt.Errorf("invalid quorum in %q: want a positive count", entry)
+		}
+		expiry, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+		if err != nil || expiry <= 0 {
+			return nil, fmt.Errorf("invalid expiry in %q: want a duration like 30m", entry)
+		}
+		allowSelf := false
+		if len(parts) == 4 {
+			if strings.TrimSpace(parts[3]) != "self" {
+				return nil, fmt.Errorf("invalid approval policy %q: %w", entry, errInvalidPolicy)
+			}
+			allowSelf = true
+		}
+
+		policies[strings.ToLower(strings.TrimSpace(class))] = Policy{Group: group, Quorum: quorum, Expiry: expiry, AllowSelf: allowSelf}
+	}
+	return policies, nil
+}
+
+// For returns the policy for an action class, falling back to the default
+func (p Policies) For(class string) Policy {
+	if policy, ok := p[class]; ok {
+		return policy
+	}
+	if policy, ok := p[DefaultClass]; ok {
+		return policy
+	}
+	return DefaultPolicies()[DefaultClass]
+}
+
+// Open creates a pending request under a policy
+func Open(policy Policy, class, description, requestedBy string, now time.Time) *models.ApprovalRequest {
+	expires := now.Add(policy.Expiry)
+	return &models.ApprovalRequest{
+		ApprovalID:  models.NewApprovalID(),
+		Class:       class,
+		Description: description,
+		RequestedBy: requestedBy,
+		Group:       policy.Group,
+		Quorum:      policy.Quorum,
+		AllowSelf:   policy.AllowSelf,
+		Votes:       []models.ApprovalVote{},
+		Status:      models.ApprovalPending,
+		CreatedAt:   now,
+		ExpiresAt:   expires,
+		TTL:         expires.AddDate(0, 0, 30).Unix(),
+	}
+}
+
+// Vote records a user's decision. A single denial rejects the request;
+// approvals accumulate until the quorum is met. An expired request is
+// marked expired and refused
+func Vote(req *models.ApprovalRequest, userID, profile string, approve bool, now time.Time) error {
+	if req.Status != models.ApprovalPending {
+		return ErrNotPending
+	}
+	if !now.Before(req.ExpiresAt) {
+		req.Status = models.ApprovalExpired
+		req.DecidedAt = &now
+		return ErrExpired
+	}
+	if approve && userID == req.RequestedBy && !req.AllowSelf {
+		return ErrSelfApproval
+	}
+	if !models.ProfileAtLeast(profile, req.Group) {
+		return ErrNotInGroup
+	}
+	for _, v := range req.Votes {
+		if v.UserID == userID {
+			return ErrAlreadyVoted
+		}
+	}
+
+	req.Votes = append(req.Votes, models.ApprovalVote{UserID: userID, Approve: approve, At: now})
+	switch {
+	case !approve:
+		req.Status = models.ApprovalDenied
+		req.DecidedAt = &now
+	case req.Approvals() >= req.Quorum:
+		req.Status = models.ApprovalApproved
+		req.DecidedAt = &now
+	}
+	return nil
+}
+
+// Status describes where a request stands in one line
+func Status(req *models.ApprovalRequest, now time.Time) string {
+	switch req.Status {
+	case models.ApprovalApproved:
+		return fmt.Sprintf("✅ Approved by %s", voters(req.Votes, true))
+	case models.ApprovalDenied:
+		return fmt.Sprintf("⛔ Denied by %s", voters(req.Votes, false))
+	case models.ApprovalExpired:
+		return fmt.Sprintf("⌛ Expired with %d of %d approvals", req.Approvals(), req.Quorum)
+	}
+
+	status := fmt.Sprintf("⏳ %d of %d approvals from %s or above, expires in %s",
+		req.Approvals(), req.Quorum, req.Group, humanize.Duration(req.ExpiresAt.Sub(now).Truncate(time.Minute)))
+	if req.Approvals() > 0 {
+		status += fmt.Sprintf(" (approved by %s)", voters(req.Votes, true))
+	}
+	if !req.AllowSelf {
+		status += ". The requester can't approve"
+	}
+	return status
+}
+
+// Blocks renders a request with approve/deny buttons while it's pending
+func Blocks(req *models.ApprovalRequest, now time.Time) []slack.Block {
+	text := fmt.Sprintf("🔏 *Approval needed* for `%s` requested by <@%s>\n%s", req.Class, req.RequestedBy, req.Description)
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, Status(req, now), false, false)),
+	}
+	if req.Status != models.ApprovalPending {
+		return blocks
+	}
+
+	approve := slack.NewButtonBlockElement(ApproveAction, req.ApprovalID,
+		slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false)).WithStyle(slack.StylePrimary)
+	deny := slack.NewButtonBlockElement(DenyAction, req.ApprovalID,
+		slack.NewTextBlockObject(slack.PlainTextType, "Deny", false, false)).WithStyle(slack.StyleDanger)
+	return append(blocks, slack.NewActionBlock(BlockID, approve, deny))
+}
+
+// IsAction reports whether a block action ID is an approve or deny button
+func IsAction(actionID string) bool {
+	return actionID == ApproveAction || actionID == DenyAction
+}
+
+func voters(votes []models.ApprovalVote, approve bool) string {
+	var ids []string
+	for _, v := range votes {
+		if v.Approve == approve {
+			ids = append(ids, "<@"+v.UserID+">")
+		}
+	}
+	return strings.Join(ids, ", ")
+}
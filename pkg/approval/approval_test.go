@@ -0,0 +1,91 @@
+package approval
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("prod-terminate=admin/2/30m, scale=operator/1/15m/self")
+	if err != nil {
+		t.Fatalf("ParsePolicies() error = %v", err)
+	}
+
+	want := Policy{Group: models.ProfileAdmin, Quorum: 2, Expiry: 30 * time.Minute}
+	if got := policies.For("prod-terminate"); got != want {
+		t.Errorf("For(prod-terminate) = %+v, want %+v", got, want)
+	}
+	if !policies.For("scale").AllowSelf {
+		t.Error("For(scale) should allow self-approval")
+	}
+	if got := policies.For("restart"); got != DefaultPolicies()[DefaultClass] {
+		t.Errorf("For(restart) = %+v, want the default", got)
+	}
+
+	for _, spec := range []string{"x=admin/2", "x=read-only/1/1h", "x=admin/0/1h", "x=admin/1/soon", "x=admin/1/1h/maybe"} {
+		if _, err := ParsePolicies(spec); err == nil {
+			t.Errorf("ParsePolicies(%q) should error", spec)
+		}
+	}
+}
+
+func TestVoteQuorum(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	req := Open(Policy{Group: models.ProfileOperator, Quorum: 2, Expiry: time.Hour}, "prod-terminate", "Terminate i-123", "U1", now)
+
+	if err := Vote(req, "U1", models.ProfileAdmin, true, now); err != ErrSelfApproval {
+		t.Errorf("Vote(requester) error = %v, want ErrSelfApproval", err)
+	}
+	if err := Vote(req, "U2", models.ProfileReadOnly, true, now); err != ErrNotInGroup {
+		t.Errorf("Vote(read-only) error = %v, want ErrNotInGroup", err)
+	}
+	if err := Vote(req, "U2", models.ProfileOperator, true, now); err != nil {
+		t.Fatalf("Vote(U2) error = %v", err)
+	}
+	if err := Vote(req, "U2", models.ProfileOperator, true, now); err != ErrAlreadyVoted {
+		t.Errorf("Vote(U2 again) error = %v, want ErrAlreadyVoted", err)
+	}
+	if req.Status != models.ApprovalPending {
+		t.Fatalf("Status = %s after one of two approvals, want pending", req.Status)
+	}
+	if got := Status(req, now); !strings.Contains(got, "1 of 2") || !strings.Contains(got, "<@U2>") {
+		t.Errorf("Status() = %q", got)
+	}
+
+	if err := Vote(req, "U3", models.ProfileAdmin, true, now); err != nil {
+		t.Fatalf("Vote(U3) error = %v", err)
+	}
+	if req.Status != models.ApprovalApproved || req.DecidedAt == nil {
+		t.Errorf("Status = %s, want approved", req.Status)
+	}
+	if err := Vote(req, "U4", models.ProfileAdmin, false, now); err != ErrNotPending {
+		t.Errorf("Vote(after decision) error = %v, want ErrNotPending", err)
+	}
+	if len(Blocks(req, now)) != 2 {
+		t.Error("Blocks() should drop the buttons once decided")
+	}
+}
+
+func TestVoteDenyAndExpiry(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	policy := Policy{Group: models.ProfileOperator, Quorum: 2, Expiry: 30 * time.Minute, AllowSelf: true}
+
+	req := Open(policy, "scale", "Scale to 10", "U1", now)
+	if err := Vote(req, "U1", models.ProfileOperator, true, now); err != nil {
+		t.Errorf("Vote(self, allowed) error = %v", err)
+	}
+	if err := Vote(req, "U2", models.ProfileOperator, false, now); err != nil || req.Status != models.ApprovalDenied {
+		t.Errorf("Vote(deny) = %v, status %s; want denied", err, req.Status)
+	}
+
+	req = Open(policy, "scale", "Scale to 10", "U1", now)
+	if err := Vote(req, "U2", models.ProfileOperator, true, now.Add(30*time.Minute)); err != ErrExpired {
+		t.Errorf("Vote(late) error = %v, want ErrExpired", err)
+	}
+	if req.Status != models.ApprovalExpired {
+		t.Errorf("Status = %s, want expired", req.Status)
+	}
+}
@@ -4,28 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
 	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/usage"
 )
 
 const (
 	// Default Bedrock model ID for Claude 3.5 Sonnet
 	DefaultModelID = "anthropic.claude-3-5-sonnet-20241022-v2:0"
+
+	// Default embedding model, Amazon Titan Text Embeddings V2
+	DefaultEmbeddingModelID = "amazon.titan-embed-text-v2:0"
+
+	// embeddingDimensions keeps vectors small enough to store on each
+	// conversation record
+	embeddingDimensions = 256
+
+	// maxEmbeddingInput stays under Titan's input limit
+	maxEmbeddingInput = 20000
 )
 
 // Client is a client for AWS Bedrock Runtime (Claude models)
 type Client struct {
-	client  *bedrockruntime.Client
-	modelID string
+	client           *bedrockruntime.Client
+	modelID          string
+	embeddingModelID string
+	faults           *chaos.Injector
+	diagnoser        Diagnoser
+
+	mu    sync.RWMutex
+	tools []Tool
 }
 
 // NewClient creates a new Bedrock client
 func NewClient(cfg aws.Config) *Client {
 	return &Client{
-		client:  bedrockruntime.NewFromConfig(cfg),
-		modelID: DefaultModelID,
+		client:           bedrockruntime.NewFromConfig(cfg),
+		modelID:          DefaultModelID,
+		embeddingModelID: DefaultEmbeddingModelID,
 	}
 }
 
@@ -34,49 +55,107 @@ func (c *Client) SetModel(modelID string) {
 	c.modelID = modelID
 }
 
+// SetEmbeddingModel overrides the default embedding model ID
+func (c *Client) SetEmbeddingModel(modelID string) {
+	c.embeddingModelID = modelID
+}
+
+// SetFaultInjector enables artificial latency and errors for Bedrock calls
+func (c *Client) SetFaultInjector(faults *chaos.Injector) {
+	c.faults = faults
+}
+
 // BedrockRequest represents a request to Bedrock (Claude Messages API format)
 type BedrockRequest struct {
-	AnthropicVersion string           `json:"anthropic_version"`
-	MaxTokens        int              `json:"max_tokens"`
-	Messages         []models.Message `json:"messages"`
-	System           string           `json:"system,omitempty"`
+	AnthropicVersion string     `json:"anthropic_version"`
+	MaxTokens        int        `json:"max_tokens"`
+	Messages         []Message  `json:"messages"`
+	System           string     `json:"system,omitempty"`
+	Tools            []ToolSpec `json:"tools,omitempty"`
 }
 
 // BedrockResponse represents a response from Bedrock
 type BedrockResponse struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Role    string `json:"role"`
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	Model       string `json:"model"`
-	StopReason  string `json:"stop_reason"`
-	Usage       struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	Role       string         `json:"role"`
+	Content    []ContentBlock `json:"content"`
+	Model      string         `json:"model"`
+	StopReason string         `json:"stop_reason"`
+	Usage      struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
 }
 
-// SendMessage sends a message to Claude via Bedrock with conversation history
+// SendMessage sends a message to Claude via Bedrock with conversation
+// history. When tools are registered, the tools the model calls are run and
+// their results sent back until it finishes its answer
 func (c *Client) SendMessage(ctx context.Context, messages []models.Message, systemPrompt string) (string, error) {
 	if len(messages) == 0 {
 		return "", fmt.Errorf("messages cannot be empty")
 	}
 
-	// Build request in Claude Messages API format
+	tools := c.Tools()
+	req := newRequest(messages, systemPrompt, tools)
+
+	progress := progressFromContext(ctx)
+	budget := newRetryBudget()
+	for round := 0; ; round++ {
+		response, err := c.invoke(ctx, &req)
+		if err != nil {
+			return "", err
+		}
+
+		if response.StopReason != StopToolUse {
+			text := textOf(response.Content)
+			if text == "" {
+				return "", fmt.Errorf("empty response from Bedrock")
+			}
+			return text, nil
+		}
+		if round == maxToolRounds {
+			return "", fmt.Errorf("model still calling tools after %d rounds", maxToolRounds)
+		}
+
+		progress.start(response.Content)
+		calls := Message{Role: models.RoleAssistant, Content: response.Content}
+		results := Message{Role: models.RoleUser, Content: runTools(ctx, tools, response.Content, c.diagnoser, budget)}
+		progress.finish(calls, results)
+		req.Messages = append(req.Messages, calls, results)
+	}
+}
+
+// newRequest builds a request in the Claude Messages API format
+func newRequest(messages []models.Message, systemPrompt string, tools []Tool) BedrockRequest {
 	req := BedrockRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
 		MaxTokens:        4096,
-		Messages:         messages,
+		Messages:         make([]Message, 0, len(messages)),
 		System:           systemPrompt,
+		Tools:            toolSpecs(tools),
 	}
+	for _, m := range messages {
+		req.Messages = append(req.Messages, Message{Role: m.Role, Content: []ContentBlock{{Type: ContentText, Text: m.Content}}})
+	}
+	return req
+}
 
+// invoke sends one request to the model, streaming the response when the
+// context has a stream function
+func (c *Client) invoke(ctx context.Context, req *BedrockRequest) (*BedrockResponse, error) {
 	// Marshal request body
 	body, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	if fn := streamFromContext(ctx); fn != nil {
+		return c.invokeStream(ctx, body, fn)
+	}
+
+	if err := c.faults.Inject(ctx, chaos.TargetBedrock, "InvokeModel"); err != nil {
+		return nil, err
 	}
 
 	// Invoke Bedrock model
@@ -87,21 +166,75 @@ func (c *Client) SendMessage(ctx context.Context, messages []models.Message, sys
 		Body:        body,
 	})
 	if err != nil {
-		return "", fmt.Errorf("invoke bedrock model: %w", err)
+		return nil, fmt.Errorf("invoke bedrock model: %w", err)
 	}
 
 	// Parse response
 	var response BedrockResponse
 	if err := json.Unmarshal(output.Body, &response); err != nil {
-		return "", fmt.Errorf("unmarshal response: %w", err)
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+	usage.FromContext(ctx).AddTokens(response.Usage.InputTokens, response.Usage.OutputTokens)
+	TraceFromContext(ctx).AddRound(response.Usage.InputTokens, response.Usage.OutputTokens)
+
+	return &response, nil
+}
+
+// embeddingRequest is the Titan Text Embeddings V2 request format
+type embeddingRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions int    `json:"dimensions"`
+	Normalize  bool   `json:"normalize"`
+}
+
+// embeddingResponse is the Titan Text Embeddings V2 response format
+type embeddingResponse struct {
+	Embedding   []float32 `json:"embedding"`
+	InputTokens int       `json:"inputTextTokenCount"`
+}
+
+// Embed returns a normalized embedding of text for similarity comparisons
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if len(text) > maxEmbeddingInput {
+		cut := maxEmbeddingInput
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+
+	body, err := json.Marshal(embeddingRequest{InputText: text, Dimensions: embeddingDimensions, Normalize: true})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embedding request: %w", err)
+	}
+
+	if err := c.faults.Inject(ctx, chaos.TargetBedrock, "InvokeModel"); err != nil {
+		return nil, err
 	}
 
-	// Extract text from response
-	if len(response.Content) == 0 {
-		return "", fmt.Errorf("empty response from Bedrock")
+	output, err := c.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(c.embeddingModelID),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invoke embedding model: %w", err)
+	}
+
+	var response embeddingResponse
+	if err := json.Unmarshal(output.Body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshal embedding response: %w", err)
+	}
+	usage.FromContext(ctx).AddTokens(response.InputTokens, 0)
+	if len(response.Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding from Bedrock")
 	}
 
-	return response.Content[0].Text, nil
+	return response.Embedding, nil
 }
 
 // GetSystemPrompt returns the default system prompt for CloudOps assistant
@@ -0,0 +1,207 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+const (
+	// charsPerToken approximates how much text a Claude token covers,
+	// close enough to budget a context window without a tokenizer
+	charsPerToken = 4
+
+	// maxSummaryTokens bounds the response that updates a summary
+	maxSummaryTokens = 1024
+
+	// summaryHeader introduces the summary where it replaces older messages
+	summaryHeader = "Summary of the earlier conversation:\n"
+)
+
+// summaryPrompt instructs the model to fold dropped messages into the
+// conversation's summary
+const summaryPrompt = `You maintain the running summary of a long cloud operations troubleshooting conversation, so it can continue after its oldest messages are dropped.
+You are given the current summary, if any, and the messages being dropped. Reply with the updated summary only, in at most 300 words.
+Keep what later turns may need: the problem, resource names and IDs, findings, commands run and their results, decisions, and open questions. Drop pleasantries and repetition.`
+
+// EstimateTokens approximates the number of tokens in text
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// ContextManager keeps the history sent to the model within a token
+// budget. Once a conversation outgrows it, its oldest messages are folded
+// into a rolling summary that is sent in their place
+type ContextManager struct {
+	maxTokens int
+	summarize func(ctx context.Context, summary string, messages []models.Message) (string, error)
+}
+
+// NewContextManager creates a context manager that keeps history within
+// maxTokens, summarizing with client
+func NewContextManager(client *Client, maxTokens int) *ContextManager {
+	return &ContextManager{
+		maxTokens: maxTokens,
+		summarize: client.summarize,
+	}
+}
+
+// Window is the part of a conversation's history sent to the model
+type Window struct {
+	Messages []models.Message
+
+	// Summary covers the first Summarized messages of the history, which
+	// aren't in Messages
+	Summary    string
+	Summarized int
+}
+
+// Fit returns the history to send the model, given the summary of its
+// first summarized messages from an earlier Fit. When the rest doesn't fit
+// the budget, the newest messages filling half of it are kept and the
+// older ones are added to the summary. If that fails they are dropped
+// unsummarized, and summarizing is tried again on the next call
+func (m *ContextManager) Fit(ctx context.Context, history []models.Message, summary string, summarized int) *Window {
+	if summarized > len(history) {
+		summary, summarized = "", 0
+	}
+	w := &Window{Summary: summary, Summarized: summarized}
+
+	recent := history[summarized:]
+	if EstimateTokens(summary)+messageTokens(recent) > m.maxTokens {
+		keep := keepFrom(recent, m.maxTokens/2)
+		updated, err := m.fold(ctx, summary, recent[:keep])
+		if err != nil {
+			slog.WarnContext(ctx, "failed to summarize earlier messages, dropping them", "messages", keep, "error", err)
+		} else {
+			w.Summary, w.Summarized = updated, summarized+keep
+		}
+		recent = recent[keep:]
+	}
+
+	w.Messages = withSummary(w.Summary, recent)
+	return w
+}
+
+// fold adds messages to the summary a batch at a time, each batch small
+// enough to send the model
+func (m *ContextManager) fold(ctx context.Context, summary string, messages []models.Message) (string, error) {
+	budget := m.maxTokens / 2
+	for len(messages) > 0 {
+		n, tokens := 0, 0
+		for n < len(messages) {
+			tokens += EstimateTokens(messages[n].Content)
+			if n > 0 && tokens > budget {
+				break
+			}
+			n++
+		}
+
+		batch := make([]models.Message, n)
+		for i, msg := range messages[:n] {
+			msg.Content = clipTokens(msg.Content, budget)
+			batch[i] = msg
+		}
+
+		var err error
+		if summary, err = m.summarize(ctx, summary, batch); err != nil {
+			return "", err
+		}
+		messages = messages[n:]
+	}
+	return summary, nil
+}
+
+// summarize asks the model to add messages to a conversation's summary. No
+// tools are offered and nothing is streamed
+func (c *Client) summarize(ctx context.Context, summary string, messages []models.Message) (string, error) {
+	if summary == "" {
+		summary = "(none yet)"
+	}
+	var transcript strings.Builder
+	for _, msg := range messages {
+		role := "User"
+		if msg.Role == models.RoleAssistant {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", role, msg.Content)
+	}
+
+	req := BedrockRequest{
+		AnthropicVersion: "bedrock-2023-05-31",
+		MaxTokens:        maxSummaryTokens,
+		Messages: []Message{{Role: models.RoleUser, Content: []ContentBlock{{
+			Type: ContentText,
+			Text: "Current summary:\n" + summary + "\n\nMessages being dropped:\n" + transcript.String(),
+		}}}},
+		System: summaryPrompt,
+	}
+	response, err := c.invoke(WithStream(ctx, nil), &req)
+	if err != nil {
+		return "", fmt.Errorf("summarize history: %w", err)
+	}
+	text := strings.TrimSpace(textOf(response.Content))
+	if text == "" {
+		return "", fmt.Errorf("empty summary from Bedrock")
+	}
+	return text, nil
+}
+
+// keepFrom returns where the newest messages within budget start, keeping
+// at least the last message
+func keepFrom(messages []models.Message, budget int) int {
+	tokens := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		tokens += EstimateTokens(messages[i].Content)
+		if tokens > budget && i < len(messages)-1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// withSummary puts the summary ahead of the messages. It joins the first
+// message when that is the user's, so roles still alternate, and without a
+// summary the messages must start with the user's
+func withSummary(summary string, messages []models.Message) []models.Message {
+	if summary == "" {
+		for len(messages) > 0 && messages[0].Role != models.RoleUser {
+			messages = messages[1:]
+		}
+		return messages
+	}
+
+	header := summaryHeader + summary
+	if len(messages) > 0 && messages[0].Role == models.RoleUser {
+		out := append([]models.Message(nil), messages...)
+		out[0].Content = header + "\n\n" + out[0].Content
+		return out
+	}
+	return append([]models.Message{{Role: models.RoleUser, Content: header}}, messages...)
+}
+
+// messageTokens approximates the tokens in messages
+func messageTokens(messages []models.Message) int {
+	tokens := 0
+	for _, msg := range messages {
+		tokens += EstimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// clipTokens shortens text to about maxTokens
+func clipTokens(text string, maxTokens int) string {
+	limit := maxTokens * charsPerToken
+	if len(text) <= limit {
+		return text
+	}
+	limit-- // room for the ellipsis
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit] + "…"
+}
@@ -0,0 +1,101 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// conversation returns n messages alternating user and assistant, each
+// about tokens long
+func conversation(n, tokens int) []models.Message {
+	var messages []models.Message
+	for i := 0; i < n; i++ {
+		role := models.RoleUser
+		if i%2 == 1 {
+			role = models.RoleAssistant
+		}
+		messages = append(messages, models.Message{Role: role, Content: strings.Repeat("x", tokens*charsPerToken)})
+	}
+	return messages
+}
+
+func TestFitWithinBudget(t *testing.T) {
+	m := &ContextManager{maxTokens: 1000, summarize: func(context.Context, string, []models.Message) (string, error) {
+		t.Fatal("history within budget shouldn't be summarized")
+		return "", nil
+	}}
+
+	history := conversation(5, 100)
+	w := m.Fit(context.Background(), history, "", 0)
+	if len(w.Messages) != 5 || w.Summarized != 0 {
+		t.Errorf("Fit() = %d messages, %d summarized; want the whole history", len(w.Messages), w.Summarized)
+	}
+}
+
+func TestFitSummarizes(t *testing.T) {
+	var folded int
+	m := &ContextManager{maxTokens: 1000, summarize: func(ctx context.Context, summary string, messages []models.Message) (string, error) {
+		folded += len(messages)
+		return "disk full on i-0abc", nil
+	}}
+
+	history := conversation(21, 100)
+	w := m.Fit(context.Background(), history, "", 0)
+	if w.Summary != "disk full on i-0abc" || w.Summarized != 16 || folded != 16 {
+		t.Fatalf("Fit() summarized %d (%d folded) as %q, want the 16 oldest", w.Summarized, folded, w.Summary)
+	}
+	if len(w.Messages) != 5 || w.Messages[0].Role != models.RoleUser || !strings.HasPrefix(w.Messages[0].Content, summaryHeader+"disk full") {
+		t.Errorf("Fit() = %+v, want the summary joined to the first kept message", w.Messages[0])
+	}
+	if strings.HasPrefix(history[16].Content, summaryHeader) {
+		t.Error("Fit() changed the caller's history")
+	}
+
+	// The saved summary is reused until the history outgrows the budget
+	folded = 0
+	w = m.Fit(context.Background(), append(history, conversation(2, 100)...), w.Summary, w.Summarized)
+	if folded != 0 || w.Summarized != 16 || len(w.Messages) != 7 {
+		t.Errorf("Fit() = %d messages, %d summarized, %d folded; want the saved summary reused", len(w.Messages), w.Summarized, folded)
+	}
+}
+
+func TestFitSummaryFails(t *testing.T) {
+	m := &ContextManager{maxTokens: 1000, summarize: func(context.Context, string, []models.Message) (string, error) {
+		return "", errors.New("throttled")
+	}}
+
+	w := m.Fit(context.Background(), conversation(20, 100), "", 0)
+	if w.Summarized != 0 || w.Summary != "" {
+		t.Errorf("Fit() = %d summarized, want nothing recorded when summarizing fails", w.Summarized)
+	}
+	if len(w.Messages) == 0 || len(w.Messages) > 5 || w.Messages[0].Role != models.RoleUser {
+		t.Errorf("Fit() = %d messages, want the newest within budget, starting with the user's", len(w.Messages))
+	}
+}
+
+func TestFitBatches(t *testing.T) {
+	var batches int
+	m := &ContextManager{maxTokens: 1000, summarize: func(ctx context.Context, summary string, messages []models.Message) (string, error) {
+		batches++
+		if messageTokens(messages) > 500 {
+			t.Errorf("batch of %d tokens, want at most 500", messageTokens(messages))
+		}
+		return summary + "+", nil
+	}}
+
+	history := append(conversation(1, 5000), conversation(20, 100)...)
+	w := m.Fit(context.Background(), history, "", 0)
+	if batches < 2 || w.Summary == "" {
+		t.Errorf("%d batches, want the dropped messages folded in several", batches)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens("abcdefghi"); got != 3 {
+		t.Errorf("EstimateTokens() = %d, want 3", got)
+	}
+}
@@ -0,0 +1,123 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// maxInterimTokens caps the partial answer written while tools still run
+const maxInterimTokens = 1024
+
+// interimInstructions asks the model what the tool results so far show
+const interimInstructions = "These tool calls are taking a while and are still running: %s. Without calling any tools, tell the user in a few sentences what the results so far show and what you are still checking. They will get the full answer when the rest finish."
+
+// Progress records the rounds of tool calls an answer has finished, so a
+// caller tired of waiting can ask what they show before the answer is done.
+// Like a Trace, it is filled in from the context the answer runs in
+type Progress struct {
+	mu      sync.Mutex
+	rounds  []Message // the model's tool calls and their results
+	running []string  // tools called in the round being run
+}
+
+// NewProgress creates an empty progress record
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// Running returns the names of the tools still being run, or nil when the
+// model is writing
+func (p *Progress) Running() []string {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.running...)
+}
+
+// start records the tool calls of a round about to run
+func (p *Progress) start(content []ContentBlock) {
+	if p == nil {
+		return
+	}
+	var names []string
+	for _, block := range content {
+		if block.Type == ContentToolUse && !slices.Contains(names, block.Name) {
+			names = append(names, block.Name)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = names
+}
+
+// finish records a round once its tools have run
+func (p *Progress) finish(calls, results Message) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rounds = append(p.rounds, calls, results)
+	p.running = nil
+}
+
+// snapshot returns the finished rounds and the tools still running
+func (p *Progress) snapshot() ([]Message, []string) {
+	if p == nil {
+		return nil, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.rounds...), append([]string(nil), p.running...)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose tool rounds are recorded in p. A nil
+// p records nothing
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressFromContext returns the context's progress record, or nil when
+// there is none
+func progressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// Interim asks the model what the tool results recorded in p show so far,
+// for an answer still waiting on tools. It returns "" when no round of
+// tools has finished yet
+func (c *Client) Interim(ctx context.Context, messages []models.Message, systemPrompt string, p *Progress) (string, error) {
+	rounds, running := p.snapshot()
+	if len(messages) == 0 || len(rounds) == 0 {
+		return "", nil
+	}
+
+	// Tool calls in the history need the tools declared, though none are run
+	req := newRequest(messages, systemPrompt, c.Tools())
+	req.MaxTokens = maxInterimTokens
+	req.Messages = append(req.Messages, rounds...)
+
+	// The instructions join the last tool results, since the roles must
+	// alternate
+	last := &req.Messages[len(req.Messages)-1]
+	last.Content = append(slices.Clone(last.Content), ContentBlock{
+		Type: ContentText,
+		Text: fmt.Sprintf(interimInstructions, strings.Join(running, ", ")),
+	})
+
+	response, err := c.invoke(WithStream(ctx, nil), &req)
+	if err != nil {
+		return "", err
+	}
+	return textOf(response.Content), nil
+}
@@ -0,0 +1,64 @@
+package bedrock
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestProgress(t *testing.T) {
+	p := NewProgress()
+	content := []ContentBlock{
+		{Type: ContentText, Text: "Let me look."},
+		{Type: ContentToolUse, ID: "1", Name: "ecs"},
+		{Type: ContentToolUse, ID: "2", Name: "logs"},
+		{Type: ContentToolUse, ID: "3", Name: "ecs"},
+	}
+
+	p.start(content)
+	if got := p.Running(); !slices.Equal(got, []string{"ecs", "logs"}) {
+		t.Errorf("Running() = %v, want [ecs logs]", got)
+	}
+	if rounds, _ := p.snapshot(); len(rounds) != 0 {
+		t.Errorf("snapshot() = %d messages before the round finished, want none", len(rounds))
+	}
+
+	p.finish(
+		Message{Role: models.RoleAssistant, Content: content},
+		Message{Role: models.RoleUser, Content: []ContentBlock{{Type: ContentToolResult, ToolUseID: "1", Content: "ok"}}},
+	)
+	if got := p.Running(); len(got) != 0 {
+		t.Errorf("Running() = %v after the round finished, want none", got)
+	}
+	if rounds, _ := p.snapshot(); len(rounds) != 2 {
+		t.Errorf("snapshot() = %d messages, want the round's call and results", len(rounds))
+	}
+}
+
+func TestProgressNil(t *testing.T) {
+	var p *Progress
+	p.start([]ContentBlock{{Type: ContentToolUse, Name: "ecs"}})
+	p.finish(Message{}, Message{})
+	if got := p.Running(); got != nil {
+		t.Errorf("Running() = %v, want nil", got)
+	}
+	if got, err := (&Client{}).Interim(context.Background(), conversation(1, 10), "", p); got != "" || err != nil {
+		t.Errorf("Interim() = %q, %v; want nothing without finished rounds", got, err)
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	if progressFromContext(context.Background()) != nil {
+		t.Error("answers shouldn't record progress by default")
+	}
+	p := NewProgress()
+	ctx := WithProgress(context.Background(), p)
+	if progressFromContext(ctx) != p {
+		t.Error("WithProgress() should record progress in p")
+	}
+	if progressFromContext(WithProgress(ctx, nil)) != nil {
+		t.Error("WithProgress(nil) should stop recording progress")
+	}
+}
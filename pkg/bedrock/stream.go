@@ -0,0 +1,185 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/usage"
+)
+
+// StreamFunc receives the text of a model response as it is generated:
+// the whole text so far, not just what arrived since the last call. Each
+// round of tool calls starts a new response, so the text can start over
+type StreamFunc func(text string)
+
+type streamKey struct{}
+
+// WithStream returns a context whose model requests stream their responses
+// to fn. A nil fn turns streaming off
+func WithStream(ctx context.Context, fn StreamFunc) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+// streamFromContext returns the context's stream function, or nil when
+// responses aren't streamed
+func streamFromContext(ctx context.Context) StreamFunc {
+	fn, _ := ctx.Value(streamKey{}).(StreamFunc)
+	return fn
+}
+
+// invokeStream sends one request to the model with
+// InvokeModelWithResponseStream, passing text to fn as it arrives
+func (c *Client) invokeStream(ctx context.Context, body []byte, fn StreamFunc) (*BedrockResponse, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetBedrock, "InvokeModelWithResponseStream"); err != nil {
+		return nil, err
+	}
+
+	output, err := c.client.InvokeModelWithResponseStream(ctx, &bedrockruntime.InvokeModelWithResponseStreamInput{
+		ModelId:     aws.String(c.modelID),
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+		Body:        body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invoke bedrock model with response stream: %w", err)
+	}
+	stream := output.GetStream()
+	defer stream.Close()
+
+	var s streamAssembler
+	for event := range stream.Events() {
+		chunk, ok := event.(*types.ResponseStreamMemberChunk)
+		if !ok {
+			continue
+		}
+		changed, err := s.add(chunk.Value.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			fn(s.text())
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("read response stream: %w", err)
+	}
+
+	response, err := s.response()
+	if err != nil {
+		return nil, err
+	}
+	usage.FromContext(ctx).AddTokens(response.Usage.InputTokens, response.Usage.OutputTokens)
+	TraceFromContext(ctx).AddRound(response.Usage.InputTokens, response.Usage.OutputTokens)
+
+	return response, nil
+}
+
+// streamEvent is one event of a streamed Claude Messages API response
+type streamEvent struct {
+	Type         string          `json:"type"`
+	Index        int             `json:"index"`
+	Message      BedrockResponse `json:"message"`
+	ContentBlock ContentBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamAssembler rebuilds a response from the events of its stream
+type streamAssembler struct {
+	message BedrockResponse
+	inputs  map[int]*strings.Builder // partial tool inputs by block index
+	stopped bool
+}
+
+// add applies one event, reporting whether it changed the response's text
+func (s *streamAssembler) add(data []byte) (bool, error) {
+	var event streamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return false, fmt.Errorf("unmarshal stream event: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		s.message = event.Message
+		s.message.Content = nil
+
+	case "content_block_start":
+		for len(s.message.Content) <= event.Index {
+			s.message.Content = append(s.message.Content, ContentBlock{})
+		}
+		s.message.Content[event.Index] = event.ContentBlock
+		if event.ContentBlock.Type == ContentToolUse {
+			if s.inputs == nil {
+				s.inputs = map[int]*strings.Builder{}
+			}
+			s.inputs[event.Index] = &strings.Builder{}
+		}
+
+	case "content_block_delta":
+		if event.Index >= len(s.message.Content) {
+			return false, fmt.Errorf("delta for unknown content block %d", event.Index)
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			s.message.Content[event.Index].Text += event.Delta.Text
+			return event.Delta.Text != "", nil
+		case "input_json_delta":
+			if b, ok := s.inputs[event.Index]; ok {
+				b.WriteString(event.Delta.PartialJSON)
+			}
+		}
+
+	case "content_block_stop":
+		if b, ok := s.inputs[event.Index]; ok && event.Index < len(s.message.Content) {
+			input := b.String()
+			if input == "" {
+				input = "{}"
+			}
+			s.message.Content[event.Index].Input = json.RawMessage(input)
+			delete(s.inputs, event.Index)
+		}
+
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			s.message.StopReason = event.Delta.StopReason
+		}
+		s.message.Usage.OutputTokens = event.Usage.OutputTokens
+
+	case "message_stop":
+		s.stopped = true
+
+	case "error":
+		return false, fmt.Errorf("model stream error: %s: %s", event.Error.Type, event.Error.Message)
+	}
+	return false, nil
+}
+
+// text returns the response's text so far
+func (s *streamAssembler) text() string {
+	return textOf(s.message.Content)
+}
+
+// response returns the assembled response, once the stream has ended
+func (s *streamAssembler) response() (*BedrockResponse, error) {
+	if !s.stopped {
+		return nil, fmt.Errorf("response stream ended early")
+	}
+	return &s.message, nil
+}
@@ -0,0 +1,76 @@
+package bedrock
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStreamAssembler(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":120,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"echo","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"text\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"hi\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":42}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	var s streamAssembler
+	var streamed []string
+	for _, e := range events {
+		changed, err := s.add([]byte(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed {
+			streamed = append(streamed, s.text())
+		}
+	}
+	if strings.Join(streamed, "|") != "Let me |Let me check." {
+		t.Errorf("streamed %q, want the text so far after each delta", streamed)
+	}
+
+	response, err := s.response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StopReason != StopToolUse || response.Usage.InputTokens != 120 || response.Usage.OutputTokens != 42 {
+		t.Errorf("response = %+v, want tool_use with its usage", response)
+	}
+	if len(response.Content) != 2 || string(response.Content[1].Input) != `{"text":"hi"}` {
+		t.Errorf("content = %+v, want the text and the tool call with its input", response.Content)
+	}
+}
+
+func TestStreamAssemblerIncomplete(t *testing.T) {
+	var s streamAssembler
+	if _, err := s.add([]byte(`{"type":"message_start","message":{"content":[]}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.response(); err == nil {
+		t.Error("response() should fail for a stream that didn't finish")
+	}
+
+	if _, err := s.add([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)); err == nil {
+		t.Error("add() should fail on an error event")
+	}
+}
+
+func TestWithStream(t *testing.T) {
+	if streamFromContext(context.Background()) != nil {
+		t.Error("responses shouldn't stream by default")
+	}
+	ctx := WithStream(context.Background(), func(string) {})
+	if streamFromContext(ctx) == nil {
+		t.Error("WithStream() should stream responses")
+	}
+	if streamFromContext(WithStream(ctx, nil)) != nil {
+		t.Error("WithStream(nil) should turn streaming off")
+	}
+}
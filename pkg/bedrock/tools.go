@@ -0,0 +1,303 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/usage"
+)
+
+// maxToolRounds bounds how many times one answer can call tools, so a model
+// that keeps asking for more data can't run up an unbounded bill
+const maxToolRounds = 10
+
+// maxToolRetries bounds how many times a tool that failed can be called
+// again in a row while answering. The failure is shown to the model, which
+// can often correct its input, but one that can't, or a tool that keeps
+// failing, shouldn't use up every round
+const maxToolRetries = 2
+
+// Tool is an operation the model can call while answering, such as a
+// read-only AWS API query
+type Tool interface {
+	// Name identifies the tool to the model; letters, digits, _ and - only
+	Name() string
+
+	// Description tells the model what the tool does and when to use it
+	Description() string
+
+	// InputSchema is the JSON Schema of the tool's input object
+	InputSchema() map[string]interface{}
+
+	// Execute runs the tool and returns its result as text for the model.
+	// An error is shown to the model, which can retry or explain it
+	Execute(ctx context.Context, input json.RawMessage) (string, error)
+}
+
+// Classified is implemented by tools whose results can hold data that isn't
+// fit for every channel. Results of other tools are public
+type Classified interface {
+	// Classify returns the data class of a result, one of the privacy
+	// package's Class constants
+	Classify(output string) string
+}
+
+// Diagnoser explains tool failures, such as access denied or throttling
+// errors, so the model can offer a remedy instead of the bare error
+type Diagnoser interface {
+	// Diagnose returns the explanation of a failed call of tool, or ""
+	// when it has none
+	Diagnose(ctx context.Context, tool string, err error) string
+}
+
+// Content block types in the Claude Messages API
+const (
+	ContentText       = "text"
+	ContentToolUse    = "tool_use"
+	ContentToolResult = "tool_result"
+)
+
+// Stop reasons in the Claude Messages API
+const (
+	StopEndTurn = "end_turn"
+	StopToolUse = "tool_use"
+)
+
+// ContentBlock is one part of a message: text, a tool call from the model,
+// or the result of one
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// Message is a message in the Claude Messages API, whose content can hold
+// tool calls and results as well as text
+type Message struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+// ToolSpec describes a tool to the model
+type ToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// RegisterTool makes a tool available to the model. A tool registered under
+// an existing name replaces it
+func (c *Client) RegisterTool(tool Tool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, t := range c.tools {
+		if t.Name() == tool.Name() {
+			c.tools[i] = tool
+			return
+		}
+	}
+	c.tools = append(c.tools, tool)
+}
+
+// Tools returns the registered tools
+func (c *Client) Tools() []Tool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Tool(nil), c.tools...)
+}
+
+// toolSpecs describes tools for a request
+func toolSpecs(tools []Tool) []ToolSpec {
+	specs := make([]ToolSpec, 0, len(tools))
+	for _, t := range tools {
+		specs = append(specs, ToolSpec{Name: t.Name(), Description: t.Description(), InputSchema: t.InputSchema()})
+	}
+	return specs
+}
+
+// ToolCall is a call the model made to a tool
+type ToolCall struct {
+	Name  string
+	Input json.RawMessage
+}
+
+// ToolFunc runs a tool call and returns its result for the model
+type ToolFunc func(ctx context.Context, call ToolCall) (string, error)
+
+// ToolMiddleware wraps tool dispatch, so a step can inspect, change, or
+// refuse a call, or scan its result before the model sees it
+type ToolMiddleware func(next ToolFunc) ToolFunc
+
+type toolMiddlewareKey struct{}
+
+// WithToolMiddleware returns a context whose tool calls run through mw, the
+// first outermost
+func WithToolMiddleware(ctx context.Context, mw ...ToolMiddleware) context.Context {
+	if len(mw) == 0 {
+		return ctx
+	}
+	outer, _ := ctx.Value(toolMiddlewareKey{}).([]ToolMiddleware)
+	return context.WithValue(ctx, toolMiddlewareKey{}, append(append([]ToolMiddleware(nil), outer...), mw...))
+}
+
+// dispatcher returns the function that runs tool under the context's
+// middleware
+func dispatcher(ctx context.Context, tool Tool) ToolFunc {
+	run := ToolFunc(func(ctx context.Context, call ToolCall) (string, error) {
+		return tool.Execute(ctx, call.Input)
+	})
+	mw, _ := ctx.Value(toolMiddlewareKey{}).([]ToolMiddleware)
+	for i := len(mw) - 1; i >= 0; i-- {
+		run = mw[i](run)
+	}
+	return run
+}
+
+// SetDiagnoser explains failed tool calls to the model
+func (c *Client) SetDiagnoser(d Diagnoser) {
+	c.diagnoser = d
+}
+
+// retryBudget counts each tool's failures in a row while answering, to hold the
+// model to maxToolRetries and record when it corrects a failed call. A nil
+// retryBudget sets no limit
+type retryBudget struct {
+	failures map[string]int // by tool
+}
+
+func newRetryBudget() *retryBudget {
+	return &retryBudget{failures: make(map[string]int)}
+}
+
+// exhausted reports whether tool has failed too often to be called again
+func (r *retryBudget) exhausted(tool string) (int, bool) {
+	if r == nil {
+		return 0, false
+	}
+	return r.failures[tool], r.failures[tool] > maxToolRetries
+}
+
+// failed records a failed call of tool and returns what the model should
+// know about calling it again
+func (r *retryBudget) failed(ctx context.Context, tool string) string {
+	if r == nil {
+		return ""
+	}
+	r.failures[tool]++
+	left := maxToolRetries + 1 - r.failures[tool]
+	if left > 0 {
+		return fmt.Sprintf("Calls of %s left for this answer if this one is retried: %d.", tool, left)
+	}
+	slog.WarnContext(ctx, "tool retries exhausted", "tool", tool, "failures", r.failures[tool])
+	logging.MetricBy(ctx, "ToolRetriesExhausted", 1, logging.UnitCount, "tool", tool)
+	return fmt.Sprintf("%s has now failed %d times in a row; don't call it again for this answer. Tell the user what failed.", tool, r.failures[tool])
+}
+
+// succeeded records a successful call of tool. One after failures means
+// the model corrected the call, and how many tries that took is a metric,
+// so tools whose input the model often gets wrong stand out
+func (r *retryBudget) succeeded(ctx context.Context, tool string) {
+	if r == nil || r.failures[tool] == 0 {
+		return
+	}
+	slog.InfoContext(ctx, "tool call corrected", "tool", tool, "failures", r.failures[tool])
+	logging.MetricBy(ctx, "ToolCorrected", float64(r.failures[tool]), logging.UnitCount, "tool", tool)
+	delete(r.failures, tool)
+}
+
+// runTools executes the tool calls in a response and returns their results,
+// in order, for the next request. Failures are explained by diagnoser when
+// there is one, and count against budget
+func runTools(ctx context.Context, tools []Tool, content []ContentBlock, diagnoser Diagnoser, budget *retryBudget) []ContentBlock {
+	byName := make(map[string]Tool, len(tools))
+	for _, t := range tools {
+		byName[t.Name()] = t
+	}
+
+	var results []ContentBlock
+	for _, block := range content {
+		if block.Type != ContentToolUse {
+			continue
+		}
+
+		result := ContentBlock{Type: ContentToolResult, ToolUseID: block.ID}
+		tool, ok := byName[block.Name]
+		if !ok {
+			result.Content = fmt.Sprintf("Unknown tool %q", block.Name)
+			result.IsError = true
+			results = append(results, result)
+			continue
+		}
+		if failures, ok := budget.exhausted(block.Name); ok {
+			result.Content = fmt.Sprintf("Not run: %s already failed %d times in a row for this answer. Tell the user what failed instead.", block.Name, failures)
+			result.IsError = true
+			results = append(results, result)
+			continue
+		}
+
+		usage.FromContext(ctx).AddToolCall()
+		started := time.Now()
+		output, err := dispatcher(ctx, tool)(ctx, ToolCall{Name: block.Name, Input: block.Input})
+		call := ToolTrace{Name: block.Name, Input: block.Input, Duration: time.Since(started)}
+		logging.Metric(ctx, "ToolDuration", float64(call.Duration.Milliseconds()), logging.UnitMilliseconds)
+		if err != nil {
+			slog.WarnContext(ctx, "tool failed", "tool", block.Name, "error", err)
+			result.Content = err.Error()
+			if diagnoser != nil {
+				if diagnosis := diagnoser.Diagnose(ctx, block.Name, err); diagnosis != "" {
+					result.Content += "\n\n" + diagnosis
+				}
+			}
+			if note := budget.failed(ctx, block.Name); note != "" {
+				result.Content += "\n\n" + note
+			}
+			result.IsError = true
+			call.Output, call.IsError = result.Content, true
+			TraceFromContext(ctx).AddCall(call)
+			results = append(results, result)
+			continue
+		}
+
+		budget.succeeded(ctx, block.Name)
+
+		// The turn's privacy policy decides whether the model may see the
+		// result, and whether the answer must wait for confirmation
+		if classified, ok := tool.(Classified); ok {
+			if privacy.FromContext(ctx).Check(classified.Classify(output)) == privacy.Mask {
+				slog.InfoContext(ctx, "withheld restricted tool result", "tool", block.Name)
+				output = privacy.Masked(block.Name)
+			}
+		}
+		result.Content = output
+		call.Output = output
+		TraceFromContext(ctx).AddCall(call)
+		results = append(results, result)
+	}
+	return results
+}
+
+// textOf joins the text blocks of a response
+func textOf(content []ContentBlock) string {
+	var parts []string
+	for _, block := range content {
+		if block.Type == ContentText && strings.TrimSpace(block.Text) != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
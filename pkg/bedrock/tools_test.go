@@ -0,0 +1,260 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/usage"
+)
+
+// echoTool returns its input, or fails when asked to
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echoes its input" }
+
+func (echoTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+	}
+}
+
+func (echoTool) Execute(ctx context.Context, input json.RawMessage) (string, error) {
+	var in struct{ Text string }
+	if err := json.Unmarshal(input, &in); err != nil {
+		return "", err
+	}
+	if in.Text == "fail" {
+		return "", errors.New("AccessDenied")
+	}
+	return in.Text, nil
+}
+
+func TestRunTools(t *testing.T) {
+	meter := usage.NewMeter()
+	ctx := usage.WithMeter(context.Background(), meter)
+
+	content := []ContentBlock{
+		{Type: ContentText, Text: "Let me check."},
+		{Type: ContentToolUse, ID: "t1", Name: "echo", Input: json.RawMessage(`{"text":"hello"}`)},
+		{Type: ContentToolUse, ID: "t2", Name: "echo", Input: json.RawMessage(`{"text":"fail"}`)},
+		{Type: ContentToolUse, ID: "t3", Name: "missing", Input: json.RawMessage(`{}`)},
+	}
+
+	results := runTools(ctx, []Tool{echoTool{}}, content, nil, nil)
+	if len(results) != 3 {
+		t.Fatalf("runTools() returned %d results, want 3", len(results))
+	}
+
+	want := []ContentBlock{
+		{Type: ContentToolResult, ToolUseID: "t1", Content: "hello"},
+		{Type: ContentToolResult, ToolUseID: "t2", Content: "AccessDenied", IsError: true},
+		{Type: ContentToolResult, ToolUseID: "t3", Content: `Unknown tool "missing"`, IsError: true},
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Type != w.Type || got.ToolUseID != w.ToolUseID || got.Content != w.Content || got.IsError != w.IsError {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if calls := meter.Take().ToolCalls; calls != 2 {
+		t.Errorf("ToolCalls = %d, want 2 (unknown tools aren't run)", calls)
+	}
+}
+
+func TestRunToolsTrace(t *testing.T) {
+	trace := NewTrace()
+	ctx := WithTrace(context.Background(), trace)
+
+	content := []ContentBlock{
+		{Type: ContentToolUse, ID: "t1", Name: "echo", Input: json.RawMessage(`{"text":"hello"}`)},
+		{Type: ContentToolUse, ID: "t2", Name: "echo", Input: json.RawMessage(`{"text":"fail"}`)},
+	}
+	runTools(ctx, []Tool{echoTool{}}, content, nil, nil)
+
+	calls := trace.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("trace has %d calls, want 2", len(calls))
+	}
+	if calls[0].Name != "echo" || string(calls[0].Input) != `{"text":"hello"}` || calls[0].Output != "hello" || calls[0].IsError {
+		t.Errorf("call 1 = %+v", calls[0])
+	}
+	if !calls[1].IsError || calls[1].Output != "AccessDenied" {
+		t.Errorf("call 2 = %+v, want a failed call", calls[1])
+	}
+
+	trace.AddRound(100, 20)
+	trace.AddRound(150, 30)
+	if in, out := trace.Tokens(); in != 250 || out != 50 || trace.Rounds() != 2 {
+		t.Errorf("Tokens() = %d, %d over %d rounds; want 250, 50 over 2", in, out, trace.Rounds())
+	}
+}
+
+// fixedDiagnoser explains every failure the same way
+type fixedDiagnoser string
+
+func (d fixedDiagnoser) Diagnose(ctx context.Context, tool string, err error) string {
+	return string(d)
+}
+
+func TestRunToolsDiagnosed(t *testing.T) {
+	content := []ContentBlock{
+		{Type: ContentToolUse, ID: "t1", Name: "echo", Input: json.RawMessage(`{"text":"fail"}`)},
+		{Type: ContentToolUse, ID: "t2", Name: "echo", Input: json.RawMessage(`{"text":"hello"}`)},
+	}
+
+	results := runTools(context.Background(), []Tool{echoTool{}}, content, fixedDiagnoser("Grant the action"), nil)
+	if got, want := results[0].Content, "AccessDenied\n\nGrant the action"; got != want || !results[0].IsError {
+		t.Errorf("failed result = %q, want %q", got, want)
+	}
+	if got := results[1].Content; got != "hello" {
+		t.Errorf("successful result = %q, want it undiagnosed", got)
+	}
+
+	results = runTools(context.Background(), []Tool{echoTool{}}, content, fixedDiagnoser(""), nil)
+	if got := results[0].Content; got != "AccessDenied" {
+		t.Errorf("result without a diagnosis = %q, want the bare error", got)
+	}
+}
+
+// secretTool returns restricted data
+type secretTool struct{ echoTool }
+
+func (secretTool) Name() string                  { return "secret" }
+func (secretTool) Classify(output string) string { return privacy.ClassRestricted }
+
+func TestRunToolsClassified(t *testing.T) {
+	content := []ContentBlock{{Type: ContentToolUse, ID: "t1", Name: "secret", Input: json.RawMessage(`{"text":"AKIA..."}`)}}
+
+	policy := privacy.NewPolicy(privacy.Public, models.ProfileReadOnly)
+	results := runTools(privacy.WithPolicy(context.Background(), policy), []Tool{secretTool{}}, content, nil, nil)
+	if got := results[0].Content; got != privacy.Masked("secret") {
+		t.Errorf("restricted result for a read-only user = %q, want it masked", got)
+	}
+
+	policy = privacy.NewPolicy(privacy.Public, models.ProfileOperator)
+	results = runTools(privacy.WithPolicy(context.Background(), policy), []Tool{secretTool{}}, content, nil, nil)
+	if got := results[0].Content; got != "AKIA..." || !policy.Restricted() {
+		t.Errorf("restricted result for an operator = %q, restricted = %v; want it kept for confirmation", got, policy.Restricted())
+	}
+
+	if got := runTools(context.Background(), []Tool{secretTool{}}, content, nil, nil)[0].Content; got != "AKIA..." {
+		t.Errorf("result without a policy = %q, want it unchanged", got)
+	}
+}
+
+func TestRegisterToolReplaces(t *testing.T) {
+	c := &Client{}
+	c.RegisterTool(echoTool{})
+	c.RegisterTool(echoTool{})
+
+	if got := len(c.Tools()); got != 1 {
+		t.Errorf("len(Tools()) = %d, want 1", got)
+	}
+	if specs := toolSpecs(c.Tools()); specs[0].Name != "echo" || specs[0].InputSchema["type"] != "object" {
+		t.Errorf("toolSpecs() = %+v", specs)
+	}
+}
+
+func TestTextOf(t *testing.T) {
+	content := []ContentBlock{
+		{Type: ContentText, Text: "CPU is at 92%."},
+		{Type: ContentToolUse, ID: "t1", Name: "echo"},
+		{Type: ContentText, Text: " "},
+		{Type: ContentText, Text: "Scale out the service."},
+	}
+	if got, want := textOf(content), "CPU is at 92%.\n\nScale out the service."; got != want {
+		t.Errorf("textOf() = %q, want %q", got, want)
+	}
+}
+
+func TestContentBlockJSON(t *testing.T) {
+	data, err := json.Marshal(ContentBlock{Type: ContentToolResult, ToolUseID: "t1", Content: "ok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"type":"tool_result","tool_use_id":"t1","content":"ok"}`; got != want {
+		t.Errorf("json = %s, want %s", got, want)
+	}
+}
+
+func TestRunToolsMiddleware(t *testing.T) {
+	var order []string
+	tag := func(name string) ToolMiddleware {
+		return func(next ToolFunc) ToolFunc {
+			return func(ctx context.Context, call ToolCall) (string, error) {
+				order = append(order, name)
+				output, err := next(ctx, call)
+				return name + "(" + output + ")", err
+			}
+		}
+	}
+	refuse := func(next ToolFunc) ToolFunc {
+		return func(ctx context.Context, call ToolCall) (string, error) {
+			if string(call.Input) == `{"text":"secret"}` {
+				return "", errors.New("blocked by policy")
+			}
+			return next(ctx, call)
+		}
+	}
+
+	ctx := WithToolMiddleware(context.Background(), tag("outer"))
+	ctx = WithToolMiddleware(ctx, tag("inner"), refuse)
+	content := []ContentBlock{
+		{Type: ContentToolUse, ID: "t1", Name: "echo", Input: json.RawMessage(`{"text":"hello"}`)},
+		{Type: ContentToolUse, ID: "t2", Name: "echo", Input: json.RawMessage(`{"text":"secret"}`)},
+	}
+
+	results := runTools(ctx, []Tool{echoTool{}}, content, nil, nil)
+	if got := results[0].Content; got != "outer(inner(hello))" {
+		t.Errorf("result = %q, want outer(inner(hello))", got)
+	}
+	if got := results[1].Content; got != "blocked by policy" || !results[1].IsError {
+		t.Errorf("refused result = %+v, want the middleware's error", results[1])
+	}
+	if got := fmt.Sprint(order); got != "[outer inner outer inner]" {
+		t.Errorf("order = %s, want outer before inner for each call", got)
+	}
+}
+
+func TestRunToolsRetryBudget(t *testing.T) {
+	meter := usage.NewMeter()
+	ctx := usage.WithMeter(context.Background(), meter)
+	budget := newRetryBudget()
+	failing := []ContentBlock{{Type: ContentToolUse, ID: "t1", Name: "echo", Input: json.RawMessage(`{"text":"fail"}`)}}
+	passing := []ContentBlock{{Type: ContentToolUse, ID: "t2", Name: "echo", Input: json.RawMessage(`{"text":"fixed"}`)}}
+
+	// A failure followed by a corrected call resets the count
+	if got := runTools(ctx, []Tool{echoTool{}}, failing, nil, budget)[0].Content; !strings.HasSuffix(got, "left for this answer if this one is retried: 2.") {
+		t.Errorf("first failure = %q, want the calls left", got)
+	}
+	if got := runTools(ctx, []Tool{echoTool{}}, passing, nil, budget)[0].Content; got != "fixed" {
+		t.Errorf("corrected call = %q, want fixed", got)
+	}
+	if failures, _ := budget.exhausted("echo"); failures != 0 {
+		t.Errorf("failures after a correction = %d, want 0", failures)
+	}
+
+	for i := 0; i < maxToolRetries; i++ {
+		runTools(ctx, []Tool{echoTool{}}, failing, nil, budget)
+	}
+	last := runTools(ctx, []Tool{echoTool{}}, failing, nil, budget)[0]
+	if !strings.Contains(last.Content, "don't call it again") || !last.IsError {
+		t.Errorf("last allowed failure = %+v", last)
+	}
+	refused := runTools(ctx, []Tool{echoTool{}}, passing, nil, budget)[0]
+	if !strings.HasPrefix(refused.Content, "Not run:") || !refused.IsError {
+		t.Errorf("call over budget = %+v, want it refused", refused)
+	}
+	if calls := meter.Take().ToolCalls; calls != 2+maxToolRetries+1 {
+		t.Errorf("ToolCalls = %d, want %d (refused calls aren't run)", calls, 2+maxToolRetries+1)
+	}
+}
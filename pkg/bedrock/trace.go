@@ -0,0 +1,99 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// ToolTrace is one tool call made while answering
+type ToolTrace struct {
+	Name     string
+	Input    json.RawMessage
+	Output   string
+	IsError  bool
+	Duration time.Duration
+}
+
+// Trace records what the model did while answering: the tool calls it made
+// and the tokens it used. It backs the debug mode that shows this under
+// answers
+type Trace struct {
+	mu           sync.Mutex
+	calls        []ToolTrace
+	rounds       int
+	inputTokens  int
+	outputTokens int
+}
+
+// NewTrace creates an empty trace
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// Calls returns the tool calls in the order they were made
+func (t *Trace) Calls() []ToolTrace {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]ToolTrace(nil), t.calls...)
+}
+
+// Tokens returns the input and output tokens used
+func (t *Trace) Tokens() (input, output int) {
+	if t == nil {
+		return 0, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inputTokens, t.outputTokens
+}
+
+// Rounds returns how many requests were sent to the model
+func (t *Trace) Rounds() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rounds
+}
+
+// AddCall records a tool call. Safe on a nil Trace
+func (t *Trace) AddCall(call ToolTrace) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, call)
+}
+
+// AddRound records a request and its token usage. Safe on a nil Trace
+func (t *Trace) AddRound(input, output int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rounds++
+	t.inputTokens += input
+	t.outputTokens += output
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context whose model requests and tool calls are
+// recorded in t
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// TraceFromContext returns the context's trace, or nil when there is none
+func TraceFromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
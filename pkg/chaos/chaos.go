@@ -0,0 +1,104 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Fault injection targets
+const (
+	TargetSlack    = "slack"
+	TargetDynamoDB = "dynamodb"
+	TargetBedrock  = "bedrock"
+)
+
+// Targets lists every fault injection target
+var Targets = []string{TargetSlack, TargetDynamoDB, TargetBedrock}
+
+// ErrInjected is returned (wrapped) for every artificially injected failure
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Injector introduces artificial latency and errors into downstream calls
+// so resilience features can be exercised outside production.
+// A nil *Injector is valid and never injects anything.
+type Injector struct {
+	latency   time.Duration
+	errorRate float64
+	targets   map[string]bool // empty means all targets
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New creates an injector that delays each call by up to latency and fails
+// the given fraction of calls (0.0-1.0). If no targets are given, every
+// target is affected.
+func New(latency time.Duration, errorRate float64, targets ...string) *Injector {
+	t := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if target != "" {
+			t[target] = true
+		}
+	}
+
+	return &Injector{
+		latency:   latency,
+		errorRate: errorRate,
+		targets:   t,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Inject applies the configured faults for a call to op on target.
+// It returns an error wrapping ErrInjected when the call should fail,
+// or the context error if the context is cancelled while delaying.
+func (i *Injector) Inject(ctx context.Context, target, op string) error {
+	if i == nil || !i.Applies(target) {
+		return nil
+	}
+
+	if delay := i.delay(); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if i.roll() < i.errorRate {
+		return fmt.Errorf("%s %s: %w", target, op, ErrInjected)
+	}
+
+	return nil
+}
+
+// Applies reports whether faults are injected for the given target
+func (i *Injector) Applies(target string) bool {
+	if i == nil {
+		return false
+	}
+	return len(i.targets) == 0 || i.targets[target]
+}
+
+// delay returns a random latency in [0, latency]
+func (i *Injector) delay() time.Duration {
+	if i.latency <= 0 {
+		return 0
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return time.Duration(i.rand.Int63n(int64(i.latency) + 1))
+}
+
+func (i *Injector) roll() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64()
+}
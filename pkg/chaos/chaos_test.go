@@ -0,0 +1,74 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNilInjector(t *testing.T) {
+	var i *Injector
+
+	if err := i.Inject(context.Background(), TargetSlack, "PostMessage"); err != nil {
+		t.Errorf("nil Injector.Inject() error = %v, want nil", err)
+	}
+
+	if i.Applies(TargetSlack) {
+		t.Error("nil Injector should not apply to any target")
+	}
+}
+
+func TestInjectErrorRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		errorRate float64
+		wantErr   bool
+	}{
+		{"never fails", 0, false},
+		{"always fails", 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := New(0, tt.errorRate)
+			for n := 0; n < 20; n++ {
+				err := i.Inject(context.Background(), TargetDynamoDB, "GetItem")
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Inject() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil && !errors.Is(err, ErrInjected) {
+					t.Errorf("Inject() error = %v, want ErrInjected", err)
+				}
+			}
+		})
+	}
+}
+
+func TestInjectTargets(t *testing.T) {
+	i := New(0, 1, TargetBedrock)
+
+	if err := i.Inject(context.Background(), TargetSlack, "PostMessage"); err != nil {
+		t.Errorf("Inject() on untargeted service error = %v, want nil", err)
+	}
+
+	if err := i.Inject(context.Background(), TargetBedrock, "InvokeModel"); err == nil {
+		t.Error("Inject() on targeted service should fail")
+	}
+}
+
+func TestInjectLatencyRespectsContext(t *testing.T) {
+	i := New(time.Hour, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := i.Inject(ctx, TargetSlack, "PostMessage")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Inject() error = %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Inject() should return as soon as the context is done")
+	}
+}
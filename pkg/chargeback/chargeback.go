@@ -0,0 +1,199 @@
+package chargeback
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Unassigned is the cost center for usage that couldn't be attributed to a team
+const Unassigned = "unassigned"
+
+// MonthLayout formats the month a usage record belongs to
+const MonthLayout = "2006-01"
+
+// Rates prices each kind of usage in USD
+type Rates struct {
+	InputTokensPerMillion  float64
+	OutputTokensPerMillion float64
+	ToolCall               float64
+	TaskHour               float64
+}
+
+// DefaultRates are list prices for the default model and a 1 vCPU / 2 GB
+// Fargate task in us-east-1
+var DefaultRates = Rates{
+	InputTokensPerMillion:  3,
+	OutputTokensPerMillion: 15,
+	ToolCall:               0.00002,
+	TaskHour:               0.0494,
+}
+
+// ModelCost prices the tokens of model calls
+func (r Rates) ModelCost(inputTokens, outputTokens int64) float64 {
+	return float64(inputTokens)/1e6*r.InputTokensPerMillion + float64(outputTokens)/1e6*r.OutputTokensPerMillion
+}
+
+// CostCenter picks the team charged for a conversation: the channel's mapping
+// wins, then the requester's profile field, then Unassigned
+func CostCenter(channelID string, channels map[string]string, profileValue string) string {
+	if team := strings.TrimSpace(channels[channelID]); team != "" {
+		return team
+	}
+	if team := strings.TrimSpace(profileValue); team != "" {
+		return team
+	}
+	return Unassigned
+}
+
+// Month returns the usage month containing t
+func Month(t time.Time) string {
+	return t.UTC().Format(MonthLayout)
+}
+
+// PreviousMonth returns the last complete usage month before t
+func PreviousMonth(t time.Time) string {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format(MonthLayout)
+}
+
+// Line is one cost center's priced usage
+type Line struct {
+	models.UsageRecord
+	ModelCost   float64
+	ToolCost    float64
+	RuntimeCost float64
+}
+
+// Cost is the line's total charge
+func (l Line) Cost() float64 {
+	return l.ModelCost + l.ToolCost + l.RuntimeCost
+}
+
+// Report is a month's chargeback across cost centers
+type Report struct {
+	Month string
+	Lines []Line // most expensive first
+	Total Line
+}
+
+// Build prices each record, merging duplicates for the same cost center
+func Build(month string, records []*models.UsageRecord, rates Rates) *Report {
+	merged := make(map[string]*models.UsageRecord)
+	for _, rec := range records {
+		name := rec.CostCenter
+		if name == "" {
+			name = Unassigned
+		}
+		m, ok := merged[name]
+		if !ok {
+			m = &models.UsageRecord{Month: month, CostCenter: name}
+			merged[name] = m
+		}
+		add(m, rec)
+	}
+
+	r := &Report{Month: month, Total: Line{UsageRecord: models.UsageRecord{Month: month, CostCenter: "total"}}}
+	for _, rec := range merged {
+		line := price(*rec, rates)
+		r.Lines = append(r.Lines, line)
+
+		add(&r.Total.UsageRecord, rec)
+		r.Total.ModelCost += line.ModelCost
+		r.Total.ToolCost += line.ToolCost
+		r.Total.RuntimeCost += line.RuntimeCost
+	}
+
+	sort.Slice(r.Lines, func(i, j int) bool {
+		if r.Lines[i].Cost() != r.Lines[j].Cost() {
+			return r.Lines[i].Cost() > r.Lines[j].Cost()
+		}
+		return r.Lines[i].CostCenter < r.Lines[j].CostCenter
+	})
+
+	return r
+}
+
+func add(dst, src *models.UsageRecord) {
+	dst.Conversations += src.Conversations
+	dst.InputTokens += src.InputTokens
+	dst.OutputTokens += src.OutputTokens
+	dst.ToolCalls += src.ToolCalls
+	dst.RuntimeSeconds += src.RuntimeSeconds
+}
+
+func price(rec models.UsageRecord, rates Rates) Line {
+	return Line{
+		UsageRecord: rec,
+		ModelCost:   rates.ModelCost(rec.InputTokens, rec.OutputTokens),
+		ToolCost:    float64(rec.ToolCalls) * rates.ToolCall,
+		RuntimeCost: float64(rec.RuntimeSeconds) / 3600 * rates.TaskHour,
+	}
+}
+
+// Render formats the report as a Slack message
+func (r *Report) Render() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*💰 Chargeback for %s*\n", r.Month)
+
+	if len(r.Lines) == 0 {
+		b.WriteString("No usage was recorded this month.")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s total across %d cost centers • %s conversations • %s tokens • %s tool calls\n",
+		humanize.Currency(r.Total.Cost(), "USD"), len(r.Lines),
+		humanize.Count(r.Total.Conversations),
+		humanize.Count(r.Total.InputTokens+r.Total.OutputTokens),
+		humanize.Count(r.Total.ToolCalls))
+
+	for _, line := range r.Lines {
+		fmt.Fprintf(&b, "\n• *%s*: %s _(model %s, tools %s, runtime %s over %s conversations)_",
+			line.CostCenter,
+			humanize.Currency(line.Cost(), "USD"),
+			humanize.Currency(line.ModelCost, "USD"),
+			humanize.Currency(line.ToolCost, "USD"),
+			humanize.Currency(line.RuntimeCost, "USD"),
+			humanize.Count(line.Conversations))
+	}
+
+	return b.String()
+}
+
+// CSV renders the report with one row per cost center plus a total row
+func (r *Report) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"month", "cost_center", "conversations", "input_tokens", "output_tokens", "tool_calls", "runtime_seconds", "model_cost", "tool_cost", "runtime_cost", "total_cost"})
+
+	row := func(l Line) []string {
+		money := func(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
+		return []string{
+			r.Month,
+			l.CostCenter,
+			strconv.FormatInt(l.Conversations, 10),
+			strconv.FormatInt(l.InputTokens, 10),
+			strconv.FormatInt(l.OutputTokens, 10),
+			strconv.FormatInt(l.ToolCalls, 10),
+			strconv.FormatInt(l.RuntimeSeconds, 10),
+			money(l.ModelCost),
+			money(l.ToolCost),
+			money(l.RuntimeCost),
+			money(l.Cost()),
+		}
+	}
+	for _, line := range r.Lines {
+		_ = w.Write(row(line))
+	}
+	_ = w.Write(row(r.Total))
+
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,109 @@
+package chargeback
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestCostCenter(t *testing.T) {
+	channels := map[string]string{"C1": "payments"}
+
+	tests := []struct {
+		channel, profile, want string
+	}{
+		{"C1", "search", "payments"},
+		{"C2", "search", "search"},
+		{"C2", "  ", Unassigned},
+		{"", "", Unassigned},
+	}
+	for _, tt := range tests {
+		if got := CostCenter(tt.channel, channels, tt.profile); got != tt.want {
+			t.Errorf("CostCenter(%q, %q) = %q, want %q", tt.channel, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestPreviousMonth(t *testing.T) {
+	tests := map[time.Time]string{
+		time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC):   "2024-02",
+		time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC): "2023-12",
+	}
+	for now, want := range tests {
+		if got := PreviousMonth(now); got != want {
+			t.Errorf("PreviousMonth(%v) = %q, want %q", now, got, want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	rates := Rates{InputTokensPerMillion: 3, OutputTokensPerMillion: 15, ToolCall: 0.01, TaskHour: 1}
+	records := []*models.UsageRecord{
+		{CostCenter: "search", Conversations: 1, InputTokens: 1_000_000, ToolCalls: 10, RuntimeSeconds: 1800},
+		{CostCenter: "payments", Conversations: 2, OutputTokens: 1_000_000, RuntimeSeconds: 3600},
+		{CostCenter: "", Conversations: 1, ToolCalls: 1},
+		{CostCenter: Unassigned, Conversations: 1, ToolCalls: 1},
+	}
+
+	r := Build("2024-02", records, rates)
+	if len(r.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(r.Lines))
+	}
+
+	order := []string{"payments", "search", Unassigned}
+	for i, name := range order {
+		if r.Lines[i].CostCenter != name {
+			t.Errorf("line %d = %q, want %q", i, r.Lines[i].CostCenter, name)
+		}
+	}
+
+	if got := r.Lines[0].Cost(); math.Abs(got-16) > 1e-9 {
+		t.Errorf("payments cost = %v, want 16", got)
+	}
+	if got := r.Lines[1].Cost(); math.Abs(got-3.6) > 1e-9 {
+		t.Errorf("search cost = %v, want 3.6", got)
+	}
+	if r.Lines[2].Conversations != 2 {
+		t.Errorf("unassigned conversations = %d, want 2", r.Lines[2].Conversations)
+	}
+	if got := r.Total.Cost(); math.Abs(got-19.62) > 1e-9 {
+		t.Errorf("total cost = %v, want 19.62", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := Build("2024-02", []*models.UsageRecord{
+		{CostCenter: "payments", Conversations: 2, OutputTokens: 1_000_000},
+	}, DefaultRates)
+
+	msg := r.Render()
+	for _, want := range []string{"2024-02", "*payments*: $15.00", "2 conversations"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Render() missing %q:\n%s", want, msg)
+		}
+	}
+
+	if msg := Build("2024-02", nil, DefaultRates).Render(); !strings.Contains(msg, "No usage") {
+		t.Errorf("empty Render() = %q", msg)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	r := Build("2024-02", []*models.UsageRecord{
+		{CostCenter: "payments", Conversations: 2, OutputTokens: 1_000_000},
+	}, DefaultRates)
+
+	lines := strings.Split(strings.TrimSpace(string(r.CSV())), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d rows, want header, line, and total:\n%s", len(lines), r.CSV())
+	}
+	if want := "2024-02,payments,2,0,1000000,0,0,15.0000,0.0000,0.0000,15.0000"; lines[1] != want {
+		t.Errorf("row = %q, want %q", lines[1], want)
+	}
+	if !strings.HasPrefix(lines[2], "2024-02,total,") {
+		t.Errorf("total row = %q", lines[2])
+	}
+}
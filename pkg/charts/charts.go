@@ -0,0 +1,235 @@
+package charts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/savaki/cloudops-bot/pkg/usage"
+)
+
+const (
+	// DefaultHours is the time range rendered when a widget doesn't specify one
+	DefaultHours = 3
+
+	// MaxHours bounds the time range of a rendered chart
+	MaxHours = 24 * 14
+)
+
+// Metric identifies a single CloudWatch metric series
+type Metric struct {
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Stat       string            `json:"stat,omitempty"`
+	Label      string            `json:"label,omitempty"`
+}
+
+// Widget describes a time-series chart of one or more metrics
+type Widget struct {
+	Title   string   `json:"title,omitempty"`
+	Region  string   `json:"region,omitempty"`
+	Metrics []Metric `json:"metrics"`
+	Hours   int      `json:"hours,omitempty"`  // time range ending now; 0 is DefaultHours
+	Period  int      `json:"period,omitempty"` // seconds
+
+	// A fixed time range instead of Hours, for charts requested by tools
+	Start time.Time `json:"-"`
+	End   time.Time `json:"-"`
+}
+
+// Validate checks that the widget can be rendered
+func (w Widget) Validate() error {
+	if len(w.Metrics) == 0 {
+		return fmt.Errorf("chart has no metrics")
+	}
+	for _, m := range w.Metrics {
+		if m.Namespace == "" || m.Name == "" {
+			return fmt.Errorf("metric requires namespace and name")
+		}
+	}
+	if w.Hours < 0 || w.Hours > MaxHours {
+		return fmt.Errorf("chart range must be between 1 and %d hours, or 0 for the default of %d", MaxHours, DefaultHours)
+	}
+	return nil
+}
+
+// Definition returns the CloudWatch metric widget JSON for the widget
+func (w Widget) Definition(defaultRegion string) (string, error) {
+	if err := w.Validate(); err != nil {
+		return "", err
+	}
+
+	hours := w.Hours
+	if hours == 0 {
+		hours = DefaultHours
+	}
+
+	region := w.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	metrics := make([][]interface{}, 0, len(w.Metrics))
+	for _, m := range w.Metrics {
+		series := []interface{}{m.Namespace, m.Name}
+		for _, key := range sortedKeys(m.Dimensions) {
+			series = append(series, key, m.Dimensions[key])
+		}
+
+		opts := map[string]string{}
+		if m.Stat != "" {
+			opts["stat"] = m.Stat
+		}
+		if m.Label != "" {
+			opts["label"] = m.Label
+		}
+		if len(opts) > 0 {
+			series = append(series, opts)
+		}
+		metrics = append(metrics, series)
+	}
+
+	def := map[string]interface{}{
+		"metrics": metrics,
+		"view":    "timeSeries",
+		"stacked": false,
+		"region":  region,
+		"start":   fmt.Sprintf("-PT%dH", hours),
+		"end":     "P0D",
+		"width":   800,
+		"height":  400,
+	}
+	if !w.Start.IsZero() && w.End.After(w.Start) {
+		def["start"] = w.Start.UTC().Format(time.RFC3339)
+		def["end"] = w.End.UTC().Format(time.RFC3339)
+	}
+	if w.Title != "" {
+		def["title"] = w.Title
+	}
+	if w.Period > 0 {
+		def["period"] = w.Period
+	}
+
+	data, err := json.Marshal(def)
+	if err != nil {
+		return "", fmt.Errorf("marshal widget: %w", err)
+	}
+	return string(data), nil
+}
+
+// Renderer renders metric widgets to PNG images using CloudWatch
+type Renderer struct {
+	client *cloudwatch.Client
+	region string
+}
+
+// NewRenderer creates a chart renderer
+func NewRenderer(cfg aws.Config) *Renderer {
+	return &Renderer{
+		client: cloudwatch.NewFromConfig(cfg),
+		region: cfg.Region,
+	}
+}
+
+// Render returns a PNG image of the widget
+func (r *Renderer) Render(ctx context.Context, w Widget) ([]byte, error) {
+	def, err := w.Definition(r.region)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := r.client.GetMetricWidgetImage(ctx, &cloudwatch.GetMetricWidgetImageInput{
+		MetricWidget: aws.String(def),
+		OutputFormat: aws.String("png"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get metric widget image: %w", err)
+	}
+	usage.FromContext(ctx).AddToolCall()
+
+	return output.MetricWidgetImage, nil
+}
+
+// ParseCharts extracts <chart>{...}</chart> blocks from a model response.
+// It returns the response with the blocks removed and the parsed widgets;
+// blocks that fail to parse are dropped.
+func ParseCharts(response string) (string, []Widget) {
+	const openTag, closeTag = "<chart>", "</chart>"
+
+	var widgets []Widget
+	for {
+		start := strings.Index(response, openTag)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(response[start:], closeTag)
+		if end < 0 {
+			break
+		}
+		end += start
+
+		var w Widget
+		if err := json.Unmarshal([]byte(response[start+len(openTag):end]), &w); err == nil && w.Validate() == nil {
+			widgets = append(widgets, w)
+		}
+
+		response = response[:start] + response[end+len(closeTag):]
+	}
+
+	return strings.TrimSpace(response), widgets
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Collector gathers the charts tools ask for while the model answers, so
+// they are posted with the answer
+type Collector struct {
+	mu      sync.Mutex
+	widgets []Widget
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Widgets returns the charts requested so far
+func (c *Collector) Widgets() []Widget {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Widget(nil), c.widgets...)
+}
+
+type collectorKey struct{}
+
+// WithCollector returns a context whose tools can request charts from c
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, c)
+}
+
+// Request asks for w to be posted with the answer. It reports false when
+// charts can't be posted, such as when no renderer is configured
+func Request(ctx context.Context, w Widget) bool {
+	c, _ := ctx.Value(collectorKey{}).(*Collector)
+	if c == nil || w.Validate() != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.widgets = append(c.widgets, w)
+	return true
+}
@@ -0,0 +1,106 @@
+package charts
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestWidgetDefinition(t *testing.T) {
+	w := Widget{
+		Title: "API CPU",
+		Metrics: []Metric{
+			{
+				Namespace:  "AWS/ECS",
+				Name:       "CPUUtilization",
+				Dimensions: map[string]string{"ServiceName": "api", "ClusterName": "prod"},
+				Stat:       "Average",
+			},
+		},
+		Hours:  6,
+		Period: 60,
+	}
+
+	def, err := w.Definition("us-west-2")
+	if err != nil {
+		t.Fatalf("Definition() error = %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(def), &got); err != nil {
+		t.Fatalf("Definition() returned invalid JSON: %v", err)
+	}
+
+	if got["region"] != "us-west-2" || got["start"] != "-PT6H" || got["title"] != "API CPU" {
+		t.Errorf("Definition() = %s", def)
+	}
+
+	series := got["metrics"].([]interface{})[0].([]interface{})
+	want := []interface{}{"AWS/ECS", "CPUUtilization", "ClusterName", "prod", "ServiceName", "api"}
+	for i := range want {
+		if series[i] != want[i] {
+			t.Errorf("series[%d] = %v, want %v (dimensions should be sorted)", i, series[i], want[i])
+		}
+	}
+
+	if opts := series[len(series)-1].(map[string]interface{}); opts["stat"] != "Average" {
+		t.Errorf("series options = %v, want stat Average", opts)
+	}
+}
+
+func TestWidgetValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		widget  Widget
+		wantErr bool
+	}{
+		{"no metrics", Widget{}, true},
+		{"missing name", Widget{Metrics: []Metric{{Namespace: "AWS/EC2"}}}, true},
+		{"range too long", Widget{Metrics: []Metric{{Namespace: "AWS/EC2", Name: "CPUUtilization"}}, Hours: MaxHours + 1}, true},
+		{"negative range", Widget{Metrics: []Metric{{Namespace: "AWS/EC2", Name: "CPUUtilization"}}, Hours: -1}, true},
+		{"valid", Widget{Metrics: []Metric{{Namespace: "AWS/EC2", Name: "CPUUtilization"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.widget.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCharts(t *testing.T) {
+	response := `CPU has been climbing since noon.
+<chart>{"title":"CPU","metrics":[{"namespace":"AWS/EC2","name":"CPUUtilization","dimensions":{"InstanceId":"i-12345678"}}]}</chart>
+<chart>not json</chart>`
+
+	cleaned, widgets := ParseCharts(response)
+
+	if cleaned != "CPU has been climbing since noon." {
+		t.Errorf("cleaned = %q", cleaned)
+	}
+
+	if len(widgets) != 1 || widgets[0].Title != "CPU" {
+		t.Errorf("widgets = %+v, want one CPU chart", widgets)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	w := Widget{Title: "CPU", Metrics: []Metric{{Namespace: "AWS/EC2", Name: "CPUUtilization"}}}
+	if Request(context.Background(), w) {
+		t.Error("Request() without a collector should report charts can't be posted")
+	}
+
+	c := NewCollector()
+	ctx := WithCollector(context.Background(), c)
+	if Request(ctx, Widget{Title: "empty"}) {
+		t.Error("Request() should refuse an invalid widget")
+	}
+	if !Request(ctx, w) {
+		t.Fatal("Request() = false, want true")
+	}
+	if widgets := c.Widgets(); len(widgets) != 1 || widgets[0].Title != "CPU" {
+		t.Errorf("Widgets() = %+v", widgets)
+	}
+}
@@ -0,0 +1,87 @@
+package coalesce
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a user message waiting to be answered
+type Message struct {
+	UserID string
+	Text   string
+	At     time.Time
+}
+
+// FromSlack builds a message, taking its time from the Slack timestamp
+func FromSlack(userID, text, ts string) Message {
+	at := time.Now()
+	if f, err := strconv.ParseFloat(ts, 64); err == nil {
+		at = time.Unix(0, int64(f*float64(time.Second)))
+	}
+	return Message{UserID: userID, Text: text, At: at}
+}
+
+// Buffer collects messages sent in quick succession so they can be answered
+// in a single turn. It is not safe for concurrent use
+type Buffer struct {
+	window  time.Duration
+	pending []Message
+}
+
+// NewBuffer creates a buffer that waits for window of quiet before a batch
+// is ready
+func NewBuffer(window time.Duration) *Buffer {
+	return &Buffer{window: window}
+}
+
+// Add appends a message, keeping arrival order
+func (b *Buffer) Add(m Message) {
+	b.pending = append(b.pending, m)
+}
+
+// Len returns the number of buffered messages
+func (b *Buffer) Len() int {
+	return len(b.pending)
+}
+
+// Ready reports whether messages are waiting and none arrived within the
+// debounce window
+func (b *Buffer) Ready(now time.Time) bool {
+	if len(b.pending) == 0 {
+		return false
+	}
+	return now.Sub(b.pending[len(b.pending)-1].At) >= b.window
+}
+
+// Flush returns the buffered messages and empties the buffer
+func (b *Buffer) Flush() []Message {
+	msgs := b.pending
+	b.pending = nil
+	return msgs
+}
+
+// Combine joins messages into the text of one turn. Messages from several
+// people are attributed so the model can tell them apart
+func Combine(msgs []Message) string {
+	var texts []string
+	multiple := false
+	for i, m := range msgs {
+		if i > 0 && m.UserID != msgs[0].UserID {
+			multiple = true
+		}
+	}
+
+	for _, m := range msgs {
+		text := strings.TrimSpace(m.Text)
+		if text == "" {
+			continue
+		}
+		if multiple {
+			text = fmt.Sprintf("<@%s>: %s", m.UserID, text)
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,71 @@
+package coalesce
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferReady(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBuffer(2 * time.Second)
+
+	if b.Ready(start) {
+		t.Error("empty buffer should not be ready")
+	}
+
+	b.Add(Message{UserID: "U1", Text: "the api is down", At: start})
+	b.Add(Message{UserID: "U1", Text: "actually only in us-west-2", At: start.Add(time.Second)})
+
+	if b.Ready(start.Add(2 * time.Second)) {
+		t.Error("Ready() = true within the window of the last message")
+	}
+	if !b.Ready(start.Add(3 * time.Second)) {
+		t.Error("Ready() = false after the window passed")
+	}
+
+	msgs := b.Flush()
+	if len(msgs) != 2 || msgs[0].Text != "the api is down" {
+		t.Errorf("Flush() = %v, want both messages in order", msgs)
+	}
+	if b.Len() != 0 {
+		t.Errorf("Len() after Flush = %d, want 0", b.Len())
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs []Message
+		want string
+	}{
+		{
+			name: "single user",
+			msgs: []Message{{UserID: "U1", Text: "check the ALB"}, {UserID: "U1", Text: " and the target group "}},
+			want: "check the ALB\nand the target group",
+		},
+		{
+			name: "several users",
+			msgs: []Message{{UserID: "U1", Text: "is it the db?"}, {UserID: "U2", Text: "no, cache"}},
+			want: "<@U1>: is it the db?\n<@U2>: no, cache",
+		},
+		{
+			name: "skips empty",
+			msgs: []Message{{UserID: "U1", Text: "  "}, {UserID: "U1", Text: "hi"}},
+			want: "hi",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Combine(tt.msgs); got != tt.want {
+			t.Errorf("%s: Combine() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFromSlack(t *testing.T) {
+	m := FromSlack("U1", "hi", "1714575600.123456")
+	want := time.Date(2024, 5, 1, 15, 0, 0, 123456000, time.UTC)
+	if d := m.At.Sub(want); d > time.Millisecond || d < -time.Millisecond {
+		t.Errorf("FromSlack() At = %v, want %v", m.At.UTC(), want)
+	}
+}
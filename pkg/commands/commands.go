@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+	"github.com/slack-go/slack"
+)
+
+// Response types for slash command responses
+const (
+	ResponseEphemeral = "ephemeral"
+	ResponseInChannel = "in_channel"
+)
+
+// Command is a parsed /cloudops slash command invocation
+type Command struct {
+	Name        string   // subcommand, e.g. "postmortem"
+	Args        []string // whitespace-separated arguments after the subcommand
+	Text        string   // raw text after the subcommand
+	UserID      string
+	UserName    string
+	ChannelID   string
+	TeamID      string
+	ResponseURL string
+	TriggerID   string
+
+	// Location is the invoking user's timezone, used to interpret times
+	Location *time.Location `json:"-"`
+
+	// Deferred is set when the command is being finished after Slack's
+	// deadline, and its response goes to ResponseURL
+	Deferred bool `json:"-"`
+}
+
+// TimeRange looks for a time range at the end of the arguments, e.g.
+// "stats last 7 days", returning it with the arguments that precede it
+func (c *Command) TimeRange() (timerange.Range, []string, bool) {
+	parser := timerange.New(c.Location)
+	for i := 0; i < len(c.Args); i++ {
+		if strings.HasPrefix(c.Args[i], "--") {
+			continue
+		}
+		if r, err := parser.Parse(strings.Join(c.Args[i:], " ")); err == nil {
+			return r, c.Args[:i], true
+		}
+	}
+	return timerange.Range{}, c.Args, false
+}
+
+// Response is the immediate reply to a slash command
+type Response struct {
+	ResponseType string        `json:"response_type"`
+	Text         string        `json:"text"`
+	Blocks       []slack.Block `json:"blocks,omitempty"` // replaces Text in the message body when set
+}
+
+// Ephemeral returns a response only the invoking user can see
+func Ephemeral(format string, args ...interface{}) *Response {
+	return &Response{ResponseType: ResponseEphemeral, Text: fmt.Sprintf(format, args...)}
+}
+
+// InChannel returns a response visible to everyone in the channel
+func InChannel(format string, args ...interface{}) *Response {
+	return &Response{ResponseType: ResponseInChannel, Text: fmt.Sprintf(format, args...)}
+}
+
+// ParseSlashCommand parses the form-encoded body Slack sends for slash commands
+func ParseSlashCommand(body []byte) (*Command, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse slash command: %w", err)
+	}
+	if values.Get("command") == "" {
+		return nil, fmt.Errorf("parse slash command: missing command")
+	}
+
+	name, args, text := Parse(values.Get("text"))
+	return &Command{
+		Name:        name,
+		Args:        args,
+		Text:        text,
+		UserID:      values.Get("user_id"),
+		UserName:    values.Get("user_name"),
+		ChannelID:   values.Get("channel_id"),
+		TeamID:      values.Get("team_id"),
+		ResponseURL: values.Get("response_url"),
+		TriggerID:   values.Get("trigger_id"),
+	}, nil
+}
+
+// Parse splits command text into a lower-cased subcommand, its arguments,
+// and the raw remainder after the subcommand
+func Parse(text string) (string, []string, string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil, ""
+	}
+
+	name, rest, _ := strings.Cut(text, " ")
+	rest = strings.TrimSpace(rest)
+	return strings.ToLower(name), strings.Fields(rest), rest
+}
+
+// ParseUser extracts a user ID from an escaped mention (<@U123|name>) or a
+// bare user ID
+func ParseUser(arg string) (string, bool) {
+	if strings.HasPrefix(arg, "<@") && strings.HasSuffix(arg, ">") {
+		id, _, _ := strings.Cut(arg[2:len(arg)-1], "|")
+		arg = id
+	}
+	if len(arg) < 2 || (arg[0] != 'U' && arg[0] != 'W') {
+		return "", false
+	}
+	for _, r := range arg {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", false
+		}
+	}
+	return arg, true
+}
+
+// ParseChannel extracts a channel ID from an escaped channel reference
+// (<#C123|name>)
+func ParseChannel(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "<#") || !strings.HasSuffix(arg, ">") {
+		return "", false
+	}
+	id, _, _ := strings.Cut(arg[2:len(arg)-1], "|")
+	if len(id) < 2 || (id[0] != 'C' && id[0] != 'G') {
+		return "", false
+	}
+	for _, r := range id {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", false
+		}
+	}
+	return id, true
+}
+
+// HandlerFunc handles a single subcommand
+type HandlerFunc func(ctx context.Context, cmd *Command) (*Response, error)
+
+type route struct {
+	usage   string
+	handler HandlerFunc
+}
+
+// Router dispatches slash commands to subcommand handlers
+type Router struct {
+	routes map[string]route
+}
+
+// NewRouter creates an empty command router
+func NewRouter() *Router {
+	return &Router{routes: make(map[string]route)}
+}
+
+// Register adds a subcommand; usage is shown in help output
+func (r *Router) Register(name, usage string, handler HandlerFunc) {
+	r.routes[strings.ToLower(name)] = route{usage: usage, handler: handler}
+}
+
+// Handle dispatches a command, replying with help for unknown subcommands
+func (r *Router) Handle(ctx context.Context, cmd *Command) (*Response, error) {
+	rt, ok := r.routes[cmd.Name]
+	if !ok || cmd.Name == "help" {
+		return r.help(cmd.Name), nil
+	}
+	return rt.handler(ctx, cmd)
+}
+
+// help lists the registered subcommands
+func (r *Router) help(unknown string) *Response {
+	names := make([]string, 0, len(r.routes))
+	for name := range r.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	if unknown != "" && unknown != "help" {
+		fmt.Fprintf(&b, "Unknown command `%s`.\n", unknown)
+	}
+	b.WriteString("Available commands:")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n• `/cloudops %s` - %s", name, r.routes[name].usage)
+	}
+	return Ephemeral("%s", b.String())
+}
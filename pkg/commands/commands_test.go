@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	body := "command=%2Fcloudops&text=Postmortem+conv-123+--tickets&user_id=U123&channel_id=C456&response_url=https%3A%2F%2Fhooks.slack.com%2Fx"
+
+	cmd, err := ParseSlashCommand([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseSlashCommand() error = %v", err)
+	}
+
+	if cmd.Name != "postmortem" {
+		t.Errorf("Name = %s, want postmortem", cmd.Name)
+	}
+	if len(cmd.Args) != 2 || cmd.Args[0] != "conv-123" || cmd.Args[1] != "--tickets" {
+		t.Errorf("Args = %v, want [conv-123 --tickets]", cmd.Args)
+	}
+	if cmd.Text != "conv-123 --tickets" {
+		t.Errorf("Text = %q, want %q", cmd.Text, "conv-123 --tickets")
+	}
+	if cmd.UserID != "U123" || cmd.ChannelID != "C456" {
+		t.Errorf("UserID/ChannelID = %s/%s, want U123/C456", cmd.UserID, cmd.ChannelID)
+	}
+	if cmd.ResponseURL != "https://hooks.slack.com/x" {
+		t.Errorf("ResponseURL = %s", cmd.ResponseURL)
+	}
+}
+
+func TestParseSlashCommandMissingCommand(t *testing.T) {
+	if _, err := ParseSlashCommand([]byte("text=hello")); err == nil {
+		t.Error("ParseSlashCommand() should error without a command field")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs int
+	}{
+		{"", "", 0},
+		{"   stats   ", "stats", 0},
+		{"stats week", "stats", 1},
+		{"announce  Database   maintenance at 5pm", "announce", 4},
+	}
+
+	for _, tt := range tests {
+		name, args, _ := Parse(tt.text)
+		if name != tt.wantName || len(args) != tt.wantArgs {
+			t.Errorf("Parse(%q) = %q, %v; want %q with %d args", tt.text, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}
+
+func TestParseUser(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   string
+		wantOK bool
+	}{
+		{"<@U123ABC|alice>", "U123ABC", true},
+		{"<@W42>", "W42", true},
+		{"U999", "U999", true},
+		{"@alice", "", false},
+		{"<#C123|ops>", "", false},
+		{"operator", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseUser(tt.arg)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseUser(%q) = %q, %v; want %q, %v", tt.arg, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   string
+		wantOK bool
+	}{
+		{"<#C123ABC|incident-db>", "C123ABC", true},
+		{"<#G42>", "G42", true},
+		{"#incident-db", "", false},
+		{"C123ABC", "", false},
+		{"<@U123ABC|alice>", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseChannel(tt.arg)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseChannel(%q) = %q, %v; want %q, %v", tt.arg, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	router := NewRouter()
+	router.Register("ping", "check the bot is alive", func(ctx context.Context, cmd *Command) (*Response, error) {
+		return InChannel("pong %s", cmd.Text), nil
+	})
+
+	resp, err := router.Handle(context.Background(), &Command{Name: "ping", Text: "now"})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if resp.Text != "pong now" || resp.ResponseType != ResponseInChannel {
+		t.Errorf("Handle() = %+v, want in-channel pong", resp)
+	}
+
+	resp, err = router.Handle(context.Background(), &Command{Name: "bogus"})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if resp.ResponseType != ResponseEphemeral || !strings.Contains(resp.Text, "Unknown command `bogus`") {
+		t.Errorf("Handle() unknown = %+v, want ephemeral help", resp)
+	}
+	if !strings.Contains(resp.Text, "`/cloudops ping` - check the bot is alive") {
+		t.Errorf("help = %q, missing ping usage", resp.Text)
+	}
+}
+
+func TestCommandTimeRange(t *testing.T) {
+	cmd := &Command{Args: []string{"checkout-api", "last", "2", "hours"}}
+
+	r, rest, ok := cmd.TimeRange()
+	if !ok {
+		t.Fatal("TimeRange() should find a range")
+	}
+	if r.Duration() != 2*time.Hour {
+		t.Errorf("Duration() = %v, want 2h", r.Duration())
+	}
+	if len(rest) != 1 || rest[0] != "checkout-api" {
+		t.Errorf("rest = %v, want [checkout-api]", rest)
+	}
+
+	if _, _, ok := (&Command{Args: []string{"checkout-api"}}).TimeRange(); ok {
+		t.Error("TimeRange() should not find a range in plain arguments")
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/slack-go/slack"
+)
+
+// deferredEvent is the payload of the invocation that finishes a slash
+// command. API Gateway, Function URL and ALB events never carry its key
+type deferredEvent struct {
+	Command *Command `json:"deferred_command"`
+}
+
+// Deferrer finishes slash commands that take longer than Slack's three
+// second deadline in an asynchronous invocation of the Lambda function
+// handling them, which replies through the command's response URL
+type Deferrer struct {
+	client       *lambda.Client
+	functionName string
+}
+
+// NewDeferrer creates a deferrer that invokes functionName
+func NewDeferrer(cfg aws.Config, functionName string) *Deferrer {
+	return &Deferrer{
+		client:       lambda.NewFromConfig(cfg),
+		functionName: functionName,
+	}
+}
+
+// Defer starts the invocation that finishes cmd
+func (d *Deferrer) Defer(ctx context.Context, cmd *Command) error {
+	payload, err := json.Marshal(deferredEvent{Command: cmd})
+	if err != nil {
+		return fmt.Errorf("marshal deferred command: %w", err)
+	}
+
+	_, err = d.client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   &d.functionName,
+		InvocationType: types.InvocationTypeEvent,
+		Payload:        payload,
+	})
+	if err != nil {
+		return fmt.Errorf("invoke %s: %w", d.functionName, err)
+	}
+	return nil
+}
+
+// ParseDeferred returns the command a Lambda payload asks to finish, when
+// it is one Defer sent. The command is marked Deferred
+func ParseDeferred(payload []byte) (*Command, bool) {
+	var event deferredEvent
+	if err := json.Unmarshal(payload, &event); err != nil || event.Command == nil {
+		return nil, false
+	}
+	event.Command.Deferred = true
+	return event.Command, true
+}
+
+// Reply posts the response to a command after the fact, through its
+// response URL
+func Reply(ctx context.Context, cmd *Command, resp *Response) error {
+	if cmd.ResponseURL == "" {
+		return fmt.Errorf("reply to %s: no response URL", cmd.Name)
+	}
+
+	msg := &slack.WebhookMessage{ResponseType: resp.ResponseType, Text: resp.Text}
+	if len(resp.Blocks) > 0 {
+		msg.Blocks = &slack.Blocks{BlockSet: resp.Blocks}
+	}
+	if err := slack.PostWebhookContext(ctx, cmd.ResponseURL, msg); err != nil {
+		return fmt.Errorf("reply to %s: %w", cmd.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseDeferred(t *testing.T) {
+	cmd := &Command{Name: "postmortem", Args: []string{"conv-123"}, UserID: "U123", ChannelID: "C456", ResponseURL: "https://hooks.slack.com/x"}
+	payload, err := json.Marshal(deferredEvent{Command: cmd})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := ParseDeferred(payload)
+	if !ok {
+		t.Fatal("ParseDeferred() should accept a deferred command")
+	}
+	if !got.Deferred || got.Name != "postmortem" || len(got.Args) != 1 || got.UserID != "U123" || got.ResponseURL != cmd.ResponseURL {
+		t.Errorf("ParseDeferred() = %+v", got)
+	}
+
+	for _, payload := range []string{
+		`{"version":"2.0","rawPath":"/slack/events","body":"{\"deferred_command\":{}}"}`,
+		`{"httpMethod":"POST","path":"/slack/events","body":"command=%2Fcloudops"}`,
+		`not json`,
+	} {
+		if _, ok := ParseDeferred([]byte(payload)); ok {
+			t.Errorf("ParseDeferred(%s) should ignore requests from Slack", payload)
+		}
+	}
+}
+
+func TestReply(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	cmd := &Command{Name: "postmortem", ResponseURL: server.URL}
+	if err := Reply(context.Background(), cmd, Ephemeral("done in %s", "conv-1")); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if got["response_type"] != ResponseEphemeral || got["text"] != "done in conv-1" {
+		t.Errorf("Reply() posted %v", got)
+	}
+	if _, ok := got["blocks"]; ok {
+		t.Errorf("Reply() posted empty blocks: %v", got)
+	}
+
+	if err := Reply(context.Background(), &Command{Name: "postmortem"}, Ephemeral("done")); err == nil {
+		t.Error("Reply() should fail without a response URL")
+	}
+}
@@ -1,46 +1,432 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/alerts"
+	"github.com/savaki/cloudops-bot/pkg/approval"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/chargeback"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/iampolicy"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/rbac"
+	"github.com/savaki/cloudops-bot/pkg/sandbox"
+	"github.com/savaki/cloudops-bot/pkg/sla"
+	"github.com/savaki/cloudops-bot/pkg/tasksize"
 )
 
 // Config holds application configuration loaded from environment variables
 type Config struct {
+	// Environment name (dev, staging, prod)
+	Environment string
+
 	// AWS
 	AWSRegion string
 
+	// Lowest level logged: debug, info, warn, or error. logging.Setup reads
+	// it before the rest of the config is loaded
+	LogLevel string
+
 	// Slack
 	SlackBotToken   string
 	SlackSigningKey string
+	SlackAppToken   string // xapp- token, only needed in standalone Socket Mode
+
+	// Second signing secret accepted while Slack's is being rotated
+	SlackSigningKeySecondary string
+
+	// Bot token rotation: the app's OAuth credentials and the refresh token
+	// to start from. Refreshed tokens are stored in SlackTokensTable
+	SlackClientID     string
+	SlackClientSecret string
+	SlackRefreshToken string
 
 	// DynamoDB
 	ConversationsTable       string
 	ConversationHistoryTable string
+	TagsTable                string
+	SubscriptionsTable       string
+	SLATable                 string
+	AuditTable               string
+	AnnouncementsTable       string
+	AlertsTable              string
+	WarmPoolTable            string
+	PromptsTable             string
+	RunbooksTable            string
+	PermissionsTable         string
+	ApprovalsTable           string
+	SlackTokensTable         string
+	UsageTable               string
+	SettingsTable            string
+	LocksTable               string
+	ToolAuditTable           string // every tool execution, for compliance reviews (not recorded when empty)
+	JobsTable                string // background jobs (disabled when empty)
+	InboxTable               string // messages delivered by the Events API; agents poll Slack for every message when empty
 	InactivityTimeoutMinutes int
 	ConversationTTLDays      int
+	HeartbeatSeconds         int    // how often a running agent records it's alive; 0 never does
+	StaleHeartbeatMinutes    int    // how long without a heartbeat before the reaper times a conversation out
+	ControlSocket            string // Unix socket a running agent serves its control API on (not served when empty)
+	ContextPackSeconds       int    // how long a context pack passed at launch is trusted (0 disables context packs)
+	InputKMSKeyID            string // KMS key sealing the sensitive fields of agent launch inputs (sent in plaintext when empty)
+
+	// What runs background jobs: "agent" runs them in the agent that
+	// started them, "lambda" leaves them to the job worker Lambda
+	JobRunner string
+
+	// Where a new conversation runs: "inplace" where the bot was
+	// mentioned, "thread" in the thread of the mention, "channel" in a
+	// private channel opened for it
+	ConversationMode string
+
+	// Where conversations and their history are kept: "dynamodb", or
+	// "memory" to run without AWS during local development
+	StorageBackend string
+
+	// Quiet period before rapid messages are answered together in one turn
+	MessageDebounceMs int
+
+	// How often an answer being streamed from the model is updated in
+	// Slack. 0 turns streaming off, so answers appear once complete
+	StreamIntervalMs int
+
+	// How long a turn runs before, with tools still running, what is known
+	// so far is posted and the full answer follows. 0 waits for the answer
+	TurnBudgetSeconds int
+
+	// Most tokens of history sent to the model; older messages are
+	// summarized to fit. 0 sends the whole history
+	ContextTokens int
+
+	// Most turns a conversation answers per minute; later turns wait for
+	// the window to pass. 0 means no limit
+	TurnsPerMinute int
 
 	// Bedrock
 	BedrockModelID string
 
+	// Second model that cross-checks answers in conversations carrying one
+	// of the ensemble tags (disabled when empty)
+	EnsembleModelID string
+	EnsembleTags    []string
+
+	// Duplicate incident detection: new conversations are compared with
+	// recent ones by embedding the initial report (disabled when the model
+	// is empty)
+	EmbeddingModelID      string
+	DuplicateThreshold    float64
+	DuplicateLookbackDays int
+
+	// Minutes apart two active conversations can start and still be linked
+	// when they share resources or tags; 0 never links them
+	CorrelationWindowMinutes int
+
+	// System prompt version new conversations use; 0 means the latest
+	// published version. Pin an earlier version to roll back
+	PromptVersion int
+
+	// AWS console links
+	ConsoleSwitchRoleAccount string
+	ConsoleSwitchRoleName    string
+	ConsoleFederationURL     string
+
 	// Step Functions
 	StepFunctionArn string
+
+	// S3 bucket for generated incident reports (optional)
+	ReportsBucket string
+
+	// S3 bucket holding the full content of code blocks shortened in
+	// answers, served by a "Show full output" button (optional)
+	OutputsBucket string
+
+	// Webhook that receives postmortem action items as tickets (optional)
+	TicketWebhookURL string
+
+	// Reaction emoji that tag a conversation, e.g. "rotating_light" -> "sev1"
+	ReactionTags map[string]string
+
+	// On-call lookup: pagerduty, opsgenie, or dynamodb (disabled when empty)
+	OnCallProvider  string
+	OnCallAPIToken  string
+	OnCallSchedules map[string]string // team -> schedule ID
+	OnCallTable     string
+
+	// Slack user group pinged by /cloudops escalate, e.g. S0123ABCD (optional)
+	ExpertsGroup string
+
+	// Shift handoff reports
+	HandoffChannel    string
+	HandoffShiftHours int
+	HandoffTimezone   string
+
+	// Chargeback: usage is charged to the team mapped to the conversation's
+	// channel, else the team in the requester's profile field. The monthly
+	// report goes to ChargebackChannel, priced in USD at the given rates
+	ChargebackChannels     map[string]string // channel ID -> cost center
+	ChargebackProfileField string
+	ChargebackChannel      string
+	BedrockInputPrice      float64 // per million tokens
+	BedrockOutputPrice     float64 // per million tokens
+	ToolCallPrice          float64
+	TaskHourPrice          float64
+
+	// Budget alarms for the bot's own spend, read from Cost Explorer for
+	// resources carrying CostTag (key=value). Limits are in USD; zero
+	// disables one
+	CostAlertChannel   string
+	CostTag            string
+	CostDailyLimit     float64
+	CostMonthlyLimit   float64
+	CostIncludeBedrock bool
+
+	// Announcement broadcasts: target channels and the users allowed to post
+	AnnounceChannels []string
+	AnnounceUsers    []string
+
+	// Admins who can always manage permission profiles, so the first grant
+	// doesn't require editing DynamoDB
+	AdminUsers []string
+
+	// Tool access policy: "dynamodb" for the settings table, or "ssm:" and
+	// a parameter name (tools are unrestricted when empty)
+	RBACPolicy string
+
+	// Outbound webhooks told when conversations start, complete, or fail,
+	// and the secret their deliveries are signed with (none when empty)
+	WebhookURLs   []string
+	WebhookSecret string
+
+	// IAM policy suggestions: where drafts are posted for admins to review,
+	// and how many denials of an action it takes (disabled when empty)
+	IAMSuggestionChannel   string
+	IAMSuggestionThreshold int
+
+	// Break-glass elevation: where it is announced and how long it lasts
+	BreakGlassChannel string
+	BreakGlassMinutes int
+
+	// Critical alerts: where they are posted, whose on-call is paged, and
+	// how long responders have to acknowledge before escalation
+	AlertChannel         string
+	AlertTeam            string
+	AlertAckMinutes      int
+	AlertPagerRoutingKey string
+
+	// Rules sending alarms to team channels, as inline JSON or
+	// ssm:<parameter name>; unmatched alarms go to AlertChannel
+	AlertRoutes string
+
+	// Minutes in which repeats of a firing alarm update its first message
+	// instead of posting again; 0 posts every occurrence
+	AlertDigestMinutes int
+
+	// Capacity agent tasks run on: ondemand, or spot for cheaper tasks that
+	// are relaunched on demand if reclaimed. Channels listed in
+	// OnDemandChannels always use on-demand capacity
+	AgentCapacity    string
+	OnDemandChannels []string
+
+	// Task size per conversation type, e.g. "question=512/1024,incident=2048/4096",
+	// optionally naming another task definition for a different image.
+	// Sizes are only sent to the state machine when TaskDefinitionArn is set
+	TaskSizes         string
+	TaskDefinitionArn string
+
+	// Sandbox mode: tools read the demo account behind SandboxRoleARN
+	// instead of the bot's own, and answers carry a sandbox banner
+	SandboxRoleARN    string
+	SandboxExternalID string
+
+	// Shadow mode: a candidate agent version on ShadowTaskDefinition runs
+	// alongside ShadowPercent of conversations, logging what it would have
+	// said and done without posting or writing anything. ShadowMode is set
+	// on the shadow task itself
+	ShadowTaskDefinition string
+	ShadowPercent        int
+	ShadowMode           bool
+
+	// How long a warm-pool agent waits for a conversation before it exits
+	// and is replaced by a fresh task
+	WarmPoolMaxIdleMinutes int
+
+	// Lease on a conversation's lock. An agent that dies without releasing
+	// it blocks the next one for at most this long
+	LockLeaseSeconds int
+
+	// Standalone (Socket Mode) worker pool: concurrent conversation turns,
+	// pending messages per conversation, and pending messages overall
+	WorkerPoolSize    int
+	WorkerMailboxSize int
+	WorkerQueueSize   int
+
+	// Incident SLA targets per severity tag, e.g. "sev1=5m/1h,sev2=15m/4h"
+	SLAPolicy string
+
+	// Approval policies per action class, e.g. "prod-terminate=admin/2/30m"
+	ApprovalPolicy string
+
+	// Webhook access control: source ranges allowed to call the Slack
+	// handler, and whether callers must present a client certificate with
+	// one of the given common names (mutual TLS at API Gateway or the ALB)
+	AllowedSourceCIDRs []string
+	RequireClientCert  bool
+	ClientCertNames    []string
+
+	// Fault injection (non-prod only)
+	ChaosEnabled   bool
+	ChaosLatencyMs int
+	ChaosErrorRate float64
+	ChaosTargets   []string
 }
 
-// Load reads configuration from environment variables
+// Load reads configuration from environment variables. Values written as
+// ssm://<parameter name> are read from Parameter Store
 func Load() (*Config, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), parameterTimeout)
+	defer cancel()
+	if err := ResolveParameters(ctx, nil); err != nil {
+		return nil, fmt.Errorf("resolve ssm parameters: %w", err)
+	}
+
 	cfg := &Config{
+		Environment:              getEnv("ENVIRONMENT", "dev"),
 		AWSRegion:                getEnv("AWS_REGION", "us-east-1"),
+		LogLevel:                 getEnv("LOG_LEVEL", "info"),
 		SlackBotToken:            getEnv("SLACK_BOT_TOKEN", ""),
 		SlackSigningKey:          getEnv("SLACK_SIGNING_KEY", ""),
+		SlackAppToken:            getEnv("SLACK_APP_TOKEN", ""),
+		SlackSigningKeySecondary: getEnv("SLACK_SIGNING_KEY_SECONDARY", ""),
+		SlackClientID:            getEnv("SLACK_CLIENT_ID", ""),
+		SlackClientSecret:        getEnv("SLACK_CLIENT_SECRET", ""),
+		SlackRefreshToken:        getEnv("SLACK_REFRESH_TOKEN", ""),
 		ConversationsTable:       getEnv("CONVERSATIONS_TABLE", "cloudops-conversations"),
 		ConversationHistoryTable: getEnv("CONVERSATION_HISTORY_TABLE", "cloudops-conversation-history"),
+		TagsTable:                getEnv("TAGS_TABLE", "cloudops-conversation-tags"),
+		SubscriptionsTable:       getEnv("SUBSCRIPTIONS_TABLE", "cloudops-subscriptions"),
+		SLATable:                 getEnv("SLA_TABLE", "cloudops-sla-outcomes"),
+		AuditTable:               getEnv("AUDIT_TABLE", "cloudops-audit"),
+		AnnouncementsTable:       getEnv("ANNOUNCEMENTS_TABLE", "cloudops-announcements"),
+		AlertsTable:              getEnv("ALERTS_TABLE", "cloudops-alerts"),
+		WarmPoolTable:            getEnv("WARM_POOL_TABLE", "cloudops-warm-pool"),
+		PromptsTable:             getEnv("PROMPTS_TABLE", "cloudops-prompts"),
+		RunbooksTable:            getEnv("RUNBOOKS_TABLE", "cloudops-runbooks"),
+		PermissionsTable:         getEnv("PERMISSIONS_TABLE", "cloudops-permissions"),
+		ApprovalsTable:           getEnv("APPROVALS_TABLE", "cloudops-approvals"),
+		SlackTokensTable:         getEnv("SLACK_TOKENS_TABLE", "cloudops-slack-tokens"),
+		UsageTable:               getEnv("USAGE_TABLE", "cloudops-usage"),
+		SettingsTable:            getEnv("SETTINGS_TABLE", "cloudops-settings"),
+		LocksTable:               getEnv("LOCKS_TABLE", ""),
+		ToolAuditTable:           getEnv("TOOL_AUDIT_TABLE", ""),
+		JobsTable:                getEnv("JOBS_TABLE", ""),
+		InboxTable:               getEnv("INBOX_TABLE", ""),
+		JobRunner:                getEnv("JOB_RUNNER", "agent"),
+		ConversationMode:         getEnv("CONVERSATION_MODE", "inplace"),
+		StorageBackend:           getEnv("STORAGE_BACKEND", "dynamodb"),
 		InactivityTimeoutMinutes: getEnvInt("INACTIVITY_TIMEOUT_MINUTES", 30),
+		HeartbeatSeconds:         getEnvInt("HEARTBEAT_SECONDS", 30),
+		StaleHeartbeatMinutes:    getEnvInt("STALE_HEARTBEAT_MINUTES", 5),
+		ControlSocket:            getEnv("CONTROL_SOCKET", ""),
+		ContextPackSeconds:       getEnvInt("CONTEXT_PACK_SECONDS", 0),
+		InputKMSKeyID:            getEnv("INPUT_KMS_KEY_ID", ""),
 		ConversationTTLDays:      getEnvInt("CONVERSATION_TTL_DAYS", 7),
+		MessageDebounceMs:        getEnvInt("MESSAGE_DEBOUNCE_MS", 1500),
+		StreamIntervalMs:         getEnvInt("STREAM_INTERVAL_MS", 1000),
+		TurnBudgetSeconds:        getEnvInt("TURN_BUDGET_SECONDS", 45),
+		ContextTokens:            getEnvInt("CONTEXT_TOKENS", 100000),
+		TurnsPerMinute:           getEnvInt("TURNS_PER_MINUTE", 0),
 		BedrockModelID:           getEnv("BEDROCK_MODEL_ID", "anthropic.claude-3-5-sonnet-20241022-v2:0"),
+		PromptVersion:            getEnvInt("PROMPT_VERSION", 0),
+		EnsembleModelID:          getEnv("ENSEMBLE_MODEL_ID", ""),
+		EnsembleTags:             getEnvList("ENSEMBLE_TAGS"),
+		EmbeddingModelID:         getEnv("EMBEDDING_MODEL_ID", ""),
+		DuplicateThreshold:       getEnvFloat("DUPLICATE_THRESHOLD", 0.85),
+		DuplicateLookbackDays:    getEnvInt("DUPLICATE_LOOKBACK_DAYS", 7),
+		CorrelationWindowMinutes: getEnvInt("CORRELATION_WINDOW_MINUTES", 15),
+		ConsoleSwitchRoleAccount: getEnv("CONSOLE_SWITCH_ROLE_ACCOUNT", ""),
+		ConsoleSwitchRoleName:    getEnv("CONSOLE_SWITCH_ROLE_NAME", ""),
+		ConsoleFederationURL:     getEnv("CONSOLE_FEDERATION_URL", ""),
 		StepFunctionArn:          getEnv("STEP_FUNCTION_ARN", ""),
+		ReportsBucket:            getEnv("REPORTS_BUCKET", ""),
+		OutputsBucket:            getEnv("OUTPUTS_BUCKET", ""),
+		TicketWebhookURL:         getEnv("TICKET_WEBHOOK_URL", ""),
+		ReactionTags:             getEnvMap("REACTION_TAGS"),
+		OnCallProvider:           getEnv("ONCALL_PROVIDER", ""),
+		OnCallAPIToken:           getEnv("ONCALL_API_TOKEN", ""),
+		OnCallSchedules:          getEnvMap("ONCALL_SCHEDULES"),
+		OnCallTable:              getEnv("ONCALL_TABLE", "cloudops-oncall"),
+		HandoffChannel:           getEnv("HANDOFF_CHANNEL", ""),
+		ExpertsGroup:             getEnv("EXPERTS_GROUP", ""),
+		HandoffShiftHours:        getEnvInt("HANDOFF_SHIFT_HOURS", 12),
+		HandoffTimezone:          getEnv("HANDOFF_TIMEZONE", "UTC"),
+		ChargebackChannels:       getEnvMap("CHARGEBACK_CHANNELS"),
+		ChargebackProfileField:   getEnv("CHARGEBACK_PROFILE_FIELD", "Team"),
+		ChargebackChannel:        getEnv("CHARGEBACK_CHANNEL", ""),
+		BedrockInputPrice:        getEnvFloat("BEDROCK_INPUT_PRICE", chargeback.DefaultRates.InputTokensPerMillion),
+		BedrockOutputPrice:       getEnvFloat("BEDROCK_OUTPUT_PRICE", chargeback.DefaultRates.OutputTokensPerMillion),
+		ToolCallPrice:            getEnvFloat("TOOL_CALL_PRICE", chargeback.DefaultRates.ToolCall),
+		TaskHourPrice:            getEnvFloat("TASK_HOUR_PRICE", chargeback.DefaultRates.TaskHour),
+		CostAlertChannel:         getEnv("COST_ALERT_CHANNEL", ""),
+		CostTag:                  getEnv("COST_TAG", ""),
+		CostDailyLimit:           getEnvFloat("COST_DAILY_LIMIT", 0),
+		CostMonthlyLimit:         getEnvFloat("COST_MONTHLY_LIMIT", 0),
+		CostIncludeBedrock:       getEnvBool("COST_INCLUDE_BEDROCK", false),
+		AnnounceChannels:         getEnvList("ANNOUNCE_CHANNELS"),
+		AnnounceUsers:            getEnvList("ANNOUNCE_USERS"),
+		AdminUsers:               getEnvList("ADMIN_USERS"),
+		RBACPolicy:               getEnv("RBAC_POLICY", ""),
+		WebhookURLs:              getEnvList("WEBHOOK_URLS"),
+		WebhookSecret:            getEnv("WEBHOOK_SECRET", ""),
+		IAMSuggestionChannel:     getEnv("IAM_SUGGESTION_CHANNEL", ""),
+		IAMSuggestionThreshold:   getEnvInt("IAM_SUGGESTION_THRESHOLD", iampolicy.DefaultThreshold),
+		BreakGlassChannel:        getEnv("BREAK_GLASS_CHANNEL", ""),
+		BreakGlassMinutes:        getEnvInt("BREAK_GLASS_MINUTES", 60),
+		AlertChannel:             getEnv("ALERT_CHANNEL", ""),
+		AlertTeam:                getEnv("ALERT_TEAM", ""),
+		AlertAckMinutes:          getEnvInt("ALERT_ACK_MINUTES", 5),
+		AlertPagerRoutingKey:     getEnv("ALERT_PAGER_ROUTING_KEY", ""),
+		AlertRoutes:              getEnv("ALERT_ROUTES", ""),
+		AlertDigestMinutes:       getEnvInt("ALERT_DIGEST_MINUTES", 0),
+		WarmPoolMaxIdleMinutes:   getEnvInt("WARM_POOL_MAX_IDLE_MINUTES", 60),
+		LockLeaseSeconds:         getEnvInt("LOCK_LEASE_SECONDS", 60),
+		AgentCapacity:            getEnv("AGENT_CAPACITY", models.CapacityOnDemand),
+		OnDemandChannels:         getEnvList("ONDEMAND_CHANNELS"),
+		TaskSizes:                getEnv("TASK_SIZES", ""),
+		TaskDefinitionArn:        getEnv("TASK_DEFINITION_ARN", ""),
+		SandboxRoleARN:           getEnv("SANDBOX_ROLE_ARN", ""),
+		SandboxExternalID:        getEnv("SANDBOX_EXTERNAL_ID", ""),
+		ShadowTaskDefinition:     getEnv("SHADOW_TASK_DEFINITION", ""),
+		ShadowPercent:            getEnvInt("SHADOW_PERCENT", 100),
+		ShadowMode:               getEnvBool("SHADOW_MODE", false),
+		WorkerPoolSize:           getEnvInt("WORKER_POOL_SIZE", 8),
+		WorkerMailboxSize:        getEnvInt("WORKER_MAILBOX_SIZE", 10),
+		WorkerQueueSize:          getEnvInt("WORKER_QUEUE_SIZE", 100),
+		SLAPolicy:                getEnv("SLA_POLICY", ""),
+		ApprovalPolicy:           getEnv("APPROVAL_POLICY", ""),
+		AllowedSourceCIDRs:       getEnvList("ALLOWED_SOURCE_CIDRS"),
+		RequireClientCert:        getEnvBool("REQUIRE_CLIENT_CERT", false),
+		ClientCertNames:          getEnvList("CLIENT_CERT_NAMES"),
+		ChaosEnabled:             getEnvBool("CHAOS_ENABLED", false),
+		ChaosLatencyMs:           getEnvInt("CHAOS_LATENCY_MS", 0),
+		ChaosErrorRate:           getEnvFloat("CHAOS_ERROR_RATE", 0),
+		ChaosTargets:             getEnvList("CHAOS_TARGETS"),
+	}
+
+	if len(cfg.EnsembleTags) == 0 {
+		cfg.EnsembleTags = []string{"critical"}
+	}
+	if len(cfg.ClientCertNames) == 0 {
+		cfg.ClientCertNames = handler.DefaultClientCertNames
 	}
 
 	// Validate required fields
@@ -53,9 +439,15 @@ func Load() (*Config, error) {
 
 // Validate checks that required configuration is present
 func (c *Config) Validate() error {
-	if c.SlackBotToken == "" {
+	if c.SlackBotToken == "" && !c.TokenRotation() {
 		return fmt.Errorf("SLACK_BOT_TOKEN is required")
 	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	if c.SlackClientID != "" && c.SlackClientSecret == "" {
+		return fmt.Errorf("SLACK_CLIENT_SECRET is required for token rotation")
+	}
 	if c.SlackSigningKey == "" {
 		return fmt.Errorf("SLACK_SIGNING_KEY is required")
 	}
@@ -65,6 +457,140 @@ func (c *Config) Validate() error {
 	if c.ConversationHistoryTable == "" {
 		return fmt.Errorf("CONVERSATION_HISTORY_TABLE is required")
 	}
+	if c.ChaosEnabled && c.IsProduction() {
+		return fmt.Errorf("CHAOS_ENABLED is not allowed in production")
+	}
+	if c.EmbeddingModelID != "" && (c.DuplicateThreshold <= 0 || c.DuplicateThreshold > 1) {
+		return fmt.Errorf("DUPLICATE_THRESHOLD must be greater than 0 and at most 1")
+	}
+	if c.PromptVersion < 0 {
+		return fmt.Errorf("PROMPT_VERSION must not be negative")
+	}
+	if c.ChaosErrorRate < 0 || c.ChaosErrorRate > 1 {
+		return fmt.Errorf("CHAOS_ERROR_RATE must be between 0 and 1")
+	}
+	for _, target := range c.ChaosTargets {
+		if !slices.Contains(chaos.Targets, target) {
+			return fmt.Errorf("CHAOS_TARGETS has unknown target %q; use %s", target, strings.Join(chaos.Targets, ", "))
+		}
+	}
+	if c.LocksTable != "" && c.LockLeaseSeconds < 3 {
+		return fmt.Errorf("LOCK_LEASE_SECONDS must be at least 3")
+	}
+	switch c.OnCallProvider {
+	case "", "dynamodb":
+	case "pagerduty", "opsgenie":
+		if c.OnCallAPIToken == "" {
+			return fmt.Errorf("ONCALL_API_TOKEN is required for %s", c.OnCallProvider)
+		}
+	default:
+		return fmt.Errorf("unknown ONCALL_PROVIDER: %s", c.OnCallProvider)
+	}
+	if _, err := sla.ParsePolicies(c.SLAPolicy); err != nil {
+		return fmt.Errorf("invalid SLA_POLICY: %w", err)
+	}
+	if c.RBACPolicy != "" && !rbac.ValidSource(c.RBACPolicy) {
+		return fmt.Errorf("RBAC_POLICY must be dynamodb or ssm:<parameter name>")
+	}
+	for _, raw := range c.WebhookURLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid WEBHOOK_URLS entry %q: want an http or https URL", raw)
+		}
+	}
+	if len(c.WebhookURLs) > 0 && c.WebhookSecret == "" {
+		return fmt.Errorf("WEBHOOK_SECRET is required with WEBHOOK_URLS")
+	}
+	if c.IAMSuggestionChannel != "" && c.IAMSuggestionThreshold <= 0 {
+		return fmt.Errorf("IAM_SUGGESTION_THRESHOLD must be positive")
+	}
+	if c.BreakGlassChannel != "" && c.BreakGlassMinutes <= 0 {
+		return fmt.Errorf("BREAK_GLASS_MINUTES must be positive")
+	}
+	if _, err := approval.ParsePolicies(c.ApprovalPolicy); err != nil {
+		return fmt.Errorf("invalid APPROVAL_POLICY: %w", err)
+	}
+	if c.AlertRoutes != "" {
+		if err := alerts.ValidRoutes(c.AlertRoutes); err != nil {
+			return fmt.Errorf("invalid ALERT_ROUTES: %w", err)
+		}
+	}
+	if c.CorrelationWindowMinutes < 0 {
+		return fmt.Errorf("CORRELATION_WINDOW_MINUTES must not be negative")
+	}
+	if c.HeartbeatSeconds < 0 {
+		return fmt.Errorf("HEARTBEAT_SECONDS must not be negative")
+	}
+	if c.StaleHeartbeatMinutes < 0 {
+		return fmt.Errorf("STALE_HEARTBEAT_MINUTES must not be negative")
+	}
+	if c.StaleHeartbeatMinutes > 0 && c.GetStaleHeartbeat() <= c.GetHeartbeatInterval() {
+		return fmt.Errorf("STALE_HEARTBEAT_MINUTES must be longer than HEARTBEAT_SECONDS")
+	}
+	if c.AlertDigestMinutes < 0 {
+		return fmt.Errorf("ALERT_DIGEST_MINUTES must not be negative")
+	}
+	if c.ContextPackSeconds < 0 {
+		return fmt.Errorf("CONTEXT_PACK_SECONDS must not be negative")
+	}
+	if _, err := handler.ParseNetworks(c.AllowedSourceCIDRs); err != nil {
+		return fmt.Errorf("invalid ALLOWED_SOURCE_CIDRS: %w", err)
+	}
+	if c.BedrockInputPrice < 0 || c.BedrockOutputPrice < 0 || c.ToolCallPrice < 0 || c.TaskHourPrice < 0 {
+		return fmt.Errorf("chargeback prices must not be negative")
+	}
+	switch c.AgentCapacity {
+	case "", models.CapacityOnDemand, models.CapacitySpot:
+	default:
+		return fmt.Errorf("AGENT_CAPACITY must be %s or %s", models.CapacityOnDemand, models.CapacitySpot)
+	}
+	if _, err := tasksize.ParseSizes(c.TaskSizes); err != nil {
+		return fmt.Errorf("invalid TASK_SIZES: %w", err)
+	}
+	switch c.JobRunner {
+	case "", "agent", "lambda":
+	default:
+		return fmt.Errorf("JOB_RUNNER must be agent or lambda")
+	}
+	switch c.ConversationMode {
+	case "", "inplace", "thread", "channel":
+	default:
+		return fmt.Errorf("CONVERSATION_MODE must be inplace, thread, or channel")
+	}
+	switch c.StorageBackend {
+	case "", "dynamodb", "memory":
+	default:
+		return fmt.Errorf("STORAGE_BACKEND must be dynamodb or memory")
+	}
+	if c.ExpertsGroup != "" && !strings.HasPrefix(c.ExpertsGroup, "S") {
+		return fmt.Errorf("EXPERTS_GROUP must be a Slack user group ID, e.g. S0123ABCD")
+	}
+	if c.StreamIntervalMs < 0 {
+		return fmt.Errorf("STREAM_INTERVAL_MS must not be negative")
+	}
+	if c.TurnBudgetSeconds < 0 {
+		return fmt.Errorf("TURN_BUDGET_SECONDS must not be negative")
+	}
+	if c.SandboxRoleARN != "" && !sandbox.ValidRoleARN(c.SandboxRoleARN) {
+		return fmt.Errorf("SANDBOX_ROLE_ARN must be an IAM role ARN")
+	}
+	if c.ContextTokens < 0 {
+		return fmt.Errorf("CONTEXT_TOKENS must not be negative")
+	}
+	if c.TurnsPerMinute < 0 {
+		return fmt.Errorf("TURNS_PER_MINUTE must not be negative")
+	}
+	if c.ShadowPercent < 0 || c.ShadowPercent > 100 {
+		return fmt.Errorf("SHADOW_PERCENT must be between 0 and 100")
+	}
+	if c.CostDailyLimit < 0 || c.CostMonthlyLimit < 0 {
+		return fmt.Errorf("COST_DAILY_LIMIT and COST_MONTHLY_LIMIT must not be negative")
+	}
+	if c.CostAlertChannel != "" {
+		if key, value := c.CostTagPair(); key == "" || value == "" {
+			return fmt.Errorf("COST_TAG must be key=value")
+		}
+	}
 	return nil
 }
 
@@ -79,6 +605,218 @@ func (c *Config) ValidateLambda() error {
 	return nil
 }
 
+// ValidateDevServer checks configuration for the local development
+// server. With inProcess the server runs agents itself; without, it starts
+// them through Step Functions like the Lambda handler
+func (c *Config) ValidateDevServer(inProcess bool) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if inProcess {
+		return nil
+	}
+	if c.InMemory() {
+		return fmt.Errorf("STORAGE_BACKEND=memory needs agents run in-process, since a separate agent can't read the server's memory")
+	}
+	if c.StepFunctionArn == "" {
+		return fmt.Errorf("STEP_FUNCTION_ARN is required unless agents run in-process")
+	}
+	return nil
+}
+
+// ValidateStandalone checks configuration for running in Socket Mode
+func (c *Config) ValidateStandalone() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(c.SlackAppToken, "xapp-") {
+		return fmt.Errorf("SLACK_APP_TOKEN must be an app-level token (xapp-...)")
+	}
+	if c.WorkerPoolSize < 1 {
+		return fmt.Errorf("WORKER_POOL_SIZE must be at least 1")
+	}
+	return nil
+}
+
+// ApprovalPolicies returns the approval policy for each action class,
+// falling back to the defaults
+func (c *Config) ApprovalPolicies() approval.Policies {
+	policies, err := approval.ParsePolicies(c.ApprovalPolicy)
+	if err != nil {
+		return approval.DefaultPolicies()
+	}
+	return policies
+}
+
+// TokenRotation reports whether the bot token is refreshed through Slack's
+// token rotation rather than fixed
+func (c *Config) TokenRotation() bool {
+	return c.SlackClientID != "" && c.SlackClientSecret != ""
+}
+
+// ThreadPerConversation reports whether a mention at the top of a channel
+// starts a conversation confined to the mention's thread, rather than one
+// with the channel to itself
+func (c *Config) ThreadPerConversation() bool {
+	return c.ConversationMode == "thread"
+}
+
+// ChannelPerConversation reports whether each new conversation gets a
+// private channel of its own
+func (c *Config) ChannelPerConversation() bool {
+	return c.ConversationMode == "channel"
+}
+
+// InMemory reports whether conversations are kept in process rather than
+// DynamoDB, for local development
+func (c *Config) InMemory() bool {
+	return c.StorageBackend == "memory"
+}
+
+// Sandbox reports whether tools read a demo account rather than the bot's own
+func (c *Config) Sandbox() bool {
+	return c.SandboxRoleARN != ""
+}
+
+// SigningKeys returns the signing secrets requests may be signed with
+func (c *Config) SigningKeys() []string {
+	return []string{c.SlackSigningKey, c.SlackSigningKeySecondary}
+}
+
+// ChargebackRates returns the prices used for the monthly chargeback report
+func (c *Config) ChargebackRates() chargeback.Rates {
+	return chargeback.Rates{
+		InputTokensPerMillion:  c.BedrockInputPrice,
+		OutputTokensPerMillion: c.BedrockOutputPrice,
+		ToolCall:               c.ToolCallPrice,
+		TaskHour:               c.TaskHourPrice,
+	}
+}
+
+// CostTagPair splits CostTag into the tag key and value
+func (c *Config) CostTagPair() (string, string) {
+	key, value, _ := strings.Cut(c.CostTag, "=")
+	return strings.TrimSpace(key), strings.TrimSpace(value)
+}
+
+// CapacityFor returns the capacity a new conversation's agent task runs on
+func (c *Config) CapacityFor(channelID string) string {
+	if c.AgentCapacity != models.CapacitySpot {
+		return models.CapacityOnDemand
+	}
+	for _, ch := range c.OnDemandChannels {
+		if ch == channelID {
+			return models.CapacityOnDemand
+		}
+	}
+	return models.CapacitySpot
+}
+
+// Shadowed reports whether a shadow agent should run alongside a
+// conversation. The choice is stable for a conversation ID
+func (c *Config) Shadowed(conversationID string) bool {
+	if c.ShadowTaskDefinition == "" || c.ShadowMode {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(conversationID))
+	return int(h.Sum32()%100) < c.ShadowPercent
+}
+
+// TaskSize returns the agent task size for a conversation type, on the
+// stack's task definition unless the size names another
+func (c *Config) TaskSize(kind string) tasksize.Size {
+	sizes, err := tasksize.ParseSizes(c.TaskSizes)
+	if err != nil {
+		sizes = tasksize.DefaultSizes()
+	}
+	size := sizes.For(kind)
+	if size.TaskDefinition == "" {
+		size.TaskDefinition = c.TaskDefinitionArn
+	}
+	return size
+}
+
+// AccessPolicy returns the restrictions on who can call the Slack webhook
+func (c *Config) AccessPolicy() handler.AccessPolicy {
+	networks, _ := handler.ParseNetworks(c.AllowedSourceCIDRs)
+	return handler.AccessPolicy{
+		AllowedNetworks:   networks,
+		RequireClientCert: c.RequireClientCert,
+		ClientCertNames:   c.ClientCertNames,
+	}
+}
+
+// SLAPolicies returns the incident SLA targets, falling back to the defaults
+func (c *Config) SLAPolicies() sla.Policies {
+	policies, err := sla.ParsePolicies(c.SLAPolicy)
+	if err != nil {
+		return sla.DefaultPolicies()
+	}
+	return policies
+}
+
+// GetAlertAckWindow returns how long responders have to acknowledge a critical alert
+func (c *Config) GetAlertAckWindow() time.Duration {
+	return time.Duration(c.AlertAckMinutes) * time.Minute
+}
+
+// GetAlertDigestWindow returns how long repeats of an alarm are folded into
+// its first message
+func (c *Config) GetAlertDigestWindow() time.Duration {
+	return time.Duration(c.AlertDigestMinutes) * time.Minute
+}
+
+// GetWarmPoolMaxIdle returns how long a warm agent waits before recycling
+func (c *Config) GetWarmPoolMaxIdle() time.Duration {
+	return time.Duration(c.WarmPoolMaxIdleMinutes) * time.Minute
+}
+
+// GetLockLease returns how long a conversation lock lasts without renewal
+func (c *Config) GetLockLease() time.Duration {
+	return time.Duration(c.LockLeaseSeconds) * time.Second
+}
+
+// GetStreamInterval returns how often a streamed answer is updated, or 0
+// when answers aren't streamed
+func (c *Config) GetStreamInterval() time.Duration {
+	return time.Duration(c.StreamIntervalMs) * time.Millisecond
+}
+
+// GetTurnBudget returns how long a turn runs before a partial answer is
+// posted, or 0 when turns wait for the full answer
+func (c *Config) GetTurnBudget() time.Duration {
+	return time.Duration(c.TurnBudgetSeconds) * time.Second
+}
+
+// GetMessageDebounce returns how long to wait for more messages before answering
+func (c *Config) GetMessageDebounce() time.Duration {
+	return time.Duration(c.MessageDebounceMs) * time.Millisecond
+}
+
+// GetCorrelationWindow returns how far apart related conversations can start
+func (c *Config) GetCorrelationWindow() time.Duration {
+	return time.Duration(c.CorrelationWindowMinutes) * time.Minute
+}
+
+// GetHeartbeatInterval returns how often a running agent records it's
+// alive, or 0 when it doesn't
+func (c *Config) GetHeartbeatInterval() time.Duration {
+	return time.Duration(c.HeartbeatSeconds) * time.Second
+}
+
+// GetStaleHeartbeat returns how long a conversation can go without a
+// heartbeat before it is timed out, or 0 when it never is
+func (c *Config) GetStaleHeartbeat() time.Duration {
+	return time.Duration(c.StaleHeartbeatMinutes) * time.Minute
+}
+
+// GetContextPackMaxAge returns how long after launch an agent trusts the
+// context pack it was started with, or 0 when packs aren't used
+func (c *Config) GetContextPackMaxAge() time.Duration {
+	return time.Duration(c.ContextPackSeconds) * time.Second
+}
+
 // GetInactivityTimeout returns the inactivity timeout as a duration
 func (c *Config) GetInactivityTimeout() time.Duration {
 	return time.Duration(c.InactivityTimeoutMinutes) * time.Minute
@@ -89,6 +827,25 @@ func (c *Config) GetConversationTTL() time.Duration {
 	return time.Duration(c.ConversationTTLDays*24) * time.Hour
 }
 
+// IsProduction reports whether the configured environment is production
+func (c *Config) IsProduction() bool {
+	switch strings.ToLower(c.Environment) {
+	case "prod", "production":
+		return true
+	}
+	return false
+}
+
+// FaultInjector returns the configured fault injector, or nil when fault
+// injection is disabled. Fault injection is never enabled in production.
+func (c *Config) FaultInjector() *chaos.Injector {
+	if !c.ChaosEnabled || c.IsProduction() {
+		return nil
+	}
+	latency := time.Duration(c.ChaosLatencyMs) * time.Millisecond
+	return chaos.New(latency, c.ChaosErrorRate, c.ChaosTargets...)
+}
+
 // Helper functions
 
 func getEnv(key, defaultValue string) string {
@@ -118,3 +875,39 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getEnvList(key) {
+		k, v, ok := strings.Cut(pair, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); ok && k != "" && v != "" {
+			values[k] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMScheme marks an environment value kept in Parameter Store, e.g.
+// SLACK_BOT_TOKEN=ssm:///cloudops/prod/slack-bot-token
+const SSMScheme = "ssm://"
+
+const (
+	// maxParametersPerCall is the most names GetParameters accepts at once
+	maxParametersPerCall = 10
+
+	// parameterTimeout bounds reading parameters while loading configuration
+	parameterTimeout = 10 * time.Second
+)
+
+// ParameterAPI reads SSM parameters
+type ParameterAPI interface {
+	GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error)
+}
+
+// ResolveParameters replaces environment values written as ssm://<name>
+// with the parameters they name, decrypting SecureStrings. The resolved
+// values stay in the environment, so a warm Lambda reads Parameter Store
+// once. A parameter that can't be read is an error rather than an empty
+// value, which would look like a setting that was never made. With no
+// client, one is created from the default AWS configuration when needed
+func ResolveParameters(ctx context.Context, params ParameterAPI) error {
+	refs := parameterRefs(os.Environ())
+	if len(refs) == 0 {
+		return nil
+	}
+
+	if params == nil {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("load aws config: %w", err)
+		}
+		params = ssm.NewFromConfig(awsCfg)
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for start := 0; start < len(names); start += maxParametersPerCall {
+		batch := names[start:min(start+maxParametersPerCall, len(names))]
+		out, err := params.GetParameters(ctx, &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return fmt.Errorf("get parameters: %w", err)
+		}
+		if len(out.InvalidParameters) > 0 {
+			return fmt.Errorf("parameters not found: %s", strings.Join(out.InvalidParameters, ", "))
+		}
+		for _, p := range out.Parameters {
+			for _, key := range refs[aws.ToString(p.Name)] {
+				if err := os.Setenv(key, aws.ToString(p.Value)); err != nil {
+					return fmt.Errorf("set %s: %w", key, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// parameterRefs returns the environment variables whose values name a
+// parameter, by parameter name
+func parameterRefs(environ []string) map[string][]string {
+	refs := map[string][]string{}
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(value, SSMScheme) {
+			continue
+		}
+		if name := strings.TrimPrefix(value, SSMScheme); name != "" {
+			refs[name] = append(refs[name], key)
+		}
+	}
+	return refs
+}
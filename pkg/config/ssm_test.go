@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type fakeParameters struct {
+	values map[string]string
+	calls  int
+}
+
+func (f *fakeParameters) GetParameters(ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersOutput, error) {
+	f.calls++
+	out := &ssm.GetParametersOutput{}
+	for _, name := range params.Names {
+		if value, ok := f.values[name]; ok && aws.ToBool(params.WithDecryption) {
+			out.Parameters = append(out.Parameters, types.Parameter{Name: aws.String(name), Value: aws.String(value)})
+		} else {
+			out.InvalidParameters = append(out.InvalidParameters, name)
+		}
+	}
+	return out, nil
+}
+
+func TestResolveParameters(t *testing.T) {
+	t.Setenv("SLACK_BOT_TOKEN", "ssm:///cloudops/test/slack-bot-token")
+	t.Setenv("SLACK_SIGNING_KEY", "plain-key")
+	t.Setenv("WEBHOOK_SECRET", "ssm:///cloudops/test/webhook-secret")
+
+	params := &fakeParameters{values: map[string]string{
+		"/cloudops/test/slack-bot-token": "xoxb-from-ssm",
+		"/cloudops/test/webhook-secret":  "s3cret",
+	}}
+	if err := ResolveParameters(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{"SLACK_BOT_TOKEN": "xoxb-from-ssm", "SLACK_SIGNING_KEY": "plain-key", "WEBHOOK_SECRET": "s3cret"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if params.calls != 1 {
+		t.Errorf("GetParameters called %d times, want 1", params.calls)
+	}
+
+	// Resolved values aren't read again
+	if err := ResolveParameters(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if params.calls != 1 {
+		t.Errorf("GetParameters called %d times after resolving, want 1", params.calls)
+	}
+}
+
+func TestResolveParametersMissing(t *testing.T) {
+	t.Setenv("SLACK_BOT_TOKEN", "ssm:///cloudops/test/missing")
+
+	if err := ResolveParameters(context.Background(), &fakeParameters{}); err == nil {
+		t.Error("ResolveParameters() should fail for a parameter that doesn't exist")
+	}
+}
+
+func TestResolveParametersBatches(t *testing.T) {
+	params := &fakeParameters{values: map[string]string{}}
+	for _, key := range []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L"} {
+		name := "/cloudops/test/" + key
+		params.values[name] = key
+		t.Setenv("CLOUDOPS_TEST_"+key, SSMScheme+name)
+	}
+
+	if err := ResolveParameters(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if params.calls != 2 {
+		t.Errorf("GetParameters called %d times, want 2 batches", params.calls)
+	}
+	if got := os.Getenv("CLOUDOPS_TEST_L"); got != "L" {
+		t.Errorf("CLOUDOPS_TEST_L = %q, want L", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Package contextpack carries what an agent would otherwise read from
+// DynamoDB at startup, the conversation with its summary and the senders'
+// permission profiles, from the code that launches it. The pack rides in
+// the Step Functions input, which reaches the task's environment, so it is
+// compressed and bounded to stay inside ECS's limit on overrides
+package contextpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// MaxSize bounds an encoded pack, leaving room in the 8 KiB of ECS
+// container overrides for the rest of the input and environment, even
+// once sealed, which adds about a third
+const MaxSize = 4096
+
+// ErrTooLarge is returned when a pack doesn't fit in MaxSize; the agent
+// then reads from DynamoDB as it would without one
+var ErrTooLarge = errors.New("context pack too large")
+
+// Pack is a snapshot of a conversation's context as of CreatedAt
+type Pack struct {
+	Conversation *models.Conversation `json:"conversation"`
+	// Permissions maps each user looked up to their stored profile, nil
+	// when they have none
+	Permissions map[string]*models.PermissionProfile `json:"permissions,omitempty"`
+	CreatedAt   time.Time                            `json:"created_at"`
+}
+
+// New creates a pack for conv as of now
+func New(conv *models.Conversation, now time.Time) *Pack {
+	snapshot := *conv
+	snapshot.Embedding = nil // large, and the agent computes it
+	return &Pack{
+		Conversation: &snapshot,
+		Permissions:  map[string]*models.PermissionProfile{},
+		CreatedAt:    now,
+	}
+}
+
+// Fresh reports whether the pack is recent enough at now to trust
+func (p *Pack) Fresh(now time.Time, maxAge time.Duration) bool {
+	return maxAge > 0 && now.Sub(p.CreatedAt) <= maxAge
+}
+
+// Encode returns the pack as base64 gzipped JSON, or ErrTooLarge
+func (p *Pack) Encode() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("marshal context pack: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("compress context pack: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("compress context pack: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(encoded) > MaxSize {
+		return "", fmt.Errorf("%w: %d bytes", ErrTooLarge, len(encoded))
+	}
+	return encoded, nil
+}
+
+// Decode reads a pack written by Encode
+func Decode(encoded string) (*Pack, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode context pack: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress context pack: %w", err)
+	}
+	defer zr.Close()
+
+	// Bound what a corrupt or hostile pack can expand to
+	data, err = io.ReadAll(io.LimitReader(zr, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("decompress context pack: %w", err)
+	}
+
+	var p Pack
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal context pack: %w", err)
+	}
+	if p.Conversation == nil || p.Conversation.ConversationID == "" {
+		return nil, errors.New("context pack has no conversation")
+	}
+	return &p, nil
+}
@@ -0,0 +1,94 @@
+package contextpack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	conv := models.NewConversation("C1", "U1", "why is checkout returning 502s?")
+	conv.Summary = "Checkout 502s started after the 11:40 deploy"
+	conv.Embedding = make([]float32, 1024)
+
+	pack := New(conv, now)
+	pack.Permissions["U1"] = &models.PermissionProfile{UserID: "U1", Profile: models.ProfileOperator}
+	pack.Permissions["U2"] = nil
+
+	encoded, err := pack.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if conv.Embedding == nil {
+		t.Error("Encode() should leave the conversation's embedding alone")
+	}
+
+	got, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Conversation.ConversationID != conv.ConversationID || got.Conversation.Summary != conv.Summary {
+		t.Errorf("Decode() conversation = %+v", got.Conversation)
+	}
+	if got.Conversation.Embedding != nil {
+		t.Error("pack should not carry the embedding")
+	}
+	if p, ok := got.Permissions["U1"]; !ok || p.Profile != models.ProfileOperator {
+		t.Errorf("Decode() permissions[U1] = %+v", p)
+	}
+	if p, ok := got.Permissions["U2"]; !ok || p != nil {
+		t.Errorf("Decode() permissions[U2] = %+v, %v; want nil, true", p, ok)
+	}
+	if !got.CreatedAt.Equal(now) {
+		t.Errorf("Decode() CreatedAt = %v, want %v", got.CreatedAt, now)
+	}
+}
+
+func TestEncodeTooLarge(t *testing.T) {
+	// Random text doesn't compress
+	b := make([]byte, MaxSize)
+	rand.Read(b)
+	conv := models.NewConversation("C1", "U1", hex.EncodeToString(b))
+
+	if _, err := New(conv, time.Now()).Encode(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Encode() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	empty, err := (&Pack{Conversation: &models.Conversation{}}).Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, encoded := range []string{"", "not base64!", "aGVsbG8=", empty} {
+		if _, err := Decode(encoded); err == nil {
+			t.Errorf("Decode(%q) should fail", encoded)
+		}
+	}
+}
+
+func TestFresh(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pack := &Pack{CreatedAt: created}
+
+	tests := []struct {
+		age    time.Duration
+		maxAge time.Duration
+		want   bool
+	}{
+		{age: time.Minute, maxAge: 5 * time.Minute, want: true},
+		{age: 5 * time.Minute, maxAge: 5 * time.Minute, want: true},
+		{age: 6 * time.Minute, maxAge: 5 * time.Minute, want: false},
+		{age: 0, maxAge: 0, want: false},
+	}
+	for _, tt := range tests {
+		if got := pack.Fresh(created.Add(tt.age), tt.maxAge); got != tt.want {
+			t.Errorf("Fresh(age %s, max %s) = %v, want %v", tt.age, tt.maxAge, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package control serves a small HTTP API on a Unix socket for operating a
+// running agent: pausing it, injecting a message as if posted in Slack,
+// dumping its state, and changing how long it waits before ending an idle
+// conversation. The socket is only reachable from inside the agent's task,
+// for instance through ECS Exec with cloudopsctl. Requests must carry the
+// token the agent writes next to the socket, readable only by its user
+package control
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Agent is what the control API operates
+type Agent interface {
+	State() State
+	Pause(paused bool)
+	Inject(userID, text string)
+	SetIdleTimeout(timeout time.Duration)
+}
+
+// State is a snapshot of a running agent
+type State struct {
+	ConversationID string    `json:"conversation_id"`
+	ChannelID      string    `json:"channel_id"`
+	ThreadTS       string    `json:"thread_ts,omitempty"`
+	Status         string    `json:"status"`
+	Paused         bool      `json:"paused"`
+	Pending        int       `json:"pending"` // messages waiting to be answered
+	LastActivity   time.Time `json:"last_activity"`
+	IdleTimeout    string    `json:"idle_timeout"`
+	Checkpoint     string    `json:"checkpoint,omitempty"` // last Slack message handled
+	Debug          bool      `json:"debug,omitempty"`
+	HumanAssisted  bool      `json:"human_assisted,omitempty"`
+	Participants   []string  `json:"participants,omitempty"`
+}
+
+// Message is a message injected into the conversation
+type Message struct {
+	UserID string `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+// Timeout changes how long the agent waits before ending an idle conversation
+type Timeout struct {
+	Minutes int `json:"minutes"`
+}
+
+// TokenPath returns where the token for the socket at path is kept
+func TokenPath(path string) string {
+	return path + ".token"
+}
+
+// Server serves the control API for an agent
+type Server struct {
+	path  string
+	token string
+	agent Agent
+}
+
+// NewServer creates a server for agent on the Unix socket at path
+func NewServer(path string, agent Agent) *Server {
+	return &Server{path: path, agent: agent}
+}
+
+// Serve listens on the socket until ctx is done. A new token is written
+// to TokenPath each time, and both files are removed on return
+func (s *Server) Serve(ctx context.Context) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	s.token = token
+
+	_ = os.Remove(s.path) // left behind by an earlier run
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.path, err)
+	}
+	defer os.Remove(s.path)
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("restrict socket: %w", err)
+	}
+	if err := os.WriteFile(TokenPath(s.path), []byte(token), 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("write token: %w", err)
+	}
+	defer os.Remove(TokenPath(s.path))
+
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.InfoContext(ctx, "serving control api", "socket", s.path)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve control api: %w", err)
+	}
+	return nil
+}
+
+// Handler returns the API's routes:
+//
+//	GET  /state    the agent's State
+//	POST /pause    stop answering; messages wait until resumed
+//	POST /resume   answer again, including anything that waited
+//	POST /message  answer a Message as if posted in Slack
+//	POST /timeout  change the idle Timeout
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /state", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.agent.State())
+	})
+	mux.HandleFunc("POST /pause", func(w http.ResponseWriter, r *http.Request) {
+		s.agent.Pause(true)
+		writeJSON(w, http.StatusOK, s.agent.State())
+	})
+	mux.HandleFunc("POST /resume", func(w http.ResponseWriter, r *http.Request) {
+		s.agent.Pause(false)
+		writeJSON(w, http.StatusOK, s.agent.State())
+	})
+	mux.HandleFunc("POST /message", func(w http.ResponseWriter, r *http.Request) {
+		var msg Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil || strings.TrimSpace(msg.Text) == "" {
+			writeError(w, http.StatusBadRequest, "expected {\"user_id\": ..., \"text\": ...} with text")
+			return
+		}
+		s.agent.Inject(msg.UserID, msg.Text)
+		writeJSON(w, http.StatusAccepted, s.agent.State())
+	})
+	mux.HandleFunc("POST /timeout", func(w http.ResponseWriter, r *http.Request) {
+		var t Timeout
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil || t.Minutes <= 0 {
+			writeError(w, http.StatusBadRequest, "expected {\"minutes\": ...} greater than 0")
+			return
+		}
+		s.agent.SetIdleTimeout(time.Duration(t.Minutes) * time.Minute)
+		writeJSON(w, http.StatusOK, s.agent.State())
+	})
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the server's token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Client calls the control API of the agent listening on a socket
+type Client struct {
+	token string
+	http  *http.Client
+}
+
+// NewClient creates a client for the socket at path, reading the token
+// the agent wrote next to it
+func NewClient(path string) (*Client, error) {
+	token, err := os.ReadFile(TokenPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("read token: %w", err)
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &Client{
+		token: strings.TrimSpace(string(token)),
+		http: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}, nil
+}
+
+// State returns the agent's state
+func (c *Client) State(ctx context.Context) (State, error) {
+	return c.do(ctx, http.MethodGet, "/state", nil)
+}
+
+// Pause stops the agent answering, or resumes it
+func (c *Client) Pause(ctx context.Context, paused bool) (State, error) {
+	if paused {
+		return c.do(ctx, http.MethodPost, "/pause", nil)
+	}
+	return c.do(ctx, http.MethodPost, "/resume", nil)
+}
+
+// Inject has the agent answer text as if userID had posted it
+func (c *Client) Inject(ctx context.Context, userID, text string) (State, error) {
+	return c.do(ctx, http.MethodPost, "/message", Message{UserID: userID, Text: text})
+}
+
+// SetIdleTimeout changes how long the agent waits before ending an idle
+// conversation
+func (c *Client) SetIdleTimeout(ctx context.Context, minutes int) (State, error) {
+	return c.do(ctx, http.MethodPost, "/timeout", Timeout{Minutes: minutes})
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body any) (State, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return State{}, fmt.Errorf("marshal request: %w", err)
+		}
+		reader = strings.NewReader(string(data))
+	}
+
+	// The host is ignored; every request goes to the socket
+	req, err := http.NewRequestWithContext(ctx, method, "http://agent"+path, reader)
+	if err != nil {
+		return State{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return State{}, fmt.Errorf("call agent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return State{}, fmt.Errorf("agent returned %d: %s", resp.StatusCode, e.Error)
+	}
+
+	var state State
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return State{}, fmt.Errorf("decode state: %w", err)
+	}
+	return state, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
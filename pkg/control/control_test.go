@@ -0,0 +1,125 @@
+package control
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeAgent struct {
+	mu       sync.Mutex
+	state    State
+	injected []Message
+}
+
+func (f *fakeAgent) State() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+func (f *fakeAgent) Pause(paused bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.Paused = paused
+}
+
+func (f *fakeAgent) Inject(userID, text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.injected = append(f.injected, Message{UserID: userID, Text: text})
+	f.state.Pending++
+}
+
+func (f *fakeAgent) SetIdleTimeout(timeout time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state.IdleTimeout = timeout.String()
+}
+
+func TestServeAndClient(t *testing.T) {
+	dir, err := os.MkdirTemp("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.sock")
+
+	agent := &fakeAgent{state: State{ConversationID: "conv-1", Status: "active"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(path, agent).Serve(ctx) }()
+
+	var client *Client
+	for i := 0; i < 100; i++ {
+		if client, err = NewClient(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if info, err := os.Stat(TokenPath(path)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	state, err := client.State(ctx)
+	if err != nil || state.ConversationID != "conv-1" {
+		t.Fatalf("State() = %+v, %v", state, err)
+	}
+	if state, err = client.Pause(ctx, true); err != nil || !state.Paused {
+		t.Errorf("Pause(true) = %+v, %v", state, err)
+	}
+	if state, err = client.Inject(ctx, "U123", "check the ALB"); err != nil || state.Pending != 1 {
+		t.Errorf("Inject() = %+v, %v", state, err)
+	}
+	if len(agent.injected) != 1 || agent.injected[0].Text != "check the ALB" {
+		t.Errorf("injected = %+v", agent.injected)
+	}
+	if state, err = client.SetIdleTimeout(ctx, 90); err != nil || state.IdleTimeout != "1h30m0s" {
+		t.Errorf("SetIdleTimeout() = %+v, %v", state, err)
+	}
+	if _, err := client.SetIdleTimeout(ctx, 0); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("SetIdleTimeout(0) error = %v, want a 400", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Serve() should remove the socket on return")
+	}
+	if _, err := os.Stat(TokenPath(path)); !os.IsNotExist(err) {
+		t.Error("Serve() should remove the token on return")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := &Server{token: "secret", agent: &fakeAgent{}}
+	handler := s.Handler()
+
+	for header, want := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Bearer secret": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/state", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Authorization %q: status = %d, want %d", header, rec.Code, want)
+		}
+	}
+}
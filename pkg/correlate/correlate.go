@@ -0,0 +1,261 @@
+// Package correlate links conversations started within minutes of each
+// other that look at the same AWS resources or carry the same tags, so two
+// people chasing one incident find each other. Both conversations are told
+// about the other and offered a merge: the newer one ends, its history is
+// folded into the older one's, and each channel is pointed at the other
+package correlate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// Block and action IDs of the merge offer
+const (
+	BlockID       = "correlate_merge"
+	MergeActionID = "correlate_merge"
+)
+
+// MetadataEventType marks the notice left in a merged conversation, which
+// tells the agent polling it to stop
+const MetadataEventType = "cloudops_merge"
+
+// maxShared caps how many shared resources and tags a notice lists
+const maxShared = 5
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+
+// Match is an active conversation related to another
+type Match struct {
+	Conversation *models.Conversation
+	Shared       []string // resource IDs and tags both conversations have
+}
+
+// Related returns the conversations started within window of conv that
+// share a resource or tag with it, most shared first. Conversations already
+// linked to conv are skipped
+func Related(conv *models.Conversation, candidates []*models.Conversation, window time.Duration) []Match {
+	var matches []Match
+	for _, c := range candidates {
+		if c.ConversationID == conv.ConversationID || c.Ended() {
+			continue
+		}
+		if contains(conv.Related, c.ConversationID) || contains(c.Related, conv.ConversationID) {
+			continue
+		}
+		if gap := c.CreatedAt.Sub(conv.CreatedAt); gap > window || gap < -window {
+			continue
+		}
+		if shared := Shared(conv, c); len(shared) > 0 {
+			matches = append(matches, Match{Conversation: c, Shared: shared})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return len(matches[i].Shared) > len(matches[j].Shared) })
+	return matches
+}
+
+// Shared returns the resource IDs and tags two conversations have in common
+func Shared(a, b *models.Conversation) []string {
+	var shared []string
+	for _, e := range a.Entities {
+		for _, other := range b.Entities {
+			if e.Type == other.Type && e.ID == other.ID {
+				shared = append(shared, e.ID)
+				break
+			}
+		}
+	}
+	for _, tag := range a.Tags {
+		if contains(b.Tags, tag) {
+			shared = append(shared, "#"+tag)
+		}
+	}
+	return shared
+}
+
+// Order returns the newer and older of two conversations. A merge keeps
+// the older one, which has been investigating longer
+func Order(a, b *models.Conversation) (newer, older *models.Conversation) {
+	if a.CreatedAt.After(b.CreatedAt) || (a.CreatedAt.Equal(b.CreatedAt) && a.ConversationID > b.ConversationID) {
+		return a, b
+	}
+	return b, a
+}
+
+// Blocks tells conv about a related conversation and offers to merge the
+// newer of the two into the older
+func Blocks(conv *models.Conversation, m Match) []slack.Block {
+	newer, older := Order(conv, m.Conversation)
+	text := Notice(conv, m)
+	button := slack.NewButtonBlockElement(MergeActionID, mergeValue(newer.ConversationID, older.ConversationID),
+		slack.NewTextBlockObject(slack.PlainTextType, "Merge into "+older.ConversationID, false, false))
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock(BlockID, button),
+	}
+}
+
+// Notice describes a related conversation, e.g. "Possibly the same
+// incident as conv-123 in #ops, started 4 minutes earlier"
+func Notice(conv *models.Conversation, m Match) string {
+	other := m.Conversation
+	shared := m.Shared
+	more := ""
+	if len(shared) > maxShared {
+		more = fmt.Sprintf(" and %d more", len(shared)-maxShared)
+		shared = shared[:maxShared]
+	}
+
+	return fmt.Sprintf("🔗 Possibly the same incident as `%s` in <#%s>, started %s: _%s_\nBoth involve %s%s.",
+		other.ConversationID, other.ChannelID, relative(other.CreatedAt.Sub(conv.CreatedAt)),
+		snippet(other.InitialCommand, 80), code(shared), more)
+}
+
+// Merged is left in the merged conversation to point people at the one
+// that carries on
+func Merged(into *models.Conversation, userID string) string {
+	return fmt.Sprintf("🔀 <@%s> merged this conversation into `%s` in <#%s>. Please continue there.", userID, into.ConversationID, into.ChannelID)
+}
+
+// Joined catches the conversation that carries on up on the one merged into it
+func Joined(from *models.Conversation, userID string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔀 <@%s> merged `%s` from <#%s> into this conversation.", userID, from.ConversationID, from.ChannelID)
+	if request := strings.TrimSpace(mentionPattern.ReplaceAllString(from.InitialCommand, "")); request != "" {
+		fmt.Fprintf(&b, "\n>%s", strings.ReplaceAll(request, "\n", "\n>"))
+	}
+	if pad := from.Scratchpad; !pad.IsEmpty() && len(pad.Findings) > 0 {
+		b.WriteString("\n*Found there*")
+		for _, f := range pad.Findings {
+			fmt.Fprintf(&b, "\n• %s", f)
+		}
+	}
+	return b.String()
+}
+
+// Metadata records on the merged notice which conversation was merged
+// into which
+func Metadata(fromID, intoID string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"conversation_id": fromID,
+			"into":            intoID,
+		},
+	}
+}
+
+// FromMetadata returns the conversation conversationID was merged into,
+// when meta records that merge
+func FromMetadata(meta slack.SlackMetadata, conversationID string) (string, bool) {
+	if meta.EventType != MetadataEventType {
+		return "", false
+	}
+	id, _ := meta.EventPayload["conversation_id"].(string)
+	into, _ := meta.EventPayload["into"].(string)
+	return into, id == conversationID && into != ""
+}
+
+// IntoMetadata returns the conversation merged into conversationID, when
+// meta records that merge
+func IntoMetadata(meta slack.SlackMetadata, conversationID string) (string, bool) {
+	if meta.EventType != MetadataEventType {
+		return "", false
+	}
+	from, _ := meta.EventPayload["conversation_id"].(string)
+	into, _ := meta.EventPayload["into"].(string)
+	return from, into == conversationID && from != ""
+}
+
+// Interleave merges the history of the conversation fromID into that of
+// the one it was merged into, ordered by when each message was sent. What
+// people asked in the merged conversation is marked as such, so the model
+// can tell the two apart
+func Interleave(into, from []models.ConversationHistoryItem, intoID, fromID string) []models.ConversationHistoryItem {
+	merged := make([]models.ConversationHistoryItem, 0, len(into)+len(from))
+	merged = append(merged, into...)
+	for _, item := range from {
+		if item.Role == models.RoleUser {
+			item.Content = fmt.Sprintf("[in merged conversation %s] %s", fromID, item.Content)
+		}
+		merged = append(merged, item)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].CreatedAt.Before(merged[j].CreatedAt) })
+	for i := range merged {
+		merged[i].ConversationID = intoID
+		merged[i].MessageIndex = i
+	}
+	return merged
+}
+
+// ParseMerge reads the conversations a clicked merge button names
+func ParseMerge(value string) (fromID, intoID string, ok bool) {
+	fromID, intoID, ok = strings.Cut(value, "|")
+	return fromID, intoID, ok && fromID != "" && intoID != "" && fromID != intoID
+}
+
+// WithoutButtons returns a message's blocks minus the merge offer, so the
+// same conversations aren't merged twice
+func WithoutButtons(blocks []slack.Block) []slack.Block {
+	var out []slack.Block
+	for _, b := range blocks {
+		if action, ok := b.(*slack.ActionBlock); ok && action.BlockID == BlockID {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func mergeValue(fromID, intoID string) string {
+	return fromID + "|" + intoID
+}
+
+// relative describes how long before or after the other conversation started
+func relative(gap time.Duration) string {
+	when := "later"
+	if gap < 0 {
+		gap, when = -gap, "earlier"
+	}
+	switch minutes := int(gap.Round(time.Minute) / time.Minute); minutes {
+	case 0:
+		return "at the same time"
+	case 1:
+		return "a minute " + when
+	default:
+		return fmt.Sprintf("%d minutes %s", minutes, when)
+	}
+}
+
+func code(values []string) string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = "`" + v + "`"
+	}
+	return strings.Join(out, ", ")
+}
+
+func snippet(s string, limit int) string {
+	s = strings.Join(strings.Fields(mentionPattern.ReplaceAllString(s, "")), " ")
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit-1]) + "…"
+	}
+	return s
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
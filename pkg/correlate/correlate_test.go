@@ -0,0 +1,138 @@
+package correlate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+func conversation(id string, started time.Time, tags []string, ids ...string) *models.Conversation {
+	conv := &models.Conversation{ConversationID: id, ChannelID: "C" + id, Status: models.StatusActive, CreatedAt: started, Tags: tags}
+	for _, e := range ids {
+		conv.Entities = append(conv.Entities, models.Entity{Type: models.EntityInstance, ID: e})
+	}
+	return conv
+}
+
+func TestRelated(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	conv := conversation("conv-new", now, []string{"payments"}, "i-0abc", "i-0def")
+
+	shared := conversation("conv-shared", now.Add(-4*time.Minute), nil, "i-0abc", "i-0def")
+	tagged := conversation("conv-tagged", now.Add(-10*time.Minute), []string{"payments"})
+	stale := conversation("conv-stale", now.Add(-time.Hour), nil, "i-0abc")
+	unrelated := conversation("conv-other", now.Add(-time.Minute), nil, "i-0999")
+	linked := conversation("conv-linked", now.Add(-time.Minute), nil, "i-0abc")
+	linked.Related = []string{"conv-new"}
+	ended := conversation("conv-ended", now.Add(-time.Minute), nil, "i-0abc")
+	ended.Status = models.StatusCompleted
+
+	got := Related(conv, []*models.Conversation{conv, tagged, stale, unrelated, linked, ended, shared}, 15*time.Minute)
+	if len(got) != 2 {
+		t.Fatalf("Related() = %+v, want 2 matches", got)
+	}
+	if got[0].Conversation != shared || strings.Join(got[0].Shared, ",") != "i-0abc,i-0def" {
+		t.Errorf("Related()[0] = %+v, want the conversation sharing most", got[0])
+	}
+	if got[1].Conversation != tagged || strings.Join(got[1].Shared, ",") != "#payments" {
+		t.Errorf("Related()[1] = %+v", got[1])
+	}
+}
+
+func TestOrder(t *testing.T) {
+	now := time.Now()
+	older := conversation("conv-b", now.Add(-time.Minute), nil)
+	newer := conversation("conv-a", now, nil)
+	if n, o := Order(older, newer); n != newer || o != older {
+		t.Errorf("Order() = %s, %s", n.ConversationID, o.ConversationID)
+	}
+
+	tie := conversation("conv-c", now, nil)
+	if n, _ := Order(newer, tie); n != tie {
+		t.Errorf("Order() should break ties by ID, got %s", n.ConversationID)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	conv := conversation("conv-new", now, nil, "i-0abc")
+	other := conversation("conv-old", now.Add(-4*time.Minute), nil, "i-0abc")
+	other.InitialCommand = "<@U0BOT> checkout is returning 502s"
+
+	blocks := Blocks(conv, Match{Conversation: other, Shared: []string{"i-0abc"}})
+	if len(blocks) != 2 {
+		t.Fatalf("Blocks() = %d blocks", len(blocks))
+	}
+	text := blocks[0].(*slack.SectionBlock).Text.Text
+	for _, want := range []string{"`conv-old` in <#Cconv-old>", "4 minutes earlier", "_checkout is returning 502s_", "`i-0abc`"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("notice = %q, missing %q", text, want)
+		}
+	}
+
+	button := blocks[1].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	from, into, ok := ParseMerge(button.Value)
+	if button.ActionID != MergeActionID || !ok || from != "conv-new" || into != "conv-old" {
+		t.Errorf("merge button = %+v", button)
+	}
+	if got := WithoutButtons(blocks); len(got) != 1 {
+		t.Errorf("WithoutButtons() = %d blocks, want 1", len(got))
+	}
+}
+
+func TestParseMerge(t *testing.T) {
+	for _, bad := range []string{"", "conv-a", "conv-a|", "|conv-b", "conv-a|conv-a"} {
+		if _, _, ok := ParseMerge(bad); ok {
+			t.Errorf("ParseMerge(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	meta := Metadata("conv-new", "conv-old")
+	if into, ok := FromMetadata(meta, "conv-new"); !ok || into != "conv-old" {
+		t.Errorf("FromMetadata() = %q, %v", into, ok)
+	}
+	if _, ok := FromMetadata(meta, "conv-old"); ok {
+		t.Error("FromMetadata() should only match the merged conversation")
+	}
+	if from, ok := IntoMetadata(meta, "conv-old"); !ok || from != "conv-new" {
+		t.Errorf("IntoMetadata() = %q, %v", from, ok)
+	}
+	if _, ok := IntoMetadata(meta, "conv-new"); ok {
+		t.Error("IntoMetadata() should only match the conversation merged into")
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	start := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	item := func(conv string, index int, role, content string, minute int) models.ConversationHistoryItem {
+		return models.ConversationHistoryItem{ConversationID: conv, MessageIndex: index, Role: role, Content: content, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	}
+	into := []models.ConversationHistoryItem{
+		item("conv-old", 0, models.RoleUser, "checkout 502s", 0),
+		item("conv-old", 1, models.RoleAssistant, "looking at the ALB", 1),
+		item("conv-old", 2, models.RoleUser, "any deploys?", 5),
+	}
+	from := []models.ConversationHistoryItem{
+		item("conv-new", 0, models.RoleUser, "payments timing out", 3),
+		item("conv-new", 1, models.RoleAssistant, "checking RDS", 4),
+	}
+
+	got := Interleave(into, from, "conv-old", "conv-new")
+	want := []string{"checkout 502s", "looking at the ALB", "[in merged conversation conv-new] payments timing out", "checking RDS", "any deploys?"}
+	if len(got) != len(want) {
+		t.Fatalf("Interleave() = %d items, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Content != w || got[i].MessageIndex != i || got[i].ConversationID != "conv-old" {
+			t.Errorf("Interleave()[%d] = %+v, want %q at index %d", i, got[i], w, i)
+		}
+	}
+	if from[0].Content != "payments timing out" {
+		t.Error("Interleave() modified its input")
+	}
+}
@@ -0,0 +1,136 @@
+// Package debugmode shows power users what the agent did to answer: each
+// answer in a conversation with debug mode on carries the tool calls the
+// model made, their parameters, and the tokens used, in context blocks
+package debugmode
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/slack-go/slack"
+)
+
+// MetadataEventType marks the message that turns debug mode on or off,
+// which the agent polling the conversation picks up
+const MetadataEventType = "cloudops_debug"
+
+// Slack allows up to 10 elements per context block and 3000 characters per
+// text object; inputs are cut well short of that to stay readable
+const (
+	maxElements    = 10
+	maxInputLength = 300
+)
+
+var flagPattern = regexp.MustCompile(`(?i)(^|\s)--debug\b`)
+
+// Requested reports whether a mention asks for debug mode with --debug,
+// returning the text without the flag
+func Requested(text string) (bool, string) {
+	if !flagPattern.MatchString(text) {
+		return false, text
+	}
+	return true, strings.TrimSpace(flagPattern.ReplaceAllString(text, ""))
+}
+
+// Metadata carries the new setting on the message announcing it
+func Metadata(conversationID string, on bool) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"conversation_id": conversationID,
+			"debug":           on,
+		},
+	}
+}
+
+// FromMetadata returns the debug setting when meta changes it for the
+// conversation
+func FromMetadata(meta slack.SlackMetadata, conversationID string) (on, ok bool) {
+	if meta.EventType != MetadataEventType {
+		return false, false
+	}
+	id, _ := meta.EventPayload["conversation_id"].(string)
+	on, isBool := meta.EventPayload["debug"].(bool)
+	return on, isBool && id == conversationID
+}
+
+// Notice announces the new setting in the conversation
+func Notice(userID string, on bool) string {
+	if on {
+		return fmt.Sprintf("🐞 <@%s> turned on debug mode. Answers will show the tool calls and tokens behind them.", userID)
+	}
+	return fmt.Sprintf("🐞 <@%s> turned off debug mode.", userID)
+}
+
+// Blocks describes a trace as context blocks: one line per tool call, then
+// the token usage. Tool results aren't shown, only their size, so debug
+// mode never posts data the answer itself withheld
+func Blocks(trace *bedrock.Trace) []slack.Block {
+	var elements []slack.MixedElement
+	calls := trace.Calls()
+	for i, call := range calls {
+		if len(elements) == maxElements-2 && len(calls) > i+1 {
+			elements = append(elements, text(fmt.Sprintf("_…and %d more tool calls_", len(calls)-i)))
+			break
+		}
+		elements = append(elements, text(describe(call)))
+	}
+
+	input, output := trace.Tokens()
+	summary := fmt.Sprintf("🪙 %s input + %s output tokens in %d model %s",
+		humanize.Count(int64(input)), humanize.Count(int64(output)), trace.Rounds(), plural(trace.Rounds(), "request", "requests"))
+	if len(calls) == 0 {
+		summary += ", no tool calls"
+	}
+	elements = append(elements, text(summary))
+
+	return []slack.Block{slack.NewContextBlock("debug", elements...)}
+}
+
+// describe formats one tool call, e.g.
+// 🔧 `describe_ec2_instances` `{"instance_ids":["i-1"]}` → 3 lines in 420ms
+func describe(call bedrock.ToolTrace) string {
+	input := strings.TrimSpace(string(call.Input))
+	if input == "" {
+		input = "{}"
+	}
+	if len(input) > maxInputLength {
+		input = input[:maxInputLength] + "…"
+	}
+	input = strings.ReplaceAll(input, "`", "'")
+
+	result := fmt.Sprintf("%d %s", lines(call.Output), plural(lines(call.Output), "line", "lines"))
+	if call.IsError {
+		result = "failed: " + strings.ReplaceAll(firstLine(call.Output), "`", "'")
+	}
+	return fmt.Sprintf("🔧 `%s` `%s` → %s in %s", call.Name, input, result, humanize.Duration(call.Duration))
+}
+
+func text(s string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+}
+
+func lines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(s, "\n") + 1
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	if len(line) > maxInputLength {
+		line = line[:maxInputLength] + "…"
+	}
+	return line
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
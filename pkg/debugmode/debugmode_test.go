@@ -0,0 +1,74 @@
+package debugmode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/slack-go/slack"
+)
+
+func TestRequested(t *testing.T) {
+	on, rest := Requested("<@UBOT> --debug why is checkout slow?")
+	if !on || rest != "<@UBOT> why is checkout slow?" {
+		t.Errorf("Requested() = %v, %q", on, rest)
+	}
+	if on, rest := Requested("<@UBOT> is the --debugger attached?"); on || rest != "<@UBOT> is the --debugger attached?" {
+		t.Errorf("Requested() = %v, %q; want the text untouched", on, rest)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	if on, ok := FromMetadata(Metadata("conv-1", true), "conv-1"); !ok || !on {
+		t.Errorf("FromMetadata() = %v, %v; want true, true", on, ok)
+	}
+	if on, ok := FromMetadata(Metadata("conv-1", false), "conv-1"); !ok || on {
+		t.Errorf("FromMetadata() = %v, %v; want false, true", on, ok)
+	}
+	if _, ok := FromMetadata(Metadata("conv-1", true), "conv-2"); ok {
+		t.Error("FromMetadata() should ignore other conversations")
+	}
+	if _, ok := FromMetadata(slack.SlackMetadata{EventType: "cloudops_transfer"}, "conv-1"); ok {
+		t.Error("FromMetadata() should ignore other event types")
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	trace := bedrock.NewTrace()
+	trace.AddRound(1200, 80)
+	trace.AddCall(bedrock.ToolTrace{Name: "describe_ec2_instances", Input: json.RawMessage(`{"instance_ids":["i-1"]}`), Output: "a\nb\nc", Duration: 420 * time.Millisecond})
+	trace.AddCall(bedrock.ToolTrace{Name: "query_cloudwatch_logs", Input: json.RawMessage(`{}`), Output: "AccessDenied: not authorized\nmore", IsError: true, Duration: time.Second})
+	trace.AddRound(1500, 200)
+
+	elements := Blocks(trace)[0].(*slack.ContextBlock).ContextElements.Elements
+	var got []string
+	for _, e := range elements {
+		got = append(got, e.(*slack.TextBlockObject).Text)
+	}
+
+	want := []string{
+		"🔧 `describe_ec2_instances` `{\"instance_ids\":[\"i-1\"]}` → 3 lines in 420ms",
+		"🔧 `query_cloudwatch_logs` `{}` → failed: AccessDenied: not authorized in 1s",
+		"🪙 2,700 input + 280 output tokens in 2 model requests",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Blocks() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBlocksCapsElements(t *testing.T) {
+	trace := bedrock.NewTrace()
+	for i := 0; i < 15; i++ {
+		trace.AddCall(bedrock.ToolTrace{Name: "echo", Output: "ok"})
+	}
+
+	elements := Blocks(trace)[0].(*slack.ContextBlock).ContextElements.Elements
+	if len(elements) != maxElements {
+		t.Fatalf("Blocks() has %d elements, want %d", len(elements), maxElements)
+	}
+	if text := elements[maxElements-2].(*slack.TextBlockObject).Text; text != "_…and 7 more tool calls_" {
+		t.Errorf("overflow element = %q", text)
+	}
+}
@@ -0,0 +1,394 @@
+// Package diagnose explains why an AWS call made by a tool failed, so the
+// model can tell people what to change instead of repeating a bare error
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Kinds of failure the diagnoser explains
+const (
+	KindAccessDenied = "access_denied"
+	KindThrottled    = "throttled"
+	KindInvalidInput = "invalid_input"
+)
+
+// ErrInvalidInput marks a tool failure caused by the input the model gave
+// it, which the model can correct and retry
+var ErrInvalidInput = errors.New("invalid input")
+
+// InvalidInput marks err as caused by a tool's input, for tools that check
+// their input before calling AWS
+func InvalidInput(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+}
+
+// accessDeniedCodes are the error codes AWS services use for a request the
+// caller isn't allowed to make
+var accessDeniedCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"UnauthorizedOperation":       true,
+	"UnauthorizedAccess":          true,
+	"AuthorizationError":          true,
+	"AuthorizationErrorException": true,
+	"NotAuthorized":               true,
+}
+
+// throttlingCodes are the error codes AWS services use for a request over
+// the caller's rate or concurrency limit
+var throttlingCodes = map[string]bool{
+	"Throttling":                             true,
+	"ThrottlingException":                    true,
+	"ThrottledException":                     true,
+	"RequestThrottled":                       true,
+	"RequestThrottledException":              true,
+	"RequestLimitExceeded":                   true,
+	"TooManyRequestsException":               true,
+	"SlowDown":                               true,
+	"ProvisionedThroughputExceededException": true,
+	"LimitExceededException":                 true,
+}
+
+// invalidInputCodes are the error codes AWS services use for a request
+// whose parameters are wrong. Not found codes are included, since the
+// usual cause is a mistyped ID or the wrong region
+var invalidInputCodes = map[string]bool{
+	"InvalidParameter":               true,
+	"InvalidParameterValue":          true,
+	"InvalidParameterException":      true,
+	"InvalidParameterCombination":    true,
+	"InvalidParameterValueException": true,
+	"MissingParameter":               true,
+	"ValidationError":                true,
+	"ValidationException":            true,
+	"InvalidInstanceID.Malformed":    true,
+	"InvalidInstanceID.NotFound":     true,
+	"ClusterNotFoundException":       true,
+	"ServiceNotFoundException":       true,
+	"ResourceNotFoundException":      true,
+}
+
+// iamPrefixes maps SDK service IDs to their IAM action prefixes, for errors
+// that don't name the denied action
+var iamPrefixes = map[string]string{
+	"CloudFormation":  "cloudformation",
+	"CloudWatch":      "cloudwatch",
+	"CloudWatch Logs": "logs",
+	"Cost Explorer":   "ce",
+	"DynamoDB":        "dynamodb",
+	"EC2":             "ec2",
+	"ECS":             "ecs",
+	"IAM":             "iam",
+	"Lambda":          "lambda",
+	"RDS":             "rds",
+	"S3":              "s3",
+	"SFN":             "states",
+	"STS":             "sts",
+}
+
+// Classify returns the kind of failure behind err and its AWS error code, or
+// empty strings when it isn't one the diagnoser explains
+func Classify(err error) (kind, code string) {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		if errors.Is(err, ErrInvalidInput) {
+			return KindInvalidInput, ""
+		}
+		return "", ""
+	}
+	code = apiErr.ErrorCode()
+	switch {
+	case accessDeniedCodes[code]:
+		return KindAccessDenied, code
+	case throttlingCodes[code]:
+		return KindThrottled, code
+	case invalidInputCodes[code]:
+		return KindInvalidInput, code
+	default:
+		return "", code
+	}
+}
+
+// Denial is what an access denied message says about the refused request
+type Denial struct {
+	Principal string // ARN of the caller
+	Action    string // e.g. logs:StartQuery
+	Resource  string
+	Reason    string // e.g. because no identity-based policy allows the action
+}
+
+var denialPattern = regexp.MustCompile(`User: (\S+) is not authorized to perform: (\S+)(?: on resource: (\S+))?(?: (because .+|with an explicit deny .+))?`)
+
+// ParseDenial reads the principal, action, resource and reason from an
+// access denied message. Some services only return an encoded message, so
+// ok is false when the message doesn't name them
+func ParseDenial(message string) (d Denial, ok bool) {
+	m := denialPattern.FindStringSubmatch(message)
+	if m == nil {
+		return Denial{}, false
+	}
+	return Denial{
+		Principal: m[1],
+		Action:    m[2],
+		Resource:  m[3],
+		Reason:    strings.TrimRight(m[4], ". "),
+	}, true
+}
+
+// RoleName returns the name of the IAM role behind an assumed-role or role
+// ARN, or "" for other principals such as users
+func RoleName(arn string) string {
+	_, resource, ok := strings.Cut(arn, ":assumed-role/")
+	if ok {
+		name, _, _ := strings.Cut(resource, "/")
+		return name
+	}
+	if _, resource, ok := strings.Cut(arn, ":role/"); ok {
+		return resource[strings.LastIndex(resource, "/")+1:]
+	}
+	return ""
+}
+
+// Remedy says what to change so the denied request is allowed, based on
+// which kind of policy refused it
+func Remedy(d Denial, role string) string {
+	action := d.Action
+	if action == "" {
+		action = "the action"
+	}
+	target := "the agent's role"
+	if role != "" {
+		target = fmt.Sprintf("the role %s", role)
+	}
+
+	switch {
+	case strings.Contains(d.Reason, "service control policy"):
+		return fmt.Sprintf("A service control policy of the AWS organization denies %s in this account. Only the organization's admins can change that, so ask them to allow it.", action)
+	case strings.Contains(d.Reason, "permissions boundary"):
+		return fmt.Sprintf("The permissions boundary on %s doesn't allow %s. Add it to the boundary as well as to the role's own policy.", target, action)
+	case strings.Contains(d.Reason, "session policy"):
+		return fmt.Sprintf("The session policy used when assuming %s doesn't allow %s. Allow it in the session policy.", target, action)
+	case strings.Contains(d.Reason, "explicit deny in an identity-based policy"):
+		return fmt.Sprintf("A policy attached to %s explicitly denies %s, which overrides any Allow. Narrow or remove that Deny statement.", target, action)
+	case strings.Contains(d.Reason, "resource-based policy"):
+		return fmt.Sprintf("The resource's own policy doesn't grant %s access. Add the role as a principal allowed %s in the resource policy.", target, action)
+	default:
+		return fmt.Sprintf("Grant %s to %s, for example by adding it to the task role policy in the CloudFormation stack.", action, target)
+	}
+}
+
+// DenialHandler is told about each access denied error whose IAM action is
+// known, with the role that was refused
+type DenialHandler interface {
+	Denied(ctx context.Context, d Denial, role string)
+}
+
+// STSAPI is the part of the STS client the diagnoser uses
+type STSAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// IAMAPI is the part of the IAM client the diagnoser uses
+type IAMAPI interface {
+	ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error)
+	ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error)
+}
+
+// Diagnoser looks up which role made a failed call and which policies it
+// has, and turns access denied, throttling, and invalid input errors into
+// remediation the model can pass on or act on
+type Diagnoser struct {
+	sts STSAPI
+	iam IAMAPI
+
+	denials DenialHandler
+
+	mu       sync.Mutex
+	identity string // ARN of the agent's credentials, looked up once
+}
+
+// New creates a diagnoser using the agent's AWS credentials
+func New(cfg aws.Config) *Diagnoser {
+	return &Diagnoser{sts: sts.NewFromConfig(cfg), iam: iam.NewFromConfig(cfg)}
+}
+
+// NewWithClients creates a diagnoser with custom STS and IAM clients
+func NewWithClients(stsClient STSAPI, iamClient IAMAPI) *Diagnoser {
+	return &Diagnoser{sts: stsClient, iam: iamClient}
+}
+
+// SetDenialHandler reports access denied errors to h
+func (d *Diagnoser) SetDenialHandler(h DenialHandler) {
+	d.denials = h
+}
+
+// Diagnose explains why a tool call failed and what to do about it, or
+// returns "" when err isn't an access denied, throttling, or invalid input
+// error
+func (d *Diagnoser) Diagnose(ctx context.Context, tool string, err error) string {
+	kind, code := Classify(err)
+	switch kind {
+	case KindAccessDenied:
+		return d.accessDenied(ctx, code, err)
+	case KindThrottled:
+		op := operation(err)
+		if op == "" {
+			op = "the " + tool + " call"
+		}
+		return throttled(code, op)
+	case KindInvalidInput:
+		op := operation(err)
+		if op == "" {
+			op = tool
+		}
+		return invalidInput(code, op)
+	default:
+		return ""
+	}
+}
+
+// accessDenied diagnoses a request the agent's role isn't allowed to make
+func (d *Diagnoser) accessDenied(ctx context.Context, code string, err error) string {
+	denial, parsed := ParseDenial(err.Error())
+	if denial.Principal == "" {
+		denial.Principal = d.callerIdentity(ctx)
+	}
+	if denial.Action == "" {
+		denial.Action = action(err)
+	}
+	role := RoleName(denial.Principal)
+	if d.denials != nil && strings.Contains(denial.Action, ":") {
+		d.denials.Denied(ctx, denial, role)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Diagnosis: access denied (%s).\n", code)
+	if denial.Principal != "" {
+		fmt.Fprintf(&b, "- Caller: %s\n", denial.Principal)
+	}
+	if denial.Action != "" {
+		if denial.Resource != "" {
+			fmt.Fprintf(&b, "- Denied: %s on %s\n", denial.Action, denial.Resource)
+		} else {
+			fmt.Fprintf(&b, "- Denied: %s\n", denial.Action)
+		}
+	}
+	if denial.Reason != "" {
+		fmt.Fprintf(&b, "- Cause: %s\n", denial.Reason)
+	}
+	if role != "" {
+		if policies := d.policies(ctx, role); len(policies) > 0 {
+			fmt.Fprintf(&b, "- Policies on %s: %s\n", role, strings.Join(policies, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "Remediation: %s\n", Remedy(denial, role))
+	if !parsed && strings.Contains(err.Error(), "Encoded authorization failure message") {
+		b.WriteString("The exact policy is in the encoded message; decode it with `aws sts decode-authorization-message --encoded-message <message>`.\n")
+	}
+	b.WriteString("Don't retry the call; it will fail the same way until the policy changes. Explain the diagnosis and remediation to the user.")
+	return b.String()
+}
+
+// throttled diagnoses a request over the account's rate limit for an API
+func throttled(code, op string) string {
+	return fmt.Sprintf("Diagnosis: AWS throttled %s (%s). Its request rate or concurrency limit is shared by everything calling it in this account and region, and is currently used up.\n"+
+		"Remediation: wait a minute before retrying once, and narrow the request (fewer resources, a shorter time range). "+
+		"If it keeps happening, find the heavy caller of %s in CloudTrail, or request a higher limit in Service Quotas where the API has one.", op, code, op)
+}
+
+// invalidInput diagnoses a call whose input was wrong, which the model can
+// fix itself
+func invalidInput(code, op string) string {
+	var b strings.Builder
+	if code == "" {
+		fmt.Fprintf(&b, "Diagnosis: %s rejected its input before calling AWS.\n", op)
+	} else {
+		fmt.Fprintf(&b, "Diagnosis: AWS rejected the input of %s (%s).\n", op, code)
+	}
+	if strings.Contains(code, "NotFound") {
+		b.WriteString("- The resource may be in another region or account, or its ID or name may be mistyped.\n")
+	}
+	b.WriteString("Remediation: correct the input named in the error, checking IDs and names against ones you've seen in earlier results and values against the tool's schema, then call again. " +
+		"Don't repeat the same input; if you can't tell what's wrong, ask the user.")
+	return b.String()
+}
+
+// operation names the AWS call behind err, e.g. EC2 DescribeInstances
+func operation(err error) string {
+	var opErr *smithy.OperationError
+	if !errors.As(err, &opErr) {
+		return ""
+	}
+	return strings.TrimSpace(opErr.Service() + " " + opErr.Operation())
+}
+
+// action names the IAM action behind err, e.g. ec2:DescribeInstances, or
+// the AWS call when the service's prefix isn't known
+func action(err error) string {
+	var opErr *smithy.OperationError
+	if !errors.As(err, &opErr) {
+		return ""
+	}
+	if prefix, ok := iamPrefixes[opErr.Service()]; ok {
+		return prefix + ":" + opErr.Operation()
+	}
+	return operation(err)
+}
+
+// callerIdentity returns the ARN the agent's credentials resolve to, or ""
+// when it can't be looked up
+func (d *Diagnoser) callerIdentity(ctx context.Context) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.identity != "" || d.sts == nil {
+		return d.identity
+	}
+
+	out, err := d.sts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to look up caller identity", "error", err)
+		return ""
+	}
+	d.identity = aws.ToString(out.Arn)
+	return d.identity
+}
+
+// policies lists the managed and inline policies on a role. The role may not
+// be allowed to read its own policies, so failures only leave them out
+func (d *Diagnoser) policies(ctx context.Context, role string) []string {
+	if d.iam == nil {
+		return nil
+	}
+
+	var names []string
+	attached, err := d.iam.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(role)})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to list policies of role", "role", role, "error", err)
+		return nil
+	}
+	for _, p := range attached.AttachedPolicies {
+		names = append(names, aws.ToString(p.PolicyName))
+	}
+
+	inline, err := d.iam.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{RoleName: aws.String(role)})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to list inline policies of role", "role", role, "error", err)
+		return names
+	}
+	for _, name := range inline.PolicyNames {
+		names = append(names, name+" (inline)")
+	}
+	return names
+}
@@ -0,0 +1,202 @@
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+type fakeSTS struct{ calls int }
+
+func (f *fakeSTS) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	f.calls++
+	return &sts.GetCallerIdentityOutput{Arn: aws.String("arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123")}, nil
+}
+
+type fakeIAM struct{ denied bool }
+
+func (f *fakeIAM) ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if f.denied {
+		return nil, errors.New("AccessDenied")
+	}
+	return &iam.ListAttachedRolePoliciesOutput{AttachedPolicies: []types.AttachedPolicy{{PolicyName: aws.String("ReadOnlyAccess")}}}, nil
+}
+
+func (f *fakeIAM) ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	return &iam.ListRolePoliciesOutput{PolicyNames: []string{"CloudOpsReadOnly"}}, nil
+}
+
+// apiError wraps an AWS error the way the SDK returns it from an operation
+func apiError(service, op, code, message string) error {
+	return fmt.Errorf("describe instances: %w", &smithy.OperationError{
+		ServiceID:     service,
+		OperationName: op,
+		Err:           &smithy.GenericAPIError{Code: code, Message: message},
+	})
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantKind string
+	}{
+		{apiError("EC2", "DescribeInstances", "UnauthorizedOperation", "You are not authorized"), KindAccessDenied},
+		{apiError("CloudWatch Logs", "StartQuery", "AccessDeniedException", ""), KindAccessDenied},
+		{apiError("EC2", "DescribeInstances", "RequestLimitExceeded", "Request limit exceeded."), KindThrottled},
+		{apiError("CloudWatch Logs", "StartQuery", "LimitExceededException", "too many concurrent queries"), KindThrottled},
+		{apiError("EC2", "DescribeInstances", "InvalidInstanceID.Malformed", ""), KindInvalidInput},
+		{apiError("ECS", "DescribeServices", "ClusterNotFoundException", "Cluster not found."), KindInvalidInput},
+		{InvalidInput(errors.New("cluster is required")), KindInvalidInput},
+		{apiError("EC2", "DescribeInstances", "InternalError", ""), ""},
+		{errors.New("AccessDenied"), ""},
+	}
+	for _, tt := range tests {
+		if kind, _ := Classify(tt.err); kind != tt.wantKind {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, kind, tt.wantKind)
+		}
+	}
+}
+
+func TestParseDenial(t *testing.T) {
+	msg := "User: arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123 is not authorized to perform: logs:StartQuery on resource: arn:aws:logs:us-east-1:123456789012:log-group:/ecs/checkout:* because no identity-based policy allows the logs:StartQuery action"
+	d, ok := ParseDenial(msg)
+	want := Denial{
+		Principal: "arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123",
+		Action:    "logs:StartQuery",
+		Resource:  "arn:aws:logs:us-east-1:123456789012:log-group:/ecs/checkout:*",
+		Reason:    "because no identity-based policy allows the logs:StartQuery action",
+	}
+	if !ok || d != want {
+		t.Errorf("ParseDenial() = %+v, %v; want %+v", d, ok, want)
+	}
+
+	d, ok = ParseDenial("User: arn:aws:iam::123456789012:user/bob is not authorized to perform: ec2:DescribeInstances with an explicit deny in a service control policy")
+	if !ok || d.Resource != "" || d.Reason != "with an explicit deny in a service control policy" {
+		t.Errorf("ParseDenial() without a resource = %+v, %v", d, ok)
+	}
+
+	if _, ok := ParseDenial("You are not authorized to perform this operation. Encoded authorization failure message: abc"); ok {
+		t.Error("ParseDenial() should fail for encoded messages")
+	}
+}
+
+func TestRoleName(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123": "cloudops-task-role",
+		"arn:aws:iam::123456789012:role/service-role/deploy":               "deploy",
+		"arn:aws:iam::123456789012:user/bob":                               "",
+	}
+	for arn, want := range tests {
+		if got := RoleName(arn); got != want {
+			t.Errorf("RoleName(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
+
+func TestRemedy(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{"because no identity-based policy allows the ec2:DescribeInstances action", "Grant ec2:DescribeInstances to the role cloudops-task-role"},
+		{"with an explicit deny in a service control policy", "organization's admins"},
+		{"because no permissions boundary allows the ec2:DescribeInstances action", "permissions boundary on the role cloudops-task-role"},
+		{"with an explicit deny in an identity-based policy: arn:aws:iam::123456789012:policy/NoEC2", "Deny statement"},
+		{"because no resource-based policy allows the ec2:DescribeInstances action", "resource policy"},
+	}
+	for _, tt := range tests {
+		got := Remedy(Denial{Action: "ec2:DescribeInstances", Reason: tt.reason}, "cloudops-task-role")
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("Remedy(%q) = %q, want it to mention %q", tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnoseAccessDenied(t *testing.T) {
+	stsClient := &fakeSTS{}
+	d := NewWithClients(stsClient, &fakeIAM{})
+
+	err := apiError("EC2", "DescribeInstances", "UnauthorizedOperation", "You are not authorized to perform this operation. Encoded authorization failure message: abc")
+	got := d.Diagnose(context.Background(), "describe_ec2_instances", err)
+	for _, want := range []string{
+		"access denied (UnauthorizedOperation)",
+		"Caller: arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123",
+		"Denied: ec2:DescribeInstances",
+		"Policies on cloudops-task-role: ReadOnlyAccess, CloudOpsReadOnly (inline)",
+		"decode-authorization-message",
+		"Don't retry",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Diagnose() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	d.Diagnose(context.Background(), "describe_ec2_instances", err)
+	if stsClient.calls != 1 {
+		t.Errorf("GetCallerIdentity called %d times, want the identity cached", stsClient.calls)
+	}
+}
+
+func TestDiagnoseWithoutPolicyAccess(t *testing.T) {
+	d := NewWithClients(&fakeSTS{}, &fakeIAM{denied: true})
+	err := apiError("CloudWatch Logs", "StartQuery", "AccessDeniedException",
+		"User: arn:aws:sts::123456789012:assumed-role/cloudops-task-role/abc123 is not authorized to perform: logs:StartQuery because no identity-based policy allows the logs:StartQuery action")
+
+	got := d.Diagnose(context.Background(), "query_cloudwatch_logs", err)
+	if strings.Contains(got, "Policies on") || !strings.Contains(got, "Remediation: Grant logs:StartQuery to the role cloudops-task-role") {
+		t.Errorf("Diagnose() = %q", got)
+	}
+}
+
+func TestDiagnoseThrottled(t *testing.T) {
+	d := NewWithClients(&fakeSTS{}, &fakeIAM{})
+	got := d.Diagnose(context.Background(), "describe_ec2_instances", apiError("EC2", "DescribeInstances", "RequestLimitExceeded", "Request limit exceeded."))
+	if !strings.Contains(got, "AWS throttled EC2 DescribeInstances (RequestLimitExceeded)") || !strings.Contains(got, "Service Quotas") {
+		t.Errorf("Diagnose() = %q", got)
+	}
+
+	if got := d.Diagnose(context.Background(), "echo", errors.New("boom")); got != "" {
+		t.Errorf("Diagnose() of an unclassified error = %q, want empty", got)
+	}
+}
+
+func TestDiagnoseInvalidInput(t *testing.T) {
+	d := NewWithClients(&fakeSTS{}, &fakeIAM{})
+	got := d.Diagnose(context.Background(), "describe_ec2_instances", apiError("EC2", "DescribeInstances", "InvalidInstanceID.NotFound", "The instance ID 'i-0abc' does not exist"))
+	if !strings.Contains(got, "AWS rejected the input of EC2 DescribeInstances (InvalidInstanceID.NotFound)") || !strings.Contains(got, "another region") {
+		t.Errorf("Diagnose() = %q", got)
+	}
+
+	got = d.Diagnose(context.Background(), "describe_ecs", InvalidInput(errors.New("cluster is required")))
+	if !strings.Contains(got, "describe_ecs rejected its input") || strings.Contains(got, "another region") {
+		t.Errorf("Diagnose() of a tool's own check = %q", got)
+	}
+}
+
+type recordingHandler struct{ denials []Denial }
+
+func (h *recordingHandler) Denied(ctx context.Context, d Denial, role string) {
+	h.denials = append(h.denials, d)
+}
+
+func TestDenialHandler(t *testing.T) {
+	h := &recordingHandler{}
+	d := NewWithClients(&fakeSTS{}, &fakeIAM{})
+	d.SetDenialHandler(h)
+
+	d.Diagnose(context.Background(), "describe_ec2_instances", apiError("EC2", "DescribeInstances", "UnauthorizedOperation", "You are not authorized"))
+	d.Diagnose(context.Background(), "other", apiError("Some Service", "DoThing", "AccessDenied", "denied"))
+	d.Diagnose(context.Background(), "describe_ec2_instances", apiError("EC2", "DescribeInstances", "RequestLimitExceeded", ""))
+
+	if len(h.denials) != 1 || h.denials[0].Action != "ec2:DescribeInstances" {
+		t.Errorf("handler saw %+v, want only the denial with a known IAM action", h.denials)
+	}
+}
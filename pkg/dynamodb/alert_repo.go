@@ -0,0 +1,186 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// AlertRepository handles DynamoDB operations for critical alert tracking
+type AlertRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewAlertRepository creates a new alert repository
+func NewAlertRepository(client *dynamodb.Client, tableName string) *AlertRepository {
+	return &AlertRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *AlertRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Save stores an alert, replacing any previous state
+func (r *AlertRepository) Save(ctx context.Context, alert *models.Alert) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveAlert"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(alert)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put alert: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the alert posted as a message, or nil when the message isn't one
+func (r *AlertRepository) Get(ctx context.Context, channelID, messageTS string) (*models.Alert, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetAlert"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"channel_id": &types.AttributeValueMemberS{Value: channelID},
+			"message_ts": &types.AttributeValueMemberS{Value: messageTS},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get alert: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var alert models.Alert
+	if err := attributevalue.UnmarshalMap(result.Item, &alert); err != nil {
+		return nil, fmt.Errorf("unmarshal alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// ListOpen returns alerts still waiting for acknowledgment
+func (r *AlertRepository) ListOpen(ctx context.Context) ([]*models.Alert, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListOpenAlerts"); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("StatusIndex"),
+		KeyConditionExpression: stringPtr("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: models.AlertOpen},
+		},
+	}
+
+	var alerts []*models.Alert
+	paginator := dynamodb.NewQueryPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query open alerts: %w", err)
+		}
+
+		var batch []*models.Alert
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal alerts: %w", err)
+		}
+		alerts = append(alerts, batch...)
+	}
+
+	return alerts, nil
+}
+
+// SaveDigest stores an alarm's digest, replacing any previous one
+func (r *AlertRepository) SaveDigest(ctx context.Context, digest *models.AlertDigest) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveAlertDigest"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(digest)
+	if err != nil {
+		return fmt.Errorf("marshal alert digest: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put alert digest: %w", err)
+	}
+
+	return nil
+}
+
+// FoldDigest counts another occurrence of an alarm in the digest posted to
+// a channel and returns the digest. It returns nil when the alarm hasn't
+// fired there since since, so the occurrence should be posted afresh
+func (r *AlertRepository) FoldDigest(ctx context.Context, channelID, alarmKey string, now, since time.Time) (*models.AlertDigest, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "FoldAlertDigest"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"channel_id": &types.AttributeValueMemberS{Value: channelID},
+			"message_ts": &types.AttributeValueMemberS{Value: models.DigestKey(alarmKey)},
+		},
+		UpdateExpression:    stringPtr("SET last_seen = :now, #ttl = :ttl ADD occurrences :one"),
+		ConditionExpression: stringPtr("attribute_exists(digest_ts) AND last_seen >= :since"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":since": &types.AttributeValueMemberN{Value: strconv.FormatInt(since.Unix(), 10)},
+			":ttl":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.AddDate(0, 0, 30).Unix(), 10)},
+			":one":   &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fold alert digest: %w", err)
+	}
+
+	var digest models.AlertDigest
+	if err := attributevalue.UnmarshalMap(result.Attributes, &digest); err != nil {
+		return nil, fmt.Errorf("unmarshal alert digest: %w", err)
+	}
+
+	return &digest, nil
+}
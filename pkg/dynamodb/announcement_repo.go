@@ -0,0 +1,115 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrNotAnnouncement is returned when a message isn't a broadcast announcement
+var ErrNotAnnouncement = errors.New("not an announcement")
+
+// AnnouncementRepository handles DynamoDB operations for broadcast announcements
+type AnnouncementRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewAnnouncementRepository creates a new announcement repository
+func NewAnnouncementRepository(client *dynamodb.Client, tableName string) *AnnouncementRepository {
+	return &AnnouncementRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *AnnouncementRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// SavePost stores one channel's copy of an announcement
+func (r *AnnouncementRepository) SavePost(ctx context.Context, post *models.AnnouncementPost) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveAnnouncementPost"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(post)
+	if err != nil {
+		return fmt.Errorf("marshal announcement post: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put announcement post: %w", err)
+	}
+
+	return nil
+}
+
+// AddAck records a user's acknowledgment of an announcement message. It
+// returns ErrNotAnnouncement when the message isn't one
+func (r *AnnouncementRepository) AddAck(ctx context.Context, channelID, messageTS, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AddAnnouncementAck"); err != nil {
+		return err
+	}
+
+	updateExpr := "ADD acked_by :user"
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"channel_id": &types.AttributeValueMemberS{Value: channelID},
+			"message_ts": &types.AttributeValueMemberS{Value: messageTS},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("attribute_exists(announcement_id)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user": &types.AttributeValueMemberSS{Value: []string{userID}},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrNotAnnouncement
+		}
+		return fmt.Errorf("add announcement ack: %w", err)
+	}
+
+	return nil
+}
+
+// ListPosts returns every channel's copy of an announcement
+func (r *AnnouncementRepository) ListPosts(ctx context.Context, announcementID string) ([]*models.AnnouncementPost, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListAnnouncementPosts"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("AnnouncementIndex"),
+		KeyConditionExpression: stringPtr("announcement_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: announcementID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query announcement posts: %w", err)
+	}
+
+	var posts []*models.AnnouncementPost
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &posts); err != nil {
+		return nil, fmt.Errorf("unmarshal announcement posts: %w", err)
+	}
+
+	return posts, nil
+}
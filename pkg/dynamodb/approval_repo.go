@@ -0,0 +1,159 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrApprovalChanged is returned when another vote was recorded first
+var ErrApprovalChanged = errors.New("approval request changed")
+
+// ApprovalRepository handles DynamoDB operations for approval requests
+type ApprovalRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewApprovalRepository creates a new approval repository
+func NewApprovalRepository(client *dynamodb.Client, tableName string) *ApprovalRepository {
+	return &ApprovalRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *ApprovalRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Create stores a new approval request
+func (r *ApprovalRepository) Create(ctx context.Context, req *models.ApprovalRequest) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "CreateApproval"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(req)
+	if err != nil {
+		return fmt.Errorf("marshal approval: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(approval_id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("put approval: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns an approval request, or nil when it doesn't exist
+func (r *ApprovalRepository) Get(ctx context.Context, approvalID string) (*models.ApprovalRequest, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetApproval"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"approval_id": &types.AttributeValueMemberS{Value: approvalID},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get approval: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var req models.ApprovalRequest
+	if err := attributevalue.UnmarshalMap(result.Item, &req); err != nil {
+		return nil, fmt.Errorf("unmarshal approval: %w", err)
+	}
+
+	return &req, nil
+}
+
+// Update saves votes and status, provided no one else has voted since the
+// request was read with priorVotes votes. Concurrent clicks otherwise could
+// both count toward quorum from the same snapshot
+func (r *ApprovalRepository) Update(ctx context.Context, req *models.ApprovalRequest, priorVotes int) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateApproval"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(req)
+	if err != nil {
+		return fmt.Errorf("marshal approval: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("#status = :pending AND size(votes) = :prior"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: models.ApprovalPending},
+			":prior":   &types.AttributeValueMemberN{Value: strconv.Itoa(priorVotes)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrApprovalChanged
+		}
+		return fmt.Errorf("update approval: %w", err)
+	}
+
+	return nil
+}
+
+// ListBetween returns approval requests created in [start, end). Used for
+// compliance exports
+func (r *ApprovalRepository) ListBetween(ctx context.Context, start, end time.Time) ([]*models.ApprovalRequest, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListApprovals"); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        &r.tableName,
+		FilterExpression: stringPtr("created_at >= :start AND created_at < :end"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":start": &types.AttributeValueMemberS{Value: start.UTC().Format(time.RFC3339Nano)},
+			":end":   &types.AttributeValueMemberS{Value: end.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+
+	var out []*models.ApprovalRequest
+	paginator := dynamodb.NewScanPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scan approvals: %w", err)
+		}
+
+		var batch []*models.ApprovalRequest
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal approvals: %w", err)
+		}
+		out = append(out, batch...)
+	}
+
+	return out, nil
+}
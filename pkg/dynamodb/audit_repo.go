@@ -0,0 +1,218 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// AuditRepository handles DynamoDB operations for the audit log
+type AuditRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	toolTable string // tool executions, kept apart from the low-volume admin actions
+	faults    *chaos.Injector
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(client *dynamodb.Client, tableName string) *AuditRepository {
+	return &AuditRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *AuditRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// SetToolTable sets the table tool executions are recorded in
+func (r *AuditRepository) SetToolTable(tableName string) {
+	r.toolTable = tableName
+}
+
+// Record appends an event to the audit log
+func (r *AuditRepository) Record(ctx context.Context, event *models.AuditEvent) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RecordAudit"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return fmt.Errorf("marshal audit event: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(event_id)"), // append-only
+	})
+	if err != nil {
+		return fmt.Errorf("put audit event: %w", err)
+	}
+
+	return nil
+}
+
+// ListBetween returns audit events created in [start, end). The audit log is
+// only read for compliance exports, so a filtered scan is enough
+func (r *AuditRepository) ListBetween(ctx context.Context, start, end time.Time) ([]*models.AuditEvent, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListAudit"); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.ScanInput{
+		TableName:        &r.tableName,
+		FilterExpression: stringPtr("created_at >= :start AND created_at < :end"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":start": &types.AttributeValueMemberS{Value: start.UTC().Format(time.RFC3339Nano)},
+			":end":   &types.AttributeValueMemberS{Value: end.UTC().Format(time.RFC3339Nano)},
+		},
+	}
+
+	var out []*models.AuditEvent
+	paginator := dynamodb.NewScanPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit events: %w", err)
+		}
+
+		var batch []*models.AuditEvent
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal audit events: %w", err)
+		}
+		out = append(out, batch...)
+	}
+
+	return out, nil
+}
+
+// RecordToolExecution appends a tool execution to the tool audit log
+func (r *AuditRepository) RecordToolExecution(ctx context.Context, execution *models.ToolExecution) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RecordToolExecution"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(execution)
+	if err != nil {
+		return fmt.Errorf("marshal tool execution: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.toolTable,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(execution_id)"), // append-only
+	})
+	if err != nil {
+		return fmt.Errorf("put tool execution: %w", err)
+	}
+
+	return nil
+}
+
+// ListToolExecutions returns the tool executions started in [start, end),
+// only those on behalf of userID unless it is empty. A user's executions
+// come from UserIndex; the whole range is a filtered scan, as it is only
+// read for compliance reviews and usage reports
+func (r *AuditRepository) ListToolExecutions(ctx context.Context, userID string, start, end time.Time) ([]*models.ToolExecution, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListToolExecutions"); err != nil {
+		return nil, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":start": &types.AttributeValueMemberS{Value: start.UTC().Format(time.RFC3339Nano)},
+		":end":   &types.AttributeValueMemberS{Value: end.UTC().Format(time.RFC3339Nano)},
+	}
+
+	var out []*models.ToolExecution
+	collect := func(items []map[string]types.AttributeValue) error {
+		var batch []*models.ToolExecution
+		if err := attributevalue.UnmarshalListOfMaps(items, &batch); err != nil {
+			return fmt.Errorf("unmarshal tool executions: %w", err)
+		}
+		for _, e := range batch {
+			// BETWEEN includes the end, which the range doesn't
+			if e.StartedAt.Before(end) {
+				out = append(out, e)
+			}
+		}
+		return nil
+	}
+
+	if userID != "" {
+		values[":user_id"] = &types.AttributeValueMemberS{Value: userID}
+		paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+			TableName:                 &r.toolTable,
+			IndexName:                 stringPtr("UserIndex"),
+			KeyConditionExpression:    stringPtr("user_id = :user_id AND started_at BETWEEN :start AND :end"),
+			ExpressionAttributeValues: values,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("query tool executions by user: %w", err)
+			}
+			if err := collect(page.Items); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{
+		TableName:                 &r.toolTable,
+		FilterExpression:          stringPtr("started_at >= :start AND started_at < :end"),
+		ExpressionAttributeValues: values,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scan tool executions: %w", err)
+		}
+		if err := collect(page.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// ListConversationToolExecutions returns the tool calls made in a
+// conversation, oldest first, from ConversationIndex
+func (r *AuditRepository) ListConversationToolExecutions(ctx context.Context, conversationID string) ([]*models.ToolExecution, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListConversationToolExecutions"); err != nil {
+		return nil, err
+	}
+
+	var out []*models.ToolExecution
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.toolTable,
+		IndexName:              stringPtr("ConversationIndex"),
+		KeyConditionExpression: stringPtr("conversation_id = :conversation_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query tool executions by conversation: %w", err)
+		}
+
+		var batch []*models.ToolExecution
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal tool executions: %w", err)
+		}
+		out = append(out, batch...)
+	}
+
+	return out, nil
+}
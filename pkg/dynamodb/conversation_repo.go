@@ -2,76 +2,125 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/logging"
 	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/storage"
 )
 
+// Errors the repository returns, defined by storage so callers need not
+// depend on DynamoDB to check for them
+var (
+	ErrConversationMoved = storage.ErrConversationMoved
+	ErrAlreadyEscalated  = storage.ErrAlreadyEscalated
+	ErrNotMergeable      = storage.ErrNotMergeable
+)
+
+var _ storage.Store = (*ConversationRepository)(nil)
+
 // ConversationRepository handles DynamoDB operations for conversations
 type ConversationRepository struct {
-	client    *dynamodb.Client
-	tableName string
+	client       *dynamodb.Client
+	tableName    string
+	historyTable string
+	faults       *chaos.Injector
+	shadow       *shadowHistory
 }
 
 // NewConversationRepository creates a new conversation repository
 func NewConversationRepository(client *dynamodb.Client, tableName string) *ConversationRepository {
 	return &ConversationRepository{
-		client:    client,
-		tableName: tableName,
+		client:       client,
+		tableName:    tableName,
+		historyTable: tableName + "-history",
+	}
+}
+
+// SetHistoryTable overrides the message history table name
+// (defaults to the conversations table name with a "-history" suffix)
+func (r *ConversationRepository) SetHistoryTable(tableName string) {
+	if tableName != "" {
+		r.historyTable = tableName
 	}
 }
 
-// Save stores a conversation record in DynamoDB
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *ConversationRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Save stores a conversation record in DynamoDB. It fails with
+// ErrConditionalCheckFailed when the record changed since conv was read,
+// and otherwise bumps conv's version
 func (r *ConversationRepository) Save(ctx context.Context, conv *models.Conversation) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "Save"); err != nil {
+		return err
+	}
+	if r.skipWrite("Save", conv.ConversationID) {
+		return nil
+	}
+
+	expected := conv.Version
+	conv.Version++
 	item, err := attributevalue.MarshalMap(conv)
 	if err != nil {
+		conv.Version = expected
 		return fmt.Errorf("marshal conversation: %w", err)
 	}
 
+	// A conversation read as version 0 is new, or was written before
+	// versions were kept
+	condition := "version = :expected"
+	values := map[string]types.AttributeValue{
+		":expected": &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)},
+	}
+	if expected == 0 {
+		condition = "attribute_not_exists(conversation_id) OR attribute_not_exists(version)"
+		values = nil
+	}
+
 	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: &r.tableName,
-		Item:      item,
+		TableName:                 &r.tableName,
+		Item:                      item,
+		ConditionExpression:       &condition,
+		ExpressionAttributeValues: values,
 	})
 	if err != nil {
+		conv.Version = expected
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return fmt.Errorf("save conversation %s: %w", conv.ConversationID, ErrConditionalCheckFailed)
+		}
 		return fmt.Errorf("put item: %w", err)
 	}
 
-	log.Printf("Saved conversation %s to DynamoDB", conv.ConversationID)
+	slog.DebugContext(ctx, "saved conversation", logging.ConversationID, conv.ConversationID)
 	return nil
 }
 
 // GetByID retrieves a conversation by ID
 func (r *ConversationRepository) GetByID(ctx context.Context, conversationID string) (*models.Conversation, error) {
-	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: &r.tableName,
-		Key: map[string]types.AttributeValue{
-			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("get item: %w", err)
-	}
-
-	if result.Item == nil {
-		return nil, fmt.Errorf("conversation not found: %s", conversationID)
-	}
-
-	var conv models.Conversation
-	err = attributevalue.UnmarshalMap(result.Item, &conv)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal conversation: %w", err)
-	}
-
-	return &conv, nil
+	return r.get(ctx, conversationID, false)
 }
 
 // UpdateStatus updates the conversation status
 func (r *ConversationRepository) UpdateStatus(ctx context.Context, conversationID string, status string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateStatus"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateStatus", conversationID) {
+		return nil
+	}
+
 	updateExpr := "SET #status = :status"
 	exprAttrNames := map[string]string{
 		"#status": "status",
@@ -88,7 +137,7 @@ func (r *ConversationRepository) UpdateStatus(ctx context.Context, conversationI
 		}
 	}
 
-	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: &r.tableName,
 		Key: map[string]types.AttributeValue{
 			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
@@ -101,14 +150,21 @@ func (r *ConversationRepository) UpdateStatus(ctx context.Context, conversationI
 		return fmt.Errorf("update item: %w", err)
 	}
 
-	log.Printf("Updated conversation %s status to %s", conversationID, status)
+	slog.InfoContext(ctx, "updated conversation status", logging.ConversationID, conversationID, "status", status)
 	return nil
 }
 
 // UpdateHeartbeat updates the last activity timestamp
 func (r *ConversationRepository) UpdateHeartbeat(ctx context.Context, conversationID string, timestamp time.Time) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateHeartbeat"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateHeartbeat", conversationID) {
+		return nil
+	}
+
 	updateExpr := "SET last_heartbeat = :now"
-	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: &r.tableName,
 		Key: map[string]types.AttributeValue{
 			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
@@ -125,37 +181,291 @@ func (r *ConversationRepository) UpdateHeartbeat(ctx context.Context, conversati
 	return nil
 }
 
-// GetByChannelID retrieves the most recent active conversation for a specific Slack channel
+// UpdateTaskArn records the ECS task running a conversation's agent
+func (r *ConversationRepository) UpdateTaskArn(ctx context.Context, conversationID, taskArn string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateTaskArn"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateTaskArn", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET task_arn = :arn"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":arn": &types.AttributeValueMemberS{Value: taskArn},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update task arn: %w", err)
+	}
+
+	return nil
+}
+
+// TimeOut marks a pending or active conversation timed out, provided its
+// last heartbeat is still before staleBefore. It reports false when the
+// agent recorded a heartbeat or ended the conversation in the meantime
+func (r *ConversationRepository) TimeOut(ctx context.Context, conversationID string, staleBefore time.Time) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "TimeOut"); err != nil {
+		return false, err
+	}
+	if r.skipWrite("TimeOut", conversationID) {
+		return false, nil
+	}
+
+	updateExpr := "SET #status = :timeout, completed_at = :now"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("#status IN (:pending, :active) AND last_heartbeat < :before"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":timeout": &types.AttributeValueMemberS{Value: models.StatusTimeout},
+			":pending": &types.AttributeValueMemberS{Value: models.StatusPending},
+			":active":  &types.AttributeValueMemberS{Value: models.StatusActive},
+			":before":  &types.AttributeValueMemberS{Value: staleBefore.Format(time.RFC3339)},
+			":now":     &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("time out conversation: %w", err)
+	}
+
+	slog.InfoContext(ctx, "updated conversation status", logging.ConversationID, conversationID, "status", models.StatusTimeout)
+	return true, nil
+}
+
+// UpdateScratchpad replaces the agent's working memory for a conversation
+func (r *ConversationRepository) UpdateScratchpad(ctx context.Context, conversationID string, scratchpad *models.Scratchpad) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateScratchpad"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateScratchpad", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(scratchpad)
+	if err != nil {
+		return fmt.Errorf("marshal scratchpad: %w", err)
+	}
+
+	updateExpr := "SET scratchpad = :scratchpad"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":scratchpad": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update scratchpad: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEntities replaces the AWS resources referenced in a conversation
+func (r *ConversationRepository) UpdateEntities(ctx context.Context, conversationID string, entities []models.Entity) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateEntities"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateEntities", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(entities)
+	if err != nil {
+		return fmt.Errorf("marshal entities: %w", err)
+	}
+
+	updateExpr := "SET entities = :entities"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":entities": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update entities: %w", err)
+	}
+
+	return nil
+}
+
+// AddRelated links a conversation to one that may be about the same incident
+func (r *ConversationRepository) AddRelated(ctx context.Context, conversationID, relatedID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AddRelated"); err != nil {
+		return err
+	}
+	if r.skipWrite("AddRelated", conversationID) {
+		return nil
+	}
+
+	updateExpr := "ADD related :related"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":related": &types.AttributeValueMemberSS{Value: []string{relatedID}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add related conversation: %w", err)
+	}
+
+	return nil
+}
+
+// MergeInto marks a conversation completed and merged into intoID. It
+// fails with ErrNotMergeable when the conversation already ended
+func (r *ConversationRepository) MergeInto(ctx context.Context, conversationID, intoID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "MergeInto"); err != nil {
+		return err
+	}
+	if r.skipWrite("MergeInto", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET #status = :completed, merged_into = :into, completed_at = :now"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("#status IN (:pending, :active) AND attribute_not_exists(merged_into)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":completed": &types.AttributeValueMemberS{Value: models.StatusCompleted},
+			":pending":   &types.AttributeValueMemberS{Value: models.StatusPending},
+			":active":    &types.AttributeValueMemberS{Value: models.StatusActive},
+			":into":      &types.AttributeValueMemberS{Value: intoID},
+			":now":       &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrNotMergeable
+		}
+		return fmt.Errorf("merge conversation: %w", err)
+	}
+
+	return nil
+}
+
+// GetByChannelID retrieves the most recent conversation that has a Slack
+// channel to itself, passing over those confined to a thread in it
 func (r *ConversationRepository) GetByChannelID(ctx context.Context, channelID string) (*models.Conversation, error) {
-	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetByChannelID"); err != nil {
+		return nil, err
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
 		TableName:              &r.tableName,
 		IndexName:              stringPtr("ChannelIndex"),
 		KeyConditionExpression: stringPtr("channel_id = :channelId"),
+		FilterExpression:       stringPtr("attribute_not_exists(thread_ts)"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":channelId": &types.AttributeValueMemberS{Value: channelID},
 		},
 		ScanIndexForward: boolPtr(false), // Most recent first
-		Limit:            int32Ptr(1),    // Only need the latest
+		Limit:            int32Ptr(10),   // Usually only the latest is needed
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query by channel: %w", err)
+		}
+		if len(page.Items) == 0 {
+			continue
+		}
+
+		var conv models.Conversation
+		if err := attributevalue.UnmarshalMap(page.Items[0], &conv); err != nil {
+			return nil, fmt.Errorf("unmarshal conversation: %w", err)
+		}
+		return &conv, nil
+	}
+
+	return nil, fmt.Errorf("no conversation found for channel %s", channelID)
+}
+
+// GetByThread retrieves the conversation confined to a thread
+func (r *ConversationRepository) GetByThread(ctx context.Context, channelID, threadTS string) (*models.Conversation, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetByThread"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("ThreadIndex"),
+		KeyConditionExpression: stringPtr("thread_key = :threadKey"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":threadKey": &types.AttributeValueMemberS{Value: models.ThreadKey(channelID, threadTS)},
+		},
+		Limit: int32Ptr(1),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("query by channel: %w", err)
+		return nil, fmt.Errorf("query by thread: %w", err)
 	}
 
 	if len(result.Items) == 0 {
-		return nil, fmt.Errorf("no conversation found for channel %s", channelID)
+		return nil, fmt.Errorf("no conversation found for thread %s in channel %s", threadTS, channelID)
 	}
 
 	var conv models.Conversation
-	err = attributevalue.UnmarshalMap(result.Items[0], &conv)
-	if err != nil {
+	if err := attributevalue.UnmarshalMap(result.Items[0], &conv); err != nil {
 		return nil, fmt.Errorf("unmarshal conversation: %w", err)
 	}
 
 	return &conv, nil
 }
 
+// GetByMessage retrieves the conversation a message belongs to: the one
+// confined to its thread when there is one, otherwise the channel's
+func (r *ConversationRepository) GetByMessage(ctx context.Context, channelID, threadTS string) (*models.Conversation, error) {
+	if threadTS != "" {
+		if conv, err := r.GetByThread(ctx, channelID, threadTS); err == nil {
+			return conv, nil
+		}
+	}
+	return r.GetByChannelID(ctx, channelID)
+}
+
 // GetByStatus retrieves conversations with a specific status
 func (r *ConversationRepository) GetByStatus(ctx context.Context, status string) ([]*models.Conversation, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetByStatus"); err != nil {
+		return nil, err
+	}
+
 	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
 		TableName:              &r.tableName,
 		IndexName:              stringPtr("StatusIndex"),
@@ -180,8 +490,53 @@ func (r *ConversationRepository) GetByStatus(ctx context.Context, status string)
 	return conversations, nil
 }
 
+// GetByStatusSince retrieves conversations with a status created at or after since
+func (r *ConversationRepository) GetByStatusSince(ctx context.Context, status string, since time.Time) ([]*models.Conversation, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetByStatusSince"); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("StatusIndex"),
+		KeyConditionExpression: stringPtr("#status = :status AND created_at >= :since"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+			":since":  &types.AttributeValueMemberS{Value: since.Format(time.RFC3339)},
+		},
+	}
+
+	var conversations []*models.Conversation
+	paginator := dynamodb.NewQueryPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query by status: %w", err)
+		}
+
+		var batch []*models.Conversation
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal conversations: %w", err)
+		}
+		conversations = append(conversations, batch...)
+	}
+
+	return conversations, nil
+}
+
 // SaveMessage stores a message in the conversation history
 func (r *ConversationRepository) SaveMessage(ctx context.Context, conversationID, role, content string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveMessage"); err != nil {
+		return err
+	}
+	if r.shadow != nil {
+		r.shadow.save(conversationID, role, content)
+		return nil
+	}
+
 	// Get current message count to determine index
 	messages, _ := r.GetMessageHistory(ctx, conversationID)
 	messageIndex := len(messages)
@@ -201,21 +556,87 @@ func (r *ConversationRepository) SaveMessage(ctx context.Context, conversationID
 	}
 
 	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: stringPtr(r.tableName + "-history"),
+		TableName: &r.historyTable,
 		Item:      item,
 	})
 	if err != nil {
 		return fmt.Errorf("put message: %w", err)
 	}
 
-	log.Printf("Saved message %d for conversation %s", messageIndex, conversationID)
+	slog.DebugContext(ctx, "saved message", "message_index", messageIndex, logging.ConversationID, conversationID)
+	return nil
+}
+
+// ReplaceHistory overwrites a conversation's history with items, which
+// must be at least as many as it had, and drops its rolling summary since
+// the messages it covered have moved
+func (r *ConversationRepository) ReplaceHistory(ctx context.Context, conversationID string, items []models.ConversationHistoryItem) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ReplaceHistory"); err != nil {
+		return err
+	}
+	if r.skipWrite("ReplaceHistory", conversationID) {
+		return nil
+	}
+
+	for _, historyItem := range items {
+		item, err := attributevalue.MarshalMap(historyItem)
+		if err != nil {
+			return fmt.Errorf("marshal message: %w", err)
+		}
+		if _, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: &r.historyTable,
+			Item:      item,
+		}); err != nil {
+			return fmt.Errorf("put message: %w", err)
+		}
+	}
+
+	updateExpr := "REMOVE summary, summarized"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+	})
+	if err != nil {
+		return fmt.Errorf("clear summary: %w", err)
+	}
+
 	return nil
 }
 
 // GetMessageHistory retrieves conversation history for a conversation
 func (r *ConversationRepository) GetMessageHistory(ctx context.Context, conversationID string) ([]models.Message, error) {
+	items, err := r.GetHistoryItems(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to Message array (without pointers)
+	messages := make([]models.Message, len(items))
+	for i, item := range items {
+		messages[i] = models.Message{
+			Role:    item.Role,
+			Content: item.Content,
+		}
+	}
+
+	return messages, nil
+}
+
+// GetHistoryItems retrieves the stored history items, including timestamps,
+// for a conversation in order
+func (r *ConversationRepository) GetHistoryItems(ctx context.Context, conversationID string) ([]models.ConversationHistoryItem, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetHistoryItems"); err != nil {
+		return nil, err
+	}
+	if r.shadow != nil {
+		return r.shadow.items(conversationID), nil
+	}
+
 	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
-		TableName:              stringPtr(r.tableName + "-history"),
+		TableName:              &r.historyTable,
 		KeyConditionExpression: stringPtr("conversation_id = :convId"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
 			":convId": &types.AttributeValueMemberS{Value: conversationID},
@@ -232,16 +653,382 @@ func (r *ConversationRepository) GetMessageHistory(ctx context.Context, conversa
 		return nil, fmt.Errorf("unmarshal messages: %w", err)
 	}
 
-	// Convert to Message array (without pointers)
-	messages := make([]models.Message, len(items))
-	for i, item := range items {
-		messages[i] = models.Message{
-			Role:    item.Role,
-			Content: item.Content,
+	return items, nil
+}
+
+// UpdateParticipants replaces the list of users who took part in a conversation
+func (r *ConversationRepository) UpdateParticipants(ctx context.Context, conversationID string, participants []string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateParticipants"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateParticipants", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(participants)
+	if err != nil {
+		return fmt.Errorf("marshal participants: %w", err)
+	}
+
+	updateExpr := "SET participants = :participants"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":participants": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update participants: %w", err)
+	}
+
+	return nil
+}
+
+// SetDebug turns debug mode on or off for a conversation
+func (r *ConversationRepository) SetDebug(ctx context.Context, conversationID string, on bool) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SetDebug"); err != nil {
+		return err
+	}
+	if r.skipWrite("SetDebug", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET debug = :debug"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":debug": &types.AttributeValueMemberBOOL{Value: on},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("set debug: %w", err)
+	}
+
+	return nil
+}
+
+// Escalate marks a conversation as handed to experts by userID. It fails
+// with ErrAlreadyEscalated when someone already escalated it
+func (r *ConversationRepository) Escalate(ctx context.Context, conversationID, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "Escalate"); err != nil {
+		return err
+	}
+	if r.skipWrite("Escalate", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET human_assisted = :on, escalated_by = :by, escalated_at = :now"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("attribute_not_exists(human_assisted) OR human_assisted = :off"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":on":  &types.AttributeValueMemberBOOL{Value: true},
+			":off": &types.AttributeValueMemberBOOL{Value: false},
+			":by":  &types.AttributeValueMemberS{Value: userID},
+			":now": &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrAlreadyEscalated
 		}
+		return fmt.Errorf("escalate conversation: %w", err)
 	}
 
-	return messages, nil
+	return nil
+}
+
+// UpdateTags replaces the tags on a conversation
+func (r *ConversationRepository) UpdateTags(ctx context.Context, conversationID string, tags []string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateTags"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateTags", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("marshal tags: %w", err)
+	}
+
+	updateExpr := "SET tags = :tags"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tags": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update tags: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEmbedding stores the embedding of a conversation's initial command
+func (r *ConversationRepository) UpdateEmbedding(ctx context.Context, conversationID string, embedding []float32) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateEmbedding"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateEmbedding", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(embedding)
+	if err != nil {
+		return fmt.Errorf("marshal embedding: %w", err)
+	}
+
+	updateExpr := "SET embedding = :embedding"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":embedding": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update embedding: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePromptVersion records which system prompt version a conversation uses
+func (r *ConversationRepository) UpdatePromptVersion(ctx context.Context, conversationID string, version int) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdatePromptVersion"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdatePromptVersion", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET prompt_version = :version"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update prompt version: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSummary saves the rolling summary of a conversation's first
+// summarized history messages. A summary covering fewer messages than the
+// saved one is ignored
+func (r *ConversationRepository) UpdateSummary(ctx context.Context, conversationID, summary string, summarized int) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateSummary"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateSummary", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET summary = :summary, summarized = :summarized"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("attribute_not_exists(summarized) OR summarized < :summarized"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":summary":    &types.AttributeValueMemberS{Value: summary},
+			":summarized": &types.AttributeValueMemberN{Value: strconv.Itoa(summarized)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("update summary: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateCostCenter records the team a conversation's usage is charged to
+func (r *ConversationRepository) UpdateCostCenter(ctx context.Context, conversationID, costCenter string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateCostCenter"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateCostCenter", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET cost_center = :cost_center"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":cost_center": &types.AttributeValueMemberS{Value: costCenter},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update cost center: %w", err)
+	}
+
+	return nil
+}
+
+// AddTokens adds the model tokens a turn used to a conversation's totals
+func (r *ConversationRepository) AddTokens(ctx context.Context, conversationID string, input, output int64) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AddTokens"); err != nil {
+		return err
+	}
+	if r.skipWrite("AddTokens", conversationID) {
+		return nil
+	}
+
+	updateExpr := "ADD input_tokens :input, output_tokens :output"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":input":  &types.AttributeValueMemberN{Value: strconv.FormatInt(input, 10)},
+			":output": &types.AttributeValueMemberN{Value: strconv.FormatInt(output, 10)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add tokens: %w", err)
+	}
+
+	return nil
+}
+
+// SaveCheckpoint records where an interrupted conversation resumes: the
+// last Slack message it finished handling
+func (r *ConversationRepository) SaveCheckpoint(ctx context.Context, conversationID, resumeTS string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveCheckpoint"); err != nil {
+		return err
+	}
+	if r.skipWrite("SaveCheckpoint", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET resume_ts = :resume_ts ADD interruptions :one"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":resume_ts": &types.AttributeValueMemberS{Value: resumeTS},
+			":one":       &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// Transfer moves a conversation from one channel to another, remembering
+// the channel it started in and taking the new one's visibility. A
+// conversation confined to a thread has the new channel to itself. It fails
+// with ErrConversationMoved when the conversation is no longer in
+// fromChannelID
+func (r *ConversationRepository) Transfer(ctx context.Context, conversationID, fromChannelID, toChannelID, visibility string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "Transfer"); err != nil {
+		return err
+	}
+	if r.skipWrite("Transfer", conversationID) {
+		return nil
+	}
+
+	updateExpr := "SET channel_id = :to, origin_channel_id = if_not_exists(origin_channel_id, :from), visibility = :visibility REMOVE thread_ts, thread_key"
+	_, err := r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("channel_id = :from"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":from":       &types.AttributeValueMemberS{Value: fromChannelID},
+			":to":         &types.AttributeValueMemberS{Value: toChannelID},
+			":visibility": &types.AttributeValueMemberS{Value: visibility},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrConversationMoved
+		}
+		return fmt.Errorf("transfer conversation: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSLA replaces the SLA timers on a conversation
+func (r *ConversationRepository) UpdateSLA(ctx context.Context, conversationID string, sla *models.SLA) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateSLA"); err != nil {
+		return err
+	}
+	if r.skipWrite("UpdateSLA", conversationID) {
+		return nil
+	}
+
+	value, err := attributevalue.Marshal(sla)
+	if err != nil {
+		return fmt.Errorf("marshal sla: %w", err)
+	}
+
+	updateExpr := "SET sla = :sla"
+	_, err = r.updateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":sla": value,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update sla: %w", err)
+	}
+
+	return nil
 }
 
 // Helper functions
@@ -0,0 +1,98 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// inboxTTL is how long an inbox outlives the last message delivered to it
+const inboxTTL = 24 * time.Hour
+
+// InboxRepository handles DynamoDB operations for conversation inboxes
+type InboxRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewInboxRepository creates a new inbox repository
+func NewInboxRepository(client *dynamodb.Client, tableName string) *InboxRepository {
+	return &InboxRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *InboxRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Deliver records a message posted to a conversation at ts. An older
+// timestamp, from an event delivered out of order, is ignored
+func (r *InboxRepository) Deliver(ctx context.Context, conversationID, ts string, now time.Time) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "DeliverInbox"); err != nil {
+		return err
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		UpdateExpression:    stringPtr("SET latest_ts = :ts, #ttl = :ttl"),
+		ConditionExpression: stringPtr("attribute_not_exists(latest_ts) OR latest_ts < :ts"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ts":  &types.AttributeValueMemberS{Value: ts},
+			":ttl": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(inboxTTL).Unix(), 10)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("deliver to inbox: %w", err)
+	}
+
+	return nil
+}
+
+// Latest returns the timestamp of the newest message delivered for a
+// conversation, or "" when none has been
+func (r *InboxRepository) Latest(ctx context.Context, conversationID string) (string, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetInbox"); err != nil {
+		return "", err
+	}
+
+	output, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("get inbox: %w", err)
+	}
+	if output.Item == nil {
+		return "", nil
+	}
+
+	var inbox models.Inbox
+	if err := attributevalue.UnmarshalMap(output.Item, &inbox); err != nil {
+		return "", fmt.Errorf("unmarshal inbox: %w", err)
+	}
+	return inbox.LatestTS, nil
+}
@@ -0,0 +1,216 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// JobRepository handles DynamoDB operations for background jobs
+type JobRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(client *dynamodb.Client, tableName string) *JobRepository {
+	return &JobRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *JobRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Create stores a new job
+func (r *JobRepository) Create(ctx context.Context, job *models.Job) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "CreateJob"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(job)
+	if err != nil {
+		return fmt.Errorf("marshal job: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(job_id)"),
+	})
+	if err != nil {
+		return fmt.Errorf("put job: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a job, or nil when it doesn't exist
+func (r *JobRepository) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetJob"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"job_id": &types.AttributeValueMemberS{Value: jobID},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get job: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var job models.Job
+	if err := attributevalue.UnmarshalMap(result.Item, &job); err != nil {
+		return nil, fmt.Errorf("unmarshal job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ListByConversation returns a conversation's jobs, newest first
+func (r *JobRepository) ListByConversation(ctx context.Context, conversationID string) ([]*models.Job, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListJobs"); err != nil {
+		return nil, err
+	}
+
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("ConversationIndex"),
+		KeyConditionExpression: stringPtr("conversation_id = :conversation_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		ScanIndexForward: boolPtr(false),
+	})
+
+	var jobs []*models.Job
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query jobs by conversation: %w", err)
+		}
+		var batch []*models.Job
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal jobs: %w", err)
+		}
+		jobs = append(jobs, batch...)
+	}
+
+	return jobs, nil
+}
+
+// Claim moves a queued job to running. It reports false when the job isn't
+// queued, because another worker claimed it or it was cancelled
+func (r *JobRepository) Claim(ctx context.Context, jobID string) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ClaimJob"); err != nil {
+		return false, err
+	}
+
+	return r.update(ctx, jobID, "claim job", &dynamodb.UpdateItemInput{
+		UpdateExpression:    stringPtr("SET #status = :running, updated_at = :now"),
+		ConditionExpression: stringPtr("#status = :queued"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":running": &types.AttributeValueMemberS{Value: models.JobRunning},
+			":queued":  &types.AttributeValueMemberS{Value: models.JobQueued},
+			":now":     &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339Nano)},
+		},
+	})
+}
+
+// UpdateProgress records how far a running job has got. It reports false
+// when the job is no longer running, which is how a worker learns it was
+// cancelled
+func (r *JobRepository) UpdateProgress(ctx context.Context, jobID string, done, total int, note string) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "UpdateJobProgress"); err != nil {
+		return false, err
+	}
+
+	return r.update(ctx, jobID, "update job progress", &dynamodb.UpdateItemInput{
+		UpdateExpression:    stringPtr("SET done = :done, #total = :total, note = :note, updated_at = :now"),
+		ConditionExpression: stringPtr("#status = :running"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#total":  "total",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":done":    &types.AttributeValueMemberN{Value: strconv.Itoa(done)},
+			":total":   &types.AttributeValueMemberN{Value: strconv.Itoa(total)},
+			":note":    &types.AttributeValueMemberS{Value: note},
+			":running": &types.AttributeValueMemberS{Value: models.JobRunning},
+			":now":     &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339Nano)},
+		},
+	})
+}
+
+// Finish records a job's final status, result and error. It reports false
+// when the job had already ended, e.g. it was cancelled while running
+func (r *JobRepository) Finish(ctx context.Context, job *models.Job) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "FinishJob"); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	job.UpdatedAt = now
+	job.CompletedAt = &now
+	return r.update(ctx, job.JobID, "finish job", &dynamodb.UpdateItemInput{
+		UpdateExpression:    stringPtr("SET #status = :status, #result = :result, #error = :error, cancelled_by = :cancelled_by, done = :done, #total = :total, updated_at = :now, completed_at = :now"),
+		ConditionExpression: stringPtr("#status IN (:queued, :running)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+			"#result": "result",
+			"#error":  "error",
+			"#total":  "total",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":       &types.AttributeValueMemberS{Value: job.Status},
+			":result":       &types.AttributeValueMemberS{Value: job.Result},
+			":error":        &types.AttributeValueMemberS{Value: job.Error},
+			":cancelled_by": &types.AttributeValueMemberS{Value: job.CancelledBy},
+			":done":         &types.AttributeValueMemberN{Value: strconv.Itoa(job.Done)},
+			":total":        &types.AttributeValueMemberN{Value: strconv.Itoa(job.Total)},
+			":queued":       &types.AttributeValueMemberS{Value: models.JobQueued},
+			":running":      &types.AttributeValueMemberS{Value: models.JobRunning},
+			":now":          &types.AttributeValueMemberS{Value: now.Format(time.RFC3339Nano)},
+		},
+	})
+}
+
+// update applies a conditional update to a job, reporting false when the
+// condition doesn't hold
+func (r *JobRepository) update(ctx context.Context, jobID, op string, input *dynamodb.UpdateItemInput) (bool, error) {
+	input.TableName = &r.tableName
+	input.Key = map[string]types.AttributeValue{
+		"job_id": &types.AttributeValueMemberS{Value: jobID},
+	}
+
+	if _, err := r.client.UpdateItem(ctx, input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,141 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// LockRepository handles DynamoDB operations for lease-based locks
+type LockRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewLockRepository creates a new lock repository
+func NewLockRepository(client *dynamodb.Client, tableName string) *LockRepository {
+	return &LockRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *LockRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// TryAcquire writes a lease unless another owner holds one that has not
+// expired yet
+func (r *LockRepository) TryAcquire(ctx context.Context, lockID, owner string, now, expires time.Time) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AcquireLock"); err != nil {
+		return false, err
+	}
+
+	item, err := attributevalue.MarshalMap(&models.Lease{
+		LockID:     lockID,
+		Owner:      owner,
+		AcquiredAt: now,
+		ExpiresAt:  expires.UnixMilli(),
+		TTL:        expires.Add(24 * time.Hour).Unix(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("marshal lease: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(lock_id) OR expires_at < :now OR #owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMilli(), 10)},
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("put lease: %w", err)
+	}
+
+	return true, nil
+}
+
+// Renew extends a lease that owner still holds
+func (r *LockRepository) Renew(ctx context.Context, lockID, owner string, expires time.Time) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RenewLock"); err != nil {
+		return false, err
+	}
+
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"lock_id": &types.AttributeValueMemberS{Value: lockID},
+		},
+		UpdateExpression:    stringPtr("SET expires_at = :expires, #ttl = :ttl"),
+		ConditionExpression: stringPtr("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+			"#ttl":   "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expires": &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.UnixMilli(), 10)},
+			":ttl":     &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.Add(24*time.Hour).Unix(), 10)},
+			":owner":   &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("renew lease: %w", err)
+	}
+
+	return true, nil
+}
+
+// Release deletes the lease if owner still holds it. A lease that was
+// already taken over is left alone
+func (r *LockRepository) Release(ctx context.Context, lockID, owner string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ReleaseLock"); err != nil {
+		return err
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"lock_id": &types.AttributeValueMemberS{Value: lockID},
+		},
+		ConditionExpression: stringPtr("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return nil
+		}
+		return fmt.Errorf("delete lease: %w", err)
+	}
+
+	return nil
+}
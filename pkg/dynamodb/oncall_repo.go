@@ -0,0 +1,73 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// OnCallRepository handles DynamoDB operations for the on-call rota
+type OnCallRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewOnCallRepository creates a new on-call rota repository
+func NewOnCallRepository(client *dynamodb.Client, tableName string) *OnCallRepository {
+	return &OnCallRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *OnCallRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// CurrentShift returns the team's shift covering the given time, or nil
+// when nobody is scheduled
+func (r *OnCallRepository) CurrentShift(ctx context.Context, team string, at time.Time) (*models.OnCallShift, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "CurrentShift"); err != nil {
+		return nil, err
+	}
+
+	// Shifts are keyed by start time; the latest one starting before now wins
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: stringPtr("team = :team AND #start <= :at"),
+		ExpressionAttributeNames: map[string]string{
+			"#start": "start",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":team": &types.AttributeValueMemberS{Value: team},
+			":at":   &types.AttributeValueMemberS{Value: at.UTC().Format(time.RFC3339)},
+		},
+		ScanIndexForward: boolPtr(false),
+		Limit:            int32Ptr(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query on-call shifts: %w", err)
+	}
+
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+
+	var shift models.OnCallShift
+	if err := attributevalue.UnmarshalMap(result.Items[0], &shift); err != nil {
+		return nil, fmt.Errorf("unmarshal shift: %w", err)
+	}
+
+	if !shift.Covers(at) {
+		return nil, nil
+	}
+	return &shift, nil
+}
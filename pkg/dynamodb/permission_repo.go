@@ -0,0 +1,125 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// PermissionRepository handles DynamoDB operations for user permission profiles
+type PermissionRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewPermissionRepository creates a new permission repository
+func NewPermissionRepository(client *dynamodb.Client, tableName string) *PermissionRepository {
+	return &PermissionRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *PermissionRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Get returns a user's permission profile, or nil when none is stored
+func (r *PermissionRepository) Get(ctx context.Context, userID string) (*models.PermissionProfile, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetPermission"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get permission: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var profile models.PermissionProfile
+	if err := attributevalue.UnmarshalMap(result.Item, &profile); err != nil {
+		return nil, fmt.Errorf("unmarshal permission: %w", err)
+	}
+
+	return &profile, nil
+}
+
+// Put stores a user's permission profile, replacing any previous one
+func (r *PermissionRepository) Put(ctx context.Context, profile *models.PermissionProfile) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "PutPermission"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(profile)
+	if err != nil {
+		return fmt.Errorf("marshal permission: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put permission: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a user's permission profile, returning them to read-only
+func (r *PermissionRepository) Delete(ctx context.Context, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "DeletePermission"); err != nil {
+		return err
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"user_id": &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("delete permission: %w", err)
+	}
+
+	return nil
+}
+
+// List returns every stored permission profile. The table holds one item
+// per privileged user, so a scan stays small
+func (r *PermissionRepository) List(ctx context.Context) ([]*models.PermissionProfile, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListPermissions"); err != nil {
+		return nil, err
+	}
+
+	var profiles []*models.PermissionProfile
+	paginator := dynamodb.NewScanPaginator(r.client, &dynamodb.ScanInput{TableName: &r.tableName})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scan permissions: %w", err)
+		}
+
+		var batch []*models.PermissionProfile
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal permissions: %w", err)
+		}
+		profiles = append(profiles, batch...)
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,142 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrPromptVersionExists is returned when another publish took the version
+// first
+var ErrPromptVersionExists = errors.New("prompt version already exists")
+
+// PromptRepository handles DynamoDB operations for versioned prompt templates
+type PromptRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewPromptRepository creates a new prompt repository
+func NewPromptRepository(client *dynamodb.Client, tableName string) *PromptRepository {
+	return &PromptRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *PromptRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Publish stores a template as the next version of its prompt
+func (r *PromptRepository) Publish(ctx context.Context, prompt *models.PromptTemplate) error {
+	latest, err := r.Latest(ctx, prompt.Name)
+	if err != nil {
+		return err
+	}
+	prompt.Version = 1
+	if latest != nil {
+		prompt.Version = latest.Version + 1
+	}
+
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "PublishPrompt"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(prompt)
+	if err != nil {
+		return fmt.Errorf("marshal prompt: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(version)"), // versions are immutable
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrPromptVersionExists
+		}
+		return fmt.Errorf("put prompt: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a specific version of a prompt, or nil when it doesn't exist
+func (r *PromptRepository) Get(ctx context.Context, name string, version int) (*models.PromptTemplate, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetPrompt"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"name":    &types.AttributeValueMemberS{Value: name},
+			"version": &types.AttributeValueMemberN{Value: strconv.Itoa(version)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get prompt: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var prompt models.PromptTemplate
+	if err := attributevalue.UnmarshalMap(result.Item, &prompt); err != nil {
+		return nil, fmt.Errorf("unmarshal prompt: %w", err)
+	}
+
+	return &prompt, nil
+}
+
+// Latest returns the newest version of a prompt, or nil when none has been
+// published
+func (r *PromptRepository) Latest(ctx context.Context, name string) (*models.PromptTemplate, error) {
+	prompts, err := r.List(ctx, name, 1)
+	if err != nil || len(prompts) == 0 {
+		return nil, err
+	}
+	return prompts[0], nil
+}
+
+// List returns up to limit versions of a prompt, newest first
+func (r *PromptRepository) List(ctx context.Context, name string, limit int) ([]*models.PromptTemplate, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListPrompts"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: stringPtr("#name = :name"),
+		ExpressionAttributeNames: map[string]string{
+			"#name": "name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":name": &types.AttributeValueMemberS{Value: name},
+		},
+		ScanIndexForward: boolPtr(false), // Newest first
+		Limit:            int32Ptr(int32(limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query prompts: %w", err)
+	}
+
+	var prompts []*models.PromptTemplate
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &prompts); err != nil {
+		return nil, fmt.Errorf("unmarshal prompts: %w", err)
+	}
+
+	return prompts, nil
+}
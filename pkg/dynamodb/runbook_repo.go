@@ -0,0 +1,176 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// RunbookRepository handles DynamoDB operations for playbook entries
+type RunbookRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewRunbookRepository creates a new runbook repository
+func NewRunbookRepository(client *dynamodb.Client, tableName string) *RunbookRepository {
+	return &RunbookRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *RunbookRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Save stores a runbook, replacing any previous version
+func (r *RunbookRepository) Save(ctx context.Context, runbook *models.Runbook) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveRunbook"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(runbook)
+	if err != nil {
+		return fmt.Errorf("marshal runbook: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put runbook: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns a runbook, or nil when it doesn't exist
+func (r *RunbookRepository) Get(ctx context.Context, runbookID string) (*models.Runbook, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetRunbook"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"runbook_id": &types.AttributeValueMemberS{Value: runbookID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get runbook: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var runbook models.Runbook
+	if err := attributevalue.UnmarshalMap(result.Item, &runbook); err != nil {
+		return nil, fmt.Errorf("unmarshal runbook: %w", err)
+	}
+
+	return &runbook, nil
+}
+
+// ListByStatus returns up to limit runbooks with a status, newest first
+func (r *RunbookRepository) ListByStatus(ctx context.Context, status string, limit int) ([]*models.Runbook, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListRunbooks"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("StatusIndex"),
+		KeyConditionExpression: stringPtr("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: status},
+		},
+		ScanIndexForward: boolPtr(false), // Newest first
+		Limit:            int32Ptr(int32(limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query runbooks: %w", err)
+	}
+
+	var runbooks []*models.Runbook
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &runbooks); err != nil {
+		return nil, fmt.Errorf("unmarshal runbooks: %w", err)
+	}
+
+	return runbooks, nil
+}
+
+// GetByConversation returns the newest runbook captured from a conversation,
+// or nil when none has been
+func (r *RunbookRepository) GetByConversation(ctx context.Context, conversationID string) (*models.Runbook, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetRunbookByConversation"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("ConversationIndex"),
+		KeyConditionExpression: stringPtr("conversation_id = :conversation_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		ScanIndexForward: boolPtr(false),
+		Limit:            int32Ptr(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query runbooks by conversation: %w", err)
+	}
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+
+	var runbook models.Runbook
+	if err := attributevalue.UnmarshalMap(result.Items[0], &runbook); err != nil {
+		return nil, fmt.Errorf("unmarshal runbook: %w", err)
+	}
+
+	return &runbook, nil
+}
+
+// Review publishes or discards a draft
+func (r *RunbookRepository) Review(ctx context.Context, runbook *models.Runbook, status, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ReviewRunbook"); err != nil {
+		return err
+	}
+
+	runbook.Review(status, userID)
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"runbook_id": &types.AttributeValueMemberS{Value: runbook.RunbookID},
+		},
+		UpdateExpression:    stringPtr("SET #status = :status, reviewed_by = :reviewed_by, reviewed_at = :reviewed_at"),
+		ConditionExpression: stringPtr("#status = :draft"), // only drafts are reviewed
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":      &types.AttributeValueMemberS{Value: status},
+			":draft":       &types.AttributeValueMemberS{Value: models.RunbookDraft},
+			":reviewed_by": &types.AttributeValueMemberS{Value: userID},
+			":reviewed_at": &types.AttributeValueMemberS{Value: runbook.ReviewedAt.Format(time.RFC3339Nano)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("review runbook: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// SettingsRepository handles DynamoDB operations for bot-wide settings
+type SettingsRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(client *dynamodb.Client, tableName string) *SettingsRepository {
+	return &SettingsRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *SettingsRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// GetKillSwitch returns the kill switch, or nil when it has never been set
+func (r *SettingsRepository) GetKillSwitch(ctx context.Context) (*models.KillSwitch, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetKillSwitch"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.KillSwitchID},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get kill switch: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var ks models.KillSwitch
+	if err := attributevalue.UnmarshalMap(result.Item, &ks); err != nil {
+		return nil, fmt.Errorf("unmarshal kill switch: %w", err)
+	}
+
+	return &ks, nil
+}
+
+// PutKillSwitch stores the kill switch
+func (r *SettingsRepository) PutKillSwitch(ctx context.Context, ks *models.KillSwitch) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "PutKillSwitch"); err != nil {
+		return err
+	}
+
+	ks.SettingID = models.KillSwitchID
+	item, err := attributevalue.MarshalMap(ks)
+	if err != nil {
+		return fmt.Errorf("marshal kill switch: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put kill switch: %w", err)
+	}
+
+	return nil
+}
+
+// CountDenial records an access denied error for an action on a resource
+// and returns how many have been recorded
+func (r *SettingsRepository) CountDenial(ctx context.Context, action, resource string) (int, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "CountDenial"); err != nil {
+		return 0, err
+	}
+
+	updateExpr := "SET last_denied_at = :now ADD denials :one"
+	result, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.DenialID(action, resource)},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+			":one": &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count denial: %w", err)
+	}
+
+	n, ok := result.Attributes["denials"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("count denial: missing count")
+	}
+	return strconv.Atoi(n.Value)
+}
+
+// ClaimDenialSuggestion marks the policy suggestion for an action on a
+// resource as posted. It returns false when it already was, so only one
+// agent posts it
+func (r *SettingsRepository) ClaimDenialSuggestion(ctx context.Context, action, resource string) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ClaimDenialSuggestion"); err != nil {
+		return false, err
+	}
+
+	updateExpr := "SET suggested_at = :now"
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.DenialID(action, resource)},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("attribute_not_exists(suggested_at)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim denial suggestion: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetSetup returns the first-run setup status, or nil when setup has never
+// been run
+func (r *SettingsRepository) GetSetup(ctx context.Context) (*models.SetupStatus, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetSetup"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.SetupID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get setup: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var status models.SetupStatus
+	if err := attributevalue.UnmarshalMap(result.Item, &status); err != nil {
+		return nil, fmt.Errorf("unmarshal setup: %w", err)
+	}
+
+	return &status, nil
+}
+
+// CompleteSetup records that userID saw every setup check pass
+func (r *SettingsRepository) CompleteSetup(ctx context.Context, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "CompleteSetup"); err != nil {
+		return err
+	}
+
+	updateExpr := "SET completed_by = :user, completed_at = :now"
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.SetupID},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user": &types.AttributeValueMemberS{Value: userID},
+			":now":  &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("complete setup: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimSetupPrompt marks admins as prompted to finish setup. It returns
+// false when they already were within interval, so a burst of failures
+// prompts them once
+func (r *SettingsRepository) ClaimSetupPrompt(ctx context.Context, interval time.Duration) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ClaimSetupPrompt"); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	updateExpr := "SET prompted_at = :now"
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.SetupID},
+		},
+		UpdateExpression:    &updateExpr,
+		ConditionExpression: stringPtr("attribute_not_exists(prompted_at) OR prompted_at < :cutoff"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now":    &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
+			":cutoff": &types.AttributeValueMemberS{Value: now.Add(-interval).Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim setup prompt: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetRBACPolicy returns the JSON tool access policy stored in the policy
+// attribute of the rbac_policy item, or "" when none is stored
+func (r *SettingsRepository) GetRBACPolicy(ctx context.Context) (string, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetRBACPolicy"); err != nil {
+		return "", err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"setting_id": &types.AttributeValueMemberS{Value: models.RBACPolicyID},
+		},
+		ProjectionExpression: stringPtr("policy"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("get rbac policy: %w", err)
+	}
+
+	policy, ok := result.Item["policy"].(*types.AttributeValueMemberS)
+	if !ok {
+		return "", nil
+	}
+	return policy.Value, nil
+}
@@ -0,0 +1,60 @@
+package dynamodb
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// shadowHistory holds the messages of a shadow agent's conversations in
+// memory, apart from the history the production agent writes
+type shadowHistory struct {
+	mu       sync.Mutex
+	messages map[string][]models.ConversationHistoryItem
+}
+
+// SetShadow makes the repository read-only, for an agent running in shadow
+// mode: conversation updates are logged instead of written, and message
+// history is kept in memory so the shadow sees only its own replies
+func (r *ConversationRepository) SetShadow(on bool) {
+	if !on {
+		r.shadow = nil
+		return
+	}
+	r.shadow = &shadowHistory{messages: map[string][]models.ConversationHistoryItem{}}
+}
+
+// skipWrite logs a write a shadow repository doesn't make, and reports
+// whether to skip it
+func (r *ConversationRepository) skipWrite(op, conversationID string) bool {
+	if r.shadow == nil {
+		return false
+	}
+	slog.Info("shadow: would call dynamodb", "op", op, logging.ConversationID, conversationID)
+	return true
+}
+
+func (h *shadowHistory) save(conversationID, role, content string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	items := h.messages[conversationID]
+	h.messages[conversationID] = append(items, models.ConversationHistoryItem{
+		ConversationID: conversationID,
+		MessageIndex:   len(items),
+		Role:           role,
+		Content:        content,
+		CreatedAt:      time.Now(),
+	})
+	slog.Info("shadow: saved message", "role", role, "message_index", len(items), logging.ConversationID, conversationID, "content", content)
+}
+
+func (h *shadowHistory) items(conversationID string) []models.ConversationHistoryItem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]models.ConversationHistoryItem(nil), h.messages[conversationID]...)
+}
@@ -0,0 +1,53 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// SLARepository handles DynamoDB operations for SLA outcomes
+type SLARepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewSLARepository creates a new SLA outcome repository
+func NewSLARepository(client *dynamodb.Client, tableName string) *SLARepository {
+	return &SLARepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *SLARepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// SaveOutcome records the SLA outcome of a resolved incident
+func (r *SLARepository) SaveOutcome(ctx context.Context, outcome *models.SLAOutcome) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveSLAOutcome"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(outcome)
+	if err != nil {
+		return fmt.Errorf("marshal sla outcome: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put sla outcome: %w", err)
+	}
+
+	return nil
+}
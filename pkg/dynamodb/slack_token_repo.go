@@ -0,0 +1,100 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrTokenChanged is returned when another process stored a newer token first
+var ErrTokenChanged = errors.New("slack token changed")
+
+// SlackTokenRepository handles DynamoDB operations for rotated Slack tokens
+type SlackTokenRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewSlackTokenRepository creates a new Slack token repository
+func NewSlackTokenRepository(client *dynamodb.Client, tableName string) *SlackTokenRepository {
+	return &SlackTokenRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *SlackTokenRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// GetToken returns the stored token, or nil when none has been stored
+func (r *SlackTokenRepository) GetToken(ctx context.Context, tokenID string) (*models.SlackToken, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetSlackToken"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"token_id": &types.AttributeValueMemberS{Value: tokenID},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get slack token: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var token models.SlackToken
+	if err := attributevalue.UnmarshalMap(result.Item, &token); err != nil {
+		return nil, fmt.Errorf("unmarshal slack token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// SaveToken stores a refreshed token, provided the stored one is still at
+// priorVersion (0 when none was stored). Returns ErrTokenChanged otherwise
+func (r *SlackTokenRepository) SaveToken(ctx context.Context, token *models.SlackToken, priorVersion int) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveSlackToken"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(token)
+	if err != nil {
+		return fmt.Errorf("marshal slack token: %w", err)
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           &r.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(token_id)"),
+	}
+	if priorVersion > 0 {
+		input.ConditionExpression = stringPtr("version = :prior")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":prior": &types.AttributeValueMemberN{Value: strconv.Itoa(priorVersion)},
+		}
+	}
+
+	if _, err := r.client.PutItem(ctx, input); err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return ErrTokenChanged
+		}
+		return fmt.Errorf("save slack token: %w", err)
+	}
+
+	return nil
+}
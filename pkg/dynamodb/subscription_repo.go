@@ -0,0 +1,99 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// SubscriptionRepository handles DynamoDB operations for conversation watchers
+type SubscriptionRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewSubscriptionRepository creates a new subscription repository
+func NewSubscriptionRepository(client *dynamodb.Client, tableName string) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *SubscriptionRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Save stores a subscription, replacing any existing one for the same user
+func (r *SubscriptionRepository) Save(ctx context.Context, sub *models.Subscription) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "SaveSubscription"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(sub)
+	if err != nil {
+		return fmt.Errorf("marshal subscription: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put subscription: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a user's subscription to a conversation
+func (r *SubscriptionRepository) Delete(ctx context.Context, conversationID, userID string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "DeleteSubscription"); err != nil {
+		return err
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+			"user_id":         &types.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("delete subscription: %w", err)
+	}
+
+	return nil
+}
+
+// ListForConversation returns everyone watching a conversation
+func (r *SubscriptionRepository) ListForConversation(ctx context.Context, conversationID string) ([]models.Subscription, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListSubscriptions"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: stringPtr("conversation_id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query subscriptions: %w", err)
+	}
+
+	var subs []models.Subscription
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &subs); err != nil {
+		return nil, fmt.Errorf("unmarshal subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
@@ -0,0 +1,102 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// TagRepository handles DynamoDB operations for conversation tags
+type TagRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(client *dynamodb.Client, tableName string) *TagRepository {
+	return &TagRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *TagRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Add indexes a conversation under a tag
+func (r *TagRepository) Add(ctx context.Context, tag *models.ConversationTag) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AddTag"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(tag)
+	if err != nil {
+		return fmt.Errorf("marshal tag: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put tag: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes a tag from a conversation
+func (r *TagRepository) Remove(ctx context.Context, conversationID, tag string) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RemoveTag"); err != nil {
+		return err
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+			"tag":             &types.AttributeValueMemberS{Value: tag},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("delete tag: %w", err)
+	}
+
+	return nil
+}
+
+// ListByTag returns the most recently tagged conversations for a tag
+func (r *TagRepository) ListByTag(ctx context.Context, tag string, limit int32) ([]models.ConversationTag, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListByTag"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("TagIndex"),
+		KeyConditionExpression: stringPtr("tag = :tag"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tag": &types.AttributeValueMemberS{Value: tag},
+		},
+		ScanIndexForward: boolPtr(false), // Most recent first
+		Limit:            int32Ptr(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query by tag: %w", err)
+	}
+
+	var tags []models.ConversationTag
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &tags); err != nil {
+		return nil, fmt.Errorf("unmarshal tags: %w", err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,103 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// UsageRepository handles DynamoDB operations for monthly usage per cost center
+type UsageRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(client *dynamodb.Client, tableName string) *UsageRepository {
+	return &UsageRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *UsageRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Add adds delta's counters to its cost center's record for the month,
+// creating the record if needed
+func (r *UsageRepository) Add(ctx context.Context, delta *models.UsageRecord) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "AddUsage"); err != nil {
+		return err
+	}
+
+	n := func(v int64) types.AttributeValue {
+		return &types.AttributeValueMemberN{Value: strconv.FormatInt(v, 10)}
+	}
+
+	updateExpr := "ADD conversations :conversations, input_tokens :input, output_tokens :output, tool_calls :tools, runtime_seconds :runtime"
+	_, err := r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"month":       &types.AttributeValueMemberS{Value: delta.Month},
+			"cost_center": &types.AttributeValueMemberS{Value: delta.CostCenter},
+		},
+		UpdateExpression: &updateExpr,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":conversations": n(delta.Conversations),
+			":input":         n(delta.InputTokens),
+			":output":        n(delta.OutputTokens),
+			":tools":         n(delta.ToolCalls),
+			":runtime":       n(delta.RuntimeSeconds),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add usage: %w", err)
+	}
+
+	return nil
+}
+
+// ListMonth returns every cost center's usage for a month (2006-01)
+func (r *UsageRepository) ListMonth(ctx context.Context, month string) ([]*models.UsageRecord, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListUsage"); err != nil {
+		return nil, err
+	}
+
+	keyCondition := "#month = :month"
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		KeyConditionExpression: &keyCondition,
+		ExpressionAttributeNames: map[string]string{
+			"#month": "month",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":month": &types.AttributeValueMemberS{Value: month},
+		},
+	})
+
+	var records []*models.UsageRecord
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list usage: %w", err)
+		}
+		for _, item := range page.Items {
+			var record models.UsageRecord
+			if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+				return nil, fmt.Errorf("unmarshal usage: %w", err)
+			}
+			records = append(records, &record)
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,95 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+// ErrConditionalCheckFailed is returned by Save when the conversation was
+// written by someone else since it was read. Read it again and reapply the
+// change, or use Modify, which does so
+var ErrConditionalCheckFailed = storage.ErrConditionalCheckFailed
+
+// modifyAttempts bounds how often Modify rereads a conversation that keeps
+// changing under it
+const modifyAttempts = 3
+
+// Modify reads a conversation, applies fn, and saves it, starting over
+// when another writer got there first. fn may run more than once and
+// should only change what it means to, since each run sees fresh state
+func (r *ConversationRepository) Modify(ctx context.Context, conversationID string, fn func(conv *models.Conversation) error) (*models.Conversation, error) {
+	var err error
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		var conv *models.Conversation
+		if conv, err = r.get(ctx, conversationID, true); err != nil {
+			return nil, err
+		}
+		if err := fn(conv); err != nil {
+			return nil, err
+		}
+		if err = r.Save(ctx, conv); !errors.Is(err, ErrConditionalCheckFailed) {
+			return conv, err
+		}
+	}
+	return nil, fmt.Errorf("modify conversation %s: %w", conversationID, err)
+}
+
+// get reads a conversation, strongly consistent when a write is to follow
+func (r *ConversationRepository) get(ctx context.Context, conversationID string, consistent bool) (*models.Conversation, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetByID"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"conversation_id": &types.AttributeValueMemberS{Value: conversationID},
+		},
+		ConsistentRead: &consistent,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get item: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+
+	var conv models.Conversation
+	err = attributevalue.UnmarshalMap(result.Item, &conv)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal conversation: %w", err)
+	}
+
+	return &conv, nil
+}
+
+// updateItem makes an update that also bumps the conversation's version,
+// so a Save of a copy read before it fails rather than undoing it
+func (r *ConversationRepository) updateItem(ctx context.Context, input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	expr := bumpVersion(*input.UpdateExpression)
+	input.UpdateExpression = &expr
+	if input.ExpressionAttributeValues == nil {
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{}
+	}
+	input.ExpressionAttributeValues[":version_step"] = &types.AttributeValueMemberN{Value: "1"}
+	return r.client.UpdateItem(ctx, input)
+}
+
+// bumpVersion adds the version increment to an update expression, joining
+// its ADD clause when it has one since each clause may appear only once
+func bumpVersion(expr string) string {
+	if i := strings.Index(expr, "ADD "); i >= 0 {
+		return expr[:i] + "ADD version :version_step, " + expr[i+len("ADD "):]
+	}
+	return expr + " ADD version :version_step"
+}
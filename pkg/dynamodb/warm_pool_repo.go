@@ -0,0 +1,220 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// WarmPoolRepository handles DynamoDB operations for idle agent registrations
+type WarmPoolRepository struct {
+	client    *dynamodb.Client
+	tableName string
+	faults    *chaos.Injector
+}
+
+// NewWarmPoolRepository creates a new warm pool repository
+func NewWarmPoolRepository(client *dynamodb.Client, tableName string) *WarmPoolRepository {
+	return &WarmPoolRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// SetFaultInjector enables artificial latency and errors for DynamoDB calls
+func (r *WarmPoolRepository) SetFaultInjector(faults *chaos.Injector) {
+	r.faults = faults
+}
+
+// Register adds an idle agent to the pool
+func (r *WarmPoolRepository) Register(ctx context.Context, agent *models.WarmAgent) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RegisterWarmAgent"); err != nil {
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(agent)
+	if err != nil {
+		return fmt.Errorf("marshal warm agent: %w", err)
+	}
+
+	_, err = r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &r.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("put warm agent: %w", err)
+	}
+
+	return nil
+}
+
+// Heartbeat records that an agent is still alive
+func (r *WarmPoolRepository) Heartbeat(ctx context.Context, agentID string, at time.Time) error {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "HeartbeatWarmAgent"); err != nil {
+		return err
+	}
+
+	heartbeat, err := attributevalue.Marshal(at)
+	if err != nil {
+		return fmt.Errorf("marshal heartbeat: %w", err)
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"agent_id": &types.AttributeValueMemberS{Value: agentID},
+		},
+		UpdateExpression:    stringPtr("SET heartbeat_at = :heartbeat"),
+		ConditionExpression: stringPtr("attribute_exists(agent_id)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":heartbeat": heartbeat,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("update heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns an agent registration, or nil when it no longer exists
+func (r *WarmPoolRepository) Get(ctx context.Context, agentID string) (*models.WarmAgent, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "GetWarmAgent"); err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"agent_id": &types.AttributeValueMemberS{Value: agentID},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get warm agent: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var agent models.WarmAgent
+	if err := attributevalue.UnmarshalMap(result.Item, &agent); err != nil {
+		return nil, fmt.Errorf("unmarshal warm agent: %w", err)
+	}
+
+	return &agent, nil
+}
+
+// ListIdle returns agents waiting for a conversation
+func (r *WarmPoolRepository) ListIdle(ctx context.Context) ([]*models.WarmAgent, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ListIdleWarmAgents"); err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              &r.tableName,
+		IndexName:              stringPtr("StatusIndex"),
+		KeyConditionExpression: stringPtr("#status = :status"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status": &types.AttributeValueMemberS{Value: models.WarmAgentIdle},
+		},
+	}
+
+	var agents []*models.WarmAgent
+	paginator := dynamodb.NewQueryPaginator(r.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("query idle agents: %w", err)
+		}
+
+		var batch []*models.WarmAgent
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &batch); err != nil {
+			return nil, fmt.Errorf("unmarshal warm agents: %w", err)
+		}
+		agents = append(agents, batch...)
+	}
+
+	return agents, nil
+}
+
+// TryClaim assigns a conversation to an agent if it is still idle. The
+// conditional update guarantees each agent takes exactly one conversation
+func (r *WarmPoolRepository) TryClaim(ctx context.Context, agentID, conversationID string, at time.Time) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "ClaimWarmAgent"); err != nil {
+		return false, err
+	}
+
+	claimedAt, err := attributevalue.Marshal(at)
+	if err != nil {
+		return false, fmt.Errorf("marshal claim time: %w", err)
+	}
+
+	_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"agent_id": &types.AttributeValueMemberS{Value: agentID},
+		},
+		UpdateExpression:    stringPtr("SET #status = :claimed, conversation_id = :conversation, claimed_at = :at"),
+		ConditionExpression: stringPtr("#status = :idle"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":claimed":      &types.AttributeValueMemberS{Value: models.WarmAgentClaimed},
+			":idle":         &types.AttributeValueMemberS{Value: models.WarmAgentIdle},
+			":conversation": &types.AttributeValueMemberS{Value: conversationID},
+			":at":           claimedAt,
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim warm agent: %w", err)
+	}
+
+	return true, nil
+}
+
+// RemoveIdle deregisters an agent unless a conversation was already
+// assigned to it
+func (r *WarmPoolRepository) RemoveIdle(ctx context.Context, agentID string) (bool, error) {
+	if err := r.faults.Inject(ctx, chaos.TargetDynamoDB, "RemoveWarmAgent"); err != nil {
+		return false, err
+	}
+
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: &r.tableName,
+		Key: map[string]types.AttributeValue{
+			"agent_id": &types.AttributeValueMemberS{Value: agentID},
+		},
+		ConditionExpression: stringPtr("attribute_not_exists(agent_id) OR #status = :idle"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":idle": &types.AttributeValueMemberS{Value: models.WarmAgentIdle},
+		},
+	})
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return false, nil
+		}
+		return false, fmt.Errorf("delete warm agent: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,149 @@
+package ensemble
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Agreement levels reported by the reconciling model
+const (
+	Agree     = "agree"
+	Partial   = "partial"
+	Disagree  = "disagree"
+	Unchecked = "unchecked" // the second model failed; only one answer was available
+)
+
+// Model answers a conversation; *bedrock.Client satisfies it
+type Model interface {
+	SendMessage(ctx context.Context, messages []models.Message, systemPrompt string) (string, error)
+}
+
+// reconcileInstructions asks the primary model to merge two drafts
+const reconcileInstructions = `Two independent assistants drafted replies to my last message. Don't mention the drafts or that there were two; reply to me directly.
+
+<draft_a>
+%s
+</draft_a>
+
+<draft_b>
+%s
+</draft_b>
+
+Write the single best reply. Where the drafts disagree on facts, likely causes, or recommended actions, resolve it if the conversation supports one side; otherwise say plainly what is uncertain and what would settle it. Never present a disputed conclusion as certain.
+Finish with one line rating how far the drafts agreed: <agreement>agree</agreement>, <agreement>partial</agreement>, or <agreement>disagree</agreement>.`
+
+var agreementPattern = regexp.MustCompile(`(?i)\s*<agreement>\s*(\w+)\s*</agreement>\s*`)
+
+// Result is a cross-checked answer
+type Result struct {
+	Answer    string
+	Agreement string
+}
+
+// Ensemble answers with two models and has the primary reconcile them
+type Ensemble struct {
+	primary   Model
+	secondary Model
+	clean     func(string) string
+}
+
+// New creates an ensemble. The primary model drafts and reconciles; the
+// secondary gives an independent second draft
+func New(primary, secondary Model) *Ensemble {
+	return &Ensemble{primary: primary, secondary: secondary}
+}
+
+// SetCleaner strips control blocks (scratchpad, charts, follow-ups) from the
+// drafts before they are compared
+func (e *Ensemble) SetCleaner(clean func(string) string) {
+	e.clean = clean
+}
+
+// Answer drafts a reply with both models in parallel and reconciles them. If
+// the secondary fails, the primary draft is returned unchecked; if the
+// primary fails, so does Answer
+func (e *Ensemble) Answer(ctx context.Context, history []models.Message, systemPrompt string) (*Result, error) {
+	var (
+		wg     sync.WaitGroup
+		draftB string
+		errB   error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		draftB, errB = e.secondary.SendMessage(ctx, history, systemPrompt)
+	}()
+	draftA, errA := e.primary.SendMessage(ctx, history, systemPrompt)
+	wg.Wait()
+
+	if errA != nil {
+		return nil, fmt.Errorf("primary model: %w", errA)
+	}
+	if errB != nil {
+		return &Result{Answer: draftA, Agreement: Unchecked}, nil
+	}
+
+	prompt := fmt.Sprintf(reconcileInstructions, e.cleaned(draftA), e.cleaned(draftB))
+	reconciled, err := e.primary.SendMessage(ctx, withRequest(history, prompt), systemPrompt)
+	if err != nil {
+		return &Result{Answer: draftA, Agreement: Unchecked}, nil
+	}
+
+	answer, agreement := ParseAgreement(reconciled)
+	return &Result{Answer: answer, Agreement: agreement}, nil
+}
+
+// withRequest adds a request to the user's last turn, keeping roles
+// alternating as the Messages API requires
+func withRequest(history []models.Message, request string) []models.Message {
+	messages := append([]models.Message(nil), history...)
+	if n := len(messages); n > 0 && messages[n-1].Role == models.RoleUser {
+		messages[n-1].Content += "\n\n" + request
+		return messages
+	}
+	return append(messages, models.Message{Role: models.RoleUser, Content: request})
+}
+
+func (e *Ensemble) cleaned(draft string) string {
+	if e.clean == nil {
+		return draft
+	}
+	return e.clean(draft)
+}
+
+// ParseAgreement removes the agreement tag from a reconciled answer. A
+// missing or unknown rating counts as partial, so it is never mistaken for
+// full agreement
+func ParseAgreement(response string) (string, string) {
+	match := agreementPattern.FindStringSubmatch(response)
+	if match == nil {
+		return strings.TrimSpace(response), Partial
+	}
+
+	cleaned := strings.TrimSpace(agreementPattern.ReplaceAllString(response, "\n"))
+	switch level := strings.ToLower(match[1]); level {
+	case Agree, Partial, Disagree:
+		return cleaned, level
+	default:
+		return cleaned, Partial
+	}
+}
+
+// Note describes how well the models agreed, for the answer's context line
+func Note(agreement string) string {
+	switch agreement {
+	case Agree:
+		return "⚖️ Cross-checked with a second model: consistent"
+	case Partial:
+		return "⚖️ Cross-checked with a second model: partly consistent, uncertainties noted"
+	case Disagree:
+		return "⚠️ A second model disagreed; treat this answer as uncertain"
+	default:
+		return "⚠️ Not cross-checked: the second model was unavailable"
+	}
+}
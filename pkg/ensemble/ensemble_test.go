@@ -0,0 +1,104 @@
+package ensemble
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// fakeModel returns canned responses in order and records requests
+type fakeModel struct {
+	mu        sync.Mutex
+	responses []string
+	err       error
+	calls     [][]models.Message
+}
+
+func (m *fakeModel) SendMessage(_ context.Context, messages []models.Message, _ string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, messages)
+	if m.err != nil {
+		return "", m.err
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+func TestAnswerReconciles(t *testing.T) {
+	primary := &fakeModel{responses: []string{
+		"RDS is CPU bound <scratchpad>finding: cpu</scratchpad>",
+		"Likely CPU bound, but the second check points at IOPS.\n<agreement>partial</agreement>",
+	}}
+	secondary := &fakeModel{responses: []string{"IOPS are saturated"}}
+
+	e := New(primary, secondary)
+	e.SetCleaner(func(s string) string { return strings.Split(s, " <scratchpad>")[0] })
+
+	history := []models.Message{{Role: models.RoleUser, Content: "why is orders-db slow?"}}
+	result, err := e.Answer(context.Background(), history, "system")
+	if err != nil {
+		t.Fatalf("Answer() error = %v", err)
+	}
+	if result.Agreement != Partial || strings.Contains(result.Answer, "<agreement>") {
+		t.Errorf("Answer() = %+v", result)
+	}
+
+	// The reconcile request folds both cleaned drafts into the user's turn
+	req := primary.calls[1]
+	if len(req) != 1 || req[0].Role != models.RoleUser {
+		t.Fatalf("reconcile request = %+v, want a single user turn", req)
+	}
+	for _, want := range []string{"why is orders-db slow?", "RDS is CPU bound\n</draft_a>", "IOPS are saturated"} {
+		if !strings.Contains(req[0].Content, want) {
+			t.Errorf("reconcile request missing %q", want)
+		}
+	}
+	if history[0].Content != "why is orders-db slow?" {
+		t.Error("Answer() should not modify the caller's history")
+	}
+}
+
+func TestAnswerSecondaryFails(t *testing.T) {
+	primary := &fakeModel{responses: []string{"draft"}}
+	e := New(primary, &fakeModel{err: errors.New("throttled")})
+
+	result, err := e.Answer(context.Background(), []models.Message{{Role: models.RoleUser, Content: "q"}}, "")
+	if err != nil {
+		t.Fatalf("Answer() error = %v", err)
+	}
+	if result.Answer != "draft" || result.Agreement != Unchecked {
+		t.Errorf("Answer() = %+v, want the unchecked primary draft", result)
+	}
+}
+
+func TestAnswerPrimaryFails(t *testing.T) {
+	e := New(&fakeModel{err: errors.New("throttled")}, &fakeModel{responses: []string{"draft"}})
+	if _, err := e.Answer(context.Background(), []models.Message{{Role: models.RoleUser, Content: "q"}}, ""); err == nil {
+		t.Error("Answer() should fail when the primary model fails")
+	}
+}
+
+func TestParseAgreement(t *testing.T) {
+	tests := []struct {
+		response string
+		want     string
+	}{
+		{"Answer\n<agreement>agree</agreement>", Agree},
+		{"Answer <AGREEMENT> Disagree </AGREEMENT>", Disagree},
+		{"Answer\n<agreement>mostly</agreement>", Partial},
+		{"Answer", Partial},
+	}
+
+	for _, tt := range tests {
+		answer, got := ParseAgreement(tt.response)
+		if got != tt.want || answer != "Answer" {
+			t.Errorf("ParseAgreement(%q) = %q, %s, want Answer, %s", tt.response, answer, got, tt.want)
+		}
+	}
+}
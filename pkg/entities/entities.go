@@ -0,0 +1,160 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/links"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+var (
+	arnPattern      = regexp.MustCompile(`arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:[0-9]*:[^\s"'<>|,()\x60]+`)
+	instancePattern = regexp.MustCompile(`\bi-(?:[0-9a-f]{17}|[0-9a-f]{8})\b`)
+	logGroupPattern = regexp.MustCompile(`(?:^|[\s(])(/(?:aws|ecs)/[A-Za-z0-9_\-/.#]+)`)
+)
+
+// match is an entity found at a position in some text
+type match struct {
+	start, end int
+	entity     models.Entity
+}
+
+// Extract finds ARNs, EC2 instance IDs, and log group names in text.
+// Entities without an explicit region are assigned defaultRegion.
+func Extract(text, defaultRegion string) []models.Entity {
+	var entities []models.Entity
+	seen := make(map[string]bool)
+	for _, m := range findAll(text, defaultRegion) {
+		key := m.entity.Type + ":" + m.entity.ID
+		if !seen[key] {
+			seen[key] = true
+			entities = append(entities, m.entity)
+		}
+	}
+	return entities
+}
+
+// Linkify rewrites entity references in Slack mrkdwn text as links to the
+// AWS console. Text inside code spans, code blocks, and existing links and
+// mentions is left untouched.
+func Linkify(text, defaultRegion string, builder *links.Builder) string {
+	var b strings.Builder
+	for i, segment := range splitVerbatim(text) {
+		if i%2 == 1 {
+			b.WriteString(segment) // code or a link
+			continue
+		}
+
+		last := 0
+		for _, m := range findAll(segment, defaultRegion) {
+			b.WriteString(segment[last:m.start])
+			if link := builder.Entity(m.entity); link != "" {
+				fmt.Fprintf(&b, "<%s|%s>", link, m.entity.ID)
+			} else {
+				b.WriteString(m.entity.ID)
+			}
+			last = m.end
+		}
+		b.WriteString(segment[last:])
+	}
+	return b.String()
+}
+
+// findAll returns non-overlapping entity matches ordered by position.
+// ARNs take precedence over the instance IDs and log groups they contain.
+func findAll(text, defaultRegion string) []match {
+	var matches []match
+
+	for _, loc := range arnPattern.FindAllStringIndex(text, -1) {
+		arn := strings.TrimRight(text[loc[0]:loc[1]], ".:;")
+		matches = append(matches, match{
+			start:  loc[0],
+			end:    loc[0] + len(arn),
+			entity: models.Entity{Type: models.EntityARN, ID: arn, Region: regionOf(arn, defaultRegion)},
+		})
+	}
+
+	for _, loc := range instancePattern.FindAllStringIndex(text, -1) {
+		matches = appendIfFree(matches, match{
+			start:  loc[0],
+			end:    loc[1],
+			entity: models.Entity{Type: models.EntityInstance, ID: text[loc[0]:loc[1]], Region: defaultRegion},
+		})
+	}
+
+	for _, loc := range logGroupPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := strings.TrimRight(text[loc[2]:loc[3]], ".")
+		matches = appendIfFree(matches, match{
+			start:  loc[2],
+			end:    loc[2] + len(name),
+			entity: models.Entity{Type: models.EntityLogGroup, ID: name, Region: defaultRegion},
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// appendIfFree adds m unless it overlaps an existing match
+func appendIfFree(matches []match, m match) []match {
+	for _, existing := range matches {
+		if m.start < existing.end && existing.start < m.end {
+			return matches
+		}
+	}
+	return append(matches, m)
+}
+
+// regionOf returns the region component of an ARN, or def when it is empty
+func regionOf(arn, def string) string {
+	parts := strings.SplitN(arn, ":", 5)
+	if len(parts) >= 4 && parts[3] != "" {
+		return parts[3]
+	}
+	return def
+}
+
+// splitVerbatim splits text into alternating prose and verbatim segments:
+// code spans and blocks, and Slack's <url|text> links and <@U…> mentions
+// (even indexes are prose, odd indexes are verbatim, including delimiters)
+func splitVerbatim(text string) []string {
+	var segments []string
+	prose := 0
+	for i := 0; i < len(text); i++ {
+		end := verbatimEnd(text, i)
+		if end < 0 {
+			continue
+		}
+		segments = append(segments, text[prose:i], text[i:end])
+		prose, i = end, end-1
+	}
+	return append(segments, text[prose:])
+}
+
+// verbatimEnd returns the end of the code span, code block, or link that
+// starts at i, or -1 when none does
+func verbatimEnd(text string, i int) int {
+	switch text[i] {
+	case '`':
+		delim := "`"
+		if strings.HasPrefix(text[i:], "```") {
+			delim = "```"
+		}
+		end := strings.Index(text[i+len(delim):], delim)
+		if end < 0 {
+			return -1
+		}
+		return i + 2*len(delim) + end
+	case '<':
+		// Links and mentions never span lines or nest
+		end := strings.IndexAny(text[i+1:], "<>\n")
+		if end < 0 || text[i+1+end] != '>' {
+			return -1
+		}
+		return i + end + 2
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/links"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/mrkdwn"
+)
+
+func TestExtract(t *testing.T) {
+	text := "Instance i-0123456789abcdef0 is writing to /aws/lambda/payments. " +
+		"See arn:aws:ecs:us-west-2:123456789012:service/prod/api and i-0123456789abcdef0 again."
+
+	got := Extract(text, "us-east-1")
+	want := []models.Entity{
+		{Type: models.EntityInstance, ID: "i-0123456789abcdef0", Region: "us-east-1"},
+		{Type: models.EntityLogGroup, ID: "/aws/lambda/payments", Region: "us-east-1"},
+		{Type: models.EntityARN, ID: "arn:aws:ecs:us-west-2:123456789012:service/prod/api", Region: "us-west-2"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Extract() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Extract()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExtractARNContainingOtherEntities(t *testing.T) {
+	text := "arn:aws:ec2:eu-west-1:123456789012:instance/i-0123456789abcdef0"
+
+	got := Extract(text, "us-east-1")
+	if len(got) != 1 || got[0].Type != models.EntityARN {
+		t.Errorf("Extract() = %+v, want a single ARN", got)
+	}
+}
+
+func TestExtractIgnoresLookalikes(t *testing.T) {
+	for _, text := range []string{"hi-there", "ami-12345678", "path/aws/lambda/x", "i-xyz"} {
+		if got := Extract(text, "us-east-1"); len(got) != 0 {
+			t.Errorf("Extract(%q) = %+v, want none", text, got)
+		}
+	}
+}
+
+func TestLinkify(t *testing.T) {
+	text := "Check i-0123456789abcdef0 but not `i-0123456789abcdef1` or\n```\n/aws/lambda/x\n```"
+
+	got := Linkify(text, "us-west-2", links.New("us-west-2"))
+
+	if !strings.Contains(got, "<https://us-west-2.console.aws.amazon.com/ec2/home?region=us-west-2#InstanceDetails:instanceId=i-0123456789abcdef0|i-0123456789abcdef0>") {
+		t.Errorf("Linkify() = %q, missing instance link", got)
+	}
+	if !strings.Contains(got, "`i-0123456789abcdef1`") {
+		t.Errorf("Linkify() = %q, should not link inside code spans", got)
+	}
+	if !strings.Contains(got, "```\n/aws/lambda/x\n```") {
+		t.Errorf("Linkify() = %q, should not link inside code blocks", got)
+	}
+}
+
+func TestLinkifySkipsLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "link text", text: "See <https://example.com/runbook|i-0123456789abcdef0>."},
+		{name: "link url", text: "See <https://example.com/i-0123456789abcdef0|the runbook>."},
+		{name: "log group", text: "Logs are in <https://example.com/logs| /aws/lambda/x>."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Linkify(tt.text, "us-west-2", links.New("us-west-2")); got != tt.text {
+				t.Errorf("Linkify() = %q, want it unchanged", got)
+			}
+		})
+	}
+
+	// The agent links entities in the model's markdown after converting it
+	text := mrkdwn.Convert("Restarted [i-0123456789abcdef0](https://example.com/i-0123456789abcdef0) at 10:02.")
+	if got := Linkify(text, "us-west-2", links.New("us-west-2")); got != text {
+		t.Errorf("Linkify(%q) = %q, want it unchanged", text, got)
+	}
+
+	// Text around a link, and a lone "<" without one, is still linked
+	got := Linkify("latency < 5ms on i-0123456789abcdef0, see <https://example.com|docs>", "us-west-2", links.New("us-west-2"))
+	if !strings.Contains(got, "|i-0123456789abcdef0>") || !strings.HasSuffix(got, "<https://example.com|docs>") {
+		t.Errorf("Linkify() = %q", got)
+	}
+}
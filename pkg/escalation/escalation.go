@@ -0,0 +1,206 @@
+// Package escalation hands a conversation to human experts. The experts
+// group is pinged in the conversation with a compact context pack, and the
+// agent steps back to assist them, answering only when it is mentioned
+package escalation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/postmortem"
+	"github.com/slack-go/slack"
+)
+
+// MetadataEventType marks the message that escalates a conversation, which
+// the agent polling the conversation picks up
+const MetadataEventType = "cloudops_escalation"
+
+// maxPackItems caps each list in the context pack
+const maxPackItems = 5
+
+// AssistPrompt is added to the system prompt once experts have taken over
+const AssistPrompt = `A human expert has taken over this investigation and you are now assisting them.
+Answer only what you are asked, run the checks they request, and keep answers short.
+Don't propose next steps or draw conclusions unless they ask for them.`
+
+// systemPrompt instructs the model to brief an expert joining the investigation
+const systemPrompt = `You are an SRE handing an investigation over to a human expert who hasn't seen it.
+Respond with a single JSON object and nothing else, using this shape:
+{
+  "summary": "two or three sentences: what was asked, what is known, and where the investigation is stuck",
+  "findings": ["established fact, most important first"]
+}
+Keep each finding to one line. Use only facts present in the transcript.`
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+
+// Pack is what an expert needs to pick up a conversation
+type Pack struct {
+	Summary   string   `json:"summary"`
+	Findings  []string `json:"findings"`
+	Resources []string `json:"-"`
+}
+
+// Drafter asks Claude to summarize conversations for experts
+type Drafter struct {
+	bedrock *bedrock.Client
+}
+
+// NewDrafter creates a context pack drafter
+func NewDrafter(bedrockClient *bedrock.Client) *Drafter {
+	return &Drafter{bedrock: bedrockClient}
+}
+
+// Draft summarizes a conversation for the experts joining it
+func (d *Drafter) Draft(ctx context.Context, conv *models.Conversation, history []models.ConversationHistoryItem) (*Pack, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("conversation %s has no messages", conv.ConversationID)
+	}
+
+	messages := []models.Message{{Role: models.RoleUser, Content: postmortem.Transcript(conv, history)}}
+	response, err := d.bedrock.SendMessage(ctx, messages, systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("draft context pack: %w", err)
+	}
+
+	pack, err := Parse(response)
+	if err != nil {
+		return nil, err
+	}
+	pack.Resources = resources(conv)
+	return pack, nil
+}
+
+// Parse extracts the context pack from the model's response
+func Parse(response string) (*Pack, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var p Pack
+	if err := json.Unmarshal([]byte(response[start:end+1]), &p); err != nil {
+		return nil, fmt.Errorf("unmarshal context pack: %w", err)
+	}
+	if strings.TrimSpace(p.Summary) == "" {
+		return nil, fmt.Errorf("context pack has no summary")
+	}
+	return &p, nil
+}
+
+// FromNotes builds a context pack from the conversation's request and
+// investigation notes, for when the model can't summarize it
+func FromNotes(conv *models.Conversation) *Pack {
+	pack := &Pack{
+		Summary:   strings.TrimSpace(mentionPattern.ReplaceAllString(conv.InitialCommand, "")),
+		Resources: resources(conv),
+	}
+	if conv.Scratchpad != nil {
+		pack.Findings = conv.Scratchpad.Findings
+	}
+	return pack
+}
+
+// resources lists the resources the investigation looked at, from its
+// notes and the entities found in its messages
+func resources(conv *models.Conversation) []string {
+	var ids []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if conv.Scratchpad != nil {
+		for _, r := range conv.Scratchpad.Resources {
+			add(r)
+		}
+	}
+	for _, e := range conv.Entities {
+		add(e.ID)
+	}
+	return ids
+}
+
+// Metadata marks the escalation message for the agent
+func Metadata(conversationID, userID string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"conversation_id": conversationID,
+			"escalated_by":    userID,
+		},
+	}
+}
+
+// FromMetadata returns who escalated the conversation, when meta records
+// its escalation
+func FromMetadata(meta slack.SlackMetadata, conversationID string) (string, bool) {
+	if meta.EventType != MetadataEventType {
+		return "", false
+	}
+	id, _ := meta.EventPayload["conversation_id"].(string)
+	userID, _ := meta.EventPayload["escalated_by"].(string)
+	return userID, id == conversationID
+}
+
+// Addressed reports whether a message mentions the bot, which is all it
+// answers once experts have taken over
+func Addressed(text, botUserID string) bool {
+	return botUserID != "" && strings.Contains(text, "<@"+botUserID+">")
+}
+
+// Text is the notification text of the escalation message
+func Text(conv *models.Conversation, groupID, userID string) string {
+	return fmt.Sprintf("🙋 <@%s> asked <!subteam^%s> to take over `%s`", userID, groupID, conv.ConversationID)
+}
+
+// Blocks lays out the escalation message: who asked for help and why, then
+// the context pack. permalink links back to where the conversation started
+// and may be empty
+func Blocks(conv *models.Conversation, pack *Pack, groupID, userID, reason, permalink string) []slack.Block {
+	text := func(s string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+	}
+
+	header := Text(conv, groupID, userID)
+	if permalink != "" {
+		header += fmt.Sprintf(" (<%s|original request>)", permalink)
+	}
+	if reason = strings.TrimSpace(reason); reason != "" {
+		header += "\n>" + strings.ReplaceAll(reason, "\n", "\n>")
+	}
+	blocks := []slack.Block{slack.NewSectionBlock(text(header), nil, nil)}
+
+	if pack.Summary != "" {
+		blocks = append(blocks, slack.NewSectionBlock(text("*Summary*\n"+pack.Summary), nil, nil))
+	}
+	if list := bullets(pack.Findings, func(s string) string { return s }); list != "" {
+		blocks = append(blocks, slack.NewSectionBlock(text("*Key findings*\n"+list), nil, nil))
+	}
+	if list := bullets(pack.Resources, func(s string) string { return "`" + s + "`" }); list != "" {
+		blocks = append(blocks, slack.NewSectionBlock(text("*Resources*\n"+list), nil, nil))
+	}
+
+	return append(blocks, slack.NewContextBlock("",
+		text("I'll step back and assist from here: mention me to ask for a check or a summary.")))
+}
+
+// bullets formats the most recent items as a bullet list
+func bullets(items []string, format func(string) string) string {
+	if len(items) > maxPackItems {
+		items = items[len(items)-maxPackItems:]
+	}
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "• " + format(item)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,87 @@
+package escalation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+func TestParse(t *testing.T) {
+	pack, err := Parse("Here you go:\n```json\n{\"summary\": \"checkout-api 5xx since 14:05\", \"findings\": [\"RDS CPU at 100%\"]}\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pack.Summary != "checkout-api 5xx since 14:05" || len(pack.Findings) != 1 {
+		t.Errorf("Parse() = %+v", pack)
+	}
+
+	if _, err := Parse(`{"findings": ["x"]}`); err == nil {
+		t.Error("Parse() should reject a pack without a summary")
+	}
+	if _, err := Parse("no json"); err == nil {
+		t.Error("Parse() should reject a response without JSON")
+	}
+}
+
+func TestFromNotes(t *testing.T) {
+	conv := &models.Conversation{
+		InitialCommand: "<@U0BOT> why is checkout slow?",
+		Scratchpad:     &models.Scratchpad{Findings: []string{"p99 latency 4s"}, Resources: []string{"i-0abc"}},
+		Entities:       []models.Entity{{Type: models.EntityInstance, ID: "i-0abc"}, {Type: models.EntityLogGroup, ID: "/ecs/checkout"}},
+	}
+
+	pack := FromNotes(conv)
+	if pack.Summary != "why is checkout slow?" {
+		t.Errorf("Summary = %q", pack.Summary)
+	}
+	if len(pack.Findings) != 1 || strings.Join(pack.Resources, ",") != "i-0abc,/ecs/checkout" {
+		t.Errorf("FromNotes() = %+v, want the notes' findings and deduplicated resources", pack)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	meta := Metadata("conv-1", "U123")
+
+	// Metadata read back from Slack has been through JSON
+	data, _ := json.Marshal(meta)
+	var got slack.SlackMetadata
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if userID, ok := FromMetadata(got, "conv-1"); !ok || userID != "U123" {
+		t.Errorf("FromMetadata() = %q, %v", userID, ok)
+	}
+	if _, ok := FromMetadata(got, "conv-2"); ok {
+		t.Error("FromMetadata() matched another conversation")
+	}
+}
+
+func TestAddressed(t *testing.T) {
+	if !Addressed("<@U0BOT> check the RDS metrics", "U0BOT") {
+		t.Error("a mention should address the bot")
+	}
+	if Addressed("I think it's the RDS failover", "U0BOT") {
+		t.Error("a message without a mention shouldn't address the bot")
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	conv := &models.Conversation{ConversationID: "conv-1"}
+	pack := &Pack{Summary: "Checkout is failing.", Findings: []string{"a", "b", "c", "d", "e", "f"}, Resources: []string{"i-0abc"}}
+
+	blocks := Blocks(conv, pack, "S0EXPERTS", "U123", "stuck on the RDS failover", "")
+	data, _ := json.Marshal(blocks)
+	out := string(data)
+	for _, want := range []string{"!subteam^S0EXPERTS", "stuck on the RDS failover", "Checkout is failing.", "`i-0abc`", "mention me"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Blocks() missing %q", want)
+		}
+	}
+	if strings.Contains(out, "• a") {
+		t.Error("Blocks() should keep only the most recent findings")
+	}
+}
@@ -0,0 +1,155 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// scriptedModel answers with canned responses and records what it was sent
+type scriptedModel struct {
+	responses []string
+	calls     [][]models.Message
+	err       error
+}
+
+func (m *scriptedModel) SendMessage(_ context.Context, messages []models.Message, _ string) (string, error) {
+	m.calls = append(m.calls, append([]models.Message(nil), messages...))
+	if m.err != nil {
+		return "", m.err
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return resp, nil
+}
+
+func TestLoadCorpus(t *testing.T) {
+	corpus := `# sample
+{"id": "rds-cpu", "turns": ["Why is RDS CPU high?", "What about last week?"], "expected": "Mentions slow queries"}
+
+{"id": "alb-5xx", "turns": ["ALB 5xx spike"], "must_mention": ["target health"]}`
+
+	cases, err := LoadCorpus(strings.NewReader(corpus))
+	if err != nil {
+		t.Fatalf("LoadCorpus() error = %v", err)
+	}
+	if len(cases) != 2 || len(cases[0].Turns) != 2 || cases[1].MustMention[0] != "target health" {
+		t.Errorf("LoadCorpus() = %+v", cases)
+	}
+}
+
+func TestLoadCorpusInvalid(t *testing.T) {
+	tests := []string{
+		`{"id": "x"}`,
+		`{"turns": ["hi"]}`,
+		`{"id": "x", "turns": ["a"]}` + "\n" + `{"id": "x", "turns": ["b"]}`,
+		`not json`,
+	}
+
+	for _, corpus := range tests {
+		if _, err := LoadCorpus(strings.NewReader(corpus)); err == nil {
+			t.Errorf("LoadCorpus(%q) should error", corpus)
+		}
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	g, err := ParseGrade("Verdict:\n" + `{"accuracy": 4, "helpfulness": 5, "safety": 3, "missed": ["target health"], "notes": "ok"}`)
+	if err != nil {
+		t.Fatalf("ParseGrade() error = %v", err)
+	}
+	if g.Average() != 4 || len(g.Missed) != 1 || g.Notes != "ok" {
+		t.Errorf("ParseGrade() = %+v", g)
+	}
+
+	for _, response := range []string{
+		"no json",
+		`{"accuracy": 4, "helpfulness": 5}`,
+		`{"accuracy": 9, "helpfulness": 5, "safety": 3}`,
+	} {
+		if _, err := ParseGrade(response); err == nil {
+			t.Errorf("ParseGrade(%q) should error", response)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	model := &scriptedModel{responses: []string{"first <noise>", "second"}}
+	judge := &scriptedModel{responses: []string{`{"accuracy": 5, "helpfulness": 4, "safety": 5, "notes": "good"}`}}
+
+	runner := NewRunner(model, judge, "system")
+	runner.SetCleaner(func(s string) string { return strings.TrimSuffix(s, " <noise>") })
+
+	results := runner.Run(context.Background(), []Case{{ID: "c1", Turns: []string{"q1", "q2"}, Expected: "be right"}})
+	if len(results) != 1 || results[0].Error != "" {
+		t.Fatalf("Run() = %+v", results)
+	}
+
+	// The second turn sees the cleaned first answer
+	if got := model.calls[1][1].Content; got != "first" {
+		t.Errorf("history answer = %q, want %q", got, "first")
+	}
+	if results[0].Answer != "second" || results[0].Grade.Scores[CriterionHelpfulness] != 4 {
+		t.Errorf("Run() result = %+v", results[0])
+	}
+	if input := judge.calls[0][0].Content; !strings.Contains(input, "be right") || !strings.Contains(input, "Final answer:\nsecond") {
+		t.Errorf("judge input = %q", input)
+	}
+}
+
+func TestRunRecordsErrors(t *testing.T) {
+	runner := NewRunner(&scriptedModel{err: errors.New("throttled")}, &scriptedModel{}, "system")
+
+	results := runner.Run(context.Background(), []Case{{ID: "c1", Turns: []string{"q"}}, {ID: "c2", Turns: []string{"q"}}})
+	if len(results) != 2 || !strings.Contains(results[1].Error, "throttled") {
+		t.Errorf("Run() = %+v, want both cases recorded with errors", results)
+	}
+}
+
+func grade(a, h, s int) Grade {
+	return Grade{Scores: map[string]int{CriterionAccuracy: a, CriterionHelpfulness: h, CriterionSafety: s}}
+}
+
+func TestScorecardCompare(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	baseline := NewScorecard("v1", "sonnet", "sonnet", "prompt v1", []Result{
+		{CaseID: "a", Grade: grade(5, 5, 5)},
+		{CaseID: "b", Grade: grade(4, 4, 4)},
+		{CaseID: "c", Grade: grade(3, 3, 3)},
+	}, now)
+	current := NewScorecard("v2", "sonnet", "sonnet", "prompt v2", []Result{
+		{CaseID: "a", Grade: grade(5, 5, 4)},
+		{CaseID: "b", Grade: grade(2, 3, 4)},
+		{CaseID: "c", Error: "judge: no JSON object in judge response"},
+		{CaseID: "d", Grade: grade(1, 1, 1)},
+	}, now)
+
+	if current.Errors != 1 || baseline.Overall != 4 {
+		t.Errorf("NewScorecard() errors = %d, baseline overall = %v", current.Errors, baseline.Overall)
+	}
+	if baseline.PromptHash == current.PromptHash {
+		t.Error("PromptHash() should differ for different prompts")
+	}
+
+	regressions := Compare(baseline, current, 0.5)
+	var got []string
+	for _, r := range regressions {
+		got = append(got, r.CaseID)
+	}
+	// Overall first, then cases; "a" is within tolerance and "d" is new
+	if strings.Join(got, ",") != ",b,c" {
+		t.Errorf("Compare() = %v, want overall, b, c", regressions)
+	}
+
+	md := current.Markdown(baseline, regressions)
+	for _, want := range []string{"Eval scorecard: v2", "| overall |", "Regressions against v1", "- d (1.00)"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Scorecard summarizes a run so it can be saved and compared with the next
+type Scorecard struct {
+	Label      string             `json:"label"`
+	Model      string             `json:"model"`
+	Judge      string             `json:"judge"`
+	PromptHash string             `json:"prompt_hash"`
+	RunAt      time.Time          `json:"run_at"`
+	Averages   map[string]float64 `json:"averages"`
+	Overall    float64            `json:"overall"`
+	Errors     int                `json:"errors"`
+	Results    []Result           `json:"results"`
+}
+
+// NewScorecard averages each criterion over the graded results. Errored
+// cases count against the error total rather than the averages
+func NewScorecard(label, model, judge, prompt string, results []Result, now time.Time) *Scorecard {
+	sc := &Scorecard{
+		Label:      label,
+		Model:      model,
+		Judge:      judge,
+		PromptHash: models.PromptHash(prompt),
+		RunAt:      now.UTC(),
+		Averages:   map[string]float64{},
+		Results:    results,
+	}
+
+	graded := 0
+	for _, r := range results {
+		if r.Error != "" {
+			sc.Errors++
+			continue
+		}
+		graded++
+		for _, c := range Criteria {
+			sc.Averages[c] += float64(r.Grade.Scores[c])
+		}
+	}
+	if graded == 0 {
+		return sc
+	}
+
+	for _, c := range Criteria {
+		sc.Averages[c] /= float64(graded)
+		sc.Overall += sc.Averages[c]
+	}
+	sc.Overall /= float64(len(Criteria))
+	return sc
+}
+
+// Regression is a drop in score between a baseline and the current run
+type Regression struct {
+	CaseID   string // empty for the overall average
+	Baseline float64
+	Current  float64
+	Reason   string // set when the case now fails
+}
+
+func (r Regression) String() string {
+	name := r.CaseID
+	if name == "" {
+		name = "overall"
+	}
+	if r.Reason != "" {
+		return fmt.Sprintf("%s: %s", name, r.Reason)
+	}
+	return fmt.Sprintf("%s: %.2f → %.2f", name, r.Baseline, r.Current)
+}
+
+// Compare lists cases whose average score dropped by more than tolerance,
+// cases that now fail, and an overall drop, comparing only cases in both runs
+func Compare(baseline, current *Scorecard, tolerance float64) []Regression {
+	before := map[string]Result{}
+	for _, r := range baseline.Results {
+		before[r.CaseID] = r
+	}
+
+	var regressions []Regression
+	for _, r := range current.Results {
+		b, ok := before[r.CaseID]
+		switch {
+		case !ok || b.Error != "":
+			continue
+		case r.Error != "":
+			regressions = append(regressions, Regression{CaseID: r.CaseID, Baseline: b.Grade.Average(), Reason: r.Error})
+		case b.Grade.Average()-r.Grade.Average() > tolerance:
+			regressions = append(regressions, Regression{CaseID: r.CaseID, Baseline: b.Grade.Average(), Current: r.Grade.Average()})
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool { return regressions[i].CaseID < regressions[j].CaseID })
+
+	if baseline.Overall-current.Overall > tolerance {
+		regressions = append([]Regression{{Baseline: baseline.Overall, Current: current.Overall}}, regressions...)
+	}
+	return regressions
+}
+
+// Markdown renders the scorecard, and regressions against a baseline when
+// one is given
+func (sc *Scorecard) Markdown(baseline *Scorecard, regressions []Regression) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Eval scorecard: %s\n\n", sc.Label)
+	fmt.Fprintf(&b, "Model `%s`, judge `%s`, prompt `%s`, %s\n\n", sc.Model, sc.Judge, sc.PromptHash, sc.RunAt.Format(time.RFC3339))
+
+	b.WriteString("| Criterion | Score |")
+	if baseline != nil {
+		b.WriteString(" Baseline |")
+	}
+	b.WriteString("\n|---|---|")
+	if baseline != nil {
+		b.WriteString("---|")
+	}
+	b.WriteString("\n")
+
+	rows := append(append([]string{}, Criteria...), "overall")
+	for _, c := range rows {
+		score, base := sc.Averages[c], 0.0
+		if baseline != nil {
+			base = baseline.Averages[c]
+		}
+		if c == "overall" {
+			score = sc.Overall
+			if baseline != nil {
+				base = baseline.Overall
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %.2f |", c, score)
+		if baseline != nil {
+			fmt.Fprintf(&b, " %.2f |", base)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n%d case(s), %d error(s)\n", len(sc.Results), sc.Errors)
+
+	if baseline != nil {
+		if len(regressions) == 0 {
+			fmt.Fprintf(&b, "\nNo regressions against %s.\n", baseline.Label)
+		} else {
+			fmt.Fprintf(&b, "\n## Regressions against %s\n\n", baseline.Label)
+			for _, r := range regressions {
+				fmt.Fprintf(&b, "- %s\n", r)
+			}
+		}
+	}
+
+	var low []Result
+	for _, r := range sc.Results {
+		if r.Error == "" && r.Grade.Average() < 3 {
+			low = append(low, r)
+		}
+	}
+	if len(low) > 0 {
+		b.WriteString("\n## Low scores\n\n")
+		for _, r := range low {
+			fmt.Fprintf(&b, "- %s (%.2f): %s\n", r.CaseID, r.Grade.Average(), r.Grade.Notes)
+		}
+	}
+
+	return b.String()
+}
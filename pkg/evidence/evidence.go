@@ -0,0 +1,259 @@
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ManifestName is the manifest's file name inside an archive
+const ManifestName = "manifest.json"
+
+// manifestVersion is bumped when the chain or signature scheme changes
+const manifestVersion = 1
+
+// Entry is one file in an archive. Chain commits to this file and every
+// file before it, so removing, reordering, or editing any file breaks the
+// chain from that point on
+type Entry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+	Chain  string `json:"chain"`
+}
+
+// Manifest describes an archive's contents. Root is the last entry's chain
+// hash, and Signature covers the manifest with Signature left empty
+type Manifest struct {
+	Version   int       `json:"version"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Entries   []Entry   `json:"entries"`
+	Root      string    `json:"root"`
+	PublicKey string    `json:"public_key,omitempty"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+type file struct {
+	name string
+	data []byte
+}
+
+// Archive collects evidence files for a time range
+type Archive struct {
+	manifest Manifest
+	files    []file
+}
+
+// New starts an archive for evidence between start and end
+func New(start, end time.Time, createdBy string, now time.Time) *Archive {
+	return &Archive{manifest: Manifest{
+		Version:   manifestVersion,
+		Start:     start.UTC(),
+		End:       end.UTC(),
+		CreatedAt: now.UTC(),
+		CreatedBy: createdBy,
+	}}
+}
+
+// Add appends a file and extends the hash chain
+func (a *Archive) Add(name string, data []byte) {
+	sum := sha256.Sum256(data)
+	entry := Entry{Name: name, SHA256: hex.EncodeToString(sum[:]), Size: len(data)}
+	entry.Chain = link(a.manifest.Root, entry)
+
+	a.manifest.Entries = append(a.manifest.Entries, entry)
+	a.manifest.Root = entry.Chain
+	a.files = append(a.files, file{name: name, data: data})
+}
+
+// AddJSONLines appends records as a JSON Lines file
+func AddJSONLines[T any](a *Archive, name string, records []T) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+	}
+	a.Add(name, buf.Bytes())
+	return nil
+}
+
+// Sign signs the manifest with an Ed25519 key and embeds the public key
+func (a *Archive) Sign(key ed25519.PrivateKey) error {
+	a.manifest.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	a.manifest.Signature = ""
+	payload, err := json.Marshal(a.manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	a.manifest.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return nil
+}
+
+// Manifest returns the archive's manifest
+func (a *Archive) Manifest() Manifest {
+	return a.manifest
+}
+
+// Write writes the archive as a gzipped tarball with the manifest last
+func (a *Archive) Write(w io.Writer) error {
+	manifest, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range append(a.files, file{name: ManifestName, data: manifest}) {
+		hdr := &tar.Header{Name: f.name, Mode: 0o444, Size: int64(len(f.data)), ModTime: a.manifest.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("write %s: %w", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return gz.Close()
+}
+
+// Verify checks an archive's files against its manifest, the hash chain, and
+// the signature. When trusted is set the archive must be signed by that
+// key; otherwise the embedded key is used, which proves integrity but not
+// origin
+func Verify(r io.Reader, trusted ed25519.PublicKey) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", hdr.Name, err)
+		}
+		files[hdr.Name] = data
+	}
+
+	raw, ok := files[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", ManifestName)
+	}
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	if err := verifySignature(m, trusted); err != nil {
+		return nil, err
+	}
+
+	prev := ""
+	for _, e := range m.Entries {
+		data, ok := files[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s is listed in the manifest but missing", e.Name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != e.SHA256 || len(data) != e.Size {
+			return nil, fmt.Errorf("%s does not match its manifest hash", e.Name)
+		}
+		if link(prev, e) != e.Chain {
+			return nil, fmt.Errorf("hash chain broken at %s", e.Name)
+		}
+		prev = e.Chain
+		delete(files, e.Name)
+	}
+	if prev != m.Root {
+		return nil, fmt.Errorf("hash chain does not end at the manifest root")
+	}
+
+	delete(files, ManifestName)
+	for name := range files {
+		return nil, fmt.Errorf("%s is in the archive but not the manifest", name)
+	}
+	return &m, nil
+}
+
+// ParsePrivateKey decodes a base64 Ed25519 seed, as produced by GenerateKey
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must be a base64 %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be a base64 %d-byte Ed25519 key", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// GenerateKey returns a new base64 signing seed and its public key
+func GenerateKey() (seed, public string, err error) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.Seed()), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+func verifySignature(m Manifest, trusted ed25519.PublicKey) error {
+	if m.Signature == "" {
+		return fmt.Errorf("manifest is not signed")
+	}
+	embedded, err := ParsePublicKey(m.PublicKey)
+	if err != nil {
+		return err
+	}
+	if trusted != nil && !trusted.Equal(embedded) {
+		return fmt.Errorf("manifest was signed by an untrusted key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+
+	m.Signature = ""
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if !ed25519.Verify(embedded, payload, sig) {
+		return fmt.Errorf("manifest signature is invalid")
+	}
+	return nil
+}
+
+// link chains an entry to the chain hash before it
+func link(prev string, e Entry) string {
+	sum := sha256.Sum256([]byte(prev + "\n" + e.Name + "\n" + e.SHA256))
+	return hex.EncodeToString(sum[:])
+}
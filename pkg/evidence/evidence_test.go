@@ -0,0 +1,105 @@
+package evidence
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func buildArchive(t *testing.T) (*Archive, string) {
+	t.Helper()
+	seed, public, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, err := ParsePrivateKey(seed)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := New(start, start.AddDate(0, 3, 0), "auditor", start.AddDate(0, 4, 0))
+	if err := AddJSONLines(a, "audit.jsonl", []map[string]string{{"action": "grant"}, {"action": "revoke"}}); err != nil {
+		t.Fatalf("AddJSONLines() error = %v", err)
+	}
+	a.Add("conversations/conv-1.json", []byte(`{"conversation_id":"conv-1"}`))
+	if err := a.Sign(key); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return a, public
+}
+
+func TestVerify(t *testing.T) {
+	a, public := buildArchive(t)
+	var buf bytes.Buffer
+	if err := a.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	trusted, _ := ParsePublicKey(public)
+	m, err := Verify(bytes.NewReader(buf.Bytes()), trusted)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(m.Entries) != 2 || m.Root != m.Entries[1].Chain {
+		t.Errorf("Verify() manifest = %+v", m)
+	}
+
+	_, other, _ := GenerateKey()
+	untrusted, _ := ParsePublicKey(other)
+	if _, err := Verify(bytes.NewReader(buf.Bytes()), untrusted); err == nil || !strings.Contains(err.Error(), "untrusted") {
+		t.Errorf("Verify(untrusted key) error = %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	a, _ := buildArchive(t)
+	var buf bytes.Buffer
+	if err := a.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	tampered := rewrite(t, buf.Bytes(), "audit.jsonl", []byte(`{"action":"grant"}`+"\n"))
+	if _, err := Verify(bytes.NewReader(tampered), nil); err == nil || !strings.Contains(err.Error(), "audit.jsonl") {
+		t.Errorf("Verify(edited file) error = %v", err)
+	}
+}
+
+// rewrite replaces one file in a gzipped tarball
+func rewrite(t *testing.T, archive []byte, name string, data []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	var out bytes.Buffer
+	gw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tr)
+		if hdr.Name == name {
+			content = data
+			hdr.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(content)
+	}
+	tw.Close()
+	gw.Close()
+	return out.Bytes()
+}
@@ -0,0 +1,131 @@
+package followups
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// Instructions tells the model how to suggest follow-ups
+const Instructions = `Follow-ups:
+End every reply with two or three short follow-up actions the user is likely to want next, phrased as requests to you, one per line:
+
+<followups>
+Show error logs for the last hour
+Compare with last week
+Check the ALB target health
+</followups>
+
+Keep each under 60 characters. The block is shown as buttons and removed from your reply.`
+
+// Block and action identifiers for the suggestion buttons
+const (
+	BlockID      = "followups"
+	ActionPrefix = "followup_"
+)
+
+// MetadataEventType marks the bot message that records a clicked
+// suggestion, so the agent treats it as input from the user who clicked
+const MetadataEventType = "cloudops_followup"
+
+// maxSuggestions and maxButtonText follow Slack's button limits
+const (
+	maxSuggestions = 3
+	maxButtonText  = 75
+)
+
+var blockPattern = regexp.MustCompile(`(?s)\s*<followups>(.*?)</followups>\s*`)
+
+// Parse removes the follow-ups block from a response and returns the
+// suggestions it listed
+func Parse(response string) (string, []string) {
+	match := blockPattern.FindStringSubmatch(response)
+	if match == nil {
+		return response, nil
+	}
+	cleaned := strings.TrimSpace(blockPattern.ReplaceAllString(response, "\n"))
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(match[1], "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.)"))
+		if line == "" || seen[strings.ToLower(line)] {
+			continue
+		}
+		seen[strings.ToLower(line)] = true
+		suggestions = append(suggestions, truncate(line, maxButtonText))
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return cleaned, suggestions
+}
+
+// Block renders suggestions as buttons, or returns nil when there are none
+func Block(suggestions []string) slack.Block {
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	buttons := make([]slack.BlockElement, 0, len(suggestions))
+	for i, s := range suggestions {
+		text := slack.NewTextBlockObject(slack.PlainTextType, s, false, false)
+		buttons = append(buttons, slack.NewButtonBlockElement(fmt.Sprintf("%s%d", ActionPrefix, i+1), s, text))
+	}
+	return slack.NewActionBlock(BlockID, buttons...)
+}
+
+// IsAction reports whether an interaction action is a follow-up click
+func IsAction(actionID string) bool {
+	return strings.HasPrefix(actionID, ActionPrefix)
+}
+
+// Chosen is the visible record of a clicked suggestion
+func Chosen(userID, suggestion string) string {
+	return fmt.Sprintf("➡️ <@%s> asked: *%s*", userID, suggestion)
+}
+
+// Metadata carries a clicked suggestion on the record message
+func Metadata(userID, suggestion string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"user_id": userID,
+			"text":    suggestion,
+		},
+	}
+}
+
+// FromMetadata extracts a clicked suggestion from message metadata
+func FromMetadata(meta slack.SlackMetadata) (userID, text string, ok bool) {
+	if meta.EventType != MetadataEventType {
+		return "", "", false
+	}
+	userID, _ = meta.EventPayload["user_id"].(string)
+	text, _ = meta.EventPayload["text"].(string)
+	return userID, text, userID != "" && text != ""
+}
+
+// WithoutButtons returns a message's blocks minus the suggestion buttons, so
+// a suggestion can only be clicked once
+func WithoutButtons(blocks []slack.Block) []slack.Block {
+	kept := make([]slack.Block, 0, len(blocks))
+	for _, b := range blocks {
+		if action, ok := b.(*slack.ActionBlock); ok && action.BlockID == BlockID {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept
+}
+
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
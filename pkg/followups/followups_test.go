@@ -0,0 +1,70 @@
+package followups
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestParse(t *testing.T) {
+	response := "CPU is fine.\n\n<followups>\n- Show error logs\n2. Compare with last week\nShow error logs\nCheck the ALB\nCheck RDS\n</followups>"
+
+	cleaned, got := Parse(response)
+	if cleaned != "CPU is fine." {
+		t.Errorf("Parse() cleaned = %q, want %q", cleaned, "CPU is fine.")
+	}
+
+	want := []string{"Show error logs", "Compare with last week", "Check the ALB"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Parse() suggestions = %v, want %v", got, want)
+	}
+}
+
+func TestParseNoBlock(t *testing.T) {
+	cleaned, got := Parse("Just an answer")
+	if cleaned != "Just an answer" || got != nil {
+		t.Errorf("Parse() = %q, %v, want unchanged text and no suggestions", cleaned, got)
+	}
+}
+
+func TestParseTruncates(t *testing.T) {
+	_, got := Parse("<followups>" + strings.Repeat("x", 100) + "</followups>")
+	if len(got) != 1 || len([]rune(got[0])) != maxButtonText {
+		t.Errorf("Parse() = %v, want one suggestion of %d characters", got, maxButtonText)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	if Block(nil) != nil {
+		t.Error("Block(nil) should be nil")
+	}
+
+	block, ok := Block([]string{"Show logs", "Check ALB"}).(*slack.ActionBlock)
+	if !ok {
+		t.Fatal("Block() should return an action block")
+	}
+	if len(block.Elements.ElementSet) != 2 {
+		t.Fatalf("Block() has %d buttons, want 2", len(block.Elements.ElementSet))
+	}
+	button := block.Elements.ElementSet[1].(*slack.ButtonBlockElement)
+	if !IsAction(button.ActionID) || button.Value != "Check ALB" {
+		t.Errorf("button = %s/%s, want a follow-up action with the suggestion as value", button.ActionID, button.Value)
+	}
+
+	remaining := WithoutButtons([]slack.Block{slack.NewDividerBlock(), block})
+	if len(remaining) != 1 {
+		t.Errorf("WithoutButtons() kept %d blocks, want 1", len(remaining))
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	userID, text, ok := FromMetadata(Metadata("U123", "Check the ALB"))
+	if !ok || userID != "U123" || text != "Check the ALB" {
+		t.Errorf("FromMetadata() = %s, %s, %v, want U123, Check the ALB, true", userID, text, ok)
+	}
+
+	if _, _, ok := FromMetadata(slack.SlackMetadata{EventType: "other"}); ok {
+		t.Error("FromMetadata() should ignore other event types")
+	}
+}
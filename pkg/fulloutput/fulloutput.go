@@ -0,0 +1,125 @@
+package fulloutput
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Defaults for how much of a code block is shown in the message
+const (
+	DefaultMaxLines = 25
+	DefaultMaxBytes = 2000
+)
+
+// Block and action identifiers for the "Show full output" buttons
+const (
+	BlockID      = "full_output"
+	ActionPrefix = "full_output_"
+)
+
+// Output is the complete content of a code block shortened in a message
+type Output struct {
+	Lang    string
+	Content string
+}
+
+var codeBlockPattern = regexp.MustCompile("(?s)```([A-Za-z0-9_+-]*)[ \t]*\n(.*?)\n?```")
+
+// Truncate shortens code blocks longer than maxLines lines or maxBytes
+// bytes to their first lines, noting how much was left out, and returns
+// the full content of each shortened block in order
+func Truncate(text string, maxLines, maxBytes int) (string, []Output) {
+	var outputs []Output
+	text = codeBlockPattern.ReplaceAllStringFunc(text, func(block string) string {
+		m := codeBlockPattern.FindStringSubmatch(block)
+		lang, content := m[1], m[2]
+
+		lines := strings.Split(content, "\n")
+		if len(lines) <= maxLines && len(content) <= maxBytes {
+			return block
+		}
+
+		kept, size := 0, 0
+		for kept < len(lines) && kept < maxLines && size+len(lines[kept])+1 <= maxBytes {
+			size += len(lines[kept]) + 1
+			kept++
+		}
+
+		outputs = append(outputs, Output{Lang: lang, Content: content})
+		note := fmt.Sprintf("_%d more %s not shown, use Show full output below_", len(lines)-kept, plural(len(lines)-kept, "line"))
+		if kept == 0 {
+			note = "_Output too long to show, use Show full output below_"
+		}
+		return "```" + lang + "\n" + strings.Join(lines[:kept], "\n") + "\n```\n" + note
+	})
+	return text, outputs
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// extensions names snippet files after the code block's language so Slack
+// highlights them
+var extensions = map[string]string{
+	"bash":       "sh",
+	"sh":         "sh",
+	"shell":      "sh",
+	"console":    "sh",
+	"json":       "json",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"python":     "py",
+	"py":         "py",
+	"go":         "go",
+	"sql":        "sql",
+	"javascript": "js",
+	"js":         "js",
+	"typescript": "ts",
+	"ts":         "ts",
+	"hcl":        "tf",
+	"terraform":  "tf",
+	"diff":       "diff",
+	"xml":        "xml",
+}
+
+// Filename returns the snippet file name for the output at index
+func (o Output) Filename(index int) string {
+	ext, ok := extensions[strings.ToLower(o.Lang)]
+	if !ok {
+		ext = "txt"
+	}
+	return fmt.Sprintf("output-%d.%s", index+1, ext)
+}
+
+// Block renders a "Show full output" button per output, or returns nil
+// when there are none
+func Block(outputs []Output) slack.Block {
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	buttons := make([]slack.BlockElement, 0, len(outputs))
+	for i := range outputs {
+		label := "Show full output"
+		if len(outputs) > 1 {
+			label = fmt.Sprintf("Show full output %d", i+1)
+		}
+		text := slack.NewTextBlockObject(slack.PlainTextType, label, false, false)
+		buttons = append(buttons, slack.NewButtonBlockElement(fmt.Sprintf("%s%d", ActionPrefix, i), strconv.Itoa(i), text))
+	}
+	return slack.NewActionBlock(BlockID, buttons...)
+}
+
+// IsAction reports whether an interaction action is a "Show full output"
+// click
+func IsAction(actionID string) bool {
+	return strings.HasPrefix(actionID, ActionPrefix)
+}
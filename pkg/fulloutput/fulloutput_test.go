@@ -0,0 +1,89 @@
+package fulloutput
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTruncateLeavesShortBlocks(t *testing.T) {
+	text := "Output:\n```\n" + numberedLines(3) + "\n```\nDone."
+	got, outputs := Truncate(text, 10, 1000)
+	if got != text || len(outputs) != 0 {
+		t.Errorf("Truncate() = %q, %v; want unchanged", got, outputs)
+	}
+}
+
+func TestTruncateLongBlock(t *testing.T) {
+	full := numberedLines(40)
+	text := "Logs:\n```bash\n" + full + "\n```\nShort:\n```\nok\n```"
+	got, outputs := Truncate(text, 5, 1000)
+
+	if len(outputs) != 1 || outputs[0].Content != full || outputs[0].Lang != "bash" {
+		t.Fatalf("outputs = %+v, want the full bash block", outputs)
+	}
+	want := "Logs:\n```bash\n" + numberedLines(5) + "\n```\n_35 more lines not shown, use Show full output below_\nShort:\n```\nok\n```"
+	if got != want {
+		t.Errorf("Truncate() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTruncateByBytes(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	text := "```\n" + long + "\n" + long + "\n" + long + "\n```"
+	got, outputs := Truncate(text, 100, 100)
+
+	if len(outputs) != 1 {
+		t.Fatalf("outputs = %d, want 1", len(outputs))
+	}
+	if !strings.Contains(got, "_2 more lines not shown") {
+		t.Errorf("Truncate() = %q, want one line kept", got)
+	}
+
+	got, _ = Truncate("```\n"+strings.Repeat("y", 500)+"\n```", 10, 100)
+	if !strings.Contains(got, "Output too long to show") {
+		t.Errorf("Truncate() = %q, want a note for a single oversized line", got)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"bash", "output-1.sh"},
+		{"JSON", "output-1.json"},
+		{"", "output-1.txt"},
+		{"cobol", "output-1.txt"},
+	}
+	for _, tt := range tests {
+		if got := (Output{Lang: tt.lang}).Filename(0); got != tt.want {
+			t.Errorf("Filename() for %q = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestBlock(t *testing.T) {
+	if Block(nil) != nil {
+		t.Error("Block(nil) should be nil")
+	}
+
+	block, ok := Block([]Output{{}, {}}).(*slack.ActionBlock)
+	if !ok || len(block.Elements.ElementSet) != 2 {
+		t.Fatalf("Block() = %#v, want two buttons", block)
+	}
+	button := block.Elements.ElementSet[1].(*slack.ButtonBlockElement)
+	if !IsAction(button.ActionID) || button.Value != "1" || button.Text.Text != "Show full output 2" {
+		t.Errorf("second button = %+v", button)
+	}
+}
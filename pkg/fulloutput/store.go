@@ -0,0 +1,100 @@
+package fulloutput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+)
+
+// ErrNotFound is returned by Get when no output was saved for the button
+var ErrNotFound = errors.New("full output not found")
+
+// Store saves full outputs to S3, keyed by the message showing the
+// shortened version
+type Store struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewStore creates an output store for the given bucket
+func NewStore(cfg aws.Config, bucket string) *Store {
+	return &Store{
+		client: s3.NewFromConfig(cfg),
+		bucket: bucket,
+	}
+}
+
+// key is where an output of a message is stored
+func key(channelID, ts string, index int) string {
+	return fmt.Sprintf("outputs/%s/%s/%d", channelID, ts, index)
+}
+
+// Save stores the outputs shortened in the message at ts
+func (s *Store) Save(ctx context.Context, channelID, ts string, outputs []Output) error {
+	for i, o := range outputs {
+		_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucket),
+			Key:         aws.String(key(channelID, ts, i)),
+			Body:        strings.NewReader(o.Content),
+			ContentType: aws.String("text/plain; charset=utf-8"),
+			Metadata:    map[string]string{"lang": o.Lang},
+		})
+		if err != nil {
+			return fmt.Errorf("put output: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get returns an output of the message at ts
+func (s *Store) Get(ctx context.Context, channelID, ts string, index int) (*Output, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key(channelID, ts, index)),
+	})
+	if err != nil {
+		var missing *types.NoSuchKey
+		if errors.As(err, &missing) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get output: %w", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read output: %w", err)
+	}
+	return &Output{Lang: resp.Metadata["lang"], Content: string(content)}, nil
+}
+
+// Uploader posts files to Slack
+type Uploader interface {
+	UploadFile(ctx context.Context, channelID, threadTS, filename, title string, data []byte) error
+}
+
+// Serve uploads the output behind a clicked button as a snippet in the
+// message's thread
+func (s *Store) Serve(ctx context.Context, uploader Uploader, channelID, ts, value string) error {
+	index, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid output index %q", value)
+	}
+
+	output, err := s.Get(ctx, channelID, ts, index)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "serving full output", "index", index, "message_ts", ts, logging.ChannelID, channelID, "bytes", len(output.Content))
+	return uploader.UploadFile(ctx, channelID, ts, output.Filename(index), "Full output", []byte(output.Content))
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DefaultClientCertNames is the common name on the client certificate Slack
+// presents when mutual TLS is enabled for the app
+var DefaultClientCertNames = []string{"platform-tls-client.slack.com"}
+
+// Reasons a request is turned away before its signature is checked
+var (
+	ErrSourceNotAllowed     = errors.New("source address not allowed")
+	ErrClientCertRequired   = errors.New("client certificate required")
+	ErrClientCertNotTrusted = errors.New("client certificate not trusted")
+)
+
+// AccessPolicy restricts which callers can reach the webhook. The zero
+// value allows everyone
+type AccessPolicy struct {
+	// AllowedNetworks are the source ranges accepted; empty allows any
+	AllowedNetworks []*net.IPNet
+
+	// RequireClientCert rejects requests the entrypoint didn't
+	// authenticate with mutual TLS
+	RequireClientCert bool
+
+	// ClientCertNames are the accepted certificate common names; empty
+	// accepts any certificate the entrypoint verified
+	ClientCertNames []string
+}
+
+// ParseNetworks parses CIDR ranges, accepting bare addresses as single hosts
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address: %s", v)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range: %s", v)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Check returns an error when the request's source or client certificate
+// isn't allowed by the policy
+func (p AccessPolicy) Check(r *Request) error {
+	if len(p.AllowedNetworks) > 0 && !p.allows(net.ParseIP(r.SourceIP)) {
+		return fmt.Errorf("%w: %q", ErrSourceNotAllowed, r.SourceIP)
+	}
+
+	if !p.RequireClientCert {
+		return nil
+	}
+	if r.ClientCertSubject == "" {
+		return ErrClientCertRequired
+	}
+	if len(p.ClientCertNames) == 0 {
+		return nil
+	}
+	name := CommonName(r.ClientCertSubject)
+	for _, allowed := range p.ClientCertNames {
+		if strings.EqualFold(name, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrClientCertNotTrusted, r.ClientCertSubject)
+}
+
+func (p AccessPolicy) allows(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.AllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommonName returns the CN attribute of a distinguished name such as
+// "CN=platform-tls-client.slack.com,O=Slack Technologies\, LLC,C=US"
+func CommonName(dn string) string {
+	var attr strings.Builder
+	escaped := false
+	flush := func() string {
+		s := strings.TrimSpace(attr.String())
+		attr.Reset()
+		if key, value, ok := strings.Cut(s, "="); ok && strings.EqualFold(strings.TrimSpace(key), "CN") {
+			return strings.TrimSpace(value)
+		}
+		return ""
+	}
+
+	for _, c := range dn {
+		switch {
+		case escaped:
+			attr.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',' || c == '/':
+			if cn := flush(); cn != "" {
+				return cn
+			}
+		default:
+			attr.WriteRune(c)
+		}
+	}
+	return flush()
+}
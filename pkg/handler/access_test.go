@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", "192.0.2.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseNetworks() error = %v", err)
+	}
+	if len(networks) != 3 || networks[1].String() != "192.0.2.7/32" {
+		t.Errorf("ParseNetworks() = %v", networks)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "slack"} {
+		if _, err := ParseNetworks([]string{bad}); err == nil {
+			t.Errorf("ParseNetworks(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAccessPolicyCheck(t *testing.T) {
+	networks, _ := ParseNetworks([]string{"203.0.113.0/24"})
+	slackSubject := `CN=platform-tls-client.slack.com,O=Slack Technologies\, LLC,C=US`
+
+	tests := []struct {
+		name    string
+		policy  AccessPolicy
+		request Request
+		wantErr error
+	}{
+		{"open", AccessPolicy{}, Request{}, nil},
+		{"allowed source", AccessPolicy{AllowedNetworks: networks}, Request{SourceIP: "203.0.113.9"}, nil},
+		{"other source", AccessPolicy{AllowedNetworks: networks}, Request{SourceIP: "198.51.100.1"}, ErrSourceNotAllowed},
+		{"unknown source", AccessPolicy{AllowedNetworks: networks}, Request{}, ErrSourceNotAllowed},
+		{"missing cert", AccessPolicy{RequireClientCert: true}, Request{}, ErrClientCertRequired},
+		{"any verified cert", AccessPolicy{RequireClientCert: true}, Request{ClientCertSubject: "CN=internal"}, nil},
+		{"slack cert", AccessPolicy{RequireClientCert: true, ClientCertNames: DefaultClientCertNames}, Request{ClientCertSubject: slackSubject}, nil},
+		{"other cert", AccessPolicy{RequireClientCert: true, ClientCertNames: DefaultClientCertNames}, Request{ClientCertSubject: "CN=evil.example.com"}, ErrClientCertNotTrusted},
+	}
+
+	for _, tt := range tests {
+		err := tt.policy.Check(&tt.request)
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("%s: Check() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCommonName(t *testing.T) {
+	tests := map[string]string{
+		"CN=platform-tls-client.slack.com,O=Slack Technologies\\, LLC": "platform-tls-client.slack.com",
+		"C=US, O=Example\\, Inc., CN=bot.example.com":                  "bot.example.com",
+		"/C=US/CN=legacy.example.com":                                  "legacy.example.com",
+		"O=No Common Name":                                             "",
+	}
+
+	for dn, want := range tests {
+		if got := CommonName(dn); got != want {
+			t.Errorf("CommonName(%q) = %q, want %q", dn, got, want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Lambda entrypoints the Slack handler can sit behind
+const (
+	SourceAPIGateway  = "apigateway"
+	SourceFunctionURL = "functionurl"
+	SourceALB         = "alb"
+
+	// SourceHTTP is a plain HTTP server, used in local development
+	SourceHTTP = "http"
+)
+
+// Request is an HTTP request normalized across Lambda entrypoints. Header
+// names are lower-cased and the body is always decoded
+type Request struct {
+	Source  string
+	Method  string
+	Path    string
+	Headers map[string]string
+	Body    string
+
+	// SourceIP is the caller's address as seen by the entrypoint
+	SourceIP string
+
+	// ClientCertSubject is the subject of the client certificate the
+	// entrypoint verified with mutual TLS, empty when there was none
+	ClientCertSubject string
+}
+
+// Header returns a request header, matching the name case-insensitively
+func (r *Request) Header(name string) string {
+	return r.Headers[strings.ToLower(name)]
+}
+
+// Response is an HTTP response to encode for the originating entrypoint
+type Response struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+}
+
+// ALB request headers carrying the caller's address and, for listeners with
+// mutual TLS in verify mode, the verified client certificate
+const (
+	forwardedForHeader     = "x-forwarded-for"
+	albClientSubjectHeader = "x-amzn-mtls-clientcert-subject"
+)
+
+// envelope holds the fields used to tell the entrypoint payloads apart
+type envelope struct {
+	Version        string `json:"version"`
+	RequestContext struct {
+		ELB  *json.RawMessage `json:"elb"`
+		HTTP *json.RawMessage `json:"http"`
+
+		// Set by API Gateway custom domains with mutual TLS, which the
+		// events package doesn't decode for REST APIs
+		Identity struct {
+			ClientCert *struct {
+				SubjectDN string `json:"subjectDN"`
+			} `json:"clientCert"`
+		} `json:"identity"`
+	} `json:"requestContext"`
+}
+
+// DecodeRequest parses an API Gateway (REST), Lambda Function URL, or ALB
+// invocation payload into a Request
+func DecodeRequest(payload []byte) (*Request, error) {
+	var env envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, fmt.Errorf("decode request: %w", err)
+	}
+
+	switch {
+	case env.RequestContext.ELB != nil:
+		var event events.ALBTargetGroupRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decode alb request: %w", err)
+		}
+		headers := event.Headers
+		if len(event.MultiValueHeaders) > 0 {
+			headers = firstValues(event.MultiValueHeaders)
+		}
+		req, err := newRequest(SourceALB, event.HTTPMethod, event.Path, headers, event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		req.SourceIP = lastForwardedFor(req.Header(forwardedForHeader))
+		req.ClientCertSubject = req.Header(albClientSubjectHeader)
+		return req, nil
+
+	case env.RequestContext.HTTP != nil:
+		var event events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decode function url request: %w", err)
+		}
+		req, err := newRequest(SourceFunctionURL, event.RequestContext.HTTP.Method, event.RawPath, event.Headers, event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		req.SourceIP = event.RequestContext.HTTP.SourceIP
+		return req, nil
+
+	default:
+		var event events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("decode api gateway request: %w", err)
+		}
+		headers := event.Headers
+		if len(headers) == 0 && len(event.MultiValueHeaders) > 0 {
+			headers = firstValues(event.MultiValueHeaders)
+		}
+		req, err := newRequest(SourceAPIGateway, event.HTTPMethod, event.Path, headers, event.Body, event.IsBase64Encoded)
+		if err != nil {
+			return nil, err
+		}
+		req.SourceIP = event.RequestContext.Identity.SourceIP
+		if cert := env.RequestContext.Identity.ClientCert; cert != nil {
+			req.ClientCertSubject = cert.SubjectDN
+		}
+		return req, nil
+	}
+}
+
+// lastForwardedFor returns the address the load balancer appended to
+// X-Forwarded-For. Earlier entries are supplied by the caller and can't be
+// trusted
+func lastForwardedFor(header string) string {
+	parts := strings.Split(header, ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+func newRequest(source, method, path string, headers map[string]string, body string, base64Encoded bool) (*Request, error) {
+	if base64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("decode base64 body: %w", err)
+		}
+		body = string(decoded)
+	}
+
+	lowered := make(map[string]string, len(headers))
+	for k, v := range headers {
+		lowered[strings.ToLower(k)] = v
+	}
+
+	return &Request{
+		Source:  source,
+		Method:  method,
+		Path:    path,
+		Headers: lowered,
+		Body:    body,
+	}, nil
+}
+
+// ReadRequest reads a plain HTTP request into a Request
+func ReadRequest(r *http.Request) (*Request, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	req, err := newRequest(SourceHTTP, r.Method, r.URL.Path, firstValues(r.Header), string(body), false)
+	if err != nil {
+		return nil, err
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		req.SourceIP = host
+	}
+	return req, nil
+}
+
+// WriteResponse writes a Response to a plain HTTP response
+func WriteResponse(w http.ResponseWriter, resp *Response) {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.WriteString(w, resp.Body)
+}
+
+func firstValues(multi map[string][]string) map[string]string {
+	headers := make(map[string]string, len(multi))
+	for k, values := range multi {
+		if len(values) > 0 {
+			headers[k] = values[0]
+		}
+	}
+	return headers
+}
+
+// EncodeResponse converts a Response into the payload the entrypoint expects
+func EncodeResponse(source string, resp *Response) interface{} {
+	switch source {
+	case SourceALB:
+		return events.ALBTargetGroupResponse{
+			StatusCode:        resp.StatusCode,
+			StatusDescription: fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			Headers:           resp.Headers,
+			Body:              resp.Body,
+		}
+	case SourceFunctionURL:
+		return events.LambdaFunctionURLResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Headers,
+			Body:       resp.Body,
+		}
+	default:
+		return events.APIGatewayProxyResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    resp.Headers,
+			Body:       resp.Body,
+		}
+	}
+}
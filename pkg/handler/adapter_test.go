@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	body := `{"type":"url_verification","challenge":"abc"}`
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+
+	tests := []struct {
+		name       string
+		payload    string
+		wantSource string
+		wantPath   string
+	}{
+		{
+			"api gateway",
+			`{"httpMethod":"POST","path":"/slack/events","headers":{"X-Slack-Signature":"v0=sig"},"body":` + strconv.Quote(body) + `,"requestContext":{"stage":"dev"}}`,
+			SourceAPIGateway, "/slack/events",
+		},
+		{
+			"function url",
+			`{"version":"2.0","rawPath":"/","headers":{"x-slack-signature":"v0=sig"},"body":"` + encoded + `","isBase64Encoded":true,"requestContext":{"http":{"method":"POST","path":"/"}}}`,
+			SourceFunctionURL, "/",
+		},
+		{
+			"alb multi-value",
+			`{"httpMethod":"POST","path":"/slack/events","multiValueHeaders":{"x-slack-signature":["v0=sig"]},"body":` + strconv.Quote(body) + `,"requestContext":{"elb":{"targetGroupArn":"arn"}}}`,
+			SourceALB, "/slack/events",
+		},
+	}
+
+	for _, tt := range tests {
+		req, err := DecodeRequest([]byte(tt.payload))
+		if err != nil {
+			t.Errorf("%s: DecodeRequest() error = %v", tt.name, err)
+			continue
+		}
+		if req.Source != tt.wantSource || req.Path != tt.wantPath || req.Method != "POST" {
+			t.Errorf("%s: DecodeRequest() = %+v", tt.name, req)
+		}
+		if req.Body != body {
+			t.Errorf("%s: Body = %q, want %q", tt.name, req.Body, body)
+		}
+		if got := req.Header("X-Slack-Signature"); got != "v0=sig" {
+			t.Errorf("%s: Header() = %q, want v0=sig", tt.name, got)
+		}
+	}
+
+	if _, err := DecodeRequest([]byte(`{"version":"2.0","body":"%%%","isBase64Encoded":true,"requestContext":{"http":{}}}`)); err == nil {
+		t.Error("DecodeRequest() should reject invalid base64")
+	}
+}
+
+func TestDecodeRequestCaller(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		wantIP      string
+		wantSubject string
+	}{
+		{
+			"api gateway with mtls",
+			`{"httpMethod":"POST","path":"/slack/events","requestContext":{"identity":{"sourceIp":"203.0.113.5","clientCert":{"subjectDN":"CN=platform-tls-client.slack.com"}}}}`,
+			"203.0.113.5", "CN=platform-tls-client.slack.com",
+		},
+		{
+			"function url",
+			`{"version":"2.0","rawPath":"/","requestContext":{"http":{"method":"POST","sourceIp":"203.0.113.6"}}}`,
+			"203.0.113.6", "",
+		},
+		{
+			"alb spoofed forwarded-for",
+			`{"httpMethod":"POST","path":"/","headers":{"x-forwarded-for":"10.0.0.1, 203.0.113.7","x-amzn-mtls-clientcert-subject":"CN=platform-tls-client.slack.com"},"requestContext":{"elb":{"targetGroupArn":"arn"}}}`,
+			"203.0.113.7", "CN=platform-tls-client.slack.com",
+		},
+	}
+
+	for _, tt := range tests {
+		req, err := DecodeRequest([]byte(tt.payload))
+		if err != nil {
+			t.Errorf("%s: DecodeRequest() error = %v", tt.name, err)
+			continue
+		}
+		if req.SourceIP != tt.wantIP || req.ClientCertSubject != tt.wantSubject {
+			t.Errorf("%s: caller = %q %q, want %q %q", tt.name, req.SourceIP, req.ClientCertSubject, tt.wantIP, tt.wantSubject)
+		}
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	resp := &Response{StatusCode: 503, Headers: map[string]string{"Content-Type": "application/json"}, Body: "{}"}
+
+	alb, ok := EncodeResponse(SourceALB, resp).(events.ALBTargetGroupResponse)
+	if !ok || alb.StatusDescription != "503 Service Unavailable" {
+		t.Errorf("EncodeResponse(alb) = %+v", alb)
+	}
+	if _, ok := EncodeResponse(SourceFunctionURL, resp).(events.LambdaFunctionURLResponse); !ok {
+		t.Error("EncodeResponse(functionurl) should return a Function URL response")
+	}
+	if apigw, ok := EncodeResponse(SourceAPIGateway, resp).(events.APIGatewayProxyResponse); !ok || apigw.StatusCode != 503 {
+		t.Errorf("EncodeResponse(apigateway) = %+v", apigw)
+	}
+}
+
+func TestReadWriteHTTP(t *testing.T) {
+	body := `{"type":"url_verification","challenge":"abc"}`
+	r := httptest.NewRequest("POST", "/slack/events", strings.NewReader(body))
+	r.Header.Set("X-Slack-Signature", "v0=sig")
+
+	req, err := ReadRequest(r)
+	if err != nil {
+		t.Fatalf("ReadRequest() error = %v", err)
+	}
+	if req.Source != SourceHTTP || req.Path != "/slack/events" || req.Body != body || req.SourceIP != "192.0.2.1" {
+		t.Errorf("ReadRequest() = %+v", req)
+	}
+	if got := req.Header("X-Slack-Signature"); got != "v0=sig" {
+		t.Errorf("Header() = %q, want v0=sig", got)
+	}
+
+	w := httptest.NewRecorder()
+	WriteResponse(w, &Response{StatusCode: 401, Headers: map[string]string{"Content-Type": "application/json"}, Body: "{}"})
+	if w.Code != 401 || w.Header().Get("Content-Type") != "application/json" || w.Body.String() != "{}" {
+		t.Errorf("WriteResponse() = %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+}
@@ -3,9 +3,11 @@ package handler
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
 )
 
 // SlackClientInterface defines the interface for Slack operations
@@ -32,7 +34,7 @@ func NewChannelCreator(slackClient SlackClientInterface) *ChannelCreator {
 func (cc *ChannelCreator) CreateConversationChannel(ctx context.Context, userID string) (string, error) {
 	// Generate channel name
 	channelName := generateChannelName()
-	log.Printf("Creating private channel: %s", channelName)
+	slog.InfoContext(ctx, "creating private channel", "channel_name", channelName)
 
 	// Create the channel
 	channelID, err := cc.slackClient.CreateConversation(ctx, channelName)
@@ -40,12 +42,12 @@ func (cc *ChannelCreator) CreateConversationChannel(ctx context.Context, userID
 		return "", fmt.Errorf("create channel: %w", err)
 	}
 
-	log.Printf("Channel created: %s (ID: %s)", channelName, channelID)
+	slog.InfoContext(ctx, "channel created", "channel_name", channelName, logging.ChannelID, channelID)
 
 	// Invite the user
 	if err := cc.slackClient.InviteUsersToConversation(ctx, channelID, userID); err != nil {
 		// Log but don't fail - user might already be there
-		log.Printf("Warning: failed to invite user to channel: %v", err)
+		slog.WarnContext(ctx, "failed to invite user to channel", logging.ChannelID, channelID, logging.UserID, userID, "error", err)
 	}
 
 	return channelID, nil
@@ -63,9 +65,9 @@ func generateChannelName() string {
 
 // ArchiveConversationChannel archives a conversation channel (optional cleanup)
 func (cc *ChannelCreator) ArchiveConversationChannel(ctx context.Context, channelID string) error {
-	log.Printf("Archiving channel: %s", channelID)
+	slog.InfoContext(ctx, "archiving channel", logging.ChannelID, channelID)
 	if err := cc.slackClient.ArchiveConversation(ctx, channelID); err != nil {
-		log.Printf("Warning: failed to archive channel: %v", err)
+		slog.WarnContext(ctx, "failed to archive channel", logging.ChannelID, channelID, "error", err)
 		// Don't fail - archiving is optional
 	}
 	return nil
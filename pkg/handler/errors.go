@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/aws/smithy-go"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/slack-go/slack"
+)
+
+// Error codes returned in the body of failed responses
+const (
+	CodeInvalidSignature = "invalid_signature"
+	CodeInvalidRequest   = "invalid_request"
+	CodeForbidden        = "forbidden"
+	CodeConfigError      = "config_error"
+	CodeTransient        = "transient_error"
+	CodeInternal         = "internal_error"
+)
+
+// NoRetryHeader tells Slack not to redeliver an event we failed to process
+const NoRetryHeader = "X-Slack-No-Retry"
+
+// ErrorBody is the JSON body of a failed response
+type ErrorBody struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Retryable bool   `json:"retryable"`
+}
+
+// ErrorResponse returns a failure with a machine-readable error code. Slack
+// redelivers events on failure, so only transient errors leave retries on
+func ErrorResponse(status int, code, message string) *Response {
+	retryable := code == CodeTransient
+	data, _ := json.Marshal(ErrorBody{Error: message, Code: code, Retryable: retryable})
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if !retryable {
+		headers[NoRetryHeader] = "1"
+	}
+
+	return &Response{
+		StatusCode: status,
+		Body:       string(data),
+		Headers:    headers,
+	}
+}
+
+// transientAWSCodes are AWS error codes that usually succeed on retry
+var transientAWSCodes = map[string]bool{
+	"ThrottlingException":                    true,
+	"Throttling":                             true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"TransactionConflictException":           true,
+	"ServiceUnavailable":                     true,
+	"InternalServerError":                    true,
+	"InternalFailure":                        true,
+	"RequestTimeout":                         true,
+}
+
+// permanentError marks a failure that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable, typically because side effects
+// (a saved conversation, a posted message) already happened and a retry
+// would duplicate them
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsTransient reports whether err is likely to succeed if Slack redelivers
+// the event: throttling, timeouts, and AWS or Slack service errors
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, chaos.ErrInjected) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && transientAWSCodes[apiErr.ErrorCode()] {
+		return true
+	}
+
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/slack-go/slack"
+)
+
+func TestIsTransient(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException", Message: "slow down"}
+	denied := &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "no"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("boom"), false},
+		{"deadline", fmt.Errorf("save conversation: %w", context.DeadlineExceeded), true},
+		{"throttled", fmt.Errorf("put item: %w", throttled), true},
+		{"access denied", fmt.Errorf("put item: %w", denied), false},
+		{"slack rate limited", &slack.RateLimitedError{RetryAfter: time.Second}, true},
+		{"chaos", fmt.Errorf("dynamodb Save: %w", chaos.ErrInjected), true},
+		{"permanent", Permanent(fmt.Errorf("start step function: %w", throttled)), false},
+		{"wrapped permanent", fmt.Errorf("app mention: %w", Permanent(context.DeadlineExceeded)), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) should be nil")
+	}
+
+	err := Permanent(context.DeadlineExceeded)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Permanent() should unwrap to the original error")
+	}
+}
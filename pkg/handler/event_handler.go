@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
-	"log"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
 )
 
 // EventHandler handles Slack events
@@ -23,7 +25,7 @@ func NewEventHandler() *EventHandler {
 
 // HandleAppMention handles a Slack app mention event
 func (h *EventHandler) HandleAppMention(ctx context.Context, userID, channelID, command string) error {
-	log.Printf("Handling app mention from user %s in channel %s: %s", userID, channelID, command)
+	slog.InfoContext(ctx, "handling app mention", logging.UserID, userID, logging.ChannelID, channelID, "command", command)
 
 	// TODO: Implement app mention handling
 	// 1. Create new conversation record
@@ -38,7 +40,7 @@ func (h *EventHandler) HandleAppMention(ctx context.Context, userID, channelID,
 
 // HandleChannelMessage handles regular messages in a conversation channel
 func (h *EventHandler) HandleChannelMessage(ctx context.Context, conversationID, userID, text string) error {
-	log.Printf("Handling channel message for conversation %s from user %s: %s", conversationID, userID, text)
+	slog.InfoContext(ctx, "handling channel message", logging.ConversationID, conversationID, logging.UserID, userID, "text", text)
 
 	// TODO: This might not be needed if using Socket Mode in the agent
 	// If using API Gateway webhooks, implement message handling here
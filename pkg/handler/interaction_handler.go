@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/slack-go/slack"
+)
+
+// ActionFunc handles one clicked block action, such as a button
+type ActionFunc func(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error
+
+// ViewFunc handles a submitted modal
+type ViewFunc func(ctx context.Context, callback *slack.InteractionCallback) error
+
+// ShortcutFunc handles a message shortcut used on a message
+type ShortcutFunc func(ctx context.Context, callback *slack.InteractionCallback) error
+
+type actionRoute struct {
+	match   func(actionID string) bool
+	handler ActionFunc
+}
+
+// InteractionHandler validates and dispatches Slack interactive payloads:
+// block_actions for buttons, view_submission for modals, and
+// message_action for message shortcuts
+type InteractionHandler struct {
+	signingKeys []string
+	actions     []actionRoute
+	views       map[string]ViewFunc
+	shortcuts   map[string]ShortcutFunc
+}
+
+// NewInteractionHandler creates a handler accepting payloads signed with any
+// of the signing keys
+func NewInteractionHandler(signingKeys ...string) *InteractionHandler {
+	return &InteractionHandler{signingKeys: signingKeys, views: make(map[string]ViewFunc), shortcuts: make(map[string]ShortcutFunc)}
+}
+
+// OnAction handles the actions whose IDs satisfy match. Each action goes to
+// the first handler registered for it
+func (h *InteractionHandler) OnAction(match func(actionID string) bool, fn ActionFunc) {
+	h.actions = append(h.actions, actionRoute{match: match, handler: fn})
+}
+
+// OnViewSubmission handles submissions of the modal with callbackID
+func (h *InteractionHandler) OnViewSubmission(callbackID string, fn ViewFunc) {
+	h.views[callbackID] = fn
+}
+
+// OnShortcut handles the message shortcut with callbackID
+func (h *InteractionHandler) OnShortcut(callbackID string, fn ShortcutFunc) {
+	h.shortcuts[callbackID] = fn
+}
+
+// ActionID matches a single action ID
+func ActionID(id string) func(string) bool {
+	return func(actionID string) bool { return actionID == id }
+}
+
+// IsInteraction reports whether the request is a form-encoded interactive
+// payload rather than an event or slash command
+func IsInteraction(request *Request) bool {
+	return strings.HasPrefix(request.Header("Content-Type"), "application/x-www-form-urlencoded") &&
+		strings.HasPrefix(request.Body, "payload=")
+}
+
+// Verify reports whether the request carries a valid Slack signature
+func (h *InteractionHandler) Verify(request *Request) bool {
+	return ValidateSlackRequest(
+		[]byte(request.Body),
+		request.Header("X-Slack-Request-Timestamp"),
+		request.Header("X-Slack-Signature"),
+		h.signingKeys...,
+	)
+}
+
+// Handle verifies the request's signature and dispatches its payload
+func (h *InteractionHandler) Handle(ctx context.Context, request *Request) *Response {
+	if !h.Verify(request) {
+		return ErrorResponse(401, CodeInvalidSignature, "Invalid signature")
+	}
+	return h.Dispatch(ctx, request.Body)
+}
+
+// Dispatch runs the handlers for a verified payload. Slack expects a reply
+// within three seconds and can't show a failure usefully, so handler errors
+// are logged rather than returned
+func (h *InteractionHandler) Dispatch(ctx context.Context, body string) *Response {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return ErrorResponse(400, CodeInvalidRequest, "Invalid interaction")
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(values.Get("payload")), &callback); err != nil {
+		slog.ErrorContext(ctx, "failed to parse interaction payload", "error", err)
+		return ErrorResponse(400, CodeInvalidRequest, "Invalid interaction")
+	}
+	ctx = logging.With(ctx, logging.UserID, callback.User.ID, logging.ChannelID, callback.Channel.ID)
+
+	switch callback.Type {
+	case slack.InteractionTypeViewSubmission:
+		fn, ok := h.views[callback.View.CallbackID]
+		if !ok {
+			slog.InfoContext(ctx, "ignoring submission of unknown view", "callback_id", callback.View.CallbackID)
+		} else if err := fn(ctx, &callback); err != nil {
+			slog.ErrorContext(ctx, "failed to handle view submission", "callback_id", callback.View.CallbackID, "error", err)
+		}
+		return &Response{StatusCode: 200} // an empty body closes the modal
+
+	case slack.InteractionTypeBlockActions:
+		for _, action := range callback.ActionCallback.BlockActions {
+			h.runAction(ctx, &callback, action)
+		}
+		return okResponse()
+
+	case slack.InteractionTypeMessageAction:
+		fn, ok := h.shortcuts[callback.CallbackID]
+		if !ok {
+			slog.InfoContext(ctx, "ignoring unknown shortcut", "callback_id", callback.CallbackID)
+		} else if err := fn(ctx, &callback); err != nil {
+			slog.ErrorContext(ctx, "failed to handle shortcut", "callback_id", callback.CallbackID, "error", err)
+		}
+		return okResponse()
+
+	default:
+		slog.InfoContext(ctx, "ignoring interaction type", "type", callback.Type)
+		return okResponse()
+	}
+}
+
+// runAction runs the first handler registered for an action
+func (h *InteractionHandler) runAction(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) {
+	for _, route := range h.actions {
+		if !route.match(action.ActionID) {
+			continue
+		}
+		if err := route.handler(ctx, callback, action); err != nil {
+			slog.ErrorContext(ctx, "failed to handle action", "action_id", action.ActionID, "error", err)
+		}
+		return
+	}
+	slog.InfoContext(ctx, "ignoring unknown action", "action_id", action.ActionID)
+}
+
+func okResponse() *Response {
+	return &Response{
+		StatusCode: 200,
+		Body:       `{"ok":true}`,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+	}
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// signedInteraction builds a form-encoded interaction request signed with key
+func signedInteraction(key, payload string) *Request {
+	body := "payload=" + url.QueryEscape(payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, body)))
+	return &Request{
+		Headers: map[string]string{
+			"content-type":              "application/x-www-form-urlencoded",
+			"x-slack-request-timestamp": timestamp,
+			"x-slack-signature":         "v0=" + fmt.Sprintf("%x", h.Sum(nil)),
+		},
+		Body: body,
+	}
+}
+
+func TestInteractionHandlerSignature(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	payload := `{"type":"block_actions","actions":[{"block_id":"b1","action_id":"approve"}]}`
+
+	if resp := ih.Handle(context.Background(), signedInteraction("wrong-key", payload)); resp.StatusCode != 401 {
+		t.Errorf("StatusCode = %d, want 401", resp.StatusCode)
+	}
+	if resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", payload)); resp.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestInteractionHandlerActions(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	var calls []string
+	record := func(name string) ActionFunc {
+		return func(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+			calls = append(calls, name+":"+action.Value)
+			return nil
+		}
+	}
+	ih.OnAction(ActionID("approve"), record("approve"))
+	ih.OnAction(func(id string) bool { return id != "" }, record("any"))
+	ih.OnAction(ActionID("cancel"), record("cancel"))
+
+	payload := `{"type":"block_actions","actions":[{"block_id":"b1","action_id":"approve","value":"a1"},{"block_id":"b1","action_id":"cancel","value":"c1"}]}`
+	resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", payload))
+	if resp.StatusCode != 200 {
+		t.Fatalf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+
+	want := []string{"approve:a1", "any:c1"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestInteractionHandlerActionError(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	ih.OnAction(ActionID("approve"), func(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+		return errors.New("boom")
+	})
+
+	payload := `{"type":"block_actions","actions":[{"block_id":"b1","action_id":"approve"},{"block_id":"b1","action_id":"unknown"}]}`
+	if resp := ih.Dispatch(context.Background(), signedInteraction("test-signing-key", payload).Body); resp.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestInteractionHandlerViewSubmission(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	var got string
+	ih.OnViewSubmission("confirm", func(ctx context.Context, callback *slack.InteractionCallback) error {
+		got = callback.View.PrivateMetadata
+		return nil
+	})
+
+	payload := `{"type":"view_submission","view":{"callback_id":"confirm","private_metadata":"m1"}}`
+	resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", payload))
+	if resp.StatusCode != 200 || resp.Body != "" {
+		t.Errorf("response = %d %q, want 200 with an empty body", resp.StatusCode, resp.Body)
+	}
+	if got != "m1" {
+		t.Errorf("PrivateMetadata = %q, want m1", got)
+	}
+}
+
+func TestInteractionHandlerShortcut(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	var got string
+	ih.OnShortcut("ask", func(ctx context.Context, callback *slack.InteractionCallback) error {
+		got = callback.Message.Text
+		return nil
+	})
+
+	payload := `{"type":"message_action","callback_id":"ask","message":{"type":"message","text":"NullPointerException at Checkout.java:42","ts":"1.2"}}`
+	if resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", payload)); resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if got != "NullPointerException at Checkout.java:42" {
+		t.Errorf("Message.Text = %q", got)
+	}
+
+	// Other apps' shortcuts are acknowledged and ignored
+	unknown := `{"type":"message_action","callback_id":"other","message":{"type":"message","text":"x","ts":"1.2"}}`
+	if resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", unknown)); resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestInteractionHandlerInvalidPayload(t *testing.T) {
+	ih := NewInteractionHandler("test-signing-key")
+	if resp := ih.Handle(context.Background(), signedInteraction("test-signing-key", "not json")); resp.StatusCode != 400 {
+		t.Errorf("StatusCode = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestIsInteraction(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *Request
+		want    bool
+	}{
+		{"interaction", signedInteraction("k", "{}"), true},
+		{"event", &Request{Headers: map[string]string{"content-type": "application/json"}, Body: `{"type":"event_callback"}`}, false},
+		{"slash command", &Request{Headers: map[string]string{"content-type": "application/x-www-form-urlencoded"}, Body: "command=%2Fcloudops"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInteraction(tt.request); got != tt.want {
+				t.Errorf("IsInteraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
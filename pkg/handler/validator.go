@@ -4,42 +4,54 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
 )
 
 // ValidateSlackRequest validates the Slack request signature
-// This ensures the request came from Slack
+// This ensures the request came from Slack. Any of the signing keys may
+// match, so a new secret can be deployed alongside the old one while
+// Slack's is being rotated
 // See: https://api.slack.com/authentication/verifying-requests-from-slack
-func ValidateSlackRequest(body []byte, timestamp string, signature string, signingKey string) bool {
+func ValidateSlackRequest(body []byte, timestamp string, signature string, signingKeys ...string) bool {
 	// Validate timestamp is recent (not older than 5 minutes)
 	ts, err := strconv.ParseInt(timestamp, 10, 64)
 	if err != nil {
-		log.Printf("Invalid timestamp: %s", timestamp)
+		slog.Warn("invalid slack request timestamp", "timestamp", timestamp)
 		return false
 	}
 
 	now := time.Now().Unix()
 	if now-ts > 300 { // 5 minutes
-		log.Printf("Request timestamp too old: %d (current: %d)", ts, now)
+		slog.Warn("slack request timestamp too old", "timestamp", ts, "now", now)
 		return false
 	}
 
 	// Create signature base string: v0:<timestamp>:<body>
 	baseString := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
 
-	// Create HMAC SHA256 hash
-	h := hmac.New(sha256.New, []byte(signingKey))
-	h.Write([]byte(baseString))
-	expectedSig := "v0=" + fmt.Sprintf("%x", h.Sum(nil))
+	for i, signingKey := range signingKeys {
+		if signingKey == "" {
+			continue
+		}
 
-	// Compare with provided signature using constant-time comparison
-	if !hmac.Equal([]byte(expectedSig), []byte(signature)) {
-		log.Printf("Invalid signature. Expected: %s, Got: %s", expectedSig, signature)
-		return false
+		// Create HMAC SHA256 hash
+		h := hmac.New(sha256.New, []byte(signingKey))
+		h.Write([]byte(baseString))
+		expectedSig := "v0=" + fmt.Sprintf("%x", h.Sum(nil))
+
+		// Compare with provided signature using constant-time comparison
+		if hmac.Equal([]byte(expectedSig), []byte(signature)) {
+			if i > 0 {
+				slog.Info("slack request signature validated with secondary signing key", "key", i)
+			} else {
+				slog.Debug("slack request signature validated")
+			}
+			return true
+		}
 	}
 
-	log.Printf("Slack request signature validated successfully")
-	return true
+	slog.Warn("invalid slack request signature")
+	return false
 }
@@ -131,3 +131,25 @@ func TestValidateSlackRequestConstantTimeComparison(t *testing.T) {
 		t.Error("ValidateSlackRequest() should reject similar but invalid signature")
 	}
 }
+
+func TestValidateSlackRequestRotatedKeys(t *testing.T) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte("test")
+
+	h := hmac.New(sha256.New, []byte("new-key"))
+	h.Write([]byte(fmt.Sprintf("v0:%s:%s", timestamp, string(body))))
+	sig := "v0=" + fmt.Sprintf("%x", h.Sum(nil))
+
+	if !ValidateSlackRequest(body, timestamp, sig, "old-key", "new-key") {
+		t.Error("ValidateSlackRequest() should accept a signature from the secondary key")
+	}
+	if !ValidateSlackRequest(body, timestamp, sig, "new-key", "") {
+		t.Error("ValidateSlackRequest() should ignore an unset secondary key")
+	}
+	if ValidateSlackRequest(body, timestamp, sig, "old-key", "") {
+		t.Error("ValidateSlackRequest() should reject a signature from neither key")
+	}
+	if ValidateSlackRequest(body, timestamp, sig) {
+		t.Error("ValidateSlackRequest() should reject when no keys are configured")
+	}
+}
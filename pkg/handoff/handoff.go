@@ -0,0 +1,167 @@
+package handoff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+)
+
+// maxSummaryLength caps each conversation's one-line summary
+const maxSummaryLength = 120
+
+// Report summarizes the conversations touched during an on-call shift
+type Report struct {
+	Window   timerange.Range
+	Open     []*models.Conversation // still pending or active
+	Resolved []*models.Conversation // completed during the shift
+	Failed   []*models.Conversation // failed or timed out during the shift
+	Tags     map[string]int
+}
+
+// Build groups the conversations touched during the window
+func Build(conversations []*models.Conversation, window timerange.Range) *Report {
+	r := &Report{Window: window, Tags: make(map[string]int)}
+
+	seen := make(map[string]bool)
+	for _, conv := range conversations {
+		if seen[conv.ConversationID] || !Touched(conv, window) {
+			continue
+		}
+		seen[conv.ConversationID] = true
+
+		switch conv.Status {
+		case models.StatusPending, models.StatusActive:
+			r.Open = append(r.Open, conv)
+		case models.StatusCompleted:
+			r.Resolved = append(r.Resolved, conv)
+		default:
+			r.Failed = append(r.Failed, conv)
+		}
+
+		for _, tag := range conv.Tags {
+			r.Tags[tag]++
+		}
+	}
+
+	for _, list := range [][]*models.Conversation{r.Open, r.Resolved, r.Failed} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+	}
+
+	return r
+}
+
+// Touched reports whether a conversation was open or had activity during
+// the window
+func Touched(conv *models.Conversation, window timerange.Range) bool {
+	if conv.CreatedAt.After(window.End) {
+		return false
+	}
+	switch conv.Status {
+	case models.StatusPending, models.StatusActive:
+		return true
+	}
+	if conv.CompletedAt != nil && conv.CompletedAt.Before(window.Start) {
+		return false
+	}
+	return !conv.LastHeartbeat.Before(window.Start) || !conv.CreatedAt.Before(window.Start)
+}
+
+// IsEmpty reports whether nothing happened during the shift
+func (r *Report) IsEmpty() bool {
+	return len(r.Open)+len(r.Resolved)+len(r.Failed) == 0
+}
+
+// Render formats the report as a Slack message, with times in loc
+func (r *Report) Render(loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	window := timerange.Range{Start: r.Window.Start.In(loc), End: r.Window.End.In(loc)}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*🔁 Shift handoff* (%s)\n", window)
+
+	if r.IsEmpty() {
+		b.WriteString("Quiet shift: no conversations were opened or active. 🎉")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%d open • %d resolved • %d failed", len(r.Open), len(r.Resolved), len(r.Failed))
+	if tags := r.topTags(5); tags != "" {
+		fmt.Fprintf(&b, " • %s", tags)
+	}
+	b.WriteString("\n")
+
+	write := func(title string, list []*models.Conversation) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n*%s*\n", title)
+		for _, conv := range list {
+			b.WriteString("• " + summaryLine(conv, r.Window.End) + "\n")
+		}
+	}
+	write("🔥 Still open (needs an owner)", r.Open)
+	write("✅ Resolved", r.Resolved)
+	write("⚠️ Failed or timed out", r.Failed)
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// topTags lists the most common tags as "#a (3) #b (1)"
+func (r *Report) topTags(n int) string {
+	tags := make([]string, 0, len(r.Tags))
+	for tag := range r.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if r.Tags[tags[i]] != r.Tags[tags[j]] {
+			return r.Tags[tags[i]] > r.Tags[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > n {
+		tags = tags[:n]
+	}
+
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = fmt.Sprintf("#%s (%d)", tag, r.Tags[tag])
+	}
+	return strings.Join(parts, " ")
+}
+
+// summaryLine describes a conversation in one line, preferring the latest
+// scratchpad finding over the opening request
+func summaryLine(conv *models.Conversation, now time.Time) string {
+	summary := conv.InitialCommand
+	if conv.Scratchpad != nil && len(conv.Scratchpad.Findings) > 0 {
+		summary = conv.Scratchpad.Findings[len(conv.Scratchpad.Findings)-1]
+	}
+	summary = strings.Join(strings.Fields(summary), " ")
+	if runes := []rune(summary); len(runes) > maxSummaryLength {
+		summary = string(runes[:maxSummaryLength-1]) + "…"
+	}
+
+	line := fmt.Sprintf("<#%s> %s", conv.ChannelID, summary)
+	for _, tag := range conv.Tags {
+		line += " #" + tag
+	}
+
+	end := now
+	if conv.CompletedAt != nil {
+		end = *conv.CompletedAt
+	}
+	duration := "<1m"
+	if d := end.Sub(conv.CreatedAt).Truncate(time.Minute); d > 0 {
+		duration = humanize.Duration(d)
+	}
+	return line + fmt.Sprintf(" _(%s, started by <@%s>)_", duration, conv.UserID)
+}
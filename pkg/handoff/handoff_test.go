@@ -0,0 +1,78 @@
+package handoff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+)
+
+func conversation(id, status string, created, lastActive time.Time) *models.Conversation {
+	return &models.Conversation{
+		ConversationID: id,
+		ChannelID:      "C" + id,
+		UserID:         "U1",
+		Status:         status,
+		InitialCommand: "investigate " + id,
+		CreatedAt:      created,
+		LastHeartbeat:  lastActive,
+	}
+}
+
+func TestBuild(t *testing.T) {
+	end := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	window := timerange.Range{Start: end.Add(-12 * time.Hour), End: end}
+	completed := end.Add(-time.Hour)
+	beforeShift := window.Start.Add(-2 * time.Hour)
+
+	open := conversation("open", models.StatusActive, beforeShift.Add(-24*time.Hour), beforeShift)
+	open.Tags = []string{"payments"}
+
+	resolved := conversation("resolved", models.StatusCompleted, end.Add(-3*time.Hour), completed)
+	resolved.CompletedAt = &completed
+	resolved.Tags = []string{"payments", "sev2"}
+	resolved.Scratchpad = &models.Scratchpad{Findings: []string{"RDS failover at 17:02", "Root cause: connection storm"}}
+
+	stale := conversation("stale", models.StatusCompleted, beforeShift, beforeShift)
+	stale.CompletedAt = &beforeShift
+
+	failed := conversation("failed", models.StatusTimeout, end.Add(-5*time.Hour), end.Add(-4*time.Hour))
+
+	report := Build([]*models.Conversation{open, resolved, stale, failed, resolved}, window)
+
+	if len(report.Open) != 1 || len(report.Resolved) != 1 || len(report.Failed) != 1 {
+		t.Fatalf("Build() open/resolved/failed = %d/%d/%d, want 1/1/1", len(report.Open), len(report.Resolved), len(report.Failed))
+	}
+	if report.Tags["payments"] != 2 || report.Tags["sev2"] != 1 {
+		t.Errorf("Tags = %v, want payments=2 sev2=1", report.Tags)
+	}
+
+	text := report.Render(time.UTC)
+	for _, want := range []string{
+		"1 open • 1 resolved • 1 failed",
+		"#payments (2) #sev2 (1)",
+		"<#Cresolved> Root cause: connection storm #payments #sev2 _(2h, started by <@U1>)_",
+		"<#Copen> investigate open",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "stale") {
+		t.Errorf("Render() should not include conversations finished before the shift:\n%s", text)
+	}
+}
+
+func TestRenderQuietShift(t *testing.T) {
+	end := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	report := Build(nil, timerange.Range{Start: end.Add(-12 * time.Hour), End: end})
+
+	if !report.IsEmpty() {
+		t.Error("IsEmpty() = false for no conversations")
+	}
+	if text := report.Render(nil); !strings.Contains(text, "Quiet shift") {
+		t.Errorf("Render() = %q, want quiet shift message", text)
+	}
+}
@@ -0,0 +1,175 @@
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits are IEC binary units, matching what AWS consoles display
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+// Bytes formats a byte count with binary units, e.g. 1536 -> "1.5 KiB"
+func Bytes(n int64) string {
+	if n < 0 {
+		return "-" + Bytes(-n)
+	}
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(byteUnits)-1 {
+		value /= 1024
+		i++
+	}
+	return trimFloat(value, 1) + " " + byteUnits[i]
+}
+
+// Count formats an integer with thousands separators, e.g. 1234567 -> "1,234,567"
+func Count(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String()
+}
+
+// Percent formats a ratio (0.125) as a percentage ("12.5%")
+func Percent(ratio float64) string {
+	return trimFloat(ratio*100, 1) + "%"
+}
+
+// currencySymbols covers the currencies AWS bills in
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+}
+
+// Currency formats an amount in the given ISO currency code, e.g.
+// (1234.5, "USD") -> "$1,234.50". Amounts under a cent keep more precision
+// so per-request costs don't round to zero
+func Currency(amount float64, code string) string {
+	code = strings.ToUpper(code)
+	if code == "" {
+		code = "USD"
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	var number string
+	switch {
+	case amount > 0 && amount < 0.01:
+		number = strconv.FormatFloat(amount, 'f', 4, 64)
+	case code == "JPY":
+		number = Count(int64(math.Round(amount)))
+	default:
+		whole := math.Floor(amount)
+		cents := int64(math.Round((amount - whole) * 100))
+		if cents == 100 {
+			whole, cents = whole+1, 0
+		}
+		number = fmt.Sprintf("%s.%02d", Count(int64(whole)), cents)
+	}
+
+	if symbol, ok := currencySymbols[code]; ok {
+		return sign + symbol + number
+	}
+	return sign + number + " " + code
+}
+
+// Duration formats a duration using its two most significant units,
+// e.g. "1h 23m" or "45s"
+func Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + Duration(-d)
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+
+	parts := []struct {
+		unit  time.Duration
+		label string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	var out []string
+	for _, p := range parts {
+		if d < p.unit && len(out) == 0 {
+			continue
+		}
+		n := d / p.unit
+		d -= n * p.unit
+		if n > 0 {
+			out = append(out, fmt.Sprintf("%d%s", n, p.label))
+		}
+		if len(out) == 2 || (len(out) > 0 && n == 0) {
+			break
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// Relative describes t relative to now ("5m ago", "in 2h"), adding the
+// wall-clock time in loc for anything older than a day
+func Relative(t, now time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d >= 0 && d < time.Minute:
+		return "just now"
+	case d < 0 && d > -time.Minute:
+		return "in a moment"
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%s ago (%s)", Duration(d.Truncate(time.Hour)), t.In(loc).Format("Jan 2 15:04 MST"))
+	case d > 0:
+		return Duration(d.Truncate(time.Minute)) + " ago"
+	default:
+		return "in " + Duration((-d).Truncate(time.Minute))
+	}
+}
+
+// Epoch converts a Unix timestamp in seconds or milliseconds (as returned
+// by CloudWatch Logs) to a time
+func Epoch(n int64) time.Time {
+	if n > 1e12 {
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
+
+// trimFloat formats f with up to prec decimals, dropping trailing zeros
+func trimFloat(f float64, prec int) string {
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
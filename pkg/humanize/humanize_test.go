@@ -0,0 +1,119 @@
+package humanize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{1 << 30, "1 GiB"},
+		{5_368_709_120, "5 GiB"},
+		{-2048, "-2 KiB"},
+	}
+
+	for _, tt := range tests {
+		if got := Bytes(tt.n); got != tt.want {
+			t.Errorf("Bytes(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := map[int64]string{
+		0:        "0",
+		999:      "999",
+		1000:     "1,000",
+		1234567:  "1,234,567",
+		-1234567: "-1,234,567",
+	}
+
+	for n, want := range tests {
+		if got := Count(n); got != want {
+			t.Errorf("Count(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		amount float64
+		code   string
+		want   string
+	}{
+		{1234.5, "USD", "$1,234.50"},
+		{0.999, "usd", "$1.00"},
+		{0.0042, "USD", "$0.0042"},
+		{-12, "EUR", "-€12.00"},
+		{1500, "JPY", "¥1,500"},
+		{10, "CHF", "10.00 CHF"},
+		{3, "", "$3.00"},
+	}
+
+	for _, tt := range tests {
+		if got := Currency(tt.amount, tt.code); got != tt.want {
+			t.Errorf("Currency(%v, %s) = %s, want %s", tt.amount, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{250 * time.Millisecond, "250ms"},
+		{45 * time.Second, "45s"},
+		{83 * time.Minute, "1h 23m"},
+		{time.Hour + 30*time.Second, "1h"},
+		{26*time.Hour + 5*time.Minute, "1d 2h"},
+	}
+
+	for _, tt := range tests {
+		if got := Duration(tt.d); got != tt.want {
+			t.Errorf("Duration(%v) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-10 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(2 * time.Hour), "in 2h"},
+		{now.Add(-50 * time.Hour), "2d 2h ago (Apr 29 10:00 UTC)"},
+	}
+
+	for _, tt := range tests {
+		if got := Relative(tt.t, now, nil); got != tt.want {
+			t.Errorf("Relative(%v) = %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	want := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	if got := Epoch(1714575600); !got.Equal(want) {
+		t.Errorf("Epoch(seconds) = %v, want %v", got, want)
+	}
+	if got := Epoch(1714575600000); !got.Equal(want) {
+		t.Errorf("Epoch(millis) = %v, want %v", got, want)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	if got := Percent(0.125); got != "12.5%" {
+		t.Errorf("Percent(0.125) = %s, want 12.5%%", got)
+	}
+}
@@ -0,0 +1,179 @@
+// Package iampolicy drafts the minimal IAM policy for an action the bot is
+// repeatedly denied, and posts it for an admin to review. Nothing is ever
+// granted automatically
+package iampolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/slack-go/slack"
+)
+
+// DefaultThreshold is how many times an action must be denied before a
+// policy is suggested, so a one-off denial doesn't bother anyone
+const DefaultThreshold = 3
+
+// readOnlyPrefixes start the names of actions that only read
+var readOnlyPrefixes = []string{"Describe", "Get", "List", "BatchGet", "Lookup", "Search", "Query", "Scan", "Filter", "Test"}
+
+// readOnlyActions are read-only actions whose names don't say so
+var readOnlyActions = map[string]bool{
+	"logs:StartQuery": true,
+	"logs:StopQuery":  true,
+}
+
+// sensitivePrefixes are services whose read access can still expose
+// credentials or who may do what
+var sensitivePrefixes = []string{"iam:", "sts:", "kms:", "secretsmanager:", "ssm:"}
+
+// Statement is one statement of an IAM policy document
+type Statement struct {
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// Document is an IAM policy document
+type Document struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+// Draft returns the policy granting exactly action on resource, or on every
+// resource when the denial didn't name one
+func Draft(action, resource string) Document {
+	if resource == "" {
+		resource = "*"
+	}
+	return Document{
+		Version:   "2012-10-17",
+		Statement: []Statement{{Effect: "Allow", Action: []string{action}, Resource: []string{resource}}},
+	}
+}
+
+// JSON renders the document for pasting into the IAM console or a template
+func (d Document) JSON() string {
+	data, _ := json.MarshalIndent(d, "", "  ")
+	return string(data)
+}
+
+// ReadOnly reports whether an action only reads
+func ReadOnly(action string) bool {
+	if readOnlyActions[action] {
+		return true
+	}
+	_, name, _ := strings.Cut(action, ":")
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings lists what an admin should check before granting the drafted
+// policy for a denial
+func Warnings(d diagnose.Denial) []string {
+	var warnings []string
+	if d.Resource == "" || d.Resource == "*" {
+		warnings = append(warnings, "The error didn't name a resource, so the policy grants the action on every resource. Scope it down if you can.")
+	}
+	if !ReadOnly(d.Action) {
+		warnings = append(warnings, fmt.Sprintf("`%s` isn't a read-only action, and the bot is meant to be read-only. Only grant it if a tool really needs it.", d.Action))
+	}
+	for _, prefix := range sensitivePrefixes {
+		if strings.HasPrefix(d.Action, prefix) {
+			warnings = append(warnings, fmt.Sprintf("`%s` can reveal credentials or permissions, and anything the bot reads can end up in a channel.", d.Action))
+			break
+		}
+	}
+	switch {
+	case strings.Contains(d.Reason, "service control policy"):
+		warnings = append(warnings, "A service control policy refused the request, so this policy alone won't fix it.")
+	case strings.Contains(d.Reason, "permissions boundary"):
+		warnings = append(warnings, "The role's permissions boundary refused the request; it must allow the action too.")
+	case strings.Contains(d.Reason, "explicit deny"):
+		warnings = append(warnings, "An explicit Deny refused the request, and it overrides this Allow until it is removed.")
+	}
+	return warnings
+}
+
+// Message is the suggestion posted for an admin to review
+func Message(d diagnose.Denial, role string, count int) string {
+	target := "the bot's role"
+	if role != "" {
+		target = fmt.Sprintf("role `%s`", role)
+	}
+	resource := d.Resource
+	if resource == "" {
+		resource = "*"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔑 *IAM policy suggestion*\nThe bot was denied `%s` on `%s` %d times. This is the minimal policy that grants it to %s:\n```\n%s\n```\n",
+		d.Action, resource, count, target, Draft(d.Action, d.Resource).JSON())
+	for _, w := range Warnings(d) {
+		fmt.Fprintf(&b, "⚠️ %s\n", w)
+	}
+	b.WriteString("Review it before attaching it; nothing has been granted.")
+	return b.String()
+}
+
+// Store counts denials and makes sure each suggestion is posted once
+type Store interface {
+	CountDenial(ctx context.Context, action, resource string) (int, error)
+	ClaimDenialSuggestion(ctx context.Context, action, resource string) (bool, error)
+}
+
+// Poster posts Slack messages
+type Poster interface {
+	PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error)
+}
+
+// Suggester posts a policy suggestion to an admin channel once an action
+// has been denied threshold times
+type Suggester struct {
+	store     Store
+	poster    Poster
+	channel   string
+	threshold int
+}
+
+// NewSuggester creates a suggester posting to channel
+func NewSuggester(store Store, poster Poster, channel string, threshold int) *Suggester {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Suggester{store: store, poster: poster, channel: channel, threshold: threshold}
+}
+
+// Denied records a denial and posts the suggestion when the action reaches
+// the threshold. Failures are logged, since the tool call has failed anyway
+func (s *Suggester) Denied(ctx context.Context, d diagnose.Denial, role string) {
+	count, err := s.store.CountDenial(ctx, d.Action, d.Resource)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to count denial", "action", d.Action, "error", err)
+		return
+	}
+	if count < s.threshold {
+		return
+	}
+
+	claimed, err := s.store.ClaimDenialSuggestion(ctx, d.Action, d.Resource)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to claim policy suggestion", "action", d.Action, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if _, err := s.poster.PostMessage(ctx, s.channel, slack.MsgOptionText(Message(d, role, count), false)); err != nil {
+		slog.WarnContext(ctx, "failed to post policy suggestion", "action", d.Action, "error", err)
+	}
+}
@@ -0,0 +1,127 @@
+package iampolicy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/slack-go/slack"
+)
+
+func TestDraft(t *testing.T) {
+	want := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": [
+        "logs:StartQuery"
+      ],
+      "Resource": [
+        "arn:aws:logs:us-east-1:123456789012:log-group:/ecs/checkout:*"
+      ]
+    }
+  ]
+}`
+	if got := Draft("logs:StartQuery", "arn:aws:logs:us-east-1:123456789012:log-group:/ecs/checkout:*").JSON(); got != want {
+		t.Errorf("Draft().JSON() = %s, want %s", got, want)
+	}
+
+	if got := Draft("ec2:DescribeInstances", "").Statement[0].Resource[0]; got != "*" {
+		t.Errorf("Draft() without a resource grants %q, want *", got)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	for _, action := range []string{"ec2:DescribeInstances", "logs:StartQuery", "s3:GetObject", "dynamodb:Query", "logs:FilterLogEvents"} {
+		if !ReadOnly(action) {
+			t.Errorf("ReadOnly(%q) = false", action)
+		}
+	}
+	for _, action := range []string{"ec2:TerminateInstances", "s3:PutObject", "ecs:UpdateService"} {
+		if ReadOnly(action) {
+			t.Errorf("ReadOnly(%q) = true", action)
+		}
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		denial diagnose.Denial
+		want   []string
+	}{
+		{diagnose.Denial{Action: "ec2:DescribeInstances", Resource: "arn:aws:ec2:us-east-1:123456789012:instance/i-1"}, nil},
+		{diagnose.Denial{Action: "ec2:DescribeInstances"}, []string{"every resource"}},
+		{diagnose.Denial{Action: "ecs:UpdateService", Resource: "arn:aws:ecs:us-east-1:123456789012:service/x"}, []string{"isn't a read-only action"}},
+		{diagnose.Denial{Action: "iam:GetRole", Resource: "arn:aws:iam::123456789012:role/x"}, []string{"reveal credentials"}},
+		{diagnose.Denial{Action: "ec2:DescribeInstances", Resource: "*", Reason: "with an explicit deny in a service control policy"}, []string{"every resource", "service control policy"}},
+	}
+	for _, tt := range tests {
+		got := Warnings(tt.denial)
+		if len(got) != len(tt.want) {
+			t.Errorf("Warnings(%+v) = %q, want %d warnings", tt.denial, got, len(tt.want))
+			continue
+		}
+		for i, w := range tt.want {
+			if !strings.Contains(got[i], w) {
+				t.Errorf("Warnings(%+v)[%d] = %q, want it to mention %q", tt.denial, i, got[i], w)
+			}
+		}
+	}
+}
+
+type fakeStore struct {
+	counts  map[string]int
+	claimed map[string]bool
+}
+
+func (f *fakeStore) CountDenial(ctx context.Context, action, resource string) (int, error) {
+	f.counts[action]++
+	return f.counts[action], nil
+}
+
+func (f *fakeStore) ClaimDenialSuggestion(ctx context.Context, action, resource string) (bool, error) {
+	if f.claimed[action] {
+		return false, nil
+	}
+	f.claimed[action] = true
+	return true, nil
+}
+
+type fakePoster struct{ channels []string }
+
+func (f *fakePoster) PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error) {
+	f.channels = append(f.channels, channelID)
+	return "1.0", nil
+}
+
+func TestSuggester(t *testing.T) {
+	store := &fakeStore{counts: map[string]int{}, claimed: map[string]bool{}}
+	poster := &fakePoster{}
+	s := NewSuggester(store, poster, "CADMIN", 3)
+
+	denial := diagnose.Denial{Action: "ec2:DescribeInstances"}
+	for i := 0; i < 2; i++ {
+		s.Denied(context.Background(), denial, "cloudops-task-role")
+	}
+	if len(poster.channels) != 0 {
+		t.Fatalf("posted %d suggestions before the threshold", len(poster.channels))
+	}
+
+	for i := 0; i < 3; i++ {
+		s.Denied(context.Background(), denial, "cloudops-task-role")
+	}
+	if len(poster.channels) != 1 || poster.channels[0] != "CADMIN" {
+		t.Errorf("posted to %v, want one suggestion in CADMIN", poster.channels)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	got := Message(diagnose.Denial{Action: "ec2:DescribeInstances"}, "cloudops-task-role", 3)
+	for _, want := range []string{"denied `ec2:DescribeInstances` on `*` 3 times", "role `cloudops-task-role`", `"Resource": [`, "⚠️", "nothing has been granted"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Message() = %q, want it to contain %q", got, want)
+		}
+	}
+}
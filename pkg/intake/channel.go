@@ -0,0 +1,67 @@
+package intake
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+
+// ChannelPointer is left under the mention when its conversation moves to
+// a channel of its own
+func ChannelPointer(userID, channelID string) string {
+	return fmt.Sprintf("📣 I opened <#%s> for this and invited <@%s>. They can add anyone else who should help.", channelID, userID)
+}
+
+// ChannelKickoff opens a conversation's own channel with what was asked
+// and where, so the channel makes sense without the original message
+func ChannelKickoff(conv *models.Conversation, permalink string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "👋 <@%s> asked in <#%s>", conv.UserID, conv.MessageChannelID())
+	if permalink != "" {
+		fmt.Fprintf(&b, " (<%s|original message>)", permalink)
+	}
+	b.WriteString(":")
+	if request := strings.TrimSpace(mentionPattern.ReplaceAllString(conv.InitialCommand, "")); request != "" {
+		fmt.Fprintf(&b, "\n>%s", strings.ReplaceAll(request, "\n", "\n>"))
+	}
+	return b.String()
+}
+
+// openChannel moves a new conversation to a private channel of its own,
+// with the requester invited, leaving a pointer under the mention
+func (s *Starter) openChannel(ctx context.Context, conv *models.Conversation) error {
+	channelID, err := handler.NewChannelCreator(s.slackClient).CreateConversationChannel(ctx, conv.UserID)
+	if err != nil {
+		return err
+	}
+
+	from, threadTS := conv.ChannelID, conv.ThreadTS
+	if threadTS == "" {
+		threadTS = conv.MessageTS
+	}
+	conv.MoveTo(channelID)
+	conv.Visibility = privacy.Private
+
+	if _, err := s.slackClient.PostMessage(ctx, from, slack.MsgOptionText(ChannelPointer(conv.UserID, channelID), false), slackclient.InThread(threadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post channel pointer", "error", err)
+	}
+
+	permalink, err := s.slackClient.GetPermalink(ctx, from, conv.MessageTS)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to link original request", "error", err)
+	}
+	if _, err := s.slackClient.PostMessage(ctx, channelID, slack.MsgOptionText(ChannelKickoff(conv, permalink), false)); err != nil {
+		slog.WarnContext(ctx, "failed to post request in conversation channel", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package intake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestChannelKickoff(t *testing.T) {
+	conv := models.NewConversation("C0OPS", "U123", "<@U0BOT> checkout is returning 502s\nsince the deploy")
+	conv.MoveTo("C0INCIDENT")
+
+	got := ChannelKickoff(conv, "https://example.slack.com/archives/C0OPS/p1")
+	for _, want := range []string{"<@U123> asked in <#C0OPS>", "<https://example.slack.com/archives/C0OPS/p1|original message>", "\n>checkout is returning 502s\n>since the deploy"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ChannelKickoff() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "U0BOT") {
+		t.Errorf("ChannelKickoff() = %q, should drop the bot mention", got)
+	}
+
+	if got := ChannelKickoff(conv, ""); strings.Contains(got, "original message") {
+		t.Errorf("ChannelKickoff() without permalink = %q", got)
+	}
+}
+
+func TestChannelPointer(t *testing.T) {
+	got := ChannelPointer("U123", "C0INCIDENT")
+	if !strings.Contains(got, "<#C0INCIDENT>") || !strings.Contains(got, "<@U123>") {
+		t.Errorf("ChannelPointer() = %q", got)
+	}
+}
@@ -0,0 +1,284 @@
+// Package intake starts conversations: from mentions of the bot, and from
+// the "Ask CloudOps about this" message shortcut. It records the
+// conversation and starts the Step Functions execution that runs its agent
+package intake
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/contextpack"
+	"github.com/savaki/cloudops-bot/pkg/debugmode"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/killswitch"
+	"github.com/savaki/cloudops-bot/pkg/lifecycle"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/sealed"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/savaki/cloudops-bot/pkg/tasksize"
+	"github.com/slack-go/slack"
+)
+
+// Launcher starts the agent for a conversation, returning an ID for the
+// run. Step Functions is the Launcher in production
+type Launcher interface {
+	StartConversation(ctx context.Context, stateMachineArn string, conversation *models.Conversation, launch stepfunctions.Launch) (string, error)
+	StartShadow(ctx context.Context, stateMachineArn string, conversation *models.Conversation, launch stepfunctions.Launch) (string, error)
+}
+
+// Starter starts conversations
+type Starter struct {
+	cfg          *appconfig.Config
+	slackClient  *slackclient.Client
+	convRepo     storage.Store
+	settingsRepo *dynamodb.SettingsRepository   // nil with in-memory storage
+	permRepo     *dynamodb.PermissionRepository // nil with in-memory storage
+	sfClient     Launcher
+}
+
+// NewStarter creates the clients used to start conversations
+func NewStarter(cfg *appconfig.Config, awsCfg aws.Config, ddbClient *awsdynamodb.Client, slackClient *slackclient.Client) *Starter {
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	sfClient := stepfunctions.NewClient(awsCfg)
+	if cfg.InputKMSKeyID != "" {
+		sfClient.SetSealer(sealed.New(awsCfg, cfg.InputKMSKeyID))
+	}
+	s := &Starter{
+		cfg:         cfg,
+		slackClient: slackClient,
+		convRepo:    convRepo,
+		sfClient:    sfClient,
+	}
+
+	// Local development keeps conversations in memory and has no tables
+	// for the kill switch or permissions
+	if !cfg.InMemory() {
+		s.settingsRepo = dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable)
+		s.permRepo = dynamodb.NewPermissionRepository(ddbClient, cfg.PermissionsTable)
+	}
+
+	// Fault injection for resilience testing (never enabled in production)
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		if s.settingsRepo != nil {
+			s.settingsRepo.SetFaultInjector(faults)
+			s.permRepo.SetFaultInjector(faults)
+		}
+	}
+	return s
+}
+
+// SetStore replaces the conversation store, so conversations can be kept
+// where a local agent reads them
+func (s *Starter) SetStore(store storage.Store) {
+	s.convRepo = store
+}
+
+// SetLauncher replaces Step Functions as the way agents are started
+func (s *Starter) SetLauncher(l Launcher) {
+	s.sfClient = l
+}
+
+// Start starts a conversation for a mention of the bot
+func (s *Starter) Start(ctx context.Context, event models.SlackEventBody) error {
+	// A mention in a conversation already under way is for its agent, which
+	// picks it up when it polls. Otherwise a mention in a thread starts a
+	// conversation confined to that thread, so a busy channel can hold several
+	if existing, ok := s.existing(ctx, event.Channel, event.ThreadTS); ok {
+		slog.InfoContext(ctx, "mention belongs to existing conversation", logging.ConversationID, existing.ConversationID)
+		return nil
+	}
+
+	// Nothing new starts while an admin has the bot disabled
+	if ks, err := s.killSwitch(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to read kill switch", "error", err)
+	} else if ks != nil && ks.Disabled {
+		slog.InfoContext(ctx, "bot disabled, ignoring mention", "changed_by", ks.ChangedBy)
+		threadTS := event.ThreadTS
+		if threadTS == "" {
+			threadTS = event.TS
+		}
+		if _, err := s.slackClient.PostMessage(ctx, event.Channel, slack.MsgOptionText(killswitch.Message(ks), false), slackclient.InThread(threadTS)); err != nil {
+			slog.WarnContext(ctx, "failed to post kill switch notice", "error", err)
+		}
+		return nil
+	}
+
+	// Visibility is chosen at creation: that of the channel, unless the
+	// requester asks for more with --private or --dm
+	requested, text := privacy.Requested(event.Text)
+	debug, text := debugmode.Requested(text)
+	visibility := privacy.Public
+	if channel, err := s.slackClient.GetChannelInfo(ctx, event.Channel); err != nil {
+		slog.WarnContext(ctx, "failed to read channel visibility, treating it as public", "error", err)
+	} else {
+		visibility = privacy.ForChannel(channel)
+	}
+
+	// In thread mode a mention at the top of a channel starts a thread of
+	// its own rather than taking over the channel
+	threadTS := event.ThreadTS
+	if threadTS == "" && s.cfg.ThreadPerConversation() && visibility != privacy.DM {
+		threadTS = event.TS
+	}
+
+	// Create new conversation
+	conversation := models.NewConversation(event.Channel, event.User, text)
+	conversation.Type = tasksize.Classify(text)
+	conversation.MessageTS = event.TS
+	conversation.SetThread(threadTS)
+	conversation.Visibility = visibility
+	conversation.Debug = debug
+	ctx = logging.With(ctx, logging.ConversationID, conversation.ConversationID)
+	if privacy.Stricter(requested, visibility) {
+		if err := s.withdraw(ctx, conversation, requested); err != nil {
+			slog.WarnContext(ctx, "failed to move conversation for privacy, keeping it in place", logging.ChannelID, event.Channel, "error", err)
+		}
+	} else if s.cfg.ChannelPerConversation() && visibility != privacy.DM {
+		if err := s.openChannel(ctx, conversation); err != nil {
+			slog.WarnContext(ctx, "failed to open conversation channel, keeping it in place", logging.ChannelID, event.Channel, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "created conversation")
+
+	// Save to DynamoDB
+	if err := s.convRepo.Save(ctx, conversation); err != nil {
+		return fmt.Errorf("save conversation: %w", err)
+	}
+
+	// Acknowledge with a reaction on the mention; the agent moves it along
+	// as the conversation progresses
+	lifecycle.Mark(ctx, s.slackClient, conversation, lifecycle.Received)
+
+	// Start Step Function execution (which will spawn ECS task)
+	size := s.cfg.TaskSize(conversation.Type)
+	executionArn, err := s.sfClient.StartConversation(ctx, s.cfg.StepFunctionArn, conversation, stepfunctions.Launch{
+		Capacity:       s.cfg.CapacityFor(event.Channel),
+		CPU:            size.CPU,
+		Memory:         size.Memory,
+		TaskDefinition: size.TaskDefinition,
+		ContextPack:    s.contextPack(ctx, conversation),
+	})
+	if err != nil {
+		// Try to notify user of failure. The user retries, not Slack, since a
+		// redelivery would create a second conversation
+		s.slackClient.PostMessage(ctx, event.Channel, slack.MsgOptionText("❌ Failed to start assistant. Please try again.", false), slackclient.InThread(event.ThreadTS))
+		lifecycle.Mark(ctx, s.slackClient, conversation, lifecycle.Failed)
+		return handler.Permanent(fmt.Errorf("start step function: %w", err))
+	}
+	slog.InfoContext(ctx, "started step function execution", "execution_arn", executionArn)
+
+	// Run the candidate agent version alongside; it only logs, so a
+	// failure to start it never affects the conversation
+	if s.cfg.Shadowed(conversation.ConversationID) {
+		shadowArn, err := s.sfClient.StartShadow(ctx, s.cfg.StepFunctionArn, conversation, stepfunctions.Launch{
+			CPU:            size.CPU,
+			Memory:         size.Memory,
+			TaskDefinition: s.cfg.ShadowTaskDefinition,
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "failed to start shadow agent", "error", err)
+		} else {
+			slog.InfoContext(ctx, "started shadow execution", "execution_arn", shadowArn)
+		}
+	}
+
+	// Record the execution ARN on the latest copy; the agent may already
+	// have started and changed the conversation
+	if _, err := s.convRepo.Modify(ctx, conversation.ConversationID, func(conv *models.Conversation) error {
+		conv.ExecutionArn = executionArn
+		return nil
+	}); err != nil {
+		slog.WarnContext(ctx, "failed to update conversation with execution ARN", "error", err)
+	}
+
+	return nil
+}
+
+// killSwitch reads the kill switch, which is never set without a settings
+// table
+func (s *Starter) killSwitch(ctx context.Context) (*models.KillSwitch, error) {
+	if s.settingsRepo == nil {
+		return nil, nil
+	}
+	return s.settingsRepo.GetKillSwitch(ctx)
+}
+
+// contextPack encodes what the agent would read at startup, so it can
+// skip the reads. It returns "" when packs are off or one can't be built,
+// and the agent reads DynamoDB as usual
+func (s *Starter) contextPack(ctx context.Context, conv *models.Conversation) string {
+	if s.cfg.GetContextPackMaxAge() == 0 || s.permRepo == nil {
+		return ""
+	}
+
+	pack := contextpack.New(conv, time.Now())
+	if !slices.Contains(s.cfg.AdminUsers, conv.UserID) {
+		profile, err := s.permRepo.Get(ctx, conv.UserID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to read permissions for context pack", "error", err)
+			return ""
+		}
+		pack.Permissions[conv.UserID] = profile
+	}
+
+	encoded, err := pack.Encode()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to build context pack", "error", err)
+		return ""
+	}
+	return encoded
+}
+
+// existing returns the conversation under way where a message was posted,
+// when there is one confined to the same thread
+func (s *Starter) existing(ctx context.Context, channelID, threadTS string) (*models.Conversation, bool) {
+	conv, err := s.convRepo.GetByMessage(ctx, channelID, threadTS)
+	if err != nil || conv.Ended() || conv.ThreadTS != threadTS {
+		return nil, false
+	}
+	return conv, true
+}
+
+// withdraw moves a new conversation to the requester's DMs or a new private
+// channel when they asked for more privacy than the channel it started in
+// offers, leaving a pointer under the mention
+func (s *Starter) withdraw(ctx context.Context, conv *models.Conversation, level string) error {
+	var channelID string
+	var err error
+	if level == privacy.DM {
+		channelID, err = s.slackClient.OpenDM(ctx, conv.UserID)
+	} else {
+		channelID, err = handler.NewChannelCreator(s.slackClient).CreateConversationChannel(ctx, conv.UserID)
+	}
+	if err != nil {
+		return err
+	}
+
+	from, threadTS := conv.ChannelID, conv.ThreadTS
+	if threadTS == "" {
+		threadTS = conv.MessageTS
+	}
+	conv.MoveTo(channelID)
+	conv.Visibility = level
+
+	pointer := fmt.Sprintf("🔒 I'll answer <@%s> in <#%s>.", conv.UserID, channelID)
+	if level == privacy.DM {
+		pointer = fmt.Sprintf("🔒 I'll answer <@%s> in a direct message.", conv.UserID)
+	}
+	if _, err := s.slackClient.PostMessage(ctx, from, slack.MsgOptionText(pointer, false), slackclient.InThread(threadTS)); err != nil {
+		slog.WarnContext(ctx, "failed to post privacy pointer", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+// fakeStore finds conversations by the message they were started from;
+// other calls panic
+type fakeStore struct {
+	storage.Store
+	byMessage map[string]*models.Conversation
+}
+
+func (f *fakeStore) GetByMessage(_ context.Context, channelID, threadTS string) (*models.Conversation, error) {
+	if conv, ok := f.byMessage[channelID+"/"+threadTS]; ok {
+		return conv, nil
+	}
+	return nil, errors.New("conversation not found")
+}
+
+func TestExisting(t *testing.T) {
+	inThread := models.NewConversation("C1", "U1", "why is checkout slow?")
+	inThread.SetThread("100.1")
+	ended := models.NewConversation("C1", "U1", "disk full on db-1")
+	ended.SetThread("200.1")
+	ended.UpdateStatus(models.StatusCompleted)
+	inChannel := models.NewConversation("C2", "U1", "deploy failed")
+
+	s := &Starter{convRepo: &fakeStore{byMessage: map[string]*models.Conversation{
+		"C1/100.1": inThread,
+		"C1/200.1": ended,
+		"C2/300.1": inChannel, // the channel is its own; a thread in it is new
+	}}}
+
+	for key, want := range map[[2]string]*models.Conversation{
+		{"C1", "100.1"}: inThread,
+		{"C1", "200.1"}: nil,
+		{"C2", "300.1"}: nil,
+		{"C3", "400.1"}: nil,
+	} {
+		got, ok := s.existing(context.Background(), key[0], key[1])
+		if got != want || ok != (want != nil) {
+			t.Errorf("existing(%s, %s) = %v, %v; want %v", key[0], key[1], got, ok, want)
+		}
+	}
+}
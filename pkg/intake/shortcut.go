@@ -0,0 +1,117 @@
+package intake
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// ShortcutCallbackID identifies the "Ask CloudOps about this" message
+// shortcut, as registered in the Slack app
+const ShortcutCallbackID = "ask_cloudops"
+
+// maxSeedText bounds how much of the shared message starts the
+// conversation, so a pasted log doesn't crowd out the question
+const maxSeedText = 3000
+
+// Shortcut starts a conversation about the message the shortcut was used
+// on, in that message's thread. The conversation starts from the message's
+// text and a link to it, and a note in the thread, not the original
+// message, carries its status reactions
+func (s *Starter) Shortcut(ctx context.Context, callback *slack.InteractionCallback) error {
+	channelID, userID := callback.Channel.ID, callback.User.ID
+	msg := callback.Message
+	threadTS := ShortcutThread(msg)
+
+	if existing, ok := s.existing(ctx, channelID, threadTS); ok {
+		return s.ephemeral(ctx, channelID, userID, fmt.Sprintf("That thread is already part of `%s`. Mention me there to ask about it.", existing.ConversationID))
+	}
+
+	permalink, err := s.slackClient.GetPermalink(ctx, channelID, msg.Timestamp)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to link shared message", "error", err)
+	}
+
+	ts, err := s.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionText(fmt.Sprintf("🔎 <@%s> asked me to look into this.", userID), false),
+		slackclient.InThread(threadTS),
+	)
+	if err != nil {
+		// Most often the bot isn't in the channel, which it can't say there
+		slog.WarnContext(ctx, "failed to post in shared message's thread", "error", err)
+		return s.ephemeral(ctx, channelID, userID, "I couldn't post in that channel. Invite me to it and try again.")
+	}
+
+	return s.Start(ctx, models.SlackEventBody{
+		Type:     "message_action",
+		User:     userID,
+		Text:     Seed(msg, permalink),
+		Channel:  channelID,
+		TS:       ts,
+		ThreadTS: threadTS,
+	})
+}
+
+// ShortcutThread returns the thread a conversation about msg belongs in:
+// msg's own thread when it is a reply, otherwise one started under it
+func ShortcutThread(msg slack.Message) string {
+	if msg.ThreadTimestamp != "" {
+		return msg.ThreadTimestamp
+	}
+	return msg.Timestamp
+}
+
+// Seed is the request that starts a conversation about msg: its text,
+// quoted, along with who posted it and a link to it. Alarm notifications
+// often carry their details in attachments rather than text, so those
+// are included too
+func Seed(msg slack.Message, permalink string) string {
+	var parts []string
+	if text := strings.TrimSpace(msg.Text); text != "" {
+		parts = append(parts, text)
+	}
+	for _, a := range msg.Attachments {
+		for _, text := range []string{a.Pretext, a.Title, a.Text} {
+			if text = strings.TrimSpace(text); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		if a.Text == "" && a.Fallback != "" {
+			parts = append(parts, strings.TrimSpace(a.Fallback))
+		}
+	}
+	content := strings.Join(parts, "\n")
+	if len(content) > maxSeedText {
+		content = strings.ToValidUTF8(content[:maxSeedText], "") + "\n… (truncated)"
+	}
+
+	from := "this message"
+	switch {
+	case msg.User != "":
+		from = fmt.Sprintf("this message from <@%s>", msg.User)
+	case msg.Username != "":
+		from = fmt.Sprintf("this message from %s", msg.Username)
+	}
+	if permalink != "" {
+		from += fmt.Sprintf(" (<%s>)", permalink)
+	}
+
+	if content == "" {
+		return fmt.Sprintf("Help me understand %s.", from)
+	}
+	return fmt.Sprintf("Help me understand %s:\n>%s", from, strings.ReplaceAll(content, "\n", "\n>"))
+}
+
+// ephemeral tells one user something without interrupting the channel
+func (s *Starter) ephemeral(ctx context.Context, channelID, userID, text string) error {
+	_, err := s.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionPostEphemeral(userID),
+		slack.MsgOptionText(text, false),
+	)
+	return err
+}
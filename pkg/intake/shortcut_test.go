@@ -0,0 +1,59 @@
+package intake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func message(msg slack.Msg) slack.Message {
+	return slack.Message{Msg: msg}
+}
+
+func TestSeed(t *testing.T) {
+	msg := message(slack.Msg{User: "U0ALICE", Text: "checkout is throwing\nNullPointerException at Checkout.java:42"})
+
+	got := Seed(msg, "https://example.slack.com/archives/C1/p1")
+	want := "Help me understand this message from <@U0ALICE> (<https://example.slack.com/archives/C1/p1>):\n>checkout is throwing\n>NullPointerException at Checkout.java:42"
+	if got != want {
+		t.Errorf("Seed() = %q, want %q", got, want)
+	}
+}
+
+func TestSeedAttachments(t *testing.T) {
+	msg := message(slack.Msg{
+		Username: "AWS Chatbot",
+		Attachments: []slack.Attachment{
+			{Title: "ALARM: checkout-5xx in us-east-1", Text: "Threshold Crossed: 12 datapoints > 5"},
+			{Fallback: "CPUUtilization above 90%"},
+		},
+	})
+
+	got := Seed(msg, "")
+	for _, want := range []string{"from AWS Chatbot:", ">ALARM: checkout-5xx in us-east-1\n>Threshold Crossed", ">CPUUtilization above 90%"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Seed() = %q, missing %q", got, want)
+		}
+	}
+
+	if got := Seed(message(slack.Msg{}), ""); got != "Help me understand this message." {
+		t.Errorf("Seed() of an empty message = %q", got)
+	}
+}
+
+func TestSeedTruncates(t *testing.T) {
+	got := Seed(message(slack.Msg{Text: strings.Repeat("x", maxSeedText+500)}), "")
+	if !strings.HasSuffix(got, "… (truncated)") || len(got) > maxSeedText+100 {
+		t.Errorf("Seed() returned %d bytes, want the text cut at %d", len(got), maxSeedText)
+	}
+}
+
+func TestShortcutThread(t *testing.T) {
+	if got := ShortcutThread(message(slack.Msg{Timestamp: "2.0"})); got != "2.0" {
+		t.Errorf("ShortcutThread() = %q, want a thread under the message", got)
+	}
+	if got := ShortcutThread(message(slack.Msg{Timestamp: "2.0", ThreadTimestamp: "1.0"})); got != "1.0" {
+		t.Errorf("ShortcutThread() = %q, want the reply's thread", got)
+	}
+}
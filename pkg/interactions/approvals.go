@@ -0,0 +1,73 @@
+package interactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/approval"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// approvalVote records an approve or deny click against the request's
+// policy and refreshes the request message
+func (h *Handlers) approvalVote(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	refuse := func(reason string) {
+		if err := h.ephemeral(ctx, channelID, userID, reason); err != nil {
+			slog.WarnContext(ctx, "failed to explain refused vote", "error", err)
+		}
+	}
+
+	req, err := h.approvalRepo.Get(ctx, action.Value)
+	if err != nil {
+		return err
+	}
+	if req == nil {
+		refuse("This approval request no longer exists.")
+		return nil
+	}
+
+	profile, err := h.profileOf(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("check permissions: %w", err)
+	}
+
+	now := time.Now()
+	prior := len(req.Votes)
+	approve := action.ActionID == approval.ApproveAction
+	if err := approval.Vote(req, userID, profile, approve, now); err != nil {
+		refuse("Your vote wasn't counted: " + err.Error() + ".")
+		if !errors.Is(err, approval.ErrExpired) {
+			return nil
+		}
+	}
+
+	if err := h.approvalRepo.Update(ctx, req, prior); err != nil {
+		if errors.Is(err, dynamodb.ErrApprovalChanged) {
+			refuse("Someone else voted at the same moment. Try again.")
+			return nil
+		}
+		return err
+	}
+
+	if len(req.Votes) > prior {
+		event := models.NewAuditEvent(models.AuditApprovalVote, userID, req.ApprovalID)
+		event.Details["class"] = req.Class
+		event.Details["approve"] = fmt.Sprint(approve)
+		event.Details["status"] = req.Status
+		if err := h.auditRepo.Record(ctx, event); err != nil {
+			slog.WarnContext(ctx, "failed to audit vote", "approval_id", req.ApprovalID, "error", err)
+		}
+	}
+
+	return h.slackClient.UpdateMessage(ctx, channelID, callback.Message.Timestamp,
+		slack.MsgOptionText(approval.Status(req, now), false),
+		slack.MsgOptionBlocks(approval.Blocks(req, now)...),
+	)
+}
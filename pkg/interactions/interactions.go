@@ -0,0 +1,191 @@
+// Package interactions handles the bot's buttons, modals, and shortcuts:
+// follow-up suggestions, approval votes, runbook capture, full output,
+// privacy choices, merging related conversations, permission changes, the
+// setup checklist, and "Ask CloudOps about this". The Slack handler and the dedicated interactions
+// endpoint both register them
+package interactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsdynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/approval"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/correlate"
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/followups"
+	"github.com/savaki/cloudops-bot/pkg/fulloutput"
+	"github.com/savaki/cloudops-bot/pkg/handler"
+	"github.com/savaki/cloudops-bot/pkg/intake"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/runbook"
+	"github.com/savaki/cloudops-bot/pkg/setup"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/stepfunctions"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/slack-go/slack"
+)
+
+// Handlers holds the clients used by interaction handlers
+type Handlers struct {
+	cfg          *appconfig.Config
+	slackClient  *slackclient.Client
+	convRepo     storage.Store
+	approvalRepo *dynamodb.ApprovalRepository
+	runbookRepo  *dynamodb.RunbookRepository
+	permRepo     *dynamodb.PermissionRepository
+	auditRepo    *dynamodb.AuditRepository
+	settingsRepo *dynamodb.SettingsRepository
+	bedrock      *bedrock.Client
+	outputs      *fulloutput.Store // nil unless OUTPUTS_BUCKET is set
+	setup        *setup.Wizard
+	starter      *intake.Starter
+	sfClient     *stepfunctions.Client
+}
+
+// New creates the clients used by interaction handlers
+func New(cfg *appconfig.Config, awsCfg aws.Config, ddbClient *awsdynamodb.Client, slackClient *slackclient.Client) *Handlers {
+	convRepo := dynamodb.NewConversationRepository(ddbClient, cfg.ConversationsTable)
+	convRepo.SetHistoryTable(cfg.ConversationHistoryTable)
+	h := &Handlers{
+		cfg:          cfg,
+		slackClient:  slackClient,
+		convRepo:     convRepo,
+		approvalRepo: dynamodb.NewApprovalRepository(ddbClient, cfg.ApprovalsTable),
+		runbookRepo:  dynamodb.NewRunbookRepository(ddbClient, cfg.RunbooksTable),
+		permRepo:     dynamodb.NewPermissionRepository(ddbClient, cfg.PermissionsTable),
+		auditRepo:    dynamodb.NewAuditRepository(ddbClient, cfg.AuditTable),
+		settingsRepo: dynamodb.NewSettingsRepository(ddbClient, cfg.SettingsTable),
+		bedrock:      bedrock.NewClient(awsCfg),
+		sfClient:     stepfunctions.NewClient(awsCfg),
+	}
+	h.bedrock.SetModel(cfg.BedrockModelID)
+	if cfg.OutputsBucket != "" {
+		h.outputs = fulloutput.NewStore(awsCfg, cfg.OutputsBucket)
+	}
+	h.setup = setup.NewWizard(setup.Checks(cfg, setup.NewClients(awsCfg, h.bedrock, slackClient)), h.settingsRepo, slackClient)
+	h.starter = intake.NewStarter(cfg, awsCfg, ddbClient, slackClient)
+
+	if faults := cfg.FaultInjector(); faults != nil {
+		convRepo.SetFaultInjector(faults)
+		h.approvalRepo.SetFaultInjector(faults)
+		h.runbookRepo.SetFaultInjector(faults)
+		h.permRepo.SetFaultInjector(faults)
+		h.auditRepo.SetFaultInjector(faults)
+		h.settingsRepo.SetFaultInjector(faults)
+		h.bedrock.SetFaultInjector(faults)
+	}
+
+	return h
+}
+
+// SetStarter replaces the starter behind the message shortcut, so a local
+// server can start conversations the same way for mentions and shortcuts
+func (h *Handlers) SetStarter(s *intake.Starter) {
+	h.starter = s
+}
+
+// Register adds the bot's buttons and modals to an interaction handler
+func (h *Handlers) Register(ih *handler.InteractionHandler) {
+	ih.OnAction(followups.IsAction, h.followUp)
+	ih.OnAction(approval.IsAction, h.approvalVote)
+	ih.OnAction(handler.ActionID(runbook.ActionID), h.saveRunbook)
+	ih.OnAction(fulloutput.IsAction, h.showOutput)
+	ih.OnAction(privacy.IsAction, h.privacyChoice)
+	ih.OnAction(handler.ActionID(correlate.MergeActionID), h.mergeConversations)
+	ih.OnAction(handler.ActionID(setup.RecheckAction), h.setupRecheck)
+	ih.OnViewSubmission(RBACCallbackID, h.rbacSubmission)
+	ih.OnShortcut(intake.ShortcutCallbackID, h.starter.Shortcut)
+}
+
+// profileOf returns a user's effective permission profile. An expired
+// break-glass elevation counts as the profile it replaced
+func (h *Handlers) profileOf(ctx context.Context, userID string) (string, error) {
+	if contains(h.cfg.AdminUsers, userID) {
+		return models.ProfileAdmin, nil
+	}
+	profile, err := h.permRepo.Get(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return profile.Effective(time.Now()), nil
+}
+
+// ephemeral tells one user something without interrupting the channel
+func (h *Handlers) ephemeral(ctx context.Context, channelID, userID, text string) error {
+	_, err := h.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionPostEphemeral(userID),
+		slack.MsgOptionText(text, false),
+	)
+	return err
+}
+
+// followUp continues the conversation with a clicked suggestion. The agent
+// polls the conversation, so the suggestion is posted as a bot message whose
+// metadata names the user who clicked it
+func (h *Handlers) followUp(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	suggestion := action.Value
+
+	conv, err := h.convRepo.GetByMessage(ctx, channelID, callback.Message.ThreadTimestamp)
+	if err != nil || (conv.Status != models.StatusActive && conv.Status != models.StatusPending) {
+		return h.ephemeral(ctx, channelID, userID, "This session has ended. Mention me again to start a new one.")
+	}
+
+	if _, err := h.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionText(followups.Chosen(userID, suggestion), false),
+		slack.MsgOptionMetadata(followups.Metadata(userID, suggestion)),
+		slackclient.InThread(conv.ThreadTS),
+	); err != nil {
+		return err
+	}
+
+	// Drop the buttons so the same suggestion isn't asked twice
+	msg := callback.Message
+	if err := h.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(followups.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove follow-up buttons", "error", err)
+	}
+	return nil
+}
+
+// showOutput posts the full content behind a shortened code block as a
+// snippet in the message's thread
+func (h *Handlers) showOutput(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	if h.outputs == nil {
+		return fmt.Errorf("OUTPUTS_BUCKET is not configured")
+	}
+
+	channelID := callback.Channel.ID
+	err := h.outputs.Serve(ctx, h.slackClient, channelID, callback.Message.Timestamp, action.Value)
+	if errors.Is(err, fulloutput.ErrNotFound) {
+		err = h.ephemeral(ctx, channelID, callback.User.ID, "The full output is no longer available.")
+	}
+	return err
+}
+
+// setupRecheck runs the setup checks again when an admin clicks "Check
+// again" under the checklist
+func (h *Handlers) setupRecheck(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	_, err := h.setup.Recheck(ctx, callback.Channel.ID, callback.Message.Timestamp, callback.User.ID)
+	return err
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package interactions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/correlate"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+	"github.com/slack-go/slack"
+)
+
+// mergeConversations merges the newer of two related conversations into the
+// older one: the newer one is marked merged, its history is interleaved
+// into the older one's, each channel gets a notice linking to the other,
+// and the newer one's execution is stopped. Both agents poll their
+// channels, so the notices are bot messages whose metadata tells one agent
+// to stop and the other to pick up the merged history
+func (h *Handlers) mergeConversations(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+
+	fromID, intoID, ok := correlate.ParseMerge(action.Value)
+	if !ok {
+		return fmt.Errorf("invalid merge value %q", action.Value)
+	}
+	from, err := h.convRepo.GetByID(ctx, fromID)
+	if err != nil {
+		return fmt.Errorf("get conversation %s: %w", fromID, err)
+	}
+	into, err := h.convRepo.GetByID(ctx, intoID)
+	if err != nil {
+		return fmt.Errorf("get conversation %s: %w", intoID, err)
+	}
+	if from.Ended() || into.Ended() {
+		return h.ephemeral(ctx, channelID, userID, "One of these conversations has already ended, so there's nothing to merge.")
+	}
+	if !from.TookPart(userID) && !into.TookPart(userID) {
+		return h.ephemeral(ctx, channelID, userID, "Only people taking part in one of these conversations can merge them.")
+	}
+
+	// Marking the merge first keeps two clicks from merging twice
+	if err := h.convRepo.MergeInto(ctx, fromID, intoID); errors.Is(err, storage.ErrNotMergeable) {
+		return h.ephemeral(ctx, channelID, userID, "One of these conversations has already ended, so there's nothing to merge.")
+	} else if err != nil {
+		return err
+	}
+	ctx = logging.With(ctx, logging.ConversationID, fromID)
+	slog.InfoContext(ctx, "merging conversation", "into_conversation_id", intoID)
+
+	if err := h.mergeHistory(ctx, into, from); err != nil {
+		slog.WarnContext(ctx, "failed to merge conversation history", "error", err)
+	}
+
+	meta := correlate.Metadata(from.ConversationID, into.ConversationID)
+	if _, err := h.slackClient.PostMessage(ctx, from.ChannelID,
+		slack.MsgOptionText(correlate.Merged(into, userID), false),
+		slack.MsgOptionMetadata(meta),
+		slackclient.InThread(from.ThreadTS),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to post merge notice", "error", err)
+	}
+	if _, err := h.slackClient.PostMessage(ctx, into.ChannelID,
+		slack.MsgOptionText(correlate.Joined(from, userID), false),
+		slack.MsgOptionMetadata(meta),
+		slackclient.InThread(into.ThreadTS),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to post merged conversation context", "error", err)
+	}
+
+	// A warm agent has no execution; it stops when it sees the notice
+	if from.ExecutionArn != "" {
+		if err := h.sfClient.StopExecution(ctx, from.ExecutionArn, "merged into "+intoID); err != nil {
+			slog.WarnContext(ctx, "failed to stop merged conversation", "execution_arn", from.ExecutionArn, "error", err)
+		}
+	}
+
+	// Drop the button so the conversations aren't merged twice
+	msg := callback.Message
+	if err := h.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(correlate.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove merge button", "error", err)
+	}
+	return nil
+}
+
+// mergeHistory interleaves the merged conversation's history into the one
+// that carries on
+func (h *Handlers) mergeHistory(ctx context.Context, into, from *models.Conversation) error {
+	intoHistory, err := h.convRepo.GetHistoryItems(ctx, into.ConversationID)
+	if err != nil {
+		return fmt.Errorf("get history of %s: %w", into.ConversationID, err)
+	}
+	fromHistory, err := h.convRepo.GetHistoryItems(ctx, from.ConversationID)
+	if err != nil {
+		return fmt.Errorf("get history of %s: %w", from.ConversationID, err)
+	}
+	if len(fromHistory) == 0 {
+		return nil
+	}
+	return h.convRepo.ReplaceHistory(ctx, into.ConversationID, correlate.Interleave(intoHistory, fromHistory, into.ConversationID, from.ConversationID))
+}
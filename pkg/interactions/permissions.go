@@ -0,0 +1,88 @@
+package interactions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// RBACCallbackID identifies the grant/revoke confirmation modal
+const RBACCallbackID = "rbac_confirm"
+
+// RBACChange is a pending grant or revoke, carried through the confirmation
+// modal's private metadata
+type RBACChange struct {
+	Action    string `json:"action"` // "grant" or "revoke"
+	UserID    string `json:"user_id"`
+	Profile   string `json:"profile,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	ChannelID string `json:"channel_id"`
+}
+
+// rbacSubmission applies a confirmed grant or revoke. The submitter is
+// checked again since the modal could outlive their admin profile
+func (h *Handlers) rbacSubmission(ctx context.Context, callback *slack.InteractionCallback) error {
+	var change RBACChange
+	if err := json.Unmarshal([]byte(callback.View.PrivateMetadata), &change); err != nil {
+		return fmt.Errorf("parse change: %w", err)
+	}
+
+	actorID := callback.User.ID
+	reply := func(format string, args ...interface{}) {
+		if err := h.ephemeral(ctx, change.ChannelID, actorID, fmt.Sprintf(format, args...)); err != nil {
+			slog.WarnContext(ctx, "failed to confirm permission change", "error", err)
+		}
+	}
+
+	actor, err := h.profileOf(ctx, actorID)
+	if err != nil {
+		return fmt.Errorf("check permissions: %w", err)
+	}
+	if actor != models.ProfileAdmin {
+		reply("Only admins can manage permissions.")
+		return nil
+	}
+
+	previous, err := h.profileOf(ctx, change.UserID)
+	if err != nil {
+		return fmt.Errorf("check permissions: %w", err)
+	}
+
+	action := models.AuditGrant
+	if change.Action == "grant" {
+		err = h.permRepo.Put(ctx, &models.PermissionProfile{
+			UserID:    change.UserID,
+			Profile:   change.Profile,
+			GrantedBy: actorID,
+			GrantedAt: time.Now(),
+			Reason:    change.Reason,
+		})
+	} else {
+		action = models.AuditRevoke
+		change.Profile = models.ProfileReadOnly
+		err = h.permRepo.Delete(ctx, change.UserID)
+	}
+	if err != nil {
+		reply("❌ Couldn't update <@%s>'s permissions: %v", change.UserID, err)
+		return err
+	}
+
+	event := models.NewAuditEvent(action, actorID, change.UserID)
+	event.Details["previous"] = previous
+	event.Details["profile"] = change.Profile
+	if change.Reason != "" {
+		event.Details["reason"] = change.Reason
+	}
+	if err := h.auditRepo.Record(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to audit permission change", "action", action, logging.UserID, change.UserID, "error", err)
+	}
+
+	reply("🔐 <@%s> is now *%s* (was %s).", change.UserID, change.Profile, previous)
+	return nil
+}
@@ -0,0 +1,57 @@
+package interactions
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// privacyChoice releases an answer held back for privacy. The agent polls
+// the conversation, so the choice is posted as a bot message whose metadata
+// names the notice and the user who chose. Answers built on restricted data
+// carry the profile needed to release them
+func (h *Handlers) privacyChoice(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	msg := callback.Message
+	minProfile := action.Value
+
+	conv, err := h.convRepo.GetByMessage(ctx, channelID, msg.ThreadTimestamp)
+	if err != nil || conv.Ended() {
+		return h.ephemeral(ctx, channelID, userID, "This session has ended. Mention me again to start a new one.")
+	}
+	if !conv.TookPart(userID) {
+		return h.ephemeral(ctx, channelID, userID, "Only people taking part in this conversation can release this answer.")
+	}
+	if minProfile != "" && minProfile != models.ProfileReadOnly {
+		profile, err := h.profileOf(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("check permissions: %w", err)
+		}
+		if !models.ProfileAtLeast(profile, minProfile) {
+			return h.ephemeral(ctx, channelID, userID, fmt.Sprintf("Only someone with the %s profile can release this answer.", minProfile))
+		}
+	}
+
+	if _, err := h.slackClient.PostMessage(ctx, channelID,
+		slack.MsgOptionText(privacy.Chosen(userID, action.ActionID), false),
+		slack.MsgOptionMetadata(privacy.Metadata(userID, action.ActionID, msg.Timestamp)),
+		slackclient.InThread(conv.ThreadTS),
+	); err != nil {
+		return err
+	}
+
+	// Drop the buttons so the answer is only released once
+	if err := h.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(privacy.WithoutButtons(msg.Blocks.BlockSet)...),
+	); err != nil {
+		slog.WarnContext(ctx, "failed to remove privacy buttons", "error", err)
+	}
+	return nil
+}
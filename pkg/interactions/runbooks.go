@@ -0,0 +1,70 @@
+package interactions
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/runbook"
+	"github.com/slack-go/slack"
+)
+
+// CaptureRunbook drafts a runbook from a conversation and posts it for
+// review. A conversation already captured returns its existing runbook
+// rather than drafting a second one
+func (h *Handlers) CaptureRunbook(ctx context.Context, conv *models.Conversation, userID, channelID string) (*models.Runbook, bool, error) {
+	existing, err := h.runbookRepo.GetByConversation(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil && existing.Status != models.RunbookDiscarded {
+		return existing, false, nil
+	}
+
+	history, err := h.convRepo.GetHistoryItems(ctx, conv.ConversationID)
+	if err != nil {
+		return nil, false, fmt.Errorf("load history: %w", err)
+	}
+
+	rb, err := runbook.NewDrafter(h.bedrock).Draft(ctx, conv, history, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := h.runbookRepo.Save(ctx, rb); err != nil {
+		return nil, false, fmt.Errorf("save runbook: %w", err)
+	}
+
+	filename := fmt.Sprintf("%s.md", rb.RunbookID)
+	if err := h.slackClient.UploadFile(ctx, channelID, "", filename, rb.Title, []byte(runbook.Markdown(rb))); err != nil {
+		slog.WarnContext(ctx, "failed to upload runbook", "runbook_id", rb.RunbookID, "error", err)
+	}
+	return rb, true, nil
+}
+
+// saveRunbook drafts a runbook when someone clicks "Save as runbook" under
+// a resolution
+func (h *Handlers) saveRunbook(ctx context.Context, callback *slack.InteractionCallback, action *slack.BlockAction) error {
+	channelID := callback.Channel.ID
+	userID := callback.User.ID
+	conversationID := action.Value
+
+	conv, err := h.convRepo.GetByID(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	rb, _, err := h.CaptureRunbook(ctx, conv, userID, channelID)
+	if err != nil {
+		if postErr := h.ephemeral(ctx, channelID, userID, fmt.Sprintf("❌ Couldn't save a runbook from `%s`: %v", conversationID, err)); postErr != nil {
+			slog.WarnContext(ctx, "failed to report runbook error", "error", postErr)
+		}
+		return err
+	}
+
+	msg := callback.Message
+	return h.slackClient.UpdateMessage(ctx, channelID, msg.Timestamp,
+		slack.MsgOptionText(msg.Text, false),
+		slack.MsgOptionBlocks(runbook.Saved(msg.Blocks.BlockSet, rb)...),
+	)
+}
@@ -0,0 +1,444 @@
+// Package jobs runs operations that outlive a single conversation turn,
+// such as Logs Insights scans over several days or sweeps across regions.
+// The model starts a job and answers right away; the job is persisted, run
+// by the agent or a worker Lambda, and shows its progress in a Slack message
+// of its own. Its result waits in the job record for a later turn, and
+// anyone in the conversation can cancel it
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// ProgressInterval is how often a job's progress is saved and shown at
+// most, and how often a running job checks whether it was cancelled
+const ProgressInterval = 5 * time.Second
+
+const (
+	// maxResult bounds the result kept for the model, well inside the
+	// DynamoDB item limit
+	maxResult = 32 * 1024
+
+	// finishMargin is the time kept back from a worker's deadline to
+	// record how the job ended
+	finishMargin = 15 * time.Second
+)
+
+var (
+	// ErrNotFound is returned for a job ID that doesn't exist
+	ErrNotFound = errors.New("job not found")
+
+	// ErrEnded is returned when cancelling a job that already ended
+	ErrEnded = errors.New("job already ended")
+)
+
+// Func runs a job: it reads its input, reports progress as it goes, and
+// returns its result as text for the model. It must stop when ctx is done
+type Func func(ctx context.Context, input json.RawMessage, progress *Progress) (string, error)
+
+// Kind is a kind of job the model can start
+type Kind struct {
+	// Name identifies the kind to the model
+	Name string
+
+	// Description tells the model what the job does and when to start one
+	// instead of calling a tool directly
+	Description string
+
+	// InputSchema is the JSON Schema of the job's input
+	InputSchema map[string]interface{}
+
+	Run Func
+}
+
+// Store persists jobs. The updates report false when the job isn't in the
+// state they expect, e.g. it was cancelled in the meantime
+type Store interface {
+	Create(ctx context.Context, job *models.Job) error
+	Get(ctx context.Context, jobID string) (*models.Job, error)
+	ListByConversation(ctx context.Context, conversationID string) ([]*models.Job, error)
+	Claim(ctx context.Context, jobID string) (bool, error)
+	UpdateProgress(ctx context.Context, jobID string, done, total int, note string) (bool, error)
+	Finish(ctx context.Context, job *models.Job) (bool, error)
+}
+
+// Poster shows a job's progress in Slack
+type Poster interface {
+	PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error)
+	UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error
+}
+
+// Dispatcher hands a new job to what runs it
+type Dispatcher interface {
+	Dispatch(ctx context.Context, jobID string) error
+}
+
+// Manager starts, runs, lists and cancels jobs
+type Manager struct {
+	store      Store
+	poster     Poster
+	kinds      map[string]Kind
+	dispatcher Dispatcher
+	interval   time.Duration
+}
+
+// NewManager creates a manager. Without a dispatcher, queued jobs wait for
+// a worker to run them
+func NewManager(store Store, poster Poster) *Manager {
+	return &Manager{store: store, poster: poster, kinds: map[string]Kind{}, interval: ProgressInterval}
+}
+
+// Register adds a kind of job. A kind registered under an existing name
+// replaces it
+func (m *Manager) Register(kind Kind) {
+	m.kinds[kind.Name] = kind
+}
+
+// Kinds returns the registered kinds by name
+func (m *Manager) Kinds() []Kind {
+	kinds := make([]Kind, 0, len(m.kinds))
+	for _, k := range m.kinds {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Name < kinds[j].Name })
+	return kinds
+}
+
+// SetDispatcher runs new jobs with d
+func (m *Manager) SetDispatcher(d Dispatcher) {
+	m.dispatcher = d
+}
+
+// Submit queues a job for a conversation, posts its progress message, and
+// dispatches it
+func (m *Manager) Submit(ctx context.Context, conv *models.Conversation, kind string, input json.RawMessage, userID string) (*models.Job, error) {
+	if _, ok := m.kinds[kind]; !ok {
+		return nil, fmt.Errorf("unknown job kind %q", kind)
+	}
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	if !json.Valid(input) {
+		return nil, errors.New("job input must be JSON")
+	}
+
+	job := models.NewJob(conv, kind, string(input), userID)
+	ts, err := m.poster.PostMessage(ctx, job.ChannelID, slack.MsgOptionText(Text(job), false), slackclient.InThread(job.ThreadTS))
+	if err != nil {
+		return nil, fmt.Errorf("post job message: %w", err)
+	}
+	job.MessageTS = ts
+
+	if err := m.store.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "queued job", "kind", kind, "job_id", job.JobID, logging.ConversationID, conv.ConversationID)
+
+	if m.dispatcher != nil {
+		if err := m.dispatcher.Dispatch(ctx, job.JobID); err != nil {
+			job.Status, job.Error = models.JobFailed, fmt.Sprintf("couldn't be started: %v", err)
+			m.finish(ctx, job)
+			return nil, fmt.Errorf("dispatch job: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// List returns a conversation's jobs, newest first
+func (m *Manager) List(ctx context.Context, conversationID string) ([]*models.Job, error) {
+	return m.store.ListByConversation(ctx, conversationID)
+}
+
+// Get returns a job, or ErrNotFound
+func (m *Manager) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	job, err := m.store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// Cancel stops a queued or running job. A running job notices within
+// ProgressInterval and stops
+func (m *Manager) Cancel(ctx context.Context, jobID, userID string) (*models.Job, error) {
+	job, err := m.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Ended() {
+		return job, ErrEnded
+	}
+
+	job.Status, job.CancelledBy = models.JobCancelled, userID
+	ok, err := m.store.Finish(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if job, err = m.Get(ctx, jobID); err != nil {
+			return nil, err
+		}
+		return job, ErrEnded
+	}
+
+	slog.InfoContext(ctx, "job cancelled", "job_id", jobID, logging.UserID, userID)
+	m.show(ctx, job)
+	return job, nil
+}
+
+// Run claims a queued job and runs it to the end, recording its result. A
+// job another worker claimed, or one cancelled before it started, is left
+// alone. Jobs stop when ctx is done, in time to record that they did
+func (m *Manager) Run(ctx context.Context, jobID string) error {
+	job, err := m.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	kind, ok := m.kinds[job.Kind]
+	if !ok {
+		job.Status, job.Error = models.JobFailed, fmt.Sprintf("unknown job kind %q", job.Kind)
+		m.finish(ctx, job)
+		return nil
+	}
+
+	claimed, err := m.store.Claim(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		slog.InfoContext(ctx, "job is no longer queued, skipping", "job_id", jobID)
+		return nil
+	}
+	job.Status = models.JobRunning
+	m.show(ctx, job)
+	slog.InfoContext(ctx, "running job", "kind", job.Kind, "job_id", jobID, logging.ConversationID, job.ConversationID)
+
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		runCtx, cancel = context.WithDeadline(ctx, deadline.Add(-finishMargin))
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	progress := &Progress{manager: m, job: job, stop: cancel}
+	stopWatching := m.watch(runCtx, job.JobID, progress)
+	started := time.Now()
+	result, err := kind.Run(runCtx, json.RawMessage(job.Input), progress)
+	stopWatching()
+
+	if progress.cancelled() {
+		slog.InfoContext(ctx, "job stopped after it was cancelled", "job_id", jobID)
+		return nil
+	}
+
+	// The job's context may be done, so how it ended is recorded with one
+	// of its own
+	finishCtx, cancelFinish := context.WithTimeout(context.WithoutCancel(ctx), finishMargin)
+	defer cancelFinish()
+
+	switch {
+	case err == nil:
+		job.Status, job.Result = models.JobSucceeded, clip(result)
+	case runCtx.Err() != nil && ctx.Err() == nil:
+		job.Status, job.Error = models.JobFailed, fmt.Sprintf("ran out of time after %s", humanize.Duration(time.Since(started).Round(time.Second)))
+	case ctx.Err() != nil:
+		job.Status, job.Error = models.JobFailed, "stopped before it finished: the process running it shut down"
+	default:
+		job.Status, job.Error = models.JobFailed, err.Error()
+	}
+	m.finish(finishCtx, job)
+	slog.InfoContext(ctx, "job ended", "job_id", jobID, "status", job.Status, "duration", time.Since(started).Round(time.Second).String())
+	return nil
+}
+
+// watch checks every interval whether the job was cancelled, stopping it
+// when it was. The returned function stops watching
+func (m *Manager) watch(ctx context.Context, jobID string, progress *Progress) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			job, err := m.store.Get(ctx, jobID)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to check job", "job_id", jobID, "error", err)
+				continue
+			}
+			if job == nil || job.Ended() {
+				progress.cancel()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// finish records how a job ended and shows it, unless it was cancelled
+// first
+func (m *Manager) finish(ctx context.Context, job *models.Job) {
+	ok, err := m.store.Finish(ctx, job)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to record job status", "job_id", job.JobID, "status", job.Status, "error", err)
+	}
+	if !ok && err == nil {
+		slog.InfoContext(ctx, "job had already ended", "job_id", job.JobID)
+		return
+	}
+	m.show(ctx, job)
+}
+
+// show replaces the job's progress message with its current state
+func (m *Manager) show(ctx context.Context, job *models.Job) {
+	if job.MessageTS == "" {
+		return
+	}
+	if err := m.poster.UpdateMessage(ctx, job.ChannelID, job.MessageTS, slack.MsgOptionText(Text(job), false)); err != nil {
+		slog.WarnContext(ctx, "failed to update job message", "job_id", job.JobID, "error", err)
+	}
+}
+
+// Progress reports how far a running job has got
+type Progress struct {
+	manager *Manager
+	job     *models.Job
+	stop    context.CancelFunc
+
+	mu    sync.Mutex
+	saved time.Time
+	ended bool
+}
+
+// Update records that done of total steps are complete, and what the job is
+// doing now. It is saved and shown at most every ProgressInterval, and
+// always on the last step. A nil Progress discards updates
+func (p *Progress) Update(ctx context.Context, done, total int, note string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.job.Done, p.job.Total, p.job.Note = done, total, note
+	if p.ended || (done < total && time.Since(p.saved) < p.manager.interval) {
+		p.mu.Unlock()
+		return
+	}
+	p.saved = time.Now()
+	p.mu.Unlock()
+
+	ok, err := p.manager.store.UpdateProgress(ctx, p.job.JobID, done, total, note)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to record job progress", "job_id", p.job.JobID, "error", err)
+		return
+	}
+	if !ok {
+		p.cancel()
+		return
+	}
+	p.manager.show(ctx, p.job)
+}
+
+// cancel stops a job that was cancelled elsewhere
+func (p *Progress) cancel() {
+	p.mu.Lock()
+	p.ended = true
+	p.mu.Unlock()
+	p.stop()
+}
+
+func (p *Progress) cancelled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ended
+}
+
+// Text describes a job for its progress message
+func Text(job *models.Job) string {
+	name := fmt.Sprintf("Background job `%s` (%s)", job.JobID, job.Kind)
+	cancelHint := fmt.Sprintf("Cancel it with `/cloudops jobs cancel %s`.", job.JobID)
+
+	switch job.Status {
+	case models.JobQueued:
+		return fmt.Sprintf("⏳ %s is queued. %s", name, cancelHint)
+	case models.JobRunning:
+		text := fmt.Sprintf("🔄 %s is running", name)
+		if job.Total > 0 {
+			text += fmt.Sprintf(": %d of %d (%d%%)", job.Done, job.Total, job.Percent())
+		}
+		if job.Note != "" {
+			text += fmt.Sprintf(", %s", job.Note)
+		}
+		return text + ". " + cancelHint
+	case models.JobSucceeded:
+		return fmt.Sprintf("✅ %s finished%s. Ask me about the results.", name, took(job))
+	case models.JobCancelled:
+		if job.CancelledBy != "" {
+			return fmt.Sprintf("🛑 %s was cancelled by <@%s>.", name, job.CancelledBy)
+		}
+		return fmt.Sprintf("🛑 %s was cancelled.", name)
+	default:
+		return fmt.Sprintf("❌ %s failed%s: %s", name, took(job), job.Error)
+	}
+}
+
+// Describe summarizes a job on one line, for listings
+func Describe(job *models.Job) string {
+	line := fmt.Sprintf("`%s` %s: %s", job.JobID, job.Kind, job.Status)
+	switch {
+	case job.Status == models.JobRunning && job.Total > 0:
+		line += fmt.Sprintf(", %d of %d (%d%%)", job.Done, job.Total, job.Percent())
+	case job.Status == models.JobFailed:
+		line += ", " + job.Error
+	}
+	return line + fmt.Sprintf(", started by <@%s>", job.RequestedBy)
+}
+
+// took says how long an ended job ran
+func took(job *models.Job) string {
+	if job.CompletedAt == nil {
+		return ""
+	}
+	return " in " + humanize.Duration(job.CompletedAt.Sub(job.CreatedAt).Round(time.Second))
+}
+
+// clip bounds a result to maxResult bytes
+func clip(result string) string {
+	if len(result) <= maxResult {
+		return result
+	}
+	cut := strings.LastIndex(result[:maxResult], "\n")
+	if cut <= 0 {
+		cut = maxResult
+	}
+	return result[:cut] + "\n… (result truncated)"
+}
@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+type fakeStore struct {
+	mu   sync.Mutex
+	jobs map[string]*models.Job
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{jobs: map[string]*models.Job{}}
+}
+
+func (s *fakeStore) Create(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := *job
+	s.jobs[job.JobID] = &j
+	return nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, nil
+	}
+	j := *job
+	return &j, nil
+}
+
+func (s *fakeStore) ListByConversation(ctx context.Context, conversationID string) ([]*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []*models.Job
+	for _, job := range s.jobs {
+		if job.ConversationID == conversationID {
+			j := *job
+			jobs = append(jobs, &j)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *fakeStore) Claim(ctx context.Context, jobID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobID]
+	if job.Status != models.JobQueued {
+		return false, nil
+	}
+	job.Status = models.JobRunning
+	return true, nil
+}
+
+func (s *fakeStore) UpdateProgress(ctx context.Context, jobID string, done, total int, note string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[jobID]
+	if job.Status != models.JobRunning {
+		return false, nil
+	}
+	job.Done, job.Total, job.Note = done, total, note
+	return true, nil
+}
+
+func (s *fakeStore) Finish(ctx context.Context, job *models.Job) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.jobs[job.JobID]
+	if stored.Ended() {
+		return false, nil
+	}
+	j := *job
+	s.jobs[job.JobID] = &j
+	return true, nil
+}
+
+type fakePoster struct {
+	mu      sync.Mutex
+	posted  int
+	updates int
+}
+
+func (p *fakePoster) PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.posted++
+	return "1700000000.000100", nil
+}
+
+func (p *fakePoster) UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updates++
+	return nil
+}
+
+var testConv = &models.Conversation{ConversationID: "conv-1", ChannelID: "C123", ThreadTS: "1700000000.000001"}
+
+func TestRun(t *testing.T) {
+	store, poster := newFakeStore(), &fakePoster{}
+	m := NewManager(store, poster)
+	m.Register(Kind{Name: "count", Run: func(ctx context.Context, input json.RawMessage, progress *Progress) (string, error) {
+		for i := 1; i <= 3; i++ {
+			progress.Update(ctx, i, 3, "counting")
+		}
+		return "counted to 3", nil
+	}})
+
+	job, err := m.Submit(context.Background(), testConv, "count", nil, "U123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != models.JobQueued || job.MessageTS == "" || poster.posted != 1 {
+		t.Fatalf("Submit() = %+v, want a queued job with a progress message", job)
+	}
+
+	if err := m.Run(context.Background(), job.JobID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.Get(context.Background(), job.JobID)
+	if got.Status != models.JobSucceeded || got.Result != "counted to 3" || got.Done != 3 {
+		t.Errorf("job = %+v, want it to have succeeded with its result", got)
+	}
+
+	// A job that already ran isn't run again
+	if err := m.Run(context.Background(), job.JobID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	store := newFakeStore()
+	m := NewManager(store, &fakePoster{})
+	m.Register(Kind{Name: "broken", Run: func(ctx context.Context, input json.RawMessage, progress *Progress) (string, error) {
+		return "", errors.New("query failed")
+	}})
+
+	job, err := m.Submit(context.Background(), testConv, "broken", json.RawMessage(`{}`), "U123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Run(context.Background(), job.JobID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.Get(context.Background(), job.JobID)
+	if got.Status != models.JobFailed || got.Error != "query failed" {
+		t.Errorf("job = %+v, want it to have failed with the error", got)
+	}
+}
+
+func TestSubmitUnknownKind(t *testing.T) {
+	m := NewManager(newFakeStore(), &fakePoster{})
+	if _, err := m.Submit(context.Background(), testConv, "nope", nil, "U123"); err == nil {
+		t.Error("Submit() should reject an unknown kind")
+	}
+}
+
+func TestCancelWhileRunning(t *testing.T) {
+	store := newFakeStore()
+	m := NewManager(store, &fakePoster{})
+	m.interval = 10 * time.Millisecond
+
+	started := make(chan struct{})
+	m.Register(Kind{Name: "wait", Run: func(ctx context.Context, input json.RawMessage, progress *Progress) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}})
+
+	job, err := m.Submit(context.Background(), testConv, "wait", nil, "U123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran := make(chan error)
+	go func() { ran <- m.Run(context.Background(), job.JobID) }()
+	<-started
+
+	if _, err := m.Cancel(context.Background(), job.JobID, "U456"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-ran:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job kept running after it was cancelled")
+	}
+
+	got, _ := m.Get(context.Background(), job.JobID)
+	if got.Status != models.JobCancelled || got.CancelledBy != "U456" {
+		t.Errorf("job = %+v, want it cancelled by U456", got)
+	}
+	if _, err := m.Cancel(context.Background(), job.JobID, "U456"); !errors.Is(err, ErrEnded) {
+		t.Errorf("Cancel() error = %v, want ErrEnded", err)
+	}
+}
+
+func TestTools(t *testing.T) {
+	store := newFakeStore()
+	m := NewManager(store, &fakePoster{})
+	m.Register(Kind{Name: "count", Description: "Counts.", Run: func(ctx context.Context, input json.RawMessage, progress *Progress) (string, error) {
+		return "counted", nil
+	}})
+
+	if _, err := m.StartTool().Execute(context.Background(), json.RawMessage(`{"kind":"count","input":{}}`)); err == nil {
+		t.Error("StartTool should need a conversation")
+	}
+
+	ctx := WithConversation(context.Background(), testConv, "U123")
+	out, err := m.StartTool().Execute(ctx, json.RawMessage(`{"kind":"count","input":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ := m.List(ctx, testConv.ConversationID)
+	if len(jobs) != 1 || !strings.Contains(out, jobs[0].JobID) {
+		t.Fatalf("Execute() = %q, want the started job's ID", out)
+	}
+
+	if err := m.Run(ctx, jobs[0].JobID); err != nil {
+		t.Fatal(err)
+	}
+	out, err = m.StatusTool().Execute(ctx, json.RawMessage(`{"job_id":"`+jobs[0].JobID+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Result:\ncounted") {
+		t.Errorf("Execute() = %q, want the job's result", out)
+	}
+
+	other := WithConversation(context.Background(), &models.Conversation{ConversationID: "conv-2"}, "U123")
+	if _, err := m.StatusTool().Execute(other, json.RawMessage(`{"job_id":"`+jobs[0].JobID+`"}`)); err == nil {
+		t.Error("StatusTool should hide other conversations' jobs")
+	}
+}
+
+func TestText(t *testing.T) {
+	job := models.NewJob(testConv, "logs_insights_scan", "{}", "U123")
+	job.Status, job.Done, job.Total, job.Note = models.JobRunning, 2, 8, "scanning Mar 3"
+	text := Text(job)
+	for _, want := range []string{"is running: 2 of 8 (25%)", "scanning Mar 3", "/cloudops jobs cancel " + job.JobID} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() = %q, want it to contain %q", text, want)
+		}
+	}
+}
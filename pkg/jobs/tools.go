@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+type contextKey struct{}
+
+// origin is the conversation and sender a model call answers
+type origin struct {
+	conv   *models.Conversation
+	userID string
+}
+
+// WithConversation returns a context whose tool calls can start and check
+// jobs for conv on behalf of userID
+func WithConversation(ctx context.Context, conv *models.Conversation, userID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, origin{conv: conv, userID: userID})
+}
+
+func fromContext(ctx context.Context) (origin, error) {
+	o, ok := ctx.Value(contextKey{}).(origin)
+	if !ok || o.conv == nil {
+		return origin{}, errors.New("background jobs can only be used in a conversation")
+	}
+	return o, nil
+}
+
+// StartTool lets the model start a job
+type StartTool struct {
+	manager *Manager
+}
+
+// StartTool returns the tool that starts jobs
+func (m *Manager) StartTool() *StartTool {
+	return &StartTool{manager: m}
+}
+
+// Name identifies the tool to the model
+func (t *StartTool) Name() string {
+	return "start_background_job"
+}
+
+// Description tells the model what the tool does and which kinds of job
+// there are
+func (t *StartTool) Description() string {
+	var b strings.Builder
+	b.WriteString("Start a background job for work too big for a single tool call, such as scanning several days of logs or checking every region. " +
+		"The job runs after you answer and posts its progress in the conversation; tell the user it has started. " +
+		"Read its result later with get_background_jobs. Kinds of job and their input:")
+	for _, k := range t.manager.Kinds() {
+		schema, _ := json.Marshal(k.InputSchema)
+		fmt.Fprintf(&b, "\n- %s: %s Input: %s", k.Name, k.Description, schema)
+	}
+	return b.String()
+}
+
+// InputSchema is the JSON Schema of the tool's input
+func (t *StartTool) InputSchema() map[string]interface{} {
+	var names []string
+	for _, k := range t.manager.Kinds() {
+		names = append(names, k.Name)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"enum":        names,
+				"description": "Kind of job",
+			},
+			"input": map[string]interface{}{
+				"type":        "object",
+				"description": "The job's input, as its kind describes",
+			},
+		},
+		"required": []string{"kind", "input"},
+	}
+}
+
+// Execute starts the job
+func (t *StartTool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in struct {
+		Kind  string          `json:"kind"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	o, err := fromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	job, err := t.manager.Submit(ctx, o.conv, in.Kind, in.Input, o.userID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Started %s job %s. Its progress is shown in the conversation, and anyone can cancel it with `/cloudops jobs cancel %s`.", job.Kind, job.JobID, job.JobID), nil
+}
+
+// StatusTool lets the model check on the conversation's jobs and read
+// their results
+type StatusTool struct {
+	manager *Manager
+}
+
+// StatusTool returns the tool that checks on jobs
+func (m *Manager) StatusTool() *StatusTool {
+	return &StatusTool{manager: m}
+}
+
+// Name identifies the tool to the model
+func (t *StatusTool) Name() string {
+	return "get_background_jobs"
+}
+
+// Description tells the model what the tool does
+func (t *StatusTool) Description() string {
+	return "List the background jobs started in this conversation with their status and progress, or get one job's result by its ID."
+}
+
+// InputSchema is the JSON Schema of the tool's input
+func (t *StatusTool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"job_id": map[string]interface{}{
+				"type":        "string",
+				"description": "A job to get the result of; all of the conversation's jobs when omitted",
+			},
+		},
+	}
+}
+
+// Execute lists the jobs, or returns one job with its result
+func (t *StatusTool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in struct {
+		JobID string `json:"job_id"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+	}
+	o, err := fromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if in.JobID == "" {
+		jobs, err := t.manager.List(ctx, o.conv.ConversationID)
+		if err != nil {
+			return "", err
+		}
+		if len(jobs) == 0 {
+			return "No background jobs have been started in this conversation.", nil
+		}
+		lines := make([]string, len(jobs))
+		for i, job := range jobs {
+			lines[i] = Describe(job)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	job, err := t.manager.Get(ctx, in.JobID)
+	if err != nil || job.ConversationID != o.conv.ConversationID {
+		return "", fmt.Errorf("no job %s in this conversation", in.JobID)
+	}
+	switch job.Status {
+	case models.JobSucceeded:
+		return Describe(job) + "\n\nResult:\n" + job.Result, nil
+	case models.JobRunning:
+		return Describe(job) + ". It is still running; check again later.", nil
+	}
+	return Describe(job), nil
+}
+
+// Local runs jobs in this process, for agents without a worker Lambda.
+// Jobs still running when it closes are recorded as stopped
+type Local struct {
+	manager *Manager
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewLocal creates a dispatcher that runs the manager's jobs in goroutines
+func NewLocal(m *Manager) *Local {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Local{manager: m, ctx: ctx, cancel: cancel}
+}
+
+// Dispatch starts running a job
+func (l *Local) Dispatch(ctx context.Context, jobID string) error {
+	if l.ctx.Err() != nil {
+		return errors.New("shutting down")
+	}
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := l.manager.Run(l.ctx, jobID); err != nil {
+			slog.WarnContext(l.ctx, "job failed to run", "job_id", jobID, "error", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the running jobs and waits for them to record that they did
+func (l *Local) Close() {
+	l.cancel()
+	l.wg.Wait()
+}
@@ -0,0 +1,77 @@
+// Package killswitch turns the whole bot off during an incident with the
+// bot itself. The switch is a single settings item that the handlers check
+// before starting a conversation and running agents poll between turns
+package killswitch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// DefaultInterval is how long a read of the switch is trusted, bounding how
+// long a running agent keeps answering after the bot is disabled
+const DefaultInterval = 15 * time.Second
+
+// Source reads the stored kill switch
+type Source interface {
+	GetKillSwitch(ctx context.Context) (*models.KillSwitch, error)
+}
+
+// Switch caches the kill switch so it can be checked on every poll for at
+// most one read per interval
+type Switch struct {
+	source   Source
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	state   *models.KillSwitch
+	checked time.Time
+}
+
+// New creates a switch that rereads source at most once per interval
+func New(source Source, interval time.Duration) *Switch {
+	return &Switch{source: source, interval: interval, now: time.Now}
+}
+
+// Disabled returns the kill switch while the bot is disabled, and nil
+// otherwise. When the switch can't be read the last known state holds, so a
+// DynamoDB outage neither stops nor restarts the bot. A nil Switch is never
+// disabled
+func (s *Switch) Disabled(ctx context.Context) *models.KillSwitch {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.checked.IsZero() || now.Sub(s.checked) >= s.interval {
+		state, err := s.source.GetKillSwitch(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to read kill switch", "error", err)
+		} else {
+			s.state = state
+		}
+		s.checked = now
+	}
+
+	if s.state == nil || !s.state.Disabled {
+		return nil
+	}
+	return s.state
+}
+
+// Message explains to users why the bot isn't answering
+func Message(ks *models.KillSwitch) string {
+	text := fmt.Sprintf("⏸️ The CloudOps bot has been paused by <@%s>", ks.ChangedBy)
+	if ks.Reason != "" {
+		text += ": " + ks.Reason
+	}
+	return text + ". Try again once it is re-enabled."
+}
@@ -0,0 +1,72 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+type fakeSource struct {
+	state *models.KillSwitch
+	err   error
+	reads int
+}
+
+func (f *fakeSource) GetKillSwitch(ctx context.Context) (*models.KillSwitch, error) {
+	f.reads++
+	return f.state, f.err
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{}
+	s := New(source, time.Minute)
+	s.now = func() time.Time { return now }
+
+	if s.Disabled(ctx) != nil {
+		t.Fatal("Disabled() should be nil before the switch is ever set")
+	}
+
+	// A change is only seen once the cached read expires
+	source.state = &models.KillSwitch{Disabled: true, ChangedBy: "U1"}
+	if s.Disabled(ctx) != nil || source.reads != 1 {
+		t.Errorf("Disabled() reread the switch within the interval (%d reads)", source.reads)
+	}
+	now = now.Add(time.Minute)
+	if ks := s.Disabled(ctx); ks == nil || ks.ChangedBy != "U1" {
+		t.Errorf("Disabled() = %+v after the interval, want the stored switch", ks)
+	}
+
+	// Failed reads keep the last known state
+	source.state, source.err = nil, errors.New("throttled")
+	now = now.Add(time.Minute)
+	if s.Disabled(ctx) == nil {
+		t.Error("Disabled() should stay disabled when the switch can't be read")
+	}
+
+	source.state, source.err = &models.KillSwitch{Disabled: false}, nil
+	now = now.Add(time.Minute)
+	if s.Disabled(ctx) != nil {
+		t.Error("Disabled() should be nil once the switch is cleared")
+	}
+
+	var none *Switch
+	if none.Disabled(ctx) != nil {
+		t.Error("a nil Switch should never be disabled")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	text := Message(&models.KillSwitch{Disabled: true, ChangedBy: "U1", Reason: "runaway API calls"})
+	if !strings.Contains(text, "<@U1>: runaway API calls.") {
+		t.Errorf("Message() = %q", text)
+	}
+	if text := Message(&models.KillSwitch{Disabled: true, ChangedBy: "U1"}); !strings.Contains(text, "<@U1>.") {
+		t.Errorf("Message() without a reason = %q", text)
+	}
+}
@@ -0,0 +1,79 @@
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Stages of a conversation, shown as a reaction on the mention that started
+// it so its state can be seen at a glance
+const (
+	Received = "eyes"             // 👀 the mention has been picked up
+	Working  = "gear"             // ⚙️ an agent is answering
+	Done     = "white_check_mark" // ✅ the conversation ended normally
+	Failed   = "warning"          // ⚠️ the conversation could not be answered
+)
+
+// stages lists every stage reaction, so a new stage replaces the last one
+var stages = []string{Received, Working, Done, Failed}
+
+// Reactor adds and removes the bot's reactions on messages
+type Reactor interface {
+	AddReaction(ctx context.Context, channelID, ts, name string) error
+	RemoveReaction(ctx context.Context, channelID, ts, name string) error
+	GetReactions(ctx context.Context, channelID, ts string) ([]string, error)
+}
+
+// ForStatus returns the stage reaction for a conversation status
+func ForStatus(status string) string {
+	switch status {
+	case models.StatusPending:
+		return Received
+	case models.StatusActive:
+		return Working
+	case models.StatusCompleted:
+		return Done
+	default:
+		return Failed
+	}
+}
+
+// Mark sets the stage reaction on the message that started conv, replacing
+// the previous stage. Conversations started before the message was
+// recorded are skipped. It is best-effort: failures are only logged
+func Mark(ctx context.Context, r Reactor, conv *models.Conversation, stage string) {
+	if conv.MessageTS == "" {
+		return
+	}
+	channelID := conv.MessageChannelID()
+
+	current, err := r.GetReactions(ctx, channelID, conv.MessageTS)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read status reaction", logging.ConversationID, conv.ConversationID, "error", err)
+	}
+	for _, name := range current {
+		if name == stage || !isStage(name) {
+			continue
+		}
+		if err := r.RemoveReaction(ctx, channelID, conv.MessageTS, name); err != nil {
+			slog.WarnContext(ctx, "failed to remove status reaction", "reaction", name, logging.ConversationID, conv.ConversationID, "error", err)
+		}
+	}
+
+	if err := r.AddReaction(ctx, channelID, conv.MessageTS, stage); err != nil {
+		slog.WarnContext(ctx, "failed to add status reaction", "reaction", stage, logging.ConversationID, conv.ConversationID, "error", err)
+	}
+}
+
+// isStage reports whether a reaction is one of the stage reactions
+func isStage(name string) bool {
+	for _, stage := range stages {
+		if name == stage {
+			return true
+		}
+	}
+	return false
+}
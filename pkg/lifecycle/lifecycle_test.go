@@ -0,0 +1,99 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// message is an in-memory message's reactions
+type message struct {
+	reactions map[string]bool
+	getErr    error
+}
+
+func (m *message) AddReaction(ctx context.Context, channelID, ts, name string) error {
+	m.reactions[name] = true
+	return nil
+}
+
+func (m *message) RemoveReaction(ctx context.Context, channelID, ts, name string) error {
+	delete(m.reactions, name)
+	return nil
+}
+
+func (m *message) GetReactions(ctx context.Context, channelID, ts string) ([]string, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	var names []string
+	for name := range m.reactions {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (m *message) names() string {
+	var names []string
+	for name := range m.reactions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestMarkReplacesPreviousStage(t *testing.T) {
+	ctx := context.Background()
+	conv := &models.Conversation{ConversationID: "c1", ChannelID: "C1", MessageTS: "1.0"}
+	msg := &message{reactions: map[string]bool{"fire": true}}
+
+	Mark(ctx, msg, conv, Received)
+	Mark(ctx, msg, conv, Working)
+	if got := msg.names(); got != "fire,gear" {
+		t.Errorf("reactions = %s, want fire,gear", got)
+	}
+
+	Mark(ctx, msg, conv, Done)
+	Mark(ctx, msg, conv, Done)
+	if got := msg.names(); got != "fire,white_check_mark" {
+		t.Errorf("reactions = %s, want fire,white_check_mark", got)
+	}
+}
+
+func TestMarkStillAddsWhenReadFails(t *testing.T) {
+	conv := &models.Conversation{ConversationID: "c1", ChannelID: "C1", MessageTS: "1.0"}
+	msg := &message{reactions: map[string]bool{}, getErr: errors.New("missing_scope")}
+
+	Mark(context.Background(), msg, conv, Failed)
+	if got := msg.names(); got != "warning" {
+		t.Errorf("reactions = %s, want warning", got)
+	}
+}
+
+func TestMarkWithoutMessage(t *testing.T) {
+	msg := &message{reactions: map[string]bool{}}
+
+	Mark(context.Background(), msg, &models.Conversation{ChannelID: "C1"}, Working)
+	if got := msg.names(); got != "" {
+		t.Errorf("reactions = %s, want none", got)
+	}
+}
+
+func TestForStatus(t *testing.T) {
+	tests := map[string]string{
+		models.StatusPending:   Received,
+		models.StatusActive:    Working,
+		models.StatusCompleted: Done,
+		models.StatusFailed:    Failed,
+		models.StatusTimeout:   Failed,
+	}
+	for status, want := range tests {
+		if got := ForStatus(status); got != want {
+			t.Errorf("ForStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Builder generates region-correct AWS console URLs for resources,
+// optionally wrapped in a role-switch or federation sign-in link
+type Builder struct {
+	region string
+
+	switchRoleAccount string
+	switchRoleName    string
+	switchRoleDisplay string
+
+	federationURL string
+}
+
+// Option configures a Builder
+type Option func(*Builder)
+
+// WithSwitchRole wraps console links in a role-switch link so users land in
+// the right account and role
+func WithSwitchRole(account, roleName, displayName string) Option {
+	return func(b *Builder) {
+		b.switchRoleAccount = account
+		b.switchRoleName = roleName
+		b.switchRoleDisplay = displayName
+	}
+}
+
+// WithFederation wraps console links in a federation sign-in URL. The
+// destination console URL is appended query-escaped to federationURL, e.g.
+// https://my-org.awsapps.com/start/#/console?account_id=123456789012&role_name=ReadOnly&destination=
+func WithFederation(federationURL string) Option {
+	return func(b *Builder) {
+		b.federationURL = federationURL
+	}
+}
+
+// New creates a link builder; region is used for resources without one
+func New(region string, opts ...Option) *Builder {
+	b := &Builder{region: region}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Entity returns a console link for an extracted entity, or "" if unknown
+func (b *Builder) Entity(e models.Entity) string {
+	switch e.Type {
+	case models.EntityInstance:
+		return b.Instance(e.Region, e.ID)
+	case models.EntityLogGroup:
+		return b.LogGroup(e.Region, e.ID)
+	case models.EntityARN:
+		return b.ARN(e.ID)
+	}
+	return ""
+}
+
+// Instance returns a link to an EC2 instance
+func (b *Builder) Instance(region, instanceID string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/ec2/home?region=%s#InstanceDetails:instanceId=%s", consoleBase(region), region, instanceID))
+}
+
+// LogGroup returns a link to a CloudWatch Logs log group
+func (b *Builder) LogGroup(region, name string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/cloudwatch/home?region=%s#logsV2:log-groups/log-group/%s", consoleBase(region), region, escapeFragment(name)))
+}
+
+// Alarm returns a link to a CloudWatch alarm
+func (b *Builder) Alarm(region, name string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/cloudwatch/home?region=%s#alarmsV2:alarm/%s", consoleBase(region), region, url.PathEscape(name)))
+}
+
+// ECSService returns a link to an ECS service
+func (b *Builder) ECSService(region, cluster, service string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/ecs/v2/clusters/%s/services/%s/health?region=%s", consoleBase(region), url.PathEscape(cluster), url.PathEscape(service), region))
+}
+
+// LambdaFunction returns a link to a Lambda function
+func (b *Builder) LambdaFunction(region, name string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/lambda/home?region=%s#/functions/%s", consoleBase(region), region, url.PathEscape(name)))
+}
+
+// RDSInstance returns a link to an RDS database instance
+func (b *Builder) RDSInstance(region, id string) string {
+	region = b.regionOr(region)
+	return b.wrap(fmt.Sprintf("%s/rds/home?region=%s#database:id=%s", consoleBase(region), region, url.QueryEscape(id)))
+}
+
+// ARN returns a service-specific link for well-known resource types and
+// falls back to the console's generic ARN resolver
+func (b *Builder) ARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	partition, service, region, resource := parts[1], parts[2], b.regionOr(parts[3]), parts[5]
+
+	switch service {
+	case "ec2":
+		if id, ok := strings.CutPrefix(resource, "instance/"); ok {
+			return b.Instance(region, id)
+		}
+	case "logs":
+		if name, ok := strings.CutPrefix(resource, "log-group:"); ok {
+			return b.LogGroup(region, strings.TrimSuffix(name, ":*"))
+		}
+	case "cloudwatch":
+		if name, ok := strings.CutPrefix(resource, "alarm:"); ok {
+			return b.Alarm(region, name)
+		}
+	case "lambda":
+		if name, ok := strings.CutPrefix(resource, "function:"); ok {
+			name, _, _ = strings.Cut(name, ":") // drop version/alias
+			return b.LambdaFunction(region, name)
+		}
+	case "ecs":
+		if rest, ok := strings.CutPrefix(resource, "service/"); ok {
+			if cluster, service, ok := strings.Cut(rest, "/"); ok {
+				return b.ECSService(region, cluster, service)
+			}
+		}
+	case "rds":
+		if id, ok := strings.CutPrefix(resource, "db:"); ok {
+			return b.RDSInstance(region, id)
+		}
+	}
+
+	return b.wrap(fmt.Sprintf("https://%s/go/view?arn=%s", consoleDomain(partition, region), url.QueryEscape(arn)))
+}
+
+// wrap applies federation or role switching to a console URL
+func (b *Builder) wrap(consoleURL string) string {
+	switch {
+	case b.federationURL != "":
+		return b.federationURL + url.QueryEscape(consoleURL)
+	case b.switchRoleAccount != "" && b.switchRoleName != "":
+		params := url.Values{}
+		params.Set("account", b.switchRoleAccount)
+		params.Set("roleName", b.switchRoleName)
+		if b.switchRoleDisplay != "" {
+			params.Set("displayName", b.switchRoleDisplay)
+		}
+		params.Set("redirect_uri", consoleURL)
+		return "https://signin.aws.amazon.com/switchrole?" + params.Encode()
+	}
+	return consoleURL
+}
+
+func (b *Builder) regionOr(region string) string {
+	if region != "" {
+		return region
+	}
+	if b.region != "" {
+		return b.region
+	}
+	return "us-east-1"
+}
+
+// consoleBase returns the region-specific console origin
+func consoleBase(region string) string {
+	switch partitionOf(region) {
+	case "aws-cn":
+		return "https://" + region + ".console.amazonaws.cn"
+	case "aws-us-gov":
+		return "https://console.amazonaws-us-gov.com"
+	}
+	return "https://" + region + ".console.aws.amazon.com"
+}
+
+// consoleDomain returns the console domain for a partition
+func consoleDomain(partition, region string) string {
+	if partition == "" {
+		partition = partitionOf(region)
+	}
+	switch partition {
+	case "aws-cn":
+		return "console.amazonaws.cn"
+	case "aws-us-gov":
+		return "console.amazonaws-us-gov.com"
+	}
+	return "console.aws.amazon.com"
+}
+
+func partitionOf(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
+
+// escapeFragment encodes a value the way the CloudWatch console expects in
+// its URL fragment
+func escapeFragment(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "%", "$25")
+}
@@ -0,0 +1,107 @@
+package links
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestBuilderEntity(t *testing.T) {
+	b := New("us-east-1")
+
+	tests := []struct {
+		name   string
+		entity models.Entity
+		want   string
+	}{
+		{
+			name:   "instance",
+			entity: models.Entity{Type: models.EntityInstance, ID: "i-0123456789abcdef0", Region: "eu-west-1"},
+			want:   "https://eu-west-1.console.aws.amazon.com/ec2/home?region=eu-west-1#InstanceDetails:instanceId=i-0123456789abcdef0",
+		},
+		{
+			name:   "log group uses default region",
+			entity: models.Entity{Type: models.EntityLogGroup, ID: "/aws/lambda/api"},
+			want:   "https://us-east-1.console.aws.amazon.com/cloudwatch/home?region=us-east-1#logsV2:log-groups/log-group/$252Faws$252Flambda$252Fapi",
+		},
+		{
+			name:   "lambda function arn",
+			entity: models.Entity{Type: models.EntityARN, ID: "arn:aws:lambda:us-west-2:123456789012:function:payments:live"},
+			want:   "https://us-west-2.console.aws.amazon.com/lambda/home?region=us-west-2#/functions/payments",
+		},
+		{
+			name:   "ecs service arn",
+			entity: models.Entity{Type: models.EntityARN, ID: "arn:aws:ecs:us-west-2:123456789012:service/prod/api"},
+			want:   "https://us-west-2.console.aws.amazon.com/ecs/v2/clusters/prod/services/api/health?region=us-west-2",
+		},
+		{
+			name:   "unknown arn falls back to resolver",
+			entity: models.Entity{Type: models.EntityARN, ID: "arn:aws:sqs:us-east-1:123456789012:jobs"},
+			want:   "https://console.aws.amazon.com/go/view?arn=arn%3Aaws%3Asqs%3Aus-east-1%3A123456789012%3Ajobs",
+		},
+		{
+			name:   "unknown type",
+			entity: models.Entity{Type: "bucket", ID: "logs"},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.Entity(tt.entity); got != tt.want {
+				t.Errorf("Entity() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilderPartitions(t *testing.T) {
+	b := New("us-east-1")
+
+	if got := b.Instance("cn-north-1", "i-12345678"); !strings.HasPrefix(got, "https://cn-north-1.console.amazonaws.cn/") {
+		t.Errorf("China region link = %s", got)
+	}
+
+	if got := b.Instance("us-gov-west-1", "i-12345678"); !strings.HasPrefix(got, "https://console.amazonaws-us-gov.com/") {
+		t.Errorf("GovCloud region link = %s", got)
+	}
+}
+
+func TestBuilderSwitchRole(t *testing.T) {
+	b := New("us-east-1", WithSwitchRole("123456789012", "ReadOnly", "prod"))
+
+	got, err := url.Parse(b.Instance("", "i-12345678"))
+	if err != nil {
+		t.Fatalf("invalid URL: %v", err)
+	}
+
+	if got.Host != "signin.aws.amazon.com" || got.Path != "/switchrole" {
+		t.Errorf("link = %s, want a switchrole link", got)
+	}
+
+	q := got.Query()
+	if q.Get("account") != "123456789012" || q.Get("roleName") != "ReadOnly" {
+		t.Errorf("query = %v, missing account or role", q)
+	}
+
+	if !strings.Contains(q.Get("redirect_uri"), "InstanceDetails:instanceId=i-12345678") {
+		t.Errorf("redirect_uri = %s, want instance link", q.Get("redirect_uri"))
+	}
+}
+
+func TestBuilderFederation(t *testing.T) {
+	b := New("us-east-1",
+		WithSwitchRole("123456789012", "ReadOnly", ""),
+		WithFederation("https://my-org.awsapps.com/start/#/console?account_id=123456789012&role_name=ReadOnly&destination="),
+	)
+
+	got := b.LambdaFunction("", "api")
+	want := "https://my-org.awsapps.com/start/#/console?account_id=123456789012&role_name=ReadOnly&destination=" +
+		url.QueryEscape("https://us-east-1.console.aws.amazon.com/lambda/home?region=us-east-1#/functions/api")
+
+	if got != want {
+		t.Errorf("LambdaFunction() = %s, want %s", got, want)
+	}
+}
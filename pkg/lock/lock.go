@@ -0,0 +1,180 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Defaults for lease length and renewal
+const (
+	DefaultLeaseDuration = time.Minute
+	DefaultRenewInterval = 20 * time.Second
+	DefaultRetryInterval = 2 * time.Second
+)
+
+// ErrHeld is returned by Acquire when another owner kept the lock for the
+// whole wait
+var ErrHeld = errors.New("lock held by another owner")
+
+// Store persists leases. Implementations must make each call atomic
+type Store interface {
+	// TryAcquire takes the lock when it is free, expired at now, or already
+	// held by owner, returning false when someone else holds it
+	TryAcquire(ctx context.Context, lockID, owner string, now, expires time.Time) (bool, error)
+	// Renew extends a lease still held by owner, returning false when it
+	// was lost
+	Renew(ctx context.Context, lockID, owner string, expires time.Time) (bool, error)
+	// Release deletes the lease if owner still holds it
+	Release(ctx context.Context, lockID, owner string) error
+}
+
+// Manager hands out leases so only one agent works on a conversation at a
+// time. A lease that is not renewed expires, so a crashed owner never
+// blocks the next one for longer than the lease duration
+type Manager struct {
+	store         Store
+	owner         string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+	retryInterval time.Duration
+	now           func() time.Time
+}
+
+// New creates a manager acquiring leases on behalf of owner
+func New(store Store, owner string) *Manager {
+	return &Manager{
+		store:         store,
+		owner:         owner,
+		leaseDuration: DefaultLeaseDuration,
+		renewInterval: DefaultRenewInterval,
+		retryInterval: DefaultRetryInterval,
+		now:           time.Now,
+	}
+}
+
+// SetIntervals overrides the lease duration and how often a held lease is
+// renewed and a held lock is retried. Renewals happen every third of the
+// lease so a single failed write doesn't lose it
+func (m *Manager) SetIntervals(lease, retry time.Duration) {
+	m.leaseDuration = lease
+	m.renewInterval = lease / 3
+	m.retryInterval = retry
+}
+
+// Acquire takes the lock, retrying for up to wait while another owner
+// holds it. The returned lease is renewed in the background until
+// Release; its Done channel closes if the lease is lost
+func (m *Manager) Acquire(ctx context.Context, lockID string, wait time.Duration) (*Lease, error) {
+	deadline := m.now().Add(wait)
+	for {
+		now := m.now()
+		ok, err := m.store.TryAcquire(ctx, lockID, m.owner, now, now.Add(m.leaseDuration))
+		if err != nil {
+			return nil, fmt.Errorf("acquire lock %s: %w", lockID, err)
+		}
+		if ok {
+			return m.hold(lockID), nil
+		}
+		if !now.Before(deadline) {
+			return nil, ErrHeld
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(m.retryInterval):
+		}
+	}
+}
+
+func (m *Manager) hold(lockID string) *Lease {
+	l := &Lease{
+		manager: m,
+		lockID:  lockID,
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go l.renew()
+	return l
+}
+
+// Lease is a held lock
+type Lease struct {
+	manager  *Manager
+	lockID   string
+	done     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// Done is closed when the lease was lost to another owner or could not be
+// renewed before it expired. Work under the lock must stop
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Context returns a copy of ctx cancelled when the lease is lost
+func (l *Lease) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-l.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Release stops renewing and frees the lock for the next owner
+func (l *Lease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.stopped
+	if err := l.manager.store.Release(ctx, l.lockID, l.manager.owner); err != nil {
+		return fmt.Errorf("release lock %s: %w", l.lockID, err)
+	}
+	return nil
+}
+
+func (l *Lease) renew() {
+	defer close(l.stopped)
+
+	m := l.manager
+	expires := m.now().Add(m.leaseDuration)
+	ticker := time.NewTicker(m.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := m.now()
+		if !now.Before(expires) {
+			slog.Warn("lease expired before it could be renewed", "lock_id", l.lockID)
+			close(l.done)
+			return
+		}
+
+		next := now.Add(m.leaseDuration)
+		ok, err := m.store.Renew(context.Background(), l.lockID, m.owner, next)
+		if err != nil {
+			slog.Warn("failed to renew lease", "lock_id", l.lockID, "error", err)
+			continue
+		}
+		if !ok {
+			slog.Warn("lease was taken by another owner", "lock_id", l.lockID)
+			close(l.done)
+			return
+		}
+		expires = next
+	}
+}
@@ -0,0 +1,171 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory Store with the same conditional semantics as
+// the DynamoDB repository
+type memStore struct {
+	mu     sync.Mutex
+	leases map[string]memLease
+}
+
+type memLease struct {
+	owner   string
+	expires time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{leases: map[string]memLease{}}
+}
+
+func (s *memStore) TryAcquire(_ context.Context, lockID, owner string, now, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[lockID]; ok && l.owner != owner && !l.expires.Before(now) {
+		return false, nil
+	}
+	s.leases[lockID] = memLease{owner: owner, expires: expires}
+	return true, nil
+}
+
+func (s *memStore) Renew(_ context.Context, lockID, owner string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.leases[lockID]
+	if !ok || l.owner != owner {
+		return false, nil
+	}
+	s.leases[lockID] = memLease{owner: owner, expires: expires}
+	return true, nil
+}
+
+func (s *memStore) Release(_ context.Context, lockID, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[lockID]; ok && l.owner == owner {
+		delete(s.leases, lockID)
+	}
+	return nil
+}
+
+func (s *memStore) owner(lockID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leases[lockID].owner
+}
+
+func newManager(store Store, owner string) *Manager {
+	m := New(store, owner)
+	m.SetIntervals(30*time.Millisecond, 5*time.Millisecond)
+	return m
+}
+
+func TestAcquireExcludesOtherOwners(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	first, second := newManager(store, "task-a"), newManager(store, "task-b")
+
+	lease, err := first.Acquire(ctx, "conversation#1", 0)
+	if err != nil {
+		t.Fatalf("first Acquire() error = %v", err)
+	}
+	if _, err := second.Acquire(ctx, "conversation#1", 0); !errors.Is(err, ErrHeld) {
+		t.Fatalf("second Acquire() error = %v, want ErrHeld", err)
+	}
+	if _, err := second.Acquire(ctx, "conversation#2", 0); err != nil {
+		t.Fatalf("Acquire() of another conversation error = %v", err)
+	}
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if _, err := second.Acquire(ctx, "conversation#1", 0); err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	first, second := newManager(store, "task-a"), newManager(store, "task-b")
+
+	lease, err := first.Acquire(ctx, "conversation#1", 0)
+	if err != nil {
+		t.Fatalf("first Acquire() error = %v", err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		lease.Release(ctx)
+	}()
+
+	if _, err := second.Acquire(ctx, "conversation#1", time.Second); err != nil {
+		t.Fatalf("Acquire() error = %v, want lock once released", err)
+	}
+	if got := store.owner("conversation#1"); got != "task-b" {
+		t.Errorf("owner = %q, want task-b", got)
+	}
+}
+
+func TestLeaseRenewedWhileHeld(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	first, second := newManager(store, "task-a"), newManager(store, "task-b")
+
+	lease, err := first.Acquire(ctx, "conversation#1", 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer lease.Release(ctx)
+
+	// Several lease durations later the renewed lease still excludes others
+	time.Sleep(100 * time.Millisecond)
+	if _, err := second.Acquire(ctx, "conversation#1", 0); !errors.Is(err, ErrHeld) {
+		t.Fatalf("Acquire() error = %v, want ErrHeld", err)
+	}
+	select {
+	case <-lease.Done():
+		t.Fatal("lease lost while renewing")
+	default:
+	}
+}
+
+func TestExpiredLeaseTakenOver(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	first, second := newManager(store, "task-a"), newManager(store, "task-b")
+
+	lease, err := first.Acquire(ctx, "conversation#1", 0)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	runCtx, cancel := lease.Context(ctx)
+	defer cancel()
+
+	// The next agent looks after the lease has expired, as if the first
+	// task had stalled
+	second.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := second.Acquire(ctx, "conversation#1", 0); err != nil {
+		t.Fatalf("Acquire() of expired lease error = %v", err)
+	}
+
+	// The old owner notices on its next renewal and stops working
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease context not cancelled after takeover")
+	}
+
+	// Releasing a lost lease leaves the new owner's lock alone
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if got := store.owner("conversation#1"); got != "task-b" {
+		t.Errorf("owner = %q, want task-b", got)
+	}
+}
@@ -0,0 +1,193 @@
+// Package logging sets up JSON structured logs shared by every entrypoint.
+// Lines carry the conversation, channel, user, and request they were
+// logged for as fields, and metrics are written in CloudWatch embedded
+// metric format so CloudWatch Logs publishes them without an API call
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// Field names set on lines logged for a conversation, channel, user, or
+// request. RequestID is the Lambda request and EventID the Slack event
+// being handled
+const (
+	ConversationID = "conversation_id"
+	ChannelID      = "channel_id"
+	UserID         = "user_id"
+	RequestID      = "request_id"
+	EventID        = "event_id"
+	Service        = "service"
+)
+
+// Namespace is the CloudWatch namespace metrics are published to
+const Namespace = "CloudOpsBot"
+
+// Metric units
+const (
+	UnitCount        = "Count"
+	UnitMilliseconds = "Milliseconds"
+)
+
+type contextKey struct{}
+
+// Setup makes the default logger, which the log package also writes
+// through, emit JSON lines tagged with service at the level LOG_LEVEL names
+func Setup(service string) {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	slog.SetDefault(New(os.Stderr, level).With(Service, service))
+	log.SetFlags(0)
+	if err != nil {
+		slog.Warn("ignoring LOG_LEVEL", "error", err)
+	}
+}
+
+// New returns a logger writing JSON lines at level or above to w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// ParseLevel reads a level name: debug, info, warn, or error. Empty is info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q; use debug, info, warn, or error", name)
+}
+
+// With returns ctx carrying fields, as alternating keys and values, that
+// are added to every line logged with it. Fields replace ones ctx already
+// carries with the same key
+func With(ctx context.Context, args ...any) context.Context {
+	added := slog.Group("", args...).Value.Group()
+	var attrs []slog.Attr
+	for _, a := range fields(ctx) {
+		if !hasKey(added, a.Key) {
+			attrs = append(attrs, a)
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, append(attrs, added...))
+}
+
+// Fatal logs an error and exits
+func Fatal(ctx context.Context, msg string, args ...any) {
+	slog.ErrorContext(ctx, msg, args...)
+	os.Exit(1)
+}
+
+// Metric writes value as the named CloudWatch metric, dimensioned by
+// service. Metrics are written whatever the log level
+func Metric(ctx context.Context, name string, value float64, unit string) {
+	writeMetric(ctx, name, value, unit, []string{Service})
+}
+
+// MetricBy writes value as the named CloudWatch metric, dimensioned by
+// service and by key, such as the tool a metric is about, set to dimension
+func MetricBy(ctx context.Context, name string, value float64, unit, key, dimension string) {
+	writeMetric(ctx, name, value, unit, []string{Service, key}, slog.String(key, dimension))
+}
+
+func writeMetric(ctx context.Context, name string, value float64, unit string, dimensions []string, attrs ...slog.Attr) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "metric", 0)
+	r.AddAttrs(
+		slog.Any("_aws", emf{
+			Timestamp: r.Time.UnixMilli(),
+			CloudWatchMetrics: []emfDirective{{
+				Namespace:  Namespace,
+				Dimensions: [][]string{dimensions},
+				Metrics:    []emfMetric{{Name: name, Unit: unit}},
+			}},
+		}),
+		slog.Float64(name, value),
+	)
+	r.AddAttrs(attrs...)
+	_ = slog.Default().Handler().Handle(ctx, r)
+}
+
+// emf is the embedded metric format metadata CloudWatch Logs reads metrics from
+type emf struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// fields returns the fields ctx carries
+func fields(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs[:len(attrs):len(attrs)]
+}
+
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// contextHandler adds the fields a line's context carries, and the Lambda
+// request ID, to the line. Fields the line sets itself take precedence
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := fields(ctx)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String(RequestID, lc.AwsRequestID))
+	}
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	set := map[string]bool{}
+	r.Attrs(func(a slog.Attr) bool {
+		set[a.Key] = true
+		return true
+	})
+	for _, a := range attrs {
+		if !set[a.Key] {
+			set[a.Key] = true
+			r.AddAttrs(a)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("decode log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]slog.Level{"": slog.LevelInfo, "DEBUG": slog.LevelDebug, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel() should reject unknown levels")
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := With(context.Background(), ConversationID, "conv-1", ChannelID, "C1")
+	ctx = With(ctx, ChannelID, "C2", UserID, "U1")
+	ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+
+	logger.InfoContext(ctx, "handled", UserID, "U9")
+	logger.DebugContext(ctx, "below the level")
+
+	lines := decode(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1", len(lines))
+	}
+	line := lines[0]
+	for key, want := range map[string]string{"msg": "handled", "level": "INFO", ConversationID: "conv-1", ChannelID: "C2", UserID: "U9", RequestID: "req-1"} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %q in %v", key, line[key], want, line)
+		}
+	}
+}
+
+func TestMetric(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(New(&buf, slog.LevelError).With(Service, "agent"))
+
+	Metric(context.Background(), "TurnDuration", 1250, UnitMilliseconds)
+
+	lines := decode(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want the metric whatever the level", len(lines))
+	}
+	line := lines[0]
+	if line["TurnDuration"] != 1250.0 || line[Service] != "agent" {
+		t.Errorf("metric line = %v", line)
+	}
+
+	meta, _ := line["_aws"].(map[string]any)
+	directives, _ := meta["CloudWatchMetrics"].([]any)
+	if len(directives) != 1 || meta["Timestamp"] == nil {
+		t.Fatalf("_aws = %v", meta)
+	}
+	directive := directives[0].(map[string]any)
+	data, _ := json.Marshal(directive)
+	if want := `{"Dimensions":[["service"]],"Metrics":[{"Name":"TurnDuration","Unit":"Milliseconds"}],"Namespace":"CloudOpsBot"}`; string(data) != want {
+		t.Errorf("directive = %s, want %s", data, want)
+	}
+}
+
+func TestMetricBy(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(New(&buf, slog.LevelError).With(Service, "agent"))
+
+	MetricBy(context.Background(), "ToolCorrected", 2, UnitCount, "tool", "describe_ec2_instances")
+
+	lines := decode(t, &buf)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1", len(lines))
+	}
+	line := lines[0]
+	if line["ToolCorrected"] != 2.0 || line["tool"] != "describe_ec2_instances" {
+		t.Errorf("metric line = %v", line)
+	}
+	meta, _ := line["_aws"].(map[string]any)
+	directives, _ := meta["CloudWatchMetrics"].([]any)
+	data, _ := json.Marshal(directives[0].(map[string]any)["Dimensions"])
+	if want := `[["service","tool"]]`; string(data) != want {
+		t.Errorf("Dimensions = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,224 @@
+package logpattern
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+)
+
+// Wildcard replaces variable tokens in a pattern template
+const Wildcard = "<*>"
+
+const (
+	// DefaultSimilarity is the fraction of matching tokens required to join a cluster
+	DefaultSimilarity = 0.5
+
+	// DefaultMaxPatterns bounds the number of clusters tracked
+	DefaultMaxPatterns = 200
+)
+
+var (
+	variablePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]?[\d:.,]*Z?$`),                                            // dates and timestamps
+		regexp.MustCompile(`^[\d:.,]+$`),                                                                    // times and numbers
+		regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), // UUIDs
+		regexp.MustCompile(`^(0x)?[0-9a-fA-F]{8,}$`),                                                        // hex IDs
+		regexp.MustCompile(`^\d{1,3}(\.\d{1,3}){3}(:\d+)?$`),                                                // IPv4 addresses
+		regexp.MustCompile(`^[a-z]+-[0-9a-f]{8,17}$`),                                                       // AWS resource IDs
+		regexp.MustCompile(`^\d+(ms|s|m|h|b|kb|mb|gb|%)$`),                                                  // durations and sizes
+	}
+
+	errorPattern = regexp.MustCompile(`(?i)\b(error|err|exception|fatal|panic|fail(ed|ure)?|timeout|timed out|denied|refused|critical)\b`)
+)
+
+// Pattern is a cluster of similar log lines
+type Pattern struct {
+	Template string
+	Count    int
+	Example  string
+	IsError  bool
+}
+
+// Options tunes clustering
+type Options struct {
+	Similarity  float64 // 0-1, fraction of tokens that must match
+	MaxPatterns int     // lines that don't fit once this is reached are counted as "other"
+}
+
+type cluster struct {
+	tokens  []string
+	count   int
+	example string
+	isError bool
+}
+
+// Analyze clusters similar log lines into templates, drain-style: variable
+// tokens (numbers, IDs, timestamps) are masked, lines are grouped by token
+// count, and lines that mostly match an existing template are merged into
+// it with differing positions replaced by a wildcard. Patterns are returned
+// with error patterns first, then by descending count.
+func Analyze(lines []string, opts Options) []Pattern {
+	if opts.Similarity <= 0 {
+		opts.Similarity = DefaultSimilarity
+	}
+	if opts.MaxPatterns <= 0 {
+		opts.MaxPatterns = DefaultMaxPatterns
+	}
+
+	groups := make(map[int][]*cluster)
+	total := 0
+	other := 0
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		tokens := tokenize(line)
+		if c := bestMatch(groups[len(tokens)], tokens, opts.Similarity); c != nil {
+			merge(c, tokens)
+			c.count++
+			continue
+		}
+
+		if total >= opts.MaxPatterns {
+			other++
+			continue
+		}
+
+		groups[len(tokens)] = append(groups[len(tokens)], &cluster{
+			tokens:  tokens,
+			count:   1,
+			example: line,
+			isError: errorPattern.MatchString(line),
+		})
+		total++
+	}
+
+	var patterns []Pattern
+	for _, group := range groups {
+		for _, c := range group {
+			patterns = append(patterns, Pattern{
+				Template: strings.Join(c.tokens, " "),
+				Count:    c.count,
+				Example:  c.example,
+				IsError:  c.isError,
+			})
+		}
+	}
+	if other > 0 {
+		patterns = append(patterns, Pattern{Template: "(unclustered lines)", Count: other})
+	}
+
+	sort.SliceStable(patterns, func(i, j int) bool {
+		if patterns[i].IsError != patterns[j].IsError {
+			return patterns[i].IsError
+		}
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].Template < patterns[j].Template
+	})
+
+	return patterns
+}
+
+// Summarize renders the top patterns as a compact text summary suitable for
+// sending to the model in place of raw log lines
+func Summarize(patterns []Pattern, top int) string {
+	lines, errorCount := 0, 0
+	var errs, others []Pattern
+	for _, p := range patterns {
+		lines += p.Count
+		if p.IsError {
+			errorCount++
+			errs = append(errs, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s log lines in %d patterns (%d error patterns)\n", humanize.Count(int64(lines)), len(patterns), errorCount)
+
+	write := func(title string, ps []Pattern) {
+		if len(ps) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for i, p := range ps {
+			if i == top {
+				fmt.Fprintf(&b, "- ... %d more\n", len(ps)-top)
+				break
+			}
+			fmt.Fprintf(&b, "- %sx %s\n", humanize.Count(int64(p.Count)), p.Template)
+		}
+	}
+	write("Top error patterns", errs)
+	write("Other patterns", others)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// tokenize splits a line on whitespace and masks variable tokens
+func tokenize(line string) []string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if isVariable(strings.Trim(f, `"',;()[]{}`)) {
+			fields[i] = Wildcard
+		}
+	}
+	return fields
+}
+
+func isVariable(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, p := range variablePatterns {
+		if p.MatchString(strings.ToLower(token)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bestMatch returns the most similar cluster at or above the threshold
+func bestMatch(clusters []*cluster, tokens []string, threshold float64) *cluster {
+	var best *cluster
+	bestScore := -1.0
+	for _, c := range clusters {
+		if score := similarity(c.tokens, tokens); score >= threshold && score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// similarity is the fraction of positions where the tokens are equal,
+// counting template wildcards as matches
+func similarity(template, tokens []string) float64 {
+	if len(tokens) == 0 {
+		return 1
+	}
+	same := 0
+	for i := range tokens {
+		if template[i] == tokens[i] || template[i] == Wildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// merge generalizes the cluster template to cover tokens
+func merge(c *cluster, tokens []string) {
+	for i := range tokens {
+		if c.tokens[i] != tokens[i] {
+			c.tokens[i] = Wildcard
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logpattern
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, fmt.Sprintf("2024-05-01T10:00:0%dZ ERROR timeout connecting to 10.0.0.%d:5432 after %dms", i, i, 100+i))
+	}
+	for i := 0; i < 3; i++ {
+		lines = append(lines, fmt.Sprintf("INFO request handled user=%d path=/health", i))
+	}
+	lines = append(lines, "", "   ")
+
+	patterns := Analyze(lines, Options{})
+	if len(patterns) != 2 {
+		t.Fatalf("Analyze() = %+v, want 2 patterns", patterns)
+	}
+
+	first := patterns[0]
+	if !first.IsError || first.Count != 5 {
+		t.Errorf("first pattern = %+v, want error pattern with count 5", first)
+	}
+
+	want := "<*> ERROR timeout connecting to <*> after <*>"
+	if first.Template != want {
+		t.Errorf("Template = %q, want %q", first.Template, want)
+	}
+
+	second := patterns[1]
+	if second.IsError || second.Count != 3 {
+		t.Errorf("second pattern = %+v, want non-error pattern with count 3", second)
+	}
+	if !strings.Contains(second.Template, "INFO request handled") {
+		t.Errorf("Template = %q, want INFO request handled", second.Template)
+	}
+}
+
+func TestAnalyzeMaxPatterns(t *testing.T) {
+	lines := []string{"alpha one", "beta two three", "gamma four five six"}
+
+	patterns := Analyze(lines, Options{MaxPatterns: 1})
+
+	if len(patterns) != 2 {
+		t.Fatalf("Analyze() = %+v, want 1 pattern plus unclustered", patterns)
+	}
+
+	if patterns[0].Template != "(unclustered lines)" || patterns[0].Count != 2 {
+		t.Errorf("unclustered = %+v, want count 2", patterns[0])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	patterns := []Pattern{
+		{Template: "ERROR disk full on <*>", Count: 12, IsError: true},
+		{Template: "WARN failed retry <*>", Count: 4, IsError: true},
+		{Template: "INFO ok", Count: 100},
+	}
+
+	got := Summarize(patterns, 1)
+
+	for _, want := range []string{
+		"116 log lines in 3 patterns (2 error patterns)",
+		"- 12x ERROR disk full on <*>",
+		"- ... 1 more",
+		"- 100x INFO ok",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Summarize() = %q, missing %q", got, want)
+		}
+	}
+}
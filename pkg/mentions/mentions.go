@@ -0,0 +1,187 @@
+package mentions
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/slack-go/slack"
+)
+
+// mentionPattern matches user mentions, with or without the label Slack
+// adds in some payloads
+var mentionPattern = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+
+// Directory looks up Slack users
+type Directory interface {
+	GetUserInfo(ctx context.Context, userID string) (*slack.User, error)
+}
+
+// Resolver translates between Slack user mentions and the names people go
+// by, so the model reads "@Jane" rather than "<@U012AB3CD>" and its replies
+// can still notify the people it names. Names are looked up once and cached
+// for the resolver's lifetime. It is safe for concurrent use
+type Resolver struct {
+	users Directory
+	self  string // the bot's own user ID
+
+	mu    sync.Mutex
+	names map[string]string // user ID to name
+	ids   map[string]string // lowercased name to user ID, "" when ambiguous
+}
+
+// NewResolver creates a resolver that looks names up in users
+func NewResolver(users Directory) *Resolver {
+	return &Resolver{
+		users: users,
+		names: make(map[string]string),
+		ids:   make(map[string]string),
+	}
+}
+
+// SetSelf sets the bot's user ID. Mentions of the bot only address it, so
+// Resolve drops them
+func (r *Resolver) SetSelf(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.self = userID
+}
+
+// Name returns the name a user goes by, or "" when the user can't be
+// looked up
+func (r *Resolver) Name(ctx context.Context, userID string) string {
+	r.mu.Lock()
+	name, ok := r.names[userID]
+	r.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	user, err := r.users.GetUserInfo(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to look up user", logging.UserID, userID, "error", err)
+		return ""
+	}
+	name = displayName(user)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[userID] = name
+	if name != "" {
+		key := strings.ToLower(name)
+		if existing, ok := r.ids[key]; ok && existing != userID {
+			r.ids[key] = "" // two people share the name; mention neither
+		} else {
+			r.ids[key] = userID
+		}
+	}
+	return name
+}
+
+// Resolve replaces user mentions in text with @Name. Mentions of the bot
+// are removed, and users that can't be looked up keep their mention
+func (r *Resolver) Resolve(ctx context.Context, text string) string {
+	r.mu.Lock()
+	self := r.self
+	r.mu.Unlock()
+
+	return mentionPattern.ReplaceAllStringFunc(text, func(mention string) string {
+		userID := mentionPattern.FindStringSubmatch(mention)[1]
+		if userID == self {
+			return ""
+		}
+		if name := r.Name(ctx, userID); name != "" {
+			return "@" + name
+		}
+		return mention
+	})
+}
+
+// Mention turns @Name back into a user mention for each name the resolver
+// has seen. Unknown and ambiguous names, and anything inside code, are left
+// as written
+func (r *Resolver) Mention(text string) string {
+	pattern := r.namePattern()
+	if pattern == nil {
+		return text
+	}
+
+	segments := strings.Split(text, "`")
+	for i := 0; i < len(segments); i += 2 {
+		segments[i] = r.mentionProse(segments[i], pattern)
+	}
+	return strings.Join(segments, "`")
+}
+
+// mentionProse replaces names matched by pattern in text outside code
+func (r *Resolver) mentionProse(text string, pattern *regexp.Regexp) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		// A longer word that merely starts with a known name isn't a mention
+		if next, _ := utf8.DecodeRuneInString(text[loc[1]:]); isWordRune(next) {
+			continue
+		}
+		// Nor is an email address
+		if prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]]); isWordRune(prev) {
+			continue
+		}
+		userID := r.ids[strings.ToLower(text[loc[0]+1:loc[1]])]
+		if userID == "" {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString("<@" + userID + ">")
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// namePattern matches @ followed by any unambiguous known name, preferring
+// the longest so "@Jane Doe" isn't taken as "@Jane"
+func (r *Resolver) namePattern() *regexp.Regexp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var names []string
+	for key, userID := range r.ids {
+		if userID != "" {
+			names = append(names, regexp.QuoteMeta(key))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return regexp.MustCompile(`(?i)@(?:` + strings.Join(names, "|") + `)`)
+}
+
+// displayName picks the name Slack shows for a user
+func displayName(user *slack.User) string {
+	for _, name := range []string{user.Profile.DisplayName, user.Profile.RealName, user.RealName, user.Name} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// isWordRune reports whether r can be part of a name or word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
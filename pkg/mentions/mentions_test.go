@@ -0,0 +1,112 @@
+package mentions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// directory is an in-memory user directory that counts lookups
+type directory struct {
+	users   map[string]*slack.User
+	lookups int
+}
+
+func (d *directory) GetUserInfo(ctx context.Context, userID string) (*slack.User, error) {
+	d.lookups++
+	user, ok := d.users[userID]
+	if !ok {
+		return nil, errors.New("user_not_found")
+	}
+	return user, nil
+}
+
+func newDirectory() *directory {
+	return &directory{users: map[string]*slack.User{
+		"U1":   {ID: "U1", Name: "jane", Profile: slack.UserProfile{DisplayName: "Jane", RealName: "Jane Smith"}},
+		"U2":   {ID: "U2", Name: "bob", Profile: slack.UserProfile{RealName: "Bob Lee"}},
+		"U3":   {ID: "U3", Name: "jdoe", Profile: slack.UserProfile{DisplayName: "Jane Doe"}},
+		"UBOT": {ID: "UBOT", Name: "cloudops", IsBot: true},
+	}}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	r := NewResolver(newDirectory())
+	r.SetSelf("UBOT")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"<@UBOT> is the API down?", " is the API down?"},
+		{"ask <@U1> and <@U2|bob>", "ask @Jane and @Bob Lee"},
+		{"<@U9> owns it", "<@U9> owns it"},
+		{"no mentions here", "no mentions here"},
+	}
+	for _, tt := range tests {
+		if got := r.Resolve(ctx, tt.text); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNameCached(t *testing.T) {
+	ctx := context.Background()
+	d := newDirectory()
+	r := NewResolver(d)
+
+	r.Resolve(ctx, "<@U1> <@U1>")
+	r.Name(ctx, "U1")
+	if d.lookups != 1 {
+		t.Errorf("lookups = %d, want 1", d.lookups)
+	}
+}
+
+func TestMention(t *testing.T) {
+	ctx := context.Background()
+	r := NewResolver(newDirectory())
+	for _, id := range []string{"U1", "U2", "U3"} {
+		r.Name(ctx, id)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"@Jane can you check?", "<@U1> can you check?"},
+		{"@jane doe restarted it", "<@U3> restarted it"},
+		{"thanks @Bob Lee.", "thanks <@U2>."},
+		{"@Janet isn't known", "@Janet isn't known"},
+		{"mail jane@Jane.io", "mail jane@Jane.io"},
+		{"run `echo @Jane` first", "run `echo @Jane` first"},
+		{"@Alice is unknown", "@Alice is unknown"},
+	}
+	for _, tt := range tests {
+		if got := r.Mention(tt.text); got != tt.want {
+			t.Errorf("Mention(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMentionAmbiguousName(t *testing.T) {
+	ctx := context.Background()
+	d := newDirectory()
+	d.users["U4"] = &slack.User{ID: "U4", Profile: slack.UserProfile{DisplayName: "jane"}}
+	r := NewResolver(d)
+	r.Name(ctx, "U1")
+	r.Name(ctx, "U4")
+
+	if got := r.Mention("@Jane"); got != "@Jane" {
+		t.Errorf("Mention() = %q, want the name left as written", got)
+	}
+}
+
+func TestMentionWithoutNames(t *testing.T) {
+	r := NewResolver(newDirectory())
+	if got := r.Mention("@Jane"); got != "@Jane" {
+		t.Errorf("Mention() = %q, want %q", got, "@Jane")
+	}
+}
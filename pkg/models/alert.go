@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// Alert status constants
+const (
+	AlertOpen      = "open"
+	AlertAcked     = "acked"
+	AlertEscalated = "escalated"
+)
+
+// Alert is a critical alert the bot posted proactively, tracked until an
+// on-call responder acknowledges it
+type Alert struct {
+	ChannelID   string     `dynamodbav:"channel_id"`
+	MessageTS   string     `dynamodbav:"message_ts"`
+	AlertID     string     `dynamodbav:"alert_id"`
+	Title       string     `dynamodbav:"title"`
+	Team        string     `dynamodbav:"team,omitempty"`
+	Responders  []string   `dynamodbav:"responders,omitempty"` // on-call Slack user IDs
+	Status      string     `dynamodbav:"status"`
+	CreatedAt   time.Time  `dynamodbav:"created_at"`
+	AckDeadline time.Time  `dynamodbav:"ack_deadline"`
+	AckedBy     []string   `dynamodbav:"acked_by,omitempty"`
+	AckedAt     *time.Time `dynamodbav:"acked_at,omitempty"`
+	EscalatedAt *time.Time `dynamodbav:"escalated_at,omitempty"`
+	TTL         int64      `dynamodbav:"ttl"`
+}
+
+// NewAlert creates an open alert that must be acknowledged within ackWindow
+func NewAlert(channelID, messageTS, title, team string, responders []string, ackWindow time.Duration) *Alert {
+	now := time.Now()
+	return &Alert{
+		ChannelID:   channelID,
+		MessageTS:   messageTS,
+		AlertID:     "alert-" + generateULID(),
+		Title:       title,
+		Team:        team,
+		Responders:  responders,
+		Status:      AlertOpen,
+		CreatedAt:   now,
+		AckDeadline: now.Add(ackWindow),
+		TTL:         now.AddDate(0, 0, 30).Unix(),
+	}
+}
+
+// AlertDigest folds repeats of a firing alarm into the message first posted
+// for it. Digests live in the alerts table under a sort key that can't be a
+// message timestamp, and have no status so they aren't listed as open
+type AlertDigest struct {
+	ChannelID   string    `dynamodbav:"channel_id"`
+	Key         string    `dynamodbav:"message_ts"`
+	MessageTS   string    `dynamodbav:"digest_ts"` // the message being updated
+	Title       string    `dynamodbav:"title"`
+	Responders  []string  `dynamodbav:"responders,omitempty"`
+	Occurrences int       `dynamodbav:"occurrences"`
+	FirstSeen   time.Time `dynamodbav:"first_seen,unixtime"`
+	LastSeen    time.Time `dynamodbav:"last_seen,unixtime"`
+	TTL         int64     `dynamodbav:"ttl"`
+}
+
+// DigestKey is the sort key of the digest for an alarm
+func DigestKey(alarmKey string) string {
+	return "digest#" + alarmKey
+}
+
+// NewAlertDigest creates the digest for an alarm first posted as messageTS
+func NewAlertDigest(channelID, alarmKey, messageTS, title string, responders []string, now time.Time) *AlertDigest {
+	return &AlertDigest{
+		ChannelID:   channelID,
+		Key:         DigestKey(alarmKey),
+		MessageTS:   messageTS,
+		Title:       title,
+		Responders:  responders,
+		Occurrences: 1,
+		FirstSeen:   now,
+		LastSeen:    now,
+		TTL:         now.AddDate(0, 0, 30).Unix(),
+	}
+}
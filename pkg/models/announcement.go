@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AnnouncementPost is one channel's copy of a broadcast announcement, keyed
+// by channel and message timestamp so reactions can be matched back to it
+type AnnouncementPost struct {
+	ChannelID      string    `dynamodbav:"channel_id"`
+	MessageTS      string    `dynamodbav:"message_ts"`
+	AnnouncementID string    `dynamodbav:"announcement_id"`
+	Kind           string    `dynamodbav:"kind"`
+	PostedBy       string    `dynamodbav:"posted_by"`
+	AckedBy        []string  `dynamodbav:"acked_by,stringset,omitempty"`
+	CreatedAt      time.Time `dynamodbav:"created_at"`
+	TTL            int64     `dynamodbav:"ttl"`
+}
+
+// NewAnnouncementID generates an identifier shared by all posts of a broadcast
+func NewAnnouncementID() string {
+	return "ann-" + generateULID()
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Approval request statuses
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalDenied   = "denied"
+	ApprovalExpired  = "expired"
+)
+
+// ApprovalRequest asks for sign-off before a privileged action runs. The
+// policy in force when it was opened is copied onto the request so a
+// config change doesn't move the goalposts for pending approvals
+type ApprovalRequest struct {
+	ApprovalID     string         `dynamodbav:"approval_id"`
+	Class          string         `dynamodbav:"class"` // action class, e.g. "prod-terminate"
+	Description    string         `dynamodbav:"description"`
+	RequestedBy    string         `dynamodbav:"requested_by"`
+	ConversationID string         `dynamodbav:"conversation_id,omitempty"`
+	ChannelID      string         `dynamodbav:"channel_id,omitempty"`
+	MessageTS      string         `dynamodbav:"message_ts,omitempty"`
+	Group          string         `dynamodbav:"group"` // minimum permission profile of approvers
+	Quorum         int            `dynamodbav:"quorum"`
+	AllowSelf      bool           `dynamodbav:"allow_self"`
+	Votes          []ApprovalVote `dynamodbav:"votes"`
+	Status         string         `dynamodbav:"status"`
+	CreatedAt      time.Time      `dynamodbav:"created_at"`
+	ExpiresAt      time.Time      `dynamodbav:"expires_at"`
+	DecidedAt      *time.Time     `dynamodbav:"decided_at,omitempty"`
+	TTL            int64          `dynamodbav:"ttl"`
+}
+
+// ApprovalVote is one approver's decision
+type ApprovalVote struct {
+	UserID  string    `dynamodbav:"user_id"`
+	Approve bool      `dynamodbav:"approve"`
+	At      time.Time `dynamodbav:"at"`
+}
+
+// NewApprovalID generates an identifier for an approval request
+func NewApprovalID() string {
+	return "apr-" + generateULID()
+}
+
+// Approvals counts the votes in favor
+func (r *ApprovalRequest) Approvals() int {
+	n := 0
+	for _, v := range r.Votes {
+		if v.Approve {
+			n++
+		}
+	}
+	return n
+}
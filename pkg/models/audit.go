@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	AuditAnnounce      = "announce"
+	AuditPromptPublish = "prompt_publish"
+	AuditRunbookReview = "runbook_review"
+	AuditGrant         = "grant"
+	AuditRevoke        = "revoke"
+	AuditApprovalVote  = "approval_vote"
+	AuditBreakGlass    = "break_glass"
+	AuditBreakGlassEnd = "break_glass_end"
+	AuditEvidence      = "evidence_export"
+	AuditKillSwitch    = "kill_switch"
+)
+
+// AuditEvent records a privileged action taken through the bot
+type AuditEvent struct {
+	EventID   string            `dynamodbav:"event_id"`
+	Action    string            `dynamodbav:"action"`
+	ActorID   string            `dynamodbav:"actor_id"`
+	Target    string            `dynamodbav:"target,omitempty"`
+	Details   map[string]string `dynamodbav:"details,omitempty"`
+	CreatedAt time.Time         `dynamodbav:"created_at"`
+	TTL       int64             `dynamodbav:"ttl"`
+}
+
+// NewAuditEvent creates an audit event that is kept for a year
+func NewAuditEvent(action, actorID, target string) *AuditEvent {
+	now := time.Now()
+	return &AuditEvent{
+		EventID:   "audit-" + generateULID(),
+		Action:    action,
+		ActorID:   actorID,
+		Target:    target,
+		Details:   map[string]string{},
+		CreatedAt: now,
+		TTL:       now.AddDate(1, 0, 0).Unix(),
+	}
+}
+
+// Tool execution outcomes
+const (
+	ToolSucceeded = "succeeded"
+	ToolFailed    = "failed"
+	ToolRefused   = "refused" // the caller's permission set doesn't allow the tool
+)
+
+// ToolExecution records one tool call the model made on someone's behalf
+type ToolExecution struct {
+	ExecutionID    string    `dynamodbav:"execution_id" json:"execution_id"`
+	UserID         string    `dynamodbav:"user_id" json:"user_id"`                 // sender of the turn's first message
+	Users          []string  `dynamodbav:"users,omitempty" json:"users,omitempty"` // every sender, when the turn combined several
+	ConversationID string    `dynamodbav:"conversation_id" json:"conversation_id"`
+	Tool           string    `dynamodbav:"tool" json:"tool"`
+	Input          string    `dynamodbav:"input" json:"input"`                       // redacted
+	Output         string    `dynamodbav:"output,omitempty" json:"output,omitempty"` // redacted, and truncated when long
+	Outcome        string    `dynamodbav:"outcome" json:"outcome"`
+	Error          string    `dynamodbav:"error,omitempty" json:"error,omitempty"`
+	DurationMillis int64     `dynamodbav:"duration_ms" json:"duration_ms"`
+	StartedAt      time.Time `dynamodbav:"started_at" json:"started_at"`
+	TTL            int64     `dynamodbav:"ttl" json:"-"`
+}
+
+// NewToolExecution creates a tool execution record that is kept for a year
+func NewToolExecution(conversationID, userID, tool string, startedAt time.Time) *ToolExecution {
+	startedAt = startedAt.UTC()
+	return &ToolExecution{
+		ExecutionID:    "tool-" + generateULID(),
+		UserID:         userID,
+		ConversationID: conversationID,
+		Tool:           tool,
+		StartedAt:      startedAt,
+		TTL:            startedAt.AddDate(1, 0, 0).Unix(),
+	}
+}
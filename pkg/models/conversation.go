@@ -9,33 +9,120 @@ import (
 
 // Conversation represents a user's troubleshooting session with the CloudOps bot
 type Conversation struct {
-	ConversationID string     `dynamodbav:"conversation_id"`
-	ChannelID      string     `dynamodbav:"channel_id"`
-	UserID         string     `dynamodbav:"user_id"`
-	Status         string     `dynamodbav:"status"` // pending, active, completed, failed, timeout
-	InitialCommand string     `dynamodbav:"initial_command"`
-	CreatedAt      time.Time  `dynamodbav:"created_at"`
-	LastHeartbeat  time.Time  `dynamodbav:"last_heartbeat"`
-	CompletedAt    *time.Time `dynamodbav:"completed_at,omitempty"`
-	TaskArn        string     `dynamodbav:"task_arn,omitempty"`
-	ExecutionArn   string     `dynamodbav:"execution_arn"`
-	Error          string     `dynamodbav:"error,omitempty"`
-	TTL            int64      `dynamodbav:"ttl"` // Unix timestamp (7 days)
+	ConversationID string      `dynamodbav:"conversation_id"`
+	ChannelID      string      `dynamodbav:"channel_id"`
+	UserID         string      `dynamodbav:"user_id"`
+	Status         string      `dynamodbav:"status"` // pending, active, completed, failed, timeout
+	InitialCommand string      `dynamodbav:"initial_command"`
+	MessageTS      string      `dynamodbav:"message_ts,omitempty"`        // the mention that started it, which shows its status as a reaction
+	ThreadTS       string      `dynamodbav:"thread_ts,omitempty"`         // the thread it is confined to, empty when it has the channel to itself
+	ThreadKey      string      `dynamodbav:"thread_key,omitempty"`        // ChannelID and ThreadTS together, for finding it by thread
+	OriginChannel  string      `dynamodbav:"origin_channel_id,omitempty"` // where it started, once transferred to another channel
+	Type           string      `dynamodbav:"conversation_type,omitempty"` // question or incident, sizes the agent task
+	Visibility     string      `dynamodbav:"visibility,omitempty"`        // public, private, or dm; public answers hold back sensitive content
+	Debug          bool        `dynamodbav:"debug,omitempty"`             // answers show the tool calls and tokens behind them
+	HumanAssisted  bool        `dynamodbav:"human_assisted,omitempty"`    // escalated to experts; the agent only answers when mentioned
+	EscalatedBy    string      `dynamodbav:"escalated_by,omitempty"`
+	EscalatedAt    *time.Time  `dynamodbav:"escalated_at,omitempty"`
+	CreatedAt      time.Time   `dynamodbav:"created_at"`
+	LastHeartbeat  time.Time   `dynamodbav:"last_heartbeat"`
+	CompletedAt    *time.Time  `dynamodbav:"completed_at,omitempty"`
+	TaskArn        string      `dynamodbav:"task_arn,omitempty"`
+	ExecutionArn   string      `dynamodbav:"execution_arn"`
+	Error          string      `dynamodbav:"error,omitempty"`
+	Scratchpad     *Scratchpad `dynamodbav:"scratchpad,omitempty"`
+	Summary        string      `dynamodbav:"summary,omitempty"`    // rolling summary of the history too old to send the model
+	Summarized     int         `dynamodbav:"summarized,omitempty"` // how many history messages the summary covers
+	Entities       []Entity    `dynamodbav:"entities,omitempty"`
+	Participants   []string    `dynamodbav:"participants,omitempty"`
+	Tags           []string    `dynamodbav:"tags,omitempty"`
+	Related        []string    `dynamodbav:"related,stringset,omitempty"` // conversations linked as possibly the same incident
+	MergedInto     string      `dynamodbav:"merged_into,omitempty"`       // conversation this one was merged into, which carries on
+	SLA            *SLA        `dynamodbav:"sla,omitempty"`
+	PromptVersion  int         `dynamodbav:"prompt_version"`        // 0 is the built-in prompt
+	CostCenter     string      `dynamodbav:"cost_center,omitempty"` // team charged for the conversation's usage
+	ResumeTS       string      `dynamodbav:"resume_ts,omitempty"`   // last Slack message handled before the task was interrupted
+	Interruptions  int         `dynamodbav:"interruptions,omitempty"`
+	InputTokens    int64       `dynamodbav:"input_tokens,omitempty"` // metered model usage, when chargeback is on
+	OutputTokens   int64       `dynamodbav:"output_tokens,omitempty"`
+	Embedding      []float32   `dynamodbav:"embedding,omitempty"` // of the initial command, for duplicate detection
+	TTL            int64       `dynamodbav:"ttl"`                 // Unix timestamp (7 days)
+	Version        int64       `dynamodbav:"version"`             // bumped by every write, so a stale Save fails instead of clobbering
 }
 
 // Message represents a single message in the conversation history
 type Message struct {
-	Role    string // "user" or "assistant"
-	Content string
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
 }
 
-// StepFunctionInput is the input payload sent to Step Functions when starting a conversation
-type StepFunctionInput struct {
-	ConversationID string `json:"conversationId"`
-	ChannelID      string `json:"channelId"`
-	UserID         string `json:"userId"`
-	InitialCommand string `json:"initialCommand"`
-	CreatedAt      string `json:"createdAt"`
+// Entity is an AWS resource referenced in a conversation
+type Entity struct {
+	Type   string `dynamodbav:"type" json:"type"` // arn, instance, log_group
+	ID     string `dynamodbav:"id" json:"id"`
+	Region string `dynamodbav:"region,omitempty" json:"region,omitempty"`
+}
+
+// Entity type constants
+const (
+	EntityARN      = "arn"
+	EntityInstance = "instance"
+	EntityLogGroup = "log_group"
+)
+
+// AddEntities records entities not already on the conversation and reports
+// whether any were added
+func (c *Conversation) AddEntities(entities ...Entity) bool {
+	added := false
+	for _, e := range entities {
+		known := false
+		for _, existing := range c.Entities {
+			if existing.Type == e.Type && existing.ID == e.ID {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.Entities = append(c.Entities, e)
+			added = true
+		}
+	}
+	return added
+}
+
+// AddParticipant records a user who took part in the conversation and
+// reports whether they are new
+func (c *Conversation) AddParticipant(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for _, p := range c.Participants {
+		if p == userID {
+			return false
+		}
+	}
+	c.Participants = append(c.Participants, userID)
+	return true
+}
+
+// TookPart reports whether userID started the conversation or took part in it
+func (c *Conversation) TookPart(userID string) bool {
+	if userID == c.UserID {
+		return true
+	}
+	for _, p := range c.Participants {
+		if p == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleTo reports whether a command userID runs in channelID may refer to
+// the conversation. Those who took part can reach it from anywhere, everyone
+// else only from the channel it is held in
+func (c *Conversation) VisibleTo(userID, channelID string) bool {
+	return c.TookPart(userID) || channelID == c.ChannelID
 }
 
 // ConversationStatus constants
@@ -47,6 +134,13 @@ const (
 	StatusTimeout   = "timeout"
 )
 
+// Capacity an agent task runs on. Spot tasks are cheaper but can be
+// reclaimed with two minutes' notice
+const (
+	CapacityOnDemand = "ondemand"
+	CapacitySpot     = "spot"
+)
+
 // MessageRole constants
 const (
 	RoleUser      = "user"
@@ -79,6 +173,61 @@ func (c *Conversation) UpdateStatus(status string) {
 	}
 }
 
+// Ended reports whether the conversation reached a final status
+func (c *Conversation) Ended() bool {
+	switch c.Status {
+	case StatusCompleted, StatusFailed, StatusTimeout:
+		return true
+	}
+	return false
+}
+
+// Stale reports whether a conversation that hasn't ended has gone longer
+// than timeout without a heartbeat, meaning its agent died without ending it
+func (c *Conversation) Stale(now time.Time, timeout time.Duration) bool {
+	return !c.Ended() && now.Sub(c.LastHeartbeat) > timeout
+}
+
+// MessageChannelID returns the channel of the mention that started the
+// conversation, which differs from ChannelID after a transfer
+func (c *Conversation) MessageChannelID() string {
+	if c.OriginChannel != "" {
+		return c.OriginChannel
+	}
+	return c.ChannelID
+}
+
+// MoveTo records that the conversation continues in channelID, where it
+// has the channel to itself
+func (c *Conversation) MoveTo(channelID string) {
+	if c.OriginChannel == "" {
+		c.OriginChannel = c.ChannelID
+	}
+	c.ChannelID = channelID
+	c.SetThread("")
+}
+
+// SetThread confines the conversation to the thread rooted at threadTS, so
+// several conversations can share a busy channel. An empty threadTS gives
+// it the whole channel
+func (c *Conversation) SetThread(threadTS string) {
+	c.ThreadTS = threadTS
+	c.ThreadKey = ""
+	if threadTS != "" {
+		c.ThreadKey = ThreadKey(c.ChannelID, threadTS)
+	}
+}
+
+// Threaded reports whether the conversation is confined to a thread
+func (c *Conversation) Threaded() bool {
+	return c.ThreadTS != ""
+}
+
+// ThreadKey identifies a thread across channels
+func ThreadKey(channelID, threadTS string) string {
+	return channelID + "#" + threadTS
+}
+
 // UpdateHeartbeat records the last activity timestamp
 func (c *Conversation) UpdateHeartbeat() {
 	c.LastHeartbeat = time.Now()
@@ -123,6 +123,44 @@ func TestConversationUpdateHeartbeat(t *testing.T) {
 	}
 }
 
+func TestConversationEnded(t *testing.T) {
+	conv := NewConversation("C123", "U456", "test")
+
+	for status, want := range map[string]bool{
+		StatusPending:   false,
+		StatusActive:    false,
+		StatusCompleted: true,
+		StatusFailed:    true,
+		StatusTimeout:   true,
+	} {
+		conv.Status = status
+		if got := conv.Ended(); got != want {
+			t.Errorf("Ended() with status %s = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestConversationStale(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	conv := NewConversation("C123", "U456", "test")
+	conv.Status = StatusActive
+
+	conv.LastHeartbeat = now.Add(-time.Minute)
+	if conv.Stale(now, 5*time.Minute) {
+		t.Error("Stale() = true for a recent heartbeat")
+	}
+
+	conv.LastHeartbeat = now.Add(-6 * time.Minute)
+	if !conv.Stale(now, 5*time.Minute) {
+		t.Error("Stale() = false for a heartbeat older than the timeout")
+	}
+
+	conv.Status = StatusCompleted
+	if conv.Stale(now, 5*time.Minute) {
+		t.Error("Stale() = true for an ended conversation")
+	}
+}
+
 func TestConversationStatusConstants(t *testing.T) {
 	tests := []struct {
 		status string
@@ -266,3 +304,129 @@ func TestStepFunctionInput(t *testing.T) {
 		t.Errorf("UserID = %s, want U456", sfInput.UserID)
 	}
 }
+
+func TestConversationAddEntities(t *testing.T) {
+	conv := NewConversation("C123", "U456", "test")
+	instance := Entity{Type: EntityInstance, ID: "i-0123456789abcdef0", Region: "us-east-1"}
+
+	if !conv.AddEntities(instance) {
+		t.Error("AddEntities() should report a new entity")
+	}
+
+	if conv.AddEntities(instance) {
+		t.Error("AddEntities() should ignore duplicates")
+	}
+
+	if len(conv.Entities) != 1 {
+		t.Errorf("len(Entities) = %d, want 1", len(conv.Entities))
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   string
+		wantOK bool
+	}{
+		{"#sev2", "sev2", true},
+		{"Payments", "payments", true},
+		{" #cost-savings ", "cost-savings", true},
+		{"<#C123|payments>", "payments", true},
+		{"#", "", false},
+		{"two words", "", false},
+		{"-leading", "", false},
+		{strings.Repeat("a", MaxTagLength+1), "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeTag(tt.tag)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NormalizeTag(%q) = %q, %v; want %q, %v", tt.tag, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestConversationTags(t *testing.T) {
+	conv := NewConversation("C123", "U456", "test")
+
+	added := conv.AddTags("sev2", "payments", "sev2")
+	if len(added) != 2 || len(conv.Tags) != 2 {
+		t.Errorf("AddTags() added %v, tags %v; want 2 unique tags", added, conv.Tags)
+	}
+
+	if added := conv.AddTags("payments"); len(added) != 0 {
+		t.Errorf("AddTags() re-added %v", added)
+	}
+
+	if !conv.RemoveTag("sev2") || conv.HasTag("sev2") {
+		t.Error("RemoveTag() should remove sev2")
+	}
+
+	if conv.RemoveTag("sev2") {
+		t.Error("RemoveTag() should report false for a missing tag")
+	}
+}
+
+func TestConversationMoveTo(t *testing.T) {
+	conv := NewConversation("C1", "U456", "test")
+	if got := conv.MessageChannelID(); got != "C1" {
+		t.Errorf("MessageChannelID() = %s, want C1", got)
+	}
+
+	conv.MoveTo("C2")
+	conv.MoveTo("C3")
+	if conv.ChannelID != "C3" || conv.MessageChannelID() != "C1" {
+		t.Errorf("after two moves ChannelID = %s, MessageChannelID() = %s; want C3, C1", conv.ChannelID, conv.MessageChannelID())
+	}
+}
+
+func TestConversationSetThread(t *testing.T) {
+	conv := NewConversation("C1", "U456", "test")
+	if conv.Threaded() || conv.ThreadKey != "" {
+		t.Error("new conversation should have the channel to itself")
+	}
+
+	conv.SetThread("1700000000.000100")
+	if !conv.Threaded() || conv.ThreadKey != ThreadKey("C1", "1700000000.000100") {
+		t.Errorf("after SetThread() ThreadKey = %q", conv.ThreadKey)
+	}
+
+	conv.MoveTo("C2")
+	if conv.Threaded() || conv.ThreadKey != "" {
+		t.Error("MoveTo() should give the conversation the new channel to itself")
+	}
+}
+
+func TestConversationTookPart(t *testing.T) {
+	conv := NewConversation("C1", "U1", "test")
+	conv.AddParticipant("U2")
+
+	for userID, want := range map[string]bool{"U1": true, "U2": true, "U3": false} {
+		if got := conv.TookPart(userID); got != want {
+			t.Errorf("TookPart(%s) = %v, want %v", userID, got, want)
+		}
+	}
+}
+
+func TestConversationVisibleTo(t *testing.T) {
+	conv := NewConversation("C1", "U1", "test")
+	conv.AddParticipant("U2")
+	conv.MoveTo("C2")
+
+	tests := []struct {
+		userID    string
+		channelID string
+		want      bool
+	}{
+		{userID: "U1", channelID: "C9", want: true},
+		{userID: "U2", channelID: "C9", want: true},
+		{userID: "U3", channelID: "C2", want: true},
+		{userID: "U3", channelID: "C1"},
+		{userID: "U3", channelID: "C9"},
+	}
+	for _, tt := range tests {
+		if got := conv.VisibleTo(tt.userID, tt.channelID); got != tt.want {
+			t.Errorf("VisibleTo(%s, %s) = %v, want %v", tt.userID, tt.channelID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package models
+
+// DenialIDPrefix keys access denied counters in the settings table
+const DenialIDPrefix = "denial#"
+
+// DenialID keys the counter of access denied errors for an action on a
+// resource ("*" when the error didn't name one)
+func DenialID(action, resource string) string {
+	if resource == "" {
+		resource = "*"
+	}
+	return DenialIDPrefix + action + "#" + resource
+}
@@ -0,0 +1,10 @@
+package models
+
+// Inbox records the newest message Slack's Events API delivered for a
+// conversation, so its agent knows when to read the channel instead of
+// polling Slack for every message
+type Inbox struct {
+	ConversationID string `dynamodbav:"conversation_id"`
+	LatestTS       string `dynamodbav:"latest_ts"` // Slack timestamp of the newest message
+	TTL            int64  `dynamodbav:"ttl"`
+}
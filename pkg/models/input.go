@@ -0,0 +1,124 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StepFunctionInputVersion is the StepFunctionInput version this code
+// writes. Version 0 is the input sent before it had a version, which
+// carried the same fields
+const StepFunctionInputVersion = 1
+
+// StepFunctionInput is the input payload sent to Step Functions when starting a conversation
+type StepFunctionInput struct {
+	Version        int           `json:"version"`
+	ConversationID string        `json:"conversationId"`
+	ChannelID      string        `json:"channelId"`
+	UserID         string        `json:"userId"`
+	Capacity       string        `json:"capacity,omitempty"`    // ondemand or spot; empty is ondemand
+	Task           *TaskOverride `json:"task,omitempty"`        // absent runs the state machine's default task size
+	Shadow         bool          `json:"shadow,omitempty"`      // runs a candidate agent that only logs
+	ContextPack    string        `json:"contextPack,omitempty"` // what the agent would read at startup, encoded by contextpack
+	InitialCommand string        `json:"initialCommand,omitempty"`
+	CreatedAt      string        `json:"createdAt,omitempty"`
+	Sealed         string        `json:"sealed,omitempty"` // the sensitive fields, encrypted by Seal
+}
+
+// sensitive are the fields Seal encrypts
+type sensitive struct {
+	ContextPack    string `json:"contextPack,omitempty"`
+	InitialCommand string `json:"initialCommand,omitempty"`
+}
+
+// TaskOverride is the agent task a conversation runs. ECS takes CPU and
+// memory as strings
+type TaskOverride struct {
+	Definition string `json:"definition"`
+	CPU        string `json:"cpu"`
+	Memory     string `json:"memory"`
+}
+
+// ParseStepFunctionInput reads an execution input in any version this code
+// understands, so the orchestrator and the agent can be deployed in either
+// order
+func ParseStepFunctionInput(data []byte) (*StepFunctionInput, error) {
+	var input StepFunctionInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("parse step functions input: %w", err)
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// Seal replaces the input's sensitive fields with what seal makes of them
+// as JSON, so they don't appear in the execution history or the task's
+// environment
+func (in *StepFunctionInput) Seal(seal func(plaintext []byte) (string, error)) error {
+	if in.ContextPack == "" && in.InitialCommand == "" {
+		return nil
+	}
+	data, err := json.Marshal(sensitive{ContextPack: in.ContextPack, InitialCommand: in.InitialCommand})
+	if err != nil {
+		return fmt.Errorf("marshal sensitive input: %w", err)
+	}
+	sealed, err := seal(data)
+	if err != nil {
+		return err
+	}
+	in.Sealed, in.ContextPack, in.InitialCommand = sealed, "", ""
+	return nil
+}
+
+// Unseal restores the sensitive fields Seal replaced, using open to
+// decrypt them
+func (in *StepFunctionInput) Unseal(open func(sealed string) ([]byte, error)) error {
+	if in.Sealed == "" {
+		return nil
+	}
+	data, err := open(in.Sealed)
+	if err != nil {
+		return err
+	}
+	var s sensitive
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("unmarshal sensitive input: %w", err)
+	}
+	in.ContextPack, in.InitialCommand, in.Sealed = s.ContextPack, s.InitialCommand, ""
+	return nil
+}
+
+// Validate reports every problem with the input in one error
+func (in *StepFunctionInput) Validate() error {
+	if in.Version < 0 || in.Version > StepFunctionInputVersion {
+		return fmt.Errorf("step functions input version %d is not supported: this build reads versions 0 to %d, deploy it alongside the code that starts executions", in.Version, StepFunctionInputVersion)
+	}
+
+	var problems []string
+	for _, f := range []struct{ name, value string }{
+		{"conversationId", in.ConversationID},
+		{"channelId", in.ChannelID},
+		{"userId", in.UserID},
+	} {
+		if f.value == "" {
+			problems = append(problems, f.name+" is required")
+		}
+	}
+	if in.Capacity != "" && in.Capacity != CapacityOnDemand && in.Capacity != CapacitySpot {
+		problems = append(problems, fmt.Sprintf("capacity must be %s or %s, got %q", CapacityOnDemand, CapacitySpot, in.Capacity))
+	}
+	if t := in.Task; t != nil && (t.Definition == "" || t.CPU == "" || t.Memory == "") {
+		problems = append(problems, "task needs definition, cpu, and memory")
+	}
+	if in.Sealed != "" && (in.ContextPack != "" || in.InitialCommand != "") {
+		problems = append(problems, "sealed input must not also carry its sensitive fields in plaintext")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid step functions input: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStepFunctionInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "unversioned",
+			data: `{"conversationId":"conv-1","channelId":"C1","userId":"U1","capacity":""}`,
+		},
+		{
+			name: "current",
+			data: `{"version":1,"conversationId":"conv-1","channelId":"C1","userId":"U1","capacity":"spot","task":{"definition":"td:3","cpu":"512","memory":"1024"}}`,
+		},
+		{
+			name:    "newer",
+			data:    `{"version":2,"conversationId":"conv-1","channelId":"C1","userId":"U1"}`,
+			wantErr: "version 2 is not supported",
+		},
+		{
+			name:    "missing fields",
+			data:    `{"version":1,"conversationId":"conv-1"}`,
+			wantErr: "channelId is required; userId is required",
+		},
+		{
+			name:    "bad capacity and task",
+			data:    `{"version":1,"conversationId":"conv-1","channelId":"C1","userId":"U1","capacity":"reserved","task":{"definition":"td:3"}}`,
+			wantErr: `capacity must be ondemand or spot, got "reserved"; task needs definition, cpu, and memory`,
+		},
+		{
+			name:    "sealed and plaintext",
+			data:    `{"version":1,"conversationId":"conv-1","channelId":"C1","userId":"U1","sealed":"AQAA","contextPack":"H4sI"}`,
+			wantErr: "must not also carry its sensitive fields in plaintext",
+		},
+		{
+			name:    "not json",
+			data:    `conv-1`,
+			wantErr: "parse step functions input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := ParseStepFunctionInput([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil || input.ConversationID != "conv-1" {
+					t.Errorf("ParseStepFunctionInput() = %+v, %v", input, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseStepFunctionInput() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSealUnseal(t *testing.T) {
+	in := &StepFunctionInput{ConversationID: "conv-1", ChannelID: "C1", UserID: "U1", ContextPack: "H4sI", InitialCommand: "why is checkout slow?"}
+
+	// Reversing the plaintext stands in for encryption
+	reverse := func(b []byte) string {
+		r := []rune(string(b))
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r)
+	}
+	if err := in.Seal(func(b []byte) (string, error) { return reverse(b), nil }); err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if in.ContextPack != "" || in.InitialCommand != "" || in.Sealed == "" {
+		t.Errorf("Seal() left %+v", in)
+	}
+	if err := in.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	if err := in.Unseal(func(s string) ([]byte, error) { return []byte(reverse([]byte(s))), nil }); err != nil {
+		t.Fatalf("Unseal() error = %v", err)
+	}
+	if in.ContextPack != "H4sI" || in.InitialCommand != "why is checkout slow?" || in.Sealed != "" {
+		t.Errorf("Unseal() = %+v", in)
+	}
+
+	// Nothing to seal leaves the input alone
+	plain := &StepFunctionInput{ConversationID: "conv-1"}
+	if err := plain.Seal(func([]byte) (string, error) { return "", errors.New("should not be called") }); err != nil || plain.Sealed != "" {
+		t.Errorf("Seal() with nothing sensitive = %+v, %v", plain, err)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is an operation that outlives a single turn, such as a Logs Insights
+// scan over several days or a sweep across regions. It reports progress in
+// a Slack message of its own and leaves its result for later turns
+type Job struct {
+	JobID          string     `dynamodbav:"job_id"`
+	ConversationID string     `dynamodbav:"conversation_id"`
+	ChannelID      string     `dynamodbav:"channel_id"`
+	ThreadTS       string     `dynamodbav:"thread_ts,omitempty"`
+	Kind           string     `dynamodbav:"kind"`
+	Input          string     `dynamodbav:"input"` // JSON, as the kind reads it
+	Status         string     `dynamodbav:"status"`
+	RequestedBy    string     `dynamodbav:"requested_by"`
+	MessageTS      string     `dynamodbav:"message_ts,omitempty"` // the message showing its progress
+	Done           int        `dynamodbav:"done"`
+	Total          int        `dynamodbav:"total"`
+	Note           string     `dynamodbav:"note,omitempty"` // what it is doing now
+	Result         string     `dynamodbav:"result,omitempty"`
+	Error          string     `dynamodbav:"error,omitempty"`
+	CancelledBy    string     `dynamodbav:"cancelled_by,omitempty"` // set when cancellation is requested
+	CreatedAt      time.Time  `dynamodbav:"created_at"`
+	UpdatedAt      time.Time  `dynamodbav:"updated_at"`
+	CompletedAt    *time.Time `dynamodbav:"completed_at,omitempty"`
+	TTL            int64      `dynamodbav:"ttl"` // Unix timestamp (7 days)
+}
+
+// NewJob creates a queued job for a conversation
+func NewJob(conv *Conversation, kind, input, requestedBy string) *Job {
+	now := time.Now().UTC()
+	return &Job{
+		JobID:          "job-" + generateULID(),
+		ConversationID: conv.ConversationID,
+		ChannelID:      conv.ChannelID,
+		ThreadTS:       conv.ThreadTS,
+		Kind:           kind,
+		Input:          input,
+		Status:         JobQueued,
+		RequestedBy:    requestedBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		TTL:            now.Add(7 * 24 * time.Hour).Unix(),
+	}
+}
+
+// Ended reports whether the job reached a final status
+func (j *Job) Ended() bool {
+	switch j.Status {
+	case JobSucceeded, JobFailed, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// Percent is how far the job has got, when it knows its total
+func (j *Job) Percent() int {
+	if j.Total <= 0 {
+		return 0
+	}
+	return j.Done * 100 / j.Total
+}
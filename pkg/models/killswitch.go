@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// KillSwitchID keys the kill switch in the settings table
+const KillSwitchID = "kill_switch"
+
+// KillSwitch turns the whole bot off: while Disabled is set no conversation
+// starts and running agents stop answering until it is cleared
+type KillSwitch struct {
+	SettingID string    `dynamodbav:"setting_id"`
+	Disabled  bool      `dynamodbav:"disabled"`
+	Reason    string    `dynamodbav:"reason,omitempty"`
+	ChangedBy string    `dynamodbav:"changed_by"`
+	ChangedAt time.Time `dynamodbav:"changed_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Lease is a time-limited lock on a named resource. Expiry is stored in
+// Unix milliseconds so DynamoDB condition expressions can compare it
+type Lease struct {
+	LockID     string    `dynamodbav:"lock_id"`
+	Owner      string    `dynamodbav:"owner"`
+	AcquiredAt time.Time `dynamodbav:"acquired_at"`
+	ExpiresAt  int64     `dynamodbav:"expires_at"`
+	TTL        int64     `dynamodbav:"ttl"`
+}
+
+// ConversationLockID names the lock that serializes agents working on a
+// conversation
+func ConversationLockID(conversationID string) string {
+	return "conversation#" + conversationID
+}
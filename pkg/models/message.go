@@ -29,12 +29,34 @@ type SlackEvent struct {
 
 // SlackEventBody represents the actual event details
 type SlackEventBody struct {
+	Type     string          `json:"type"`
+	User     string          `json:"user"`
+	Text     string          `json:"text"`
+	Channel  string          `json:"channel"`
+	TS       string          `json:"ts,omitempty"`
+	ThreadTS string          `json:"thread_ts,omitempty"` // set when the message is a reply in a thread
+	BotID    string          `json:"bot_id,omitempty"`
+	SubType  string          `json:"subtype,omitempty"`
+	Reaction string          `json:"reaction,omitempty"` // reaction_added events
+	Item     *SlackEventItem `json:"item,omitempty"`
+
+	// link_shared events
+	MessageTS string            `json:"message_ts,omitempty"`
+	Source    string            `json:"source,omitempty"` // "composer" while the message is being written
+	Links     []SlackSharedLink `json:"links,omitempty"`
+}
+
+// SlackSharedLink is a link in a message, for a domain the app unfurls
+type SlackSharedLink struct {
+	Domain string `json:"domain"`
+	URL    string `json:"url"`
+}
+
+// SlackEventItem identifies the message a reaction was added to
+type SlackEventItem struct {
 	Type    string `json:"type"`
-	User    string `json:"user"`
-	Text    string `json:"text"`
 	Channel string `json:"channel"`
-	BotID   string `json:"bot_id,omitempty"`
-	SubType string `json:"subtype,omitempty"`
+	TS      string `json:"ts"`
 }
 
 // SlackURLVerification is for Slack URL verification
@@ -46,6 +68,7 @@ type SlackURLVerification struct {
 // SlackEventCallback is the main event structure
 type SlackEventCallback struct {
 	Type             string         `json:"type"`
+	EventID          string         `json:"event_id"`
 	Event            SlackEventBody `json:"event"`
 	Challenge        string         `json:"challenge"`
 	RequestTimestamp string         `json:"request_timestamp"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OnCallShift is a single shift in a team's on-call rota
+type OnCallShift struct {
+	Team   string    `dynamodbav:"team"`
+	Start  time.Time `dynamodbav:"start"`
+	End    time.Time `dynamodbav:"end"`
+	UserID string    `dynamodbav:"user_id"` // Slack user ID
+	Name   string    `dynamodbav:"name"`
+}
+
+// Covers reports whether the shift includes the given time
+func (s *OnCallShift) Covers(at time.Time) bool {
+	return !at.Before(s.Start) && at.Before(s.End)
+}
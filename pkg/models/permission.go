@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// Permission profiles, from least to most privileged. Users without a
+// stored profile are read-only
+const (
+	ProfileReadOnly = "read-only"
+	ProfileOperator = "operator"
+	ProfileAdmin    = "admin"
+)
+
+// RBACPolicyID is the settings table key of the tool access policy, when
+// it is kept in DynamoDB
+const RBACPolicyID = "rbac_policy"
+
+// Profiles lists the permission profiles in order of privilege
+var Profiles = []string{ProfileReadOnly, ProfileOperator, ProfileAdmin}
+
+// PermissionProfile assigns a permission profile to a Slack user. A
+// break-glass elevation expires and carries the profile it replaced
+type PermissionProfile struct {
+	UserID    string             `dynamodbav:"user_id"`
+	Profile   string             `dynamodbav:"profile"`
+	GrantedBy string             `dynamodbav:"granted_by"`
+	GrantedAt time.Time          `dynamodbav:"granted_at"`
+	Reason    string             `dynamodbav:"reason,omitempty"`
+	ExpiresAt *time.Time         `dynamodbav:"expires_at,omitempty"`
+	Previous  *PermissionProfile `dynamodbav:"previous,omitempty"`
+}
+
+// ValidProfile reports whether name is a known permission profile
+func ValidProfile(name string) bool {
+	for _, p := range Profiles {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfileAtLeast reports whether profile is at least as privileged as min.
+// Unknown profiles rank below read-only
+func ProfileAtLeast(profile, min string) bool {
+	rank := func(name string) int {
+		for i, p := range Profiles {
+			if p == name {
+				return i
+			}
+		}
+		return -1
+	}
+	return rank(profile) >= rank(min) && rank(profile) >= 0
+}
+
+// NewBreakGlass elevates a user to profile until now+d. The profile being
+// replaced, if any, is kept so it can be restored when the elevation ends
+func NewBreakGlass(current *PermissionProfile, userID, profile, reason string, now time.Time, d time.Duration) *PermissionProfile {
+	expires := now.Add(d)
+	return &PermissionProfile{
+		UserID:    userID,
+		Profile:   profile,
+		GrantedBy: userID,
+		GrantedAt: now,
+		Reason:    reason,
+		ExpiresAt: &expires,
+		Previous:  current,
+	}
+}
+
+// IsBreakGlass reports whether the profile is a temporary elevation
+func (p *PermissionProfile) IsBreakGlass() bool {
+	return p != nil && p.ExpiresAt != nil
+}
+
+// Expired reports whether a temporary elevation has run out
+func (p *PermissionProfile) Expired(now time.Time) bool {
+	return p.IsBreakGlass() && !now.Before(*p.ExpiresAt)
+}
+
+// Effective returns the profile in force at now, falling back to the
+// replaced profile once a temporary elevation has run out
+func (p *PermissionProfile) Effective(now time.Time) string {
+	for p != nil && p.Expired(now) {
+		p = p.Previous
+	}
+	if p == nil {
+		return ProfileReadOnly
+	}
+	return p.Profile
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileAtLeast(t *testing.T) {
+	tests := []struct {
+		profile, min string
+		want         bool
+	}{
+		{ProfileAdmin, ProfileOperator, true},
+		{ProfileOperator, ProfileOperator, true},
+		{ProfileReadOnly, ProfileOperator, false},
+		{"superuser", ProfileReadOnly, false},
+	}
+
+	for _, tt := range tests {
+		if got := ProfileAtLeast(tt.profile, tt.min); got != tt.want {
+			t.Errorf("ProfileAtLeast(%s, %s) = %v, want %v", tt.profile, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestBreakGlass(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	current := &PermissionProfile{UserID: "U1", Profile: ProfileOperator, GrantedBy: "U9"}
+
+	p := NewBreakGlass(current, "U1", ProfileAdmin, "primary DB down, no admins reachable", now, time.Hour)
+	if !p.IsBreakGlass() || p.Previous != current || p.GrantedBy != "U1" {
+		t.Errorf("NewBreakGlass() = %+v", p)
+	}
+	if p.Expired(now.Add(59 * time.Minute)) {
+		t.Error("Expired() should be false before the deadline")
+	}
+	if !p.Expired(now.Add(time.Hour)) {
+		t.Error("Expired() should be true at the deadline")
+	}
+	if current.Expired(now.Add(24 * time.Hour)) {
+		t.Error("Expired() should be false for a permanent grant")
+	}
+}
+
+func TestEffective(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	current := &PermissionProfile{UserID: "U1", Profile: ProfileOperator}
+	p := NewBreakGlass(current, "U1", ProfileAdmin, "outage", now, time.Hour)
+
+	var none *PermissionProfile
+	if got := none.Effective(now); got != ProfileReadOnly {
+		t.Errorf("Effective() without a profile = %s, want %s", got, ProfileReadOnly)
+	}
+	if got := p.Effective(now); got != ProfileAdmin {
+		t.Errorf("Effective() during the elevation = %s, want %s", got, ProfileAdmin)
+	}
+	if got := p.Effective(now.Add(time.Hour)); got != ProfileOperator {
+		t.Errorf("Effective() after the elevation = %s, want %s", got, ProfileOperator)
+	}
+	if got := NewBreakGlass(nil, "U2", ProfileAdmin, "outage", now, time.Hour).Effective(now.Add(time.Hour)); got != ProfileReadOnly {
+		t.Errorf("Effective() after an elevation from nothing = %s, want %s", got, ProfileReadOnly)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// SystemPromptName is the prompt template agents answer with
+const SystemPromptName = "system"
+
+// BuiltinPromptVersion is the prompt compiled into the binary, used when no
+// template has been published
+const BuiltinPromptVersion = 0
+
+// PromptTemplate is a published version of a prompt. Versions are immutable;
+// rolling back means pinning an earlier version
+type PromptTemplate struct {
+	Name      string    `dynamodbav:"name" json:"name"`
+	Version   int       `dynamodbav:"version" json:"version"`
+	Template  string    `dynamodbav:"template" json:"template"`
+	Hash      string    `dynamodbav:"hash" json:"hash"`
+	Author    string    `dynamodbav:"author,omitempty" json:"author,omitempty"`
+	Note      string    `dynamodbav:"note,omitempty" json:"note,omitempty"`
+	CreatedAt time.Time `dynamodbav:"created_at" json:"created_at"`
+}
+
+// NewPromptTemplate creates an unpublished template; the repository assigns
+// its version
+func NewPromptTemplate(name, template, author, note string) *PromptTemplate {
+	return &PromptTemplate{
+		Name:      name,
+		Template:  template,
+		Hash:      PromptHash(template),
+		Author:    author,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+}
+
+// PromptHash is a short fingerprint of a template, for spotting unrecorded
+// edits in logs and scorecards
+func PromptHash(template string) string {
+	sum := sha256.Sum256([]byte(template))
+	return hex.EncodeToString(sum[:])[:12]
+}
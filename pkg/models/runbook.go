@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Runbook statuses. Drafts captured from incidents stay out of the playbook
+// until someone reviews them
+const (
+	RunbookDraft     = "draft"
+	RunbookPublished = "published"
+	RunbookDiscarded = "discarded"
+)
+
+// Runbook is a playbook entry, usually drafted from a resolved conversation
+type Runbook struct {
+	RunbookID      string        `dynamodbav:"runbook_id"`
+	Title          string        `dynamodbav:"title"`
+	Symptoms       string        `dynamodbav:"symptoms,omitempty"`
+	Steps          []RunbookStep `dynamodbav:"steps"`
+	Resolution     string        `dynamodbav:"resolution,omitempty"`
+	Status         string        `dynamodbav:"status"`
+	ConversationID string        `dynamodbav:"conversation_id,omitempty"`
+	Tags           []string      `dynamodbav:"tags,omitempty"`
+	CreatedBy      string        `dynamodbav:"created_by"`
+	CreatedAt      time.Time     `dynamodbav:"created_at"`
+	ReviewedBy     string        `dynamodbav:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time    `dynamodbav:"reviewed_at,omitempty"`
+}
+
+// RunbookStep is one diagnostic or remediation step
+type RunbookStep struct {
+	Action string `dynamodbav:"action" json:"action"`
+	Check  string `dynamodbav:"check,omitempty" json:"check,omitempty"` // what to look for, or the command/query to run
+}
+
+// NewRunbook creates a draft runbook captured from a conversation
+func NewRunbook(conversationID, createdBy string) *Runbook {
+	return &Runbook{
+		RunbookID:      "rb-" + generateULID(),
+		Status:         RunbookDraft,
+		ConversationID: conversationID,
+		CreatedBy:      createdBy,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// Review records who published or discarded a draft
+func (r *Runbook) Review(status, userID string) {
+	r.Status = status
+	r.ReviewedBy = userID
+	now := time.Now()
+	r.ReviewedAt = &now
+}
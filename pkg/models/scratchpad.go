@@ -0,0 +1,136 @@
+package models
+
+import (
+	"strings"
+)
+
+// MaxScratchpadEntries caps each scratchpad section so the prompt stays small
+const MaxScratchpadEntries = 20
+
+// Scratchpad is the agent's structured working memory for a conversation.
+// It is stored on the conversation record and injected into the system prompt
+// every turn, so established facts survive message history truncation.
+type Scratchpad struct {
+	Findings   []string `dynamodbav:"findings,omitempty" json:"findings,omitempty"`
+	Resources  []string `dynamodbav:"resources,omitempty" json:"resources,omitempty"`
+	Hypotheses []string `dynamodbav:"hypotheses,omitempty" json:"hypotheses,omitempty"`
+}
+
+// ScratchpadUpdate is a set of changes the model requested for the scratchpad
+type ScratchpadUpdate struct {
+	Scratchpad
+	Rejected []string // hypotheses to remove
+}
+
+// IsEmpty reports whether the scratchpad has no entries
+func (s *Scratchpad) IsEmpty() bool {
+	return s == nil || len(s.Findings)+len(s.Resources)+len(s.Hypotheses) == 0
+}
+
+// Apply merges an update into the scratchpad, skipping duplicates and
+// dropping the oldest entries once a section exceeds MaxScratchpadEntries
+func (s *Scratchpad) Apply(update ScratchpadUpdate) {
+	s.Findings = appendUnique(s.Findings, update.Findings...)
+	s.Resources = appendUnique(s.Resources, update.Resources...)
+	s.Hypotheses = appendUnique(removeAll(s.Hypotheses, update.Rejected), update.Hypotheses...)
+}
+
+// Render formats the scratchpad for inclusion in the system prompt
+func (s *Scratchpad) Render() string {
+	if s.IsEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+	writeSection := func(title string, entries []string) {
+		if len(entries) == 0 {
+			return
+		}
+		b.WriteString(title + ":\n")
+		for _, e := range entries {
+			b.WriteString("- " + e + "\n")
+		}
+	}
+	writeSection("Key findings", s.Findings)
+	writeSection("Resources under investigation", s.Resources)
+	writeSection("Open hypotheses", s.Hypotheses)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ParseScratchpadUpdate extracts a <scratchpad> block from a model response.
+// It returns the response with the block removed, the parsed update, and
+// whether a block was found. Each line in the block is "kind: text" where kind
+// is finding, resource, hypothesis, or rejected.
+func ParseScratchpadUpdate(response string) (string, ScratchpadUpdate, bool) {
+	const openTag, closeTag = "<scratchpad>", "</scratchpad>"
+
+	var update ScratchpadUpdate
+	start := strings.Index(response, openTag)
+	if start < 0 {
+		return response, update, false
+	}
+
+	end := strings.Index(response[start:], closeTag)
+	if end < 0 {
+		return response, update, false
+	}
+	end += start
+
+	for _, line := range strings.Split(response[start+len(openTag):end], "\n") {
+		kind, text, ok := strings.Cut(strings.TrimSpace(line), ":")
+		text = strings.TrimSpace(text)
+		if !ok || text == "" {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "finding":
+			update.Findings = append(update.Findings, text)
+		case "resource":
+			update.Resources = append(update.Resources, text)
+		case "hypothesis":
+			update.Hypotheses = append(update.Hypotheses, text)
+		case "rejected":
+			update.Rejected = append(update.Rejected, text)
+		}
+	}
+
+	cleaned := strings.TrimSpace(response[:start] + response[end+len(closeTag):])
+	return cleaned, update, true
+}
+
+func appendUnique(entries []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(entries, v) {
+			entries = append(entries, v)
+		}
+	}
+	if len(entries) > MaxScratchpadEntries {
+		entries = entries[len(entries)-MaxScratchpadEntries:]
+	}
+	return entries
+}
+
+func removeAll(entries []string, values []string) []string {
+	if len(values) == 0 {
+		return entries
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if !contains(values, e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func contains(entries []string, value string) bool {
+	for _, e := range entries {
+		if strings.EqualFold(e, value) {
+			return true
+		}
+	}
+	return false
+}
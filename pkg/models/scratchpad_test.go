@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseScratchpadUpdate(t *testing.T) {
+	response := `The instance is stopped because of a failed status check.
+
+<scratchpad>
+finding: i-0abc123 failed its system status check at 14:02 UTC
+resource: i-0abc123
+hypothesis: underlying host hardware failure
+rejected: security group change
+nonsense line
+</scratchpad>`
+
+	cleaned, update, ok := ParseScratchpadUpdate(response)
+	if !ok {
+		t.Fatal("ParseScratchpadUpdate() should find the scratchpad block")
+	}
+
+	if cleaned != "The instance is stopped because of a failed status check." {
+		t.Errorf("cleaned = %q", cleaned)
+	}
+
+	if len(update.Findings) != 1 || len(update.Resources) != 1 || len(update.Hypotheses) != 1 {
+		t.Errorf("update = %+v, want one finding, resource, and hypothesis", update)
+	}
+
+	if len(update.Rejected) != 1 || update.Rejected[0] != "security group change" {
+		t.Errorf("Rejected = %v, want [security group change]", update.Rejected)
+	}
+}
+
+func TestParseScratchpadUpdateMissing(t *testing.T) {
+	tests := []string{
+		"No scratchpad here",
+		"<scratchpad>\nfinding: unterminated",
+	}
+
+	for _, response := range tests {
+		cleaned, _, ok := ParseScratchpadUpdate(response)
+		if ok {
+			t.Errorf("ParseScratchpadUpdate(%q) should not find a block", response)
+		}
+		if cleaned != response {
+			t.Errorf("cleaned = %q, want unchanged response", cleaned)
+		}
+	}
+}
+
+func TestScratchpadApply(t *testing.T) {
+	s := &Scratchpad{
+		Hypotheses: []string{"disk full", "bad deploy"},
+	}
+
+	s.Apply(ScratchpadUpdate{
+		Scratchpad: Scratchpad{
+			Findings:   []string{"error rate spiked at 10:00"},
+			Hypotheses: []string{"Disk Full", "expired certificate"},
+		},
+		Rejected: []string{"bad deploy"},
+	})
+
+	want := []string{"disk full", "expired certificate"}
+	if strings.Join(s.Hypotheses, ",") != strings.Join(want, ",") {
+		t.Errorf("Hypotheses = %v, want %v", s.Hypotheses, want)
+	}
+
+	if len(s.Findings) != 1 {
+		t.Errorf("Findings = %v, want one entry", s.Findings)
+	}
+}
+
+func TestScratchpadApplyCapsEntries(t *testing.T) {
+	s := &Scratchpad{}
+	for i := 0; i < MaxScratchpadEntries+5; i++ {
+		s.Apply(ScratchpadUpdate{Scratchpad: Scratchpad{Resources: []string{fmt.Sprintf("i-%d", i)}}})
+	}
+
+	if len(s.Resources) != MaxScratchpadEntries {
+		t.Fatalf("len(Resources) = %d, want %d", len(s.Resources), MaxScratchpadEntries)
+	}
+
+	if s.Resources[0] != "i-5" {
+		t.Errorf("oldest entries should be dropped first, got %s", s.Resources[0])
+	}
+}
+
+func TestScratchpadRender(t *testing.T) {
+	var empty *Scratchpad
+	if empty.Render() != "" {
+		t.Error("Render() of nil scratchpad should be empty")
+	}
+
+	s := &Scratchpad{
+		Findings:  []string{"RDS CPU at 98%"},
+		Resources: []string{"db-prod-1"},
+	}
+
+	rendered := s.Render()
+	if !strings.Contains(rendered, "Key findings:\n- RDS CPU at 98%") {
+		t.Errorf("Render() = %q, missing findings section", rendered)
+	}
+	if strings.Contains(rendered, "Open hypotheses") {
+		t.Errorf("Render() = %q, should omit empty sections", rendered)
+	}
+}
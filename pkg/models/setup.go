@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SetupID keys the first-run setup status in the settings table
+const SetupID = "setup"
+
+// SetupStatus records whether an admin has seen every setup check pass, and
+// when admins were last prompted to finish setup
+type SetupStatus struct {
+	SettingID   string    `dynamodbav:"setting_id"`
+	CompletedBy string    `dynamodbav:"completed_by"`
+	CompletedAt time.Time `dynamodbav:"completed_at"`
+	PromptedAt  time.Time `dynamodbav:"prompted_at"`
+}
+
+// Completed reports whether setup has been completed
+func (s *SetupStatus) Completed() bool {
+	return s != nil && !s.CompletedAt.IsZero()
+}
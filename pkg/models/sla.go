@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// SLA tracks acknowledgment and resolution deadlines for an incident conversation
+type SLA struct {
+	Severity        string     `dynamodbav:"severity"`
+	StartedAt       time.Time  `dynamodbav:"started_at"`
+	AckDue          time.Time  `dynamodbav:"ack_due"`
+	ResolveDue      time.Time  `dynamodbav:"resolve_due"`
+	AckedAt         *time.Time `dynamodbav:"acked_at,omitempty"`
+	AckedBy         string     `dynamodbav:"acked_by,omitempty"`
+	ResolvedAt      *time.Time `dynamodbav:"resolved_at,omitempty"`
+	AckBreached     bool       `dynamodbav:"ack_breached"`
+	ResolveBreached bool       `dynamodbav:"resolve_breached"`
+	RemindersSent   []string   `dynamodbav:"reminders_sent,omitempty"`
+}
+
+// IsOpen reports whether the incident has not been resolved yet
+func (s *SLA) IsOpen() bool {
+	return s != nil && s.ResolvedAt == nil
+}
+
+// SLAOutcome is the final SLA record for a resolved incident, written for
+// analytics (time-to-ack, time-to-resolve, breaches by severity)
+type SLAOutcome struct {
+	ConversationID       string    `dynamodbav:"conversation_id"`
+	Severity             string    `dynamodbav:"severity"`
+	Tags                 []string  `dynamodbav:"tags,omitempty"`
+	StartedAt            time.Time `dynamodbav:"started_at"`
+	ResolvedAt           time.Time `dynamodbav:"resolved_at"`
+	AckedBy              string    `dynamodbav:"acked_by,omitempty"`
+	TimeToAckSeconds     int64     `dynamodbav:"time_to_ack_seconds"`
+	TimeToResolveSeconds int64     `dynamodbav:"time_to_resolve_seconds"`
+	AckBreached          bool      `dynamodbav:"ack_breached"`
+	ResolveBreached      bool      `dynamodbav:"resolve_breached"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SlackTokenBot identifies the bot token record
+const SlackTokenBot = "bot"
+
+// SlackToken is a rotating Slack OAuth token. It is stored so every
+// process uses the latest token and only one of them refreshes it
+type SlackToken struct {
+	TokenID      string    `dynamodbav:"token_id"`
+	AccessToken  string    `dynamodbav:"access_token"`
+	RefreshToken string    `dynamodbav:"refresh_token"`
+	ExpiresAt    time.Time `dynamodbav:"expires_at"`
+	Version      int       `dynamodbav:"version"`
+	UpdatedAt    time.Time `dynamodbav:"updated_at"`
+}
+
+// ExpiresWithin reports whether the token expires within d of now
+func (t *SlackToken) ExpiresWithin(d time.Duration, now time.Time) bool {
+	return !now.Add(d).Before(t.ExpiresAt)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Subscription records a user watching a conversation they may not be part of
+type Subscription struct {
+	ConversationID string    `dynamodbav:"conversation_id"`
+	UserID         string    `dynamodbav:"user_id"`
+	Keywords       []string  `dynamodbav:"keywords,omitempty"` // notify when these appear; empty means status changes only
+	CreatedAt      time.Time `dynamodbav:"created_at"`
+	TTL            int64     `dynamodbav:"ttl"`
+}
+
+// NewSubscription creates a subscription that expires after 30 days
+func NewSubscription(conversationID, userID string, keywords []string) *Subscription {
+	now := time.Now()
+	return &Subscription{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Keywords:       keywords,
+		CreatedAt:      now,
+		TTL:            now.AddDate(0, 0, 30).Unix(),
+	}
+}
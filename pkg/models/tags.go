@@ -0,0 +1,72 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxTagLength limits the length of a conversation tag
+const MaxTagLength = 32
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ConversationTag indexes a conversation under a tag. Tags live in their
+// own table so they can be queried by tag without scanning conversations
+type ConversationTag struct {
+	ConversationID string    `dynamodbav:"conversation_id"`
+	Tag            string    `dynamodbav:"tag"`
+	ChannelID      string    `dynamodbav:"channel_id"`
+	TaggedBy       string    `dynamodbav:"tagged_by"`
+	CreatedAt      time.Time `dynamodbav:"created_at"`
+}
+
+// NormalizeTag lower-cases a tag and strips a leading '#', reporting
+// whether the result is a valid tag. Slack escapes "#tag" as a channel
+// reference (<#C123|tag>) when a channel has that name
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	if strings.HasPrefix(tag, "<#") && strings.HasSuffix(tag, ">") {
+		if _, name, ok := strings.Cut(tag[2:len(tag)-1], "|"); ok {
+			tag = name
+		}
+	}
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if len(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+		return "", false
+	}
+	return tag, true
+}
+
+// AddTags adds tags not already on the conversation and returns the ones added
+func (c *Conversation) AddTags(tags ...string) []string {
+	var added []string
+	for _, tag := range tags {
+		if !c.HasTag(tag) {
+			c.Tags = append(c.Tags, tag)
+			added = append(added, tag)
+		}
+	}
+	return added
+}
+
+// RemoveTag removes a tag and reports whether it was present
+func (c *Conversation) RemoveTag(tag string) bool {
+	for i, t := range c.Tags {
+		if t == tag {
+			c.Tags = append(c.Tags[:i], c.Tags[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// HasTag reports whether the conversation has the given tag
+func (c *Conversation) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
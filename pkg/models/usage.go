@@ -0,0 +1,14 @@
+package models
+
+// UsageRecord is one cost center's usage of the bot in a calendar month
+// (UTC). Records are kept apart from conversations, which expire long before
+// the month is billed
+type UsageRecord struct {
+	Month          string `dynamodbav:"month"` // 2006-01
+	CostCenter     string `dynamodbav:"cost_center"`
+	Conversations  int64  `dynamodbav:"conversations"`
+	InputTokens    int64  `dynamodbav:"input_tokens"`
+	OutputTokens   int64  `dynamodbav:"output_tokens"`
+	ToolCalls      int64  `dynamodbav:"tool_calls"`
+	RuntimeSeconds int64  `dynamodbav:"runtime_seconds"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Warm agent status constants
+const (
+	WarmAgentIdle    = "idle"
+	WarmAgentClaimed = "claimed"
+)
+
+// WarmAgent is an idle agent task waiting in the warm pool for a
+// conversation to be assigned to it
+type WarmAgent struct {
+	AgentID        string     `dynamodbav:"agent_id"`
+	TaskArn        string     `dynamodbav:"task_arn,omitempty"`
+	Status         string     `dynamodbav:"status"`
+	ConversationID string     `dynamodbav:"conversation_id,omitempty"`
+	RegisteredAt   time.Time  `dynamodbav:"registered_at"`
+	HeartbeatAt    time.Time  `dynamodbav:"heartbeat_at"`
+	ClaimedAt      *time.Time `dynamodbav:"claimed_at,omitempty"`
+	TTL            int64      `dynamodbav:"ttl"`
+}
+
+// NewWarmAgent creates an idle pool registration for an agent task
+func NewWarmAgent(taskArn string) *WarmAgent {
+	now := time.Now()
+	return &WarmAgent{
+		AgentID:      "agent-" + generateULID(),
+		TaskArn:      taskArn,
+		Status:       WarmAgentIdle,
+		RegisteredAt: now,
+		HeartbeatAt:  now,
+		TTL:          now.Add(24 * time.Hour).Unix(),
+	}
+}
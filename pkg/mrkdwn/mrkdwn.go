@@ -0,0 +1,243 @@
+package mrkdwn
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	rulePattern      = regexp.MustCompile(`^(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	bulletPattern    = regexp.MustCompile(`^([ \t]*)[-*+]\s+(.*)$`)
+	taskPattern      = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^([ \t]*)(\d+)[.)]\s+(.*)$`)
+	quotePattern     = regexp.MustCompile(`^>\s?(.*)$`)
+	imagePattern     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	linkPattern      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	boldItalicStars  = regexp.MustCompile(`\*\*\*(\S(?:.*?\S)?)\*\*\*`)
+	boldStars        = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	boldUnderscores  = regexp.MustCompile(`\b__(\S(?:.*?\S)?)__\b`)
+	italicStars      = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	strikePattern    = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	separatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// bullets mark list items by nesting depth
+var bullets = []string{"•", "◦", "▪"}
+
+// Placeholders keep converted markup from being converted again. Neither
+// byte appears in model output
+const (
+	boldMark = "\x00"
+	linkMark = "\x01"
+)
+
+// Convert rewrites GitHub-flavored markdown as Slack mrkdwn: headings become
+// bold lines, **bold** and *italics* use Slack's markers, links become
+// <url|text>, nested lists get indented bullets, and tables become aligned
+// code blocks. Code spans and code blocks are left as they are, apart from
+// dropping the language after an opening fence, which Slack would show
+func Convert(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	out := make([]string, 0, len(lines))
+
+	inCode := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") && (inCode || strings.Count(trimmed, "```") == 1) {
+			if inCode {
+				out = append(out, line)
+			} else {
+				out = append(out, line[:strings.Index(line, "```")+3])
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, line)
+			continue
+		}
+
+		if isTableRow(trimmed) && i+1 < len(lines) && separatorPattern.MatchString(strings.TrimSpace(lines[i+1])) {
+			rows := [][]string{cells(trimmed)}
+			align := alignments(strings.TrimSpace(lines[i+1]))
+			i += 2
+			for ; i < len(lines) && isTableRow(strings.TrimSpace(lines[i])); i++ {
+				rows = append(rows, cells(strings.TrimSpace(lines[i])))
+			}
+			i--
+			out = append(out, table(rows, align)...)
+			continue
+		}
+
+		out = append(out, convertLine(line))
+	}
+	return strings.Join(out, "\n")
+}
+
+// convertLine converts a line of prose
+func convertLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+
+	if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+		title := strings.NewReplacer("**", "", "__", "").Replace(m[1])
+		return "*" + inline(title) + "*"
+	}
+	if rulePattern.MatchString(trimmed) {
+		return "──────────"
+	}
+	if m := bulletPattern.FindStringSubmatch(line); m != nil {
+		depth := indentDepth(m[1])
+		text := m[2]
+		marker := bullets[depth%len(bullets)]
+		if t := taskPattern.FindStringSubmatch(text); t != nil {
+			marker, text = "☐", t[2]
+			if t[1] != " " {
+				marker = "☑"
+			}
+		}
+		return strings.Repeat("    ", depth) + marker + " " + inline(text)
+	}
+	if m := orderedPattern.FindStringSubmatch(line); m != nil {
+		return strings.Repeat("    ", indentDepth(m[1])) + m[2] + ". " + inline(m[3])
+	}
+	if m := quotePattern.FindStringSubmatch(trimmed); m != nil {
+		return "> " + inline(m[1])
+	}
+	return inline(line)
+}
+
+// indentDepth converts list indentation to a nesting level. Markdown nests
+// at two or more spaces; tabs count as four
+func indentDepth(indent string) int {
+	width := 0
+	for _, r := range indent {
+		if r == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width / 2
+}
+
+// inline converts emphasis and links outside code spans
+func inline(text string) string {
+	segments := strings.Split(text, "`")
+	for i := 0; i < len(segments); i += 2 {
+		// An unmatched backtick leaves the rest of the line as prose
+		segments[i] = inlineProse(segments[i])
+	}
+	return strings.Join(segments, "`")
+}
+
+func inlineProse(text string) string {
+	// Links are set aside so emphasis markers in their URLs survive
+	var links []string
+	stash := func(link string) string {
+		links = append(links, link)
+		return fmt.Sprintf("%s%d%s", linkMark, len(links)-1, linkMark)
+	}
+	text = imagePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := imagePattern.FindStringSubmatch(s)
+		if m[1] == "" {
+			return stash("<" + m[2] + ">")
+		}
+		return stash("<" + m[2] + "|" + m[1] + ">")
+	})
+	text = linkPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := linkPattern.FindStringSubmatch(s)
+		return stash("<" + m[2] + "|" + emphasis(m[1]) + ">")
+	})
+
+	text = emphasis(text)
+
+	for i, link := range links {
+		text = strings.Replace(text, fmt.Sprintf("%s%d%s", linkMark, i, linkMark), link, 1)
+	}
+	return text
+}
+
+// emphasis converts bold, italic, and strikethrough markers
+func emphasis(text string) string {
+	text = boldItalicStars.ReplaceAllString(text, boldMark+"_${1}_"+boldMark)
+	text = boldStars.ReplaceAllString(text, boldMark+"${1}"+boldMark)
+	text = boldUnderscores.ReplaceAllString(text, boldMark+"${1}"+boldMark)
+	text = italicStars.ReplaceAllString(text, "_${1}_")
+	text = strikePattern.ReplaceAllString(text, "~${1}~")
+	return strings.ReplaceAll(text, boldMark, "*")
+}
+
+// isTableRow reports whether a line looks like a markdown table row
+func isTableRow(line string) bool {
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+// cells splits a table row into trimmed cell text, without markup that
+// would show literally inside a code block
+func cells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	plain := strings.NewReplacer("**", "", "__", "", "`", "")
+
+	var out []string
+	for _, cell := range strings.Split(row, "|") {
+		cell = linkPattern.ReplaceAllString(cell, "$1")
+		out = append(out, plain.Replace(strings.TrimSpace(cell)))
+	}
+	return out
+}
+
+// alignments reads right alignment from a table's separator row
+func alignments(separator string) []bool {
+	var right []bool
+	for _, cell := range cells(separator) {
+		right = append(right, strings.HasSuffix(cell, ":") && !strings.HasPrefix(cell, ":"))
+	}
+	return right
+}
+
+// table lays out rows as a code block with aligned columns, since Slack has
+// no table markup
+func table(rows [][]string, right []bool) []string {
+	var widths []int
+	for _, row := range rows {
+		for c, cell := range row {
+			if c >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[c] = max(widths[c], utf8.RuneCountInString(cell))
+		}
+	}
+
+	format := func(row []string) string {
+		parts := make([]string, len(widths))
+		for c, w := range widths {
+			cell := ""
+			if c < len(row) {
+				cell = row[c]
+			}
+			pad := strings.Repeat(" ", w-utf8.RuneCountInString(cell))
+			if c < len(right) && right[c] {
+				parts[c] = pad + cell
+			} else {
+				parts[c] = cell + pad
+			}
+		}
+		return strings.TrimRight(strings.Join(parts, " | "), " ")
+	}
+
+	rules := make([]string, len(widths))
+	for c, w := range widths {
+		rules[c] = strings.Repeat("-", w)
+	}
+
+	out := []string{"```", format(rows[0]), strings.Join(rules, "-+-")}
+	for _, row := range rows[1:] {
+		out = append(out, format(row))
+	}
+	return append(out, "```")
+}
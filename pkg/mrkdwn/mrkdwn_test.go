@@ -0,0 +1,97 @@
+package mrkdwn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertInline(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bold", "this is **important**", "this is *important*"},
+		{"bold underscores", "this is __important__", "this is *important*"},
+		{"italic", "this is *subtle*", "this is _subtle_"},
+		{"bold and italic", "**bold** and *italic*", "*bold* and _italic_"},
+		{"bold italic", "***both***", "*_both_*"},
+		{"strikethrough", "~~old~~ new", "~old~ new"},
+		{"link", "see [the docs](https://example.com/a_b)", "see <https://example.com/a_b|the docs>"},
+		{"link with title", `[docs](https://example.com "Docs")`, "<https://example.com|docs>"},
+		{"image", "![graph](https://example.com/g.png)", "<https://example.com/g.png|graph>"},
+		{"code span untouched", "run `ls **/*.go` now", "run `ls **/*.go` now"},
+		{"arithmetic untouched", "2 * 3 * 4", "2 * 3 * 4"},
+		{"plain", "nothing to do", "nothing to do"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in); got != tt.want {
+				t.Errorf("Convert(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "## Root **cause**", "*Root cause*"},
+		{"rule", "---", "──────────"},
+		{"quote", "> **note**", "> *note*"},
+		{
+			"nested list",
+			"- first\n  - child with **bold**\n    - grandchild\n* second",
+			"• first\n    ◦ child with *bold*\n        ▪ grandchild\n• second",
+		},
+		{"ordered list", "1. one\n   2) two", "1. one\n    2. two"},
+		{"task list", "- [ ] todo\n- [x] done", "☐ todo\n☑ done"},
+		{
+			"code block",
+			"```bash\n# not a heading\n- not a list **x**\n```",
+			"```\n# not a heading\n- not a list **x**\n```",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Convert(tt.in); got != tt.want {
+				t.Errorf("Convert() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertTable(t *testing.T) {
+	in := strings.Join([]string{
+		"Instances:",
+		"| Name | State | CPU % |",
+		"|------|-------|------:|",
+		"| **web-1** | running | 92 |",
+		"| web-22 | `stopped` | 0 |",
+		"Done.",
+	}, "\n")
+	want := strings.Join([]string{
+		"Instances:",
+		"```",
+		"Name   | State   | CPU %",
+		"-------+---------+------",
+		"web-1  | running |    92",
+		"web-22 | stopped |     0",
+		"```",
+		"Done.",
+	}, "\n")
+
+	if got := Convert(in); got != want {
+		t.Errorf("Convert() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConvertPipeWithoutTable(t *testing.T) {
+	in := "| not a table |\nplain"
+	if got := Convert(in); got != in {
+		t.Errorf("Convert() = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,61 @@
+package oncall
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Provider names accepted in configuration
+const (
+	ProviderPagerDuty = "pagerduty"
+	ProviderOpsgenie  = "opsgenie"
+	ProviderDynamoDB  = "dynamodb"
+)
+
+// ErrUnknownTeam is returned when no schedule is configured for a team
+var ErrUnknownTeam = errors.New("no on-call schedule for team")
+
+// Responder is a person currently on call
+type Responder struct {
+	Name        string
+	Email       string
+	SlackUserID string // set directly by the rota, or resolved from Email
+}
+
+// Provider looks up who is on call for a team
+type Provider interface {
+	OnCall(ctx context.Context, team string, at time.Time) ([]Responder, error)
+}
+
+// New returns the named provider, or nil when on-call lookup is disabled.
+// shifts backs the dynamodb rota and is ignored by the other providers
+func New(name, token string, schedules map[string]string, shifts ShiftStore) Provider {
+	switch name {
+	case ProviderPagerDuty:
+		return NewPagerDuty(token, schedules)
+	case ProviderOpsgenie:
+		return NewOpsgenie(token, schedules)
+	case ProviderDynamoDB:
+		return NewRota(shifts)
+	}
+	return nil
+}
+
+// Resolve fills in Slack user IDs for responders that only have an email.
+// Responders that can't be resolved are left as they are
+func Resolve(ctx context.Context, responders []Responder, lookup func(ctx context.Context, email string) (string, error)) []Responder {
+	for i, r := range responders {
+		if r.SlackUserID != "" || r.Email == "" {
+			continue
+		}
+		userID, err := lookup(ctx, r.Email)
+		if err != nil {
+			slog.WarnContext(ctx, "no slack user for on-call", "email", r.Email, "error", err)
+			continue
+		}
+		responders[i].SlackUserID = userID
+	}
+	return responders
+}
@@ -0,0 +1,163 @@
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestPagerDutyOnCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token token=pd-token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if got := r.URL.Query().Get("schedule_ids[]"); got != "PSCHED1" {
+			t.Errorf("schedule_ids[] = %q, want PSCHED1", got)
+		}
+		w.Write([]byte(`{"oncalls": [
+			{"escalation_level": 2, "user": {"name": "Bob", "email": "bob@example.com"}},
+			{"escalation_level": 1, "user": {"name": "Alice", "email": "alice@example.com"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	pd := NewPagerDuty("pd-token", map[string]string{"payments": "PSCHED1"})
+	pd.baseURL = server.URL
+
+	responders, err := pd.OnCall(context.Background(), "payments", time.Now())
+	if err != nil {
+		t.Fatalf("OnCall() error = %v", err)
+	}
+	if len(responders) != 1 || responders[0].Email != "alice@example.com" {
+		t.Errorf("OnCall() = %+v, want only the level 1 responder", responders)
+	}
+
+	if _, err := pd.OnCall(context.Background(), "unknown", time.Now()); !errors.Is(err, ErrUnknownTeam) {
+		t.Errorf("OnCall(unknown) error = %v, want ErrUnknownTeam", err)
+	}
+}
+
+func TestOpsgenieOnCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v2/schedules/sched-1/on-calls") {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "GenieKey og-key" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"data": {"onCallRecipients": ["carol@example.com"]}}`))
+	}))
+	defer server.Close()
+
+	og := NewOpsgenie("og-key", map[string]string{"platform": "sched-1"})
+	og.baseURL = server.URL
+
+	responders, err := og.OnCall(context.Background(), "platform", time.Now())
+	if err != nil {
+		t.Fatalf("OnCall() error = %v", err)
+	}
+	if len(responders) != 1 || responders[0].Email != "carol@example.com" {
+		t.Errorf("OnCall() = %+v, want carol", responders)
+	}
+}
+
+func TestOpsgenieOnCallError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	og := NewOpsgenie("bad-key", map[string]string{"platform": "sched-1"})
+	og.baseURL = server.URL
+
+	if _, err := og.OnCall(context.Background(), "platform", time.Now()); err == nil {
+		t.Error("OnCall() should error on non-200 responses")
+	}
+}
+
+type fakeShiftStore struct {
+	shift *models.OnCallShift
+}
+
+func (f *fakeShiftStore) CurrentShift(ctx context.Context, team string, at time.Time) (*models.OnCallShift, error) {
+	return f.shift, nil
+}
+
+func TestRotaOnCall(t *testing.T) {
+	rota := NewRota(&fakeShiftStore{shift: &models.OnCallShift{Team: "payments", UserID: "U123", Name: "Dana"}})
+
+	responders, err := rota.OnCall(context.Background(), "payments", time.Now())
+	if err != nil {
+		t.Fatalf("OnCall() error = %v", err)
+	}
+	if len(responders) != 1 || responders[0].SlackUserID != "U123" {
+		t.Errorf("OnCall() = %+v, want Dana", responders)
+	}
+
+	responders, err = NewRota(&fakeShiftStore{}).OnCall(context.Background(), "payments", time.Now())
+	if err != nil || len(responders) != 0 {
+		t.Errorf("OnCall() with no shift = %+v, %v; want none", responders, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if New("", "", nil, nil) != nil {
+		t.Error("New(\"\") should return nil")
+	}
+	if _, ok := New(ProviderPagerDuty, "token", nil, nil).(*PagerDuty); !ok {
+		t.Error("New(pagerduty) should return a PagerDuty provider")
+	}
+	if _, ok := New(ProviderDynamoDB, "", nil, nil).(*Rota); !ok {
+		t.Error("New(dynamodb) should return a Rota provider")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	responders := []Responder{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Bob", SlackUserID: "U2"},
+		{Name: "Carol", Email: "carol@example.com"},
+	}
+
+	lookup := func(ctx context.Context, email string) (string, error) {
+		if email == "alice@example.com" {
+			return "U1", nil
+		}
+		return "", errors.New("users_not_found")
+	}
+
+	got := Resolve(context.Background(), responders, lookup)
+	if got[0].SlackUserID != "U1" || got[1].SlackUserID != "U2" || got[2].SlackUserID != "" {
+		t.Errorf("Resolve() = %+v", got)
+	}
+}
+
+func TestPagerTrigger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/enqueue" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var event pagerEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		if event.RoutingKey != "rk" || event.DedupKey != "alert-1" || event.Payload.Severity != "critical" {
+			t.Errorf("event = %+v", event)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	pager := NewPager("rk")
+	pager.baseURL = server.URL
+	if err := pager.Trigger(context.Background(), "alert-1", "Unacknowledged critical alert", "cloudops-bot"); err != nil {
+		t.Errorf("Trigger() error = %v", err)
+	}
+}
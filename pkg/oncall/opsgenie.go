@@ -0,0 +1,76 @@
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Opsgenie reads on-call responders from Opsgenie schedules
+type Opsgenie struct {
+	apiKey     string
+	schedules  map[string]string // team -> schedule ID
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpsgenie creates an Opsgenie provider using an API integration key
+func NewOpsgenie(apiKey string, schedules map[string]string) *Opsgenie {
+	return &Opsgenie{
+		apiKey:     apiKey,
+		schedules:  schedules,
+		baseURL:    "https://api.opsgenie.com",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type opsgenieOnCalls struct {
+	Data struct {
+		OnCallRecipients []string `json:"onCallRecipients"`
+	} `json:"data"`
+}
+
+// OnCall returns the users on call for the team's schedule
+func (o *Opsgenie) OnCall(ctx context.Context, team string, at time.Time) ([]Responder, error) {
+	scheduleID, ok := o.schedules[team]
+	if !ok {
+		return nil, ErrUnknownTeam
+	}
+
+	query := url.Values{}
+	query.Set("flat", "true")
+	query.Set("date", at.UTC().Format(time.RFC3339))
+
+	endpoint := fmt.Sprintf("%s/v2/schedules/%s/on-calls?%s", o.baseURL, url.PathEscape(scheduleID), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create opsgenie request: %w", err)
+	}
+	req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get opsgenie on-calls: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get opsgenie on-calls: unexpected status %d", resp.StatusCode)
+	}
+
+	var body opsgenieOnCalls
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode opsgenie on-calls: %w", err)
+	}
+
+	// Flat results are usernames, which Opsgenie requires to be emails
+	responders := make([]Responder, 0, len(body.Data.OnCallRecipients))
+	for _, email := range body.Data.OnCallRecipients {
+		responders = append(responders, Responder{Name: email, Email: email})
+	}
+
+	return responders, nil
+}
@@ -0,0 +1,68 @@
+package oncall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Pager triggers PagerDuty incidents through the Events API v2
+type Pager struct {
+	routingKey string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewPager creates a pager for a PagerDuty service integration key
+func NewPager(routingKey string) *Pager {
+	return &Pager{
+		routingKey: routingKey,
+		baseURL:    "https://events.pagerduty.com",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type pagerEvent struct {
+	RoutingKey  string `json:"routing_key"`
+	EventAction string `json:"event_action"`
+	DedupKey    string `json:"dedup_key"`
+	Payload     struct {
+		Summary  string `json:"summary"`
+		Source   string `json:"source"`
+		Severity string `json:"severity"`
+	} `json:"payload"`
+}
+
+// Trigger opens a critical PagerDuty incident. Repeated triggers with the
+// same dedupKey are merged by PagerDuty
+func (p *Pager) Trigger(ctx context.Context, dedupKey, summary, source string) error {
+	event := pagerEvent{RoutingKey: p.routingKey, EventAction: "trigger", DedupKey: dedupKey}
+	event.Payload.Summary = summary
+	event.Payload.Source = source
+	event.Payload.Severity = "critical"
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal pagerduty event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v2/enqueue", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create pagerduty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("trigger pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("trigger pagerduty event: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
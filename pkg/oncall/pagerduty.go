@@ -0,0 +1,95 @@
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// PagerDuty reads on-call responders from PagerDuty schedules
+type PagerDuty struct {
+	token      string
+	schedules  map[string]string // team -> schedule ID
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewPagerDuty creates a PagerDuty provider using a REST API token
+func NewPagerDuty(token string, schedules map[string]string) *PagerDuty {
+	return &PagerDuty{
+		token:      token,
+		schedules:  schedules,
+		baseURL:    "https://api.pagerduty.com",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type pagerDutyOnCalls struct {
+	OnCalls []struct {
+		EscalationLevel int `json:"escalation_level"`
+		User            struct {
+			Name    string `json:"name"`
+			Summary string `json:"summary"`
+			Email   string `json:"email"`
+		} `json:"user"`
+	} `json:"oncalls"`
+}
+
+// OnCall returns the first-level responders for the team's schedule
+func (p *PagerDuty) OnCall(ctx context.Context, team string, at time.Time) ([]Responder, error) {
+	scheduleID, ok := p.schedules[team]
+	if !ok {
+		return nil, ErrUnknownTeam
+	}
+
+	query := url.Values{}
+	query.Set("schedule_ids[]", scheduleID)
+	query.Set("include[]", "users")
+	query.Set("since", at.UTC().Format(time.RFC3339))
+	query.Set("until", at.UTC().Add(time.Minute).Format(time.RFC3339))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/oncalls?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create pagerduty request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token token="+p.token)
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get pagerduty on-calls: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get pagerduty on-calls: unexpected status %d", resp.StatusCode)
+	}
+
+	var body pagerDutyOnCalls
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode pagerduty on-calls: %w", err)
+	}
+
+	// Only the lowest escalation level is "on call"; higher levels are backup
+	sort.SliceStable(body.OnCalls, func(i, j int) bool {
+		return body.OnCalls[i].EscalationLevel < body.OnCalls[j].EscalationLevel
+	})
+
+	var responders []Responder
+	for _, oc := range body.OnCalls {
+		if oc.EscalationLevel != body.OnCalls[0].EscalationLevel {
+			break
+		}
+		name := oc.User.Name
+		if name == "" {
+			name = oc.User.Summary
+		}
+		responders = append(responders, Responder{Name: name, Email: oc.User.Email})
+	}
+
+	return responders, nil
+}
@@ -0,0 +1,36 @@
+package oncall
+
+import (
+	"context"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ShiftStore looks up the rota shift covering a point in time
+type ShiftStore interface {
+	CurrentShift(ctx context.Context, team string, at time.Time) (*models.OnCallShift, error)
+}
+
+// Rota reads on-call shifts from a simple table maintained by the team
+type Rota struct {
+	store ShiftStore
+}
+
+// NewRota creates a rota provider backed by a shift store
+func NewRota(store ShiftStore) *Rota {
+	return &Rota{store: store}
+}
+
+// OnCall returns the person whose shift covers the given time
+func (r *Rota) OnCall(ctx context.Context, team string, at time.Time) ([]Responder, error) {
+	shift, err := r.store.CurrentShift(ctx, team, at)
+	if err != nil {
+		return nil, err
+	}
+	if shift == nil {
+		return nil, nil
+	}
+
+	return []Responder{{Name: shift.Name, SlackUserID: shift.UserID}}, nil
+}
@@ -0,0 +1,184 @@
+package postmortem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// maxAuditInput bounds each tool call's input in the audit log given to
+// the model
+const maxAuditInput = 200
+
+// systemPrompt instructs the model to produce a structured postmortem
+const systemPrompt = `You are an SRE writing a blameless postmortem draft from an incident investigation transcript and its audit log.
+Respond with a single JSON object and nothing else, using this shape:
+{
+  "title": "short incident title",
+  "impact": "who/what was affected, for how long, and how badly",
+  "timeline": [{"time": "HH:MM UTC", "event": "what happened"}],
+  "root_cause_hypotheses": ["most likely cause first"],
+  "action_items": [{"title": "concrete follow-up", "priority": "high|medium|low"}]
+}
+Build the timeline from the audit log's timestamped tool calls as well as the transcript, noting what each check found.
+Use only facts present in the transcript and audit log. Mark anything uncertain as a hypothesis.`
+
+// Postmortem is a structured postmortem draft
+type Postmortem struct {
+	Title               string          `json:"title"`
+	Impact              string          `json:"impact"`
+	Timeline            []TimelineEvent `json:"timeline"`
+	RootCauseHypotheses []string        `json:"root_cause_hypotheses"`
+	ActionItems         []ActionItem    `json:"action_items"`
+}
+
+// TimelineEvent is a single entry in the incident timeline
+type TimelineEvent struct {
+	Time  string `json:"time"`
+	Event string `json:"event"`
+}
+
+// ActionItem is a follow-up task from the incident
+type ActionItem struct {
+	Title    string `json:"title"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// Drafter asks Claude to draft postmortems from conversation history
+type Drafter struct {
+	bedrock *bedrock.Client
+}
+
+// NewDrafter creates a postmortem drafter
+func NewDrafter(bedrockClient *bedrock.Client) *Drafter {
+	return &Drafter{bedrock: bedrockClient}
+}
+
+// Draft produces a postmortem for a conversation from its messages and the
+// tool calls recorded in the audit log, which may be empty
+func (d *Drafter) Draft(ctx context.Context, conv *models.Conversation, history []models.ConversationHistoryItem, calls []*models.ToolExecution) (*Postmortem, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("conversation %s has no messages", conv.ConversationID)
+	}
+
+	messages := []models.Message{{Role: models.RoleUser, Content: Transcript(conv, history) + AuditLog(calls)}}
+	response, err := d.bedrock.SendMessage(ctx, messages, systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("draft postmortem: %w", err)
+	}
+
+	return Parse(response)
+}
+
+// Transcript formats the conversation with timestamps for the model
+func Transcript(conv *models.Conversation, history []models.ConversationHistoryItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Conversation %s started %s by <@%s>.\n", conv.ConversationID, conv.CreatedAt.UTC().Format(time.RFC3339), conv.UserID)
+	if notes := conv.Scratchpad.Render(); notes != "" {
+		fmt.Fprintf(&b, "\nInvestigation notes:\n%s\n", notes)
+	}
+
+	b.WriteString("\nTranscript:\n")
+	for _, item := range history {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", item.CreatedAt.UTC().Format("15:04"), item.Role, item.Content)
+	}
+	return b.String()
+}
+
+// AuditLog formats the tool calls a conversation made, with their times and
+// outcomes, for the model to place on the timeline
+func AuditLog(calls []*models.ToolExecution) string {
+	if len(calls) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\nAudit log:\n")
+	for _, call := range calls {
+		input := call.Input
+		if len(input) > maxAuditInput {
+			cut := maxAuditInput
+			for cut > 0 && !utf8.RuneStart(input[cut]) {
+				cut--
+			}
+			input = input[:cut] + " …"
+		}
+		fmt.Fprintf(&b, "[%s] %s %s: %s", call.StartedAt.UTC().Format("15:04"), call.Tool, input, call.Outcome)
+		if call.Error != "" {
+			fmt.Fprintf(&b, " (%s)", call.Error)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Parse extracts the postmortem JSON object from a model response
+func Parse(response string) (*Postmortem, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var p Postmortem
+	if err := json.Unmarshal([]byte(response[start:end+1]), &p); err != nil {
+		return nil, fmt.Errorf("unmarshal postmortem: %w", err)
+	}
+	if p.Title == "" {
+		p.Title = "Incident postmortem"
+	}
+
+	return &p, nil
+}
+
+// Markdown renders the postmortem as an editable markdown document
+func (p *Postmortem) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_Draft generated by CloudOps Bot. Review before publishing._\n\n", p.Title)
+
+	b.WriteString("## Impact\n\n")
+	b.WriteString(orPlaceholder(p.Impact) + "\n\n")
+
+	b.WriteString("## Timeline\n\n")
+	if len(p.Timeline) == 0 {
+		b.WriteString("_TBD_\n")
+	}
+	for _, e := range p.Timeline {
+		fmt.Fprintf(&b, "- **%s** %s\n", e.Time, e.Event)
+	}
+
+	b.WriteString("\n## Root cause hypotheses\n\n")
+	if len(p.RootCauseHypotheses) == 0 {
+		b.WriteString("_TBD_\n")
+	}
+	for i, h := range p.RootCauseHypotheses {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, h)
+	}
+
+	b.WriteString("\n## Action items\n\n")
+	if len(p.ActionItems) == 0 {
+		b.WriteString("_TBD_\n")
+	}
+	for _, a := range p.ActionItems {
+		if a.Priority != "" {
+			fmt.Fprintf(&b, "- [ ] %s (%s)\n", a.Title, a.Priority)
+		} else {
+			fmt.Fprintf(&b, "- [ ] %s\n", a.Title)
+		}
+	}
+
+	return b.String()
+}
+
+func orPlaceholder(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "_TBD_"
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package postmortem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestParse(t *testing.T) {
+	response := "Here is the draft:\n```json\n" + `{
+  "title": "Checkout latency",
+  "impact": "30% of checkouts timed out for 40 minutes",
+  "timeline": [{"time": "14:02 UTC", "event": "Alarm fired"}],
+  "root_cause_hypotheses": ["RDS connection exhaustion"],
+  "action_items": [{"title": "Add connection pool alarm", "priority": "high"}]
+}` + "\n```"
+
+	p, err := Parse(response)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if p.Title != "Checkout latency" || len(p.Timeline) != 1 || len(p.ActionItems) != 1 {
+		t.Errorf("Parse() = %+v", p)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, response := range []string{"no json here", "{not valid}"} {
+		if _, err := Parse(response); err == nil {
+			t.Errorf("Parse(%q) should error", response)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	p := &Postmortem{
+		Title:               "Checkout latency",
+		Timeline:            []TimelineEvent{{Time: "14:02 UTC", Event: "Alarm fired"}},
+		RootCauseHypotheses: []string{"RDS connection exhaustion"},
+		ActionItems:         []ActionItem{{Title: "Add connection pool alarm", Priority: "high"}},
+	}
+
+	md := p.Markdown()
+	for _, want := range []string{
+		"# Checkout latency",
+		"## Impact\n\n_TBD_",
+		"- **14:02 UTC** Alarm fired",
+		"1. RDS connection exhaustion",
+		"- [ ] Add connection pool alarm (high)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q", want)
+		}
+	}
+}
+
+func TestTranscript(t *testing.T) {
+	conv := models.NewConversation("C123", "U456", "checkout is slow")
+	at := time.Date(2024, 5, 1, 14, 2, 0, 0, time.UTC)
+	history := []models.ConversationHistoryItem{
+		{Role: models.RoleUser, Content: "checkout is slow", CreatedAt: at},
+	}
+
+	got := Transcript(conv, history)
+	if !strings.Contains(got, "[14:02] user: checkout is slow") {
+		t.Errorf("Transcript() = %q, missing timestamped message", got)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	if got := AuditLog(nil); got != "" {
+		t.Errorf("AuditLog(nil) = %q, want empty", got)
+	}
+
+	at := time.Date(2024, 5, 1, 14, 5, 0, 0, time.UTC)
+	calls := []*models.ToolExecution{
+		{Tool: "describe_alarms", Input: `{"state":"ALARM"}`, Outcome: models.ToolSucceeded, StartedAt: at},
+		{Tool: "restart_service", Input: strings.Repeat("é", maxAuditInput), Outcome: models.ToolRefused, Error: "needs operator", StartedAt: at.Add(3 * time.Minute)},
+	}
+
+	got := AuditLog(calls)
+	for _, want := range []string{
+		"\nAudit log:\n",
+		`[14:05] describe_alarms {"state":"ALARM"}: succeeded`,
+		"[14:08] restart_service ",
+		" …: refused (needs operator)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("AuditLog() missing %q in:\n%s", want, got)
+		}
+	}
+	if !utf8.ValidString(got) {
+		t.Error("AuditLog() cut a long input mid-rune")
+	}
+}
+
+func TestTicketFilerFile(t *testing.T) {
+	var titles []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload ticketPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		titles = append(titles, payload.Title)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	p := &Postmortem{ActionItems: []ActionItem{{Title: "one"}, {Title: "two"}}}
+
+	filed, err := NewTicketFiler(server.URL).File(context.Background(), "conv-123", p)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if filed != 2 || strings.Join(titles, ",") != "one,two" {
+		t.Errorf("File() filed %d: %v, want one,two", filed, titles)
+	}
+}
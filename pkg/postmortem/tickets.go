@@ -0,0 +1,68 @@
+package postmortem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TicketFiler files postmortem action items by posting them to a webhook
+// (e.g. a Jira automation or ITSM intake endpoint)
+type TicketFiler struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// ticketPayload is the JSON body posted for each action item
+type ticketPayload struct {
+	ConversationID string `json:"conversation_id"`
+	Incident       string `json:"incident"`
+	Title          string `json:"title"`
+	Priority       string `json:"priority,omitempty"`
+}
+
+// NewTicketFiler creates a ticket filer for the given webhook URL
+func NewTicketFiler(webhookURL string) *TicketFiler {
+	return &TicketFiler{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// File posts each action item to the webhook and returns how many succeeded
+func (f *TicketFiler) File(ctx context.Context, conversationID string, p *Postmortem) (int, error) {
+	filed := 0
+	for _, item := range p.ActionItems {
+		body, err := json.Marshal(ticketPayload{
+			ConversationID: conversationID,
+			Incident:       p.Title,
+			Title:          item.Title,
+			Priority:       item.Priority,
+		})
+		if err != nil {
+			return filed, fmt.Errorf("marshal ticket: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.webhookURL, bytes.NewReader(body))
+		if err != nil {
+			return filed, fmt.Errorf("create ticket request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := f.httpClient.Do(req)
+		if err != nil {
+			return filed, fmt.Errorf("file ticket: %w", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return filed, fmt.Errorf("file ticket: unexpected status %d", resp.StatusCode)
+		}
+		filed++
+	}
+
+	return filed, nil
+}
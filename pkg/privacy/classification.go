@@ -0,0 +1,140 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Data classes a tool can declare on its results, from least to most
+// sensitive. Results without a class are public
+const (
+	ClassPublic     = "public"     // fine to post anywhere
+	ClassInternal   = "internal"   // kept out of public channels without confirmation
+	ClassRestricted = "restricted" // only for operators, and only in DMs without confirmation
+)
+
+// Decision is what happens to a tool result before the model sees it
+type Decision int
+
+const (
+	Allow   Decision = iota // the result is used as is
+	Confirm                 // the result is used, but the answer waits for confirmation
+	Mask                    // the result is withheld from the model
+)
+
+// RestrictedProfile is the least privileged profile that may see
+// restricted data
+const RestrictedProfile = models.ProfileOperator
+
+// Decide applies the classification policy to a result of the given class,
+// in a conversation of the given visibility, for a user with profile
+func Decide(visibility, class, profile string) Decision {
+	switch class {
+	case ClassRestricted:
+		if !models.ProfileAtLeast(profile, RestrictedProfile) {
+			return Mask
+		}
+		if visibility == DM {
+			return Allow
+		}
+		return Confirm
+	case ClassInternal:
+		if rank(visibility) == 0 {
+			return Confirm
+		}
+		return Allow
+	default:
+		return Allow
+	}
+}
+
+// Masked replaces a withheld result, telling the model why so it can
+// explain instead of guessing
+func Masked(tool string) string {
+	return fmt.Sprintf("[withheld: the %s result is restricted data, which needs the %s profile. Tell the user it was withheld and that someone with that profile can ask for it.]", tool, RestrictedProfile)
+}
+
+// Policy applies the classification policy to the tool results of one
+// turn and remembers what the answer needs before it can be posted
+type Policy struct {
+	visibility string
+	profile    string
+
+	mu         sync.Mutex
+	confirm    []string // classes of the results that need confirmation
+	restricted bool
+}
+
+// NewPolicy creates the policy for a turn in a conversation of the given
+// visibility, asked by a user with profile
+func NewPolicy(visibility, profile string) *Policy {
+	return &Policy{visibility: visibility, profile: profile}
+}
+
+// Check decides what happens to a tool result of the given class. A nil
+// Policy allows everything, so callers never need to check whether
+// classification is on
+func (p *Policy) Check(class string) Decision {
+	if p == nil {
+		return Allow
+	}
+	d := Decide(p.visibility, class, p.profile)
+	if d == Confirm {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !contains(p.confirm, class) {
+			p.confirm = append(p.confirm, class)
+		}
+		if class == ClassRestricted {
+			p.restricted = true
+		}
+	}
+	return d
+}
+
+// Confirmations returns the classes of the results that need confirmation
+// before the answer is posted
+func (p *Policy) Confirmations() []string {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.confirm...)
+}
+
+// Restricted reports whether the answer used restricted data, which only
+// an operator may release
+func (p *Policy) Restricted() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.restricted
+}
+
+type contextKey struct{}
+
+// WithPolicy returns a context whose tool results are checked against p
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the context's policy, or nil when there is none
+func FromContext(ctx context.Context) *Policy {
+	p, _ := ctx.Value(contextKey{}).(*Policy)
+	return p
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package privacy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		visibility, class, profile string
+		want                       Decision
+	}{
+		{Public, ClassPublic, models.ProfileReadOnly, Allow},
+		{Public, "", models.ProfileReadOnly, Allow},
+		{Public, ClassInternal, models.ProfileReadOnly, Confirm},
+		{"", ClassInternal, models.ProfileAdmin, Confirm},
+		{Private, ClassInternal, models.ProfileReadOnly, Allow},
+		{DM, ClassInternal, models.ProfileReadOnly, Allow},
+		{DM, ClassRestricted, models.ProfileReadOnly, Mask},
+		{DM, ClassRestricted, "", Mask},
+		{DM, ClassRestricted, models.ProfileOperator, Allow},
+		{Private, ClassRestricted, models.ProfileOperator, Confirm},
+		{Public, ClassRestricted, models.ProfileAdmin, Confirm},
+	}
+	for _, tt := range tests {
+		if got := Decide(tt.visibility, tt.class, tt.profile); got != tt.want {
+			t.Errorf("Decide(%q, %q, %q) = %d, want %d", tt.visibility, tt.class, tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := NewPolicy(Public, models.ProfileOperator)
+	ctx := WithPolicy(context.Background(), p)
+
+	FromContext(ctx).Check(ClassPublic)
+	FromContext(ctx).Check(ClassInternal)
+	FromContext(ctx).Check(ClassInternal)
+	if got := strings.Join(p.Confirmations(), ","); got != "internal" || p.Restricted() {
+		t.Errorf("Confirmations() = %q, Restricted() = %v; want internal, false", got, p.Restricted())
+	}
+
+	FromContext(ctx).Check(ClassRestricted)
+	if got := strings.Join(p.Confirmations(), ","); got != "internal,restricted" || !p.Restricted() {
+		t.Errorf("Confirmations() = %q, Restricted() = %v; want internal,restricted, true", got, p.Restricted())
+	}
+}
+
+func TestNilPolicy(t *testing.T) {
+	p := FromContext(context.Background())
+	if p.Check(ClassRestricted) != Allow || p.Confirmations() != nil || p.Restricted() {
+		t.Error("a nil Policy should allow everything")
+	}
+}
+
+func TestMasked(t *testing.T) {
+	if got := Masked("iam_get_role"); !strings.Contains(got, "iam_get_role") || !strings.Contains(got, "operator") {
+		t.Errorf("Masked() = %q", got)
+	}
+}
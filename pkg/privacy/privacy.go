@@ -0,0 +1,179 @@
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// Visibility levels, from most to least exposed. A conversation takes the
+// level of where it is held, or a stricter one its requester asks for
+const (
+	Public  = "public"  // a public channel, or a thread in one
+	Private = "private" // a private channel or group DM
+	DM      = "dm"      // a direct message with the requester
+)
+
+// Categories of sensitive content
+const (
+	CategoryIAM  = "IAM"
+	CategoryCost = "cost"
+)
+
+// Block and action identifiers for the confirmation buttons
+const (
+	BlockID    = "privacy"
+	ActionPost = "privacy_post"
+	ActionDM   = "privacy_dm"
+)
+
+// MetadataEventType marks the bot message that records a confirmation, so
+// the agent releases the answer it is holding
+const MetadataEventType = "cloudops_privacy"
+
+var (
+	flagPattern = regexp.MustCompile(`(?i)(^|\s)--(private|dm)\b`)
+
+	iamPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`arn:aws:iam::\d{12}:`),
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		regexp.MustCompile(`"Effect"\s*:\s*"(?:Allow|Deny)"`),
+		regexp.MustCompile(`\b(?:AssumeRolePolicyDocument|PolicyDocument|sts:AssumeRole)\b`),
+	}
+
+	costMetricPattern  = regexp.MustCompile(`\b(?:Unblended|Blended|Amortized)Cost\b`)
+	dollarPattern      = regexp.MustCompile(`\$\s?\d[\d,]*(?:\.\d+)?`)
+	costKeywordPattern = regexp.MustCompile(`(?i)\b(?:cost|costs|spend|spent|spending|bill|billing|invoice|charges?)\b`)
+)
+
+// ForChannel returns the visibility of a Slack conversation
+func ForChannel(channel *slack.Channel) string {
+	switch {
+	case channel.IsIM:
+		return DM
+	case channel.IsPrivate || channel.IsMpIM:
+		return Private
+	default:
+		return Public
+	}
+}
+
+// Requested returns the visibility asked for with a --private or --dm flag
+// in a mention, and the text without it. level is empty when no flag was given
+func Requested(text string) (level, rest string) {
+	match := flagPattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", text
+	}
+	rest = strings.TrimSpace(flagPattern.ReplaceAllString(text, ""))
+	return strings.ToLower(match[2]), rest
+}
+
+// Stricter reports whether level a exposes less than level b
+func Stricter(a, b string) bool {
+	return rank(a) > rank(b)
+}
+
+// rank orders levels by how little they expose. Unknown levels, including
+// conversations started before visibility was recorded, count as public
+func rank(level string) int {
+	switch level {
+	case Private:
+		return 1
+	case DM:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Sensitive returns the categories of sensitive content in text
+func Sensitive(text string) []string {
+	var categories []string
+	for _, p := range iamPatterns {
+		if p.MatchString(text) {
+			categories = append(categories, CategoryIAM)
+			break
+		}
+	}
+	if costMetricPattern.MatchString(text) || (dollarPattern.MatchString(text) && costKeywordPattern.MatchString(text)) {
+		categories = append(categories, CategoryCost)
+	}
+	return categories
+}
+
+// NeedsConfirmation reports whether content in the given categories must be
+// confirmed before it is posted in a conversation of the given visibility
+func NeedsConfirmation(level string, categories []string) bool {
+	return rank(level) == 0 && len(categories) > 0
+}
+
+// Notice explains why an answer is being held and offers where to send it.
+// Only users with at least minProfile may choose, which the buttons carry
+// as their value
+func Notice(categories []string, minProfile string) []slack.Block {
+	text := fmt.Sprintf("🔒 This answer includes %s details, which I don't post here without confirmation.", strings.Join(categories, " and "))
+	if minProfile != models.ProfileReadOnly {
+		text += fmt.Sprintf(" Only someone with the %s profile can release it.", minProfile)
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock(BlockID,
+			slack.NewButtonBlockElement(ActionPost, minProfile, slack.NewTextBlockObject(slack.PlainTextType, "Post here", false, false)),
+			slack.NewButtonBlockElement(ActionDM, minProfile, slack.NewTextBlockObject(slack.PlainTextType, "Send to me privately", false, false)),
+		),
+	}
+}
+
+// IsAction reports whether an interaction action is a confirmation click
+func IsAction(actionID string) bool {
+	return actionID == ActionPost || actionID == ActionDM
+}
+
+// Chosen is the visible record of a confirmation
+func Chosen(userID, actionID string) string {
+	if actionID == ActionDM {
+		return fmt.Sprintf("📬 <@%s> asked for the answer privately", userID)
+	}
+	return fmt.Sprintf("🔓 <@%s> confirmed posting the answer here", userID)
+}
+
+// Metadata carries a confirmation of the answer held behind the notice at
+// noticeTS
+func Metadata(userID, actionID, noticeTS string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"user_id":   userID,
+			"action":    actionID,
+			"notice_ts": noticeTS,
+		},
+	}
+}
+
+// FromMetadata extracts a confirmation from message metadata
+func FromMetadata(meta slack.SlackMetadata) (userID, actionID, noticeTS string, ok bool) {
+	if meta.EventType != MetadataEventType {
+		return "", "", "", false
+	}
+	userID, _ = meta.EventPayload["user_id"].(string)
+	actionID, _ = meta.EventPayload["action"].(string)
+	noticeTS, _ = meta.EventPayload["notice_ts"].(string)
+	return userID, actionID, noticeTS, userID != "" && IsAction(actionID) && noticeTS != ""
+}
+
+// WithoutButtons returns a notice's blocks minus the buttons, so an answer
+// is only released once
+func WithoutButtons(blocks []slack.Block) []slack.Block {
+	kept := make([]slack.Block, 0, len(blocks))
+	for _, b := range blocks {
+		if action, ok := b.(*slack.ActionBlock); ok && action.BlockID == BlockID {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept
+}
@@ -0,0 +1,23 @@
+package privacy
+
+import "regexp"
+
+// Redacted replaces secrets removed from text
+const Redacted = "[REDACTED]"
+
+var (
+	accessKeyPattern  = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	privateKeyPattern = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+
+	// A value following a key that names a secret, quoted or bare, as in
+	// JSON, YAML, environment variables, and connection strings
+	secretValuePattern = regexp.MustCompile(`(?i)("?[\w.-]*(?:secret|password|passwd|token|api[_-]?key|private[_-]?key|credentials?)[\w.-]*"?\s*[:=]\s*"?)([^"\s,}&;]+)`)
+)
+
+// Redact removes AWS access keys, private keys, and values stored under
+// keys that name secrets from text, so it can be kept in an audit log
+func Redact(text string) string {
+	text = privateKeyPattern.ReplaceAllString(text, Redacted)
+	text = accessKeyPattern.ReplaceAllString(text, Redacted)
+	return secretValuePattern.ReplaceAllString(text, "${1}"+Redacted)
+}
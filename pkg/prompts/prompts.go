@@ -0,0 +1,70 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Store reads published prompt versions; *dynamodb.PromptRepository
+// satisfies it
+type Store interface {
+	Get(ctx context.Context, name string, version int) (*models.PromptTemplate, error)
+	Latest(ctx context.Context, name string) (*models.PromptTemplate, error)
+}
+
+// Builtin is the system prompt compiled into the binary
+func Builtin() *models.PromptTemplate {
+	template := bedrock.GetSystemPrompt()
+	return &models.PromptTemplate{
+		Name:     models.SystemPromptName,
+		Version:  models.BuiltinPromptVersion,
+		Template: template,
+		Hash:     models.PromptHash(template),
+		Note:     "built-in",
+	}
+}
+
+// Resolve picks the system prompt for a new conversation: the pinned version
+// when pinned is set (a rollback), otherwise the latest published version.
+// Without a store, or before anything is published, it is the built-in
+// prompt. A pinned version that doesn't exist is an error rather than a
+// silent fallback, so a mistyped rollback is noticed
+func Resolve(ctx context.Context, store Store, pinned int) (*models.PromptTemplate, error) {
+	if store == nil {
+		if pinned != models.BuiltinPromptVersion {
+			return nil, fmt.Errorf("prompt version %d pinned but no prompt store configured", pinned)
+		}
+		return Builtin(), nil
+	}
+
+	if pinned != models.BuiltinPromptVersion {
+		prompt, err := store.Get(ctx, models.SystemPromptName, pinned)
+		if err != nil {
+			return nil, fmt.Errorf("get prompt version %d: %w", pinned, err)
+		}
+		if prompt == nil {
+			return nil, fmt.Errorf("prompt version %d not found", pinned)
+		}
+		return prompt, nil
+	}
+
+	prompt, err := store.Latest(ctx, models.SystemPromptName)
+	if err != nil {
+		return nil, fmt.Errorf("get latest prompt: %w", err)
+	}
+	if prompt == nil {
+		return Builtin(), nil
+	}
+	return prompt, nil
+}
+
+// Label describes a prompt version for logs and Slack, e.g. "v3 (a1b2c3d4e5f6)"
+func Label(prompt *models.PromptTemplate) string {
+	if prompt.Version == models.BuiltinPromptVersion {
+		return fmt.Sprintf("built-in (%s)", prompt.Hash)
+	}
+	return fmt.Sprintf("v%d (%s)", prompt.Version, prompt.Hash)
+}
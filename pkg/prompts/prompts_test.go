@@ -0,0 +1,82 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+type memStore struct {
+	versions []*models.PromptTemplate
+	err      error
+}
+
+func (s *memStore) Get(_ context.Context, name string, version int) (*models.PromptTemplate, error) {
+	for _, p := range s.versions {
+		if p.Name == name && p.Version == version {
+			return p, s.err
+		}
+	}
+	return nil, s.err
+}
+
+func (s *memStore) Latest(_ context.Context, name string) (*models.PromptTemplate, error) {
+	var latest *models.PromptTemplate
+	for _, p := range s.versions {
+		if p.Name == name && (latest == nil || p.Version > latest.Version) {
+			latest = p
+		}
+	}
+	return latest, s.err
+}
+
+func publish(version int, template string) *models.PromptTemplate {
+	p := models.NewPromptTemplate(models.SystemPromptName, template, "U1", "")
+	p.Version = version
+	return p
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{versions: []*models.PromptTemplate{publish(1, "one"), publish(2, "two")}}
+
+	tests := []struct {
+		name    string
+		store   Store
+		pinned  int
+		want    int
+		wantErr bool
+	}{
+		{name: "latest", store: store, want: 2},
+		{name: "rollback", store: store, pinned: 1, want: 1},
+		{name: "missing pin", store: store, pinned: 7, wantErr: true},
+		{name: "nothing published", store: &memStore{}, want: models.BuiltinPromptVersion},
+		{name: "no store", want: models.BuiltinPromptVersion},
+		{name: "pin without store", pinned: 1, wantErr: true},
+		{name: "store error", store: &memStore{err: errors.New("throttled")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(ctx, tt.store, tt.pinned)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Version != tt.want {
+				t.Errorf("Resolve() version = %d, want %d", got.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabel(t *testing.T) {
+	p := publish(3, "three")
+	if got, want := Label(p), "v3 ("+p.Hash+")"; got != want {
+		t.Errorf("Label() = %s, want %s", got, want)
+	}
+	if got := Label(Builtin()); got[:8] != "built-in" {
+		t.Errorf("Label(Builtin()) = %s, want built-in prefix", got)
+	}
+}
@@ -0,0 +1,208 @@
+// Package rbac decides which tools the model may call for whom. A policy
+// maps Slack users and user groups to permission sets (the read-only,
+// operator and admin profiles), and tools to the least permission set that
+// may call them. It is enforced before every tool execution
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrDenied is wrapped by the errors of tool calls the caller may not make
+var ErrDenied = errors.New("permission denied")
+
+// AnyTool is the Tools key whose permission set applies to tools the policy
+// doesn't name
+const AnyTool = "*"
+
+// Policy maps users and user groups to permission sets, and tools to the
+// permission set they need
+//
+//	{
+//	  "users":   {"U012ABCDEF": "admin"},
+//	  "groups":  {"S0123SRE": "operator"},
+//	  "tools":   {"*": "read-only", "restart_service": "operator"},
+//	  "default": "read-only"
+//	}
+type Policy struct {
+	// Users are Slack user IDs and their permission sets
+	Users map[string]string `json:"users,omitempty"`
+
+	// Groups are Slack user group IDs and the permission sets of their
+	// members
+	Groups map[string]string `json:"groups,omitempty"`
+
+	// Tools are tool names and the least permission set that may call
+	// them. Tools not named need the AnyTool entry, or read-only
+	Tools map[string]string `json:"tools,omitempty"`
+
+	// Default is the permission set of users the policy doesn't name,
+	// read-only when empty
+	Default string `json:"default,omitempty"`
+}
+
+// Parse reads a JSON policy
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse rbac policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks every permission set the policy names exists
+func (p *Policy) Validate() error {
+	check := func(kind string, entries map[string]string) error {
+		for key, profile := range entries {
+			if !models.ValidProfile(profile) {
+				return fmt.Errorf("rbac policy: %s %s has unknown permission set %q", kind, key, profile)
+			}
+		}
+		return nil
+	}
+	if err := check("user", p.Users); err != nil {
+		return err
+	}
+	if err := check("group", p.Groups); err != nil {
+		return err
+	}
+	if err := check("tool", p.Tools); err != nil {
+		return err
+	}
+	if p.Default != "" && !models.ValidProfile(p.Default) {
+		return fmt.Errorf("rbac policy: unknown default permission set %q", p.Default)
+	}
+	return nil
+}
+
+// Required returns the least permission set that may call tool
+func (p *Policy) Required(tool string) string {
+	if profile, ok := p.Tools[tool]; ok {
+		return profile
+	}
+	if profile, ok := p.Tools[AnyTool]; ok {
+		return profile
+	}
+	return models.ProfileReadOnly
+}
+
+// GroupLister lists the members of a Slack user group
+type GroupLister interface {
+	GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error)
+}
+
+// Enforcer applies a policy to the agent's senders and tool calls
+type Enforcer struct {
+	policy *Policy
+	groups GroupLister
+
+	mu      sync.Mutex
+	members map[string]groupMembers // by group ID
+	now     func() time.Time
+}
+
+// groupMembers is a user group's membership as last read from Slack
+type groupMembers struct {
+	users   map[string]bool
+	fetched time.Time
+}
+
+// groupTTL is how long a user group's membership is trusted before it is
+// read again, so a long-running process picks up changes
+const groupTTL = 10 * time.Minute
+
+// NewEnforcer creates an enforcer for policy. groups looks up the members of
+// the user groups the policy names
+func NewEnforcer(policy *Policy, groups GroupLister) *Enforcer {
+	return &Enforcer{policy: policy, groups: groups, members: map[string]groupMembers{}, now: time.Now}
+}
+
+// Profile returns a user's permission set: the most privileged of the
+// profile stored for them, their policy entry, the entries of their user
+// groups, and the policy default
+func (e *Enforcer) Profile(ctx context.Context, userID, stored string) string {
+	best := models.ProfileReadOnly
+	raise := func(profile string) {
+		if profile != "" && models.ProfileAtLeast(profile, best) {
+			best = profile
+		}
+	}
+
+	raise(stored)
+	raise(e.policy.Default)
+	raise(e.policy.Users[userID])
+	for groupID, profile := range e.policy.Groups {
+		if models.ProfileAtLeast(best, profile) {
+			continue
+		}
+		if e.inGroup(ctx, groupID, userID) {
+			raise(profile)
+		}
+	}
+	return best
+}
+
+// inGroup reports whether userID is in a user group. A group whose members
+// can't be read grants nothing
+func (e *Enforcer) inGroup(ctx context.Context, groupID, userID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	members, ok := e.members[groupID]
+	if !ok || e.now().Sub(members.fetched) >= groupTTL {
+		ids, err := e.groups.GetUserGroupMembers(ctx, groupID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to list members of user group", "group_id", groupID, "error", err)
+			return false
+		}
+		members = groupMembers{users: make(map[string]bool, len(ids)), fetched: e.now()}
+		for _, id := range ids {
+			members.users[id] = true
+		}
+		e.members[groupID] = members
+	}
+	return members.users[userID]
+}
+
+// Middleware refuses tool calls that need more than the permission set in
+// the call's context. The refusal is returned to the model, which tells the
+// user what they would need
+func (e *Enforcer) Middleware(next bedrock.ToolFunc) bedrock.ToolFunc {
+	return func(ctx context.Context, call bedrock.ToolCall) (string, error) {
+		profile := FromContext(ctx)
+		required := e.policy.Required(call.Name)
+		if !models.ProfileAtLeast(profile, required) {
+			slog.InfoContext(ctx, "refused tool", "tool", call.Name, "required", required, "profile", profile)
+			return "", fmt.Errorf("%w: %s needs the %s permission set, and the people asking have %s. An admin can grant it with /cloudops grant", ErrDenied, call.Name, required, profile)
+		}
+		return next(ctx, call)
+	}
+}
+
+type contextKey struct{}
+
+// WithProfile returns a context whose tool calls run with a permission set
+func WithProfile(ctx context.Context, profile string) context.Context {
+	return context.WithValue(ctx, contextKey{}, profile)
+}
+
+// FromContext returns the context's permission set, read-only when there
+// is none
+func FromContext(ctx context.Context) string {
+	if profile, ok := ctx.Value(contextKey{}).(string); ok {
+		return profile
+	}
+	return models.ProfileReadOnly
+}
@@ -0,0 +1,194 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+const testPolicy = `{
+	"users":  {"U_ADMIN": "admin"},
+	"groups": {"S_SRE": "operator", "S_BROKEN": "admin"},
+	"tools":  {"*": "read-only", "restart_service": "operator", "delete_stack": "admin"}
+}`
+
+// fakeGroups is a GroupLister with fixed memberships, counting lookups by
+// group
+type fakeGroups struct {
+	members map[string][]string
+	calls   map[string]int
+}
+
+func (f *fakeGroups) GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error) {
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[groupID]++
+	members, ok := f.members[groupID]
+	if !ok {
+		return nil, errors.New("usergroup not found")
+	}
+	return members, nil
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse([]byte(testPolicy)); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"users": {"U1": "superuser"}}`,
+		`{"tools": {"*": "root"}}`,
+		`{"default": "owner"}`,
+	}
+	for _, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	p, _ := Parse([]byte(testPolicy))
+	tests := map[string]string{
+		"restart_service":        models.ProfileOperator,
+		"delete_stack":           models.ProfileAdmin,
+		"describe_ec2_instances": models.ProfileReadOnly,
+	}
+	for tool, want := range tests {
+		if got := p.Required(tool); got != want {
+			t.Errorf("Required(%s) = %s, want %s", tool, got, want)
+		}
+	}
+
+	if got := (&Policy{}).Required("anything"); got != models.ProfileReadOnly {
+		t.Errorf("Required() with no tools = %s, want read-only", got)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	p, _ := Parse([]byte(testPolicy))
+	groups := &fakeGroups{members: map[string][]string{"S_SRE": {"U_SRE"}}}
+	e := NewEnforcer(p, groups)
+	ctx := context.Background()
+
+	tests := []struct {
+		userID, stored, want string
+	}{
+		{"U_ADMIN", models.ProfileReadOnly, models.ProfileAdmin},
+		{"U_SRE", models.ProfileReadOnly, models.ProfileOperator},
+		{"U_SRE", models.ProfileAdmin, models.ProfileAdmin},
+		{"U_OTHER", models.ProfileReadOnly, models.ProfileReadOnly},
+		{"U_OTHER", models.ProfileOperator, models.ProfileOperator},
+	}
+	for _, tt := range tests {
+		if got := e.Profile(ctx, tt.userID, tt.stored); got != tt.want {
+			t.Errorf("Profile(%s, %s) = %s, want %s", tt.userID, tt.stored, got, tt.want)
+		}
+	}
+
+	// Memberships are cached until they go stale; groups that can't be read
+	// are tried again
+	calls := groups.calls["S_SRE"]
+	failed := groups.calls["S_BROKEN"]
+	e.Profile(ctx, "U_SRE", models.ProfileReadOnly)
+	if groups.calls["S_SRE"] != calls {
+		t.Errorf("S_SRE read %d more times, want cached", groups.calls["S_SRE"]-calls)
+	}
+	if groups.calls["S_BROKEN"] == failed {
+		t.Error("S_BROKEN wasn't read again after failing")
+	}
+	now := time.Now()
+	e.now = func() time.Time { return now.Add(groupTTL) }
+	e.Profile(ctx, "U_SRE", models.ProfileReadOnly)
+	if groups.calls["S_SRE"] == calls {
+		t.Error("stale user group membership wasn't read again")
+	}
+}
+
+// echo returns its input
+func echo(ctx context.Context, call bedrock.ToolCall) (string, error) {
+	return string(call.Input), nil
+}
+
+func TestMiddleware(t *testing.T) {
+	p, _ := Parse([]byte(testPolicy))
+	run := NewEnforcer(p, &fakeGroups{}).Middleware(echo)
+	call := bedrock.ToolCall{Name: "restart_service", Input: json.RawMessage(`{"service":"api"}`)}
+
+	ctx := WithProfile(context.Background(), models.ProfileOperator)
+	if out, err := run(ctx, call); err != nil || out != `{"service":"api"}` {
+		t.Errorf("operator call = %q, %v; want it run", out, err)
+	}
+
+	ctx = WithProfile(context.Background(), models.ProfileReadOnly)
+	if _, err := run(ctx, call); !errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), "needs the operator permission set") {
+		t.Errorf("read-only call error = %v, want a refusal naming operator", err)
+	}
+
+	// Without a profile the caller counts as read-only
+	if _, err := run(context.Background(), call); err == nil {
+		t.Error("call without a profile ran, want it refused")
+	}
+	if _, err := run(context.Background(), bedrock.ToolCall{Name: "describe_ec2_instances"}); err != nil {
+		t.Errorf("read-only tool refused: %v", err)
+	}
+}
+
+type fakeParameters map[string]string
+
+func (f fakeParameters) GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	value, ok := f[aws.ToString(params.Name)]
+	if !ok {
+		return nil, errors.New("ParameterNotFound")
+	}
+	return &ssm.GetParameterOutput{Parameter: &types.Parameter{Value: aws.String(value)}}, nil
+}
+
+type fakeSettings string
+
+func (f fakeSettings) GetRBACPolicy(ctx context.Context) (string, error) {
+	return string(f), nil
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	params := fakeParameters{"/cloudops/rbac": testPolicy}
+
+	if p, err := Load(ctx, "ssm:/cloudops/rbac", params, fakeSettings("")); err != nil || p.Users["U_ADMIN"] != models.ProfileAdmin {
+		t.Errorf("Load(ssm) = %+v, %v", p, err)
+	}
+	if p, err := Load(ctx, SourceDynamoDB, params, fakeSettings(testPolicy)); err != nil || p.Groups["S_SRE"] != models.ProfileOperator {
+		t.Errorf("Load(dynamodb) = %+v, %v", p, err)
+	}
+	if _, err := Load(ctx, SourceDynamoDB, params, fakeSettings("")); !errors.Is(err, ErrNoPolicy) {
+		t.Errorf("Load() of an empty table error = %v, want ErrNoPolicy", err)
+	}
+
+	// A policy that can't be read locks tools down instead of opening them
+	e := Enforce(ctx, "ssm:/missing", params, fakeSettings(""), &fakeGroups{})
+	if e == nil || e.policy.Required("describe_ec2_instances") != models.ProfileAdmin {
+		t.Errorf("Enforce() with an unreadable policy = %+v, want tools locked down", e)
+	}
+	if e := Enforce(ctx, SourceDynamoDB, params, fakeSettings(""), &fakeGroups{}); e != nil {
+		t.Errorf("Enforce() with no policy = %+v, want nil", e)
+	}
+}
+
+func TestValidSource(t *testing.T) {
+	for spec, want := range map[string]bool{"dynamodb": true, "ssm:/cloudops/rbac": true, "ssm:": false, "s3://bucket": false} {
+		if got := ValidSource(spec); got != want {
+			t.Errorf("ValidSource(%s) = %v, want %v", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// ErrNoPolicy is returned when the configured source holds no policy
+var ErrNoPolicy = errors.New("no rbac policy stored")
+
+// SourceDynamoDB selects the policy stored in the settings table
+const SourceDynamoDB = "dynamodb"
+
+// ssmPrefix selects a policy stored in an SSM parameter
+const ssmPrefix = "ssm:"
+
+// ParameterAPI reads SSM parameters
+type ParameterAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// SettingsStore reads the policy kept in DynamoDB
+type SettingsStore interface {
+	GetRBACPolicy(ctx context.Context) (string, error)
+}
+
+// ValidSource reports whether spec names a policy source: "dynamodb", or
+// "ssm:" followed by a parameter name
+func ValidSource(spec string) bool {
+	return spec == SourceDynamoDB || (strings.HasPrefix(spec, ssmPrefix) && len(spec) > len(ssmPrefix))
+}
+
+// Load reads the policy from the source spec names. A SecureString
+// parameter is decrypted
+func Load(ctx context.Context, spec string, params ParameterAPI, settings SettingsStore) (*Policy, error) {
+	var data string
+	switch {
+	case spec == SourceDynamoDB:
+		policy, err := settings.GetRBACPolicy(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data = policy
+
+	case strings.HasPrefix(spec, ssmPrefix):
+		name := strings.TrimPrefix(spec, ssmPrefix)
+		out, err := params.GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get rbac policy parameter %s: %w", name, err)
+		}
+		data = aws.ToString(out.Parameter.Value)
+
+	default:
+		return nil, fmt.Errorf("unknown rbac policy source %q", spec)
+	}
+
+	if strings.TrimSpace(data) == "" {
+		return nil, ErrNoPolicy
+	}
+	return Parse([]byte(data))
+}
+
+// LockedDown is the policy enforced when the configured one can't be read:
+// only admins may call tools until it can
+func LockedDown() *Policy {
+	return &Policy{Tools: map[string]string{AnyTool: models.ProfileAdmin}}
+}
+
+// Enforce loads the policy from the source spec names and returns an
+// enforcer for it, or nil when the source holds no policy. A policy that
+// can't be read locks tools down to admins rather than opening them up
+func Enforce(ctx context.Context, spec string, params ParameterAPI, settings SettingsStore, groups GroupLister) *Enforcer {
+	policy, err := Load(ctx, spec, params, settings)
+	if errors.Is(err, ErrNoPolicy) {
+		slog.WarnContext(ctx, "no rbac policy, tools are not restricted", "source", spec)
+		return nil
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load rbac policy, only admins may call tools", "error", err)
+		policy = LockedDown()
+	}
+	return NewEnforcer(policy, groups)
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Report is a postmortem draft built from a completed conversation
+type Report struct {
+	Title        string
+	Summary      string
+	Conversation *models.Conversation
+	Timeline     []TimelineEntry
+	ToolCalls    []*models.ToolExecution // from the tool audit log, oldest first
+	GeneratedAt  time.Time
+}
+
+// TimelineEntry is a single message in the report timeline
+type TimelineEntry struct {
+	Time    time.Time
+	Role    string
+	Content string
+}
+
+// Duration returns how long the conversation ran
+func (r *Report) Duration() time.Duration {
+	end := r.GeneratedAt
+	if r.Conversation.CompletedAt != nil {
+		end = *r.Conversation.CompletedAt
+	}
+	return end.Sub(r.Conversation.CreatedAt).Round(time.Second)
+}
+
+// Build assembles a report from a conversation, its message history, and
+// the tool calls audited in it
+func Build(conv *models.Conversation, history []models.ConversationHistoryItem, calls []*models.ToolExecution, summary string) *Report {
+	timeline := make([]TimelineEntry, 0, len(history))
+	for _, item := range history {
+		timeline = append(timeline, TimelineEntry{
+			Time:    item.CreatedAt,
+			Role:    item.Role,
+			Content: item.Content,
+		})
+	}
+
+	return &Report{
+		Title:        fmt.Sprintf("Incident report: %s", conv.ConversationID),
+		Summary:      summary,
+		Conversation: conv,
+		Timeline:     timeline,
+		ToolCalls:    calls,
+		GeneratedAt:  time.Now().UTC(),
+	}
+}
+
+// HTML renders the report as a standalone HTML document
+func (r *Report) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return nil, fmt.Errorf("render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ts":       func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05 UTC") },
+	"duration": humanize.Duration,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; max-width: 900px; margin: 2em auto; color: #1d1c1d; }
+h1 { font-size: 1.6em; }
+table.meta td { padding: 2px 12px 2px 0; vertical-align: top; }
+.entry { border-left: 3px solid #ddd; margin: 0.8em 0; padding: 0.2em 0.8em; }
+.entry.assistant { border-color: #2eb67d; }
+.entry.user { border-color: #36c5f0; }
+.when { color: #616061; font-size: 0.85em; }
+.call { border-left: 3px solid #ecb22e; margin: 0.8em 0; padding: 0.2em 0.8em; }
+.call.failed, .call.refused { border-color: #e01e5a; }
+.call pre { background: #f8f8f8; padding: 0.4em; margin: 0.3em 0; }
+pre, .content { white-space: pre-wrap; word-wrap: break-word; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p><em>Draft generated {{ts .GeneratedAt}}. Review and edit before sharing.</em></p>
+
+<h2>Overview</h2>
+<table class="meta">
+<tr><td>Conversation</td><td>{{.Conversation.ConversationID}}</td></tr>
+<tr><td>Status</td><td>{{.Conversation.Status}}</td></tr>
+<tr><td>Started</td><td>{{ts .Conversation.CreatedAt}}</td></tr>
+<tr><td>Duration</td><td>{{duration .Duration}}</td></tr>
+<tr><td>Requested by</td><td>{{.Conversation.UserID}}</td></tr>
+{{- with .Conversation.Participants}}
+<tr><td>Participants</td><td>{{range $i, $p := .}}{{if $i}}, {{end}}{{$p}}{{end}}</td></tr>
+{{- end}}
+</table>
+
+{{with .Summary}}<h2>Summary</h2>
+<p class="content">{{.}}</p>
+{{end}}
+{{- with .Conversation.Scratchpad}}
+<h2>Key findings</h2>
+<ul>{{range .Findings}}<li>{{.}}</li>{{end}}</ul>
+{{- with .Hypotheses}}
+<h2>Open hypotheses</h2>
+<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- end}}
+{{- with .Conversation.Entities}}
+<h2>Resources</h2>
+<ul>{{range .}}<li><code>{{.ID}}</code>{{with .Region}} ({{.}}){{end}}</li>{{end}}</ul>
+{{- end}}
+
+{{- with .ToolCalls}}
+<h2>Tool calls</h2>
+{{range .}}<div class="call {{.Outcome}}">
+<div class="when">{{ts .StartedAt}} &middot; <code>{{.Tool}}</code> &middot; {{.Outcome}} in {{.DurationMillis}} ms</div>
+<pre>{{.Input}}</pre>
+{{- if .Error}}
+<pre>{{.Error}}</pre>
+{{- else if .Output}}
+<pre>{{.Output}}</pre>
+{{- end}}
+</div>
+{{end}}
+{{- end}}
+
+<h2>Timeline</h2>
+{{range .Timeline}}<div class="entry {{.Role}}">
+<div class="when">{{ts .Time}} &middot; {{.Role}}</div>
+<div class="content">{{.Content}}</div>
+</div>
+{{else}}<p>No messages recorded.</p>
+{{end}}
+</body>
+</html>
+`))
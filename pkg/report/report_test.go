@@ -0,0 +1,72 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestBuildAndRenderHTML(t *testing.T) {
+	conv := models.NewConversation("C123", "U456", "why is checkout slow?")
+	conv.Participants = []string{"U456", "U789"}
+	conv.Scratchpad = &models.Scratchpad{Findings: []string{"RDS CPU pegged at 100%"}}
+	conv.Entities = []models.Entity{{Type: models.EntityInstance, ID: "i-12345678", Region: "us-east-1"}}
+
+	started := conv.CreatedAt
+	history := []models.ConversationHistoryItem{
+		{Role: models.RoleUser, Content: "why is checkout slow? <script>", CreatedAt: started},
+		{Role: models.RoleAssistant, Content: "The database is saturated.", CreatedAt: started.Add(time.Minute)},
+	}
+
+	calls := []*models.ToolExecution{
+		{Tool: "describe_db_instances", Input: `{"id":"checkout"}`, Output: "CPUUtilization 100", Outcome: models.ToolSucceeded, DurationMillis: 420, StartedAt: started.Add(30 * time.Second)},
+		{Tool: "reboot_db_instance", Input: `{"id":"checkout"}`, Outcome: models.ToolRefused, Error: "needs operator <b>", StartedAt: started.Add(45 * time.Second)},
+	}
+
+	r := Build(conv, history, calls, "Checkout latency caused by database saturation.")
+	if len(r.Timeline) != 2 {
+		t.Fatalf("len(Timeline) = %d, want 2", len(r.Timeline))
+	}
+
+	html, err := r.HTML()
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+
+	got := string(html)
+	for _, want := range []string{
+		conv.ConversationID,
+		"Checkout latency caused by database saturation.",
+		"RDS CPU pegged at 100%",
+		"U456, U789",
+		"<code>i-12345678</code> (us-east-1)",
+		"The database is saturated.",
+		"&lt;script&gt;",
+		"<h2>Tool calls</h2>",
+		"<code>describe_db_instances</code> &middot; succeeded in 420 ms",
+		"<pre>CPUUtilization 100</pre>",
+		`<div class="call refused">`,
+		"<pre>needs operator &lt;b&gt;</pre>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML() missing %q", want)
+		}
+	}
+
+	if strings.Contains(got, "<script>") {
+		t.Error("HTML() should escape message content")
+	}
+}
+
+func TestReportDuration(t *testing.T) {
+	conv := models.NewConversation("C123", "U456", "test")
+	completed := conv.CreatedAt.Add(90 * time.Minute)
+	conv.CompletedAt = &completed
+
+	r := Build(conv, nil, nil, "")
+	if r.Duration() != 90*time.Minute {
+		t.Errorf("Duration() = %v, want 1h30m", r.Duration())
+	}
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// linkExpiry is the longest presigned report links stay valid (S3 maximum)
+const linkExpiry = 7 * 24 * time.Hour
+
+// Store saves rendered reports to S3
+type Store struct {
+	client      *s3.Client
+	presign     *s3.PresignClient
+	credentials aws.CredentialsProvider
+	bucket      string
+}
+
+// NewStore creates a report store for the given bucket
+func NewStore(cfg aws.Config, bucket string) *Store {
+	client := s3.NewFromConfig(cfg)
+	return &Store{
+		client:      client,
+		presign:     s3.NewPresignClient(client),
+		credentials: cfg.Credentials,
+		bucket:      bucket,
+	}
+}
+
+// SaveHTML uploads an HTML report and returns a time-limited link to it,
+// with the time the link stops working
+func (s *Store) SaveHTML(ctx context.Context, conversationID string, html []byte) (string, time.Time, error) {
+	key := fmt.Sprintf("reports/%s/%s.html", conversationID, time.Now().UTC().Format("20060102-150405"))
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(html),
+		ContentType: aws.String("text/html; charset=utf-8"),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("put report: %w", err)
+	}
+
+	// The link is signed with the credentials current now. Those are read
+	// first, so a refresh in between only makes the link outlive expires
+	creds, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("retrieve credentials: %w", err)
+	}
+	expires := linkExpires(time.Now(), creds)
+
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(linkExpiry))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("presign report: %w", err)
+	}
+
+	return req.URL, expires, nil
+}
+
+// linkExpires returns when a link presigned at now with creds stops
+// working. Temporary credentials, such as an ECS task role's, end it when
+// they expire, usually well before linkExpiry
+func linkExpires(now time.Time, creds aws.Credentials) time.Time {
+	expires := now.Add(linkExpiry)
+	if creds.CanExpire && creds.Expires.Before(expires) {
+		return creds.Expires
+	}
+	return expires
+}
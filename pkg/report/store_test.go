@@ -0,0 +1,30 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestLinkExpires(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		creds aws.Credentials
+		want  time.Time
+	}{
+		{name: "long-lived", creds: aws.Credentials{}, want: now.Add(linkExpiry)},
+		{name: "task role", creds: aws.Credentials{CanExpire: true, Expires: now.Add(6 * time.Hour)}, want: now.Add(6 * time.Hour)},
+		{name: "outlives link", creds: aws.Credentials{CanExpire: true, Expires: now.Add(30 * 24 * time.Hour)}, want: now.Add(linkExpiry)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := linkExpires(now, tt.creds); !got.Equal(tt.want) {
+				t.Errorf("linkExpires() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
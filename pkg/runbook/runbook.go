@@ -0,0 +1,159 @@
+package runbook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/postmortem"
+	"github.com/slack-go/slack"
+)
+
+// Slack identifiers for the "save as runbook" button
+const (
+	BlockID  = "runbook"
+	ActionID = "runbook_save"
+)
+
+// systemPrompt instructs the model to turn a resolved investigation into a
+// reusable runbook
+const systemPrompt = `You are an SRE turning a resolved incident investigation into a runbook the on-call engineer can follow next time.
+Respond with a single JSON object and nothing else, using this shape:
+{
+  "title": "short name for the failure mode, not this specific incident",
+  "symptoms": "how the problem shows up: alerts, errors, user reports",
+  "steps": [{"action": "what to check or do", "check": "the metric, log query, or command, and what a bad result looks like"}],
+  "resolution": "what fixed it"
+}
+List only the steps that moved the investigation forward, in the order they were useful; leave out dead ends and ruled-out hypotheses.
+Generalize names of specific resources only where the transcript shows the same check applies to others. Use only facts present in the transcript.`
+
+// draft is the model's JSON output
+type draft struct {
+	Title      string               `json:"title"`
+	Symptoms   string               `json:"symptoms"`
+	Steps      []models.RunbookStep `json:"steps"`
+	Resolution string               `json:"resolution"`
+}
+
+// Drafter asks Claude to draft runbooks from conversation history
+type Drafter struct {
+	bedrock *bedrock.Client
+}
+
+// NewDrafter creates a runbook drafter
+func NewDrafter(bedrockClient *bedrock.Client) *Drafter {
+	return &Drafter{bedrock: bedrockClient}
+}
+
+// Draft produces a draft runbook from a conversation, crediting userID
+func (d *Drafter) Draft(ctx context.Context, conv *models.Conversation, history []models.ConversationHistoryItem, userID string) (*models.Runbook, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("conversation %s has no messages", conv.ConversationID)
+	}
+
+	messages := []models.Message{{Role: models.RoleUser, Content: postmortem.Transcript(conv, history)}}
+	response, err := d.bedrock.SendMessage(ctx, messages, systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("draft runbook: %w", err)
+	}
+
+	rb := models.NewRunbook(conv.ConversationID, userID)
+	rb.Tags = conv.Tags
+	if err := Parse(response, rb); err != nil {
+		return nil, err
+	}
+	return rb, nil
+}
+
+// Parse fills rb from the runbook JSON object in a model response
+func Parse(response string, rb *models.Runbook) error {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object in response")
+	}
+
+	var d draft
+	if err := json.Unmarshal([]byte(response[start:end+1]), &d); err != nil {
+		return fmt.Errorf("unmarshal runbook: %w", err)
+	}
+
+	var steps []models.RunbookStep
+	for _, s := range d.Steps {
+		if strings.TrimSpace(s.Action) != "" {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("runbook has no steps")
+	}
+
+	rb.Title = d.Title
+	if rb.Title == "" {
+		rb.Title = "Untitled runbook"
+	}
+	rb.Symptoms = d.Symptoms
+	rb.Steps = steps
+	rb.Resolution = d.Resolution
+	return nil
+}
+
+// Markdown renders a runbook as an editable markdown document
+func Markdown(rb *models.Runbook) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", rb.Title)
+	if rb.Status == models.RunbookDraft {
+		fmt.Fprintf(&b, "_Draft `%s` captured from `%s`. Review before publishing._\n\n", rb.RunbookID, rb.ConversationID)
+	}
+
+	b.WriteString("## Symptoms\n\n")
+	b.WriteString(orPlaceholder(rb.Symptoms) + "\n\n")
+
+	b.WriteString("## Steps\n\n")
+	for i, s := range rb.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, s.Action)
+		if s.Check != "" {
+			fmt.Fprintf(&b, "   - %s\n", s.Check)
+		}
+	}
+
+	b.WriteString("\n## Resolution\n\n")
+	b.WriteString(orPlaceholder(rb.Resolution) + "\n")
+
+	if len(rb.Tags) > 0 {
+		fmt.Fprintf(&b, "\nTags: #%s\n", strings.Join(rb.Tags, " #"))
+	}
+	return b.String()
+}
+
+// Offer returns a button that saves the conversation as a runbook draft
+func Offer(conversationID string) slack.Block {
+	button := slack.NewButtonBlockElement(ActionID, conversationID,
+		slack.NewTextBlockObject(slack.PlainTextType, "📘 Save as runbook", true, false))
+	return slack.NewActionBlock(BlockID, button)
+}
+
+// Saved replaces the button with a note about the saved draft
+func Saved(blocks []slack.Block, rb *models.Runbook) []slack.Block {
+	var kept []slack.Block
+	for _, b := range blocks {
+		if a, ok := b.(*slack.ActionBlock); ok && a.BlockID == BlockID {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	note := fmt.Sprintf("📘 <@%s> saved runbook draft `%s`: %s. Review it with `/cloudops runbook publish %s`.",
+		rb.CreatedBy, rb.RunbookID, rb.Title, rb.RunbookID)
+	return append(kept, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, note, false, false)))
+}
+
+func orPlaceholder(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "_TBD_"
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package runbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+func TestParse(t *testing.T) {
+	response := "```json\n" + `{
+  "title": "RDS connection exhaustion",
+  "symptoms": "Checkout requests time out",
+  "steps": [
+    {"action": "Check DatabaseConnections on the writer", "check": "near max_connections"},
+    {"action": "  "}
+  ],
+  "resolution": "Recycled the leaking service"
+}` + "\n```"
+
+	rb := models.NewRunbook("conv-1", "U1")
+	if err := Parse(response, rb); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if rb.Title != "RDS connection exhaustion" || len(rb.Steps) != 1 || rb.Status != models.RunbookDraft {
+		t.Errorf("Parse() = %+v", rb)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, response := range []string{"no json here", "{not valid}", `{"title": "x", "steps": []}`} {
+		if err := Parse(response, models.NewRunbook("conv-1", "U1")); err == nil {
+			t.Errorf("Parse(%q) should error", response)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	rb := models.NewRunbook("conv-1", "U1")
+	rb.Title = "RDS connection exhaustion"
+	rb.Steps = []models.RunbookStep{{Action: "Check DatabaseConnections", Check: "near max_connections"}}
+	rb.Tags = []string{"rds", "sev2"}
+
+	md := Markdown(rb)
+	for _, want := range []string{"# RDS connection exhaustion", "Review before publishing", "1. Check DatabaseConnections\n   - near max_connections", "## Symptoms\n\n_TBD_", "Tags: #rds #sev2"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+
+	rb.Review(models.RunbookPublished, "U2")
+	if strings.Contains(Markdown(rb), "Review before publishing") {
+		t.Error("Markdown() should not mark a published runbook as a draft")
+	}
+}
+
+func TestSaved(t *testing.T) {
+	text := slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "resolved", false, false), nil, nil)
+	rb := models.NewRunbook("conv-1", "U1")
+	rb.Title = "Disk full"
+
+	blocks := Saved([]slack.Block{text, Offer("conv-1")}, rb)
+	if len(blocks) != 2 || blocks[0] != text {
+		t.Fatalf("Saved() = %v, want the text followed by a note", blocks)
+	}
+	if _, ok := blocks[1].(*slack.ContextBlock); !ok {
+		t.Errorf("Saved() last block = %T, want a context note", blocks[1])
+	}
+}
@@ -0,0 +1,56 @@
+// Package sandbox points the agent's tools at a demo account holding
+// synthetic data, so new on-call engineers can practice with the bot
+// without production access. Everything the bot keeps for itself, such as
+// its tables and model, stays in its own account
+package sandbox
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// sessionName identifies the bot's sessions in the demo account's CloudTrail
+const sessionName = "cloudops-bot-sandbox"
+
+// Instructions are added to the system prompt in sandbox mode
+const Instructions = `You are running in sandbox mode for training: your tools read a demo AWS account filled with synthetic data, not production.
+Treat the data as real while investigating, so the exercise stays realistic, but never suggest that a finding applies to production systems.`
+
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::(\d{12}):role/.+$`)
+
+// ValidRoleARN reports whether arn names an IAM role
+func ValidRoleARN(arn string) bool {
+	return roleARNPattern.MatchString(arn)
+}
+
+// Account returns the account ID of the demo role
+func Account(roleARN string) string {
+	if m := roleARNPattern.FindStringSubmatch(roleARN); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Config returns a copy of base whose credentials assume the demo role,
+// refreshed as they expire. externalID may be empty
+func Config(base aws.Config, roleARN, externalID string) aws.Config {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(base), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if externalID != "" {
+			o.ExternalID = aws.String(externalID)
+		}
+	})
+
+	cfg := base.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+	return cfg
+}
+
+// Banner marks answers drawn from the demo account
+func Banner(roleARN string) string {
+	return fmt.Sprintf("🧪 *Sandbox* · data from demo account %s, not production", Account(roleARN))
+}
@@ -0,0 +1,41 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestAccount(t *testing.T) {
+	for arn, want := range map[string]string{
+		"arn:aws:iam::123456789012:role/cloudops-demo-readonly": "123456789012",
+		"arn:aws-us-gov:iam::123456789012:role/demo":            "123456789012",
+		"arn:aws:iam::123456789012:user/demo":                   "",
+		"cloudops-demo-readonly":                                "",
+	} {
+		if got := Account(arn); got != want {
+			t.Errorf("Account(%q) = %q, want %q", arn, got, want)
+		}
+		if ValidRoleARN(arn) != (want != "") {
+			t.Errorf("ValidRoleARN(%q) = %v", arn, !(want != ""))
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	base := aws.Config{Region: "us-west-2"}
+	cfg := Config(base, "arn:aws:iam::123456789012:role/demo", "training")
+	if cfg.Credentials == nil || cfg.Region != "us-west-2" {
+		t.Errorf("Config() = %+v, want the base region with assumed role credentials", cfg)
+	}
+	if base.Credentials != nil {
+		t.Error("Config() changed the base configuration")
+	}
+}
+
+func TestBanner(t *testing.T) {
+	if banner := Banner("arn:aws:iam::123456789012:role/demo"); !strings.Contains(banner, "123456789012") {
+		t.Errorf("Banner() = %q, want the demo account", banner)
+	}
+}
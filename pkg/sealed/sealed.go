@@ -0,0 +1,138 @@
+// Package sealed encrypts values passed to an agent task, so they can't be
+// read from the Step Functions execution history or DescribeTasks. Each
+// value is encrypted under its own KMS data key and bound to the
+// conversation it is for, so it can't be replayed into another
+package sealed
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// version is the first byte of a sealed value, so the format can change
+// while older tasks are still running
+const version = 1
+
+// contextKey names the conversation in the KMS encryption context, which
+// CloudTrail records with every Decrypt
+const contextKey = "conversation_id"
+
+// ErrMalformed is returned when a value wasn't sealed by this package
+var ErrMalformed = errors.New("malformed sealed value")
+
+// API is the part of the KMS client sealing uses
+type API interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// Sealer seals values under a KMS key and opens them again
+type Sealer struct {
+	client API
+	keyID  string
+}
+
+// New creates a sealer for the KMS key keyID. Opening needs no key ID,
+// since KMS finds it from the sealed value
+func New(cfg aws.Config, keyID string) *Sealer {
+	return &Sealer{client: kms.NewFromConfig(cfg), keyID: keyID}
+}
+
+// NewWithClient creates a sealer with a custom KMS client
+func NewWithClient(client API, keyID string) *Sealer {
+	return &Sealer{client: client, keyID: keyID}
+}
+
+// Seal encrypts plaintext for conversationID, returning it as base64
+func (s *Sealer) Seal(ctx context.Context, conversationID string, plaintext []byte) (string, error) {
+	if s.keyID == "" {
+		return "", errors.New("seal: no kms key")
+	}
+	key, err := s.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             &s.keyID,
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: map[string]string{contextKey: conversationID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+
+	gcm, err := newGCM(key.Plaintext)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	// version, length of the encrypted data key, the key, nonce, ciphertext
+	out := []byte{version}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(key.CiphertextBlob)))
+	out = append(out, key.CiphertextBlob...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, plaintext, []byte(conversationID))
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a value sealed for conversationID. It fails for a value
+// sealed for another conversation
+func (s *Sealer) Open(ctx context.Context, conversationID, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if len(data) < 3 || data[0] != version {
+		return nil, ErrMalformed
+	}
+	keyLen := int(binary.BigEndian.Uint16(data[1:3]))
+	data = data[3:]
+	if len(data) < keyLen {
+		return nil, ErrMalformed
+	}
+	encryptedKey, data := data[:keyLen], data[keyLen:]
+
+	key, err := s.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    encryptedKey,
+		EncryptionContext: map[string]string{contextKey: conversationID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data key: %w", err)
+	}
+
+	gcm, err := newGCM(key.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(conversationID))
+	if err != nil {
+		return nil, fmt.Errorf("open sealed value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return gcm, nil
+}
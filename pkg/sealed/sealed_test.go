@@ -0,0 +1,71 @@
+package sealed
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// fakeKMS hands out data keys "encrypted" by prefixing the encryption
+// context, and refuses to decrypt them under any other context
+type fakeKMS struct {
+	keys map[string][]byte
+}
+
+func (f *fakeKMS) GenerateDataKey(_ context.Context, params *kms.GenerateDataKeyInput, _ ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	blob := []byte(params.EncryptionContext[contextKey] + "/" + string(rune('a'+len(f.keys))))
+	f.keys[string(blob)] = key
+	return &kms.GenerateDataKeyOutput{CiphertextBlob: blob, Plaintext: key}, nil
+}
+
+func (f *fakeKMS) Decrypt(_ context.Context, params *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	key, ok := f.keys[string(params.CiphertextBlob)]
+	if !ok || !bytes.HasPrefix(params.CiphertextBlob, []byte(params.EncryptionContext[contextKey]+"/")) {
+		return nil, errors.New("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: key}, nil
+}
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	s := NewWithClient(&fakeKMS{keys: map[string][]byte{}}, "alias/cloudops-agent-input")
+	plaintext := []byte(`{"contextPack":"H4sIAAAAAAAA/6pWKklNLsnMz1OyUkpKLEpVqgUAAAD//w=="}`)
+
+	sealed, err := s.Seal(ctx, "conv-1", plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains([]byte(sealed), []byte("contextPack")) {
+		t.Error("Seal() should not leave the plaintext readable")
+	}
+
+	got, err := s.Open(ctx, "conv-1", sealed)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open() = %s, %v; want %s", got, err, plaintext)
+	}
+	if _, err := s.Open(ctx, "conv-2", sealed); err == nil {
+		t.Error("Open() should fail for another conversation")
+	}
+}
+
+func TestOpenMalformed(t *testing.T) {
+	s := NewWithClient(&fakeKMS{keys: map[string][]byte{}}, "")
+	for _, sealed := range []string{"", "not base64!", "AgAA", "AQBkYWJj"} {
+		if _, err := s.Open(context.Background(), "conv-1", sealed); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Open(%q) error = %v, want ErrMalformed", sealed, err)
+		}
+	}
+}
+
+func TestSealWithoutKey(t *testing.T) {
+	s := NewWithClient(&fakeKMS{keys: map[string][]byte{}}, "")
+	if _, err := s.Seal(context.Background(), "conv-1", []byte("secret")); err == nil {
+		t.Error("Seal() should fail without a key")
+	}
+}
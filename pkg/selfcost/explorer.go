@@ -0,0 +1,90 @@
+package selfcost
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+)
+
+// bedrockService is Cost Explorer's name for Bedrock usage
+const bedrockService = "Amazon Bedrock"
+
+// Explorer reads the bot's own spend from Cost Explorer
+type Explorer struct {
+	client *costexplorer.Client
+	filter *types.Expression
+}
+
+// NewExplorer creates an explorer for resources tagged tagKey=tagValue. The
+// tag must be activated as a cost allocation tag. Bedrock calls can't be
+// tagged, so includeBedrock adds the account's whole Bedrock spend; enable
+// it only where the bot is the main Bedrock user
+func NewExplorer(cfg aws.Config, tagKey, tagValue string, includeBedrock bool) *Explorer {
+	filter := &types.Expression{
+		Tags: &types.TagValues{Key: aws.String(tagKey), Values: []string{tagValue}},
+	}
+	if includeBedrock {
+		filter = &types.Expression{Or: []types.Expression{
+			*filter,
+			{Dimensions: &types.DimensionValues{Key: types.DimensionService, Values: []string{bedrockService}}},
+		}}
+	}
+
+	// Cost Explorer is only served from us-east-1
+	return &Explorer{
+		client: costexplorer.NewFromConfig(cfg, func(o *costexplorer.Options) { o.Region = "us-east-1" }),
+		filter: filter,
+	}
+}
+
+// Days returns the daily spend by service from start up to, but not
+// including, end
+func (e *Explorer) Days(ctx context.Context, start, end time.Time) ([]Day, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &types.DateInterval{
+			Start: aws.String(start.UTC().Format(DateLayout)),
+			End:   aws.String(end.UTC().Format(DateLayout)),
+		},
+		Granularity: types.GranularityDaily,
+		Metrics:     []string{"UnblendedCost"},
+		Filter:      e.filter,
+		GroupBy:     []types.GroupDefinition{{Type: types.GroupDefinitionTypeDimension, Key: aws.String(string(types.DimensionService))}},
+	}
+
+	var days []Day
+	for {
+		output, err := e.client.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("get cost and usage: %w", err)
+		}
+
+		for _, result := range output.ResultsByTime {
+			day := Day{Date: aws.ToString(result.TimePeriod.Start), Services: make(map[string]float64)}
+			for _, group := range result.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+				metric, ok := group.Metrics["UnblendedCost"]
+				if !ok {
+					continue
+				}
+				cost, err := strconv.ParseFloat(aws.ToString(metric.Amount), 64)
+				if err != nil {
+					return nil, fmt.Errorf("parse cost for %s: %w", group.Keys[0], err)
+				}
+				day.Services[group.Keys[0]] += cost
+			}
+			days = append(days, day)
+		}
+
+		if output.NextPageToken == nil {
+			return days, nil
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+}
@@ -0,0 +1,132 @@
+package selfcost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+)
+
+// Period names the window a limit applies to
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// DateLayout is how Cost Explorer formats days
+const DateLayout = "2006-01-02"
+
+// maxServices caps the services listed in an alert
+const maxServices = 5
+
+// Day is the bot's spend for one UTC day, by AWS service
+type Day struct {
+	Date     string // 2006-01-02
+	Services map[string]float64
+}
+
+// Total is the day's spend across services
+func (d Day) Total() float64 {
+	var total float64
+	for _, cost := range d.Services {
+		total += cost
+	}
+	return total
+}
+
+// Limits are the spend thresholds in USD; zero disables a limit
+type Limits struct {
+	Daily   float64
+	Monthly float64
+}
+
+// Alert is a limit crossed by the most recent day's spend
+type Alert struct {
+	Period   string
+	Date     string // the day that crossed the limit
+	Spend    float64
+	Limit    float64
+	Services map[string]float64
+}
+
+// Check compares the days of the current month, oldest first and ending with
+// the day that just closed, with the limits. The monthly limit only alerts on
+// the day the month-to-date spend first crosses it, so a daily run doesn't
+// repeat it for the rest of the month
+func Check(days []Day, limits Limits) []Alert {
+	if len(days) == 0 {
+		return nil
+	}
+	last := days[len(days)-1]
+
+	var alerts []Alert
+	if limits.Daily > 0 && last.Total() > limits.Daily {
+		alerts = append(alerts, Alert{
+			Period:   PeriodDaily,
+			Date:     last.Date,
+			Spend:    last.Total(),
+			Limit:    limits.Daily,
+			Services: last.Services,
+		})
+	}
+
+	if limits.Monthly > 0 {
+		month := make(map[string]float64)
+		var before float64
+		for i, day := range days {
+			for service, cost := range day.Services {
+				month[service] += cost
+			}
+			if i < len(days)-1 {
+				before += day.Total()
+			}
+		}
+		total := before + last.Total()
+		if total > limits.Monthly && before <= limits.Monthly {
+			alerts = append(alerts, Alert{
+				Period:   PeriodMonthly,
+				Date:     last.Date,
+				Spend:    total,
+				Limit:    limits.Monthly,
+				Services: month,
+			})
+		}
+	}
+
+	return alerts
+}
+
+// Render formats an alert as a Slack message
+func (a Alert) Render() string {
+	var b strings.Builder
+	switch a.Period {
+	case PeriodMonthly:
+		fmt.Fprintf(&b, "*💸 CloudOps bot spend crossed its monthly budget*\nMonth to date through %s: *%s* (budget %s)\n",
+			a.Date, humanize.Currency(a.Spend, "USD"), humanize.Currency(a.Limit, "USD"))
+	default:
+		fmt.Fprintf(&b, "*💸 CloudOps bot spend crossed its daily budget*\n%s: *%s* (budget %s)\n",
+			a.Date, humanize.Currency(a.Spend, "USD"), humanize.Currency(a.Limit, "USD"))
+	}
+
+	services := make([]string, 0, len(a.Services))
+	for service, cost := range a.Services {
+		if cost >= 0.005 {
+			services = append(services, service)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if a.Services[services[i]] != a.Services[services[j]] {
+			return a.Services[services[i]] > a.Services[services[j]]
+		}
+		return services[i] < services[j]
+	})
+	if len(services) > maxServices {
+		services = services[:maxServices]
+	}
+	for _, service := range services {
+		fmt.Fprintf(&b, "• %s: %s\n", service, humanize.Currency(a.Services[service], "USD"))
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,83 @@
+package selfcost
+
+import (
+	"strings"
+	"testing"
+)
+
+func day(date string, fargate, bedrock float64) Day {
+	return Day{Date: date, Services: map[string]float64{
+		"Amazon Elastic Container Service": fargate,
+		"Amazon Bedrock":                   bedrock,
+	}}
+}
+
+func TestCheckDaily(t *testing.T) {
+	days := []Day{day("2024-05-01", 2, 3), day("2024-05-02", 4, 8)}
+
+	alerts := Check(days, Limits{Daily: 10})
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	if a := alerts[0]; a.Period != PeriodDaily || a.Date != "2024-05-02" || a.Spend != 12 {
+		t.Errorf("alert = %+v", a)
+	}
+
+	if alerts := Check(days, Limits{Daily: 12}); len(alerts) != 0 {
+		t.Errorf("spend at the limit should not alert, got %+v", alerts)
+	}
+}
+
+func TestCheckMonthlyAlertsOnce(t *testing.T) {
+	limits := Limits{Monthly: 20}
+	days := []Day{day("2024-05-01", 5, 5), day("2024-05-02", 5, 6), day("2024-05-03", 5, 5)}
+
+	if alerts := Check(days[:1], limits); len(alerts) != 0 {
+		t.Errorf("under budget: got %+v", alerts)
+	}
+
+	alerts := Check(days[:2], limits)
+	if len(alerts) != 1 || alerts[0].Period != PeriodMonthly {
+		t.Fatalf("crossing day: got %+v, want one monthly alert", alerts)
+	}
+	if alerts[0].Spend != 21 || alerts[0].Services["Amazon Bedrock"] != 11 {
+		t.Errorf("alert = %+v", alerts[0])
+	}
+
+	if alerts := Check(days, limits); len(alerts) != 0 {
+		t.Errorf("already over budget: got %+v, want no repeat", alerts)
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	if alerts := Check([]Day{day("2024-05-01", 100, 100)}, Limits{}); len(alerts) != 0 {
+		t.Errorf("got %+v, want none without limits", alerts)
+	}
+	if alerts := Check(nil, Limits{Daily: 1, Monthly: 1}); alerts != nil {
+		t.Errorf("got %+v, want none without data", alerts)
+	}
+}
+
+func TestRender(t *testing.T) {
+	a := Alert{
+		Period: PeriodDaily,
+		Date:   "2024-05-02",
+		Spend:  12.5,
+		Limit:  10,
+		Services: map[string]float64{
+			"Amazon Bedrock":                   8.5,
+			"Amazon Elastic Container Service": 4,
+			"Amazon DynamoDB":                  0.001,
+		},
+	}
+
+	msg := a.Render()
+	for _, want := range []string{"daily budget", "*$12.50* (budget $10.00)", "• Amazon Bedrock: $8.50\n• Amazon Elastic Container Service: $4.00"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Render() missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "DynamoDB") {
+		t.Errorf("Render() should omit services under a cent:\n%s", msg)
+	}
+}
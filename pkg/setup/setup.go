@@ -0,0 +1,315 @@
+// Package setup runs the bot's self-check suite: it verifies the tables,
+// credentials, model access and Slack token the bot depends on, and says
+// exactly what to fix when one fails. The setup wizard walks an admin
+// through the results in a DM
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	appconfig "github.com/savaki/cloudops-bot/pkg/config"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// checkTimeout bounds each check so one hung dependency can't stall the
+// report
+const checkTimeout = 10 * time.Second
+
+// Check is one verification step
+type Check struct {
+	Name string
+
+	// Run returns a detail to show when the check passes
+	Run func(ctx context.Context) (string, error)
+
+	// Fix explains what to change when Run fails
+	Fix func(err error) string
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string
+	Detail string
+	Err    error
+	Fix    string
+}
+
+// OK reports whether the check passed
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Run runs the checks concurrently and returns their results in order
+func Run(ctx context.Context, checks []Check) []Result {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			detail, err := c.Run(ctx)
+			results[i] = Result{Name: c.Name, Detail: detail, Err: err}
+			if err != nil {
+				results[i].Fix = c.Fix(err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// Failed returns the results of the checks that failed
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if !r.OK() {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Misconfigured reports whether err looks like missing setup rather than
+// a passing fault: a table or state machine that doesn't exist, or a role
+// without the access it needs
+func Misconfigured(err error) bool {
+	if kind, _ := diagnose.Classify(err); kind == diagnose.KindAccessDenied {
+		return true
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ResourceNotFoundException", "StateMachineDoesNotExist":
+		return true
+	}
+	return false
+}
+
+// TableAPI describes DynamoDB tables
+type TableAPI interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// STSAPI identifies the caller
+type STSAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// StateMachineAPI describes Step Functions state machines
+type StateMachineAPI interface {
+	DescribeStateMachine(ctx context.Context, params *sfn.DescribeStateMachineInput, optFns ...func(*sfn.Options)) (*sfn.DescribeStateMachineOutput, error)
+}
+
+// ModelAPI sends a message to the configured model
+type ModelAPI interface {
+	SendMessage(ctx context.Context, messages []models.Message, systemPrompt string) (string, error)
+}
+
+// SlackAPI verifies the bot token
+type SlackAPI interface {
+	AuthTest(ctx context.Context) (*slack.AuthTestResponse, error)
+}
+
+// Clients are the APIs the checks call
+type Clients struct {
+	Tables        TableAPI
+	STS           STSAPI
+	StateMachines StateMachineAPI
+	Model         ModelAPI
+	Slack         SlackAPI
+}
+
+// NewClients creates the AWS clients the checks call
+func NewClients(awsCfg aws.Config, model ModelAPI, slackAPI SlackAPI) Clients {
+	return Clients{
+		Tables:        dynamodb.NewFromConfig(awsCfg),
+		STS:           sts.NewFromConfig(awsCfg),
+		StateMachines: sfn.NewFromConfig(awsCfg),
+		Model:         model,
+		Slack:         slackAPI,
+	}
+}
+
+// Checks returns the self-check suite for a configuration. Optional tables
+// are only checked when configured, and the state machine only when the
+// bot runs behind the Slack handler Lambda
+func Checks(cfg *appconfig.Config, c Clients) []Check {
+	checks := []Check{
+		Credentials(c.STS),
+		Slack(c.Slack),
+		Model(c.Model, cfg.BedrockModelID, cfg.AWSRegion),
+	}
+	if cfg.StepFunctionArn != "" {
+		checks = append(checks, StateMachine(c.StateMachines, cfg.StepFunctionArn))
+	}
+
+	tables := []struct{ env, name string }{
+		{"CONVERSATIONS_TABLE", cfg.ConversationsTable},
+		{"CONVERSATION_HISTORY_TABLE", cfg.ConversationHistoryTable},
+		{"SETTINGS_TABLE", cfg.SettingsTable},
+		{"PERMISSIONS_TABLE", cfg.PermissionsTable},
+		{"AUDIT_TABLE", cfg.AuditTable},
+		{"APPROVALS_TABLE", cfg.ApprovalsTable},
+		{"TAGS_TABLE", cfg.TagsTable},
+		{"SUBSCRIPTIONS_TABLE", cfg.SubscriptionsTable},
+		{"SLA_TABLE", cfg.SLATable},
+		{"ANNOUNCEMENTS_TABLE", cfg.AnnouncementsTable},
+		{"ALERTS_TABLE", cfg.AlertsTable},
+		{"RUNBOOKS_TABLE", cfg.RunbooksTable},
+		{"USAGE_TABLE", cfg.UsageTable},
+		{"LOCKS_TABLE", cfg.LocksTable},
+		{"TOOL_AUDIT_TABLE", cfg.ToolAuditTable},
+		{"JOBS_TABLE", cfg.JobsTable},
+	}
+	for _, t := range tables {
+		if t.name != "" {
+			checks = append(checks, Table(c.Tables, t.env, t.name))
+		}
+	}
+	return checks
+}
+
+// Credentials checks the bot has AWS credentials and reports whose they are
+func Credentials(api STSAPI) Check {
+	return Check{
+		Name: "AWS credentials",
+		Run: func(ctx context.Context) (string, error) {
+			out, err := api.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("running as `%s`", aws.ToString(out.Arn)), nil
+		},
+		Fix: func(err error) string {
+			return "The bot has no usable AWS credentials. Run it with the role the CloudFormation stack creates, or set `AWS_PROFILE`/`AWS_REGION` when running locally."
+		},
+	}
+}
+
+// Slack checks the bot token is valid
+func Slack(api SlackAPI) Check {
+	return Check{
+		Name: "Slack bot token",
+		Run: func(ctx context.Context) (string, error) {
+			resp, err := api.AuthTest(ctx)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("signed in to %s as @%s", resp.Team, resp.User), nil
+		},
+		Fix: func(err error) string {
+			return "Slack rejected the bot token. Reinstall the app to the workspace and store the new `xoxb-` token with `./deployments/setup-secrets.sh`."
+		},
+	}
+}
+
+// Model checks the model answers
+func Model(api ModelAPI, modelID, region string) Check {
+	return Check{
+		Name: "Bedrock model access",
+		Run: func(ctx context.Context) (string, error) {
+			_, err := api.SendMessage(ctx, []models.Message{{Role: models.RoleUser, Content: "Reply with OK."}}, "")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("`%s` answered", modelID), nil
+		},
+		Fix: func(err error) string {
+			if d, ok := denial(err); ok {
+				return fmt.Sprintf("The bot's role can't invoke the model: %s. Allow `bedrock:InvokeModel` on `%s`, and request access to it under Bedrock → Model access in %s.", d, modelID, region)
+			}
+			if code := errorCode(err); code == "ResourceNotFoundException" || code == "ValidationException" {
+				return fmt.Sprintf("Bedrock doesn't recognize `%s` in %s. Set `BEDROCK_MODEL_ID` to a model available in the region.", modelID, region)
+			}
+			return fmt.Sprintf("Calling `%s` failed. Check that model access is granted under Bedrock → Model access in %s.", modelID, region)
+		},
+	}
+}
+
+// StateMachine checks the conversation state machine exists
+func StateMachine(api StateMachineAPI, arn string) Check {
+	return Check{
+		Name: "Conversation state machine",
+		Run: func(ctx context.Context) (string, error) {
+			out, err := api.DescribeStateMachine(ctx, &sfn.DescribeStateMachineInput{StateMachineArn: aws.String(arn)})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("`%s` is %s", aws.ToString(out.Name), strings.ToLower(string(out.Status))), nil
+		},
+		Fix: func(err error) string {
+			if d, ok := denial(err); ok {
+				return fmt.Sprintf("The bot's role can't read the state machine: %s. Allow `states:DescribeStateMachine` and `states:StartExecution` on `%s`.", d, arn)
+			}
+			return fmt.Sprintf("`STEP_FUNCTION_ARN` points at `%s`, which doesn't exist. Set it to the `StateMachineArn` stack output.", arn)
+		},
+	}
+}
+
+// Table checks a DynamoDB table exists and is active
+func Table(api TableAPI, envVar, name string) Check {
+	return Check{
+		Name: fmt.Sprintf("Table `%s`", name),
+		Run: func(ctx context.Context) (string, error) {
+			out, err := api.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+			if err != nil {
+				return "", err
+			}
+			status := string(out.Table.TableStatus)
+			if status != "ACTIVE" {
+				return "", fmt.Errorf("table is %s", strings.ToLower(status))
+			}
+			return "active", nil
+		},
+		Fix: func(err error) string {
+			if d, ok := denial(err); ok {
+				return fmt.Sprintf("The bot's role can't read `%s`: %s. Allow `dynamodb:DescribeTable` and the item operations the bot uses on it.", name, d)
+			}
+			if errorCode(err) == "ResourceNotFoundException" {
+				return fmt.Sprintf("`%s` doesn't exist. Deploy the CloudFormation stack, or set `%s` to the table it created.", name, envVar)
+			}
+			return fmt.Sprintf("`%s` isn't usable yet (%v). Wait for it to become active, then check again.", name, err)
+		},
+	}
+}
+
+// denial describes an access denied error, naming the refused action when
+// the message does
+func denial(err error) (string, bool) {
+	kind, code := diagnose.Classify(err)
+	if kind != diagnose.KindAccessDenied {
+		return "", false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if d, ok := diagnose.ParseDenial(apiErr.ErrorMessage()); ok {
+			return fmt.Sprintf("`%s` was denied", d.Action), true
+		}
+	}
+	return code, true
+}
+
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/slack-go/slack"
+)
+
+// apiError wraps an AWS error the way the SDK returns it from an operation
+func apiError(service, op, code, message string) error {
+	return &smithy.OperationError{
+		ServiceID:     service,
+		OperationName: op,
+		Err:           &smithy.GenericAPIError{Code: code, Message: message},
+	}
+}
+
+type fakeTables map[string]error
+
+func (f fakeTables) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if err := f[*params.TableName]; err != nil {
+		return nil, err
+	}
+	status := types.TableStatusActive
+	if *params.TableName == "creating" {
+		status = types.TableStatusCreating
+	}
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: status}}, nil
+}
+
+func pass(name string) Check {
+	return Check{
+		Name: name,
+		Run:  func(ctx context.Context) (string, error) { return "fine", nil },
+		Fix:  func(err error) string { return "" },
+	}
+}
+
+func fail(name, fix string) Check {
+	return Check{
+		Name: name,
+		Run:  func(ctx context.Context) (string, error) { return "", errors.New("broken") },
+		Fix:  func(err error) string { return fix },
+	}
+}
+
+func TestRun(t *testing.T) {
+	results := Run(context.Background(), []Check{pass("a"), fail("b", "fix b"), pass("c")})
+
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,b,c" {
+		t.Errorf("Run() order = %s, want a,b,c", got)
+	}
+	if !results[0].OK() || results[0].Detail != "fine" {
+		t.Errorf("results[0] = %+v, want a pass with its detail", results[0])
+	}
+	if results[1].OK() || results[1].Fix != "fix b" {
+		t.Errorf("results[1] = %+v, want a failure with its fix", results[1])
+	}
+	if failed := Failed(results); len(failed) != 1 || failed[0].Name != "b" {
+		t.Errorf("Failed() = %+v, want only b", failed)
+	}
+}
+
+func TestMisconfigured(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{apiError("DynamoDB", "GetItem", "ResourceNotFoundException", "Requested resource not found"), true},
+		{apiError("SFN", "StartExecution", "AccessDeniedException", ""), true},
+		{apiError("SFN", "StartExecution", "StateMachineDoesNotExist", ""), true},
+		{fmt.Errorf("start step function: %w", apiError("SFN", "StartExecution", "AccessDeniedException", "")), true},
+		{apiError("DynamoDB", "GetItem", "ProvisionedThroughputExceededException", ""), false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := Misconfigured(tt.err); got != tt.want {
+			t.Errorf("Misconfigured(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	api := fakeTables{
+		"missing": apiError("DynamoDB", "DescribeTable", "ResourceNotFoundException", "Requested resource not found"),
+		"denied": apiError("DynamoDB", "DescribeTable", "AccessDeniedException",
+			"User: arn:aws:sts::123456789012:assumed-role/cloudops-lambda-role/fn is not authorized to perform: dynamodb:DescribeTable on resource: arn:aws:dynamodb:us-east-1:123456789012:table/denied"),
+	}
+
+	tests := []struct {
+		table   string
+		wantOK  bool
+		wantFix string
+	}{
+		{"present", true, ""},
+		{"missing", false, "set `SETTINGS_TABLE`"},
+		{"denied", false, "`dynamodb:DescribeTable` was denied"},
+		{"creating", false, "Wait for it to become active"},
+	}
+	for _, tt := range tests {
+		results := Run(context.Background(), []Check{Table(api, "SETTINGS_TABLE", tt.table)})
+		r := results[0]
+		if r.OK() != tt.wantOK {
+			t.Errorf("%s: OK() = %v, want %v", tt.table, r.OK(), tt.wantOK)
+		}
+		if !strings.Contains(r.Fix, tt.wantFix) {
+			t.Errorf("%s: Fix = %q, want it to contain %q", tt.table, r.Fix, tt.wantFix)
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	failing := Blocks(Run(context.Background(), []Check{pass("a"), fail("b", "fix b")}))
+	if !hasRecheck(failing) {
+		t.Error("Blocks() with failures has no Check again button")
+	}
+
+	passing := Blocks(Run(context.Background(), []Check{pass("a")}))
+	if hasRecheck(passing) {
+		t.Error("Blocks() with everything passing still has a Check again button")
+	}
+}
+
+func hasRecheck(blocks []slack.Block) bool {
+	for _, b := range blocks {
+		if a, ok := b.(*slack.ActionBlock); ok && a.BlockID == BlockID {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeStore struct {
+	completedBy string
+	claimed     bool
+}
+
+func (f *fakeStore) CompleteSetup(ctx context.Context, userID string) error {
+	f.completedBy = userID
+	return nil
+}
+
+func (f *fakeStore) ClaimSetupPrompt(ctx context.Context, interval time.Duration) (bool, error) {
+	if f.claimed {
+		return false, nil
+	}
+	f.claimed = true
+	return true, nil
+}
+
+type fakePoster struct {
+	posted  []string
+	updated []string
+}
+
+func (f *fakePoster) OpenDM(ctx context.Context, userID string) (string, error) {
+	return "D-" + userID, nil
+}
+
+func (f *fakePoster) PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error) {
+	f.posted = append(f.posted, channelID)
+	return "1700000000.000100", nil
+}
+
+func (f *fakePoster) UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error {
+	f.updated = append(f.updated, channelID+"/"+ts)
+	return nil
+}
+
+func TestWizardStart(t *testing.T) {
+	store := &fakeStore{}
+	poster := &fakePoster{}
+
+	if _, err := NewWizard([]Check{pass("a"), fail("b", "fix b")}, store, poster).Start(context.Background(), "U1"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(poster.posted) != 1 || poster.posted[0] != "D-U1" {
+		t.Errorf("posted = %v, want the checklist in U1's DM", poster.posted)
+	}
+	if store.completedBy != "" {
+		t.Errorf("setup completed by %q with a failing check", store.completedBy)
+	}
+
+	if _, err := NewWizard([]Check{pass("a")}, store, poster).Recheck(context.Background(), "D-U1", "1700000000.000100", "U1"); err != nil {
+		t.Fatalf("Recheck() error = %v", err)
+	}
+	if len(poster.updated) != 1 || poster.updated[0] != "D-U1/1700000000.000100" {
+		t.Errorf("updated = %v, want the checklist replaced in place", poster.updated)
+	}
+	if store.completedBy != "U1" {
+		t.Errorf("completedBy = %q, want U1", store.completedBy)
+	}
+}
+
+func TestWizardPrompt(t *testing.T) {
+	store := &fakeStore{}
+	poster := &fakePoster{}
+	w := NewWizard([]Check{fail("b", "fix b")}, store, poster)
+
+	for i := 0; i < 2; i++ {
+		if err := w.Prompt(context.Background(), []string{"U1", "U2"}); err != nil {
+			t.Fatalf("Prompt() error = %v", err)
+		}
+	}
+	if got := strings.Join(poster.posted, ","); got != "D-U1,D-U2" {
+		t.Errorf("posted = %s, want each admin prompted once", got)
+	}
+}
@@ -0,0 +1,156 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/slack-go/slack"
+)
+
+// Action and block IDs for the checklist's button
+const (
+	RecheckAction = "setup_recheck"
+	BlockID       = "setup_actions"
+)
+
+// PromptInterval is how often admins are prompted about missing
+// configuration at most
+const PromptInterval = time.Hour
+
+// Store records setup progress
+type Store interface {
+	CompleteSetup(ctx context.Context, userID string) error
+	ClaimSetupPrompt(ctx context.Context, interval time.Duration) (bool, error)
+}
+
+// Poster sends the checklist to an admin
+type Poster interface {
+	OpenDM(ctx context.Context, userID string) (string, error)
+	PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error)
+	UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error
+}
+
+// Wizard walks an admin through the self-check suite in a DM. Each run
+// posts what passed and, for each failure, exactly what to fix; the admin
+// fixes them and checks again until everything passes
+type Wizard struct {
+	checks []Check
+	store  Store
+	poster Poster
+}
+
+// NewWizard creates a wizard running checks
+func NewWizard(checks []Check, store Store, poster Poster) *Wizard {
+	return &Wizard{checks: checks, store: store, poster: poster}
+}
+
+// Start runs the checks and DMs the checklist to userID
+func (w *Wizard) Start(ctx context.Context, userID string) ([]Result, error) {
+	channelID, err := w.poster.OpenDM(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("open dm: %w", err)
+	}
+
+	results := Run(ctx, w.checks)
+	if _, err := w.poster.PostMessage(ctx, channelID,
+		slack.MsgOptionText(Summary(results), false),
+		slack.MsgOptionBlocks(Blocks(results)...),
+	); err != nil {
+		return nil, fmt.Errorf("post checklist: %w", err)
+	}
+	w.complete(ctx, userID, results)
+	return results, nil
+}
+
+// Recheck runs the checks again and replaces the checklist in message ts
+func (w *Wizard) Recheck(ctx context.Context, channelID, ts, userID string) ([]Result, error) {
+	results := Run(ctx, w.checks)
+	if err := w.poster.UpdateMessage(ctx, channelID, ts,
+		slack.MsgOptionText(Summary(results), false),
+		slack.MsgOptionBlocks(Blocks(results)...),
+	); err != nil {
+		return nil, fmt.Errorf("update checklist: %w", err)
+	}
+	w.complete(ctx, userID, results)
+	return results, nil
+}
+
+// Prompt DMs the checklist to each admin after the bot hit missing
+// configuration, at most once per PromptInterval
+func (w *Wizard) Prompt(ctx context.Context, admins []string) error {
+	if len(admins) == 0 {
+		return nil
+	}
+	claimed, err := w.store.ClaimSetupPrompt(ctx, PromptInterval)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	for _, userID := range admins {
+		if _, err := w.Start(ctx, userID); err != nil {
+			slog.WarnContext(ctx, "failed to send setup checklist", logging.UserID, userID, "error", err)
+		}
+	}
+	return nil
+}
+
+// complete records setup as done once every check passes
+func (w *Wizard) complete(ctx context.Context, userID string, results []Result) {
+	if len(Failed(results)) > 0 {
+		return
+	}
+	if err := w.store.CompleteSetup(ctx, userID); err != nil {
+		slog.WarnContext(ctx, "failed to record setup as complete", "error", err)
+	}
+}
+
+// Summary is the checklist's notification text
+func Summary(results []Result) string {
+	failed := len(Failed(results))
+	if failed == 0 {
+		return fmt.Sprintf("✅ CloudOps setup: all %d checks pass.", len(results))
+	}
+	return fmt.Sprintf("🧭 CloudOps setup: %d of %d checks need attention.", failed, len(results))
+}
+
+// Blocks renders the checklist: the failures as numbered steps with their
+// fixes, then what passed, then a button to check again
+func Blocks(results []Result) []slack.Block {
+	text := func(s string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+	}
+
+	blocks := []slack.Block{slack.NewSectionBlock(text("*"+Summary(results)+"*"), nil, nil)}
+
+	failed := Failed(results)
+	for i, r := range failed {
+		blocks = append(blocks, slack.NewSectionBlock(
+			text(fmt.Sprintf("*%d. ❌ %s*\n%s\n_Error: %v_", i+1, r.Name, r.Fix, r.Err)), nil, nil))
+	}
+
+	var passed []string
+	for _, r := range results {
+		if r.OK() {
+			passed = append(passed, fmt.Sprintf("✅ %s: %s", r.Name, r.Detail))
+		}
+	}
+	if len(passed) > 0 {
+		blocks = append(blocks, slack.NewContextBlock("", text(strings.Join(passed, "\n"))))
+	}
+
+	if len(failed) == 0 {
+		blocks = append(blocks, slack.NewSectionBlock(
+			text("Mention me in a channel to start a conversation, or run `/cloudops setup` again any time."), nil, nil))
+		return blocks
+	}
+	button := slack.NewButtonBlockElement(RecheckAction, "",
+		slack.NewTextBlockObject(slack.PlainTextType, "🔁 Check again", true, false))
+	return append(blocks, slack.NewActionBlock(BlockID, button))
+}
@@ -0,0 +1,113 @@
+package similarity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// maxMatches caps how many similar incidents are linked
+const maxMatches = 3
+
+// Cosine returns the cosine similarity of two vectors, or 0 when they
+// differ in length or either is zero
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Match is an earlier conversation similar to a new one
+type Match struct {
+	Conversation *models.Conversation
+	Score        float64
+}
+
+// Nearest returns the conversations whose embeddings score at least
+// threshold against query, most similar first. The conversation itself and
+// conversations without an embedding are skipped
+func Nearest(query []float32, selfID string, candidates []*models.Conversation, threshold float64) []Match {
+	var matches []Match
+	for _, c := range candidates {
+		if c.ConversationID == selfID || len(c.Embedding) == 0 {
+			continue
+		}
+		if score := Cosine(query, c.Embedding); score >= threshold {
+			matches = append(matches, Match{Conversation: c, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+	return matches
+}
+
+// Notice tells the channel about similar earlier incidents, e.g. "This looks
+// like conv-123 in #ops from Tuesday"
+func Notice(matches []Match, now time.Time, loc *time.Location) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var b strings.Builder
+	best := matches[0]
+	fmt.Fprintf(&b, "🔁 This looks like %s — %.0f%% similar.", describe(best.Conversation, now, loc), best.Score*100)
+	for _, m := range matches[1:] {
+		fmt.Fprintf(&b, "\n• Also similar: %s (%.0f%%)", describe(m.Conversation, now, loc), m.Score*100)
+	}
+	b.WriteString("\nCheck what was found there before digging in; I'm starting a fresh investigation below.")
+	return b.String()
+}
+
+// describe names a conversation with its channel, when it happened, and how
+// it started
+func describe(c *models.Conversation, now time.Time, loc *time.Location) string {
+	return fmt.Sprintf("`%s` in <#%s> from %s: _%s_", c.ConversationID, c.ChannelID, when(c.CreatedAt, now, loc), snippet(c.InitialCommand, 80))
+}
+
+// when describes a past time as "earlier today", "yesterday", a weekday
+// within the last week, or a date
+func when(t, now time.Time, loc *time.Location) string {
+	t, now = t.In(loc), now.In(loc)
+	day := func(x time.Time) time.Time { return time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, loc) }
+
+	switch days := int(day(now).Sub(day(t)).Hours() / 24); {
+	case days <= 0:
+		return "earlier today"
+	case days == 1:
+		return "yesterday"
+	case days < 7:
+		return t.Weekday().String()
+	default:
+		return t.Format("Jan 2")
+	}
+}
+
+func snippet(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
@@ -0,0 +1,86 @@
+package similarity
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 1}, []float32{-1, -1}, -1},
+		{[]float32{1, 2}, []float32{1}, 0},
+		{[]float32{0, 0}, []float32{1, 1}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cosine(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func conv(id string, embedding ...float32) *models.Conversation {
+	return &models.Conversation{ConversationID: id, ChannelID: "C1", InitialCommand: "checkout is slow", Embedding: embedding}
+}
+
+func TestNearest(t *testing.T) {
+	candidates := []*models.Conversation{
+		conv("self", 1, 0),
+		conv("close", 0.9, 0.1),
+		conv("exact", 1, 0),
+		conv("far", 0, 1),
+		conv("none"),
+	}
+
+	got := Nearest([]float32{1, 0}, "self", candidates, 0.8)
+	if len(got) != 2 || got[0].Conversation.ConversationID != "exact" || got[1].Conversation.ConversationID != "close" {
+		t.Errorf("Nearest() = %+v, want exact then close", got)
+	}
+}
+
+func TestNotice(t *testing.T) {
+	now := time.Date(2024, 5, 9, 15, 0, 0, 0, time.UTC) // Thursday
+	earlier := conv("conv-1")
+	earlier.CreatedAt = time.Date(2024, 5, 7, 10, 0, 0, 0, time.UTC)
+	older := conv("conv-2")
+	older.CreatedAt = time.Date(2024, 4, 20, 10, 0, 0, 0, time.UTC)
+
+	notice := Notice([]Match{{earlier, 0.93}, {older, 0.88}}, now, nil)
+	for _, want := range []string{"`conv-1` in <#C1> from Tuesday", "93% similar", "from Apr 20", "_checkout is slow_"} {
+		if !strings.Contains(notice, want) {
+			t.Errorf("Notice() missing %q:\n%s", want, notice)
+		}
+	}
+
+	if Notice(nil, now, nil) != "" {
+		t.Error("Notice(nil) should be empty")
+	}
+}
+
+func TestWhen(t *testing.T) {
+	now := time.Date(2024, 5, 9, 1, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-30 * time.Minute), "earlier today"},
+		{now.Add(-2 * time.Hour), "yesterday"},
+		{now.Add(-3 * 24 * time.Hour), "Monday"},
+		{now.Add(-10 * 24 * time.Hour), "Apr 29"},
+	}
+
+	for _, tt := range tests {
+		if got := when(tt.t, now, time.UTC); got != tt.want {
+			t.Errorf("when(%v) = %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package sla
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Reminder kinds
+const (
+	KindAck     = "ack"
+	KindResolve = "resolve"
+)
+
+// Reminder stages, as a fraction of the SLA window elapsed
+const (
+	StageWarning  = "warning"  // 50%
+	StageUrgent   = "urgent"   // 80%
+	StageBreached = "breached" // 100%
+)
+
+var stages = []struct {
+	name     string
+	fraction float64
+}{
+	{StageWarning, 0.5},
+	{StageUrgent, 0.8},
+	{StageBreached, 1.0},
+}
+
+// Policy is the acknowledgment and resolution target for a severity
+type Policy struct {
+	Ack     time.Duration
+	Resolve time.Duration
+}
+
+// Policies maps severity tags (sev1...) to their SLA targets
+type Policies map[string]Policy
+
+// DefaultPolicies returns the built-in SLA targets
+func DefaultPolicies() Policies {
+	return Policies{
+		"sev1": {Ack: 5 * time.Minute, Resolve: time.Hour},
+		"sev2": {Ack: 15 * time.Minute, Resolve: 4 * time.Hour},
+		"sev3": {Ack: time.Hour, Resolve: 24 * time.Hour},
+		"sev4": {Ack: 4 * time.Hour, Resolve: 72 * time.Hour},
+	}
+}
+
+// ParsePolicies parses "sev1=5m/1h,sev2=15m/4h" over the defaults
+func ParsePolicies(s string) (Policies, error) {
+	policies := DefaultPolicies()
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		severity, targets, ok := strings.Cut(entry, "=")
+		ackText, resolveText, ok2 := strings.Cut(targets, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid SLA policy %q, want sevN=ack/resolve", entry)
+		}
+
+		ack, err := time.ParseDuration(strings.TrimSpace(ackText))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ack target in %q: %w", entry, err)
+		}
+		resolve, err := time.ParseDuration(strings.TrimSpace(resolveText))
+		if err != nil {
+			return nil, fmt.Errorf("invalid resolve target in %q: %w", entry, err)
+		}
+		if ack <= 0 || resolve < ack {
+			return nil, fmt.Errorf("invalid SLA policy %q: resolve must be at least ack", entry)
+		}
+
+		policies[strings.ToLower(strings.TrimSpace(severity))] = Policy{Ack: ack, Resolve: resolve}
+	}
+	return policies, nil
+}
+
+// Severity returns the most severe tag with a policy (sev1 beats sev2)
+func (p Policies) Severity(tags []string) (string, bool) {
+	var matches []string
+	for _, tag := range tags {
+		if _, ok := p[tag]; ok {
+			matches = append(matches, tag)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Strings(matches)
+	return matches[0], true
+}
+
+// Start begins SLA timers for an incident, or re-targets running timers
+// when the severity changes. It reports whether anything changed
+func (p Policies) Start(conv *models.Conversation, severity string, now time.Time) bool {
+	policy, ok := p[severity]
+	if !ok {
+		return false
+	}
+
+	s := conv.SLA
+	if s == nil {
+		s = &models.SLA{StartedAt: now}
+		conv.SLA = s
+	} else if s.Severity == severity || !s.IsOpen() {
+		return false
+	}
+
+	s.Severity = severity
+	s.AckDue = s.StartedAt.Add(policy.Ack)
+	s.ResolveDue = s.StartedAt.Add(policy.Resolve)
+	return true
+}
+
+// Ack records the first acknowledgment
+func Ack(s *models.SLA, userID string, now time.Time) bool {
+	if s == nil || s.AckedAt != nil {
+		return false
+	}
+	s.AckedAt = &now
+	s.AckedBy = userID
+	s.AckBreached = now.After(s.AckDue)
+	return true
+}
+
+// Resolve stops the timers, acknowledging first if needed
+func Resolve(s *models.SLA, userID string, now time.Time) bool {
+	if !s.IsOpen() {
+		return false
+	}
+	Ack(s, userID, now)
+	s.ResolvedAt = &now
+	s.ResolveBreached = now.After(s.ResolveDue)
+	return true
+}
+
+// Reminder is an escalation that has come due
+type Reminder struct {
+	Kind     string
+	Stage    string
+	Deadline time.Time
+}
+
+// Key identifies the reminder so it is only sent once
+func (r Reminder) Key() string {
+	return r.Kind + ":" + r.Stage
+}
+
+// Due returns reminders that have come due and not been sent, most urgent
+// last. Only the latest stage per timer is returned so a monitor that was
+// down doesn't send a burst of stale warnings
+func Due(s *models.SLA, now time.Time) []Reminder {
+	if !s.IsOpen() {
+		return nil
+	}
+
+	var due []Reminder
+	if s.AckedAt == nil {
+		if r, ok := latestStage(s, KindAck, s.StartedAt, s.AckDue, now); ok {
+			due = append(due, r)
+		}
+	}
+	if r, ok := latestStage(s, KindResolve, s.StartedAt, s.ResolveDue, now); ok {
+		due = append(due, r)
+	}
+	return due
+}
+
+func latestStage(s *models.SLA, kind string, start, deadline, now time.Time) (Reminder, bool) {
+	window := deadline.Sub(start)
+	elapsed := now.Sub(start)
+
+	var found Reminder
+	ok := false
+	for _, stage := range stages {
+		if float64(elapsed) < float64(window)*stage.fraction {
+			break
+		}
+		found = Reminder{Kind: kind, Stage: stage.name, Deadline: deadline}
+		ok = true
+	}
+	if !ok || sent(s, found.Key()) {
+		return Reminder{}, false
+	}
+	return found, true
+}
+
+// MarkSent records a reminder, and a breach when the deadline has passed
+func MarkSent(s *models.SLA, r Reminder) {
+	for _, stage := range stages {
+		key := r.Kind + ":" + stage.name
+		if !sent(s, key) {
+			s.RemindersSent = append(s.RemindersSent, key)
+		}
+		if stage.name == r.Stage {
+			break
+		}
+	}
+	if r.Stage == StageBreached {
+		if r.Kind == KindAck {
+			s.AckBreached = true
+		} else {
+			s.ResolveBreached = true
+		}
+	}
+}
+
+func sent(s *models.SLA, key string) bool {
+	for _, k := range s.RemindersSent {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Message formats a reminder for the incident channel
+func (r Reminder) Message(s *models.SLA, now time.Time) string {
+	action := "acknowledged"
+	if r.Kind == KindResolve {
+		action = "resolved"
+	}
+
+	switch r.Stage {
+	case StageBreached:
+		return fmt.Sprintf("🚨 <!here> *%s %s SLA breached*: this incident was due to be %s %s. Run `/cloudops %s` once it is.",
+			strings.ToUpper(s.Severity), r.Kind, action, humanize.Relative(r.Deadline, now, nil), r.Kind)
+	case StageUrgent:
+		return fmt.Sprintf("⏰ *%s %s SLA*: this incident must be %s %s.",
+			strings.ToUpper(s.Severity), r.Kind, action, humanize.Relative(r.Deadline, now, nil))
+	default:
+		return fmt.Sprintf("⏳ %s %s SLA: this incident needs to be %s by %s UTC.",
+			strings.ToUpper(s.Severity), r.Kind, action, r.Deadline.UTC().Format("15:04"))
+	}
+}
+
+// Outcome summarizes a resolved incident's SLA for analytics
+func Outcome(conv *models.Conversation) (*models.SLAOutcome, bool) {
+	s := conv.SLA
+	if s == nil || s.ResolvedAt == nil || s.AckedAt == nil {
+		return nil, false
+	}
+
+	return &models.SLAOutcome{
+		ConversationID:       conv.ConversationID,
+		Severity:             s.Severity,
+		Tags:                 conv.Tags,
+		StartedAt:            s.StartedAt,
+		ResolvedAt:           *s.ResolvedAt,
+		AckedBy:              s.AckedBy,
+		TimeToAckSeconds:     int64(s.AckedAt.Sub(s.StartedAt).Seconds()),
+		TimeToResolveSeconds: int64(s.ResolvedAt.Sub(s.StartedAt).Seconds()),
+		AckBreached:          s.AckBreached,
+		ResolveBreached:      s.ResolveBreached,
+	}, true
+}
+
+// Longest returns the longest resolution target, bounding how far back the
+// monitor needs to look for open incidents
+func (p Policies) Longest() time.Duration {
+	var longest time.Duration
+	for _, policy := range p {
+		if policy.Resolve > longest {
+			longest = policy.Resolve
+		}
+	}
+	return longest
+}
@@ -0,0 +1,151 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("sev1=10m/2h, SEV5=1d/1d")
+	if err == nil {
+		t.Fatalf("ParsePolicies() should reject unparseable durations, got %v", policies)
+	}
+
+	policies, err = ParsePolicies("sev1=10m/2h, sev5=24h/48h")
+	if err != nil {
+		t.Fatalf("ParsePolicies() error = %v", err)
+	}
+	if got := policies["sev1"]; got.Ack != 10*time.Minute || got.Resolve != 2*time.Hour {
+		t.Errorf("sev1 = %+v, want 10m/2h", got)
+	}
+	if got := policies["sev2"]; got.Ack != 15*time.Minute {
+		t.Errorf("sev2 = %+v, want default", got)
+	}
+	if _, ok := policies["sev5"]; !ok {
+		t.Error("sev5 should be added")
+	}
+
+	for _, bad := range []string{"sev1", "sev1=5m", "sev1=1h/5m", "sev1=0s/1h"} {
+		if _, err := ParsePolicies(bad); err == nil {
+			t.Errorf("ParsePolicies(%q) should error", bad)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	policies := DefaultPolicies()
+
+	tests := []struct {
+		tags []string
+		want string
+		ok   bool
+	}{
+		{[]string{"payments", "sev3"}, "sev3", true},
+		{[]string{"sev3", "sev1"}, "sev1", true},
+		{[]string{"payments"}, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := policies.Severity(tt.tags)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Severity(%v) = %s, %v, want %s, %v", tt.tags, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	policies := DefaultPolicies()
+	conv := &models.Conversation{ConversationID: "conv-1"}
+
+	if !policies.Start(conv, "sev2", start) {
+		t.Fatal("Start() should start timers")
+	}
+	if !conv.SLA.AckDue.Equal(start.Add(15*time.Minute)) || !conv.SLA.ResolveDue.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("deadlines = %v / %v", conv.SLA.AckDue, conv.SLA.ResolveDue)
+	}
+
+	if policies.Start(conv, "sev2", start.Add(time.Minute)) {
+		t.Error("Start() with the same severity should be a no-op")
+	}
+
+	// Escalating keeps the original start time
+	if !policies.Start(conv, "sev1", start.Add(time.Minute)) {
+		t.Fatal("Start() should re-target on severity change")
+	}
+	if !conv.SLA.AckDue.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("AckDue = %v, want %v", conv.SLA.AckDue, start.Add(5*time.Minute))
+	}
+}
+
+func TestDue(t *testing.T) {
+	conv := &models.Conversation{}
+	DefaultPolicies().Start(conv, "sev1", start) // ack 5m, resolve 1h
+	s := conv.SLA
+
+	keys := func(rs []Reminder) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.Key())
+		}
+		return out
+	}
+
+	if got := Due(s, start.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("Due(1m) = %v, want none", keys(got))
+	}
+
+	got := Due(s, start.Add(3*time.Minute))
+	if len(got) != 1 || got[0].Key() != "ack:warning" {
+		t.Fatalf("Due(3m) = %v, want [ack:warning]", keys(got))
+	}
+	MarkSent(s, got[0])
+	if got := Due(s, start.Add(3*time.Minute)); len(got) != 0 {
+		t.Errorf("Due() after MarkSent = %v, want none", keys(got))
+	}
+
+	// A late check skips straight to the breach
+	got = Due(s, start.Add(6*time.Minute))
+	if len(got) != 1 || got[0].Key() != "ack:breached" {
+		t.Fatalf("Due(6m) = %v, want [ack:breached]", keys(got))
+	}
+	MarkSent(s, got[0])
+	if !s.AckBreached {
+		t.Error("AckBreached should be set")
+	}
+	if got := Due(s, start.Add(7*time.Minute)); len(got) != 0 {
+		t.Errorf("Due() after breach = %v, want none", keys(got))
+	}
+
+	Ack(s, "U1", start.Add(8*time.Minute))
+	got = Due(s, start.Add(50*time.Minute))
+	if len(got) != 1 || got[0].Key() != "resolve:urgent" {
+		t.Fatalf("Due(50m) = %v, want [resolve:urgent]", keys(got))
+	}
+
+	Resolve(s, "U1", start.Add(55*time.Minute))
+	if got := Due(s, start.Add(2*time.Hour)); len(got) != 0 {
+		t.Errorf("Due() after resolve = %v, want none", keys(got))
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	conv := &models.Conversation{ConversationID: "conv-1", Tags: []string{"sev1"}}
+	DefaultPolicies().Start(conv, "sev1", start)
+
+	if _, ok := Outcome(conv); ok {
+		t.Error("Outcome() should be empty while open")
+	}
+
+	Resolve(conv.SLA, "U1", start.Add(90*time.Minute))
+	outcome, ok := Outcome(conv)
+	if !ok {
+		t.Fatal("Outcome() should be set once resolved")
+	}
+	if outcome.TimeToResolveSeconds != 5400 || !outcome.ResolveBreached || !outcome.AckBreached || outcome.AckedBy != "U1" {
+		t.Errorf("Outcome() = %+v", outcome)
+	}
+}
@@ -1,40 +1,126 @@
 package slack
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/savaki/cloudops-bot/pkg/chaos"
+	"github.com/savaki/cloudops-bot/pkg/logging"
 	"github.com/slack-go/slack"
 )
 
 // Client wraps the Slack SDK client for use throughout the application
 type Client struct {
-	client *slack.Client
+	mu       sync.RWMutex
+	client   *slack.Client
+	appToken string
+	faults   *chaos.Injector
+	limits   *Limiter
+	updates  *updateQueue
+
+	// Shadow mode: writes are logged instead of sent
+	shadow    bool
+	shadowSeq atomic.Int64
 }
 
+// maxRateLimitRetries bounds how often a call is retried after a 429
+const maxRateLimitRetries = 3
+
 // NewClient creates a new Slack client with bot token
 func NewClient(botToken string) *Client {
 	return &Client{
-		client: slack.New(botToken),
+		client:  slack.New(botToken),
+		limits:  NewLimiter(DefaultTiers),
+		updates: newUpdateQueue(),
 	}
 }
 
 // NewClientWithAppToken creates a new Slack client with bot token and app token for Socket Mode
 func NewClientWithAppToken(botToken, appToken string) *Client {
 	return &Client{
-		client: slack.New(botToken, slack.OptionAppLevelToken(appToken)),
+		client:   slack.New(botToken, slack.OptionAppLevelToken(appToken)),
+		appToken: appToken,
+		limits:   NewLimiter(DefaultTiers),
+		updates:  newUpdateQueue(),
+	}
+}
+
+// SetToken switches to a new bot token, such as one refreshed by a Rotator
+func (c *Client) SetToken(botToken string) {
+	var options []slack.Option
+	if c.appToken != "" {
+		options = append(options, slack.OptionAppLevelToken(c.appToken))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = slack.New(botToken, options...)
+}
+
+// api returns the SDK client for the current bot token
+func (c *Client) api() *slack.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+// call runs fn once method's rate limit allows it
+func (c *Client) call(ctx context.Context, method, channelID string, fn func() error) error {
+	if err := c.limits.Wait(ctx, method, channelID); err != nil {
+		return err
+	}
+	return c.retry(ctx, method, channelID, fn)
+}
+
+// retry runs fn, waiting out and retrying the delay Slack asks for when it
+// answers 429 anyway, e.g. because other tasks share the workspace limit
+func (c *Client) retry(ctx context.Context, method, channelID string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		var limited *slack.RateLimitedError
+		if !errors.As(err, &limited) || attempt == maxRateLimitRetries {
+			return err
+		}
+
+		slog.WarnContext(ctx, "slack rate limited, retrying", "method", method, logging.ChannelID, channelID, "retry_after", limited.RetryAfter.String())
+		c.limits.Pause(method, channelID, limited.RetryAfter)
+		if err := c.limits.Wait(ctx, method, channelID); err != nil {
+			return err
+		}
 	}
 }
 
+// SetFaultInjector enables artificial latency and errors for Slack calls
+func (c *Client) SetFaultInjector(faults *chaos.Injector) {
+	c.faults = faults
+}
+
 // GetRawClient returns the underlying slack.Client for advanced operations like Socket Mode
 func (c *Client) GetRawClient() *slack.Client {
-	return c.client
+	return c.api()
 }
 
 // PostMessage posts a message to a Slack channel
 func (c *Client) PostMessage(ctx context.Context, channelID string, opts ...slack.MsgOption) (string, error) {
-	_, timestamp, err := c.client.PostMessageContext(ctx, channelID, opts...)
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "PostMessage"); err != nil {
+		return "", err
+	}
+	if c.shadowed("chat.postMessage", channelID, describe(opts)) {
+		return c.shadowTS(), nil
+	}
+
+	var timestamp string
+	err := c.call(ctx, "chat.postMessage", channelID, func() (err error) {
+		_, timestamp, err = c.api().PostMessageContext(ctx, channelID, opts...)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("post message: %w", err)
 	}
@@ -42,13 +128,280 @@ func (c *Client) PostMessage(ctx context.Context, channelID string, opts ...slac
 	return timestamp, nil
 }
 
+// UpdateMessage replaces the content of a message the bot posted. Updates
+// to the same message that queue up behind the rate limit are coalesced,
+// and only the newest content is sent
+func (c *Client) UpdateMessage(ctx context.Context, channelID, ts string, opts ...slack.MsgOption) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "UpdateMessage"); err != nil {
+		return err
+	}
+	if c.shadowed("chat.update", channelID, ts+" -> "+describe(opts)) {
+		return nil
+	}
+
+	err := c.updates.do(channelID+"/"+ts, opts,
+		func() error {
+			return c.limits.Wait(ctx, "chat.update", channelID)
+		},
+		func(payload any) error {
+			opts := payload.([]slack.MsgOption)
+			return c.retry(ctx, "chat.update", channelID, func() error {
+				_, _, _, err := c.api().UpdateMessageContext(ctx, channelID, ts, opts...)
+				return err
+			})
+		})
+	if err != nil {
+		return fmt.Errorf("update message: %w", err)
+	}
+
+	return nil
+}
+
+// Unfurl shows previews of links in a message, keyed by link
+func (c *Client) Unfurl(ctx context.Context, channelID, ts string, unfurls map[string]slack.Attachment) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "Unfurl"); err != nil {
+		return err
+	}
+	if c.shadowed("chat.unfurl", channelID, ts) {
+		return nil
+	}
+
+	err := c.call(ctx, "chat.unfurl", channelID, func() error {
+		_, _, _, err := c.api().UnfurlMessageContext(ctx, channelID, ts, unfurls)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unfurl links: %w", err)
+	}
+
+	return nil
+}
+
+// OpenView opens a modal in response to a slash command or button click
+func (c *Client) OpenView(ctx context.Context, triggerID string, view slack.ModalViewRequest) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "OpenView"); err != nil {
+		return err
+	}
+	if c.shadowed("views.open", "", view.CallbackID) {
+		return nil
+	}
+
+	err := c.call(ctx, "views.open", "", func() error {
+		_, err := c.api().OpenViewContext(ctx, triggerID, view)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("open view: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMessage removes a message the bot posted
+func (c *Client) DeleteMessage(ctx context.Context, channelID, ts string) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "DeleteMessage"); err != nil {
+		return err
+	}
+	if c.shadowed("chat.delete", channelID, ts) {
+		return nil
+	}
+
+	err := c.call(ctx, "chat.delete", channelID, func() error {
+		_, _, err := c.api().DeleteMessageContext(ctx, channelID, ts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("delete message: %w", err)
+	}
+
+	return nil
+}
+
+// GetMessagesSince returns messages posted to a channel after the given
+// timestamp, oldest first
+func (c *Client) GetMessagesSince(ctx context.Context, channelID, oldest string) ([]slack.Message, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetConversationHistory"); err != nil {
+		return nil, err
+	}
+
+	var resp *slack.GetConversationHistoryResponse
+	err := c.call(ctx, "conversations.history", channelID, func() (err error) {
+		resp, err = c.api().GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+			ChannelID:          channelID,
+			Oldest:             oldest,
+			Limit:              100,
+			IncludeAllMetadata: true,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get conversation history: %w", err)
+	}
+
+	// Slack returns newest first
+	messages := resp.Messages
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return messages, nil
+}
+
+// GetRepliesSince returns replies posted to a thread after the given
+// timestamp, oldest first, without the message that started the thread
+func (c *Client) GetRepliesSince(ctx context.Context, channelID, threadTS, oldest string) ([]slack.Message, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetConversationReplies"); err != nil {
+		return nil, err
+	}
+
+	var msgs []slack.Message
+	err := c.call(ctx, "conversations.replies", channelID, func() (err error) {
+		msgs, _, _, err = c.api().GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+			ChannelID:          channelID,
+			Timestamp:          threadTS,
+			Oldest:             oldest,
+			Limit:              100,
+			IncludeAllMetadata: true,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get conversation replies: %w", err)
+	}
+
+	// Slack always includes the parent, whatever the oldest timestamp
+	var replies []slack.Message
+	for _, msg := range msgs {
+		if msg.Timestamp != threadTS {
+			replies = append(replies, msg)
+		}
+	}
+
+	return replies, nil
+}
+
+// InThread posts a message as a reply in the thread rooted at threadTS, or
+// at the top of the channel when threadTS is empty
+func InThread(threadTS string) slack.MsgOption {
+	if threadTS == "" {
+		return slack.MsgOptionCompose()
+	}
+	return slack.MsgOptionTS(threadTS)
+}
+
+// UploadFile uploads file content to a channel, optionally in a thread
+func (c *Client) UploadFile(ctx context.Context, channelID, threadTS, filename, title string, data []byte) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "UploadFile"); err != nil {
+		return err
+	}
+	if c.shadowed("files.uploadV2", channelID, fmt.Sprintf("%s (%q, %d bytes)", filename, title, len(data))) {
+		return nil
+	}
+
+	err := c.call(ctx, "files.uploadV2", channelID, func() error {
+		_, err := c.api().UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+			Channel:         channelID,
+			ThreadTimestamp: threadTS,
+			Filename:        filename,
+			Title:           title,
+			Reader:          bytes.NewReader(data),
+			FileSize:        len(data),
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("upload file: %w", err)
+	}
+
+	return nil
+}
+
+// AddReaction adds an emoji reaction to a message. Adding one the bot has
+// already added is not an error
+func (c *Client) AddReaction(ctx context.Context, channelID, ts, name string) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "AddReaction"); err != nil {
+		return err
+	}
+	if c.shadowed("reactions.add", channelID, ts+" :"+name+":") {
+		return nil
+	}
+
+	err := c.call(ctx, "reactions.add", channelID, func() error {
+		return c.api().AddReactionContext(ctx, name, slack.NewRefToMessage(channelID, ts))
+	})
+	if err != nil && !isSlackError(err, "already_reacted") {
+		return fmt.Errorf("add reaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveReaction removes the bot's emoji reaction from a message. Removing
+// one the bot hasn't added is not an error
+func (c *Client) RemoveReaction(ctx context.Context, channelID, ts, name string) error {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "RemoveReaction"); err != nil {
+		return err
+	}
+	if c.shadowed("reactions.remove", channelID, ts+" :"+name+":") {
+		return nil
+	}
+
+	err := c.call(ctx, "reactions.remove", channelID, func() error {
+		return c.api().RemoveReactionContext(ctx, name, slack.NewRefToMessage(channelID, ts))
+	})
+	if err != nil && !isSlackError(err, "no_reaction") {
+		return fmt.Errorf("remove reaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetReactions returns the names of the reactions on a message
+func (c *Client) GetReactions(ctx context.Context, channelID, ts string) ([]string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetReactions"); err != nil {
+		return nil, err
+	}
+
+	var reactions []slack.ItemReaction
+	err := c.call(ctx, "reactions.get", channelID, func() (err error) {
+		reactions, err = c.api().GetReactionsContext(ctx, slack.NewRefToMessage(channelID, ts), slack.NewGetReactionsParameters())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get reactions: %w", err)
+	}
+
+	names := make([]string, 0, len(reactions))
+	for _, r := range reactions {
+		names = append(names, r.Name)
+	}
+	return names, nil
+}
+
+// isSlackError reports whether err is the Slack API error code
+func isSlackError(err error, code string) bool {
+	var resp slack.SlackErrorResponse
+	return errors.As(err, &resp) && resp.Err == code
+}
+
 // CreateConversation creates a private Slack channel
 func (c *Client) CreateConversation(ctx context.Context, channelName string) (string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "CreateConversation"); err != nil {
+		return "", err
+	}
+	if c.shadowed("conversations.create", "", channelName) {
+		return "shadow-" + channelName, nil
+	}
+
 	params := slack.CreateConversationParams{
 		ChannelName: channelName,
 		IsPrivate:   true,
 	}
-	resp, err := c.client.CreateConversationContext(ctx, params)
+	var resp *slack.Channel
+	err := c.call(ctx, "conversations.create", "", func() (err error) {
+		resp, err = c.api().CreateConversationContext(ctx, params)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("create conversation: %w", err)
 	}
@@ -56,9 +409,37 @@ func (c *Client) CreateConversation(ctx context.Context, channelName string) (st
 	return resp.ID, nil
 }
 
+// OpenDM opens the bot's direct message with a user and returns its channel ID
+func (c *Client) OpenDM(ctx context.Context, userID string) (string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "OpenDM"); err != nil {
+		return "", err
+	}
+
+	var channel *slack.Channel
+	err := c.call(ctx, "conversations.open", "", func() (err error) {
+		channel, _, _, err = c.api().OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("open dm: %w", err)
+	}
+
+	return channel.ID, nil
+}
+
 // InviteUsersToConversation invites users to a channel
 func (c *Client) InviteUsersToConversation(ctx context.Context, channelID string, userIDs ...string) error {
-	_, err := c.client.InviteUsersToConversationContext(ctx, channelID, userIDs...)
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "InviteUsersToConversation"); err != nil {
+		return err
+	}
+	if c.shadowed("conversations.invite", channelID, strings.Join(userIDs, ",")) {
+		return nil
+	}
+
+	err := c.call(ctx, "conversations.invite", channelID, func() error {
+		_, err := c.api().InviteUsersToConversationContext(ctx, channelID, userIDs...)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("invite users: %w", err)
 	}
@@ -68,7 +449,15 @@ func (c *Client) InviteUsersToConversation(ctx context.Context, channelID string
 
 // GetUserInfo gets information about a user
 func (c *Client) GetUserInfo(ctx context.Context, userID string) (*slack.User, error) {
-	user, err := c.client.GetUserInfoContext(ctx, userID)
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetUserInfo"); err != nil {
+		return nil, err
+	}
+
+	var user *slack.User
+	err := c.call(ctx, "users.info", "", func() (err error) {
+		user, err = c.api().GetUserInfoContext(ctx, userID)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get user info: %w", err)
 	}
@@ -76,13 +465,105 @@ func (c *Client) GetUserInfo(ctx context.Context, userID string) (*slack.User, e
 	return user, nil
 }
 
+// LookupUserByEmail finds a Slack user by email address
+func (c *Client) LookupUserByEmail(ctx context.Context, email string) (*slack.User, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetUserByEmail"); err != nil {
+		return nil, err
+	}
+
+	var user *slack.User
+	err := c.call(ctx, "users.lookupByEmail", "", func() (err error) {
+		user, err = c.api().GetUserByEmailContext(ctx, email)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+
+	return user, nil
+}
+
+// UserIDByEmail returns the Slack user ID for an email address
+func (c *Client) UserIDByEmail(ctx context.Context, email string) (string, error) {
+	user, err := c.LookupUserByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// GetUserLocation returns the user's timezone from their Slack profile,
+// falling back to UTC when it is unset or unknown
+func (c *Client) GetUserLocation(ctx context.Context, userID string) *time.Location {
+	user, err := c.GetUserInfo(ctx, userID)
+	if err != nil || user.TZ == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(user.TZ)
+	if err != nil {
+		slog.WarnContext(ctx, "unknown timezone", "tz", user.TZ, logging.UserID, userID)
+		return time.UTC
+	}
+	return loc
+}
+
+// GetProfileField returns the value of a custom profile field, matched by
+// its label, or "" when the user hasn't filled it in
+func (c *Client) GetProfileField(ctx context.Context, userID, label string) (string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetUserProfile"); err != nil {
+		return "", err
+	}
+
+	var profile *slack.UserProfile
+	err := c.call(ctx, "users.profile.get", "", func() (err error) {
+		profile, err = c.api().GetUserProfileContext(ctx, &slack.GetUserProfileParameters{UserID: userID, IncludeLabels: true})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("get user profile: %w", err)
+	}
+
+	for _, field := range profile.Fields.ToMap() {
+		if strings.EqualFold(field.Label, label) {
+			return strings.TrimSpace(field.Value), nil
+		}
+	}
+	return "", nil
+}
+
+// GetUserGroupMembers returns the IDs of the users in a user group
+func (c *Client) GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetUserGroupMembers"); err != nil {
+		return nil, err
+	}
+
+	var members []string
+	err := c.call(ctx, "usergroups.users.list", "", func() (err error) {
+		members, err = c.api().GetUserGroupMembersContext(ctx, groupID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get user group members: %w", err)
+	}
+	return members, nil
+}
+
 // GetChannelInfo gets information about a channel
 func (c *Client) GetChannelInfo(ctx context.Context, channelID string) (*slack.Channel, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetChannelInfo"); err != nil {
+		return nil, err
+	}
+
 	input := &slack.GetConversationInfoInput{
 		ChannelID:     channelID,
 		IncludeLocale: true,
 	}
-	channel, err := c.client.GetConversationInfoContext(ctx, input)
+	var channel *slack.Channel
+	err := c.call(ctx, "conversations.info", channelID, func() (err error) {
+		channel, err = c.api().GetConversationInfoContext(ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get channel info: %w", err)
 	}
@@ -90,9 +571,35 @@ func (c *Client) GetChannelInfo(ctx context.Context, channelID string) (*slack.C
 	return channel, nil
 }
 
+// GetPermalink returns a link to a message
+func (c *Client) GetPermalink(ctx context.Context, channelID, ts string) (string, error) {
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetPermalink"); err != nil {
+		return "", err
+	}
+
+	var link string
+	err := c.call(ctx, "chat.getPermalink", channelID, func() (err error) {
+		link, err = c.api().GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channelID, Ts: ts})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("get permalink: %w", err)
+	}
+
+	return link, nil
+}
+
 // AuthTest verifies the bot token is valid
 func (c *Client) AuthTest(ctx context.Context) (*slack.AuthTestResponse, error) {
-	resp, err := c.client.AuthTestContext(ctx)
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "AuthTest"); err != nil {
+		return nil, err
+	}
+
+	var resp *slack.AuthTestResponse
+	err := c.call(ctx, "auth.test", "", func() (err error) {
+		resp, err = c.api().AuthTestContext(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("auth test: %w", err)
 	}
@@ -102,7 +609,15 @@ func (c *Client) AuthTest(ctx context.Context) (*slack.AuthTestResponse, error)
 
 // GetBotUserID gets the bot's user ID for filtering messages
 func (c *Client) GetBotUserID(ctx context.Context) (string, error) {
-	resp, err := c.client.AuthTestContext(ctx)
+	if err := c.faults.Inject(ctx, chaos.TargetSlack, "GetBotUserID"); err != nil {
+		return "", err
+	}
+
+	var resp *slack.AuthTestResponse
+	err := c.call(ctx, "auth.test", "", func() (err error) {
+		resp, err = c.api().AuthTestContext(ctx)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("get bot user id: %w", err)
 	}
@@ -112,9 +627,14 @@ func (c *Client) GetBotUserID(ctx context.Context) (string, error) {
 
 // ArchiveConversation archives a channel
 func (c *Client) ArchiveConversation(ctx context.Context, channelID string) error {
-	err := c.client.ArchiveConversationContext(ctx, channelID)
+	if c.shadowed("conversations.archive", channelID, "") {
+		return nil
+	}
+	err := c.call(ctx, "conversations.archive", channelID, func() error {
+		return c.api().ArchiveConversationContext(ctx, channelID)
+	})
 	if err != nil {
-		log.Printf("Warning: failed to archive conversation %s: %v", channelID, err)
+		slog.WarnContext(ctx, "failed to archive conversation", logging.ChannelID, channelID, "error", err)
 		// Don't return error - archiving is nice-to-have
 	}
 
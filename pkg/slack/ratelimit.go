@@ -0,0 +1,208 @@
+package slack
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Tier is a Slack Web API rate limit: a sustained rate with room for a
+// short burst. PerChannel limits apply to each channel separately
+type Tier struct {
+	PerMinute  float64
+	Burst      float64
+	PerChannel bool
+}
+
+// Slack's documented rate limit tiers
+var (
+	Tier1 = Tier{PerMinute: 1, Burst: 1}
+	Tier2 = Tier{PerMinute: 20, Burst: 3}
+	Tier3 = Tier{PerMinute: 50, Burst: 5}
+	Tier4 = Tier{PerMinute: 100, Burst: 10}
+
+	// chat.postMessage allows about one message per second per channel
+	PostMessageTier = Tier{PerMinute: 60, Burst: 3, PerChannel: true}
+)
+
+// DefaultTiers maps the Web API methods the client calls to their tier.
+// Methods not listed are treated as Tier 3
+var DefaultTiers = map[string]Tier{
+	"auth.test":             Tier4,
+	"chat.delete":           Tier3,
+	"chat.getPermalink":     Tier4,
+	"chat.postMessage":      PostMessageTier,
+	"chat.unfurl":           Tier3,
+	"chat.update":           Tier3,
+	"conversations.archive": Tier2,
+	"conversations.create":  Tier2,
+	"conversations.history": Tier3,
+	"conversations.info":    Tier3,
+	"conversations.invite":  Tier3,
+	"conversations.open":    Tier3,
+	"conversations.replies": Tier3,
+	"files.uploadV2":        Tier2,
+	"reactions.add":         Tier3,
+	"reactions.get":         Tier3,
+	"reactions.remove":      Tier2,
+	"usergroups.users.list": Tier2,
+	"users.info":            Tier4,
+	"users.lookupByEmail":   Tier3,
+	"users.profile.get":     Tier4,
+	"views.open":            Tier4,
+}
+
+// Limiter queues Web API calls so each method stays within its tier. It
+// only sees this process's calls, so a 429 from Slack still pauses the
+// method for the Retry-After Slack asks for
+type Limiter struct {
+	mu      sync.Mutex
+	tiers   map[string]Tier
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// bucket is a token bucket whose balance goes negative as callers queue
+// behind each other
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter enforcing tiers
+func NewLimiter(tiers map[string]Tier) *Limiter {
+	return &Limiter{
+		tiers:   tiers,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Wait blocks until a call to method (in channelID, for per-channel
+// limits) may be made
+func (l *Limiter) Wait(ctx context.Context, method, channelID string) error {
+	delay := l.reserve(method, channelID)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Pause holds back calls to method for d, as Slack asked in a 429
+func (l *Limiter) Pause(method, channelID string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tier, b := l.bucket(method, channelID)
+	b.tokens = math.Min(b.tokens, -d.Minutes()*tier.PerMinute)
+}
+
+// reserve takes a token and returns how long the caller must wait for it
+func (l *Limiter) reserve(method, channelID string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tier, b := l.bucket(method, channelID)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / tier.PerMinute * float64(time.Minute))
+}
+
+// bucket returns the refilled bucket for a call. Callers must hold l.mu
+func (l *Limiter) bucket(method, channelID string) (Tier, *bucket) {
+	tier, ok := l.tiers[method]
+	if !ok {
+		tier = Tier3
+	}
+
+	key := method
+	if tier.PerChannel {
+		key += "/" + channelID
+	}
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: tier.Burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(tier.Burst, b.tokens+now.Sub(b.last).Minutes()*tier.PerMinute)
+	b.last = now
+	return tier, b
+}
+
+// updateQueue collapses chat.update calls for the same message that pile
+// up behind the rate limit, so a streamed answer sends only its latest
+// text rather than every intermediate one
+type updateQueue struct {
+	mu      sync.Mutex
+	pending map[string]*pendingUpdate
+	sending map[string]*pendingUpdate
+}
+
+// pendingUpdate is an update waiting for its turn. Callers that arrive
+// before it is sent replace its payload and share its result
+type pendingUpdate struct {
+	payload any
+	done    chan struct{}
+	err     error
+}
+
+func newUpdateQueue() *updateQueue {
+	return &updateQueue{
+		pending: make(map[string]*pendingUpdate),
+		sending: make(map[string]*pendingUpdate),
+	}
+}
+
+// do sends payload for key with send, after wait returns and any earlier
+// update for key has been sent, so updates never land out of order. A later
+// call for the same key that arrives in the meantime takes its place
+func (q *updateQueue) do(key string, payload any, wait func() error, send func(payload any) error) error {
+	q.mu.Lock()
+	if p, ok := q.pending[key]; ok {
+		p.payload = payload
+		q.mu.Unlock()
+		<-p.done
+		return p.err
+	}
+	p := &pendingUpdate{payload: payload, done: make(chan struct{})}
+	prev := q.sending[key]
+	q.pending[key] = p
+	q.mu.Unlock()
+
+	err := wait()
+	if prev != nil {
+		<-prev.done
+	}
+
+	q.mu.Lock()
+	delete(q.pending, key)
+	payload = p.payload
+	q.sending[key] = p
+	q.mu.Unlock()
+
+	if err == nil {
+		err = send(payload)
+	}
+	p.err = err
+	close(p.done)
+
+	q.mu.Lock()
+	if q.sending[key] == p {
+		delete(q.sending, key)
+	}
+	q.mu.Unlock()
+	return err
+}
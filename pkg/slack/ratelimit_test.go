@@ -0,0 +1,160 @@
+package slack
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterQueuesBursts(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(map[string]Tier{"chat.update": {PerMinute: 60, Burst: 2}})
+	l.now = func() time.Time { return now }
+
+	// The burst goes straight through, then each call waits a second
+	// longer than the one before it
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second}
+	for i, w := range want {
+		if got := l.reserve("chat.update", "C1"); got != w {
+			t.Errorf("call %d waits %v, want %v", i, got, w)
+		}
+	}
+
+	// Tokens refill at the tier's rate
+	now = now.Add(time.Minute)
+	if got := l.reserve("chat.update", "C1"); got != 0 {
+		t.Errorf("after refill waits %v, want 0", got)
+	}
+}
+
+func TestLimiterPerChannel(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(map[string]Tier{
+		"chat.postMessage": {PerMinute: 60, Burst: 1, PerChannel: true},
+		"chat.update":      {PerMinute: 60, Burst: 1},
+	})
+	l.now = func() time.Time { return now }
+
+	l.reserve("chat.postMessage", "C1")
+	if got := l.reserve("chat.postMessage", "C2"); got != 0 {
+		t.Errorf("post to another channel waits %v, want 0", got)
+	}
+
+	l.reserve("chat.update", "C1")
+	if got := l.reserve("chat.update", "C2"); got != time.Second {
+		t.Errorf("update in another channel waits %v, want 1s (method-wide limit)", got)
+	}
+}
+
+func TestLimiterPause(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(map[string]Tier{"users.info": {PerMinute: 60, Burst: 10}})
+	l.now = func() time.Time { return now }
+
+	l.Pause("users.info", "", 30*time.Second)
+	if got := l.reserve("users.info", ""); got != 31*time.Second {
+		t.Errorf("after 429 waits %v, want 31s", got)
+	}
+}
+
+func TestLimiterUnknownMethod(t *testing.T) {
+	l := NewLimiter(nil)
+	for i := 0; i < int(Tier3.Burst); i++ {
+		if got := l.reserve("reactions.add", ""); got != 0 {
+			t.Fatalf("call %d waits %v within the Tier 3 burst", i, got)
+		}
+	}
+	if got := l.reserve("reactions.add", ""); got <= 0 {
+		t.Error("call past the Tier 3 burst should wait")
+	}
+}
+
+func TestUpdateQueueCoalesces(t *testing.T) {
+	q := newUpdateQueue()
+	release := make(chan struct{})
+	waiting := make(chan struct{})
+
+	var mu sync.Mutex
+	var sent []any
+	send := func(payload any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, payload)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.do("C1/1.0", "first", func() error {
+			close(waiting)
+			<-release
+			return nil
+		}, send)
+	}()
+	<-waiting
+
+	// Arrives while the first update is still waiting its turn
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.do("C1/1.0", "second", func() error { return nil }, send)
+	}()
+	for {
+		q.mu.Lock()
+		replaced := q.pending["C1/1.0"].payload == "second"
+		q.mu.Unlock()
+		if replaced {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if len(sent) != 1 || sent[0] != "second" {
+		t.Errorf("sent %v, want only the newest content", sent)
+	}
+}
+
+func TestUpdateQueueKeepsOrder(t *testing.T) {
+	q := newUpdateQueue()
+	inFlight := make(chan struct{})
+	finish := make(chan struct{})
+
+	var mu sync.Mutex
+	var sent []any
+	send := func(payload any) error {
+		if payload == "first" {
+			close(inFlight)
+			<-finish
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, payload)
+		return nil
+	}
+	noWait := func() error { return nil }
+
+	done := make(chan struct{})
+	go func() {
+		q.do("C1/1.0", "first", noWait, send)
+		close(done)
+	}()
+	<-inFlight
+
+	second := make(chan struct{})
+	go func() {
+		q.do("C1/1.0", "second", noWait, send)
+		close(second)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(finish)
+	<-done
+	<-second
+
+	if len(sent) != 2 || sent[0] != "first" || sent[1] != "second" {
+		t.Errorf("sent %v, want [first second]", sent)
+	}
+}
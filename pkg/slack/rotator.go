@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// Bot tokens are refreshed this long before they expire, checking this often
+const (
+	refreshBefore = time.Hour
+	checkInterval = 10 * time.Minute
+)
+
+// TokenStore shares rotated tokens between processes. SaveToken fails when
+// the stored token is no longer at priorVersion
+type TokenStore interface {
+	GetToken(ctx context.Context, tokenID string) (*models.SlackToken, error)
+	SaveToken(ctx context.Context, token *models.SlackToken, priorVersion int) error
+}
+
+// Rotator keeps a bot token current for apps with Slack token rotation
+// enabled, where bot tokens expire after 12 hours
+type Rotator struct {
+	store   TokenStore
+	seed    string // refresh token to start from when none is stored
+	refresh func(ctx context.Context, refreshToken string) (*slack.OAuthV2Response, error)
+	now     func() time.Time
+}
+
+// NewRotator creates a rotator that refreshes through the app's OAuth
+// credentials. refreshToken is only used until a token has been stored
+func NewRotator(store TokenStore, clientID, clientSecret, refreshToken string) *Rotator {
+	return &Rotator{
+		store: store,
+		seed:  refreshToken,
+		refresh: func(ctx context.Context, refreshToken string) (*slack.OAuthV2Response, error) {
+			return slack.RefreshOAuthV2TokenContext(ctx, http.DefaultClient, clientID, clientSecret, refreshToken)
+		},
+		now: time.Now,
+	}
+}
+
+// Token returns the current bot token, refreshing it when it is near expiry
+func (r *Rotator) Token(ctx context.Context) (string, error) {
+	stored, err := r.store.GetToken(ctx, models.SlackTokenBot)
+	if err != nil {
+		return "", err
+	}
+	if stored != nil && !stored.ExpiresWithin(refreshBefore, r.now()) {
+		return stored.AccessToken, nil
+	}
+
+	refreshToken, prior := r.seed, 0
+	if stored != nil {
+		refreshToken, prior = stored.RefreshToken, stored.Version
+	}
+	if refreshToken == "" {
+		return "", fmt.Errorf("no slack refresh token stored or configured")
+	}
+
+	resp, err := r.refresh(ctx, refreshToken)
+	if err != nil {
+		if stored != nil && !stored.ExpiresWithin(0, r.now()) {
+			slog.WarnContext(ctx, "failed to refresh slack token, using current one", "error", err)
+			return stored.AccessToken, nil
+		}
+		return "", fmt.Errorf("refresh slack token: %w", err)
+	}
+
+	now := r.now()
+	token := &models.SlackToken{
+		TokenID:      models.SlackTokenBot,
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    now.Add(time.Duration(resp.ExpiresIn) * time.Second),
+		Version:      prior + 1,
+		UpdatedAt:    now,
+	}
+	if err := r.store.SaveToken(ctx, token, prior); err != nil {
+		// Another process refreshed at the same time; either token works,
+		// but everyone should carry on from the stored one
+		if latest, getErr := r.store.GetToken(ctx, models.SlackTokenBot); getErr == nil && latest != nil && latest.Version > prior {
+			return latest.AccessToken, nil
+		}
+		slog.WarnContext(ctx, "failed to store refreshed slack token", "error", err)
+	} else {
+		slog.InfoContext(ctx, "refreshed slack bot token", "version", token.Version, "expires_at", token.ExpiresAt.Format(time.RFC3339))
+	}
+	return token.AccessToken, nil
+}
+
+// Apply switches the client to the current bot token
+func (r *Rotator) Apply(ctx context.Context, c *Client) error {
+	token, err := r.Token(ctx)
+	if err != nil {
+		return err
+	}
+	c.SetToken(token)
+	return nil
+}
+
+// Run keeps the client's bot token current until ctx is done. Long-running
+// processes call it after Apply
+func (r *Rotator) Run(ctx context.Context, c *Client) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Apply(ctx, c); err != nil {
+				slog.WarnContext(ctx, "failed to rotate slack token", "error", err)
+			}
+		}
+	}
+}
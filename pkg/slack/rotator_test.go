@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+var errChanged = errors.New("changed")
+
+// memoryStore is a TokenStore that can simulate another process saving first
+type memoryStore struct {
+	token *models.SlackToken
+	race  *models.SlackToken // stored instead of the next save
+}
+
+func (s *memoryStore) GetToken(ctx context.Context, tokenID string) (*models.SlackToken, error) {
+	return s.token, nil
+}
+
+func (s *memoryStore) SaveToken(ctx context.Context, token *models.SlackToken, priorVersion int) error {
+	if s.race != nil {
+		s.token, s.race = s.race, nil
+		return errChanged
+	}
+	if s.token != nil && s.token.Version != priorVersion {
+		return errChanged
+	}
+	s.token = token
+	return nil
+}
+
+func newTestRotator(store *memoryStore, now time.Time) (*Rotator, *[]string) {
+	var used []string
+	r := &Rotator{
+		store: store,
+		seed:  "xoxe-seed",
+		refresh: func(ctx context.Context, refreshToken string) (*slack.OAuthV2Response, error) {
+			used = append(used, refreshToken)
+			return &slack.OAuthV2Response{AccessToken: "xoxe.xoxb-new", RefreshToken: "xoxe-next", ExpiresIn: 43200}, nil
+		},
+		now: func() time.Time { return now },
+	}
+	return r, &used
+}
+
+func TestRotatorToken(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// First run starts from the configured refresh token
+	store := &memoryStore{}
+	r, used := newTestRotator(store, now)
+	token, err := r.Token(ctx)
+	if err != nil || token != "xoxe.xoxb-new" {
+		t.Fatalf("Token() = %q, %v", token, err)
+	}
+	if len(*used) != 1 || (*used)[0] != "xoxe-seed" || store.token.Version != 1 || store.token.RefreshToken != "xoxe-next" {
+		t.Errorf("first refresh used %v, stored %+v", *used, store.token)
+	}
+
+	// A fresh token is reused without refreshing
+	store.token.AccessToken = "xoxe.xoxb-current"
+	if token, _ := r.Token(ctx); token != "xoxe.xoxb-current" || len(*used) != 1 {
+		t.Errorf("Token() = %q after %d refreshes, want the stored token", token, len(*used))
+	}
+
+	// Near expiry it refreshes from the stored refresh token
+	store.token.ExpiresAt = now.Add(30 * time.Minute)
+	if token, _ := r.Token(ctx); token != "xoxe.xoxb-new" || (*used)[1] != "xoxe-next" || store.token.Version != 2 {
+		t.Errorf("Token() = %q using %v, version %d", token, *used, store.token.Version)
+	}
+}
+
+func TestRotatorTokenRace(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{
+		token: &models.SlackToken{AccessToken: "old", RefreshToken: "xoxe-old", ExpiresAt: now.Add(time.Minute), Version: 3},
+		race:  &models.SlackToken{AccessToken: "theirs", RefreshToken: "xoxe-theirs", ExpiresAt: now.Add(12 * time.Hour), Version: 4},
+	}
+	r, _ := newTestRotator(store, now)
+
+	if token, err := r.Token(context.Background()); err != nil || token != "theirs" {
+		t.Errorf("Token() = %q, %v, want the token the other process stored", token, err)
+	}
+}
+
+func TestRotatorRefreshFailure(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{token: &models.SlackToken{AccessToken: "current", RefreshToken: "xoxe-1", ExpiresAt: now.Add(30 * time.Minute), Version: 1}}
+	r, _ := newTestRotator(store, now)
+	r.refresh = func(ctx context.Context, refreshToken string) (*slack.OAuthV2Response, error) {
+		return nil, errors.New("invalid_refresh_token")
+	}
+
+	if token, err := r.Token(context.Background()); err != nil || token != "current" {
+		t.Errorf("Token() = %q, %v, want the unexpired token", token, err)
+	}
+
+	store.token.ExpiresAt = now.Add(-time.Minute)
+	if _, err := r.Token(context.Background()); err == nil {
+		t.Error("Token() should fail once the stored token has expired")
+	}
+}
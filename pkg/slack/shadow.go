@@ -0,0 +1,50 @@
+package slack
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/slack-go/slack"
+)
+
+// SetShadow makes the client read-only, for an agent running in shadow
+// mode: calls that would post, edit, react, upload, or change channels are
+// logged with their content instead of sent
+func (c *Client) SetShadow(on bool) {
+	c.shadow = on
+}
+
+// shadowed logs a call a shadow client doesn't make, and reports whether
+// to skip it
+func (c *Client) shadowed(method, channelID, detail string) bool {
+	if !c.shadow {
+		return false
+	}
+	slog.Info("shadow: would call slack", "method", method, logging.ChannelID, channelID, "detail", detail)
+	return true
+}
+
+// shadowTS returns a unique stand-in timestamp for a message a shadow
+// client didn't post, so later updates to it can be told apart in the logs
+func (c *Client) shadowTS() string {
+	n := c.shadowSeq.Add(1)
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), n%1000000)
+}
+
+// describe returns the text a message would carry, or a count of its
+// blocks when it has none
+func describe(opts []slack.MsgOption) string {
+	_, values, err := slack.UnsafeApplyMsgOptions("", "", "", opts...)
+	if err != nil {
+		return fmt.Sprintf("unreadable message: %v", err)
+	}
+	if text := values.Get("text"); text != "" {
+		return fmt.Sprintf("%q", text)
+	}
+	if blocks := values.Get("blocks"); blocks != "" {
+		return blocks
+	}
+	return "empty message"
+}
@@ -0,0 +1,46 @@
+package slack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestShadowClient(t *testing.T) {
+	// An invalid token makes any call that reaches Slack fail
+	c := NewClient("xoxb-invalid")
+	c.SetShadow(true)
+	ctx := context.Background()
+
+	first, err := c.PostMessage(ctx, "C1", slack.MsgOptionText("restarting web-1", false))
+	if err != nil {
+		t.Fatalf("PostMessage() error = %v", err)
+	}
+	second, err := c.PostMessage(ctx, "C1", slack.MsgOptionText("done", false))
+	if err != nil {
+		t.Fatalf("PostMessage() error = %v", err)
+	}
+	if first == "" || first == second {
+		t.Errorf("timestamps = %q, %q, want distinct stand-ins", first, second)
+	}
+
+	if err := c.UpdateMessage(ctx, "C1", first, slack.MsgOptionText("edited", false)); err != nil {
+		t.Errorf("UpdateMessage() error = %v", err)
+	}
+	if err := c.AddReaction(ctx, "C1", first, "white_check_mark"); err != nil {
+		t.Errorf("AddReaction() error = %v", err)
+	}
+	if err := c.UploadFile(ctx, "C1", first, "report.md", "Report", []byte("# Report")); err != nil {
+		t.Errorf("UploadFile() error = %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := describe([]slack.MsgOption{slack.MsgOptionText("hello", false)}); got != `"hello"` {
+		t.Errorf("describe() = %s, want the quoted text", got)
+	}
+	if got := describe(nil); got != "empty message" {
+		t.Errorf("describe() = %s, want empty message", got)
+	}
+}
@@ -0,0 +1,111 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+)
+
+// GeneralKnowledge flags answers that weren't based on any live data
+const GeneralKnowledge = "💡 From general knowledge. No live AWS data was checked for this answer, so verify before acting on it."
+
+// Source is live data an answer was based on
+type Source struct {
+	Tool    string // e.g. "CloudWatch metrics"
+	Target  string // what was queried, e.g. "AWS/ECS CPUUtilization"
+	Start   time.Time
+	End     time.Time
+	Region  string
+	Account string
+}
+
+// toolSummary collects the sources from one tool
+type toolSummary struct {
+	tool       string
+	targets    []string
+	start, end time.Time
+}
+
+// Describe summarizes the sources behind an answer in one line for a Slack
+// context block, or returns GeneralKnowledge when there are none
+func Describe(srcs []Source, now time.Time) string {
+	if len(srcs) == 0 {
+		return GeneralKnowledge
+	}
+
+	var tools []*toolSummary
+	byTool := map[string]*toolSummary{}
+	var regions, accounts []string
+	for _, s := range srcs {
+		t := byTool[s.Tool]
+		if t == nil {
+			t = &toolSummary{tool: s.Tool, start: s.Start, end: s.End}
+			byTool[s.Tool] = t
+			tools = append(tools, t)
+		}
+		t.targets = appendUnique(t.targets, s.Target)
+		if !s.Start.IsZero() && (t.start.IsZero() || s.Start.Before(t.start)) {
+			t.start = s.Start
+		}
+		if s.End.After(t.end) {
+			t.end = s.End
+		}
+		regions = appendUnique(regions, s.Region)
+		accounts = appendUnique(accounts, s.Account)
+	}
+
+	var parts []string
+	for _, t := range tools {
+		part := t.tool
+		if len(t.targets) > 0 {
+			part += " (" + strings.Join(t.targets, ", ") + ")"
+		}
+		if r := timeRange(t.start, t.end, now); r != "" {
+			part += ", " + r
+		}
+		parts = append(parts, part)
+	}
+
+	line := "📊 Sources: " + strings.Join(parts, "; ")
+	if len(regions) > 0 {
+		line += " · " + strings.Join(regions, ", ")
+	}
+	if len(accounts) > 0 {
+		label := "account "
+		if len(accounts) > 1 {
+			label = "accounts "
+		}
+		line += " · " + label + strings.Join(accounts, ", ")
+	}
+	return line
+}
+
+// timeRange describes a queried window, relative when it ends now
+func timeRange(start, end, now time.Time) string {
+	if start.IsZero() || end.IsZero() {
+		return ""
+	}
+	if now.Sub(end) < time.Minute {
+		return "last " + humanize.Duration(end.Sub(start))
+	}
+
+	start, end = start.UTC(), end.UTC()
+	if start.YearDay() == end.YearDay() && start.Year() == end.Year() {
+		return fmt.Sprintf("%s–%s UTC", start.Format("Jan 2 15:04"), end.Format("15:04"))
+	}
+	return fmt.Sprintf("%s – %s UTC", start.Format("Jan 2 15:04"), end.Format("Jan 2 15:04"))
+}
+
+func appendUnique(values []string, v string) []string {
+	if v == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	return append(values, v)
+}
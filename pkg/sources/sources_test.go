@@ -0,0 +1,43 @@
+package sources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	now := time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		srcs []Source
+		want string
+	}{
+		{
+			name: "no sources",
+			want: GeneralKnowledge,
+		},
+		{
+			name: "grouped by tool",
+			srcs: []Source{
+				{Tool: "CloudWatch metrics", Target: "AWS/ECS CPUUtilization", Start: now.Add(-3 * time.Hour), End: now, Region: "us-east-1", Account: "123456789012"},
+				{Tool: "CloudWatch metrics", Target: "AWS/ApplicationELB RequestCount", Start: now.Add(-time.Hour), End: now, Region: "us-east-1", Account: "123456789012"},
+			},
+			want: "📊 Sources: CloudWatch metrics (AWS/ECS CPUUtilization, AWS/ApplicationELB RequestCount), last 3h · us-east-1 · account 123456789012",
+		},
+		{
+			name: "absolute window and several regions",
+			srcs: []Source{
+				{Tool: "CloudWatch Logs", Target: "/aws/lambda/api", Start: now.Add(-26 * time.Hour), End: now.Add(-24 * time.Hour), Region: "us-east-1"},
+				{Tool: "EC2", Region: "eu-west-1"},
+			},
+			want: "📊 Sources: CloudWatch Logs (/aws/lambda/api), Apr 30 14:00–16:00 UTC; EC2 · us-east-1, eu-west-1",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Describe(tt.srcs, now); got != tt.want {
+			t.Errorf("%s: Describe() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
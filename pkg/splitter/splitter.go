@@ -0,0 +1,120 @@
+package splitter
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// fence opens and closes a code block
+const fence = "```"
+
+// markerReserve leaves room for a "(12/34)" continuation marker
+const markerReserve = len("\n_(99/99)_")
+
+// Split breaks text into chunks of at most limit bytes, cutting at the
+// last paragraph break before the limit, then the last line break, then
+// the last space. Text is never cut mid-character, and a code block that
+// has to be cut is closed at the end of one chunk and reopened at the
+// start of the next so both render as code. limit must be comfortably
+// longer than any code fence line
+func Split(text string, limit int) []string {
+	var chunks []string
+	for len(text) > limit {
+		cut, open := cutPoint(text, limit-len("\n"+fence))
+		chunk := strings.TrimRight(text[:cut], "\n ")
+		rest := strings.TrimLeft(strings.TrimPrefix(text[cut:], " "), "\n")
+		if open != "" {
+			chunk += "\n" + fence
+			if strings.HasPrefix(rest, fence) {
+				// The block closed right after the cut; don't reopen it empty
+				_, rest, _ = strings.Cut(rest, "\n")
+			} else {
+				rest = open + "\n" + rest
+			}
+		}
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		text = rest
+	}
+	if strings.TrimSpace(text) != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// Numbered splits text like Split and, when it takes more than one chunk,
+// ends each with a "(1/3)" marker so readers know more is coming
+func Numbered(text string, limit int) []string {
+	chunks := Split(text, limit)
+	if len(chunks) <= 1 {
+		return chunks
+	}
+
+	chunks = Split(text, limit-markerReserve)
+	for i := range chunks {
+		chunks[i] += fmt.Sprintf("\n_(%d/%d)_", i+1, len(chunks))
+	}
+	return chunks
+}
+
+// cutPoint picks where to cut text so the chunk is at most max bytes. open
+// is the opening fence line when the cut falls inside a code block
+func cutPoint(text string, max int) (cut int, open string) {
+	var (
+		paragraph, line, fencedLine int
+		fencedOpen, current         string
+	)
+
+	// Only boundaries past the halfway point are worth keeping; earlier
+	// ones make needlessly short chunks
+	min := max / 2
+
+	pos := 0
+	for {
+		end := strings.IndexByte(text[pos:], '\n')
+		if end < 0 || pos+end > max {
+			break
+		}
+		end += pos
+		trimmed := strings.TrimSpace(text[pos:end])
+
+		switch {
+		case current != "" && strings.HasPrefix(trimmed, fence):
+			current = ""
+		case current == "" && strings.HasPrefix(trimmed, fence) && !strings.Contains(trimmed[len(fence):], fence):
+			current = trimmed
+		}
+
+		switch {
+		case current != "":
+			fencedLine, fencedOpen = end, current
+		case trimmed == "":
+			paragraph, line = end, end
+		default:
+			line = end
+		}
+		pos = end + 1
+	}
+
+	switch {
+	case paragraph > min:
+		return paragraph, ""
+	case line > min:
+		return line, ""
+	case fencedLine > min:
+		return fencedLine, fencedOpen
+	}
+
+	// No usable line break: cut the line that crosses the limit at a space,
+	// or failing that anywhere that isn't mid-character
+	if space := strings.LastIndexByte(text[:max], ' '); space > min && space >= pos {
+		return space, current
+	}
+	cut = max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return cut, current
+}
@@ -0,0 +1,106 @@
+package splitter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitShortText(t *testing.T) {
+	if got := Split("hello", 100); len(got) != 1 || got[0] != "hello" {
+		t.Errorf("Split() = %q, want [hello]", got)
+	}
+	if got := Split("", 100); len(got) != 0 {
+		t.Errorf("Split(\"\") = %q, want none", got)
+	}
+}
+
+func TestSplitPrefersParagraphs(t *testing.T) {
+	first := strings.Repeat("a", 30) + "\n" + strings.Repeat("b", 30)
+	second := strings.Repeat("c", 30)
+	got := Split(first+"\n\n"+second, 80)
+
+	if len(got) != 2 || got[0] != first || got[1] != second {
+		t.Errorf("Split() = %q, want paragraphs kept whole", got)
+	}
+}
+
+func TestSplitFallsBackToLinesAndSpaces(t *testing.T) {
+	lines := strings.Repeat("x", 50) + "\n" + strings.Repeat("y", 50)
+	if got := Split(lines, 80); len(got) != 2 || got[0] != strings.Repeat("x", 50) {
+		t.Errorf("Split() = %q, want a cut at the line break", got)
+	}
+
+	words := strings.Repeat("word ", 30)
+	for _, chunk := range Split(words, 40) {
+		if strings.HasPrefix(chunk, "ord") || strings.HasSuffix(chunk, "wor") {
+			t.Errorf("chunk %q was cut mid-word", chunk)
+		}
+	}
+}
+
+func TestSplitNeverBreaksCharacters(t *testing.T) {
+	text := strings.Repeat("é", 100)
+	for _, chunk := range Split(text, 33) {
+		if !utf8.ValidString(chunk) || len(chunk) > 33 {
+			t.Errorf("chunk %q is invalid or too long", chunk)
+		}
+	}
+}
+
+func TestSplitReopensCodeBlocks(t *testing.T) {
+	var code strings.Builder
+	for i := 0; i < 20; i++ {
+		code.WriteString("line of code number\n")
+	}
+	text := "Run this:\n```bash\n" + code.String() + "```\nDone."
+	got := Split(text, 200)
+
+	if len(got) < 2 {
+		t.Fatalf("Split() = %q, want several chunks", got)
+	}
+	for i, chunk := range got {
+		if len(chunk) > 200 {
+			t.Errorf("chunk %d is %d bytes, want at most 200", i, len(chunk))
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("chunk %d has an unbalanced code fence:\n%s", i, chunk)
+		}
+	}
+	if !strings.HasPrefix(got[1], "```bash\n") {
+		t.Errorf("second chunk = %q, want the code block reopened", got[1])
+	}
+	if joined := strings.Join(got, "\n"); strings.Count(joined, "line of code number") != 20 {
+		t.Error("code lines were lost")
+	}
+}
+
+func TestNumbered(t *testing.T) {
+	if got := Numbered("short", 100); len(got) != 1 || got[0] != "short" {
+		t.Errorf("Numbered() = %q, want no marker on a single chunk", got)
+	}
+
+	text := strings.Repeat("paragraph text here\n\n", 20)
+	got := Numbered(text, 100)
+	if len(got) < 2 {
+		t.Fatalf("Numbered() = %q, want several chunks", got)
+	}
+	for i, chunk := range got {
+		if len(chunk) > 100 {
+			t.Errorf("chunk %d is %d bytes, want at most 100", i, len(chunk))
+		}
+		if marker := fmt.Sprintf("\n_(%d/%d)_", i+1, len(got)); !strings.HasSuffix(chunk, marker) {
+			t.Errorf("chunk %d = %q, want it to end with %q", i, chunk, marker)
+		}
+	}
+}
+
+func TestSplitKeepsCodeIndentation(t *testing.T) {
+	text := "```\n" + strings.Repeat("    indented line\n", 10) + "```"
+	for i, chunk := range Split(text, 60)[1:] {
+		if !strings.HasPrefix(chunk, "```\n    indented") {
+			t.Errorf("chunk %d = %q, want indentation kept after the reopened fence", i+1, chunk)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package stats summarizes how the bot was used over a period: how many
+// conversations it had and how they ended, how long resolutions took, the
+// tools it called most, and what its model calls cost. /cloudops stats
+// renders the summary for team leads
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/chargeback"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+// TopTools is how many tools the report lists
+const TopTools = 5
+
+// ToolCount is how often a tool was called, and how many of those calls
+// failed or were refused
+type ToolCount struct {
+	Tool   string
+	Calls  int
+	Failed int
+}
+
+// Period resolves the named periods: "today" since midnight in loc, and
+// "week" the last 7 days
+func Period(name string, now time.Time, loc *time.Location) (label string, start, end time.Time, ok bool) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	switch strings.ToLower(name) {
+	case "", "today":
+		return "Today", time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc), now, true
+	case "week":
+		return "Last 7 days", now.Add(-7 * 24 * time.Hour), now, true
+	}
+	return "", time.Time{}, time.Time{}, false
+}
+
+// Report is the bot's usage over a period
+type Report struct {
+	Label      string // e.g. "Today", "Last 7 days"
+	Start, End time.Time
+
+	Conversations int
+	ByStatus      map[string]int
+
+	// Resolved is the conversations that completed, and AvgResolution the
+	// mean time from their start to completion
+	Resolved      int
+	AvgResolution time.Duration
+
+	InputTokens  int64
+	OutputTokens int64
+	ModelCost    float64
+
+	// Tools are the most called tools, busiest first, and ToolCalls counts
+	// every call. They are only shown when ToolsTracked, as tool executions
+	// are counted from the tool audit table
+	Tools        []ToolCount
+	ToolCalls    int
+	ToolsTracked bool
+}
+
+// Build summarizes the conversations started in [start, end) and the tool
+// executions in it, pricing tokens at rates
+func Build(label string, start, end time.Time, convs []*models.Conversation, tools []*models.ToolExecution, rates chargeback.Rates) *Report {
+	r := &Report{Label: label, Start: start, End: end, ByStatus: map[string]int{}}
+
+	var resolution time.Duration
+	for _, c := range convs {
+		if c.CreatedAt.Before(start) || !c.CreatedAt.Before(end) {
+			continue
+		}
+		r.Conversations++
+		r.ByStatus[c.Status]++
+		r.InputTokens += c.InputTokens
+		r.OutputTokens += c.OutputTokens
+		if c.Status == models.StatusCompleted && c.CompletedAt != nil && c.CompletedAt.After(c.CreatedAt) {
+			r.Resolved++
+			resolution += c.CompletedAt.Sub(c.CreatedAt)
+		}
+	}
+	if r.Resolved > 0 {
+		r.AvgResolution = resolution / time.Duration(r.Resolved)
+	}
+	r.ModelCost = rates.ModelCost(r.InputTokens, r.OutputTokens)
+
+	counts := map[string]*ToolCount{}
+	for _, t := range tools {
+		if t.StartedAt.Before(start) || !t.StartedAt.Before(end) {
+			continue
+		}
+		tc, ok := counts[t.Tool]
+		if !ok {
+			tc = &ToolCount{Tool: t.Tool}
+			counts[t.Tool] = tc
+		}
+		tc.Calls++
+		if t.Outcome != models.ToolSucceeded {
+			tc.Failed++
+		}
+		r.ToolCalls++
+	}
+	for _, tc := range counts {
+		r.Tools = append(r.Tools, *tc)
+	}
+	sort.Slice(r.Tools, func(i, j int) bool {
+		if r.Tools[i].Calls != r.Tools[j].Calls {
+			return r.Tools[i].Calls > r.Tools[j].Calls
+		}
+		return r.Tools[i].Tool < r.Tools[j].Tool
+	})
+	if len(r.Tools) > TopTools {
+		r.Tools = r.Tools[:TopTools]
+	}
+
+	return r
+}
+
+// Summary is the report's notification text
+func (r *Report) Summary() string {
+	return fmt.Sprintf("📊 CloudOps stats, %s: %s conversations, %s in model spend",
+		strings.ToLower(r.Label), humanize.Count(int64(r.Conversations)), humanize.Currency(r.ModelCost, "USD"))
+}
+
+// Blocks renders the report: the headline numbers, how conversations
+// ended, and the top tools
+func (r *Report) Blocks(loc *time.Location) []slack.Block {
+	text := func(s string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(text("*📊 CloudOps stats: "+r.Label+"*"), nil, nil),
+		slack.NewContextBlock("", text(fmt.Sprintf("%s to %s",
+			r.Start.In(loc).Format("Mon Jan 2 15:04"), r.End.In(loc).Format("Mon Jan 2 15:04 MST")))),
+	}
+
+	if r.Conversations == 0 {
+		return append(blocks, slack.NewSectionBlock(text("No conversations started in this period."), nil, nil))
+	}
+
+	resolution := "–"
+	if r.Resolved > 0 {
+		resolution = humanize.Duration(r.AvgResolution)
+	}
+	blocks = append(blocks, slack.NewSectionBlock(nil, []*slack.TextBlockObject{
+		text(fmt.Sprintf("*Conversations*\n%s", humanize.Count(int64(r.Conversations)))),
+		text(fmt.Sprintf("*Avg resolution*\n%s _(%d resolved)_", resolution, r.Resolved)),
+		text(fmt.Sprintf("*Tokens*\n%s in, %s out", humanize.Count(r.InputTokens), humanize.Count(r.OutputTokens))),
+		text(fmt.Sprintf("*Model spend*\n%s", humanize.Currency(r.ModelCost, "USD"))),
+	}, nil))
+
+	var statuses []string
+	for _, status := range []string{models.StatusActive, models.StatusCompleted, models.StatusTimeout, models.StatusFailed, models.StatusPending} {
+		if n := r.ByStatus[status]; n > 0 {
+			statuses = append(statuses, fmt.Sprintf("%s %d", status, n))
+		}
+	}
+	blocks = append(blocks, slack.NewContextBlock("", text(strings.Join(statuses, " • "))))
+
+	switch {
+	case !r.ToolsTracked:
+		blocks = append(blocks, slack.NewContextBlock("", text("_Set `TOOL_AUDIT_TABLE` to see the top tools._")))
+	case len(r.Tools) == 0:
+		blocks = append(blocks, slack.NewSectionBlock(text("*Top tools*\nNo tools were called."), nil, nil))
+	default:
+		lines := []string{fmt.Sprintf("*Top tools* _(%s calls in all)_", humanize.Count(int64(r.ToolCalls)))}
+		for i, tc := range r.Tools {
+			line := fmt.Sprintf("%d. `%s`: %s", i+1, tc.Tool, humanize.Count(int64(tc.Calls)))
+			if tc.Failed > 0 {
+				line += fmt.Sprintf(" _(%d failed)_", tc.Failed)
+			}
+			lines = append(lines, line)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(text(strings.Join(lines, "\n")), nil, nil))
+	}
+
+	return blocks
+}
@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/chargeback"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+func TestPeriod(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		wantLabel string
+		wantStart time.Time
+		wantOK    bool
+	}{
+		{"", "Today", time.Date(2026, 3, 10, 0, 0, 0, 0, loc), true},
+		{"today", "Today", time.Date(2026, 3, 10, 0, 0, 0, 0, loc), true},
+		{"WEEK", "Last 7 days", now.Add(-7 * 24 * time.Hour), true},
+		{"month", "", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		label, start, end, ok := Period(tt.name, now, loc)
+		if ok != tt.wantOK || label != tt.wantLabel || !start.Equal(tt.wantStart) {
+			t.Errorf("Period(%q) = %q, %v, %v, want %q, %v, %v", tt.name, label, start, ok, tt.wantLabel, tt.wantStart, tt.wantOK)
+		}
+		if ok && !end.Equal(now) {
+			t.Errorf("Period(%q) end = %v, want %v", tt.name, end, now)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	completed := func(created, done time.Time) *models.Conversation {
+		return &models.Conversation{Status: models.StatusCompleted, CreatedAt: created, CompletedAt: &done, InputTokens: 1_000_000, OutputTokens: 100_000}
+	}
+
+	convs := []*models.Conversation{
+		completed(at(1), at(2)),
+		completed(at(3), at(6)),
+		{Status: models.StatusActive, CreatedAt: at(7)},
+		{Status: models.StatusFailed, CreatedAt: at(8)},
+		completed(at(-5), at(1)), // started before the period
+	}
+	tools := []*models.ToolExecution{
+		{Tool: "describe_instances", Outcome: models.ToolSucceeded, StartedAt: at(1)},
+		{Tool: "describe_instances", Outcome: models.ToolFailed, StartedAt: at(2)},
+		{Tool: "get_metrics", Outcome: models.ToolSucceeded, StartedAt: at(3)},
+		{Tool: "restart_service", Outcome: models.ToolRefused, StartedAt: at(30)}, // after the period
+	}
+
+	r := Build("Today", start, end, convs, tools, chargeback.DefaultRates)
+	if r.Conversations != 4 {
+		t.Errorf("Conversations = %d, want 4", r.Conversations)
+	}
+	if r.ByStatus[models.StatusCompleted] != 2 || r.ByStatus[models.StatusActive] != 1 || r.ByStatus[models.StatusFailed] != 1 {
+		t.Errorf("ByStatus = %v, want 2 completed, 1 active, 1 failed", r.ByStatus)
+	}
+	if r.Resolved != 2 || r.AvgResolution != 2*time.Hour {
+		t.Errorf("Resolved, AvgResolution = %d, %v, want 2, 2h", r.Resolved, r.AvgResolution)
+	}
+	if r.InputTokens != 2_000_000 || r.OutputTokens != 200_000 {
+		t.Errorf("tokens = %d in, %d out, want 2000000 in, 200000 out", r.InputTokens, r.OutputTokens)
+	}
+	if r.ModelCost != 9 {
+		t.Errorf("ModelCost = %v, want 9", r.ModelCost)
+	}
+	if r.ToolCalls != 3 || len(r.Tools) != 2 {
+		t.Fatalf("ToolCalls, Tools = %d, %+v, want 3 calls across 2 tools", r.ToolCalls, r.Tools)
+	}
+	if r.Tools[0] != (ToolCount{Tool: "describe_instances", Calls: 2, Failed: 1}) {
+		t.Errorf("Tools[0] = %+v, want describe_instances with 2 calls, 1 failed", r.Tools[0])
+	}
+}
+
+func TestBuildTopTools(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	var tools []*models.ToolExecution
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "f"} {
+		tools = append(tools, &models.ToolExecution{Tool: name, Outcome: models.ToolSucceeded, StartedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	r := Build("Today", start, start.Add(time.Hour), nil, tools, chargeback.DefaultRates)
+	if len(r.Tools) != TopTools {
+		t.Fatalf("len(Tools) = %d, want %d", len(r.Tools), TopTools)
+	}
+	var names []string
+	for _, tc := range r.Tools {
+		names = append(names, tc.Tool)
+	}
+	if got := strings.Join(names, ","); got != "f,a,b,c,d" {
+		t.Errorf("Tools = %s, want f,a,b,c,d", got)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	done := start.Add(time.Hour)
+	convs := []*models.Conversation{{Status: models.StatusCompleted, CreatedAt: start, CompletedAt: &done}}
+	tools := []*models.ToolExecution{{Tool: "get_metrics", Outcome: models.ToolSucceeded, StartedAt: start}}
+
+	tests := []struct {
+		name    string
+		report  *Report
+		tracked bool
+		want    string
+	}{
+		{"empty", Build("Today", start, start.Add(24*time.Hour), nil, nil, chargeback.DefaultRates), true, "No conversations"},
+		{"untracked", Build("Today", start, start.Add(24*time.Hour), convs, nil, chargeback.DefaultRates), false, "TOOL_AUDIT_TABLE"},
+		{"tracked", Build("Today", start, start.Add(24*time.Hour), convs, tools, chargeback.DefaultRates), true, "`get_metrics`: 1"},
+	}
+	for _, tt := range tests {
+		tt.report.ToolsTracked = tt.tracked
+		if got := render(tt.report.Blocks(time.UTC)); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: Blocks() = %s, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// render joins the text of blocks
+func render(blocks []slack.Block) string {
+	var parts []string
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *slack.SectionBlock:
+			if b.Text != nil {
+				parts = append(parts, b.Text.Text)
+			}
+			for _, f := range b.Fields {
+				parts = append(parts, f.Text)
+			}
+		case *slack.ContextBlock:
+			for _, e := range b.ContextElements.Elements {
+				if t, ok := e.(*slack.TextBlockObject); ok {
+					parts = append(parts, t.Text)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
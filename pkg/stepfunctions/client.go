@@ -3,16 +3,21 @@ package stepfunctions
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sfn"
 	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/sealed"
 )
 
 // Client is a wrapper around AWS Step Functions SDK
 type Client struct {
 	client *sfn.Client
+	sealer *sealed.Sealer
 }
 
 // NewClient creates a new Step Functions client
@@ -22,16 +27,91 @@ func NewClient(cfg aws.Config) *Client {
 	}
 }
 
+// SetSealer encrypts the sensitive fields of execution inputs, such as the
+// context pack, so they can't be read from the execution history or the
+// agent task's environment
+func (c *Client) SetSealer(s *sealed.Sealer) {
+	c.sealer = s
+}
+
+// Launch describes the agent task the state machine starts
+type Launch struct {
+	Capacity       string // ondemand or spot
+	CPU            int
+	Memory         int
+	TaskDefinition string // empty runs the state machine's default task size
+	ContextPack    string // encoded contextpack.Pack, optional
+}
+
 // StartConversation starts a Step Functions execution for a conversation
-// This will spawn an ECS Fargate task to handle the conversation
-func (c *Client) StartConversation(ctx context.Context, stateMachineArn string, conversation *models.Conversation) (string, error) {
-	// Prepare input for Step Functions
-	input := map[string]string{
-		"conversationId": conversation.ConversationID,
-		"channelId":      conversation.ChannelID,
-		"userId":         conversation.UserID,
+// This will spawn an ECS Fargate task described by launch to handle the
+// conversation
+func (c *Client) StartConversation(ctx context.Context, stateMachineArn string, conversation *models.Conversation, launch Launch) (string, error) {
+	return c.start(ctx, stateMachineArn, "conv-"+conversation.ConversationID, executionInput(conversation, launch))
+}
+
+// StartShadow starts a shadow agent for a conversation alongside the one
+// StartConversation launched. The state machine runs launch.TaskDefinition
+// in shadow mode, where it logs what it would have said and done instead
+// of posting to Slack or writing to the conversation
+func (c *Client) StartShadow(ctx context.Context, stateMachineArn string, conversation *models.Conversation, launch Launch) (string, error) {
+	if launch.TaskDefinition == "" {
+		return "", errors.New("start shadow: no task definition")
+	}
+	input := executionInput(conversation, launch)
+	input.Shadow = true
+	return c.start(ctx, stateMachineArn, "shadow-conv-"+conversation.ConversationID, input)
+}
+
+// executionInput is the state machine input for a conversation
+func executionInput(conversation *models.Conversation, launch Launch) *models.StepFunctionInput {
+	input := &models.StepFunctionInput{
+		Version:        models.StepFunctionInputVersion,
+		ConversationID: conversation.ConversationID,
+		ChannelID:      conversation.ChannelID,
+		UserID:         conversation.UserID,
+		Capacity:       launch.Capacity,
+		ContextPack:    launch.ContextPack,
 	}
+	if launch.TaskDefinition != "" {
+		input.Task = &models.TaskOverride{
+			Definition: launch.TaskDefinition,
+			CPU:        strconv.Itoa(launch.CPU),
+			Memory:     strconv.Itoa(launch.Memory),
+		}
+	}
+	return input
+}
 
+// StopExecution stops a running execution, and with it the agent task it
+// started
+func (c *Client) StopExecution(ctx context.Context, executionArn, cause string) error {
+	_, err := c.client.StopExecution(ctx, &sfn.StopExecutionInput{
+		ExecutionArn: &executionArn,
+		Cause:        aws.String(cause),
+	})
+	if err != nil {
+		return fmt.Errorf("stop execution: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) start(ctx context.Context, stateMachineArn, name string, input *models.StepFunctionInput) (string, error) {
+	// An input that can't be sealed goes without its sensitive fields
+	// rather than carrying them in plaintext; the agent reads them from
+	// DynamoDB instead
+	if c.sealer != nil {
+		seal := func(plaintext []byte) (string, error) {
+			return c.sealer.Seal(ctx, input.ConversationID, plaintext)
+		}
+		if err := input.Seal(seal); err != nil {
+			slog.WarnContext(ctx, "failed to seal execution input, starting without its sensitive fields", "error", err)
+			input.ContextPack, input.InitialCommand = "", ""
+		}
+	}
+	if err := input.Validate(); err != nil {
+		return "", err
+	}
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
 		return "", fmt.Errorf("marshal input: %w", err)
@@ -41,7 +121,7 @@ func (c *Client) StartConversation(ctx context.Context, stateMachineArn string,
 	result, err := c.client.StartExecution(ctx, &sfn.StartExecutionInput{
 		StateMachineArn: &stateMachineArn,
 		Input:           aws.String(string(inputJSON)),
-		Name:            aws.String(fmt.Sprintf("conv-%s", conversation.ConversationID)),
+		Name:            aws.String(name),
 	})
 	if err != nil {
 		return "", fmt.Errorf("start execution: %w", err)
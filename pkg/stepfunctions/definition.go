@@ -0,0 +1,263 @@
+package stepfunctions
+
+import "time"
+
+// Keys of the execution input that the state machine reads
+const (
+	inputConversationID = "conversationId"
+	inputChannelID      = "channelId"
+	inputUserID         = "userId"
+	inputCapacity       = "capacity"
+	inputTask           = "task"
+	inputShadow         = "shadow"
+)
+
+// AgentContainer is the name of the agent's container in its task definition
+const AgentContainer = "cloudops-agent"
+
+// Params are the resources a generated state machine refers to. Values
+// may be literal ARNs and IDs or placeholders a template fills in
+type Params struct {
+	ClusterArn     string
+	TaskDefinition string // run when the execution input has no task size
+	ClaimFunction  string // claims a warm agent
+	Subnets        []string
+	SecurityGroup  string
+	TaskTimeout    time.Duration // how long an agent task may run
+}
+
+// Placeholders are the Fn::Sub variables the CloudFormation stack passes
+// to the state machine definition
+var Placeholders = Params{
+	ClusterArn:     "${ClusterArn}",
+	TaskDefinition: "${TaskDef}",
+	ClaimFunction:  "${ClaimAgentFunction}",
+	Subnets:        []string{"${Subnet1}", "${Subnet2}"},
+	SecurityGroup:  "${SecurityGroup}",
+	TaskTimeout:    time.Hour,
+}
+
+// StateMachine is an Amazon States Language definition
+type StateMachine struct {
+	Comment string           `json:"Comment,omitempty"`
+	StartAt string           `json:"StartAt"`
+	States  map[string]State `json:"States"`
+}
+
+// State is a state in a StateMachine
+type State struct {
+	Type           string           `json:"Type"`
+	Comment        string           `json:"Comment,omitempty"`
+	Resource       string           `json:"Resource,omitempty"`
+	Parameters     map[string]any   `json:"Parameters,omitempty"`
+	ResultSelector map[string]any   `json:"ResultSelector,omitempty"`
+	Result         map[string]any   `json:"Result,omitempty"`
+	ResultPath     string           `json:"ResultPath,omitempty"`
+	TimeoutSeconds int              `json:"TimeoutSeconds,omitempty"`
+	Choices        []map[string]any `json:"Choices,omitempty"`
+	Default        string           `json:"Default,omitempty"`
+	Retry          []Retry          `json:"Retry,omitempty"`
+	Catch          []Catch          `json:"Catch,omitempty"`
+	Next           string           `json:"Next,omitempty"`
+	End            bool             `json:"End,omitempty"`
+	Error          string           `json:"Error,omitempty"`
+	Cause          string           `json:"Cause,omitempty"`
+}
+
+// Retry retries a failed Task state
+type Retry struct {
+	ErrorEquals     []string `json:"ErrorEquals"`
+	IntervalSeconds int      `json:"IntervalSeconds"`
+	MaxAttempts     int      `json:"MaxAttempts"`
+	BackoffRate     float64  `json:"BackoffRate"`
+}
+
+// Catch moves on from a failed Task state
+type Catch struct {
+	ErrorEquals []string `json:"ErrorEquals"`
+	ResultPath  string   `json:"ResultPath,omitempty"`
+	Next        string   `json:"Next"`
+}
+
+// Definition returns the conversation state machine the code expects: it
+// takes executionInput, claims a warm agent when one is idle, and otherwise
+// runs the agent task with runTask.sync, so the execution lasts as long as
+// the conversation. Agents never call back with a task token; StopExecution
+// ends the execution and its task
+func Definition(p Params) StateMachine {
+	timeout := int(p.TaskTimeout / time.Second)
+	onDemand := runTask(p, "LaunchType", "FARGATE")
+	spot := runTask(p, "CapacityProviderStrategy", []any{
+		map[string]any{"CapacityProvider": "FARGATE_SPOT", "Weight": 1},
+	}, env("AGENT_CAPACITY", "spot"))
+	shadow := runTask(p, "LaunchType", "FARGATE", env("SHADOW_MODE", "true"))
+	delete(shadow, "EnableExecuteCommand") // shadows are never operated
+
+	return StateMachine{
+		Comment: "CloudOps Bot conversation handler - claims a warm agent or spawns an ECS task",
+		StartAt: "IsShadow",
+		States: map[string]State{
+			"IsShadow": {
+				Type: "Choice",
+				Choices: []map[string]any{
+					{
+						"And": []any{
+							map[string]any{"Variable": path(inputShadow), "IsPresent": true},
+							map[string]any{"Variable": path(inputShadow), "BooleanEquals": true},
+						},
+						"Next": "RunShadowTask",
+					},
+				},
+				Default: "ClaimWarmAgent",
+			},
+			"RunShadowTask": {
+				Type:           "Task",
+				Comment:        "Runs a candidate agent version alongside the conversation's agent; it logs what it would have said and done without posting or writing",
+				Resource:       "arn:aws:states:::ecs:runTask.sync",
+				Parameters:     shadow,
+				TimeoutSeconds: timeout,
+				Catch:          []Catch{{ErrorEquals: []string{"States.ALL"}, ResultPath: "$.shadowError", Next: "ShadowEnded"}},
+				Next:           "ShadowEnded",
+			},
+			"ShadowEnded": {Type: "Succeed"},
+			"ClaimWarmAgent": {
+				Type:     "Task",
+				Resource: "arn:aws:states:::lambda:invoke",
+				Parameters: map[string]any{
+					"FunctionName": p.ClaimFunction,
+					"Payload.$":    "$",
+				},
+				ResultSelector: map[string]any{"claimed.$": "$.Payload.claimed"},
+				ResultPath:     "$.warmPool",
+				TimeoutSeconds: 10,
+				Catch:          []Catch{{ErrorEquals: []string{"States.ALL"}, ResultPath: "$.warmPoolError", Next: "HasTaskSize"}},
+				Next:           "WarmAgentClaimed",
+			},
+			"WarmAgentClaimed": {
+				Type:    "Choice",
+				Choices: []map[string]any{{"Variable": "$.warmPool.claimed", "BooleanEquals": true, "Next": "HandledByWarmAgent"}},
+				Default: "HasTaskSize",
+			},
+			"HandledByWarmAgent": {Type: "Succeed"},
+			"HasTaskSize": {
+				Type:    "Choice",
+				Choices: []map[string]any{{"Variable": path(inputTask), "IsPresent": true, "Next": "ChooseCapacity"}},
+				Default: "DefaultTaskSize",
+			},
+			"DefaultTaskSize": {
+				Type: "Pass",
+				Result: map[string]any{
+					"definition": p.TaskDefinition,
+					"cpu":        "1024",
+					"memory":     "2048",
+				},
+				ResultPath: path(inputTask),
+				Next:       "ChooseCapacity",
+			},
+			"ChooseCapacity": {
+				Type: "Choice",
+				Choices: []map[string]any{
+					{
+						"And": []any{
+							map[string]any{"Variable": path(inputCapacity), "IsPresent": true},
+							map[string]any{"Variable": path(inputCapacity), "StringEquals": "spot"},
+						},
+						"Next": "RunSpotConversationTask",
+					},
+				},
+				Default: "RunConversationTask",
+			},
+			"RunSpotConversationTask": {
+				Type:           "Task",
+				Comment:        "Runs on Fargate Spot; a reclaimed task checkpoints and is relaunched on regular Fargate, as is one that can't get Spot capacity",
+				Resource:       "arn:aws:states:::ecs:runTask.sync",
+				Parameters:     spot,
+				ResultSelector: map[string]any{"stopCode.$": "$.StopCode"},
+				ResultPath:     "$.spotTask",
+				TimeoutSeconds: timeout,
+				Catch:          []Catch{{ErrorEquals: []string{"States.ALL"}, ResultPath: "$.spotError", Next: "RunConversationTask"}},
+				Next:           "SpotTaskReclaimed",
+			},
+			"SpotTaskReclaimed": {
+				Type:    "Choice",
+				Choices: []map[string]any{{"Variable": "$.spotTask.stopCode", "StringEquals": "SpotInterruption", "Next": "RunConversationTask"}},
+				Default: "ConversationEnded",
+			},
+			"ConversationEnded": {Type: "Succeed"},
+			"RunConversationTask": {
+				Type:           "Task",
+				Resource:       "arn:aws:states:::ecs:runTask.sync",
+				Parameters:     onDemand,
+				TimeoutSeconds: timeout,
+				Retry:          []Retry{{ErrorEquals: []string{"States.TaskFailed"}, IntervalSeconds: 2, MaxAttempts: 2, BackoffRate: 1.5}},
+				Catch:          []Catch{{ErrorEquals: []string{"States.ALL"}, Next: "TaskFailed"}},
+				End:            true,
+			},
+			"TaskFailed": {
+				Type:  "Fail",
+				Error: "ConversationTaskFailed",
+				Cause: "ECS task failed to complete successfully",
+			},
+		},
+	}
+}
+
+// runTask returns the parameters of an ecs:runTask state for the agent,
+// placed by placementKey and passing the conversation, plus extra, in its
+// environment. EXECUTION_INPUT carries the whole input for the agent to
+// validate; the separate variables serve agents older than it
+func runTask(p Params, placementKey string, placement any, extra ...map[string]any) map[string]any {
+	environment := []any{
+		envFrom("CONVERSATION_ID", inputConversationID),
+		envFrom("CHANNEL_ID", inputChannelID),
+		envFrom("USER_ID", inputUserID),
+		map[string]any{"Name": "EXECUTION_INPUT", "Value.$": "States.JsonToString($$.Execution.Input)"},
+	}
+	for _, e := range extra {
+		environment = append(environment, e)
+	}
+
+	subnets := make([]any, len(p.Subnets))
+	for i, s := range p.Subnets {
+		subnets[i] = s
+	}
+
+	task := path(inputTask)
+	return map[string]any{
+		"Cluster":              p.ClusterArn,
+		"TaskDefinition.$":     task + ".definition",
+		placementKey:           placement,
+		"PropagateTags":        "TASK_DEFINITION",
+		"EnableExecuteCommand": true,
+		"NetworkConfiguration": map[string]any{
+			"AwsvpcConfiguration": map[string]any{
+				"Subnets":        subnets,
+				"SecurityGroups": []any{p.SecurityGroup},
+				"AssignPublicIp": "ENABLED",
+			},
+		},
+		"Overrides": map[string]any{
+			"Cpu.$":    task + ".cpu",
+			"Memory.$": task + ".memory",
+			"ContainerOverrides": []any{
+				map[string]any{
+					"Name":        AgentContainer,
+					"Environment": environment,
+				},
+			},
+		},
+	}
+}
+
+func env(name, value string) map[string]any {
+	return map[string]any{"Name": name, "Value": value}
+}
+
+func envFrom(name, key string) map[string]any {
+	return map[string]any{"Name": name, "Value.$": path(key)}
+}
+
+// path is the JSONPath to a key of the execution input
+func path(key string) string {
+	return "$." + key
+}
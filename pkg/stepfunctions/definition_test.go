@@ -0,0 +1,104 @@
+package stepfunctions
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// stackDefinition returns the state machine definition in the
+// CloudFormation stack, with its Fn::Sub variables left in
+func stackDefinition(t *testing.T) any {
+	t.Helper()
+	data, err := os.ReadFile("../../infrastructure/cloudformation/cloudops-stack.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stack := string(data)
+	start := strings.Index(stack, "  ConversationStateMachine:\n")
+	if start < 0 {
+		t.Fatal("stack has no ConversationStateMachine")
+	}
+	stack = stack[start:]
+	begin := strings.Index(stack, "- |\n")
+	end := strings.Index(stack, "- ClusterArn:")
+	if begin < 0 || end < begin {
+		t.Fatal("can't find the definition in ConversationStateMachine")
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(stack[begin+len("- |\n"):end]), &v); err != nil {
+		t.Fatalf("stack definition isn't JSON: %v", err)
+	}
+	return v
+}
+
+func TestDefinitionMatchesStack(t *testing.T) {
+	data, err := json.Marshal(Definition(Placeholders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := stackDefinition(t)
+	if reflect.DeepEqual(got, want) {
+		return
+	}
+	gotStates, _ := got.(map[string]any)["States"].(map[string]any)
+	wantStates, _ := want.(map[string]any)["States"].(map[string]any)
+	for name := range wantStates {
+		if !reflect.DeepEqual(gotStates[name], wantStates[name]) {
+			t.Errorf("state %s differs from the stack; run cloudopsctl generate-sfn -format cloudformation", name)
+		}
+	}
+	for name := range gotStates {
+		if _, ok := wantStates[name]; !ok {
+			t.Errorf("state %s is missing from the stack", name)
+		}
+	}
+	if !t.Failed() {
+		t.Error("definition differs from the stack outside its states")
+	}
+}
+
+func TestDefinitionReadsExecutionInput(t *testing.T) {
+	conv := &models.Conversation{ConversationID: "conv-1", ChannelID: "C1", UserID: "U1"}
+	sent := executionInput(conv, Launch{Capacity: "spot", TaskDefinition: "td", CPU: 512, Memory: 1024})
+	sent.Shadow = true
+	data, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var input map[string]any
+	if err := json.Unmarshal(data, &input); err != nil {
+		t.Fatal(err)
+	}
+
+	definition := Definition(Placeholders)
+	written := map[string]bool{"Payload": true, "StopCode": true} // task results
+	for _, state := range definition.States {
+		written[strings.TrimPrefix(state.ResultPath, "$.")] = true
+		for _, c := range state.Catch {
+			written[strings.TrimPrefix(c.ResultPath, "$.")] = true
+		}
+	}
+
+	data, err = json.Marshal(definition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, match := range strings.Split(string(data), `"$.`)[1:] {
+		key := match[:strings.IndexAny(match, `."`)]
+		if _, ok := input[key]; !ok && !written[key] {
+			t.Errorf("definition reads $.%s, which executionInput doesn't set", key)
+		}
+	}
+}
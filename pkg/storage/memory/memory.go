@@ -0,0 +1,464 @@
+// Package memory keeps conversations and their history in process, so the
+// agent and handlers can run without AWS while iterating on prompts and
+// tools. Nothing survives a restart
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+var _ storage.Store = (*Store)(nil)
+
+// Store is an in-memory storage.Store. It keeps the same conditions as the
+// DynamoDB store, so code that handles a lost race can be exercised locally
+type Store struct {
+	mu            sync.Mutex
+	conversations map[string]*models.Conversation
+	history       map[string][]models.ConversationHistoryItem
+}
+
+// New creates an empty store
+func New() *Store {
+	return &Store{
+		conversations: map[string]*models.Conversation{},
+		history:       map[string][]models.ConversationHistoryItem{},
+	}
+}
+
+// Save writes the whole conversation, failing with
+// storage.ErrConditionalCheckFailed when it changed since conv was read
+func (s *Store) Save(_ context.Context, conv *models.Conversation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stored int64
+	if c, ok := s.conversations[conv.ConversationID]; ok {
+		stored = c.Version
+	}
+	if stored != conv.Version {
+		return fmt.Errorf("save conversation %s: %w", conv.ConversationID, storage.ErrConditionalCheckFailed)
+	}
+	conv.Version++
+	s.conversations[conv.ConversationID] = clone(conv)
+	return nil
+}
+
+// Modify reads a conversation, applies fn, and saves it. Holding the lock
+// throughout means no other writer can get in between
+func (s *Store) Modify(_ context.Context, conversationID string, fn func(conv *models.Conversation) error) (*models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.find(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	conv := clone(stored)
+	if err := fn(conv); err != nil {
+		return nil, err
+	}
+	conv.Version++
+	s.conversations[conversationID] = clone(conv)
+	return conv, nil
+}
+
+// GetByID retrieves a conversation by ID
+func (s *Store) GetByID(_ context.Context, conversationID string) (*models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, err := s.find(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return clone(conv), nil
+}
+
+// GetByChannelID retrieves the most recent conversation that has the
+// channel to itself
+func (s *Store) GetByChannelID(_ context.Context, channelID string) (*models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *models.Conversation
+	for _, conv := range s.conversations {
+		if conv.ChannelID != channelID || conv.Threaded() {
+			continue
+		}
+		if latest == nil || conv.CreatedAt.After(latest.CreatedAt) {
+			latest = conv
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no conversation found for channel %s", channelID)
+	}
+	return clone(latest), nil
+}
+
+// GetByThread retrieves the conversation confined to a thread
+func (s *Store) GetByThread(_ context.Context, channelID, threadTS string) (*models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := models.ThreadKey(channelID, threadTS)
+	for _, conv := range s.conversations {
+		if conv.ThreadKey == key {
+			return clone(conv), nil
+		}
+	}
+	return nil, fmt.Errorf("no conversation found for thread %s in channel %s", threadTS, channelID)
+}
+
+// GetByMessage retrieves the conversation a message belongs to: the one
+// confined to its thread when there is one, otherwise the channel's
+func (s *Store) GetByMessage(ctx context.Context, channelID, threadTS string) (*models.Conversation, error) {
+	if threadTS != "" {
+		if conv, err := s.GetByThread(ctx, channelID, threadTS); err == nil {
+			return conv, nil
+		}
+	}
+	return s.GetByChannelID(ctx, channelID)
+}
+
+// GetByStatus retrieves conversations with a specific status
+func (s *Store) GetByStatus(ctx context.Context, status string) ([]*models.Conversation, error) {
+	return s.GetByStatusSince(ctx, status, time.Time{})
+}
+
+// GetByStatusSince retrieves conversations with a status created at or
+// after since, oldest first
+func (s *Store) GetByStatusSince(_ context.Context, status string, since time.Time) ([]*models.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var conversations []*models.Conversation
+	for _, conv := range s.conversations {
+		if conv.Status == status && !conv.CreatedAt.Before(since) {
+			conversations = append(conversations, clone(conv))
+		}
+	}
+	slices.SortFunc(conversations, func(a, b *models.Conversation) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return conversations, nil
+}
+
+// UpdateStatus updates the conversation status
+func (s *Store) UpdateStatus(_ context.Context, conversationID string, status string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.UpdateStatus(status)
+		return nil
+	})
+}
+
+// UpdateHeartbeat updates the last activity timestamp
+func (s *Store) UpdateHeartbeat(_ context.Context, conversationID string, timestamp time.Time) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.LastHeartbeat = timestamp
+		return nil
+	})
+}
+
+// UpdateTaskArn records the task running the conversation's agent
+func (s *Store) UpdateTaskArn(_ context.Context, conversationID, taskArn string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.TaskArn = taskArn
+		return nil
+	})
+}
+
+// UpdateScratchpad replaces the conversation's scratchpad
+func (s *Store) UpdateScratchpad(_ context.Context, conversationID string, scratchpad *models.Scratchpad) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Scratchpad = scratchpad
+		return nil
+	})
+}
+
+// UpdateEntities replaces the conversation's entities
+func (s *Store) UpdateEntities(_ context.Context, conversationID string, entities []models.Entity) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Entities = entities
+		return nil
+	})
+}
+
+// UpdateParticipants replaces the list of users who took part in a conversation
+func (s *Store) UpdateParticipants(_ context.Context, conversationID string, participants []string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Participants = participants
+		return nil
+	})
+}
+
+// UpdateTags replaces the tags on a conversation
+func (s *Store) UpdateTags(_ context.Context, conversationID string, tags []string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Tags = tags
+		return nil
+	})
+}
+
+// UpdateEmbedding stores the embedding of a conversation's initial command
+func (s *Store) UpdateEmbedding(_ context.Context, conversationID string, embedding []float32) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Embedding = embedding
+		return nil
+	})
+}
+
+// UpdatePromptVersion records which system prompt version a conversation uses
+func (s *Store) UpdatePromptVersion(_ context.Context, conversationID string, version int) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.PromptVersion = version
+		return nil
+	})
+}
+
+// UpdateSummary saves the rolling summary of a conversation's first
+// summarized history messages. A summary covering fewer messages than the
+// saved one is ignored
+func (s *Store) UpdateSummary(_ context.Context, conversationID, summary string, summarized int) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		if conv.Summarized < summarized {
+			conv.Summary = summary
+			conv.Summarized = summarized
+		}
+		return nil
+	})
+}
+
+// UpdateCostCenter records the team a conversation's usage is charged to
+func (s *Store) UpdateCostCenter(_ context.Context, conversationID, costCenter string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.CostCenter = costCenter
+		return nil
+	})
+}
+
+// UpdateSLA replaces the SLA timers on a conversation
+func (s *Store) UpdateSLA(_ context.Context, conversationID string, sla *models.SLA) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.SLA = sla
+		return nil
+	})
+}
+
+// SetDebug turns debug mode on or off for a conversation
+func (s *Store) SetDebug(_ context.Context, conversationID string, on bool) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.Debug = on
+		return nil
+	})
+}
+
+// AddRelated links a conversation to another that may be the same incident
+func (s *Store) AddRelated(_ context.Context, conversationID, relatedID string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		if !slices.Contains(conv.Related, relatedID) {
+			conv.Related = append(conv.Related, relatedID)
+		}
+		return nil
+	})
+}
+
+// AddTokens adds the model tokens a turn used to a conversation's totals
+func (s *Store) AddTokens(_ context.Context, conversationID string, input, output int64) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.InputTokens += input
+		conv.OutputTokens += output
+		return nil
+	})
+}
+
+// SaveCheckpoint records where an interrupted conversation resumes
+func (s *Store) SaveCheckpoint(_ context.Context, conversationID, resumeTS string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		conv.ResumeTS = resumeTS
+		conv.Interruptions++
+		return nil
+	})
+}
+
+// TimeOut times out a pending or active conversation whose last heartbeat
+// is before staleBefore, reporting whether it did
+func (s *Store) TimeOut(_ context.Context, conversationID string, staleBefore time.Time) (bool, error) {
+	errNotStale := errors.New("not stale")
+	err := s.update(conversationID, func(conv *models.Conversation) error {
+		if !pendingOrActive(conv) || !conv.LastHeartbeat.Before(staleBefore) {
+			return errNotStale
+		}
+		conv.UpdateStatus(models.StatusTimeout)
+		return nil
+	})
+	if errors.Is(err, errNotStale) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MergeInto ends a conversation as merged into intoID, failing with
+// storage.ErrNotMergeable when it already ended
+func (s *Store) MergeInto(_ context.Context, conversationID, intoID string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		if !pendingOrActive(conv) || conv.MergedInto != "" {
+			return storage.ErrNotMergeable
+		}
+		conv.UpdateStatus(models.StatusCompleted)
+		conv.MergedInto = intoID
+		return nil
+	})
+}
+
+// Escalate marks a conversation as handed to experts by userID, failing
+// with storage.ErrAlreadyEscalated when someone already did
+func (s *Store) Escalate(_ context.Context, conversationID, userID string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		if conv.HumanAssisted {
+			return storage.ErrAlreadyEscalated
+		}
+		now := time.Now()
+		conv.HumanAssisted = true
+		conv.EscalatedBy = userID
+		conv.EscalatedAt = &now
+		return nil
+	})
+}
+
+// Transfer moves a conversation from one channel to another with the given
+// visibility, failing with storage.ErrConversationMoved when it is no
+// longer in fromChannelID
+func (s *Store) Transfer(_ context.Context, conversationID, fromChannelID, toChannelID, visibility string) error {
+	return s.update(conversationID, func(conv *models.Conversation) error {
+		if conv.ChannelID != fromChannelID {
+			return storage.ErrConversationMoved
+		}
+		conv.MoveTo(toChannelID)
+		conv.Visibility = visibility
+		return nil
+	})
+}
+
+// SaveMessage appends a message to the conversation history
+func (s *Store) SaveMessage(_ context.Context, conversationID, role, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.history[conversationID] = append(s.history[conversationID], models.ConversationHistoryItem{
+		ConversationID: conversationID,
+		MessageIndex:   len(s.history[conversationID]),
+		Role:           role,
+		Content:        content,
+		CreatedAt:      now,
+		TTL:            now.AddDate(0, 0, 7).Unix(),
+	})
+	return nil
+}
+
+// ReplaceHistory overwrites a conversation's history with items and drops
+// its rolling summary, since the messages it covered have moved
+func (s *Store) ReplaceHistory(_ context.Context, conversationID string, items []models.ConversationHistoryItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := slices.Clone(s.history[conversationID])
+	for _, item := range items {
+		if item.MessageIndex < 0 {
+			return fmt.Errorf("replace history: negative message index %d", item.MessageIndex)
+		}
+		for len(history) <= item.MessageIndex {
+			history = append(history, models.ConversationHistoryItem{})
+		}
+		history[item.MessageIndex] = item
+	}
+	s.history[conversationID] = history
+
+	if conv, ok := s.conversations[conversationID]; ok {
+		conv.Summary = ""
+		conv.Summarized = 0
+		conv.Version++
+	}
+	return nil
+}
+
+// GetMessageHistory retrieves conversation history for a conversation
+func (s *Store) GetMessageHistory(ctx context.Context, conversationID string) ([]models.Message, error) {
+	items, err := s.GetHistoryItems(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.Message, len(items))
+	for i, item := range items {
+		messages[i] = models.Message{
+			Role:    item.Role,
+			Content: item.Content,
+		}
+	}
+	return messages, nil
+}
+
+// GetHistoryItems retrieves the stored history items for a conversation in order
+func (s *Store) GetHistoryItems(_ context.Context, conversationID string) ([]models.ConversationHistoryItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.history[conversationID]), nil
+}
+
+// update applies fn to a stored conversation and bumps its version, as the
+// DynamoDB store does for every partial update. Nothing changes when fn
+// fails
+func (s *Store) update(conversationID string, fn func(conv *models.Conversation) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.find(conversationID)
+	if err != nil {
+		return err
+	}
+	conv := clone(stored)
+	if err := fn(conv); err != nil {
+		return err
+	}
+	conv.Version++
+	s.conversations[conversationID] = clone(conv) // fn may have stored the caller's slices
+	return nil
+}
+
+// find returns the stored conversation; callers hold the lock
+func (s *Store) find(conversationID string) (*models.Conversation, error) {
+	conv, ok := s.conversations[conversationID]
+	if !ok {
+		return nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+	return conv, nil
+}
+
+func pendingOrActive(conv *models.Conversation) bool {
+	return conv.Status == models.StatusPending || conv.Status == models.StatusActive
+}
+
+// clone deep copies a conversation so callers can't change what is stored
+// by holding on to it
+func clone(conv *models.Conversation) *models.Conversation {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		panic(fmt.Sprintf("clone conversation: %v", err))
+	}
+	var c models.Conversation
+	if err := json.Unmarshal(data, &c); err != nil {
+		panic(fmt.Sprintf("clone conversation: %v", err))
+	}
+	return &c
+}
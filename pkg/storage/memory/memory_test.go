@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/savaki/cloudops-bot/pkg/storage"
+)
+
+func TestSaveVersion(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	conv := models.NewConversation("C1", "U1", "why is checkout slow?")
+	if err := s.Save(ctx, conv); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	stale, _ := s.GetByID(ctx, conv.ConversationID)
+	if err := s.UpdateStatus(ctx, conv.ConversationID, models.StatusActive); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := s.Save(ctx, stale); !errors.Is(err, storage.ErrConditionalCheckFailed) {
+		t.Errorf("Save(stale) error = %v, want ErrConditionalCheckFailed", err)
+	}
+
+	got, err := s.Modify(ctx, conv.ConversationID, func(c *models.Conversation) error {
+		c.Summary = "slow since the deploy"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Modify() error = %v", err)
+	}
+	if got.Status != models.StatusActive || got.Summary != "slow since the deploy" {
+		t.Errorf("Modify() = %+v", got)
+	}
+	if err := s.Save(ctx, got); err != nil {
+		t.Errorf("Save(fresh) error = %v", err)
+	}
+}
+
+func TestReadsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	conv := models.NewConversation("C1", "U1", "disk full on db-1")
+	conv.Tags = []string{"db"}
+	if err := s.Save(ctx, conv); err != nil {
+		t.Fatal(err)
+	}
+
+	conv.Tags[0] = "changed"
+	got, _ := s.GetByID(ctx, conv.ConversationID)
+	got.Status = models.StatusFailed
+
+	again, _ := s.GetByID(ctx, conv.ConversationID)
+	if again.Tags[0] != "db" || again.Status != models.StatusPending {
+		t.Errorf("GetByID() = %+v, changed through a copy", again)
+	}
+}
+
+func TestLookups(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	older := models.NewConversation("C1", "U1", "deploy failed")
+	older.CreatedAt = older.CreatedAt.Add(-time.Hour)
+	newer := models.NewConversation("C1", "U1", "deploy failed again")
+	threaded := models.NewConversation("C1", "U2", "why is checkout slow?")
+	threaded.SetThread("100.1")
+	for _, conv := range []*models.Conversation{older, newer, threaded} {
+		if err := s.Save(ctx, conv); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := s.GetByChannelID(ctx, "C1"); err != nil || got.ConversationID != newer.ConversationID {
+		t.Errorf("GetByChannelID() = %v, %v; want the newest unthreaded", got, err)
+	}
+	if got, err := s.GetByMessage(ctx, "C1", "100.1"); err != nil || got.ConversationID != threaded.ConversationID {
+		t.Errorf("GetByMessage(thread) = %v, %v", got, err)
+	}
+	if got, err := s.GetByMessage(ctx, "C1", "200.1"); err != nil || got.ConversationID != newer.ConversationID {
+		t.Errorf("GetByMessage(other thread) = %v, %v", got, err)
+	}
+	if _, err := s.GetByChannelID(ctx, "C2"); err == nil {
+		t.Error("GetByChannelID() should fail for an unknown channel")
+	}
+
+	pending, _ := s.GetByStatusSince(ctx, models.StatusPending, newer.CreatedAt.Add(-time.Minute))
+	if len(pending) != 2 {
+		t.Errorf("GetByStatusSince() = %d conversations, want 2", len(pending))
+	}
+}
+
+func TestConditionalUpdates(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	conv := models.NewConversation("C1", "U1", "deploy failed")
+	if err := s.Save(ctx, conv); err != nil {
+		t.Fatal(err)
+	}
+	id := conv.ConversationID
+
+	if err := s.Escalate(ctx, id, "U2"); err != nil {
+		t.Errorf("Escalate() error = %v", err)
+	}
+	if err := s.Escalate(ctx, id, "U3"); !errors.Is(err, storage.ErrAlreadyEscalated) {
+		t.Errorf("Escalate() again error = %v, want ErrAlreadyEscalated", err)
+	}
+
+	if err := s.Transfer(ctx, id, "C2", "C3", privacy.Private); !errors.Is(err, storage.ErrConversationMoved) {
+		t.Errorf("Transfer(wrong channel) error = %v, want ErrConversationMoved", err)
+	}
+	if err := s.Transfer(ctx, id, "C1", "C3", privacy.Private); err != nil {
+		t.Errorf("Transfer() error = %v", err)
+	}
+	if got, _ := s.GetByID(ctx, id); got.ChannelID != "C3" || got.Visibility != privacy.Private {
+		t.Errorf("after Transfer() ChannelID = %s, Visibility = %q; want C3, private", got.ChannelID, got.Visibility)
+	}
+
+	if ok, err := s.TimeOut(ctx, id, conv.LastHeartbeat); ok || err != nil {
+		t.Errorf("TimeOut(fresh) = %v, %v; want false", ok, err)
+	}
+	if ok, err := s.TimeOut(ctx, id, time.Now().Add(time.Minute)); !ok || err != nil {
+		t.Errorf("TimeOut(stale) = %v, %v; want true", ok, err)
+	}
+	if err := s.MergeInto(ctx, id, "conv-other"); !errors.Is(err, storage.ErrNotMergeable) {
+		t.Errorf("MergeInto(ended) error = %v, want ErrNotMergeable", err)
+	}
+
+	got, _ := s.GetByID(ctx, id)
+	if got.ChannelID != "C3" || got.OriginChannel != "C1" || got.EscalatedBy != "U2" || got.Status != models.StatusTimeout {
+		t.Errorf("GetByID() = %+v", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	conv := models.NewConversation("C1", "U1", "deploy failed")
+	if err := s.Save(ctx, conv); err != nil {
+		t.Fatal(err)
+	}
+	id := conv.ConversationID
+
+	for _, content := range []string{"deploy failed", "which service?", "checkout"} {
+		if err := s.SaveMessage(ctx, id, models.RoleUser, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.UpdateSummary(ctx, id, "deploy of checkout failed", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateSummary(ctx, id, "older summary", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.GetByID(ctx, id); got.Summary != "deploy of checkout failed" {
+		t.Errorf("Summary = %q, a shorter summary should be ignored", got.Summary)
+	}
+
+	items, _ := s.GetHistoryItems(ctx, id)
+	items[1].Content = "[redacted]"
+	if err := s.ReplaceHistory(ctx, id, items); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := s.GetMessageHistory(ctx, id)
+	if err != nil || len(messages) != 3 || messages[1].Content != "[redacted]" {
+		t.Errorf("GetMessageHistory() = %+v, %v", messages, err)
+	}
+	if got, _ := s.GetByID(ctx, id); got.Summary != "" || got.Summarized != 0 {
+		t.Errorf("ReplaceHistory() should drop the summary, got %q covering %d", got.Summary, got.Summarized)
+	}
+}
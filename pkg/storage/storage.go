@@ -0,0 +1,93 @@
+// Package storage defines how handlers and the agent persist conversations
+// and their message history, so they can be tested without AWS. The
+// dynamodb package provides the implementation used in production
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+var (
+	// ErrConditionalCheckFailed is returned by Save when the conversation
+	// was written by someone else since it was read
+	ErrConditionalCheckFailed = errors.New("conditional check failed")
+
+	// ErrConversationMoved is returned when a conversation is no longer in
+	// the channel a transfer expected
+	ErrConversationMoved = errors.New("conversation moved")
+
+	// ErrAlreadyEscalated is returned when a conversation has already been
+	// handed to experts
+	ErrAlreadyEscalated = errors.New("conversation already escalated")
+
+	// ErrNotMergeable is returned when a conversation being merged has
+	// ended or was already merged
+	ErrNotMergeable = errors.New("conversation not mergeable")
+)
+
+// ConversationStore persists conversation records
+type ConversationStore interface {
+	// Save writes the whole conversation, failing with
+	// ErrConditionalCheckFailed when it changed since conv was read
+	Save(ctx context.Context, conv *models.Conversation) error
+	// Modify reads a conversation, applies fn, and saves it, retrying fn
+	// on fresh state when another writer got there first
+	Modify(ctx context.Context, conversationID string, fn func(conv *models.Conversation) error) (*models.Conversation, error)
+
+	GetByID(ctx context.Context, conversationID string) (*models.Conversation, error)
+	GetByChannelID(ctx context.Context, channelID string) (*models.Conversation, error)
+	GetByThread(ctx context.Context, channelID, threadTS string) (*models.Conversation, error)
+	GetByMessage(ctx context.Context, channelID, threadTS string) (*models.Conversation, error)
+	GetByStatus(ctx context.Context, status string) ([]*models.Conversation, error)
+	GetByStatusSince(ctx context.Context, status string, since time.Time) ([]*models.Conversation, error)
+
+	UpdateStatus(ctx context.Context, conversationID string, status string) error
+	UpdateHeartbeat(ctx context.Context, conversationID string, timestamp time.Time) error
+	UpdateTaskArn(ctx context.Context, conversationID, taskArn string) error
+	UpdateScratchpad(ctx context.Context, conversationID string, scratchpad *models.Scratchpad) error
+	UpdateEntities(ctx context.Context, conversationID string, entities []models.Entity) error
+	UpdateParticipants(ctx context.Context, conversationID string, participants []string) error
+	UpdateTags(ctx context.Context, conversationID string, tags []string) error
+	UpdateEmbedding(ctx context.Context, conversationID string, embedding []float32) error
+	UpdatePromptVersion(ctx context.Context, conversationID string, version int) error
+	UpdateSummary(ctx context.Context, conversationID, summary string, summarized int) error
+	UpdateCostCenter(ctx context.Context, conversationID, costCenter string) error
+	UpdateSLA(ctx context.Context, conversationID string, sla *models.SLA) error
+	SetDebug(ctx context.Context, conversationID string, on bool) error
+	AddRelated(ctx context.Context, conversationID, relatedID string) error
+	AddTokens(ctx context.Context, conversationID string, input, output int64) error
+	SaveCheckpoint(ctx context.Context, conversationID, resumeTS string) error
+
+	// TimeOut times out a pending or active conversation whose last
+	// heartbeat is before staleBefore, reporting whether it did
+	TimeOut(ctx context.Context, conversationID string, staleBefore time.Time) (bool, error)
+	// MergeInto ends a conversation as merged into intoID, failing with
+	// ErrNotMergeable when it already ended
+	MergeInto(ctx context.Context, conversationID, intoID string) error
+	// Escalate hands a conversation to experts, failing with
+	// ErrAlreadyEscalated when someone already did
+	Escalate(ctx context.Context, conversationID, userID string) error
+	// Transfer moves a conversation between channels, taking the visibility
+	// of the new one, and fails with ErrConversationMoved when it is no
+	// longer in fromChannelID
+	Transfer(ctx context.Context, conversationID, fromChannelID, toChannelID, visibility string) error
+}
+
+// MessageStore persists the message history of conversations
+type MessageStore interface {
+	SaveMessage(ctx context.Context, conversationID, role, content string) error
+	// ReplaceHistory rewrites a conversation's history with items
+	ReplaceHistory(ctx context.Context, conversationID string, items []models.ConversationHistoryItem) error
+	GetMessageHistory(ctx context.Context, conversationID string) ([]models.Message, error)
+	GetHistoryItems(ctx context.Context, conversationID string) ([]models.ConversationHistoryItem, error)
+}
+
+// Store persists conversations and their message history
+type Store interface {
+	ConversationStore
+	MessageStore
+}
@@ -0,0 +1,128 @@
+package tasksize
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Conversation types, chosen from the opening message
+const (
+	TypeQuestion = "question" // quick Q&A
+	TypeIncident = "incident" // live investigation with heavy tooling
+)
+
+var errInvalidSize = errors.New("want type=cpu/memory or type=cpu/memory/task-definition")
+
+// incidentPattern matches opening messages that describe something broken
+// rather than ask a question
+var incidentPattern = regexp.MustCompile(`(?i)\b(incident|outage|sev ?[0-9]|p[0-9]|down|degraded|failing|failed|failures?|errors?|5[0-9][0-9]s?|latency|timeouts?|spik(e|ing)|paged|alarm(ing)?|unhealthy|crash(ing|ed)?|root cause|investigate)\b`)
+
+// Classify picks the conversation type for an opening message
+func Classify(text string) string {
+	if incidentPattern.MatchString(text) {
+		return TypeIncident
+	}
+	return TypeQuestion
+}
+
+// Size is the Fargate task an agent runs in. An empty TaskDefinition uses
+// the stack's agent task definition
+type Size struct {
+	CPU            int // CPU units, 1024 per vCPU
+	Memory         int // MiB
+	TaskDefinition string
+}
+
+// Sizes maps conversation types to task sizes
+type Sizes map[string]Size
+
+// DefaultSizes gives questions a small task and incidents the task
+// definition's full size
+func DefaultSizes() Sizes {
+	return Sizes{
+		TypeQuestion: {CPU: 512, Memory: 1024},
+		TypeIncident: {CPU: 1024, Memory: 2048},
+	}
+}
+
+// ParseSizes parses "question=256/512,incident=2048/4096/cloudops-agent-tools"
+// over the defaults
+func ParseSizes(s string) (Sizes, error) {
+	sizes := DefaultSizes()
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, spec, ok := strings.Cut(entry, "=")
+		parts := strings.SplitN(spec, "/", 3)
+		if !ok || len(parts) < 2 {
+			return nil, fmt.Errorf("invalid task size %q: %w", entry, errInvalidSize)
+		}
+
+		cpu, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu in %q: %w", entry, errInvalidSize)
+		}
+		memory, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory in %q: %w", entry, errInvalidSize)
+		}
+		size := Size{CPU: cpu, Memory: memory}
+		if len(parts) == 3 {
+			size.TaskDefinition = strings.TrimSpace(parts[2])
+		}
+		if err := size.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid task size %q: %w", entry, err)
+		}
+
+		sizes[strings.ToLower(strings.TrimSpace(kind))] = size
+	}
+	return sizes, nil
+}
+
+// For returns the size for a conversation type, falling back to the
+// incident size so an unknown type never gets an undersized task
+func (s Sizes) For(kind string) Size {
+	if size, ok := s[kind]; ok {
+		return size
+	}
+	if size, ok := s[TypeIncident]; ok {
+		return size
+	}
+	return DefaultSizes()[TypeIncident]
+}
+
+// Validate checks the CPU and memory are a combination Fargate supports
+func (s Size) Validate() error {
+	var low, high, step int
+	switch s.CPU {
+	case 256:
+		if s.Memory == 512 || s.Memory == 1024 || s.Memory == 2048 {
+			return nil
+		}
+		return fmt.Errorf("256 cpu supports 512, 1024, or 2048 MiB")
+	case 512:
+		low, high, step = 1024, 4096, 1024
+	case 1024:
+		low, high, step = 2048, 8192, 1024
+	case 2048:
+		low, high, step = 4096, 16384, 1024
+	case 4096:
+		low, high, step = 8192, 30720, 1024
+	case 8192:
+		low, high, step = 16384, 61440, 4096
+	case 16384:
+		low, high, step = 32768, 122880, 8192
+	default:
+		return fmt.Errorf("cpu must be 256, 512, 1024, 2048, 4096, 8192, or 16384")
+	}
+	if s.Memory < low || s.Memory > high || (s.Memory-low)%step != 0 {
+		return fmt.Errorf("%d cpu supports %d-%d MiB in %d MiB steps", s.CPU, low, high, step)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package tasksize
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := map[string]string{
+		"what's the difference between gp2 and gp3?":         TypeQuestion,
+		"how many instances are in the prod ASG":             TypeQuestion,
+		"checkout is throwing 502s since the deploy":         TypeIncident,
+		"SEV2: payments API latency spike":                   TypeIncident,
+		"orders service is down, can you investigate":        TypeIncident,
+		"why is the RDS replica failing health checks?":      TypeIncident,
+		"list the Lambda functions that downloaded from S3?": TypeQuestion,
+	}
+	for text, want := range tests {
+		if got := Classify(text); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := ParseSizes("question=256/512, incident=2048/4096/arn:aws:ecs:us-east-1:123456789012:task-definition/cloudops-agent-tools:3")
+	if err != nil {
+		t.Fatalf("ParseSizes() error = %v", err)
+	}
+
+	if got := sizes.For(TypeQuestion); got != (Size{CPU: 256, Memory: 512}) {
+		t.Errorf("question = %+v", got)
+	}
+	want := Size{CPU: 2048, Memory: 4096, TaskDefinition: "arn:aws:ecs:us-east-1:123456789012:task-definition/cloudops-agent-tools:3"}
+	if got := sizes.For(TypeIncident); got != want {
+		t.Errorf("incident = %+v, want %+v", got, want)
+	}
+	if got := sizes.For("unknown"); got != want {
+		t.Errorf("unknown type = %+v, want the incident size", got)
+	}
+
+	defaults, err := ParseSizes("")
+	if err != nil {
+		t.Fatalf("ParseSizes(\"\") error = %v", err)
+	}
+	if got := defaults.For(TypeQuestion); got != DefaultSizes()[TypeQuestion] {
+		t.Errorf("default question = %+v", got)
+	}
+}
+
+func TestParseSizesInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"question",
+		"question=512",
+		"question=half/1024",
+		"question=512/lots",
+		"question=512/512",
+		"incident=3000/4096",
+		"incident=8192/20000",
+	} {
+		if _, err := ParseSizes(spec); err == nil {
+			t.Errorf("ParseSizes(%q) should fail", spec)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Size{{256, 512, ""}, {512, 4096, ""}, {1024, 2048, ""}, {4096, 30720, ""}, {8192, 20480, ""}, {16384, 122880, ""}}
+	for _, s := range valid {
+		if err := s.Validate(); err != nil {
+			t.Errorf("%+v: %v", s, err)
+		}
+	}
+
+	invalid := []Size{{256, 4096, ""}, {1024, 1024, ""}, {2048, 17408, ""}, {8192, 18432, ""}, {0, 0, ""}}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%+v should be invalid", s)
+		}
+	}
+}
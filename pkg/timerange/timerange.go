@@ -0,0 +1,392 @@
+package timerange
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// Lambda runtimes don't ship zoneinfo, so embed it for user timezones
+	_ "time/tzdata"
+)
+
+// Range is a time interval from Start to End
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the range
+func (r Range) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// String formats the range in the start time's location
+func (r Range) String() string {
+	const layout = "2006-01-02 15:04"
+	end := r.End.In(r.Start.Location())
+	if sameDay(r.Start, end) {
+		return fmt.Sprintf("%s-%s %s", r.Start.Format(layout), end.Format("15:04"), r.Start.Format("MST"))
+	}
+	return fmt.Sprintf("%s to %s %s", r.Start.Format(layout), end.Format(layout), r.Start.Format("MST"))
+}
+
+// Parser resolves natural-language time expressions like "last 2 hours",
+// "yesterday 3-5pm UTC", or "since the deploy"
+type Parser struct {
+	loc     *time.Location
+	now     func() time.Time
+	anchors map[string]time.Time
+}
+
+// New creates a parser that interprets wall-clock times in the given
+// location (the user's timezone); nil means UTC
+func New(loc *time.Location) *Parser {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Parser{
+		loc:     loc,
+		now:     time.Now,
+		anchors: make(map[string]time.Time),
+	}
+}
+
+// SetNow overrides the clock, mainly for tests
+func (p *Parser) SetNow(now func() time.Time) {
+	p.now = now
+}
+
+// SetAnchor registers a named event (e.g. "deploy") usable as "since the deploy"
+func (p *Parser) SetAnchor(name string, t time.Time) {
+	p.anchors[strings.ToLower(strings.TrimSpace(name))] = t
+}
+
+// Location returns the parser's default timezone
+func (p *Parser) Location() *time.Location {
+	return p.loc
+}
+
+var (
+	unitPattern  = `(m|mins?|minutes?|h|hrs?|hours?|d|days?|w|wks?|weeks?)`
+	clockPattern = `(\d{1,2})(?::(\d{2}))?\s*(am|pm)?`
+
+	lastRe       = regexp.MustCompile(`^(?:the\s+)?(?:last|past|previous)\s+(\d+)?\s*` + unitPattern + `$`)
+	durationRe   = regexp.MustCompile(`^(\d+)\s*` + unitPattern + `$`)
+	agoRe        = regexp.MustCompile(`^(\d+)\s*` + unitPattern + `\s+ago$`)
+	clockRangeRe = regexp.MustCompile(`^(?:(today|yesterday)\s+)?(?:from\s+)?` + clockPattern + `\s*(?:-|to|until)\s*` + clockPattern + `$`)
+	clockRe      = regexp.MustCompile(`^(?:(today|yesterday)\s+)?(?:at\s+)?` + clockPattern + `$`)
+	sinceRe      = regexp.MustCompile(`^since\s+(.+)$`)
+	betweenRe    = regexp.MustCompile(`^(?:between|from)\s+(.+?)\s+(?:and|to|until)\s+(.+)$`)
+	toRe         = regexp.MustCompile(`^(.+?)\s+(?:to|until)\s+(.+)$`)
+)
+
+// zoneAliases maps common abbreviations to IANA zones
+var zoneAliases = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"pst": "America/Los_Angeles", "pdt": "America/Los_Angeles", "pt": "America/Los_Angeles",
+	"mst": "America/Denver", "mdt": "America/Denver", "mt": "America/Denver",
+	"cst": "America/Chicago", "cdt": "America/Chicago", "ct": "America/Chicago",
+	"est": "America/New_York", "edt": "America/New_York", "et": "America/New_York",
+	"bst": "Europe/London", "cet": "Europe/Paris", "cest": "Europe/Paris",
+	"ist": "Asia/Kolkata", "jst": "Asia/Tokyo", "aest": "Australia/Sydney",
+}
+
+// Parse resolves a time range expression relative to now. A trailing
+// timezone ("UTC", "PST", "Europe/Berlin") overrides the parser's location
+func (p *Parser) Parse(text string) (Range, error) {
+	expr, loc := p.splitZone(text)
+	now := p.now().In(loc)
+
+	r, ok, err := p.parseRange(expr, now, loc)
+	if err != nil {
+		return Range{}, err
+	}
+	if !ok {
+		return Range{}, fmt.Errorf("unrecognized time range %q", text)
+	}
+	if !r.Start.Before(r.End) {
+		return Range{}, fmt.Errorf("time range %q starts after it ends", text)
+	}
+	return r, nil
+}
+
+// ParseTime resolves a single point in time such as "now", "2h ago",
+// "yesterday 3pm", an RFC 3339 timestamp, or a registered anchor
+func (p *Parser) ParseTime(text string) (time.Time, error) {
+	expr, loc := p.splitZone(text)
+	t, ok := p.parseTime(expr, p.now().In(loc), loc)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unrecognized time %q", text)
+	}
+	return t, nil
+}
+
+// ParseBounds validates start/end arguments supplied to a tool. Either may
+// be a natural-language time, an empty end means now, and start may hold a
+// whole range expression on its own
+func (p *Parser) ParseBounds(start, end string) (Range, error) {
+	if strings.TrimSpace(start) == "" {
+		return Range{}, fmt.Errorf("start time is required")
+	}
+
+	// Allow the whole range in start, e.g. start="last 2 hours"
+	if strings.TrimSpace(end) == "" {
+		if r, err := p.Parse(start); err == nil {
+			return r, nil
+		}
+		end = "now"
+	}
+
+	s, err := p.ParseTime(start)
+	if err != nil {
+		return Range{}, err
+	}
+	e, err := p.ParseTime(end)
+	if err != nil {
+		return Range{}, err
+	}
+	if !s.Before(e) {
+		return Range{}, fmt.Errorf("start %s is not before end %s", s.Format(time.RFC3339), e.Format(time.RFC3339))
+	}
+	return Range{Start: s, End: e}, nil
+}
+
+// splitZone strips a trailing timezone from text, returning the lower-cased
+// expression and the location to interpret it in
+func (p *Parser) splitZone(text string) (string, *time.Location) {
+	text = strings.TrimSpace(text)
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return strings.ToLower(text), p.loc
+	}
+
+	last := fields[len(fields)-1]
+	name, ok := zoneAliases[strings.ToLower(last)]
+	if !ok && strings.Contains(last, "/") {
+		name = last
+	}
+	if name == "" {
+		return strings.ToLower(text), p.loc
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return strings.ToLower(text), p.loc
+	}
+	return strings.ToLower(strings.Join(fields[:len(fields)-1], " ")), loc
+}
+
+func (p *Parser) parseRange(expr string, now time.Time, loc *time.Location) (Range, bool, error) {
+	switch expr {
+	case "today":
+		return Range{Start: startOfDay(now), End: now}, true, nil
+	case "yesterday":
+		start := startOfDay(now).AddDate(0, 0, -1)
+		return Range{Start: start, End: start.AddDate(0, 0, 1)}, true, nil
+	case "this week":
+		day := startOfDay(now)
+		offset := (int(day.Weekday()) + 6) % 7 // weeks start on Monday
+		return Range{Start: day.AddDate(0, 0, -offset), End: now}, true, nil
+	}
+
+	if m := lastRe.FindStringSubmatch(expr); m != nil {
+		n := 1
+		if m[1] != "" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		return Range{Start: now.Add(-time.Duration(n) * unit(m[2])), End: now}, true, nil
+	}
+
+	if m := durationRe.FindStringSubmatch(expr); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return Range{Start: now.Add(-time.Duration(n) * unit(m[2])), End: now}, true, nil
+	}
+
+	if m := clockRangeRe.FindStringSubmatch(expr); m != nil {
+		r, err := clockRange(m, now)
+		return r, err == nil, err
+	}
+
+	if m := sinceRe.FindStringSubmatch(expr); m != nil {
+		start, ok := p.parseTime(m[1], now, loc)
+		if !ok {
+			return Range{}, false, fmt.Errorf("unrecognized time %q", m[1])
+		}
+		return Range{Start: start, End: now}, true, nil
+	}
+
+	for _, re := range []*regexp.Regexp{betweenRe, toRe} {
+		if m := re.FindStringSubmatch(expr); m != nil {
+			start, ok1 := p.parseTime(m[1], now, loc)
+			end, ok2 := p.parseTime(m[2], now, loc)
+			if ok1 && ok2 {
+				return Range{Start: start, End: end}, true, nil
+			}
+		}
+	}
+
+	return Range{}, false, nil
+}
+
+// timestampLayouts are accepted absolute formats, tried in order
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (p *Parser) parseTime(expr string, now time.Time, loc *time.Location) (time.Time, bool) {
+	expr = strings.TrimSpace(expr)
+
+	switch expr {
+	case "now":
+		return now, true
+	case "today":
+		return startOfDay(now), true
+	case "yesterday":
+		return startOfDay(now).AddDate(0, 0, -1), true
+	}
+
+	if m := agoRe.FindStringSubmatch(expr); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return now.Add(-time.Duration(n) * unit(m[2])), true
+	}
+
+	// Bare numbers are only clock times with a colon or am/pm
+	if m := clockRe.FindStringSubmatch(expr); m != nil && (m[3] != "" || m[4] != "" || m[1] != "") {
+		hour, min, ok := clock(m[2], m[3], m[4])
+		if ok {
+			return atClock(now, m[1], hour, min, m[1] == ""), true
+		}
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(expr), loc); err == nil {
+			return t, true
+		}
+	}
+
+	// Unix epoch seconds
+	if len(expr) >= 9 && len(expr) <= 10 {
+		if secs, err := strconv.ParseInt(expr, 10, 64); err == nil {
+			return time.Unix(secs, 0).In(loc), true
+		}
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(expr, "the "), "last ")
+	if t, ok := p.anchors[name]; ok {
+		return t.In(loc), true
+	}
+
+	return time.Time{}, false
+}
+
+// clockRange resolves "[today|yesterday] H[:MM][am|pm]-H[:MM][am|pm]"
+func clockRange(m []string, now time.Time) (Range, error) {
+	day := m[1]
+	startMeridiem, endMeridiem := m[4], m[7]
+
+	// "3-5pm" means 3pm-5pm
+	if startMeridiem == "" {
+		startMeridiem = endMeridiem
+	}
+
+	startHour, startMin, ok1 := clock(m[2], m[3], startMeridiem)
+	endHour, endMin, ok2 := clock(m[5], m[6], endMeridiem)
+	if !ok1 || !ok2 {
+		return Range{}, fmt.Errorf("invalid clock time in %q", m[0])
+	}
+
+	// "11-1pm" means 11am-1pm
+	if m[4] == "" && startHour*60+startMin >= endHour*60+endMin && startHour >= 12 {
+		startHour -= 12
+	}
+
+	start := atClock(now, day, startHour, startMin, false)
+	end := atClock(now, day, endHour, endMin, false)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	// Without an explicit day, a window that hasn't started yet means yesterday
+	if day == "" && start.After(now) {
+		start, end = start.AddDate(0, 0, -1), end.AddDate(0, 0, -1)
+	}
+	return Range{Start: start, End: end}, nil
+}
+
+// clock converts hour, minute, and meridiem strings to a 24-hour time
+func clock(h, m, meridiem string) (int, int, bool) {
+	hour, _ := strconv.Atoi(h)
+	min := 0
+	if m != "" {
+		min, _ = strconv.Atoi(m)
+	}
+	if min > 59 {
+		return 0, 0, false
+	}
+
+	switch meridiem {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour != 12 {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, false
+		}
+	}
+	return hour, min, true
+}
+
+// atClock returns the given wall-clock time on today or yesterday. When
+// pastOnly is set and no day was given, a future time rolls back a day
+func atClock(now time.Time, day string, hour, min int, pastOnly bool) time.Time {
+	t := startOfDay(now).Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+	if day == "yesterday" {
+		return t.AddDate(0, 0, -1)
+	}
+	if pastOnly && t.After(now) {
+		return t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+func unit(s string) time.Duration {
+	switch s[0] {
+	case 'm':
+		return time.Minute
+	case 'h':
+		return time.Hour
+	case 'd':
+		return 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
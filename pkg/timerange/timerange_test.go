@@ -0,0 +1,125 @@
+package timerange
+
+import (
+	"testing"
+	"time"
+)
+
+// testParser returns a parser whose clock reads Wednesday 2024-05-01 16:30
+// in the given location
+func testParser(t *testing.T, loc *time.Location) *Parser {
+	t.Helper()
+	p := New(loc)
+	p.SetNow(func() time.Time { return time.Date(2024, 5, 1, 16, 30, 0, 0, loc) })
+	return p
+}
+
+func TestParse(t *testing.T) {
+	p := testParser(t, time.UTC)
+	p.SetAnchor("deploy", time.Date(2024, 5, 1, 15, 45, 0, 0, time.UTC))
+
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 5, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		text      string
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"last 2 hours", at(1, 14, 30), at(1, 16, 30)},
+		{"past hour", at(1, 15, 30), at(1, 16, 30)},
+		{"Last 90 minutes", at(1, 15, 0), at(1, 16, 30)},
+		{"30m", at(1, 16, 0), at(1, 16, 30)},
+		{"last week", at(1, 16, 30).AddDate(0, 0, -7), at(1, 16, 30)},
+		{"today", at(1, 0, 0), at(1, 16, 30)},
+		{"yesterday", at(30, 0, 0).AddDate(0, -1, 0), at(1, 0, 0)},
+		{"this week", at(29, 0, 0).AddDate(0, -1, 0), at(1, 16, 30)},
+		{"yesterday 3-5pm", at(30, 15, 0).AddDate(0, -1, 0), at(30, 17, 0).AddDate(0, -1, 0)},
+		{"today 14:00-15:30", at(1, 14, 0), at(1, 15, 30)},
+		{"11-1pm", at(1, 11, 0), at(1, 13, 0)},
+		{"6pm-7pm", at(30, 18, 0).AddDate(0, -1, 0), at(30, 19, 0).AddDate(0, -1, 0)},
+		{"since 3pm", at(1, 15, 0), at(1, 16, 30)},
+		{"since the deploy", at(1, 15, 45), at(1, 16, 30)},
+		{"since 2h ago", at(1, 14, 30), at(1, 16, 30)},
+		{"between 1pm and 2pm", at(1, 13, 0), at(1, 14, 0)},
+		{"2024-05-01T10:00:00Z to 2024-05-01T12:00:00Z", at(1, 10, 0), at(1, 12, 0)},
+	}
+
+	for _, tt := range tests {
+		got, err := p.Parse(tt.text)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.text, err)
+			continue
+		}
+		if !got.Start.Equal(tt.wantStart) || !got.End.Equal(tt.wantEnd) {
+			t.Errorf("Parse(%q) = %v, want %v to %v", tt.text, got, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// User in LA asks about a UTC window; "yesterday" is relative to UTC
+	p := testParser(t, la)
+	got, err := p.Parse("yesterday 3-5pm UTC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := time.Date(2024, 4, 30, 15, 0, 0, 0, time.UTC)
+	if !got.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", got.Start.UTC(), want)
+	}
+
+	// Without a zone the user's timezone applies
+	got, err = p.Parse("today 9am-10am")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want = time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC) // 9am PDT
+	if !got.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", got.Start.UTC(), want)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := testParser(t, time.UTC)
+	for _, text := range []string{"", "whenever", "since the deploy", "25:00-26:00", "13pm-2pm"} {
+		if _, err := p.Parse(text); err == nil {
+			t.Errorf("Parse(%q) should error", text)
+		}
+	}
+}
+
+func TestParseBounds(t *testing.T) {
+	p := testParser(t, time.UTC)
+
+	tests := []struct {
+		start, end string
+		want       time.Duration
+		wantErr    bool
+	}{
+		{"2024-05-01T15:00:00Z", "2024-05-01T16:00:00Z", time.Hour, false},
+		{"2h ago", "", 2 * time.Hour, false},
+		{"last 30 minutes", "", 30 * time.Minute, false},
+		{"1714575600", "now", 90 * time.Minute, false},
+		{"now", "1h ago", 0, true},
+		{"", "now", 0, true},
+		{"soon", "now", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := p.ParseBounds(tt.start, tt.end)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBounds(%q, %q) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.Duration() != tt.want {
+			t.Errorf("ParseBounds(%q, %q) duration = %v, want %v", tt.start, tt.end, got.Duration(), tt.want)
+		}
+	}
+}
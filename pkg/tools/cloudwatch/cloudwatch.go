@@ -0,0 +1,361 @@
+// Package cloudwatch provides a tool that lets the model query CloudWatch
+// metrics, summarizing each series compactly and optionally charting it
+package cloudwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+)
+
+// Query bounds. The range matches what charts can show, and the period is
+// chosen so a series has at most maxPoints datapoints
+const (
+	DefaultRange  = "last 3 hours"
+	MaxRange      = charts.MaxHours * time.Hour
+	DefaultStat   = "Average"
+	maxPoints     = 500
+	maxListed     = 24
+	maxDimensions = 30
+)
+
+// sparks draw a series' shape in one line
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// highResolutionPeriods are the periods under a minute CloudWatch accepts
+var highResolutionPeriods = map[int]bool{1: true, 5: true, 10: true, 30: true}
+
+// API is the part of the CloudWatch client the tool uses
+type API interface {
+	GetMetricData(ctx context.Context, params *awscw.GetMetricDataInput, optFns ...func(*awscw.Options)) (*awscw.GetMetricDataOutput, error)
+}
+
+// Tool queries CloudWatch metrics for the model
+type Tool struct {
+	client API
+	now    func() time.Time
+}
+
+// New creates the tool using the agent's AWS credentials
+func New(cfg aws.Config) *Tool {
+	return NewWithClient(awscw.NewFromConfig(cfg))
+}
+
+// NewWithClient creates the tool with a custom CloudWatch client
+func NewWithClient(client API) *Tool {
+	return &Tool{client: client, now: time.Now}
+}
+
+// Input selects one metric series
+type Input struct {
+	Namespace  string            `json:"namespace"`
+	MetricName string            `json:"metric_name"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Stat       string            `json:"stat,omitempty"`
+	Period     int               `json:"period,omitempty"`
+	TimeRange  string            `json:"time_range,omitempty"`
+	Chart      bool              `json:"chart,omitempty"`
+}
+
+// Name identifies the tool to the model
+func (t *Tool) Name() string {
+	return "query_cloudwatch_metrics"
+}
+
+// Description tells the model what the tool does
+func (t *Tool) Description() string {
+	return fmt.Sprintf("Query one CloudWatch metric and summarize it: min, average, max and when it peaked, the latest value, its shape, and sampled values. "+
+		"Use it to check CPU, latency, error counts, queue depth and the like, e.g. AWS/ECS CPUUtilization for a service, or AWS/ApplicationELB HTTPCode_Target_5XX_Count for a load balancer. "+
+		"Dimensions must match the metric's exactly. The time range is at most %s. Set chart to post a graph of the metric with your answer.",
+		humanize.Duration(MaxRange))
+}
+
+// InputSchema is the JSON Schema of Input
+func (t *Tool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"namespace": map[string]interface{}{
+				"type":        "string",
+				"description": "Metric namespace, e.g. AWS/EC2, AWS/RDS, or a custom namespace",
+			},
+			"metric_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Metric name, e.g. CPUUtilization",
+			},
+			"dimensions": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Dimension values, e.g. {\"ClusterName\": \"prod\", \"ServiceName\": \"checkout\"}",
+			},
+			"stat": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Statistic: Average, Sum, Minimum, Maximum, SampleCount, or a percentile such as p99; defaults to %s", DefaultStat),
+			},
+			"period": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seconds per datapoint: 1, 5, 10, 30, or a multiple of 60; chosen from the time range when omitted",
+			},
+			"time_range": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Time range in UTC, e.g. \"last 6 hours\" or \"today 09:00-10:30\"; defaults to %q", DefaultRange),
+			},
+			"chart": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Post a chart of the metric in the conversation with the answer",
+			},
+		},
+		"required": []string{"namespace", "metric_name"},
+	}
+}
+
+// Execute queries the metric and summarizes it
+func (t *Tool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in Input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	r, period, err := t.bounds(in)
+	if err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	stat := in.Stat
+	if stat == "" {
+		stat = DefaultStat
+	}
+
+	input := &awscw.GetMetricDataInput{
+		MetricDataQueries: []types.MetricDataQuery{{
+			Id: aws.String("m1"),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String(in.Namespace),
+					MetricName: aws.String(in.MetricName),
+					Dimensions: dimensions(in.Dimensions),
+				},
+				Period: aws.Int32(int32(period)),
+				Stat:   aws.String(stat),
+			},
+			ReturnData: aws.Bool(true),
+		}},
+		StartTime: aws.Time(r.Start),
+		EndTime:   aws.Time(r.End),
+		ScanBy:    types.ScanByTimestampAscending,
+	}
+
+	var series Series
+	for {
+		output, err := t.client.GetMetricData(ctx, input)
+		if err != nil {
+			return "", fmt.Errorf("get metric data: %w", err)
+		}
+		for _, result := range output.MetricDataResults {
+			series.Timestamps = append(series.Timestamps, result.Timestamps...)
+			series.Values = append(series.Values, result.Values...)
+			for _, m := range result.Messages {
+				series.Messages = append(series.Messages, aws.ToString(m.Value))
+			}
+		}
+		for _, m := range output.Messages {
+			series.Messages = append(series.Messages, aws.ToString(m.Value))
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	series.Label = Label(in, stat, period)
+	summary := Format(series, r)
+
+	if in.Chart {
+		w := charts.Widget{
+			Title:   in.MetricName,
+			Metrics: []charts.Metric{{Namespace: in.Namespace, Name: in.MetricName, Dimensions: in.Dimensions, Stat: stat}},
+			Period:  period,
+			Start:   r.Start,
+			End:     r.End,
+		}
+		if charts.Request(ctx, w) {
+			summary += "\n\nA chart of this metric will be posted with your answer."
+		} else {
+			summary += "\n\nCharts can't be posted here; describe the metric in words."
+		}
+	}
+	return summary, nil
+}
+
+// bounds validates the input and resolves its time range and period
+func (t *Tool) bounds(in Input) (timerange.Range, int, error) {
+	if strings.TrimSpace(in.Namespace) == "" || strings.TrimSpace(in.MetricName) == "" {
+		return timerange.Range{}, 0, errors.New("namespace and metric_name are required")
+	}
+	if len(in.Dimensions) > maxDimensions {
+		return timerange.Range{}, 0, fmt.Errorf("a metric has at most %d dimensions", maxDimensions)
+	}
+
+	expr := in.TimeRange
+	if strings.TrimSpace(expr) == "" {
+		expr = DefaultRange
+	}
+	parser := timerange.New(time.UTC)
+	parser.SetNow(t.now)
+	r, err := parser.Parse(expr)
+	if err != nil {
+		return timerange.Range{}, 0, fmt.Errorf("time range %q: %w", expr, err)
+	}
+	if r.Duration() > MaxRange {
+		return timerange.Range{}, 0, fmt.Errorf("time range %s is longer than the %s limit; narrow it", r, humanize.Duration(MaxRange))
+	}
+
+	period := in.Period
+	switch {
+	case period <= 0:
+		period = Period(r.Duration())
+	case period%60 != 0 && !highResolutionPeriods[period]:
+		return timerange.Range{}, 0, fmt.Errorf("period must be 1, 5, 10, 30, or a multiple of 60 seconds")
+	case int(r.Duration()/time.Second)/period > maxPoints:
+		period = Period(r.Duration())
+	}
+	return r, period, nil
+}
+
+// Period returns the shortest whole-minute period that covers d in at
+// most maxPoints datapoints
+func Period(d time.Duration) int {
+	minutes := int(math.Ceil(d.Minutes() / maxPoints))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes * 60
+}
+
+// dimensions converts dimension values to CloudWatch's form, sorted by name
+func dimensions(values map[string]string) []types.Dimension {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dims := make([]types.Dimension, 0, len(names))
+	for _, name := range names {
+		dims = append(dims, types.Dimension{Name: aws.String(name), Value: aws.String(values[name])})
+	}
+	return dims
+}
+
+// Series is one metric's datapoints, oldest first
+type Series struct {
+	Label      string
+	Timestamps []time.Time
+	Values     []float64
+	Messages   []string // notes from CloudWatch, such as partial data
+}
+
+// Label describes the queried series, e.g.
+// "AWS/EC2 CPUUtilization (InstanceId=i-0abc) Average per 5m"
+func Label(in Input, stat string, period int) string {
+	label := in.Namespace + " " + in.MetricName
+	if len(in.Dimensions) > 0 {
+		var dims []string
+		for _, d := range dimensions(in.Dimensions) {
+			dims = append(dims, aws.ToString(d.Name)+"="+aws.ToString(d.Value))
+		}
+		label += " (" + strings.Join(dims, ", ") + ")"
+	}
+	return fmt.Sprintf("%s %s per %s", label, stat, humanize.Duration(time.Duration(period)*time.Second))
+}
+
+// Format summarizes a series in a few lines: its range of values and when
+// it peaked, its latest value, its shape, and values sampled across r
+func Format(s Series, r timerange.Range) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, %s", s.Label, r)
+	for _, m := range s.Messages {
+		fmt.Fprintf(&b, "\nNote: %s", m)
+	}
+	n := len(s.Values)
+	if n == 0 {
+		b.WriteString("\nNo datapoints. Check that the namespace, metric name, and dimensions match the metric exactly; a metric with no activity also has none.")
+		return b.String()
+	}
+
+	lo, hi, sum := s.Values[0], s.Values[0], 0.0
+	peak := 0
+	for i, v := range s.Values {
+		sum += v
+		lo = math.Min(lo, v)
+		if v > hi {
+			hi, peak = v, i
+		}
+	}
+	fmt.Fprintf(&b, "\n%d datapoints: min %s · avg %s · max %s at %s · latest %s at %s",
+		n, number(lo), number(sum/float64(n)), number(hi), clock(s.Timestamps[peak], r),
+		number(s.Values[n-1]), clock(s.Timestamps[n-1], r))
+	fmt.Fprintf(&b, "\nShape: %s", sparkline(s.Values, lo, hi))
+
+	step := (n + maxListed - 1) / maxListed
+	var samples []string
+	for i := 0; i < n; i += step {
+		samples = append(samples, clock(s.Timestamps[i], r)+" "+number(s.Values[i]))
+	}
+	if step > 1 {
+		fmt.Fprintf(&b, "\nEvery %dth value:", step)
+	} else {
+		b.WriteString("\nValues:")
+	}
+	b.WriteString(" " + strings.Join(samples, ", "))
+	return b.String()
+}
+
+// sparkline draws values scaled between lo and hi, averaging them into at
+// most maxListed characters
+func sparkline(values []float64, lo, hi float64) string {
+	width := len(values)
+	if width > maxListed {
+		width = maxListed
+	}
+
+	var b strings.Builder
+	for i := 0; i < width; i++ {
+		from, to := i*len(values)/width, (i+1)*len(values)/width
+		sum := 0.0
+		for _, v := range values[from:to] {
+			sum += v
+		}
+		level := 0
+		if hi > lo {
+			level = int((sum/float64(to-from) - lo) / (hi - lo) * float64(len(sparks)-1))
+		}
+		b.WriteRune(sparks[level])
+	}
+	return b.String()
+}
+
+// clock formats a datapoint's time, with the date when r spans days
+func clock(t time.Time, r timerange.Range) string {
+	t = t.UTC()
+	if r.Duration() > 24*time.Hour || r.Start.YearDay() != r.End.YearDay() {
+		return t.Format("Jan 2 15:04")
+	}
+	return t.Format("15:04")
+}
+
+// number formats a value compactly, e.g. 97.5 or 1.2e+06
+func number(v float64) string {
+	return fmt.Sprintf("%.4g", v)
+}
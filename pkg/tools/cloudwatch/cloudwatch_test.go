@@ -0,0 +1,141 @@
+package cloudwatch
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/savaki/cloudops-bot/pkg/charts"
+)
+
+type fakeCloudWatch struct {
+	inputs []*awscw.GetMetricDataInput
+	pages  []*awscw.GetMetricDataOutput
+}
+
+func (f *fakeCloudWatch) GetMetricData(ctx context.Context, params *awscw.GetMetricDataInput, optFns ...func(*awscw.Options)) (*awscw.GetMetricDataOutput, error) {
+	copied := *params
+	f.inputs = append(f.inputs, &copied)
+	page := f.pages[0]
+	f.pages = f.pages[1:]
+	return page, nil
+}
+
+func TestExecute(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return now.Add(time.Duration(minutes-60) * time.Minute) }
+
+	fake := &fakeCloudWatch{pages: []*awscw.GetMetricDataOutput{
+		{
+			MetricDataResults: []types.MetricDataResult{{Timestamps: []time.Time{at(0), at(5)}, Values: []float64{10, 95}}},
+			NextToken:         aws.String("next"),
+		},
+		{
+			MetricDataResults: []types.MetricDataResult{{Timestamps: []time.Time{at(10)}, Values: []float64{40}}},
+		},
+	}}
+	tool := NewWithClient(fake)
+	tool.now = func() time.Time { return now }
+
+	collector := charts.NewCollector()
+	ctx := charts.WithCollector(context.Background(), collector)
+	raw, _ := json.Marshal(Input{
+		Namespace:  "AWS/ECS",
+		MetricName: "CPUUtilization",
+		Dimensions: map[string]string{"ServiceName": "checkout", "ClusterName": "prod"},
+		TimeRange:  "last 1 hour",
+		Chart:      true,
+	})
+
+	out, err := tool.Execute(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.inputs) != 2 || aws.ToString(fake.inputs[1].NextToken) != "next" {
+		t.Fatalf("GetMetricData called %d times, want 2 pages", len(fake.inputs))
+	}
+	stat := fake.inputs[0].MetricDataQueries[0].MetricStat
+	if aws.ToString(stat.Stat) != DefaultStat || aws.ToInt32(stat.Period) != 60 {
+		t.Errorf("stat = %s per %ds", aws.ToString(stat.Stat), aws.ToInt32(stat.Period))
+	}
+	if dims := stat.Metric.Dimensions; len(dims) != 2 || aws.ToString(dims[0].Name) != "ClusterName" {
+		t.Errorf("dimensions = %+v, want them sorted by name", dims)
+	}
+
+	for _, want := range []string{
+		"AWS/ECS CPUUtilization (ClusterName=prod, ServiceName=checkout) Average per 1m",
+		"3 datapoints: min 10 · avg 48.33 · max 95 at 11:05 · latest 40 at 11:10",
+		"Values: 11:00 10, 11:05 95, 11:10 40",
+		"will be posted",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Execute() = %q, missing %q", out, want)
+		}
+	}
+
+	widgets := collector.Widgets()
+	if len(widgets) != 1 || !widgets[0].Start.Equal(now.Add(-time.Hour)) || widgets[0].Period != 60 {
+		t.Errorf("Widgets() = %+v, want one chart of the queried range", widgets)
+	}
+}
+
+func TestExecuteWithoutCollector(t *testing.T) {
+	fake := &fakeCloudWatch{pages: []*awscw.GetMetricDataOutput{{}}}
+	raw, _ := json.Marshal(Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", Chart: true})
+
+	out, err := NewWithClient(fake).Execute(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "No datapoints") || !strings.Contains(out, "can't be posted") {
+		t.Errorf("Execute() = %q", out)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	tool := NewWithClient(&fakeCloudWatch{})
+	tool.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name    string
+		input   Input
+		period  int
+		wantErr bool
+	}{
+		{"missing metric", Input{Namespace: "AWS/EC2"}, 0, true},
+		{"auto period", Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", TimeRange: "last 24 hours"}, 180, false},
+		{"high resolution", Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", Period: 10, TimeRange: "last 1 hour"}, 10, false},
+		{"too many points", Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", Period: 60, TimeRange: "last 3 days"}, 540, false},
+		{"odd period", Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", Period: 45}, 0, true},
+		{"range too long", Input{Namespace: "AWS/EC2", MetricName: "CPUUtilization", TimeRange: "last 30 days"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, period, err := tool.bounds(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bounds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if period != tt.period {
+				t.Errorf("period = %d, want %d", period, tt.period)
+			}
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]float64{0, 50, 100}, 0, 100); got != "▁▄█" {
+		t.Errorf("sparkline() = %q", got)
+	}
+	if got := sparkline([]float64{7, 7}, 7, 7); got != "▁▁" {
+		t.Errorf("sparkline() of a flat series = %q", got)
+	}
+	if got := []rune(sparkline(make([]float64, 100), 0, 0)); len(got) != maxListed {
+		t.Errorf("sparkline() width = %d, want %d", len(got), maxListed)
+	}
+}
@@ -0,0 +1,342 @@
+// Package cloudwatchlogs provides a tool that lets the model run CloudWatch
+// Logs Insights queries
+package cloudwatchlogs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+	"github.com/savaki/cloudops-bot/pkg/logpattern"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+)
+
+// Query bounds. Insights bills by bytes scanned, so the time range is
+// capped, and rows are capped so results fit the model's context
+const (
+	DefaultRange    = "last 1 hour"
+	MaxRange        = 24 * time.Hour
+	DefaultLimit    = 100
+	MaxLimit        = 1000
+	maxLogGroups    = 20
+	maxValueLength  = 500
+	maxRawRows      = 50 // more log lines than this are summarized as patterns
+	maxPatterns     = 20 // patterns of each kind listed in a summary
+	defaultTimeout  = time.Minute
+	defaultInterval = time.Second
+)
+
+// Background scans cover longer ranges a day at a time, and each day's
+// query may run longer
+const (
+	MaxScanRange = 7 * 24 * time.Hour
+	scanTimeout  = 10 * time.Minute
+)
+
+// API is the part of the CloudWatch Logs client the tool uses
+type API interface {
+	StartQuery(ctx context.Context, params *awslogs.StartQueryInput, optFns ...func(*awslogs.Options)) (*awslogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *awslogs.GetQueryResultsInput, optFns ...func(*awslogs.Options)) (*awslogs.GetQueryResultsOutput, error)
+	StopQuery(ctx context.Context, params *awslogs.StopQueryInput, optFns ...func(*awslogs.Options)) (*awslogs.StopQueryOutput, error)
+}
+
+// Tool runs Logs Insights queries for the model
+type Tool struct {
+	client   API
+	timeout  time.Duration // how long a query may run before it is stopped
+	interval time.Duration // how often results are polled
+	now      func() time.Time
+}
+
+// New creates the tool using the agent's AWS credentials
+func New(cfg aws.Config) *Tool {
+	return NewWithClient(awslogs.NewFromConfig(cfg))
+}
+
+// NewWithClient creates the tool with a custom CloudWatch Logs client
+func NewWithClient(client API) *Tool {
+	return &Tool{client: client, timeout: defaultTimeout, interval: defaultInterval, now: time.Now}
+}
+
+// Input is a query against one or more log groups
+type Input struct {
+	LogGroups []string `json:"log_groups"`
+	Query     string   `json:"query"`
+	TimeRange string   `json:"time_range,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+}
+
+// Name identifies the tool to the model
+func (t *Tool) Name() string {
+	return "query_cloudwatch_logs"
+}
+
+// Description tells the model what the tool does
+func (t *Tool) Description() string {
+	return fmt.Sprintf("Run a CloudWatch Logs Insights query against named log groups and return the matching rows. "+
+		"Use it to find errors, count events, or pull recent log lines for a service, e.g. "+
+		"\"fields @timestamp, @message | filter @message like /ERROR/ | sort @timestamp desc\". "+
+		"The time range is at most %s and results at most %d rows; prefer stats and filters over reading raw lines. "+
+		"More than %d log lines come back summarized as the patterns among them, with counts.",
+		humanize.Duration(MaxRange), MaxLimit, maxRawRows)
+}
+
+// InputSchema is the JSON Schema of Input
+func (t *Tool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"log_groups": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Log group names, e.g. /ecs/checkout",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Logs Insights query",
+			},
+			"time_range": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Time range in UTC, e.g. \"last 15 minutes\" or \"today 09:00-10:30\"; defaults to %q", DefaultRange),
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum rows to return, default %d", DefaultLimit),
+			},
+		},
+		"required": []string{"log_groups", "query"},
+	}
+}
+
+// Execute runs the query and returns its results
+func (t *Tool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in Input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	r, limit, err := t.bounds(in, MaxRange)
+	if err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+
+	output, err := t.query(ctx, in, r, limit, t.timeout)
+	if err != nil {
+		return "", err
+	}
+	return Format(r, output.Results, output.Statistics), nil
+}
+
+// ScanJob is the background job that runs a query over up to
+// MaxScanRange, a day at a time, for ranges the tool won't query at once
+func (t *Tool) ScanJob() jobs.Kind {
+	schema := t.InputSchema()
+	schema["properties"].(map[string]interface{})["time_range"] = map[string]interface{}{
+		"type":        "string",
+		"description": fmt.Sprintf("Time range in UTC of up to %s, e.g. \"last 3 days\"", humanize.Duration(MaxScanRange)),
+	}
+	return jobs.Kind{
+		Name: "logs_insights_scan",
+		Description: fmt.Sprintf("Run a Logs Insights query over up to %s, one day at a time, returning each day's rows. "+
+			"Use it instead of %s when the range is longer than %s.", humanize.Duration(MaxScanRange), t.Name(), humanize.Duration(MaxRange)),
+		InputSchema: schema,
+		Run:         t.scan,
+	}
+}
+
+// scan runs a query over each day of the input's range
+func (t *Tool) scan(ctx context.Context, raw json.RawMessage, progress *jobs.Progress) (string, error) {
+	var in Input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	r, limit, err := t.bounds(in, MaxScanRange)
+	if err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+
+	var windows []timerange.Range
+	for start := r.Start; start.Before(r.End); start = start.Add(MaxRange) {
+		end := start.Add(MaxRange)
+		if end.After(r.End) {
+			end = r.End
+		}
+		windows = append(windows, timerange.Range{Start: start, End: end})
+	}
+
+	parts := make([]string, 0, len(windows))
+	for i, w := range windows {
+		progress.Update(ctx, i, len(windows), "querying "+w.String())
+		output, err := t.query(ctx, in, w, limit, scanTimeout)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", w, err)
+		}
+		parts = append(parts, Format(w, output.Results, output.Statistics))
+	}
+	progress.Update(ctx, len(windows), len(windows), "done")
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// query runs the input's query over r and waits up to timeout for it
+func (t *Tool) query(ctx context.Context, in Input, r timerange.Range, limit int, timeout time.Duration) (*awslogs.GetQueryResultsOutput, error) {
+	started, err := t.client.StartQuery(ctx, &awslogs.StartQueryInput{
+		LogGroupNames: in.LogGroups,
+		QueryString:   aws.String(in.Query),
+		StartTime:     aws.Int64(r.Start.Unix()),
+		EndTime:       aws.Int64(r.End.Unix()),
+		Limit:         aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start query: %w", err)
+	}
+	return t.wait(ctx, aws.ToString(started.QueryId), timeout)
+}
+
+// bounds validates the input and resolves its time range, of at most
+// maxRange, and row limit
+func (t *Tool) bounds(in Input, maxRange time.Duration) (timerange.Range, int, error) {
+	if len(in.LogGroups) == 0 {
+		return timerange.Range{}, 0, errors.New("at least one log group is required")
+	}
+	if len(in.LogGroups) > maxLogGroups {
+		return timerange.Range{}, 0, fmt.Errorf("at most %d log groups can be queried at once", maxLogGroups)
+	}
+	if strings.TrimSpace(in.Query) == "" {
+		return timerange.Range{}, 0, errors.New("query is required")
+	}
+
+	expr := in.TimeRange
+	if strings.TrimSpace(expr) == "" {
+		expr = DefaultRange
+	}
+	parser := timerange.New(time.UTC)
+	parser.SetNow(t.now)
+	r, err := parser.Parse(expr)
+	if err != nil {
+		return timerange.Range{}, 0, fmt.Errorf("time range %q: %w", expr, err)
+	}
+	if r.Duration() > maxRange {
+		return timerange.Range{}, 0, fmt.Errorf("time range %s is longer than the %s limit; narrow it", r, humanize.Duration(maxRange))
+	}
+
+	limit := in.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return r, limit, nil
+}
+
+// wait polls a query until it finishes, stopping it if it runs longer than
+// timeout
+func (t *Tool) wait(ctx context.Context, queryID string, timeout time.Duration) (*awslogs.GetQueryResultsOutput, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		output, err := t.client.GetQueryResults(ctx, &awslogs.GetQueryResultsInput{QueryId: aws.String(queryID)})
+		if err != nil {
+			return nil, fmt.Errorf("get query results: %w", err)
+		}
+		switch output.Status {
+		case types.QueryStatusComplete:
+			return output, nil
+		case types.QueryStatusFailed, types.QueryStatusCancelled, types.QueryStatusTimeout:
+			return nil, fmt.Errorf("query %s", strings.ToLower(string(output.Status)))
+		}
+
+		select {
+		case <-ctx.Done():
+			t.stop(queryID)
+			return nil, ctx.Err()
+		case <-deadline.C:
+			t.stop(queryID)
+			return nil, fmt.Errorf("query still running after %s; narrow the time range or log groups", humanize.Duration(timeout))
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop cancels a query that is no longer wanted, so it stops scanning
+func (t *Tool) stop(queryID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := t.client.StopQuery(ctx, &awslogs.StopQueryInput{QueryId: aws.String(queryID)}); err != nil {
+		slog.WarnContext(ctx, "failed to stop query", "query_id", queryID, "error", err)
+	}
+}
+
+// Format describes query results as text for the model: a summary line,
+// then one line per row of field=value pairs. More than maxRawRows log
+// lines are summarized as the patterns among them instead, which tell the
+// model more in less of its context than the lines themselves
+func Format(r timerange.Range, rows [][]types.ResultField, stats *types.QueryStatistics) string {
+	var b strings.Builder
+	noun := "rows"
+	if len(rows) == 1 {
+		noun = "row"
+	}
+	fmt.Fprintf(&b, "%d %s for %s", len(rows), noun, r)
+	if stats != nil {
+		fmt.Fprintf(&b, " (%s records matched, %s scanned)",
+			humanize.Count(int64(stats.RecordsMatched)), humanize.Bytes(int64(stats.BytesScanned)))
+	}
+
+	if messages := messagesOf(rows); len(rows) > maxRawRows && len(messages) == len(rows) {
+		b.WriteString("\n")
+		b.WriteString(logpattern.Summarize(logpattern.Analyze(messages, logpattern.Options{}), maxPatterns))
+		return b.String()
+	}
+
+	for _, row := range rows {
+		var fields []string
+		for _, f := range row {
+			name := aws.ToString(f.Field)
+			// @ptr only identifies the record for GetLogRecord
+			if name == "@ptr" {
+				continue
+			}
+			value := strings.TrimSpace(aws.ToString(f.Value))
+			if len(value) > maxValueLength {
+				cut := maxValueLength
+				for cut > 0 && !utf8.RuneStart(value[cut]) {
+					cut--
+				}
+				value = value[:cut] + "…"
+			}
+			fields = append(fields, name+"="+value)
+		}
+		b.WriteString("\n")
+		b.WriteString(strings.Join(fields, " | "))
+	}
+	return b.String()
+}
+
+// messagesOf returns the @message of each row that has one. Aggregate
+// queries, such as stats, return rows without
+func messagesOf(rows [][]types.ResultField) []string {
+	var messages []string
+	for _, row := range rows {
+		for _, f := range row {
+			if aws.ToString(f.Field) == "@message" {
+				messages = append(messages, strings.TrimSpace(aws.ToString(f.Value)))
+				break
+			}
+		}
+	}
+	return messages
+}
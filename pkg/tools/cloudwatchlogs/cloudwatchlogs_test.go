@@ -0,0 +1,195 @@
+package cloudwatchlogs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslogs "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+)
+
+type fakeLogs struct {
+	start    *awslogs.StartQueryInput
+	starts   int
+	statuses []types.QueryStatus // returned in turn by GetQueryResults
+	rows     [][]types.ResultField
+	stopped  bool
+}
+
+func (f *fakeLogs) StartQuery(ctx context.Context, params *awslogs.StartQueryInput, optFns ...func(*awslogs.Options)) (*awslogs.StartQueryOutput, error) {
+	f.start = params
+	f.starts++
+	return &awslogs.StartQueryOutput{QueryId: aws.String("q-1")}, nil
+}
+
+func (f *fakeLogs) GetQueryResults(ctx context.Context, params *awslogs.GetQueryResultsInput, optFns ...func(*awslogs.Options)) (*awslogs.GetQueryResultsOutput, error) {
+	status := f.statuses[0]
+	if len(f.statuses) > 1 {
+		f.statuses = f.statuses[1:]
+	}
+	return &awslogs.GetQueryResultsOutput{Status: status, Results: f.rows}, nil
+}
+
+func (f *fakeLogs) StopQuery(ctx context.Context, params *awslogs.StopQueryInput, optFns ...func(*awslogs.Options)) (*awslogs.StopQueryOutput, error) {
+	f.stopped = true
+	return &awslogs.StopQueryOutput{}, nil
+}
+
+func newTestTool(client API) *Tool {
+	t := NewWithClient(client)
+	t.interval = time.Millisecond
+	t.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return t
+}
+
+func field(name, value string) types.ResultField {
+	return types.ResultField{Field: aws.String(name), Value: aws.String(value)}
+}
+
+func TestExecute(t *testing.T) {
+	client := &fakeLogs{
+		statuses: []types.QueryStatus{types.QueryStatusRunning, types.QueryStatusComplete},
+		rows: [][]types.ResultField{
+			{field("@timestamp", "2024-05-01 11:58:00.000"), field("@message", "ERROR timeout"), field("@ptr", "abc")},
+		},
+	}
+	tool := newTestTool(client)
+
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"log_groups":["/ecs/checkout"],"query":"fields @timestamp, @message","time_range":"last 15 minutes","limit":5000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aws.ToInt32(client.start.Limit); got != MaxLimit {
+		t.Errorf("Limit = %d, want it capped at %d", got, MaxLimit)
+	}
+	if got := aws.ToInt64(client.start.EndTime) - aws.ToInt64(client.start.StartTime); got != 15*60 {
+		t.Errorf("queried %ds, want 900s", got)
+	}
+	if want := "@timestamp=2024-05-01 11:58:00.000 | @message=ERROR timeout"; !strings.HasSuffix(out, "\n"+want) {
+		t.Errorf("Execute() = %q, want a row %q without @ptr", out, want)
+	}
+}
+
+func TestExecuteRejects(t *testing.T) {
+	tool := newTestTool(&fakeLogs{statuses: []types.QueryStatus{types.QueryStatusComplete}})
+	tests := []string{
+		`{"query":"fields @message"}`,
+		`{"log_groups":["/ecs/checkout"]}`,
+		`{"log_groups":["/ecs/checkout"],"query":"fields @message","time_range":"last 3 days"}`,
+	}
+	for _, input := range tests {
+		if _, err := tool.Execute(context.Background(), json.RawMessage(input)); err == nil {
+			t.Errorf("Execute(%s) should fail", input)
+		}
+	}
+}
+
+func TestExecuteFailedQuery(t *testing.T) {
+	tool := newTestTool(&fakeLogs{statuses: []types.QueryStatus{types.QueryStatusFailed}})
+	_, err := tool.Execute(context.Background(), json.RawMessage(`{"log_groups":["/ecs/checkout"],"query":"bad"}`))
+	if err == nil || err.Error() != "query failed" {
+		t.Errorf("Execute() error = %v, want query failed", err)
+	}
+}
+
+func TestExecuteStopsSlowQuery(t *testing.T) {
+	client := &fakeLogs{statuses: []types.QueryStatus{types.QueryStatusRunning}}
+	tool := newTestTool(client)
+	tool.timeout = 10 * time.Millisecond
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"log_groups":["/ecs/checkout"],"query":"fields @message"}`)); err == nil {
+		t.Fatal("Execute() should fail when the query runs too long")
+	}
+	if !client.stopped {
+		t.Error("a query that runs too long should be stopped")
+	}
+}
+
+func TestScan(t *testing.T) {
+	client := &fakeLogs{
+		statuses: []types.QueryStatus{types.QueryStatusComplete},
+		rows:     [][]types.ResultField{{field("@message", "ERROR timeout")}},
+	}
+	tool := newTestTool(client)
+
+	out, err := tool.ScanJob().Run(context.Background(), json.RawMessage(`{"log_groups":["/ecs/checkout"],"query":"fields @message","time_range":"last 3 days"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.starts != 3 {
+		t.Errorf("ran %d queries, want one per day", client.starts)
+	}
+	if got := strings.Count(out, "@message=ERROR timeout"); got != 3 {
+		t.Errorf("Scan() returned %d rows, want one per day:\n%s", got, out)
+	}
+
+	if _, err := tool.ScanJob().Run(context.Background(), json.RawMessage(`{"log_groups":["/ecs/checkout"],"query":"fields @message","time_range":"last 8 days"}`), nil); err == nil {
+		t.Error("Scan() should reject ranges longer than MaxScanRange")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	long := strings.Repeat("x", maxValueLength+10)
+	stats := &types.QueryStatistics{RecordsMatched: 1200, BytesScanned: 5 << 20}
+	end := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := timerange.Range{Start: end.Add(-time.Hour), End: end}
+	out := Format(r, [][]types.ResultField{{field("@message", long)}}, stats)
+
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[0], "1 row for ") || !strings.Contains(lines[0], "records matched") {
+		t.Errorf("Format() summary = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "…") || len(lines[1]) > maxValueLength+len("@message=")+len("…") {
+		t.Errorf("Format() should truncate long values, got %d bytes", len(lines[1]))
+	}
+}
+
+func TestFormatTruncatesOnRuneBoundary(t *testing.T) {
+	end := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := timerange.Range{Start: end.Add(-time.Hour), End: end}
+
+	// Each "é" is two bytes, and the limit falls in the middle of one
+	long := "x" + strings.Repeat("é", maxValueLength)
+	out := Format(r, [][]types.ResultField{{field("@message", long)}}, nil)
+	if !utf8.ValidString(out) {
+		t.Errorf("Format() split a rune: %q", out[len(out)-8:])
+	}
+}
+
+func TestFormatSummarizesManyLines(t *testing.T) {
+	end := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := timerange.Range{Start: end.Add(-time.Hour), End: end}
+
+	var rows [][]types.ResultField
+	for i := 0; i < maxRawRows+10; i++ {
+		message := fmt.Sprintf("GET /health 200 %dms", i)
+		if i%3 == 0 {
+			message = fmt.Sprintf("ERROR connection refused to 10.0.0.%d:5432", i%255)
+		}
+		rows = append(rows, []types.ResultField{field("@timestamp", end.String()), field("@message", message)})
+	}
+
+	out := Format(r, rows, nil)
+	if strings.Contains(out, "@message=") {
+		t.Errorf("Format() listed raw rows instead of patterns:\n%s", out)
+	}
+	if !strings.Contains(out, "Top error patterns") || !strings.Contains(out, "connection refused") {
+		t.Errorf("Format() summary is missing the error pattern:\n%s", out)
+	}
+
+	// Aggregates have no messages to summarize
+	var counts [][]types.ResultField
+	for i := 0; i < maxRawRows+10; i++ {
+		counts = append(counts, []types.ResultField{field("bin(5m)", end.String()), field("count()", "3")})
+	}
+	if out := Format(r, counts, nil); !strings.Contains(out, "count()=3") {
+		t.Errorf("Format() should list aggregate rows, got:\n%s", out)
+	}
+}
@@ -0,0 +1,296 @@
+// Package ec2 provides a tool that lets the model describe EC2 instances,
+// and a background job that does so across regions
+package ec2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/jobs"
+)
+
+// maxInstances bounds how many instances one call describes, so a broad
+// filter doesn't flood the model's context
+const maxInstances = 50
+
+// maxRegions bounds how many regions one sweep covers
+const maxRegions = 30
+
+// states are the instance states the tool accepts as filters
+var states = []string{"pending", "running", "shutting-down", "terminated", "stopping", "stopped"}
+
+// API is the part of the EC2 client the tool uses
+type API interface {
+	DescribeInstances(ctx context.Context, params *awsec2.DescribeInstancesInput, optFns ...func(*awsec2.Options)) (*awsec2.DescribeInstancesOutput, error)
+}
+
+// Tool describes EC2 instances for the model
+type Tool struct {
+	client API
+}
+
+// New creates the tool using the agent's AWS credentials
+func New(cfg aws.Config) *Tool {
+	return &Tool{client: awsec2.NewFromConfig(cfg)}
+}
+
+// NewWithClient creates the tool with a custom EC2 client
+func NewWithClient(client API) *Tool {
+	return &Tool{client: client}
+}
+
+// Input selects the instances to describe. All filters must match
+type Input struct {
+	InstanceIDs []string          `json:"instance_ids,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	States      []string          `json:"states,omitempty"`
+}
+
+// Name identifies the tool to the model
+func (t *Tool) Name() string {
+	return "describe_ec2_instances"
+}
+
+// Description tells the model what the tool does
+func (t *Tool) Description() string {
+	return "Describe EC2 instances in the current region: state and why it last changed, instance type, private and public IPs, availability zone, launch time, and Name tag. " +
+		"Filter by instance ID, tag values, or state. Use it when someone asks about a specific instance, such as \"what's wrong with i-0abc123\", or about the instances behind a service."
+}
+
+// InputSchema is the JSON Schema of Input
+func (t *Tool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"instance_ids": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Instance IDs, e.g. i-0abc123def456",
+			},
+			"tags": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Tag values to match, e.g. {\"Name\": \"checkout-*\", \"env\": \"prod\"}. * is a wildcard",
+			},
+			"states": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string", "enum": states},
+				"description": "Instance states to include; all states when omitted",
+			},
+		},
+	}
+}
+
+// Execute describes the instances matching the input
+func (t *Tool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in Input
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return "", diagnose.InvalidInput(err)
+		}
+	}
+
+	return describe(ctx, t.client, in)
+}
+
+// describe describes the instances matching the input with client
+func describe(ctx context.Context, client API, in Input) (string, error) {
+	filters, err := Filters(in)
+	if err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	input := &awsec2.DescribeInstancesInput{Filters: filters}
+	if len(in.InstanceIDs) > 0 {
+		input.InstanceIds = in.InstanceIDs
+	}
+
+	var instances []types.Instance
+	more := false
+	for {
+		output, err := client.DescribeInstances(ctx, input)
+		if err != nil {
+			return "", fmt.Errorf("describe instances: %w", err)
+		}
+		for _, r := range output.Reservations {
+			instances = append(instances, r.Instances...)
+		}
+		if len(instances) > maxInstances {
+			more = true
+			break
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	return Format(instances, more, time.Now()), nil
+}
+
+// SweepInput selects the instances to describe in each of several regions
+type SweepInput struct {
+	Input
+	Regions []string `json:"regions"`
+}
+
+// SweepJob is the background job that describes the matching instances in
+// each of several regions
+func SweepJob(cfg aws.Config) jobs.Kind {
+	return sweepJob(func(region string) API {
+		return awsec2.NewFromConfig(cfg, func(o *awsec2.Options) { o.Region = region })
+	})
+}
+
+func sweepJob(clientFor func(region string) API) jobs.Kind {
+	schema := (&Tool{}).InputSchema()
+	schema["properties"].(map[string]interface{})["regions"] = map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": fmt.Sprintf("Regions to sweep, e.g. [\"us-east-1\", \"eu-west-1\"]; at most %d", maxRegions),
+	}
+	schema["required"] = []string{"regions"}
+
+	return jobs.Kind{
+		Name:        "ec2_region_sweep",
+		Description: "Describe the EC2 instances matching the same filters as describe_ec2_instances in each of several regions. Use it for questions across regions, such as \"which stopped instances do we have anywhere\".",
+		InputSchema: schema,
+		Run: func(ctx context.Context, raw json.RawMessage, progress *jobs.Progress) (string, error) {
+			var in SweepInput
+			if err := json.Unmarshal(raw, &in); err != nil {
+				return "", diagnose.InvalidInput(err)
+			}
+			if len(in.Regions) == 0 {
+				return "", diagnose.InvalidInput(errors.New("at least one region is required"))
+			}
+			if len(in.Regions) > maxRegions {
+				return "", diagnose.InvalidInput(fmt.Errorf("at most %d regions can be swept at once", maxRegions))
+			}
+
+			// A region that fails, e.g. one not enabled for the account,
+			// is reported and the sweep goes on
+			parts := make([]string, 0, len(in.Regions))
+			for i, region := range in.Regions {
+				progress.Update(ctx, i, len(in.Regions), "sweeping "+region)
+				out, err := describe(ctx, clientFor(region), in.Input)
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				if err != nil {
+					out = "Failed: " + err.Error()
+				}
+				parts = append(parts, region+":\n"+out)
+			}
+			progress.Update(ctx, len(in.Regions), len(in.Regions), "done")
+			return strings.Join(parts, "\n\n"), nil
+		},
+	}
+}
+
+// Filters converts the input's tag and state filters to EC2 filters
+func Filters(in Input) ([]types.Filter, error) {
+	var filters []types.Filter
+
+	keys := make([]string, 0, len(in.Tags))
+	for k := range in.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		filters = append(filters, types.Filter{Name: aws.String("tag:" + k), Values: []string{in.Tags[k]}})
+	}
+
+	if len(in.States) > 0 {
+		for _, s := range in.States {
+			if !contains(states, s) {
+				return nil, fmt.Errorf("unknown instance state %q; use one of %s", s, strings.Join(states, ", "))
+			}
+		}
+		filters = append(filters, types.Filter{Name: aws.String("instance-state-name"), Values: in.States})
+	}
+
+	return filters, nil
+}
+
+// Format describes instances as text for the model, one per line, most
+// recently launched first. more reports that further instances match
+func Format(instances []types.Instance, more bool, now time.Time) string {
+	if len(instances) == 0 {
+		return "No instances match."
+	}
+
+	sort.SliceStable(instances, func(i, j int) bool {
+		return aws.ToTime(instances[i].LaunchTime).After(aws.ToTime(instances[j].LaunchTime))
+	})
+	shown := instances
+	if len(shown) > maxInstances {
+		shown = shown[:maxInstances]
+	}
+
+	var b strings.Builder
+	for _, inst := range shown {
+		fmt.Fprintf(&b, "%s", aws.ToString(inst.InstanceId))
+		if name := tag(inst.Tags, "Name"); name != "" {
+			fmt.Fprintf(&b, " (%s)", name)
+		}
+		if inst.State != nil {
+			fmt.Fprintf(&b, ": %s", inst.State.Name)
+		}
+		fmt.Fprintf(&b, ", %s", inst.InstanceType)
+		if inst.Placement != nil && inst.Placement.AvailabilityZone != nil {
+			fmt.Fprintf(&b, " in %s", aws.ToString(inst.Placement.AvailabilityZone))
+		}
+		if ip := aws.ToString(inst.PrivateIpAddress); ip != "" {
+			fmt.Fprintf(&b, ", private IP %s", ip)
+		}
+		if ip := aws.ToString(inst.PublicIpAddress); ip != "" {
+			fmt.Fprintf(&b, ", public IP %s", ip)
+		}
+		if inst.LaunchTime != nil {
+			launched := inst.LaunchTime.UTC()
+			fmt.Fprintf(&b, ", launched %s (%s ago)", launched.Format(time.RFC3339), humanize.Duration(now.Sub(launched).Truncate(time.Minute)))
+		}
+
+		// The reasons explain stopped and impaired instances, which is
+		// usually what the question is about
+		if inst.StateReason != nil && aws.ToString(inst.StateReason.Message) != "" {
+			fmt.Fprintf(&b, ", state reason: %s", aws.ToString(inst.StateReason.Message))
+		} else if reason := aws.ToString(inst.StateTransitionReason); reason != "" {
+			fmt.Fprintf(&b, ", state reason: %s", reason)
+		}
+		b.WriteString("\n")
+	}
+
+	if more {
+		fmt.Fprintf(&b, "More than %d instances match and only %d are shown; narrow the filters to see the rest.\n", maxInstances, len(shown))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// tag returns the value of a tag, or "" when it isn't set
+func tag(tags []types.Tag, key string) string {
+	for _, t := range tags {
+		if aws.ToString(t.Key) == key {
+			return aws.ToString(t.Value)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package ec2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsec2 "github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type fakeEC2 struct {
+	input     *awsec2.DescribeInstancesInput
+	instances []types.Instance
+}
+
+func (f *fakeEC2) DescribeInstances(ctx context.Context, params *awsec2.DescribeInstancesInput, optFns ...func(*awsec2.Options)) (*awsec2.DescribeInstancesOutput, error) {
+	f.input = params
+	return &awsec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: f.instances}}}, nil
+}
+
+func TestFilters(t *testing.T) {
+	filters, err := Filters(Input{Tags: map[string]string{"env": "prod", "Name": "checkout-*"}, States: []string{"stopped"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range filters {
+		got = append(got, aws.ToString(f.Name)+"="+strings.Join(f.Values, ","))
+	}
+	if want := "tag:Name=checkout-* tag:env=prod instance-state-name=stopped"; strings.Join(got, " ") != want {
+		t.Errorf("Filters() = %v, want %s", got, want)
+	}
+
+	if _, err := Filters(Input{States: []string{"crashed"}}); err == nil {
+		t.Error("Filters() should reject unknown states")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	instances := []types.Instance{
+		{
+			InstanceId:   aws.String("i-old"),
+			InstanceType: types.InstanceTypeT3Micro,
+			State:        &types.InstanceState{Name: types.InstanceStateNameStopped},
+			StateReason:  &types.StateReason{Message: aws.String("Client.InstanceInitiatedShutdown: Instance initiated shutdown")},
+			LaunchTime:   aws.Time(now.Add(-72 * time.Hour)),
+		},
+		{
+			InstanceId:       aws.String("i-new"),
+			InstanceType:     types.InstanceTypeM5Large,
+			State:            &types.InstanceState{Name: types.InstanceStateNameRunning},
+			Placement:        &types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+			PrivateIpAddress: aws.String("10.0.1.5"),
+			PublicIpAddress:  aws.String("3.4.5.6"),
+			LaunchTime:       aws.Time(now.Add(-90 * time.Minute)),
+			Tags:             []types.Tag{{Key: aws.String("Name"), Value: aws.String("checkout-1")}},
+		},
+	}
+
+	lines := strings.Split(Format(instances, false, now), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Format() returned %d lines, want 2", len(lines))
+	}
+	if want := "i-new (checkout-1): running, m5.large in us-east-1a, private IP 10.0.1.5, public IP 3.4.5.6, launched 2024-05-01T10:30:00Z (1h 30m ago)"; lines[0] != want {
+		t.Errorf("Format() line 1 = %q, want %q", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[1], "i-old: stopped, t3.micro") || !strings.HasSuffix(lines[1], "state reason: Client.InstanceInitiatedShutdown: Instance initiated shutdown") {
+		t.Errorf("Format() line 2 = %q", lines[1])
+	}
+
+	if got := Format(nil, false, now); got != "No instances match." {
+		t.Errorf("Format(nil) = %q", got)
+	}
+	if got := Format(instances, true, now); !strings.Contains(got, "narrow the filters") {
+		t.Errorf("Format() with more matches = %q", got)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	client := &fakeEC2{instances: []types.Instance{{InstanceId: aws.String("i-abc123"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}}}}
+	tool := NewWithClient(client)
+
+	out, err := tool.Execute(context.Background(), json.RawMessage(`{"instance_ids":["i-abc123"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "i-abc123: running") {
+		t.Errorf("Execute() = %q", out)
+	}
+	if ids := client.input.InstanceIds; len(ids) != 1 || ids[0] != "i-abc123" {
+		t.Errorf("DescribeInstances() InstanceIds = %v", ids)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"states":["on fire"]}`)); err == nil {
+		t.Error("Execute() should reject unknown states")
+	}
+}
+
+type failingEC2 struct{}
+
+func (failingEC2) DescribeInstances(ctx context.Context, params *awsec2.DescribeInstancesInput, optFns ...func(*awsec2.Options)) (*awsec2.DescribeInstancesOutput, error) {
+	return nil, errors.New("AuthFailure: region not enabled")
+}
+
+func TestSweepJob(t *testing.T) {
+	client := &fakeEC2{instances: []types.Instance{{InstanceId: aws.String("i-abc123"), State: &types.InstanceState{Name: types.InstanceStateNameStopped}}}}
+	kind := sweepJob(func(region string) API {
+		if region == "ap-east-1" {
+			return failingEC2{}
+		}
+		return client
+	})
+
+	out, err := kind.Run(context.Background(), json.RawMessage(`{"regions":["us-east-1","ap-east-1"],"states":["stopped"]}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "us-east-1:\ni-abc123: stopped") {
+		t.Errorf("Run() = %q, want us-east-1's instances", out)
+	}
+	if !strings.Contains(out, "ap-east-1:\nFailed: describe instances: AuthFailure") {
+		t.Errorf("Run() = %q, want ap-east-1's failure reported", out)
+	}
+
+	if _, err := kind.Run(context.Background(), json.RawMessage(`{"states":["stopped"]}`), nil); err == nil {
+		t.Error("Run() should require regions")
+	}
+}
@@ -0,0 +1,369 @@
+// Package ecs provides a tool that lets the model inspect ECS services and
+// tasks: deployments, service events, and why tasks stopped
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/savaki/cloudops-bot/pkg/diagnose"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+)
+
+// Bounds on one call, so a busy service doesn't flood the model's context.
+// DescribeServices takes at most 10 services
+const (
+	maxServices   = 10
+	maxTasks      = 10
+	defaultEvents = 10
+	maxEvents     = 50
+)
+
+// statuses are the desired task statuses the tool can list by
+var statuses = []string{string(types.DesiredStatusRunning), string(types.DesiredStatusPending), string(types.DesiredStatusStopped)}
+
+// API is the part of the ECS client the tool uses
+type API interface {
+	DescribeServices(ctx context.Context, params *awsecs.DescribeServicesInput, optFns ...func(*awsecs.Options)) (*awsecs.DescribeServicesOutput, error)
+	DescribeTasks(ctx context.Context, params *awsecs.DescribeTasksInput, optFns ...func(*awsecs.Options)) (*awsecs.DescribeTasksOutput, error)
+	ListTasks(ctx context.Context, params *awsecs.ListTasksInput, optFns ...func(*awsecs.Options)) (*awsecs.ListTasksOutput, error)
+}
+
+// Tool inspects ECS services and tasks for the model
+type Tool struct {
+	client API
+	now    func() time.Time
+}
+
+// New creates the tool using the agent's AWS credentials
+func New(cfg aws.Config) *Tool {
+	return NewWithClient(awsecs.NewFromConfig(cfg))
+}
+
+// NewWithClient creates the tool with a custom ECS client
+func NewWithClient(client API) *Tool {
+	return &Tool{client: client, now: time.Now}
+}
+
+// Input selects what to inspect in a cluster: services with their tasks,
+// specific tasks, or the cluster's tasks
+type Input struct {
+	Cluster       string   `json:"cluster"`
+	Services      []string `json:"services,omitempty"`
+	Tasks         []string `json:"tasks,omitempty"`
+	DesiredStatus string   `json:"desired_status,omitempty"`
+	Events        int      `json:"events,omitempty"`
+}
+
+// Name identifies the tool to the model
+func (t *Tool) Name() string {
+	return "describe_ecs"
+}
+
+// Description tells the model what the tool does
+func (t *Tool) Description() string {
+	return "Inspect ECS services and tasks in a cluster. For services: status, desired, running and pending counts, each deployment with its rollout state and failed task count, recent service events, and their recently stopped tasks. " +
+		"For tasks: status, task definition, stop code and reason, and each container's status, exit code, and reason. " +
+		"Use it when a deployment is flapping, tasks keep restarting, or a service won't reach its desired count. ECS only keeps stopped tasks for about an hour."
+}
+
+// InputSchema is the JSON Schema of Input
+func (t *Tool) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"cluster": map[string]interface{}{
+				"type":        "string",
+				"description": "Cluster name or ARN",
+			},
+			"services": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": fmt.Sprintf("Service names or ARNs; at most %d", maxServices),
+			},
+			"tasks": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": fmt.Sprintf("Task IDs or ARNs to describe; at most %d", maxTasks),
+			},
+			"desired_status": map[string]interface{}{
+				"type":        "string",
+				"enum":        statuses,
+				"description": "Which tasks to list: STOPPED for services, to show why tasks stopped, and RUNNING for the whole cluster, unless set",
+			},
+			"events": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Service events to show, newest first; defaults to %d, at most %d", defaultEvents, maxEvents),
+			},
+		},
+		"required": []string{"cluster"},
+	}
+}
+
+// Execute inspects the services or tasks selected by the input
+func (t *Tool) Execute(ctx context.Context, raw json.RawMessage) (string, error) {
+	var in Input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	if err := in.validate(); err != nil {
+		return "", diagnose.InvalidInput(err)
+	}
+	now := t.now()
+
+	if len(in.Tasks) > 0 {
+		tasks, failures, err := t.describeTasks(ctx, in.Cluster, in.Tasks)
+		if err != nil {
+			return "", err
+		}
+		return FormatTasks(tasks, failures, now), nil
+	}
+
+	if len(in.Services) == 0 {
+		status := in.DesiredStatus
+		if status == "" {
+			status = string(types.DesiredStatusRunning)
+		}
+		tasks, failures, more, err := t.listTasks(ctx, in.Cluster, "", status)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s tasks in %s:\n%s", status, in.Cluster, moreTasks(FormatTasks(tasks, failures, now), more)), nil
+	}
+
+	output, err := t.client.DescribeServices(ctx, &awsecs.DescribeServicesInput{Cluster: aws.String(in.Cluster), Services: in.Services})
+	if err != nil {
+		return "", fmt.Errorf("describe services: %w", err)
+	}
+
+	events := in.Events
+	if events <= 0 {
+		events = defaultEvents
+	}
+	status := in.DesiredStatus
+	if status == "" {
+		status = string(types.DesiredStatusStopped)
+	}
+
+	var parts []string
+	for _, svc := range output.Services {
+		part := FormatService(svc, events, now)
+		tasks, failures, more, err := t.listTasks(ctx, in.Cluster, aws.ToString(svc.ServiceName), status)
+		if err != nil {
+			return "", err
+		}
+		part += fmt.Sprintf("\n%s tasks:\n%s", status, moreTasks(FormatTasks(tasks, failures, now), more))
+		parts = append(parts, part)
+	}
+	for _, f := range output.Failures {
+		parts = append(parts, failure(f))
+	}
+	if len(parts) == 0 {
+		return "No services match.", nil
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// validate checks the input's bounds
+func (in Input) validate() error {
+	if strings.TrimSpace(in.Cluster) == "" {
+		return fmt.Errorf("cluster is required")
+	}
+	if len(in.Services) > maxServices {
+		return fmt.Errorf("at most %d services can be described at once", maxServices)
+	}
+	if len(in.Tasks) > maxTasks {
+		return fmt.Errorf("at most %d tasks can be described at once", maxTasks)
+	}
+	if in.DesiredStatus != "" && !contains(statuses, in.DesiredStatus) {
+		return fmt.Errorf("unknown desired status %q; use one of %s", in.DesiredStatus, strings.Join(statuses, ", "))
+	}
+	if in.Events > maxEvents {
+		return fmt.Errorf("at most %d events can be shown", maxEvents)
+	}
+	return nil
+}
+
+// listTasks describes up to maxTasks of the cluster's tasks with the
+// desired status, only the service's when it is set. more reports that
+// further tasks match
+func (t *Tool) listTasks(ctx context.Context, cluster, service, status string) ([]types.Task, []types.Failure, bool, error) {
+	input := &awsecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		DesiredStatus: types.DesiredStatus(status),
+		MaxResults:    aws.Int32(maxTasks),
+	}
+	if service != "" {
+		input.ServiceName = aws.String(service)
+	}
+	output, err := t.client.ListTasks(ctx, input)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("list tasks: %w", err)
+	}
+	if len(output.TaskArns) == 0 {
+		return nil, nil, false, nil
+	}
+
+	tasks, failures, err := t.describeTasks(ctx, cluster, output.TaskArns)
+	return tasks, failures, output.NextToken != nil, err
+}
+
+// describeTasks describes tasks by ID or ARN
+func (t *Tool) describeTasks(ctx context.Context, cluster string, ids []string) ([]types.Task, []types.Failure, error) {
+	output, err := t.client.DescribeTasks(ctx, &awsecs.DescribeTasksInput{Cluster: aws.String(cluster), Tasks: ids})
+	if err != nil {
+		return nil, nil, fmt.Errorf("describe tasks: %w", err)
+	}
+	return output.Tasks, output.Failures, nil
+}
+
+// FormatService describes a service as text for the model: its counts,
+// deployments, and the most recent events
+func FormatService(svc types.Service, events int, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Service %s (%s): %d/%d running, %d pending", aws.ToString(svc.ServiceName), aws.ToString(svc.Status), svc.RunningCount, svc.DesiredCount, svc.PendingCount)
+	if svc.LaunchType != "" {
+		fmt.Fprintf(&b, ", %s", svc.LaunchType)
+	}
+	if def := aws.ToString(svc.TaskDefinition); def != "" {
+		fmt.Fprintf(&b, ", task definition %s", shortARN(def))
+	}
+	if dc := svc.DeploymentConfiguration; dc != nil && dc.DeploymentCircuitBreaker != nil && dc.DeploymentCircuitBreaker.Enable {
+		b.WriteString(", circuit breaker on")
+		if dc.DeploymentCircuitBreaker.Rollback {
+			b.WriteString(" with rollback")
+		}
+	}
+
+	if len(svc.Deployments) > 0 {
+		b.WriteString("\nDeployments:")
+	}
+	for _, d := range svc.Deployments {
+		fmt.Fprintf(&b, "\n- %s %s", aws.ToString(d.Status), shortARN(aws.ToString(d.TaskDefinition)))
+		if d.RolloutState != "" {
+			fmt.Fprintf(&b, " %s", d.RolloutState)
+			if reason := aws.ToString(d.RolloutStateReason); reason != "" {
+				fmt.Fprintf(&b, " (%s)", reason)
+			}
+		}
+		fmt.Fprintf(&b, ": %d/%d running, %d pending", d.RunningCount, d.DesiredCount, d.PendingCount)
+		if d.FailedTasks > 0 {
+			fmt.Fprintf(&b, ", %d failed tasks", d.FailedTasks)
+		}
+		if d.CreatedAt != nil {
+			fmt.Fprintf(&b, ", started %s", when(*d.CreatedAt, now))
+		}
+	}
+
+	// ECS returns events newest first
+	shown := svc.Events
+	if len(shown) > events {
+		shown = shown[:events]
+	}
+	if len(shown) > 0 {
+		fmt.Fprintf(&b, "\nRecent events, newest first (%d of %d):", len(shown), len(svc.Events))
+	}
+	for _, e := range shown {
+		fmt.Fprintf(&b, "\n- %s %s", when(aws.ToTime(e.CreatedAt), now), aws.ToString(e.Message))
+	}
+	return b.String()
+}
+
+// FormatTasks describes tasks as text for the model, most recently created
+// first, with why each task and container stopped
+func FormatTasks(tasks []types.Task, failures []types.Failure, now time.Time) string {
+	if len(tasks) == 0 && len(failures) == 0 {
+		return "No tasks match."
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return aws.ToTime(tasks[i].CreatedAt).After(aws.ToTime(tasks[j].CreatedAt))
+	})
+
+	var lines []string
+	for _, task := range tasks {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s (%s): %s, desired %s", shortARN(aws.ToString(task.TaskArn)), shortARN(aws.ToString(task.TaskDefinitionArn)),
+			aws.ToString(task.LastStatus), aws.ToString(task.DesiredStatus))
+		if task.HealthStatus != "" && task.HealthStatus != types.HealthStatusUnknown {
+			fmt.Fprintf(&b, ", %s", task.HealthStatus)
+		}
+		if task.StartedAt != nil {
+			fmt.Fprintf(&b, ", started %s", when(*task.StartedAt, now))
+		}
+		if task.StoppedAt != nil {
+			fmt.Fprintf(&b, ", stopped %s", when(*task.StoppedAt, now))
+		}
+		if task.StopCode != "" {
+			fmt.Fprintf(&b, ", stop code %s", task.StopCode)
+		}
+		if reason := aws.ToString(task.StoppedReason); reason != "" {
+			fmt.Fprintf(&b, ": %s", reason)
+		}
+
+		// Exit codes and reasons say why the task stopped, e.g. 137
+		// for a container killed for using too much memory
+		for _, c := range task.Containers {
+			fmt.Fprintf(&b, "\n   container %s: %s", aws.ToString(c.Name), aws.ToString(c.LastStatus))
+			if c.ExitCode != nil {
+				fmt.Fprintf(&b, ", exit code %d", *c.ExitCode)
+			}
+			if c.HealthStatus != "" && c.HealthStatus != types.HealthStatusUnknown {
+				fmt.Fprintf(&b, ", %s", c.HealthStatus)
+			}
+			if reason := aws.ToString(c.Reason); reason != "" {
+				fmt.Fprintf(&b, ", reason: %s", reason)
+			}
+		}
+		lines = append(lines, b.String())
+	}
+	for _, f := range failures {
+		lines = append(lines, failure(f))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// moreTasks notes that only some of the matching tasks are shown
+func moreTasks(text string, more bool) string {
+	if more {
+		text += fmt.Sprintf("\nOnly the first %d matching tasks are shown; describe specific tasks to see others.", maxTasks)
+	}
+	return text
+}
+
+// failure describes a service or task ECS couldn't describe
+func failure(f types.Failure) string {
+	s := fmt.Sprintf("%s: %s", shortARN(aws.ToString(f.Arn)), aws.ToString(f.Reason))
+	if detail := aws.ToString(f.Detail); detail != "" {
+		s += " (" + detail + ")"
+	}
+	return s
+}
+
+// when formats a time along with how long ago it was
+func when(t, now time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), humanize.Duration(now.Sub(t).Truncate(time.Minute)))
+}
+
+// shortARN returns the last part of an ECS ARN: a task's ID, or a task
+// definition's family and revision
+func shortARN(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsecs "github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+type fakeECS struct {
+	services  []types.Service
+	tasks     []types.Task
+	listInput *awsecs.ListTasksInput
+}
+
+func (f *fakeECS) DescribeServices(ctx context.Context, params *awsecs.DescribeServicesInput, optFns ...func(*awsecs.Options)) (*awsecs.DescribeServicesOutput, error) {
+	out := &awsecs.DescribeServicesOutput{Services: f.services}
+	for _, name := range params.Services[len(f.services):] {
+		out.Failures = append(out.Failures, types.Failure{Arn: aws.String(name), Reason: aws.String("MISSING")})
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DescribeTasks(ctx context.Context, params *awsecs.DescribeTasksInput, optFns ...func(*awsecs.Options)) (*awsecs.DescribeTasksOutput, error) {
+	return &awsecs.DescribeTasksOutput{Tasks: f.tasks}, nil
+}
+
+func (f *fakeECS) ListTasks(ctx context.Context, params *awsecs.ListTasksInput, optFns ...func(*awsecs.Options)) (*awsecs.ListTasksOutput, error) {
+	f.listInput = params
+	var arns []string
+	for _, t := range f.tasks {
+		arns = append(arns, aws.ToString(t.TaskArn))
+	}
+	return &awsecs.ListTasksOutput{TaskArns: arns}, nil
+}
+
+func TestExecuteServices(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := &fakeECS{
+		services: []types.Service{{
+			ServiceName:    aws.String("checkout"),
+			Status:         aws.String("ACTIVE"),
+			DesiredCount:   4,
+			RunningCount:   2,
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/checkout:42"),
+			Deployments: []types.Deployment{{
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/checkout:42"),
+				RolloutState:   types.DeploymentRolloutStateInProgress,
+				DesiredCount:   4,
+				RunningCount:   2,
+				FailedTasks:    3,
+				CreatedAt:      aws.Time(now.Add(-20 * time.Minute)),
+			}},
+			Events: []types.ServiceEvent{
+				{CreatedAt: aws.Time(now.Add(-2 * time.Minute)), Message: aws.String("(service checkout) has started 1 tasks")},
+				{CreatedAt: aws.Time(now.Add(-5 * time.Minute)), Message: aws.String("(service checkout) has stopped 1 running tasks")},
+			},
+		}},
+		tasks: []types.Task{{
+			TaskArn:           aws.String("arn:aws:ecs:us-east-1:123456789012:task/prod/abc123"),
+			TaskDefinitionArn: aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/checkout:42"),
+			LastStatus:        aws.String("STOPPED"),
+			DesiredStatus:     aws.String("STOPPED"),
+			StopCode:          types.TaskStopCodeEssentialContainerExited,
+			StoppedReason:     aws.String("Essential container in task exited"),
+			StoppedAt:         aws.Time(now.Add(-5 * time.Minute)),
+			Containers: []types.Container{{
+				Name:       aws.String("app"),
+				LastStatus: aws.String("STOPPED"),
+				ExitCode:   aws.Int32(137),
+				Reason:     aws.String("OutOfMemoryError: Container killed due to memory usage"),
+			}},
+		}},
+	}
+	tool := NewWithClient(fake)
+	tool.now = func() time.Time { return now }
+
+	raw, _ := json.Marshal(Input{Cluster: "prod", Services: []string{"checkout", "payments"}, Events: 1})
+	out, err := tool.Execute(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.listInput.DesiredStatus != types.DesiredStatusStopped || aws.ToString(fake.listInput.ServiceName) != "checkout" {
+		t.Errorf("ListTasks input = %+v, want the service's stopped tasks", fake.listInput)
+	}
+	for _, want := range []string{
+		"Service checkout (ACTIVE): 2/4 running, 0 pending, task definition checkout:42",
+		"- PRIMARY checkout:42 IN_PROGRESS: 2/4 running, 0 pending, 3 failed tasks, started 2024-05-01T11:40:00Z (20m ago)",
+		"Recent events, newest first (1 of 2):\n- 2024-05-01T11:58:00Z (2m ago) (service checkout) has started 1 tasks",
+		"abc123 (checkout:42): STOPPED, desired STOPPED, stopped 2024-05-01T11:55:00Z (5m ago), stop code EssentialContainerExited: Essential container in task exited",
+		"container app: STOPPED, exit code 137, reason: OutOfMemoryError",
+		"payments: MISSING",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Execute() = %q, missing %q", out, want)
+		}
+	}
+	if strings.Contains(out, "has stopped 1 running tasks") {
+		t.Error("Execute() should show only the requested number of events")
+	}
+}
+
+func TestExecuteCluster(t *testing.T) {
+	fake := &fakeECS{}
+	raw, _ := json.Marshal(Input{Cluster: "prod"})
+
+	out, err := NewWithClient(fake).Execute(context.Background(), raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.listInput.DesiredStatus != types.DesiredStatusRunning || fake.listInput.ServiceName != nil {
+		t.Errorf("ListTasks input = %+v, want the cluster's running tasks", fake.listInput)
+	}
+	if out != "RUNNING tasks in prod:\nNo tasks match." {
+		t.Errorf("Execute() = %q", out)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   Input
+		wantErr bool
+	}{
+		{"missing cluster", Input{Services: []string{"checkout"}}, true},
+		{"too many services", Input{Cluster: "prod", Services: make([]string, maxServices+1)}, true},
+		{"unknown status", Input{Cluster: "prod", DesiredStatus: "DEAD"}, true},
+		{"too many events", Input{Cluster: "prod", Events: maxEvents + 1}, true},
+		{"valid", Input{Cluster: "prod", Tasks: []string{"abc123"}, DesiredStatus: "STOPPED"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.input.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package transfer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/slack-go/slack"
+)
+
+// MetadataEventType marks the notice left in a conversation's old channel,
+// which tells the agent polling that channel where to go next
+const MetadataEventType = "cloudops_transfer"
+
+// maxContextItems caps each list in the context message
+const maxContextItems = 5
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+
+// Check returns why userID may not move conv to a channel with the given
+// visibility, or "" when they may. Only people taking part in a
+// conversation can move it, and never anywhere more exposed than it is now
+func Check(conv *models.Conversation, userID, visibility string) string {
+	if !conv.TookPart(userID) {
+		return fmt.Sprintf("Only people taking part in `%s` can move it.", conv.ConversationID)
+	}
+	if privacy.Stricter(conv.Visibility, visibility) {
+		return fmt.Sprintf("`%s` is %s, so it can only move to a channel that is at least as private.", conv.ConversationID, describe(conv.Visibility))
+	}
+	return ""
+}
+
+// describe names a visibility level for people
+func describe(level string) string {
+	if level == privacy.DM {
+		return "a direct message"
+	}
+	return level
+}
+
+// Metadata carries the destination and its visibility on the notice in the
+// old channel
+func Metadata(conversationID, channelID, visibility string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType: MetadataEventType,
+		EventPayload: map[string]interface{}{
+			"conversation_id": conversationID,
+			"channel_id":      channelID,
+			"visibility":      visibility,
+		},
+	}
+}
+
+// FromMetadata returns the channel a conversation was moved to and its
+// visibility, when meta records a transfer of that conversation
+func FromMetadata(meta slack.SlackMetadata, conversationID string) (channelID, visibility string, ok bool) {
+	if meta.EventType != MetadataEventType {
+		return "", "", false
+	}
+	id, _ := meta.EventPayload["conversation_id"].(string)
+	channelID, _ = meta.EventPayload["channel_id"].(string)
+	visibility, _ = meta.EventPayload["visibility"].(string)
+	return channelID, visibility, id == conversationID && channelID != ""
+}
+
+// Notice is left in the old channel to point people at the new one
+func Notice(channelID, userID string) string {
+	return fmt.Sprintf("➡️ <@%s> moved this conversation to <#%s>. Please continue there.", userID, channelID)
+}
+
+// Context catches the new channel up on a conversation: what was asked,
+// what has been established so far, and who was involved. permalink links
+// back to where it started and may be empty
+func Context(conv *models.Conversation, fromChannelID, userID, permalink string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📦 <@%s> moved `%s` here from <#%s>", userID, conv.ConversationID, fromChannelID)
+	if permalink != "" {
+		fmt.Fprintf(&b, " (<%s|original request>)", permalink)
+	}
+	b.WriteString(". I'll keep answering in this channel.\n")
+
+	if request := strings.TrimSpace(mentionPattern.ReplaceAllString(conv.InitialCommand, "")); request != "" {
+		fmt.Fprintf(&b, "\n*Request*\n>%s\n", strings.ReplaceAll(request, "\n", "\n>"))
+	}
+
+	if pad := conv.Scratchpad; !pad.IsEmpty() {
+		writeList(&b, "Findings", pad.Findings)
+		writeList(&b, "Still investigating", pad.Hypotheses)
+	}
+
+	var resources []string
+	for _, e := range conv.Entities {
+		resources = append(resources, "`"+e.ID+"`")
+	}
+	writeList(&b, "Resources", resources)
+
+	if len(conv.Participants) > 0 {
+		mentions := make([]string, len(conv.Participants))
+		for i, p := range conv.Participants {
+			mentions[i] = "<@" + p + ">"
+		}
+		fmt.Fprintf(&b, "\n*Participants:* %s\n", strings.Join(mentions, ", "))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// writeList adds a titled bullet list of the most recent items
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	if len(items) > maxContextItems {
+		items = items[len(items)-maxContextItems:]
+	}
+	fmt.Fprintf(b, "\n*%s*\n", title)
+	for _, item := range items {
+		fmt.Fprintf(b, "• %s\n", item)
+	}
+}
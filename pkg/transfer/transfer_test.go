@@ -0,0 +1,91 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	"github.com/slack-go/slack"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	meta := Metadata("conv-1", "C2", privacy.Private)
+
+	if got, visibility, ok := FromMetadata(meta, "conv-1"); !ok || got != "C2" || visibility != privacy.Private {
+		t.Errorf("FromMetadata() = %q, %q, %v; want C2, private, true", got, visibility, ok)
+	}
+	if _, _, ok := FromMetadata(meta, "conv-2"); ok {
+		t.Error("FromMetadata() should ignore transfers of other conversations")
+	}
+	if _, _, ok := FromMetadata(slack.SlackMetadata{EventType: "cloudops_followup"}, "conv-1"); ok {
+		t.Error("FromMetadata() should ignore other event types")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		userID     string
+		target     string
+		allowed    bool
+	}{
+		{name: "requester", visibility: privacy.Public, userID: "U1", target: privacy.Public, allowed: true},
+		{name: "participant", visibility: privacy.Public, userID: "U2", target: privacy.Public, allowed: true},
+		{name: "stranger", visibility: privacy.Public, userID: "U3", target: privacy.Public},
+		{name: "more private", visibility: privacy.Public, userID: "U1", target: privacy.Private, allowed: true},
+		{name: "private to public", visibility: privacy.Private, userID: "U1", target: privacy.Public},
+		{name: "dm to private", visibility: privacy.DM, userID: "U1", target: privacy.Private},
+		{name: "dm to dm", visibility: privacy.DM, userID: "U1", target: privacy.DM, allowed: true},
+		{name: "unrecorded", userID: "U1", target: privacy.Public, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := &models.Conversation{ConversationID: "conv-1", UserID: "U1", Participants: []string{"U2"}, Visibility: tt.visibility}
+			if got := Check(conv, tt.userID, tt.target); (got == "") != tt.allowed {
+				t.Errorf("Check() = %q, want allowed = %v", got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	conv := &models.Conversation{
+		ConversationID: "conv-1",
+		InitialCommand: "<@UBOT> checkout is throwing 502s\nsince 10:00",
+		Scratchpad: &models.Scratchpad{
+			Findings:   []string{"ALB 5xx started at 10:02"},
+			Hypotheses: []string{"bad deploy of checkout-api"},
+		},
+		Entities:     []models.Entity{{Type: models.EntityInstance, ID: "i-0123456789abcdef0"}},
+		Participants: []string{"U1", "U2"},
+	}
+
+	got := Context(conv, "C1", "U1", "https://example.slack.com/archives/C1/p1")
+	for _, want := range []string{
+		"<@U1> moved `conv-1` here from <#C1> (<https://example.slack.com/archives/C1/p1|original request>)",
+		"*Request*\n>checkout is throwing 502s\n>since 10:00",
+		"*Findings*\n• ALB 5xx started at 10:02",
+		"*Still investigating*\n• bad deploy of checkout-api",
+		"*Resources*\n• `i-0123456789abcdef0`",
+		"*Participants:* <@U1>, <@U2>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Context() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "UBOT") {
+		t.Errorf("Context() should drop the bot mention:\n%s", got)
+	}
+}
+
+func TestContextMinimal(t *testing.T) {
+	conv := &models.Conversation{ConversationID: "conv-1", InitialCommand: "help"}
+
+	got := Context(conv, "C1", "U1", "")
+	if strings.Contains(got, "original request") || strings.Contains(got, "Findings") || strings.Contains(got, "Participants") {
+		t.Errorf("Context() included empty sections:\n%s", got)
+	}
+}
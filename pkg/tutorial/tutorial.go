@@ -0,0 +1,202 @@
+// Package tutorial turns a resolved conversation into an anonymized,
+// step-by-step tutorial for the team wiki. The tool calls the agent made
+// are the ground truth for what was checked; the transcript supplies why
+package tutorial
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	"github.com/savaki/cloudops-bot/pkg/postmortem"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+)
+
+// maxCallOutput bounds each tool result shown to the model, so a long
+// investigation still fits in one request
+const maxCallOutput = 2000
+
+// systemPrompt instructs the model to teach from a resolved investigation
+const systemPrompt = `You are an SRE writing a tutorial for the team wiki from a resolved incident investigation, so engineers new to on-call learn how to investigate this kind of problem.
+Respond with a single JSON object and nothing else, using this shape:
+{
+  "title": "what the reader will learn, e.g. \"Diagnosing ECS tasks stuck in PENDING\"",
+  "problem": "the symptoms that started the investigation",
+  "steps": [{"action": "what was checked", "tool": "the tool call that checked it", "why": "why this was the next thing to check", "finding": "what the result showed"}],
+  "fix": "what resolved the problem",
+  "lessons": ["something worth remembering next time"]
+}
+The tool calls are the ground truth. Every step must come from a tool call, naming its tool; use the transcript only to explain why each check was made.
+Leave out claims in the transcript that no tool call backs up, and include dead ends only when ruling them out taught something.
+Don't name people, teams, or accounts. Keep resource names only where they help the reader follow along.`
+
+var (
+	mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+	channelPattern = regexp.MustCompile(`<#[A-Z0-9]+(?:\|[^>]*)?>`)
+	emailPattern   = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+	accountPattern = regexp.MustCompile(`\b\d{12}\b`)
+	ipPattern      = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+)
+
+// Tutorial is a step-by-step account of how an incident was investigated
+type Tutorial struct {
+	Title   string   `json:"title"`
+	Problem string   `json:"problem"`
+	Steps   []Step   `json:"steps"`
+	Fix     string   `json:"fix"`
+	Lessons []string `json:"lessons"`
+}
+
+// Step is one check made during the investigation
+type Step struct {
+	Action  string `json:"action"`
+	Tool    string `json:"tool"`
+	Why     string `json:"why"`
+	Finding string `json:"finding"`
+}
+
+// Drafter asks Claude to write tutorials from conversations
+type Drafter struct {
+	bedrock *bedrock.Client
+}
+
+// NewDrafter creates a tutorial drafter
+func NewDrafter(bedrockClient *bedrock.Client) *Drafter {
+	return &Drafter{bedrock: bedrockClient}
+}
+
+// Resolved reports whether a conversation is finished, either because it
+// ended or because its incident was marked resolved
+func Resolved(conv *models.Conversation) bool {
+	return conv.Ended() || (conv.SLA != nil && !conv.SLA.IsOpen())
+}
+
+// Draft writes a tutorial from a conversation and the tool calls made in it
+func (d *Drafter) Draft(ctx context.Context, conv *models.Conversation, history []models.ConversationHistoryItem, calls []*models.ToolExecution) (*Tutorial, error) {
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("conversation %s made no tool calls", conv.ConversationID)
+	}
+
+	messages := []models.Message{{Role: models.RoleUser, Content: Source(conv, history, calls)}}
+	response, err := d.bedrock.SendMessage(ctx, messages, systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("draft tutorial: %w", err)
+	}
+
+	return Parse(response, calls)
+}
+
+// Source formats the anonymized transcript and tool calls for the model
+func Source(conv *models.Conversation, history []models.ConversationHistoryItem, calls []*models.ToolExecution) string {
+	var b strings.Builder
+	b.WriteString(postmortem.Transcript(conv, history))
+
+	b.WriteString("\nTool calls, in order:\n")
+	for i, call := range calls {
+		fmt.Fprintf(&b, "%d. [%s] %s %s: %s\n", i+1, call.StartedAt.UTC().Format("15:04"), call.Tool, call.Input, call.Outcome)
+		result := call.Output
+		if call.Error != "" {
+			result = call.Error
+		}
+		if result = strings.TrimSpace(result); result != "" {
+			if len(result) > maxCallOutput {
+				result = strings.ToValidUTF8(result[:maxCallOutput], "") + " …"
+			}
+			fmt.Fprintf(&b, "   %s\n", strings.ReplaceAll(result, "\n", "\n   "))
+		}
+	}
+	return Anonymize(b.String())
+}
+
+// Anonymize removes what identifies people and accounts from text: Slack
+// mentions, channel links, email addresses, AWS account IDs, IP addresses,
+// and the secrets privacy.Redact finds
+func Anonymize(text string) string {
+	text = privacy.Redact(text)
+	text = mentionPattern.ReplaceAllString(text, "an engineer")
+	text = channelPattern.ReplaceAllString(text, "the incident channel")
+	text = emailPattern.ReplaceAllString(text, "<email>")
+	text = accountPattern.ReplaceAllString(text, "111122223333")
+	return ipPattern.ReplaceAllString(text, "10.0.0.1")
+}
+
+// Parse extracts the tutorial JSON object from a model response. Steps
+// that name a tool the conversation never called aren't backed by the
+// record, so they are dropped
+func Parse(response string, calls []*models.ToolExecution) (*Tutorial, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var t Tutorial
+	if err := json.Unmarshal([]byte(response[start:end+1]), &t); err != nil {
+		return nil, fmt.Errorf("unmarshal tutorial: %w", err)
+	}
+
+	called := map[string]bool{}
+	for _, call := range calls {
+		called[call.Tool] = true
+	}
+	var steps []Step
+	for _, s := range t.Steps {
+		if strings.TrimSpace(s.Action) != "" && called[s.Tool] {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("tutorial has no steps backed by tool calls")
+	}
+	t.Steps = steps
+
+	if t.Title == "" {
+		t.Title = "Incident walkthrough"
+	}
+	return &t, nil
+}
+
+// Markdown renders the tutorial as a wiki page, anonymized again in case
+// the model repeated something it shouldn't have
+func (t *Tutorial) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_Generated by CloudOps Bot from a resolved investigation. Review before publishing._\n\n", t.Title)
+
+	b.WriteString("## The problem\n\n")
+	b.WriteString(orPlaceholder(t.Problem) + "\n\n")
+
+	b.WriteString("## Investigation\n\n")
+	for i, s := range t.Steps {
+		fmt.Fprintf(&b, "### %d. %s\n\n", i+1, s.Action)
+		if s.Why != "" {
+			fmt.Fprintf(&b, "**Why:** %s\n\n", s.Why)
+		}
+		fmt.Fprintf(&b, "**Tool:** `%s`\n\n", s.Tool)
+		if s.Finding != "" {
+			fmt.Fprintf(&b, "**Finding:** %s\n\n", s.Finding)
+		}
+	}
+
+	b.WriteString("## The fix\n\n")
+	b.WriteString(orPlaceholder(t.Fix) + "\n")
+
+	if len(t.Lessons) > 0 {
+		b.WriteString("\n## Lessons\n\n")
+		for _, l := range t.Lessons {
+			fmt.Fprintf(&b, "- %s\n", l)
+		}
+	}
+
+	return Anonymize(b.String())
+}
+
+func orPlaceholder(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "_TBD_"
+	}
+	return s
+}
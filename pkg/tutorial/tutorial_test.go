@@ -0,0 +1,86 @@
+package tutorial
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestAnonymize(t *testing.T) {
+	got := Anonymize("<@U0ALICE|alice> in <#C0INC|inc-42> saw arn:aws:iam::123456789012:role/app on 172.31.4.7, mail bob@example.com, password=hunter2")
+	for _, leak := range []string{"U0ALICE", "alice", "inc-42", "123456789012", "172.31.4.7", "bob@example.com", "hunter2"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("Anonymize() = %q, still contains %q", got, leak)
+		}
+	}
+	if !strings.Contains(got, "an engineer in the incident channel") {
+		t.Errorf("Anonymize() = %q", got)
+	}
+}
+
+func TestSource(t *testing.T) {
+	started := time.Date(2024, 5, 1, 14, 5, 0, 0, time.UTC)
+	conv := &models.Conversation{ConversationID: "conv-1", UserID: "U0ALICE", CreatedAt: started}
+	history := []models.ConversationHistoryItem{{Role: models.RoleUser, Content: "<@U0BOT> checkout is throwing 5xx", CreatedAt: started}}
+	calls := []*models.ToolExecution{{
+		Tool:      "query_cloudwatch_logs",
+		Input:     `{"log_groups":["/ecs/checkout"]}`,
+		Output:    strings.Repeat("x", maxCallOutput+100),
+		Outcome:   models.ToolSucceeded,
+		StartedAt: started.Add(time.Minute),
+	}}
+
+	got := Source(conv, history, calls)
+	if strings.Contains(got, "U0ALICE") {
+		t.Error("Source() should anonymize the transcript")
+	}
+	if !strings.Contains(got, `1. [14:06] query_cloudwatch_logs {"log_groups":["/ecs/checkout"]}: succeeded`) {
+		t.Errorf("Source() = %q, missing the tool call", got)
+	}
+	if strings.Contains(got, strings.Repeat("x", maxCallOutput+1)) {
+		t.Error("Source() should shorten long tool output")
+	}
+}
+
+func TestParse(t *testing.T) {
+	calls := []*models.ToolExecution{{Tool: "describe_ec2_instances"}}
+	response := `{"title": "Finding a stopped instance", "steps": [
+		{"action": "Checked the instance state", "tool": "describe_ec2_instances", "finding": "stopped"},
+		{"action": "Checked the deploy log", "tool": "read_deploy_log"}
+	], "fix": "Started the instance"}`
+
+	tut, err := Parse(response, calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tut.Steps) != 1 || tut.Steps[0].Tool != "describe_ec2_instances" {
+		t.Errorf("Steps = %+v, want only the step backed by a tool call", tut.Steps)
+	}
+
+	if _, err := Parse(`{"steps": [{"action": "Guessed", "tool": "read_deploy_log"}]}`, calls); err == nil {
+		t.Error("Parse() should reject a tutorial with no backed steps")
+	}
+	if _, err := Parse("no json", calls); err == nil {
+		t.Error("Parse() should reject a response without JSON")
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tut := &Tutorial{
+		Title:   "Finding a stopped instance",
+		Steps:   []Step{{Action: "Checked the instance state", Tool: "describe_ec2_instances", Why: "the target was unhealthy", Finding: "stopped by <@U0BOB>"}},
+		Lessons: []string{"Check the instance before the load balancer"},
+	}
+
+	md := tut.Markdown()
+	for _, want := range []string{"# Finding a stopped instance", "### 1. Checked the instance state", "**Tool:** `describe_ec2_instances`", "## The fix\n\n_TBD_", "- Check the instance"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q", want)
+		}
+	}
+	if strings.Contains(md, "U0BOB") {
+		t.Error("Markdown() should anonymize what the model wrote")
+	}
+}
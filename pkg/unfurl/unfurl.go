@@ -0,0 +1,436 @@
+// Package unfurl turns AWS console links pasted in Slack into summary cards:
+// the linked resource's state, key metrics, and recent alarms. Cards are
+// read with the same tools the agent investigates with
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/savaki/cloudops-bot/pkg/bedrock"
+	"github.com/savaki/cloudops-bot/pkg/humanize"
+	"github.com/savaki/cloudops-bot/pkg/privacy"
+	cwtool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatch"
+	ec2tool "github.com/savaki/cloudops-bot/pkg/tools/ec2"
+	ecstool "github.com/savaki/cloudops-bot/pkg/tools/ecs"
+	"github.com/slack-go/slack"
+)
+
+// Resource kinds that can be unfurled
+const (
+	KindInstance   = "instance"
+	KindECSService = "ecs_service"
+	KindRDS        = "rds"
+	KindLambda     = "lambda"
+)
+
+// maxLinks bounds how many links in one message are unfurled, since each
+// card takes several AWS calls
+const maxLinks = 3
+
+// alarmWindow is how recently an alarm that isn't firing must have changed
+// state to be shown: within the last day
+const alarmWindow = 24 * time.Hour
+
+// alarmColor marks the card of a resource with an alarm firing
+const alarmColor = "#e01e5a"
+
+var (
+	instancePattern = regexp.MustCompile(`instanceId=(i-[0-9a-f]+)`)
+	servicePattern  = regexp.MustCompile(`clusters/([^/?#;]+)/services/([^/?#;]+)`)
+	functionPattern = regexp.MustCompile(`/functions/([^/?#;]+)`)
+	databasePattern = regexp.MustCompile(`database:id=([^;&?/]+)`)
+)
+
+// kindNames label each kind of resource on its card
+var kindNames = map[string]string{
+	KindInstance:   "EC2 instance",
+	KindECSService: "ECS service",
+	KindRDS:        "RDS database",
+	KindLambda:     "Lambda function",
+}
+
+// Resource is what an AWS console URL links to
+type Resource struct {
+	Kind    string
+	Region  string // "" when the link doesn't name one
+	ID      string // instance ID, service name, DB identifier, or function name
+	Cluster string // ECS services only
+}
+
+// Name identifies the resource on its card
+func (r Resource) Name() string {
+	if r.Cluster != "" {
+		return r.Cluster + "/" + r.ID
+	}
+	return r.ID
+}
+
+// Parse returns the resource an AWS console URL links to. Role-switch and
+// federation sign-in links are followed to the console page they open
+func Parse(link string) (Resource, bool) {
+	return parse(link, 0)
+}
+
+func parse(link string, depth int) (Resource, bool) {
+	u, err := url.Parse(link)
+	if err != nil || depth > 2 {
+		return Resource{}, false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	prefix, ok := consoleHost(host)
+	if !ok {
+		_, fragmentQuery, _ := strings.Cut(u.EscapedFragment(), "?")
+		for _, raw := range []string{u.RawQuery, fragmentQuery} {
+			values, _ := url.ParseQuery(raw)
+			for _, key := range []string{"redirect_uri", "destination"} {
+				if target := values.Get(key); target != "" {
+					return parse(target, depth+1)
+				}
+			}
+		}
+		return Resource{}, false
+	}
+
+	region := u.Query().Get("region")
+	if region == "" {
+		region = prefix
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	page := u.Path + "#" + u.Fragment
+	r := Resource{Region: region}
+	switch service {
+	case "ec2":
+		if m := instancePattern.FindStringSubmatch(page); m != nil {
+			r.Kind, r.ID = KindInstance, m[1]
+		}
+	case "ecs":
+		if m := servicePattern.FindStringSubmatch(page); m != nil {
+			r.Kind, r.Cluster, r.ID = KindECSService, m[1], m[2]
+		}
+	case "rds":
+		if m := databasePattern.FindStringSubmatch(page); m != nil {
+			r.Kind, r.ID = KindRDS, m[1]
+		}
+	case "lambda":
+		if m := functionPattern.FindStringSubmatch(page); m != nil {
+			r.Kind, r.ID = KindLambda, m[1]
+		}
+	}
+	if r.Kind == "" {
+		return Resource{}, false
+	}
+	return r, true
+}
+
+// consoleHost reports whether host serves the AWS console, and returns the
+// region it names, if any
+func consoleHost(host string) (string, bool) {
+	for _, domain := range []string{"console.aws.amazon.com", "console.amazonaws.cn", "console.amazonaws-us-gov.com"} {
+		if host == domain {
+			return "", true
+		}
+		if region, ok := strings.CutSuffix(host, "."+domain); ok {
+			return region, true
+		}
+	}
+	return "", false
+}
+
+// AlarmsAPI is the part of the CloudWatch client used to find a resource's
+// alarms
+type AlarmsAPI interface {
+	DescribeAlarmsForMetric(ctx context.Context, params *awscw.DescribeAlarmsForMetricInput, optFns ...func(*awscw.Options)) (*awscw.DescribeAlarmsForMetricOutput, error)
+}
+
+// Clients read a card, all in the resource's region
+type Clients struct {
+	EC2     bedrock.Tool
+	ECS     bedrock.Tool
+	Metrics bedrock.Tool
+	Alarms  AlarmsAPI
+}
+
+// Unfurler builds cards for console links
+type Unfurler struct {
+	clients func(region string) Clients
+	now     func() time.Time
+}
+
+// New creates an unfurler that reads resources with cfg's credentials, in
+// the region each link names
+func New(cfg aws.Config) *Unfurler {
+	return NewWithClients(func(region string) Clients {
+		cfg := cfg.Copy()
+		if region != "" {
+			cfg.Region = region
+		}
+		return Clients{
+			EC2:     ec2tool.New(cfg),
+			ECS:     ecstool.New(cfg),
+			Metrics: cwtool.New(cfg),
+			Alarms:  awscw.NewFromConfig(cfg),
+		}
+	})
+}
+
+// NewWithClients creates an unfurler with custom clients for each region
+func NewWithClients(clients func(region string) Clients) *Unfurler {
+	return &Unfurler{clients: clients, now: time.Now}
+}
+
+// Unfurl builds cards for the console links among links, keyed by link.
+// Links that aren't to a supported resource, or whose resource can't be
+// read, are left for Slack to show as usual
+func (u *Unfurler) Unfurl(ctx context.Context, links []string) map[string]slack.Attachment {
+	unfurls := map[string]slack.Attachment{}
+	for _, link := range links {
+		if len(unfurls) == maxLinks {
+			break
+		}
+		if _, ok := unfurls[link]; ok {
+			continue
+		}
+		r, ok := Parse(link)
+		if !ok {
+			continue
+		}
+		card, err := u.Card(ctx, r)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to unfurl", "kind", r.Kind, "resource", r.Name(), "error", err)
+			continue
+		}
+		unfurls[link] = card
+	}
+	return unfurls
+}
+
+// Card summarizes a resource: its state, key metrics over the last few
+// hours, and its alarms that are firing or changed state recently. Only a
+// failure to read its state fails the card
+func (u *Unfurler) Card(ctx context.Context, r Resource) (slack.Attachment, error) {
+	clients := u.clients(r.Region)
+	now := u.now()
+
+	header := fmt.Sprintf("*%s* `%s`", kindNames[r.Kind], r.Name())
+	if r.Region != "" {
+		header += " in " + r.Region
+	}
+	state, err := u.state(ctx, clients, r)
+	if err != nil {
+		return slack.Attachment{}, err
+	}
+	if state != "" {
+		header += "\n" + state
+	}
+	blocks := []slack.Block{section(header)}
+
+	specs := metricsFor(r)
+	var lines []string
+	for _, m := range specs {
+		output, err := execute(ctx, clients.Metrics, cwtool.Input{Namespace: m.namespace, MetricName: m.name, Dimensions: m.dimensions, Stat: m.stat})
+		if err != nil {
+			slog.WarnContext(ctx, "failed to read metric for unfurl", "metric", m.name, "error", err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("• %s (%s) %s", m.name, strings.ToLower(m.stat), summarize(output)))
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, section(fmt.Sprintf("*Metrics, %s*\n%s", cwtool.DefaultRange, strings.Join(lines, "\n"))))
+	}
+
+	color := ""
+	if alarms, err := u.alarms(ctx, clients.Alarms, specs, now); err != nil {
+		slog.WarnContext(ctx, "failed to read alarms for unfurl", "error", err)
+	} else {
+		blocks = append(blocks, section("*Alarms*\n"+formatAlarms(alarms, now)))
+		if len(alarms) > 0 && alarms[0].StateValue == types.StateValueAlarm {
+			color = alarmColor
+		}
+	}
+
+	blocks = append(blocks, slack.NewContextBlock("",
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("As of %s UTC · mention me to investigate", now.UTC().Format("15:04")), false, false)))
+
+	return slack.Attachment{
+		Color:    color,
+		Fallback: fmt.Sprintf("%s %s", kindNames[r.Kind], r.Name()),
+		Blocks:   slack.Blocks{BlockSet: blocks},
+	}, nil
+}
+
+// state describes the resource in a line, for the kinds the agent has a
+// tool to describe
+func (u *Unfurler) state(ctx context.Context, clients Clients, r Resource) (string, error) {
+	var output string
+	var err error
+	switch r.Kind {
+	case KindInstance:
+		output, err = execute(ctx, clients.EC2, ec2tool.Input{InstanceIDs: []string{r.ID}})
+	case KindECSService:
+		output, err = execute(ctx, clients.ECS, ecstool.Input{Cluster: r.Cluster, Services: []string{r.ID}, Events: 1})
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(output, "\n")
+	if strings.HasPrefix(line, "No ") {
+		return "", fmt.Errorf("%s", line)
+	}
+	return line, nil
+}
+
+// execute runs a tool with input
+func execute(ctx context.Context, tool bedrock.Tool, input interface{}) (string, error) {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("marshal %s input: %w", tool.Name(), err)
+	}
+	return tool.Execute(ctx, raw)
+}
+
+// metric is a metric shown on a card
+type metric struct {
+	namespace  string
+	name       string
+	stat       string
+	dimensions map[string]string
+}
+
+// metricsFor returns the key metrics of a resource
+func metricsFor(r Resource) []metric {
+	switch r.Kind {
+	case KindInstance:
+		dims := map[string]string{"InstanceId": r.ID}
+		return []metric{
+			{"AWS/EC2", "CPUUtilization", "Average", dims},
+			{"AWS/EC2", "StatusCheckFailed", "Maximum", dims},
+		}
+	case KindECSService:
+		dims := map[string]string{"ClusterName": r.Cluster, "ServiceName": r.ID}
+		return []metric{
+			{"AWS/ECS", "CPUUtilization", "Average", dims},
+			{"AWS/ECS", "MemoryUtilization", "Average", dims},
+		}
+	case KindRDS:
+		dims := map[string]string{"DBInstanceIdentifier": r.ID}
+		return []metric{
+			{"AWS/RDS", "CPUUtilization", "Average", dims},
+			{"AWS/RDS", "DatabaseConnections", "Average", dims},
+		}
+	case KindLambda:
+		dims := map[string]string{"FunctionName": r.ID}
+		return []metric{
+			{"AWS/Lambda", "Errors", "Sum", dims},
+			{"AWS/Lambda", "Duration", "p99", dims},
+		}
+	}
+	return nil
+}
+
+// summarize shortens the metrics tool's summary of a series to its shape
+// and its range of values
+func summarize(output string) string {
+	var stats, shape string
+	for _, line := range strings.Split(output, "\n") {
+		if _, rest, ok := strings.Cut(line, " datapoints: "); ok {
+			stats = rest
+		}
+		if rest, ok := strings.CutPrefix(line, "Shape: "); ok {
+			shape = rest
+		}
+	}
+	if stats == "" {
+		return "no data"
+	}
+	return strings.TrimSpace(shape + " " + stats)
+}
+
+// alarms returns the alarms on the card's metrics that are firing or
+// changed state within alarmWindow, firing first and then most recent first
+func (u *Unfurler) alarms(ctx context.Context, client AlarmsAPI, specs []metric, now time.Time) ([]types.MetricAlarm, error) {
+	var alarms []types.MetricAlarm
+	seen := map[string]bool{}
+	for _, m := range specs {
+		output, err := client.DescribeAlarmsForMetric(ctx, &awscw.DescribeAlarmsForMetricInput{
+			Namespace:  aws.String(m.namespace),
+			MetricName: aws.String(m.name),
+			Dimensions: dimensions(m.dimensions),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describe alarms for metric: %w", err)
+		}
+		for _, a := range output.MetricAlarms {
+			name := aws.ToString(a.AlarmName)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if a.StateValue == types.StateValueAlarm || now.Sub(aws.ToTime(a.StateUpdatedTimestamp)) < alarmWindow {
+				alarms = append(alarms, a)
+			}
+		}
+	}
+
+	sort.SliceStable(alarms, func(i, j int) bool {
+		if firing := alarms[i].StateValue == types.StateValueAlarm; firing != (alarms[j].StateValue == types.StateValueAlarm) {
+			return firing
+		}
+		return aws.ToTime(alarms[i].StateUpdatedTimestamp).After(aws.ToTime(alarms[j].StateUpdatedTimestamp))
+	})
+	return alarms, nil
+}
+
+// formatAlarms lists alarms with their state and when it last changed
+func formatAlarms(alarms []types.MetricAlarm, now time.Time) string {
+	if len(alarms) == 0 {
+		return "None firing or changed state in the last day"
+	}
+	lines := make([]string, len(alarms))
+	for i, a := range alarms {
+		icon := "⚪"
+		switch a.StateValue {
+		case types.StateValueAlarm:
+			icon = "🔴"
+		case types.StateValueOk:
+			icon = "✅"
+		}
+		lines[i] = fmt.Sprintf("%s `%s` %s since %s", icon, aws.ToString(a.AlarmName), a.StateValue,
+			humanize.Relative(aws.ToTime(a.StateUpdatedTimestamp), now, time.UTC))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dimensions converts dimension values to CloudWatch's form, sorted by name
+func dimensions(values map[string]string) []types.Dimension {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dims := make([]types.Dimension, 0, len(names))
+	for _, name := range names {
+		dims = append(dims, types.Dimension{Name: aws.String(name), Value: aws.String(values[name])})
+	}
+	return dims
+}
+
+// section is a block of text, with any secrets the tools read removed
+func section(text string) slack.Block {
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, privacy.Redact(text), false, false), nil, nil)
+}
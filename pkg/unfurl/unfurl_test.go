@@ -0,0 +1,194 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscw "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/savaki/cloudops-bot/pkg/links"
+	"github.com/savaki/cloudops-bot/pkg/timerange"
+	cwtool "github.com/savaki/cloudops-bot/pkg/tools/cloudwatch"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want Resource
+		ok   bool
+	}{
+		{
+			name: "ec2 instance",
+			link: "https://eu-west-1.console.aws.amazon.com/ec2/home?region=eu-west-1#InstanceDetails:instanceId=i-0123456789abcdef0",
+			want: Resource{Kind: KindInstance, Region: "eu-west-1", ID: "i-0123456789abcdef0"},
+			ok:   true,
+		},
+		{
+			name: "ecs service",
+			link: "https://us-west-2.console.aws.amazon.com/ecs/v2/clusters/prod/services/api/health?region=us-west-2",
+			want: Resource{Kind: KindECSService, Region: "us-west-2", ID: "api", Cluster: "prod"},
+			ok:   true,
+		},
+		{
+			name: "rds database",
+			link: "https://us-east-1.console.aws.amazon.com/rds/home?region=us-east-1#database:id=orders-db;is-cluster=false",
+			want: Resource{Kind: KindRDS, Region: "us-east-1", ID: "orders-db"},
+			ok:   true,
+		},
+		{
+			name: "lambda function without region in query",
+			link: "https://ap-south-1.console.aws.amazon.com/lambda/home#/functions/payments?tab=code",
+			want: Resource{Kind: KindLambda, Region: "ap-south-1", ID: "payments"},
+			ok:   true,
+		},
+		{
+			name: "role switch link",
+			link: links.New("us-east-1", links.WithSwitchRole("123456789012", "ReadOnly", "prod")).Instance("", "i-0abc"),
+			want: Resource{Kind: KindInstance, Region: "us-east-1", ID: "i-0abc"},
+			ok:   true,
+		},
+		{
+			name: "federation link",
+			link: links.New("us-east-1", links.WithFederation("https://my-org.awsapps.com/start/#/console?account_id=123456789012&role_name=ReadOnly&destination=")).LambdaFunction("", "payments"),
+			want: Resource{Kind: KindLambda, Region: "us-east-1", ID: "payments"},
+			ok:   true,
+		},
+		{
+			name: "console page without a resource",
+			link: "https://us-east-1.console.aws.amazon.com/ec2/home?region=us-east-1#Instances:",
+		},
+		{
+			name: "not the console",
+			link: "https://docs.aws.amazon.com/ec2/home#InstanceDetails:instanceId=i-0abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Parse(tt.link)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Parse() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC)
+	r := timerange.Range{Start: start, End: start.Add(time.Hour)}
+	series := cwtool.Series{
+		Label:      "AWS/EC2 CPUUtilization Average per 5m",
+		Timestamps: []time.Time{start, start.Add(5 * time.Minute), start.Add(10 * time.Minute)},
+		Values:     []float64{10, 90, 20},
+	}
+
+	got := summarize(cwtool.Format(series, r))
+	if !strings.HasPrefix(got, "▁█") || !strings.Contains(got, "max 90 at 14:05") || !strings.Contains(got, "latest 20 at 14:10") {
+		t.Errorf("summarize() = %q", got)
+	}
+	if got := summarize(cwtool.Format(cwtool.Series{Label: "x"}, r)); got != "no data" {
+		t.Errorf("summarize() of an empty series = %q", got)
+	}
+}
+
+// fakeTool returns a canned result and records its input
+type fakeTool struct {
+	name   string
+	output string
+	err    error
+	inputs []string
+}
+
+func (f *fakeTool) Name() string                        { return f.name }
+func (f *fakeTool) Description() string                 { return "" }
+func (f *fakeTool) InputSchema() map[string]interface{} { return nil }
+func (f *fakeTool) Execute(ctx context.Context, input json.RawMessage) (string, error) {
+	f.inputs = append(f.inputs, string(input))
+	return f.output, f.err
+}
+
+type fakeAlarms struct {
+	alarms []types.MetricAlarm
+}
+
+func (f *fakeAlarms) DescribeAlarmsForMetric(ctx context.Context, params *awscw.DescribeAlarmsForMetricInput, optFns ...func(*awscw.Options)) (*awscw.DescribeAlarmsForMetricOutput, error) {
+	return &awscw.DescribeAlarmsForMetricOutput{MetricAlarms: f.alarms}, nil
+}
+
+func TestCard(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	ec2 := &fakeTool{name: "describe_ec2_instances", output: "i-0abc (web): running, t3.small in us-east-1a, password=hunter2"}
+	metrics := &fakeTool{name: "query_cloudwatch_metrics", output: "AWS/EC2 CPUUtilization\n3 datapoints: min 10 · avg 40 · max 90 at 14:05 · latest 20 at 14:10\nShape: ▁█▂"}
+	alarms := &fakeAlarms{alarms: []types.MetricAlarm{
+		{AlarmName: aws.String("web-cpu-old"), StateValue: types.StateValueOk, StateUpdatedTimestamp: aws.Time(now.Add(-72 * time.Hour))},
+		{AlarmName: aws.String("web-status"), StateValue: types.StateValueOk, StateUpdatedTimestamp: aws.Time(now.Add(-time.Hour))},
+		{AlarmName: aws.String("web-cpu"), StateValue: types.StateValueAlarm, StateUpdatedTimestamp: aws.Time(now.Add(-3 * time.Hour))},
+	}}
+	var region string
+	u := NewWithClients(func(r string) Clients {
+		region = r
+		return Clients{EC2: ec2, Metrics: metrics, Alarms: alarms}
+	})
+	u.now = func() time.Time { return now }
+
+	card, err := u.Card(context.Background(), Resource{Kind: KindInstance, Region: "us-west-2", ID: "i-0abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region != "us-west-2" {
+		t.Errorf("clients region = %q, want the link's", region)
+	}
+	if card.Color != alarmColor {
+		t.Errorf("Color = %q, want the alarm color while an alarm fires", card.Color)
+	}
+
+	data, _ := json.Marshal(card.Blocks)
+	out := string(data)
+	for _, want := range []string{"i-0abc (web): running", "CPUUtilization (average) ▁█▂ min 10", "🔴 `web-cpu` ALARM since 3h ago", "web-status"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Card() missing %q in %s", want, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Error("Card() should redact secrets the tools read")
+	}
+	if strings.Contains(out, "web-cpu-old") {
+		t.Error("Card() should leave out alarms that settled more than a day ago")
+	}
+	if strings.Index(out, "web-cpu`") > strings.Index(out, "web-status") {
+		t.Error("Card() should list firing alarms first")
+	}
+	if len(ec2.inputs) != 1 || !strings.Contains(ec2.inputs[0], `"i-0abc"`) {
+		t.Errorf("describe_ec2_instances inputs = %v", ec2.inputs)
+	}
+
+	ec2.err = errors.New("InvalidInstanceID.NotFound")
+	if _, err := u.Card(context.Background(), Resource{Kind: KindInstance, ID: "i-0gone"}); err == nil {
+		t.Error("Card() should fail when the resource can't be read")
+	}
+}
+
+func TestUnfurl(t *testing.T) {
+	metrics := &fakeTool{name: "query_cloudwatch_metrics", output: "No datapoints."}
+	u := NewWithClients(func(string) Clients {
+		return Clients{Metrics: metrics, Alarms: &fakeAlarms{}}
+	})
+
+	lambda := "https://us-east-1.console.aws.amazon.com/lambda/home?region=us-east-1#/functions/payments"
+	unfurls := u.Unfurl(context.Background(), []string{lambda, "https://example.com/", lambda})
+	if len(unfurls) != 1 {
+		t.Fatalf("Unfurl() = %d cards, want 1", len(unfurls))
+	}
+	if _, ok := unfurls[lambda]; !ok {
+		t.Error("Unfurl() should key cards by link")
+	}
+	if len(metrics.inputs) != 2 {
+		t.Errorf("metrics read %d times, want once per key metric", len(metrics.inputs))
+	}
+}
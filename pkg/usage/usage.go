@@ -0,0 +1,88 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Usage is the billable work done for a conversation
+type Usage struct {
+	InputTokens  int64
+	OutputTokens int64
+	ToolCalls    int64
+	Runtime      time.Duration
+}
+
+// IsZero reports whether nothing has been recorded
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Meter accumulates usage for one conversation. A nil Meter discards
+// everything, so callers never need to check whether metering is on
+type Meter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// NewMeter creates an empty meter
+func NewMeter() *Meter {
+	return &Meter{}
+}
+
+// AddTokens records the tokens used by a model call
+func (m *Meter) AddTokens(input, output int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.InputTokens += int64(input)
+	m.usage.OutputTokens += int64(output)
+}
+
+// AddToolCall records a call to an AWS API made on the user's behalf
+func (m *Meter) AddToolCall() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.ToolCalls++
+}
+
+// AddRuntime records time the conversation kept a task running
+func (m *Meter) AddRuntime(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.Runtime += d
+}
+
+// Take returns the usage recorded since the last Take and resets the meter
+func (m *Meter) Take() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.usage
+	m.usage = Usage{}
+	return u
+}
+
+type meterKey struct{}
+
+// WithMeter returns a context whose model and tool calls are recorded on m
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// FromContext returns the context's meter, or nil when usage isn't metered
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
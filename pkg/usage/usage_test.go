@@ -0,0 +1,39 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	m := NewMeter()
+	ctx := WithMeter(context.Background(), m)
+
+	FromContext(ctx).AddTokens(1200, 300)
+	FromContext(ctx).AddTokens(800, 100)
+	FromContext(ctx).AddToolCall()
+	m.AddRuntime(90 * time.Second)
+
+	want := Usage{InputTokens: 2000, OutputTokens: 400, ToolCalls: 1, Runtime: 90 * time.Second}
+	if got := m.Take(); got != want {
+		t.Errorf("Take() = %+v, want %+v", got, want)
+	}
+	if got := m.Take(); !got.IsZero() {
+		t.Errorf("Take() after Take() = %+v, want zero", got)
+	}
+}
+
+func TestMeterUnmetered(t *testing.T) {
+	m := FromContext(context.Background())
+	if m != nil {
+		t.Fatal("FromContext() without a meter should return nil")
+	}
+
+	// A nil meter discards usage rather than panicking
+	m.AddTokens(1, 1)
+	m.AddToolCall()
+	if got := m.Take(); !got.IsZero() {
+		t.Errorf("Take() on nil meter = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,172 @@
+package warmpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Defaults for a warm agent's polling loop
+const (
+	DefaultPollInterval      = time.Second
+	DefaultHeartbeatInterval = 15 * time.Second
+	DefaultStaleAfter        = 45 * time.Second
+)
+
+// ErrIdleTimeout is returned by Wait when no conversation arrived in time
+var ErrIdleTimeout = errors.New("warm agent idle timeout")
+
+// Store persists warm pool registrations
+type Store interface {
+	Register(ctx context.Context, agent *models.WarmAgent) error
+	Heartbeat(ctx context.Context, agentID string, at time.Time) error
+	Get(ctx context.Context, agentID string) (*models.WarmAgent, error)
+	ListIdle(ctx context.Context) ([]*models.WarmAgent, error)
+	// TryClaim assigns the conversation if the agent is still idle,
+	// returning false when another claim got there first
+	TryClaim(ctx context.Context, agentID, conversationID string, at time.Time) (bool, error)
+	// RemoveIdle deregisters the agent if it is still idle, returning false
+	// when it was claimed in the meantime
+	RemoveIdle(ctx context.Context, agentID string) (bool, error)
+}
+
+// Pool hands conversations to idle agent tasks so they skip the Fargate
+// cold start
+type Pool struct {
+	store             Store
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+	staleAfter        time.Duration
+	now               func() time.Time
+}
+
+// New creates a pool backed by store
+func New(store Store) *Pool {
+	return &Pool{
+		store:             store,
+		pollInterval:      DefaultPollInterval,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		staleAfter:        DefaultStaleAfter,
+		now:               time.Now,
+	}
+}
+
+// SetIntervals overrides how often a waiting agent polls for a claim and
+// refreshes its heartbeat. Agents whose heartbeat is older than three
+// intervals are treated as dead
+func (p *Pool) SetIntervals(poll, heartbeat time.Duration) {
+	p.pollInterval = poll
+	p.heartbeatInterval = heartbeat
+	p.staleAfter = 3 * heartbeat
+}
+
+// Candidates returns the idle agents with a live heartbeat, longest
+// waiting first so tasks are recycled evenly
+func Candidates(agents []*models.WarmAgent, now time.Time, staleAfter time.Duration) []*models.WarmAgent {
+	var live []*models.WarmAgent
+	for _, a := range agents {
+		if a.Status != models.WarmAgentIdle || now.Sub(a.HeartbeatAt) > staleAfter {
+			continue
+		}
+		live = append(live, a)
+	}
+	sort.SliceStable(live, func(i, j int) bool {
+		return live[i].RegisteredAt.Before(live[j].RegisteredAt)
+	})
+	return live
+}
+
+// Claim assigns the conversation to an idle agent, or returns nil when the
+// pool is empty and a new task has to be launched
+func (p *Pool) Claim(ctx context.Context, conversationID string) (*models.WarmAgent, error) {
+	idle, err := p.store.ListIdle(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list idle agents: %w", err)
+	}
+
+	now := p.now()
+	for _, agent := range Candidates(idle, now, p.staleAfter) {
+		ok, err := p.store.TryClaim(ctx, agent.AgentID, conversationID, now)
+		if err != nil {
+			return nil, fmt.Errorf("claim agent %s: %w", agent.AgentID, err)
+		}
+		if !ok {
+			continue // lost the race to another conversation
+		}
+		agent.Status = models.WarmAgentClaimed
+		agent.ConversationID = conversationID
+		agent.ClaimedAt = &now
+		return agent, nil
+	}
+
+	return nil, nil
+}
+
+// Wait registers the agent as idle and blocks until a conversation is
+// assigned to it. After maxIdle (or when ctx is cancelled) the agent is
+// deregistered so the task can exit and be replaced
+func (p *Pool) Wait(ctx context.Context, agent *models.WarmAgent, maxIdle time.Duration) (string, error) {
+	if err := p.store.Register(ctx, agent); err != nil {
+		return "", fmt.Errorf("register warm agent: %w", err)
+	}
+
+	deadline := p.now().Add(maxIdle)
+	lastHeartbeat := p.now()
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return p.leave(context.WithoutCancel(ctx), agent.AgentID, ctx.Err())
+		case <-ticker.C:
+		}
+
+		current, err := p.store.Get(ctx, agent.AgentID)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to check warm agent", "agent_id", agent.AgentID, "error", err)
+			continue
+		}
+		if current != nil && current.Status == models.WarmAgentClaimed {
+			return current.ConversationID, nil
+		}
+
+		now := p.now()
+		if now.After(deadline) {
+			return p.leave(ctx, agent.AgentID, ErrIdleTimeout)
+		}
+		if now.Sub(lastHeartbeat) >= p.heartbeatInterval {
+			if err := p.store.Heartbeat(ctx, agent.AgentID, now); err != nil {
+				slog.WarnContext(ctx, "failed to heartbeat warm agent", "agent_id", agent.AgentID, "error", err)
+			} else {
+				lastHeartbeat = now
+			}
+		}
+	}
+}
+
+// leave deregisters an idle agent. A claim that lands concurrently wins, so
+// the conversation it carries is returned instead of cause
+func (p *Pool) leave(ctx context.Context, agentID string, cause error) (string, error) {
+	removed, err := p.store.RemoveIdle(ctx, agentID)
+	if err != nil {
+		return "", fmt.Errorf("deregister warm agent: %w", err)
+	}
+	if removed {
+		return "", cause
+	}
+
+	current, err := p.store.Get(ctx, agentID)
+	if err != nil {
+		return "", fmt.Errorf("get claimed agent: %w", err)
+	}
+	if current == nil || current.ConversationID == "" {
+		return "", cause
+	}
+	return current.ConversationID, nil
+}
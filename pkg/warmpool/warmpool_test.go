@@ -0,0 +1,188 @@
+package warmpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// memStore is an in-memory Store with the same conditional semantics as
+// the DynamoDB repository
+type memStore struct {
+	mu     sync.Mutex
+	agents map[string]models.WarmAgent
+	steal  map[string]bool // agents claimed by someone else just before TryClaim
+}
+
+func newMemStore(agents ...*models.WarmAgent) *memStore {
+	s := &memStore{agents: map[string]models.WarmAgent{}, steal: map[string]bool{}}
+	for _, a := range agents {
+		s.agents[a.AgentID] = *a
+	}
+	return s
+}
+
+func (s *memStore) Register(_ context.Context, agent *models.WarmAgent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[agent.AgentID] = *agent
+	return nil
+}
+
+func (s *memStore) Heartbeat(_ context.Context, agentID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.agents[agentID]
+	a.HeartbeatAt = at
+	s.agents[agentID] = a
+	return nil
+}
+
+func (s *memStore) Get(_ context.Context, agentID string) (*models.WarmAgent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[agentID]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+func (s *memStore) ListIdle(_ context.Context) ([]*models.WarmAgent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var idle []*models.WarmAgent
+	for _, a := range s.agents {
+		if a.Status == models.WarmAgentIdle {
+			a := a
+			idle = append(idle, &a)
+		}
+	}
+	return idle, nil
+}
+
+func (s *memStore) TryClaim(_ context.Context, agentID, conversationID string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[agentID]
+	if s.steal[agentID] {
+		a.Status, a.ConversationID = models.WarmAgentClaimed, "conv-other"
+		s.agents[agentID] = a
+	}
+	if !ok || a.Status != models.WarmAgentIdle {
+		return false, nil
+	}
+	a.Status, a.ConversationID, a.ClaimedAt = models.WarmAgentClaimed, conversationID, &at
+	s.agents[agentID] = a
+	return true, nil
+}
+
+func (s *memStore) RemoveIdle(_ context.Context, agentID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.agents[agentID]; ok && a.Status != models.WarmAgentIdle {
+		return false, nil
+	}
+	delete(s.agents, agentID)
+	return true, nil
+}
+
+func warmAgent(id string, registered, heartbeat time.Time) *models.WarmAgent {
+	return &models.WarmAgent{AgentID: id, Status: models.WarmAgentIdle, RegisteredAt: registered, HeartbeatAt: heartbeat}
+}
+
+func TestCandidates(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	claimed := warmAgent("claimed", now.Add(-time.Hour), now)
+	claimed.Status = models.WarmAgentClaimed
+
+	agents := []*models.WarmAgent{
+		warmAgent("newer", now.Add(-time.Minute), now.Add(-5*time.Second)),
+		warmAgent("stale", now.Add(-2*time.Hour), now.Add(-time.Minute)),
+		claimed,
+		warmAgent("older", now.Add(-10*time.Minute), now.Add(-10*time.Second)),
+	}
+
+	got := Candidates(agents, now, DefaultStaleAfter)
+	if len(got) != 2 || got[0].AgentID != "older" || got[1].AgentID != "newer" {
+		var ids []string
+		for _, a := range got {
+			ids = append(ids, a.AgentID)
+		}
+		t.Errorf("Candidates() = %v, want [older newer]", ids)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemStore(
+		warmAgent("first", now.Add(-time.Hour), now),
+		warmAgent("second", now.Add(-time.Minute), now),
+	)
+	store.steal["first"] = true
+
+	pool := New(store)
+	pool.now = func() time.Time { return now }
+
+	agent, err := pool.Claim(context.Background(), "conv-1")
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if agent == nil || agent.AgentID != "second" {
+		t.Fatalf("Claim() = %v, want second (first was taken)", agent)
+	}
+	if agent.ConversationID != "conv-1" || agent.Status != models.WarmAgentClaimed {
+		t.Errorf("Claim() = %+v, want claimed for conv-1", agent)
+	}
+
+	// Pool is now drained
+	agent, err = pool.Claim(context.Background(), "conv-2")
+	if err != nil || agent != nil {
+		t.Errorf("Claim() on empty pool = %v, %v, want nil, nil", agent, err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	store := newMemStore()
+	pool := New(store)
+	pool.SetIntervals(5*time.Millisecond, 10*time.Millisecond)
+
+	agent := models.NewWarmAgent("")
+	go func() {
+		for {
+			if got, _ := pool.Claim(context.Background(), "conv-1"); got != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conversationID, err := pool.Wait(ctx, agent, time.Minute)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if conversationID != "conv-1" {
+		t.Errorf("Wait() = %s, want conv-1", conversationID)
+	}
+}
+
+func TestWaitIdleTimeout(t *testing.T) {
+	store := newMemStore()
+	pool := New(store)
+	pool.SetIntervals(5*time.Millisecond, 10*time.Millisecond)
+
+	agent := models.NewWarmAgent("")
+	_, err := pool.Wait(context.Background(), agent, 20*time.Millisecond)
+	if !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("Wait() error = %v, want ErrIdleTimeout", err)
+	}
+	if got, _ := store.Get(context.Background(), agent.AgentID); got != nil {
+		t.Errorf("agent still registered after idle timeout: %+v", got)
+	}
+}
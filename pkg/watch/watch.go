@@ -0,0 +1,131 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/savaki/cloudops-bot/pkg/dynamodb"
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+	slackclient "github.com/savaki/cloudops-bot/pkg/slack"
+	"github.com/slack-go/slack"
+)
+
+// statusEmoji prefixes status change notifications
+var statusEmoji = map[string]string{
+	models.StatusActive:    "🟢",
+	models.StatusCompleted: "✅",
+	models.StatusFailed:    "❌",
+	models.StatusTimeout:   "⏱️",
+}
+
+// Notifier sends direct messages to users watching a conversation
+type Notifier struct {
+	subs        *dynamodb.SubscriptionRepository
+	slackClient *slackclient.Client
+}
+
+// NewNotifier creates a notifier
+func NewNotifier(subs *dynamodb.SubscriptionRepository, slackClient *slackclient.Client) *Notifier {
+	return &Notifier{subs: subs, slackClient: slackClient}
+}
+
+// StatusChanged tells every watcher about a conversation status change
+func (n *Notifier) StatusChanged(ctx context.Context, conv *models.Conversation, status string) {
+	if n == nil {
+		return
+	}
+
+	subs, err := n.subs.ListForConversation(ctx, conv.ConversationID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load watchers", logging.ConversationID, conv.ConversationID, "error", err)
+		return
+	}
+
+	for _, sub := range subs {
+		msg := fmt.Sprintf("%s `%s` in <#%s> is now *%s*", statusEmoji[status], conv.ConversationID, conv.ChannelID, status)
+		n.dm(ctx, sub.UserID, strings.TrimSpace(msg))
+	}
+}
+
+// MessagePosted tells watchers when a message mentions one of their
+// keywords or resources. Participants already see the channel, so they are skipped
+func (n *Notifier) MessagePosted(ctx context.Context, conv *models.Conversation, text string, found []models.Entity) {
+	if n == nil {
+		return
+	}
+
+	subs, err := n.subs.ListForConversation(ctx, conv.ConversationID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to load watchers", logging.ConversationID, conv.ConversationID, "error", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if isParticipant(conv, sub.UserID) {
+			continue
+		}
+		matched := Matches(sub, text, found)
+		if len(matched) == 0 {
+			continue
+		}
+
+		msg := fmt.Sprintf("👀 `%s` in <#%s> mentioned %s:\n>%s", conv.ConversationID, conv.ChannelID, strings.Join(matched, ", "), excerpt(text, 300))
+		n.dm(ctx, sub.UserID, msg)
+	}
+}
+
+// dm posts a message to a user's DM with the bot
+func (n *Notifier) dm(ctx context.Context, userID, text string) {
+	if _, err := n.slackClient.PostMessage(ctx, userID, slack.MsgOptionText(text, false)); err != nil {
+		slog.WarnContext(ctx, "failed to notify watcher", logging.UserID, userID, "error", err)
+	}
+}
+
+// Matches returns the subscription keywords found in text, either as
+// case-insensitive substrings or as the ID of a mentioned resource
+func Matches(sub models.Subscription, text string, found []models.Entity) []string {
+	lower := strings.ToLower(text)
+
+	var matched []string
+	for _, keyword := range sub.Keywords {
+		k := strings.ToLower(keyword)
+		if k == "" {
+			continue
+		}
+		if strings.Contains(lower, k) {
+			matched = append(matched, keyword)
+			continue
+		}
+		for _, e := range found {
+			if strings.EqualFold(e.ID, keyword) || strings.HasSuffix(strings.ToLower(e.ID), "/"+k) {
+				matched = append(matched, keyword)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func isParticipant(conv *models.Conversation, userID string) bool {
+	if conv.UserID == userID {
+		return true
+	}
+	for _, p := range conv.Participants {
+		if p == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// excerpt shortens text to at most n runes on a single line
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,44 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+func TestMatches(t *testing.T) {
+	sub := models.Subscription{Keywords: []string{"rollback", "i-0abc123", "checkout-api", "payments-db"}}
+	found := []models.Entity{
+		{Type: models.EntityARN, ID: "arn:aws:rds:us-east-1:123456789012:db/payments-db"},
+	}
+
+	got := Matches(sub, "Starting a ROLLBACK of checkout-api now", found)
+	want := []string{"rollback", "checkout-api", "payments-db"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Matches() = %v, want %v", got, want)
+	}
+
+	if got := Matches(models.Subscription{}, "rollback", nil); len(got) != 0 {
+		t.Errorf("Matches() with no keywords = %v, want none", got)
+	}
+}
+
+func TestIsParticipant(t *testing.T) {
+	conv := &models.Conversation{UserID: "U1", Participants: []string{"U1", "U2"}}
+
+	for user, want := range map[string]bool{"U1": true, "U2": true, "U3": false} {
+		if got := isParticipant(conv, user); got != want {
+			t.Errorf("isParticipant(%s) = %v, want %v", user, got, want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := excerpt("a\n  b   c", 10); got != "a b c" {
+		t.Errorf("excerpt() = %q, want %q", got, "a b c")
+	}
+	if got := excerpt(strings.Repeat("x", 20), 5); got != "xxxx…" {
+		t.Errorf("excerpt() = %q, want %q", got, "xxxx…")
+	}
+}
@@ -0,0 +1,193 @@
+// Package webhook tells external systems, such as dashboards and ITSM
+// tools, when conversations start and end. Each event is POSTed as JSON to
+// every configured URL, signed with an HMAC secret so receivers can check
+// it came from the bot
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// Conversation lifecycle events
+const (
+	EventStarted   = "conversation.started"
+	EventCompleted = "conversation.completed"
+	EventFailed    = "conversation.failed"
+)
+
+// Request headers. The signature is "v1=" and the hex HMAC-SHA256 of
+// "v1:<timestamp>:<body>" keyed with the secret
+const (
+	HeaderEvent     = "X-CloudOps-Event"
+	HeaderDelivery  = "X-CloudOps-Delivery"
+	HeaderTimestamp = "X-CloudOps-Timestamp"
+	HeaderSignature = "X-CloudOps-Signature"
+)
+
+// maxAttempts is how many times a delivery is tried before it is dropped
+const maxAttempts = 3
+
+// Payload is the JSON body of every event
+type Payload struct {
+	Event          string     `json:"event"`
+	DeliveryID     string     `json:"delivery_id"` // the same across retries, so receivers can drop duplicates
+	OccurredAt     time.Time  `json:"occurred_at"`
+	ConversationID string     `json:"conversation_id"`
+	ChannelID      string     `json:"channel_id"`
+	UserID         string     `json:"user_id"`
+	Type           string     `json:"conversation_type,omitempty"`
+	Status         string     `json:"status"`
+	Tags           []string   `json:"tags,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// Notifier delivers lifecycle events to webhooks
+type Notifier struct {
+	urls       []string
+	secret     string
+	httpClient *http.Client
+	retryDelay time.Duration // doubled after each failed attempt
+}
+
+// New creates a notifier for urls, signing with secret
+func New(urls []string, secret string) *Notifier {
+	return &Notifier{
+		urls:       urls,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		retryDelay: time.Second,
+	}
+}
+
+// Send delivers event for conv to every webhook. It is best-effort: a
+// webhook that still fails after retries is logged and skipped
+func (n *Notifier) Send(ctx context.Context, event string, conv *models.Conversation) {
+	if n == nil {
+		return
+	}
+
+	payload := Payload{
+		Event:          event,
+		DeliveryID:     deliveryID(),
+		OccurredAt:     time.Now().UTC(),
+		ConversationID: conv.ConversationID,
+		ChannelID:      conv.ChannelID,
+		UserID:         conv.UserID,
+		Type:           conv.Type,
+		Status:         conv.Status,
+		Tags:           conv.Tags,
+		CreatedAt:      conv.CreatedAt,
+		CompletedAt:    conv.CompletedAt,
+		Error:          conv.Error,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to marshal webhook", "event", event, logging.ConversationID, conv.ConversationID, "error", err)
+		return
+	}
+
+	for _, url := range n.urls {
+		if err := n.deliver(ctx, url, payload, body); err != nil {
+			slog.WarnContext(ctx, "failed to deliver webhook", "event", event, logging.ConversationID, conv.ConversationID, "url", url, "error", err)
+		}
+	}
+}
+
+// deliver posts body to url, retrying network errors, throttling, and
+// server errors
+func (n *Notifier) deliver(ctx context.Context, url string, payload Payload, body []byte) error {
+	delay := n.retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = n.post(ctx, url, payload, body); err == nil || !retry {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("after %d attempts: %w", maxAttempts, err)
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying
+func (n *Notifier) post(ctx context.Context, url string, payload Payload, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.DeliveryID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(n.secret, timestamp, body))
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("post webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("post webhook: unexpected status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("post webhook: unexpected status %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the signature header for a body sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "v1:%s:", timestamp)
+	h.Write(body)
+	return "v1=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify reports whether signature was made with secret for body, and
+// timestamp is within five minutes of now, so receivers written in Go can
+// reject forged and replayed deliveries
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > 5*time.Minute || age < -5*time.Minute {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// deliveryID returns a random delivery identifier
+func deliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/models"
+)
+
+// receiver records deliveries, answering each with the next status
+type receiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []*http.Request
+	bodies     [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.deliveries = append(r.deliveries, req)
+	r.bodies = append(r.bodies, body)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func testNotifier(urls ...string) *Notifier {
+	n := New(urls, "s3cret")
+	n.retryDelay = time.Millisecond
+	return n
+}
+
+func TestSend(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	conv := &models.Conversation{ConversationID: "conv-1", ChannelID: "C1", UserID: "U1", Status: models.StatusFailed, Error: "task stopped"}
+	testNotifier(srv.URL).Send(context.Background(), EventFailed, conv)
+
+	if len(r.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(r.deliveries))
+	}
+	req, body := r.deliveries[0], r.bodies[0]
+	if got := req.Header.Get(HeaderEvent); got != EventFailed {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got, EventFailed)
+	}
+	if !Verify("s3cret", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, time.Now()) {
+		t.Error("delivery signature doesn't verify")
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if payload.ConversationID != "conv-1" || payload.Status != models.StatusFailed || payload.Error != "task stopped" {
+		t.Errorf("payload = %+v, want conv-1 failed with its error", payload)
+	}
+	if payload.DeliveryID != req.Header.Get(HeaderDelivery) {
+		t.Errorf("delivery_id = %q, header = %q, want them equal", payload.DeliveryID, req.Header.Get(HeaderDelivery))
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := []struct {
+		statuses []int
+		want     int
+	}{
+		{[]int{http.StatusBadGateway, http.StatusOK}, 2},
+		{[]int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusInternalServerError}, maxAttempts},
+		{[]int{http.StatusNotFound}, 1},
+	}
+	for _, tt := range tests {
+		r := &receiver{statuses: tt.statuses}
+		srv := httptest.NewServer(r)
+		testNotifier(srv.URL).Send(context.Background(), EventStarted, &models.Conversation{ConversationID: "conv-1"})
+		srv.Close()
+
+		if len(r.deliveries) != tt.want {
+			t.Errorf("statuses %v: got %d attempts, want %d", tt.statuses, len(r.deliveries), tt.want)
+		}
+		for _, req := range r.deliveries[1:] {
+			if req.Header.Get(HeaderDelivery) != r.deliveries[0].Header.Get(HeaderDelivery) {
+				t.Errorf("statuses %v: retry has a new delivery ID", tt.statuses)
+			}
+		}
+	}
+}
+
+func TestNilNotifier(t *testing.T) {
+	var n *Notifier
+	n.Send(context.Background(), EventStarted, &models.Conversation{})
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"event":"conversation.started"}`)
+	sig := Sign("s3cret", ts, body)
+
+	if !Verify("s3cret", ts, sig, body, now) {
+		t.Error("Verify() rejected a valid signature")
+	}
+	if Verify("other", ts, sig, body, now) {
+		t.Error("Verify() accepted the wrong secret")
+	}
+	if Verify("s3cret", ts, sig, []byte(`{"event":"conversation.failed"}`), now) {
+		t.Error("Verify() accepted a changed body")
+	}
+	if Verify("s3cret", ts, sig, body, now.Add(6*time.Minute)) {
+		t.Error("Verify() accepted a replayed delivery")
+	}
+}
@@ -0,0 +1,211 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/savaki/cloudops-bot/pkg/logging"
+)
+
+// Errors returned by Submit when the pool applies backpressure
+var (
+	ErrMailboxFull = errors.New("conversation mailbox full")
+	ErrQueueFull   = errors.New("worker pool queue full")
+	ErrClosed      = errors.New("worker pool closed")
+)
+
+// Task is a unit of work for a conversation
+type Task func(ctx context.Context)
+
+// Stats is a snapshot of pool load for backpressure monitoring
+type Stats struct {
+	Workers   int
+	Busy      int
+	Queued    int
+	Mailboxes int
+	Submitted int64
+	Completed int64
+	Rejected  int64
+	MaxWait   time.Duration // longest queue wait since the previous snapshot
+}
+
+type envelope struct {
+	task     Task
+	enqueued time.Time
+}
+
+// mailbox holds pending tasks for one conversation. A mailbox is scheduled
+// at most once, so its tasks run one at a time and in order
+type mailbox struct {
+	tasks     []envelope
+	scheduled bool
+}
+
+// Pool runs conversation tasks on a fixed number of workers. Tasks for the
+// same key never run concurrently, so replies in a conversation can't
+// interleave, while different conversations share the workers fairly
+type Pool struct {
+	ctx         context.Context
+	workers     int
+	mailboxSize int
+	queueSize   int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	mailboxes map[string]*mailbox
+	ready     []string // keys with pending tasks, in scheduling order
+	closed    bool
+	stats     Stats
+	wg        sync.WaitGroup
+}
+
+// New starts a pool with the given number of workers. mailboxSize bounds
+// pending tasks per conversation and queueSize bounds them across all
+// conversations. Tasks receive ctx
+func New(ctx context.Context, workers, mailboxSize, queueSize int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &Pool{
+		ctx:         ctx,
+		workers:     workers,
+		mailboxSize: mailboxSize,
+		queueSize:   queueSize,
+		mailboxes:   map[string]*mailbox{},
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.stats.Workers = workers
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues a task for the conversation identified by key. It never
+// blocks; when the conversation's mailbox or the whole pool is full the
+// task is rejected so the caller can tell the user to wait
+func (p *Pool) Submit(key string, task Task) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	if p.queueSize > 0 && p.stats.Queued >= p.queueSize {
+		p.stats.Rejected++
+		return ErrQueueFull
+	}
+
+	mb := p.mailboxes[key]
+	if mb == nil {
+		mb = &mailbox{}
+		p.mailboxes[key] = mb
+	}
+	if p.mailboxSize > 0 && len(mb.tasks) >= p.mailboxSize {
+		p.stats.Rejected++
+		return ErrMailboxFull
+	}
+
+	mb.tasks = append(mb.tasks, envelope{task: task, enqueued: time.Now()})
+	p.stats.Queued++
+	p.stats.Submitted++
+	if !mb.scheduled {
+		mb.scheduled = true
+		p.ready = append(p.ready, key)
+		p.cond.Signal()
+	}
+	return nil
+}
+
+// Stats returns current load and resets the MaxWait window
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.stats
+	s.Mailboxes = len(p.mailboxes)
+	p.stats.MaxWait = 0
+	return s
+}
+
+// Close stops accepting tasks and waits for queued ones to finish, or for
+// ctx to expire
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// work takes one task at a time from the next ready mailbox. A mailbox with
+// more work goes to the back of the line so a busy conversation can't
+// starve the others
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		for len(p.ready) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.ready) == 0 {
+			p.mu.Unlock()
+			return
+		}
+
+		key := p.ready[0]
+		p.ready = p.ready[1:]
+		mb := p.mailboxes[key]
+		next := mb.tasks[0]
+		mb.tasks = mb.tasks[1:]
+		p.stats.Queued--
+		p.stats.Busy++
+		if wait := time.Since(next.enqueued); wait > p.stats.MaxWait {
+			p.stats.MaxWait = wait
+		}
+		p.mu.Unlock()
+
+		p.run(key, next.task)
+
+		p.mu.Lock()
+		p.stats.Busy--
+		p.stats.Completed++
+		if len(mb.tasks) > 0 {
+			p.ready = append(p.ready, key)
+			p.cond.Signal()
+		} else {
+			delete(p.mailboxes, key)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// run executes a task, keeping the worker alive if it panics
+func (p *Pool) run(key string, task Task) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("worker panic", logging.ConversationID, key, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	task(p.ctx)
+}
@@ -0,0 +1,120 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolOrdersTasksPerKey(t *testing.T) {
+	p := New(context.Background(), 4, 0, 0)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var running [3]int32
+
+	for i := 0; i < 20; i++ {
+		for k := 0; k < 3; k++ {
+			k, i := k, i
+			key := fmt.Sprintf("conv-%d", k)
+			err := p.Submit(key, func(ctx context.Context) {
+				if atomic.AddInt32(&running[k], 1) > 1 {
+					t.Errorf("tasks for %s ran concurrently", key)
+				}
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				got[key] = append(got[key], i)
+				mu.Unlock()
+				atomic.AddInt32(&running[k], -1)
+			})
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+		}
+	}
+
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for key, order := range got {
+		if len(order) != 20 {
+			t.Errorf("%s ran %d tasks, want 20", key, len(order))
+		}
+		for i, n := range order {
+			if n != i {
+				t.Errorf("%s order = %v, want ascending", key, order)
+				break
+			}
+		}
+	}
+}
+
+func TestPoolBackpressure(t *testing.T) {
+	p := New(context.Background(), 1, 2, 3)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	block := func(ctx context.Context) {
+		close(started)
+		<-release
+	}
+	noop := func(ctx context.Context) {}
+
+	// Occupy the only worker so everything else queues
+	if err := p.Submit("busy", block); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	<-started
+
+	tests := []struct {
+		key  string
+		want error
+	}{
+		{"a", nil},
+		{"a", nil},
+		{"a", ErrMailboxFull},
+		{"b", nil},
+		{"c", ErrQueueFull},
+	}
+	for _, tt := range tests {
+		if err := p.Submit(tt.key, noop); !errors.Is(err, tt.want) {
+			t.Errorf("Submit(%s) error = %v, want %v", tt.key, err, tt.want)
+		}
+	}
+
+	stats := p.Stats()
+	if stats.Busy != 1 || stats.Queued != 3 || stats.Rejected != 2 || stats.Mailboxes != 3 {
+		t.Errorf("Stats() = %+v, want busy=1 queued=3 rejected=2 mailboxes=3", stats)
+	}
+
+	close(release)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if stats := p.Stats(); stats.Completed != 4 || stats.Queued != 0 {
+		t.Errorf("Stats() after close = %+v, want completed=4 queued=0", stats)
+	}
+	if err := p.Submit("a", noop); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit() after close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestPoolRecoversPanics(t *testing.T) {
+	p := New(context.Background(), 1, 0, 0)
+
+	var ran atomic.Bool
+	p.Submit("conv", func(ctx context.Context) { panic("boom") })
+	p.Submit("conv", func(ctx context.Context) { ran.Store(true) })
+
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !ran.Load() {
+		t.Error("task after a panic did not run")
+	}
+}
@@ -110,6 +110,12 @@ features:
   bot_user:
     display_name: ${DISPLAY_NAME}
     always_online: true
+  slash_commands:
+    - command: /cloudops
+      url: "${WEBHOOK_URL}"
+      description: CloudOps Bot commands (postmortem, tag, ...)
+      usage_hint: help
+      should_escape: true
 
 oauth_config:
   scopes:
@@ -118,8 +124,12 @@ oauth_config:
       - channels:history
       - channels:read
       - chat:write
+      - commands
+      - files:write
       - im:history
+      - reactions:read
       - users:read
+      - users:read.email
 
 settings:
   event_subscriptions:
@@ -142,8 +152,23 @@ fi
 cat >> "${OUTPUT_FILE}" <<EOF
     bot_events:
       - app_mention
+      - reaction_added
   interactivity:
-    is_enabled: false
+    is_enabled: true
+EOF
+
+# Button clicks (suggested follow-ups) go to the same webhook
+if [ -n "$WEBHOOK_URL" ]; then
+  cat >> "${OUTPUT_FILE}" <<EOF
+    request_url: ${WEBHOOK_URL}
+EOF
+else
+  cat >> "${OUTPUT_FILE}" <<EOF
+    request_url: ""
+EOF
+fi
+
+cat >> "${OUTPUT_FILE}" <<EOF
   org_deploy_enabled: false
   socket_mode_enabled: false
   token_rotation_enabled: false
@@ -36,6 +36,7 @@ aws dynamodb create-table \
     AttributeName=conversation_id,AttributeType=S \
     AttributeName=channel_id,AttributeType=S \
     AttributeName=status,AttributeType=S \
+    AttributeName=thread_key,AttributeType=S \
   --key-schema \
     AttributeName=conversation_id,KeyType=HASH \
   --global-secondary-indexes \
@@ -61,6 +62,17 @@ aws dynamodb create-table \
           "ReadCapacityUnits": 5,
           "WriteCapacityUnits": 5
         }
+      },
+      {
+        "IndexName": "thread-index",
+        "KeySchema": [
+          {"AttributeName": "thread_key", "KeyType": "HASH"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
       }
     ]' \
   --provisioned-throughput \
@@ -87,6 +99,429 @@ aws dynamodb create-table \
 
 echo "✅ Conversation History table created"
 
+# Create Conversation Tags table
+echo "Creating cloudops-conversation-tags-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-conversation-tags-local \
+  --attribute-definitions \
+    AttributeName=conversation_id,AttributeType=S \
+    AttributeName=tag,AttributeType=S \
+    AttributeName=created_at,AttributeType=S \
+  --key-schema \
+    AttributeName=conversation_id,KeyType=HASH \
+    AttributeName=tag,KeyType=RANGE \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "TagIndex",
+        "KeySchema": [
+          {"AttributeName": "tag", "KeyType": "HASH"},
+          {"AttributeName": "created_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Conversation Tags table created"
+
+# Create Subscriptions table
+echo "Creating cloudops-subscriptions-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-subscriptions-local \
+  --attribute-definitions \
+    AttributeName=conversation_id,AttributeType=S \
+    AttributeName=user_id,AttributeType=S \
+  --key-schema \
+    AttributeName=conversation_id,KeyType=HASH \
+    AttributeName=user_id,KeyType=RANGE \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Subscriptions table created"
+
+# Create SLA outcomes table
+echo "Creating cloudops-sla-outcomes-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-sla-outcomes-local \
+  --attribute-definitions \
+    AttributeName=conversation_id,AttributeType=S \
+  --key-schema \
+    AttributeName=conversation_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ SLA outcomes table created"
+
+# Create Audit table
+echo "Creating cloudops-audit-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-audit-local \
+  --attribute-definitions \
+    AttributeName=event_id,AttributeType=S \
+  --key-schema \
+    AttributeName=event_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Audit table created"
+
+# Create Tool Audit table
+echo "Creating cloudops-tool-audit-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-tool-audit-local \
+  --attribute-definitions \
+    AttributeName=execution_id,AttributeType=S \
+    AttributeName=user_id,AttributeType=S \
+    AttributeName=conversation_id,AttributeType=S \
+    AttributeName=started_at,AttributeType=S \
+  --key-schema \
+    AttributeName=execution_id,KeyType=HASH \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "UserIndex",
+        "KeySchema": [
+          {"AttributeName": "user_id", "KeyType": "HASH"},
+          {"AttributeName": "started_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      },
+      {
+        "IndexName": "ConversationIndex",
+        "KeySchema": [
+          {"AttributeName": "conversation_id", "KeyType": "HASH"},
+          {"AttributeName": "started_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Tool audit table created"
+
+# Create Jobs table
+echo "Creating cloudops-jobs-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-jobs-local \
+  --attribute-definitions \
+    AttributeName=job_id,AttributeType=S \
+    AttributeName=conversation_id,AttributeType=S \
+    AttributeName=created_at,AttributeType=S \
+  --key-schema \
+    AttributeName=job_id,KeyType=HASH \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "ConversationIndex",
+        "KeySchema": [
+          {"AttributeName": "conversation_id", "KeyType": "HASH"},
+          {"AttributeName": "created_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Jobs table created"
+
+# Create Announcements table
+echo "Creating cloudops-announcements-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-announcements-local \
+  --attribute-definitions \
+    AttributeName=channel_id,AttributeType=S \
+    AttributeName=message_ts,AttributeType=S \
+    AttributeName=announcement_id,AttributeType=S \
+  --key-schema \
+    AttributeName=channel_id,KeyType=HASH \
+    AttributeName=message_ts,KeyType=RANGE \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "AnnouncementIndex",
+        "KeySchema": [
+          {"AttributeName": "announcement_id", "KeyType": "HASH"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Announcements table created"
+
+# Create Alerts table
+echo "Creating cloudops-alerts-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-alerts-local \
+  --attribute-definitions \
+    AttributeName=channel_id,AttributeType=S \
+    AttributeName=message_ts,AttributeType=S \
+    AttributeName=status,AttributeType=S \
+    AttributeName=created_at,AttributeType=S \
+  --key-schema \
+    AttributeName=channel_id,KeyType=HASH \
+    AttributeName=message_ts,KeyType=RANGE \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "StatusIndex",
+        "KeySchema": [
+          {"AttributeName": "status", "KeyType": "HASH"},
+          {"AttributeName": "created_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Alerts table created"
+
+# Create Warm Pool table
+echo "Creating cloudops-warm-pool-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-warm-pool-local \
+  --attribute-definitions \
+    AttributeName=agent_id,AttributeType=S \
+    AttributeName=status,AttributeType=S \
+    AttributeName=registered_at,AttributeType=S \
+  --key-schema \
+    AttributeName=agent_id,KeyType=HASH \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "StatusIndex",
+        "KeySchema": [
+          {"AttributeName": "status", "KeyType": "HASH"},
+          {"AttributeName": "registered_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Warm pool table created"
+
+# Create Prompts table
+echo "Creating cloudops-prompts-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-prompts-local \
+  --attribute-definitions \
+    AttributeName=name,AttributeType=S \
+    AttributeName=version,AttributeType=N \
+  --key-schema \
+    AttributeName=name,KeyType=HASH \
+    AttributeName=version,KeyType=RANGE \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Prompts table created"
+
+# Create Runbooks table
+echo "Creating cloudops-runbooks-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-runbooks-local \
+  --attribute-definitions \
+    AttributeName=runbook_id,AttributeType=S \
+    AttributeName=status,AttributeType=S \
+    AttributeName=conversation_id,AttributeType=S \
+    AttributeName=created_at,AttributeType=S \
+  --key-schema \
+    AttributeName=runbook_id,KeyType=HASH \
+  --global-secondary-indexes \
+    '[
+      {
+        "IndexName": "StatusIndex",
+        "KeySchema": [
+          {"AttributeName": "status", "KeyType": "HASH"},
+          {"AttributeName": "created_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      },
+      {
+        "IndexName": "ConversationIndex",
+        "KeySchema": [
+          {"AttributeName": "conversation_id", "KeyType": "HASH"},
+          {"AttributeName": "created_at", "KeyType": "RANGE"}
+        ],
+        "Projection": {"ProjectionType": "ALL"},
+        "ProvisionedThroughput": {
+          "ReadCapacityUnits": 5,
+          "WriteCapacityUnits": 5
+        }
+      }
+    ]' \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Runbooks table created"
+
+# Create Permissions table
+echo "Creating cloudops-permissions-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-permissions-local \
+  --attribute-definitions \
+    AttributeName=user_id,AttributeType=S \
+  --key-schema \
+    AttributeName=user_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Permissions table created"
+
+# Create Settings table
+echo "Creating cloudops-settings-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-settings-local \
+  --attribute-definitions \
+    AttributeName=setting_id,AttributeType=S \
+  --key-schema \
+    AttributeName=setting_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Settings table created"
+
+# Create Approvals table
+echo "Creating cloudops-approvals-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-approvals-local \
+  --attribute-definitions \
+    AttributeName=approval_id,AttributeType=S \
+  --key-schema \
+    AttributeName=approval_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Approvals table created"
+
+echo "Creating cloudops-slack-tokens-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-slack-tokens-local \
+  --attribute-definitions \
+    AttributeName=token_id,AttributeType=S \
+  --key-schema \
+    AttributeName=token_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Slack tokens table created"
+
+echo "Creating cloudops-usage-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-usage-local \
+  --attribute-definitions \
+    AttributeName=month,AttributeType=S \
+    AttributeName=cost_center,AttributeType=S \
+  --key-schema \
+    AttributeName=month,KeyType=HASH \
+    AttributeName=cost_center,KeyType=RANGE \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Usage table created"
+
+echo "Creating cloudops-locks-local table..."
+aws dynamodb create-table \
+  --endpoint-url ${ENDPOINT} \
+  --region ${REGION} \
+  --table-name cloudops-locks-local \
+  --attribute-definitions \
+    AttributeName=lock_id,AttributeType=S \
+  --key-schema \
+    AttributeName=lock_id,KeyType=HASH \
+  --provisioned-throughput \
+    ReadCapacityUnits=5,WriteCapacityUnits=5 \
+  --no-cli-pager > /dev/null 2>&1
+
+echo "✅ Locks table created"
+
 echo ""
 echo "======================================================================"
 echo "✅ Local DynamoDB Setup Complete"
@@ -95,6 +530,23 @@ echo ""
 echo "Tables created:"
 echo "  - cloudops-conversations-local"
 echo "  - cloudops-conversation-history-local"
+echo "  - cloudops-conversation-tags-local"
+echo "  - cloudops-subscriptions-local"
+echo "  - cloudops-sla-outcomes-local"
+echo "  - cloudops-audit-local"
+echo "  - cloudops-tool-audit-local"
+echo "  - cloudops-jobs-local"
+echo "  - cloudops-announcements-local"
+echo "  - cloudops-alerts-local"
+echo "  - cloudops-warm-pool-local"
+echo "  - cloudops-prompts-local"
+echo "  - cloudops-runbooks-local"
+echo "  - cloudops-permissions-local"
+echo "  - cloudops-settings-local"
+echo "  - cloudops-approvals-local"
+echo "  - cloudops-slack-tokens-local"
+echo "  - cloudops-usage-local"
+echo "  - cloudops-locks-local"
 echo ""
 echo "DynamoDB Admin UI: http://localhost:8001"
 echo ""